@@ -4,6 +4,7 @@ package discord
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -208,7 +209,24 @@ func (a *Adapter) Send(ctx context.Context, req *channels.SendRequest) (*channel
 		}
 	}
 
-	msgID, err := a.client.SendMessage(ctx, channelID, req.Text, opts)
+	var paths []string
+	for _, att := range req.Attachments {
+		if att.Path != "" {
+			paths = append(paths, att.Path)
+		}
+	}
+
+	var msgID string
+	var err error
+	if len(paths) > 0 {
+		// Discord takes up to 10 files per message.
+		if len(paths) > 10 {
+			paths = paths[:10]
+		}
+		msgID, err = a.client.SendFiles(ctx, channelID, req.Text, paths, opts)
+	} else {
+		msgID, err = a.client.SendMessage(ctx, channelID, req.Text, opts)
+	}
 	if err != nil {
 		return &channels.SendResult{Success: false, Error: err.Error()}, err
 	}
@@ -293,6 +311,29 @@ func (a *Adapter) handleEvent(event *GatewayEvent) {
 		Timestamp:   msg.Timestamp.Unix(),
 	}
 
+	ctx := context.Background()
+
+	// Audio attachments are downloaded, once the gateway has authorized the
+	// sender, so it can transcribe them.
+	for _, f := range msg.Attachments {
+		if !strings.HasPrefix(f.ContentType, "audio/") {
+			continue
+		}
+		typ := "audio"
+		if msg.Flags&MessageFlagVoiceMessage != 0 {
+			typ = "voice"
+		}
+		url, name, sender := f.URL, f.Filename, incoming.SenderID
+		incoming.Attachments = append(incoming.Attachments, channels.Attachment{
+			Type:     typ,
+			MimeType: f.ContentType,
+			Name:     name,
+			Fetch: func(ctx context.Context) (string, error) {
+				return channels.DownloadMedia(ctx, nil, "discord", sender, url, name, 0)
+			},
+		})
+	}
+
 	// Determine chat type based on guild presence
 	if msg.GuildID != "" {
 		incoming.ChatType = "group"
@@ -315,7 +356,6 @@ func (a *Adapter) handleEvent(event *GatewayEvent) {
 	a.State().LastInboundAt = &now
 
 	// Forward to handler (Gateway)
-	ctx := context.Background()
 	if err := handler.HandleIncoming(ctx, incoming); err != nil {
 		a.Logger().Error().Err(err).Msg("Failed to handle incoming message")
 	}
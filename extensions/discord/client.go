@@ -6,8 +6,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -75,6 +78,20 @@ type DiscordMessage struct {
 	Timestamp        time.Time         `json:"timestamp"`
 	Thread           *DiscordThread    `json:"thread,omitempty"`
 	MessageReference *MessageReference `json:"message_reference,omitempty"`
+	Attachments      []DiscordFile     `json:"attachments,omitempty"`
+	Flags            int               `json:"flags,omitempty"`
+}
+
+// MessageFlagVoiceMessage marks a message recorded with the voice button.
+const MessageFlagVoiceMessage = 1 << 13
+
+// DiscordFile represents a message attachment.
+type DiscordFile struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
 }
 
 // GatewayEvent represents a Discord gateway event.
@@ -138,6 +155,65 @@ func (c *Client) SendMessage(ctx context.Context, channelID, content string, opt
 	return msg.ID, nil
 }
 
+// SendFiles sends a message with the files at paths attached.
+func (c *Client) SendFiles(ctx context.Context, channelID, content string, paths []string, opts *SendMessageOptions) (string, error) {
+	payload := map[string]interface{}{
+		"content": content,
+	}
+	if opts != nil && opts.MessageReference != nil {
+		payload["message_reference"] = opts.MessageReference
+	}
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("payload_json", string(payloadJSON)); err != nil {
+		return "", err
+	}
+	for i, path := range paths {
+		if err := addFormFile(w, fmt.Sprintf("files[%d]", i), path); err != nil {
+			return "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiBaseURL+"/channels/"+channelID+"/messages", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var msg DiscordMessage
+	if err := json.Unmarshal(resp, &msg); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// addFormFile copies the file at path into a multipart field.
+func addFormFile(w *multipart.Writer, field, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	part, err := w.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
 // CreateReaction adds a reaction to a message.
 func (c *Client) CreateReaction(ctx context.Context, channelID, messageID, emoji string) error {
 	// URL encode the emoji
@@ -308,10 +384,15 @@ func (c *Client) request(ctx context.Context, method, path string, body interfac
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bot "+c.token)
 	if body != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
+	return c.do(req)
+}
+
+// do sends an authenticated request and returns the response body.
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("Authorization", "Bot "+c.token)
 
 	resp, err := c.http.Do(req)
 	if err != nil {
@@ -43,7 +43,7 @@ func New(cfg *Config, logger zerolog.Logger) *Adapter {
 		Threads:        true,
 		Media:          true,
 		Stickers:       true,
-		Voice:          false,
+		Voice:          true,
 		NativeCommands: true,
 		BlockStreaming: true,
 		Webhooks:       true,
@@ -179,9 +179,27 @@ func (a *Adapter) Send(ctx context.Context, req *channels.SendRequest) (*channel
 		ThreadID:         parseMessageID(req.ThreadID),
 	}
 
-	msgID, err := a.client.SendMessage(ctx, chatID, req.Text, opts)
-	if err != nil {
-		return &channels.SendResult{Success: false, Error: err.Error()}, err
+	var msgID string
+	var err error
+	if req.Text != "" || len(req.Attachments) == 0 {
+		msgID, err = a.client.SendMessage(ctx, chatID, req.Text, opts)
+		if err != nil {
+			return &channels.SendResult{Success: false, Error: err.Error()}, err
+		}
+	}
+
+	for _, att := range req.Attachments {
+		if att.Path == "" {
+			continue
+		}
+		method, field := uploadMethod(att.Type)
+		id, err := a.client.SendFile(ctx, method, field, chatID, att.Path, opts)
+		if err != nil {
+			return &channels.SendResult{MessageID: msgID, Success: false, Error: err.Error()}, err
+		}
+		if msgID == "" {
+			msgID = id
+		}
 	}
 
 	// Update state
@@ -255,6 +273,33 @@ func (a *Adapter) handleMessage(ctx context.Context, msg *TelegramMessage) {
 		Text:        msg.Text,
 		Timestamp:   msg.Date,
 	}
+	if incoming.Text == "" {
+		incoming.Text = msg.Caption
+	}
+
+	// Voice notes and audio are downloaded, once the gateway has authorized
+	// the sender, so it can transcribe them.
+	for _, m := range []struct {
+		typ  string
+		file *TelegramFile
+	}{{"voice", msg.Voice}, {"audio", msg.Audio}} {
+		if m.file == nil {
+			continue
+		}
+		name := m.file.FileName
+		if name == "" && m.typ == "voice" {
+			name = "voice.ogg"
+		}
+		fileID, sender := m.file.FileID, incoming.SenderID
+		incoming.Attachments = append(incoming.Attachments, channels.Attachment{
+			Type:     m.typ,
+			MimeType: m.file.MimeType,
+			Name:     name,
+			Fetch: func(ctx context.Context) (string, error) {
+				return a.client.DownloadFile(ctx, fileID, sender, name)
+			},
+		})
+	}
 
 	// Determine chat type
 	switch msg.Chat.Type {
@@ -286,6 +331,22 @@ func (a *Adapter) handleMessage(ctx context.Context, msg *TelegramMessage) {
 	}
 }
 
+// uploadMethod maps an attachment type to the Bot API method and file field.
+func uploadMethod(attType string) (method, field string) {
+	switch attType {
+	case "voice":
+		return "sendVoice", "voice"
+	case "audio":
+		return "sendAudio", "audio"
+	case "image":
+		return "sendPhoto", "photo"
+	case "video":
+		return "sendVideo", "video"
+	default:
+		return "sendDocument", "document"
+	}
+}
+
 func buildSenderName(from *TelegramUser) string {
 	if from == nil {
 		return "Unknown"
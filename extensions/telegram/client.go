@@ -6,13 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+
+	"github.com/liteclaw/liteclaw/internal/channels"
 )
 
-const apiBaseURL = "https://api.telegram.org/bot"
+const (
+	apiBaseURL  = "https://api.telegram.org/bot"
+	fileBaseURL = "https://api.telegram.org/file/bot"
+)
 
 // Client is a Telegram Bot API client.
 type Client struct {
@@ -55,10 +64,22 @@ type TelegramMessage struct {
 	Chat            *TelegramChat    `json:"chat"`
 	Date            int64            `json:"date"`
 	Text            string           `json:"text,omitempty"`
+	Caption         string           `json:"caption,omitempty"`
+	Voice           *TelegramFile    `json:"voice,omitempty"`
+	Audio           *TelegramFile    `json:"audio,omitempty"`
 	MessageThreadID int64            `json:"message_thread_id,omitempty"`
 	ReplyToMessage  *TelegramMessage `json:"reply_to_message,omitempty"`
 }
 
+// TelegramFile is the file part of a voice note or audio message.
+type TelegramFile struct {
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+}
+
 // TelegramUpdate represents a Telegram update.
 type TelegramUpdate struct {
 	UpdateID int64            `json:"update_id"`
@@ -127,6 +148,78 @@ func (c *Client) SendMessage(ctx context.Context, chatID, text string, opts *Sen
 	return fmt.Sprintf("%d", msg.MessageID), nil
 }
 
+// SendFile uploads a local file with a send* method such as sendVoice,
+// sendAudio, sendPhoto or sendDocument; field is the method's file field.
+func (c *Client) SendFile(ctx context.Context, method, field, chatID, path string, opts *SendMessageOptions) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	_ = w.WriteField("chat_id", chatID)
+	if opts != nil {
+		if opts.ReplyToMessageID > 0 {
+			_ = w.WriteField("reply_to_message_id", fmt.Sprintf("%d", opts.ReplyToMessageID))
+		}
+		if opts.ThreadID > 0 {
+			_ = w.WriteField("message_thread_id", fmt.Sprintf("%d", opts.ThreadID))
+		}
+	}
+	part, err := w.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiBaseURL+c.token+"/"+method, &buf)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var msg TelegramMessage
+	if err := json.Unmarshal(resp.Result, &msg); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", msg.MessageID), nil
+}
+
+// DownloadFile saves a file sent by peer into the inbound media dir and
+// returns its local path.
+func (c *Client) DownloadFile(ctx context.Context, fileID, peer, name string) (string, error) {
+	resp, err := c.request(ctx, "getFile", map[string]interface{}{"file_id": fileID})
+	if err != nil {
+		return "", err
+	}
+	var file struct {
+		FilePath string `json:"file_path"`
+	}
+	if err := json.Unmarshal(resp.Result, &file); err != nil {
+		return "", err
+	}
+	if file.FilePath == "" {
+		return "", fmt.Errorf("telegram returned no file path for %s", fileID)
+	}
+	if name == "" {
+		name = file.FilePath
+	}
+	path, err := channels.DownloadMedia(ctx, c.http, "telegram", peer, fileBaseURL+c.token+"/"+file.FilePath, name, 0)
+	return path, c.redactToken(err)
+}
+
 // GetUpdates gets updates via long polling.
 func (c *Client) GetUpdates(ctx context.Context, offset int64, timeout int) ([]TelegramUpdate, error) {
 	params := map[string]interface{}{
@@ -196,9 +289,14 @@ func (c *Client) request(ctx context.Context, method string, params map[string]i
 		req.Header.Set("Content-Type", "application/json")
 	}
 
+	return c.do(req)
+}
+
+// do sends req and decodes the API envelope.
+func (c *Client) do(req *http.Request) (*APIResponse, error) {
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, c.redactToken(err)
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -218,3 +316,22 @@ func (c *Client) request(ctx context.Context, method string, params map[string]i
 
 	return &apiResp, nil
 }
+
+// redactToken keeps the bot token, which is part of every request URL, out
+// of errors that quote the URL, such as the *url.Error of a failed request.
+func (c *Client) redactToken(err error) error {
+	if err == nil || c.token == "" || !strings.Contains(err.Error(), c.token) {
+		return err
+	}
+	return &tokenError{msg: strings.ReplaceAll(err.Error(), c.token, "<token>"), err: err}
+}
+
+// tokenError is an error whose message has the bot token removed.
+type tokenError struct {
+	msg string
+	err error
+}
+
+func (e *tokenError) Error() string { return e.msg }
+
+func (e *tokenError) Unwrap() error { return e.err }
@@ -7,12 +7,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 	"github.com/liteclaw/liteclaw/internal/agent/policy"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
 	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/config"
 	mcp "github.com/liteclaw/liteclaw/mcp"
 )
 
@@ -32,6 +38,17 @@ type Agent struct {
 	MCPManager      *mcp.Manager
 	Verbose         bool
 
+	// Models resolves per-run model overrides. When nil, Provider and Model are used.
+	Models *ModelRegistry
+	// Compaction bounds session history and configures memory flushes.
+	Compaction config.CompactionConfig
+	// AfterRun, when set, is called once a run that called tools finishes,
+	// before its event stream closes (e.g. to commit workspace edits).
+	AfterRun func(RunInfo)
+	// PathGuard, when set, returns the file guard for a session's tool
+	// calls, e.g. to let it read its own inbound media.
+	PathGuard func(tools.SessionContext) *pathguard.Guard
+
 	mu       sync.RWMutex
 	sessions map[string]*Session
 }
@@ -44,6 +61,59 @@ type Session struct {
 	ToolCalls    []ToolCall
 	StartedAt    int64
 	LastActiveAt int64
+	// Model is a per-session model override ("provider/model" or alias).
+	Model string
+	// AuthProfile pins the session to one auth profile of its provider.
+	AuthProfile string
+	// Usage sums the tokens reported for the session's model calls.
+	Usage llm.Usage
+
+	// systemEvents are notices (e.g. background process exits) delivered
+	// with the next user message.
+	systemEvents []string
+	// running is set while a run is in progress.
+	running bool
+	// flushing is set while a memory flush is in progress.
+	flushing bool
+	// flushedLen is the history length covered by the last memory flush.
+	flushedLen int
+	// compacting is set while a compaction is prepared in the background.
+	compacting bool
+	// compaction is applied at the start of the next run.
+	compaction *pendingCompaction
+}
+
+// RunOptions customises a single agent run.
+type RunOptions struct {
+	// Model overrides the session and default model for this run
+	// (e.g. from a cron payload or a sub-agent request).
+	Model string
+	// SystemPrompt is appended to the agent system prompt for this run.
+	SystemPrompt string
+	// AuthProfile pins the auth profile for this run, overriding the session pin.
+	AuthProfile string
+	// SessionType hints the chat type ("direct", "group", ...) for sessions
+	// whose key does not imply one; it drives sandbox selection.
+	SessionType string
+	// OnAttachment receives media produced by tools during the run, such
+	// as tts audio, for delivery with the reply.
+	OnAttachment func(tools.MediaAttachment)
+	// RunID identifies the run, e.g. in workspace commits (default: a new
+	// UUID).
+	RunID string
+}
+
+// RunInfo describes a finished run for Agent.AfterRun.
+type RunInfo struct {
+	SessionID string
+	RunID     string
+	// Tools lists the tools called, in order of first use.
+	Tools []string
+	// Files lists the files changed by file-writing tools (see
+	// tools.ChangedFiles).
+	Files []string
+	// Usage sums the tokens reported for the run's model calls.
+	Usage llm.Usage
 }
 
 // Message represents a conversation message.
@@ -52,6 +122,9 @@ type Message struct {
 	Content    string         `json:"content"`
 	ToolCalls  []llm.ToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string         `json:"tool_call_id,omitempty"`
+	// Reasoning holds the provider's reasoning items for an assistant
+	// message; see llm.Message.Reasoning.
+	Reasoning []json.RawMessage `json:"reasoning,omitempty"`
 }
 
 // ToolCall represents a tool invocation.
@@ -112,6 +185,11 @@ func (a *Agent) ExtractToolNames() []string {
 
 // Run runs the agent with the given input message.
 func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan StreamEvent, error) {
+	return a.RunWithOptions(ctx, sessionID, input, RunOptions{})
+}
+
+// RunWithOptions runs the agent with per-run options such as a model override.
+func (a *Agent) RunWithOptions(ctx context.Context, sessionID string, input string, opts RunOptions) (<-chan StreamEvent, error) {
 	events := make(chan StreamEvent, 100)
 
 	go func() {
@@ -120,6 +198,62 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 		// Get or create session
 		session := a.getOrCreateSession(sessionID)
 
+		provider, model, maxTokens, err := a.resolveModel(session, opts.Model)
+		if err != nil {
+			events <- StreamEvent{Type: "error", Error: err.Error()}
+			return
+		}
+
+		authProfile := opts.AuthProfile
+		if authProfile == "" {
+			a.mu.RLock()
+			authProfile = session.AuthProfile
+			a.mu.RUnlock()
+		}
+		ctx = WithAuthProfile(ctx, authProfile)
+		ctx = tools.WithSession(ctx, a.toolSession(sessionID, opts.SessionType))
+
+		a.mu.Lock()
+		session.running = true
+		session.LastActiveAt = time.Now().UnixMilli()
+		a.mu.Unlock()
+		defer func() {
+			a.mu.Lock()
+			session.running = false
+			session.LastActiveAt = time.Now().UnixMilli()
+			a.mu.Unlock()
+			a.maybeCompact(session)
+		}()
+
+		runID := opts.RunID
+		if runID == "" {
+			runID = uuid.New().String()
+		}
+		var toolsUsed, filesChanged []string
+		var usage llm.Usage
+		defer func() {
+			a.mu.Lock()
+			addUsage(&session.Usage, usage)
+			a.mu.Unlock()
+			if a.AfterRun != nil && len(toolsUsed) > 0 {
+				a.AfterRun(RunInfo{SessionID: sessionID, RunID: runID, Tools: toolsUsed, Files: filesChanged, Usage: usage})
+			}
+		}()
+
+		a.compactSession(session)
+
+		a.mu.Lock()
+		pending := session.systemEvents
+		session.systemEvents = nil
+		a.mu.Unlock()
+		if len(pending) > 0 {
+			var b strings.Builder
+			for _, e := range pending {
+				b.WriteString("System: " + e + "\n")
+			}
+			input = b.String() + "\n" + input
+		}
+
 		// Add user message
 		session.Messages = append(session.Messages, Message{
 			Role:    "user",
@@ -129,6 +263,9 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 		// Dynamic MCP Tool Selection
 		var dynamicTools []tools.Tool
 		systemPromptPrefix := a.SystemPrompt
+		if opts.SystemPrompt != "" {
+			systemPromptPrefix = fmt.Sprintf("%s\n\n%s", systemPromptPrefix, opts.SystemPrompt)
+		}
 		if a.MCPManager != nil {
 			selected := a.MCPManager.SelectTools(input, 100)
 			for _, st := range selected {
@@ -154,11 +291,11 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 			}
 
 			req := &llm.ChatRequest{
-				Model:        a.Model,
+				Model:        model,
 				Messages:     a.convertMessages(session.Messages),
 				Tools:        reqTools,
 				SystemPrompt: systemPromptPrefix,
-				MaxTokens:    a.MaxTokens,
+				MaxTokens:    maxTokens,
 				Temperature:  a.Temperature,
 			}
 			// Log Request
@@ -174,10 +311,11 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 
 			var fullResponse string
 			var toolCalls []llm.ToolCall
+			var reasoning []json.RawMessage
 
 			if a.Stream {
 				// Stream response from LLM
-				stream, err := a.Provider.ChatStream(ctx, req)
+				stream, err := provider.ChatStream(ctx, req)
 				if err != nil {
 					events <- StreamEvent{Type: "error", Error: err.Error()}
 					return
@@ -194,6 +332,11 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 						return
 					}
 
+					if chunk.Usage != nil {
+						addUsage(&usage, *chunk.Usage)
+					}
+					reasoning = append(reasoning, chunk.Reasoning...)
+
 					if chunk.Content != "" {
 						fullResponse += chunk.Content
 						// Ensure clean line endings for staircase effect prevention
@@ -255,7 +398,7 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 				}
 			} else {
 				// Non-Streaming Implementation
-				resp, err := a.Provider.Chat(ctx, req)
+				resp, err := provider.Chat(ctx, req)
 				if err != nil {
 					events <- StreamEvent{Type: "error", Error: err.Error()}
 					return
@@ -263,6 +406,8 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 
 				fullResponse = resp.Content
 				toolCalls = resp.ToolCalls
+				reasoning = resp.Reasoning
+				addUsage(&usage, resp.Usage)
 
 				// Emit full text event
 				if fullResponse != "" {
@@ -296,6 +441,7 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 					Role:      "assistant",
 					Content:   historyContent,
 					ToolCalls: toolCalls,
+					Reasoning: reasoning,
 				}
 				session.Messages = append(session.Messages, assistantMsg)
 			} else {
@@ -309,7 +455,13 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 
 			// Execute Tools
 			for _, tc := range toolCalls {
+				if !slices.Contains(toolsUsed, tc.Name) {
+					toolsUsed = append(toolsUsed, tc.Name)
+				}
 				result, err := a.executeTool(ctx, tc)
+				if err == nil {
+					filesChanged = append(filesChanged, tools.ChangedFiles(tc.Name, result)...)
+				}
 
 				// Notify UI of result
 				toolResult := ToolCallResult{ID: tc.ID, Result: result}
@@ -318,37 +470,149 @@ func (a *Agent) Run(ctx context.Context, sessionID string, input string) (<-chan
 				}
 				events <- StreamEvent{Type: "tool_result", ToolResult: &toolResult}
 
-				// Convert result to string for LLM
-				var resultStr string
-				if err != nil {
-					resultStr = fmt.Sprintf("Error: %s", err.Error())
-				} else {
-					// Is result a string or object?
-					if s, ok := result.(string); ok {
-						resultStr = s
-					} else if resultJSON, errJSON := json.Marshal(result); errJSON == nil {
-						resultStr = string(resultJSON)
-					} else {
-						resultStr = fmt.Sprintf("%v", result)
-					}
-				}
-
 				// Append Tool Message to History
 				session.Messages = append(session.Messages, Message{
 					Role:       "tool",
-					Content:    resultStr,
+					Content:    toolResultString(result, err),
 					ToolCallID: tc.ID,
 				})
 			}
 			// Loop continues to next turn -> sending history with tool results back to LLM
 		}
 
-		events <- StreamEvent{Type: "done"}
+		events <- StreamEvent{Type: "done", Usage: &usage}
 	}()
 
 	return events, nil
 }
 
+// addUsage adds u to total.
+func addUsage(total *llm.Usage, u llm.Usage) {
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.TotalTokens += u.TotalTokens
+	total.ReasoningTokens += u.ReasoningTokens
+}
+
+// resolveModel picks the provider and model for a run.
+// Precedence: run override, session override, configured default, then the
+// agent's static Provider/Model.
+func (a *Agent) resolveModel(session *Session, override string) (llm.Provider, string, int, error) {
+	ref := override
+	if ref == "" {
+		a.mu.RLock()
+		ref = session.Model
+		a.mu.RUnlock()
+	}
+
+	if a.Models != nil {
+		resolved, err := a.Models.Resolve(ref)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("model %q: %w", ref, err)
+		}
+		return resolved.Client, resolved.Model, resolved.MaxTokens, nil
+	}
+
+	if ref != "" {
+		return nil, "", 0, fmt.Errorf("model override %q requires a model registry", ref)
+	}
+	if a.Provider == nil {
+		return nil, "", 0, fmt.Errorf("no LLM provider configured")
+	}
+	return a.Provider, a.Model, a.MaxTokens, nil
+}
+
+// SetSessionModel sets (or clears, with an empty ref) the model override for
+// a session. It returns the canonical "provider/model" ref stored in place
+// of an alias.
+func (a *Agent) SetSessionModel(sessionID, ref string) (string, error) {
+	if ref != "" {
+		if a.Models == nil {
+			return "", fmt.Errorf("model overrides require a model registry")
+		}
+		resolved, err := a.Models.Resolve(ref)
+		if err != nil {
+			return "", err
+		}
+		ref = resolved.Ref
+	}
+
+	session := a.getOrCreateSession(sessionID)
+	a.mu.Lock()
+	session.Model = ref
+	a.mu.Unlock()
+	return ref, nil
+}
+
+// SessionModel returns the effective model ref for a session.
+func (a *Agent) SessionModel(sessionID string) string {
+	a.mu.RLock()
+	session, ok := a.sessions[sessionID]
+	override := ""
+	if ok {
+		override = session.Model
+	}
+	a.mu.RUnlock()
+
+	if override != "" {
+		return override
+	}
+	if a.Models != nil {
+		return a.Models.DefaultRef()
+	}
+	if a.Provider != nil {
+		return a.Provider.Name() + "/" + a.Model
+	}
+	return a.Model
+}
+
+// SetSessionAuthProfile pins (or clears, with an empty ID) the auth profile for a session.
+func (a *Agent) SetSessionAuthProfile(sessionID, profileID string) error {
+	if profileID != "" {
+		if a.Models == nil {
+			return fmt.Errorf("auth profiles require a model registry")
+		}
+		if _, ok := a.Models.Config().Auth.Profiles[profileID]; !ok {
+			return fmt.Errorf("auth profile '%s' is not defined in 'auth.profiles'", profileID)
+		}
+	}
+
+	session := a.getOrCreateSession(sessionID)
+	a.mu.Lock()
+	session.AuthProfile = profileID
+	a.mu.Unlock()
+	return nil
+}
+
+// SessionAuthProfile returns the auth profile pinned for a session, if any.
+func (a *Agent) SessionAuthProfile(sessionID string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if session, ok := a.sessions[sessionID]; ok {
+		return session.AuthProfile
+	}
+	return ""
+}
+
+// SessionUsage returns the tokens a session's model calls have used.
+func (a *Agent) SessionUsage(sessionID string) llm.Usage {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if session, ok := a.sessions[sessionID]; ok {
+		return session.Usage
+	}
+	return llm.Usage{}
+}
+
+// EnqueueSystemEvent queues a notice for a session. It is prepended to the
+// next message the session receives.
+func (a *Agent) EnqueueSystemEvent(sessionID, text string) {
+	session := a.getOrCreateSession(sessionID)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	session.systemEvents = append(session.systemEvents, text)
+}
+
 // HasSession checks if the session exists in memory and is populated.
 func (a *Agent) HasSession(id string) bool {
 	a.mu.RLock()
@@ -357,6 +621,32 @@ func (a *Agent) HasSession(id string) bool {
 	return ok && len(s.Messages) > 0
 }
 
+// toolSession describes sessionID to the tools it calls. hint is the chat
+// type reported by the channel, if any.
+func (a *Agent) toolSession(sessionID, hint string) tools.SessionContext {
+	sc := tools.SessionContext{
+		Key:     sessionID,
+		Type:    sandbox.ClassifySession(sessionID, hint),
+		AgentID: a.sessionAgent(sessionID),
+	}
+	if a.PathGuard != nil {
+		sc.Paths = a.PathGuard(sc)
+	}
+	return sc
+}
+
+// sessionAgent returns the agent a session runs as. Sub-agent keys carry the
+// agentId they were spawned with ("subagent:<agentId>:<run>"); every other
+// session belongs to a.
+func (a *Agent) sessionAgent(sessionID string) string {
+	if rest, ok := strings.CutPrefix(sessionID, "subagent:"); ok {
+		if id, _, ok := strings.Cut(rest, ":"); ok && id != "" {
+			return id
+		}
+	}
+	return a.ID
+}
+
 func (a *Agent) getOrCreateSession(id string) *Session {
 	a.mu.Lock()
 	defer a.mu.Unlock()
@@ -406,6 +696,7 @@ func (a *Agent) convertMessages(msgs []Message) []llm.Message {
 			Content:    m.Content,
 			ToolCalls:  m.ToolCalls,
 			ToolCallID: m.ToolCallID,
+			Reasoning:  m.Reasoning,
 		}
 	}
 	return result
@@ -427,6 +718,11 @@ func (a *Agent) convertTools() []llm.ToolDef {
 }
 
 func (a *Agent) executeTool(ctx context.Context, tc llm.ToolCall) (interface{}, error) {
+	// Tools hidden by the policy cannot be called by name either.
+	if !a.Policy.Compile()(tc.Name) {
+		return nil, fmt.Errorf("tool %s is not allowed by the tool policy", tc.Name)
+	}
+
 	// 1. Check registered tools
 	for _, t := range a.Tools {
 		if t.Name() == tc.Name {
@@ -451,6 +747,21 @@ func (a *Agent) executeTool(ctx context.Context, tc llm.ToolCall) (interface{},
 	return nil, nil
 }
 
+// toolResultString converts a tool result to the text sent to the LLM.
+func toolResultString(result interface{}, err error) string {
+	if err != nil {
+		return fmt.Sprintf("Error: %s", err.Error())
+	}
+	// Is result a string or object?
+	if s, ok := result.(string); ok {
+		return s
+	}
+	if resultJSON, errJSON := json.Marshal(result); errJSON == nil {
+		return string(resultJSON)
+	}
+	return fmt.Sprintf("%v", result)
+}
+
 // StreamEvent represents a streaming event from the agent.
 type StreamEvent struct {
 	Type       string          `json:"type"` // "text", "tool_call", "tool_result", "error", "done"
@@ -458,6 +769,8 @@ type StreamEvent struct {
 	ToolCall   *llm.ToolCall   `json:"toolCall,omitempty"`
 	ToolResult *ToolCallResult `json:"toolResult,omitempty"`
 	Error      string          `json:"error,omitempty"`
+	// Usage is set on the done event to the tokens the run used.
+	Usage *llm.Usage `json:"usage,omitempty"`
 }
 
 // ToolCallResult represents the result of a tool execution.
@@ -2,9 +2,15 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -66,7 +72,7 @@ func TestAgent_Run_Simple(t *testing.T) {
 	ch <- llm.StreamChunk{Done: true}
 	close(ch)
 
-	p.On("ChatStream", ctx, mock.Anything).Return((<-chan llm.StreamChunk)(ch), nil)
+	p.On("ChatStream", mock.Anything, mock.Anything).Return((<-chan llm.StreamChunk)(ch), nil)
 
 	events, err := a.Run(ctx, sessionID, message)
 	require.NoError(t, err)
@@ -100,21 +106,25 @@ func TestAgent_ToolExecution(t *testing.T) {
 
 	tm := new(toolMock)
 	a.RegisterTools(tm)
+	var runs []RunInfo
+	a.AfterRun = func(run RunInfo) { runs = append(runs, run) }
 
 	ctx := context.Background()
 	sessionID := "session-tool"
 	message := "Call the tool"
 
 	// 1. LLM requests tool call
-	ch1 := make(chan llm.StreamChunk, 2)
+	reasoning := json.RawMessage(`{"type":"reasoning","id":"rs_1","encrypted_content":"abc"}`)
+	ch1 := make(chan llm.StreamChunk, 3)
+	ch1 <- llm.StreamChunk{Reasoning: []json.RawMessage{reasoning}}
 	ch1 <- llm.StreamChunk{
 		ToolCalls: []llm.ToolCall{
 			{ID: "call-1", Name: "test_tool", RawArguments: `{"arg": "val"}`},
 		},
 	}
-	ch1 <- llm.StreamChunk{Done: true}
+	ch1 <- llm.StreamChunk{Done: true, Usage: &llm.Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, ReasoningTokens: 2}}
 	close(ch1)
-	p.On("ChatStream", ctx, mock.Anything).Return((<-chan llm.StreamChunk)(ch1), nil).Once()
+	p.On("ChatStream", mock.Anything, mock.Anything).Return((<-chan llm.StreamChunk)(ch1), nil).Once()
 
 	// 2. Tool execution mocked
 	tm.On("Execute", mock.Anything, map[string]interface{}{"arg": "val"}).Return("Tool Result", nil)
@@ -122,22 +132,27 @@ func TestAgent_ToolExecution(t *testing.T) {
 	// 3. LLM receives tool result and responds
 	// We use .Run to avoid signature confusion and .Return for values
 	ch2 := make(chan llm.StreamChunk, 2)
-	p.On("ChatStream", ctx, mock.Anything).Run(func(args mock.Arguments) {
+	p.On("ChatStream", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 		req := args.Get(1).(*llm.ChatRequest)
-		foundResult := false
+		foundResult, foundReasoning := false, false
 		for _, msg := range req.Messages {
 			if msg.Role == "tool" && msg.Content == "Tool Result" {
 				foundResult = true
-				break
+			}
+			if msg.Role == "assistant" && len(msg.Reasoning) == 1 && string(msg.Reasoning[0]) == string(reasoning) {
+				foundReasoning = true
 			}
 		}
 
-		if foundResult {
-			ch2 <- llm.StreamChunk{Content: "Finished!"}
-		} else {
+		switch {
+		case !foundResult:
 			ch2 <- llm.StreamChunk{Content: "Tool result missing!"}
+		case !foundReasoning:
+			ch2 <- llm.StreamChunk{Content: "Reasoning missing!"}
+		default:
+			ch2 <- llm.StreamChunk{Content: "Finished!"}
 		}
-		ch2 <- llm.StreamChunk{Done: true}
+		ch2 <- llm.StreamChunk{Done: true, Usage: &llm.Usage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}}
 		close(ch2)
 	}).Return((<-chan llm.StreamChunk)(ch2), nil).Once()
 
@@ -145,11 +160,155 @@ func TestAgent_ToolExecution(t *testing.T) {
 	require.NoError(t, err)
 
 	var fullResponse string
+	var doneUsage *llm.Usage
 	for evt := range events {
-		if evt.Type == "text" {
+		switch evt.Type {
+		case "text":
 			fullResponse += evt.Content
+		case "done":
+			doneUsage = evt.Usage
 		}
 	}
 
 	assert.Equal(t, "Finished!", fullResponse)
+	require.Len(t, runs, 1)
+	assert.Equal(t, sessionID, runs[0].SessionID)
+	assert.NotEmpty(t, runs[0].RunID)
+	assert.Equal(t, []string{"test_tool"}, runs[0].Tools)
+
+	// Streamed usage is summed over the run's model calls.
+	want := llm.Usage{PromptTokens: 30, CompletionTokens: 8, TotalTokens: 38, ReasoningTokens: 2}
+	assert.Equal(t, want, runs[0].Usage)
+	require.NotNil(t, doneUsage)
+	assert.Equal(t, want, *doneUsage)
+	assert.Equal(t, want, a.SessionUsage(sessionID))
+}
+
+func TestAgent_SystemEventsPrependedToNextMessage(t *testing.T) {
+	p := new(MockProvider)
+	a := New("test-agent", "LiteClaw", "test-model", p)
+	a.Stream = true
+
+	ch := make(chan llm.StreamChunk, 1)
+	ch <- llm.StreamChunk{Done: true}
+	close(ch)
+	p.On("ChatStream", mock.Anything, mock.Anything).Return((<-chan llm.StreamChunk)(ch), nil)
+
+	a.EnqueueSystemEvent("session-1", "Background process proc_1 (make) exited with code 0.")
+	events, err := a.Run(context.Background(), "session-1", "Hello")
+	require.NoError(t, err)
+	for range events {
+	}
+
+	req := p.Calls[0].Arguments.Get(1).(*llm.ChatRequest)
+	assert.Equal(t, "System: Background process proc_1 (make) exited with code 0.\n\nHello", req.Messages[0].Content)
+}
+
+func TestAgent_FlushMemoryWritesWithMemoryTools(t *testing.T) {
+	dir := t.TempDir()
+	p := new(MockProvider)
+	a := New("test-agent", "LiteClaw", "test-model", p)
+	a.RegisterTools(tools.NewMemoryUpdateTool(dir), tools.NewExecTool())
+	a.LoadHistoryForSession("s1", []Message{
+		{Role: "user", Content: "I moved to Porto."},
+		{Role: "assistant", Content: "Noted."},
+	})
+
+	p.On("Chat", mock.Anything, mock.MatchedBy(func(req *llm.ChatRequest) bool {
+		return len(req.Messages) == 3
+	})).Return(&llm.ChatResponse{ToolCalls: []llm.ToolCall{{
+		ID:        "call-1",
+		Name:      "memory_update",
+		Arguments: map[string]interface{}{"section": "Profile", "content": "- Lives in Porto"},
+	}}}, nil).Once()
+	p.On("Chat", mock.Anything, mock.Anything).Return(&llm.ChatResponse{Content: "Saved home city."}, nil).Once()
+
+	summary, err := a.FlushMemory(context.Background(), "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "Saved home city.", summary)
+
+	// Only memory tools are offered, and the flush turn stays out of history.
+	req := p.Calls[0].Arguments.Get(1).(*llm.ChatRequest)
+	require.Len(t, req.Tools, 1)
+	assert.Equal(t, "memory_update", req.Tools[0].Name)
+	assert.Len(t, a.sessions["s1"].Messages, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, "MEMORY.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "## Profile\n\n- Lives in Porto")
+
+	// Nothing new since the last flush: no model call.
+	_, err = a.FlushMemory(context.Background(), "s1")
+	require.NoError(t, err)
+	p.AssertNumberOfCalls(t, "Chat", 2)
+}
+
+func TestAgent_CompactionFlushesAndSummarisesHistory(t *testing.T) {
+	p := new(MockProvider)
+	a := New("test-agent", "LiteClaw", "test-model", p)
+	a.RegisterTools(tools.NewMemoryAppendTool(t.TempDir()))
+	a.Compaction.MaxHistoryChars = 100
+	a.Compaction.MemoryFlush.Enabled = true
+
+	long := strings.Repeat("x", 40)
+	a.LoadHistoryForSession("s1", []Message{
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: long},
+		{Role: "user", Content: long},
+		{Role: "assistant", Content: "short"},
+	})
+
+	lastIs := func(prefix string) interface{} {
+		return mock.MatchedBy(func(req *llm.ChatRequest) bool {
+			return strings.HasPrefix(req.Messages[len(req.Messages)-1].Content, prefix)
+		})
+	}
+	p.On("Chat", mock.Anything, lastIs("Memory flush:")).Return(&llm.ChatResponse{Content: "nothing to save"}, nil).Once()
+	p.On("Chat", mock.Anything, lastIs("Compaction:")).Return(&llm.ChatResponse{Content: `{"summary": "The user sent xs.", "openTasks": ["Reply"]}`}, nil).Once()
+	p.On("Chat", mock.Anything, mock.Anything).Return(&llm.ChatResponse{Content: "ok"}, nil).Once()
+
+	// The summary is prepared off the run, then swapped in by the next one.
+	session := a.sessions["s1"]
+	require.NoError(t, a.prepareCompaction(context.Background(), session, a.Compaction.MaxHistoryChars))
+	assert.Len(t, session.Messages, 4)
+
+	a.Compaction.MaxHistoryChars = 1000 // no further compaction after this run
+	events, err := a.Run(context.Background(), "s1", "next")
+	require.NoError(t, err)
+	for range events {
+	}
+
+	p.AssertNumberOfCalls(t, "Chat", 3)
+	msgs := session.Messages
+	require.Len(t, msgs, 5)
+	assert.Equal(t, compactionSummaryPrefix+"The user sent xs.\n\nOpen tasks:\n- Reply", msgs[0].Content)
+	assert.Equal(t, long, msgs[1].Content)
+	assert.Equal(t, "short", msgs[2].Content)
+	assert.Equal(t, "next", msgs[3].Content)
+}
+
+func TestAgent_FlushIdleSessions(t *testing.T) {
+	p := new(MockProvider)
+	a := New("test-agent", "LiteClaw", "test-model", p)
+	a.RegisterTools(tools.NewMemoryAppendTool(t.TempDir()))
+	a.LoadHistoryForSession("idle", []Message{{Role: "user", Content: "hi"}})
+	a.LoadHistoryForSession("active", []Message{{Role: "user", Content: "hi"}})
+
+	now := time.Now()
+	a.sessions["idle"].LastActiveAt = now.Add(-time.Hour).UnixMilli()
+	a.sessions["active"].LastActiveAt = now.UnixMilli()
+
+	p.On("Chat", mock.Anything, mock.Anything).Return(&llm.ChatResponse{Content: "nothing to save"}, nil)
+
+	a.flushIdleSessions(context.Background(), 30*time.Minute, now)
+	p.AssertNumberOfCalls(t, "Chat", 1)
+	assert.Equal(t, 1, a.sessions["idle"].flushedLen)
+	assert.Equal(t, 0, a.sessions["active"].flushedLen)
+}
+
+func TestAgent_SessionAgent(t *testing.T) {
+	a := New("main", "LiteClaw", "test-model", new(MockProvider))
+	assert.Equal(t, "main", a.sessionAgent("telegram:42"))
+	assert.Equal(t, "research", a.sessionAgent("subagent:research:1a2b3c4d"))
+	assert.Equal(t, "main", a.sessionAgent("subagent:"))
 }
@@ -0,0 +1,466 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/config"
+)
+
+const (
+	// defaultAuthCooldown is the base cooldown after a 429 when auth.cooldownSeconds is unset.
+	defaultAuthCooldown = time.Minute
+	// maxAuthCooldown caps the exponential backoff for repeatedly rate-limited profiles.
+	maxAuthCooldown = time.Hour
+)
+
+// AuthProfileStats tracks usage and error state for one auth profile.
+// Timestamps are Unix milliseconds.
+type AuthProfileStats struct {
+	Provider         string `json:"provider"`
+	Requests         int64  `json:"requests"`
+	Errors           int64  `json:"errors"`
+	RateLimits       int64  `json:"rateLimits"`
+	PromptTokens     int64  `json:"promptTokens"`
+	CompletionTokens int64  `json:"completionTokens"`
+	LastUsedAt       int64  `json:"lastUsedAt,omitempty"`
+	LastError        string `json:"lastError,omitempty"`
+	LastErrorAt      int64  `json:"lastErrorAt,omitempty"`
+	CooldownUntil    int64  `json:"cooldownUntil,omitempty"`
+	// Strikes counts consecutive rate limits and drives the backoff.
+	Strikes int `json:"strikes,omitempty"`
+}
+
+// AuthStore records per-profile usage and cooldowns. When created with a
+// path, state is persisted so `liteclaw models status` can report it.
+type AuthStore struct {
+	mu    sync.Mutex
+	path  string
+	stats map[string]*AuthProfileStats
+
+	// BaseCooldown is the cooldown after the first 429; it doubles per strike.
+	BaseCooldown time.Duration
+	now          func() time.Time
+}
+
+// authCooldown returns the base cooldown configured by auth.cooldownSeconds.
+func authCooldown(cfg config.AuthConfig) time.Duration {
+	if cfg.CooldownSeconds > 0 {
+		return time.Duration(cfg.CooldownSeconds) * time.Second
+	}
+	return defaultAuthCooldown
+}
+
+// SetBaseCooldown changes the cooldown applied to later rate limits.
+func (s *AuthStore) SetBaseCooldown(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.BaseCooldown = d
+}
+
+// AuthStatePath returns the default location of the auth profile state file.
+func AuthStatePath() string {
+	return filepath.Join(config.StateDir(), "auth-state.json")
+}
+
+// NewAuthStore creates a store, loading existing state from path if set.
+// An empty path keeps state in memory only.
+func NewAuthStore(path string) *AuthStore {
+	s := &AuthStore{
+		path:         path,
+		stats:        make(map[string]*AuthProfileStats),
+		BaseCooldown: defaultAuthCooldown,
+		now:          time.Now,
+	}
+	if stats, err := LoadAuthState(path); err == nil {
+		for id, st := range stats {
+			st := st
+			s.stats[id] = &st
+		}
+	}
+	return s
+}
+
+// LoadAuthState reads persisted auth profile stats.
+func LoadAuthState(path string) (map[string]AuthProfileStats, error) {
+	stats := make(map[string]AuthProfileStats)
+	if path == "" {
+		return stats, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return stats, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Snapshot returns a copy of the stats for all profiles.
+func (s *AuthStore) Snapshot() map[string]AuthProfileStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make(map[string]AuthProfileStats, len(s.stats))
+	for id, st := range s.stats {
+		out[id] = *st
+	}
+	return out
+}
+
+// Available reports whether a profile is outside its cooldown window.
+func (s *AuthStore) Available(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st, ok := s.stats[id]
+	return !ok || st.CooldownUntil <= s.now().UnixMilli()
+}
+
+// CooldownUntil returns the end of a profile's cooldown in Unix milliseconds.
+func (s *AuthStore) CooldownUntil(id string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.stats[id]; ok {
+		return st.CooldownUntil
+	}
+	return 0
+}
+
+// RecordSuccess counts a successful request and clears any backoff.
+func (s *AuthStore) RecordSuccess(id, provider string, usage llm.Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.entryLocked(id, provider)
+	st.Requests++
+	st.PromptTokens += int64(usage.PromptTokens)
+	st.CompletionTokens += int64(usage.CompletionTokens)
+	st.LastUsedAt = s.now().UnixMilli()
+	st.Strikes = 0
+	st.CooldownUntil = 0
+	s.saveLocked()
+}
+
+// RecordError counts a failed request. Rate-limit errors put the profile
+// into cooldown, honouring Retry-After when it is longer than the backoff.
+func (s *AuthStore) RecordError(id, provider string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	st := s.entryLocked(id, provider)
+	st.Requests++
+	st.Errors++
+	st.LastUsedAt = now.UnixMilli()
+	st.LastError = err.Error()
+	st.LastErrorAt = now.UnixMilli()
+
+	if llm.IsRateLimited(err) {
+		st.RateLimits++
+		st.Strikes++
+		cooldown := s.BaseCooldown
+		for i := 1; i < st.Strikes && cooldown < maxAuthCooldown; i++ {
+			cooldown *= 2
+		}
+		if cooldown > maxAuthCooldown {
+			cooldown = maxAuthCooldown
+		}
+		if ra := llm.RetryAfter(err); ra > cooldown {
+			cooldown = ra
+		}
+		st.CooldownUntil = now.Add(cooldown).UnixMilli()
+	}
+	s.saveLocked()
+}
+
+func (s *AuthStore) entryLocked(id, provider string) *AuthProfileStats {
+	st, ok := s.stats[id]
+	if !ok {
+		st = &AuthProfileStats{}
+		s.stats[id] = st
+	}
+	st.Provider = provider
+	return st
+}
+
+// saveLocked persists the stats; failures are ignored since the state is advisory.
+func (s *AuthStore) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s.stats, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	_ = os.Rename(tmp, s.path)
+}
+
+type authProfileKey struct{}
+
+// WithAuthProfile pins the auth profile used for provider calls made with ctx.
+// The pin only applies to the provider the profile belongs to.
+func WithAuthProfile(ctx context.Context, profileID string) context.Context {
+	if profileID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, authProfileKey{}, profileID)
+}
+
+// AuthProfileFromContext returns the auth profile pinned on ctx, if any.
+func AuthProfileFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(authProfileKey{}).(string)
+	return id
+}
+
+// AuthCandidate is a credential that can be used for a provider.
+type AuthCandidate struct {
+	ID     string
+	Mode   string
+	Secret string
+}
+
+// ProviderAuthCandidates returns the usable credentials for a provider in
+// rotation order. Without configured profiles (or when none has a secret),
+// the PROVIDERNAME_API_KEY lookup is used as a single "<provider>:env" profile.
+func ProviderAuthCandidates(cfg *config.Config, providerName string) []AuthCandidate {
+	var out []AuthCandidate
+	for _, id := range cfg.ProviderAuthProfiles(providerName) {
+		p := cfg.Auth.Profiles[id]
+		if secret := AuthProfileSecret(cfg, p); secret != "" {
+			out = append(out, AuthCandidate{ID: id, Mode: normalizeAuthMode(p.Mode), Secret: secret})
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, AuthCandidate{
+			ID:     providerName + ":env",
+			Mode:   config.AuthModeEnv,
+			Secret: ProviderAPIKey(cfg, providerName),
+		})
+	}
+	return out
+}
+
+// AuthProfileSecret resolves the credential of an auth profile.
+func AuthProfileSecret(cfg *config.Config, p config.AuthProfile) string {
+	switch normalizeAuthMode(p.Mode) {
+	case config.AuthModeEnv:
+		name := p.Env
+		if name == "" {
+			name = strings.ToUpper(p.Provider) + "_API_KEY"
+		}
+		return lookupEnv(cfg, name)
+	case config.AuthModeOAuth:
+		return p.Token
+	default:
+		if p.APIKey != "" {
+			return p.APIKey
+		}
+		return p.Token
+	}
+}
+
+// normalizeAuthMode maps accepted spellings onto the config.AuthMode* constants.
+func normalizeAuthMode(mode string) string {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "oauth", "token":
+		return config.AuthModeOAuth
+	case "env":
+		return config.AuthModeEnv
+	default:
+		return config.AuthModeAPIKey
+	}
+}
+
+// rotatingProvider spreads requests for one provider across its auth
+// profiles, moving to the next profile when the current one is rate limited.
+type rotatingProvider struct {
+	name     string
+	profiles []AuthCandidate
+	// pinned is the agent-level profile from agents.defaults.authProfiles.
+	pinned string
+	store  *AuthStore
+	build  func(secret string) (llm.Provider, error)
+
+	mu      sync.Mutex
+	clients map[string]llm.Provider
+}
+
+func newRotatingProvider(name string, profiles []AuthCandidate, pinned string, store *AuthStore, build func(secret string) (llm.Provider, error)) (*rotatingProvider, error) {
+	p := &rotatingProvider{
+		name:     name,
+		profiles: profiles,
+		pinned:   pinned,
+		store:    store,
+		build:    build,
+		clients:  make(map[string]llm.Provider),
+	}
+	if pinned != "" && !p.hasProfile(pinned) {
+		return nil, fmt.Errorf("auth profile '%s' for provider '%s' has no usable credentials", pinned, name)
+	}
+	// Build the first client eagerly so configuration errors surface at resolve time.
+	if _, err := p.client(profiles[0]); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *rotatingProvider) Name() string {
+	return p.name
+}
+
+func (p *rotatingProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	var lastErr error
+	for _, c := range p.order(ctx) {
+		client, err := p.client(c)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := client.Chat(ctx, req)
+		if err == nil {
+			p.store.RecordSuccess(c.ID, p.name, resp.Usage)
+			return resp, nil
+		}
+		p.store.RecordError(c.ID, p.name, err)
+		lastErr = err
+		if !llm.IsRateLimited(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+func (p *rotatingProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	var lastErr error
+	for _, c := range p.order(ctx) {
+		client, err := p.client(c)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		stream, err := client.ChatStream(ctx, req)
+		if err == nil {
+			return p.recordStream(ctx, c.ID, stream), nil
+		}
+		p.store.RecordError(c.ID, p.name, err)
+		lastErr = err
+		if !llm.IsRateLimited(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// recordStream forwards stream and records the request once it ends, with
+// the usage reported on the final chunk.
+func (p *rotatingProvider) recordStream(ctx context.Context, id string, stream <-chan llm.StreamChunk) <-chan llm.StreamChunk {
+	out := make(chan llm.StreamChunk)
+	go func() {
+		defer close(out)
+		var usage llm.Usage
+		var streamErr error
+		for chunk := range stream {
+			if chunk.Usage != nil {
+				usage = *chunk.Usage
+			}
+			if chunk.Error != "" {
+				streamErr = fmt.Errorf("%s", chunk.Error)
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if streamErr != nil {
+			p.store.RecordError(id, p.name, streamErr)
+			return
+		}
+		p.store.RecordSuccess(id, p.name, usage)
+	}()
+	return out
+}
+
+func (p *rotatingProvider) Models(ctx context.Context) ([]string, error) {
+	client, err := p.client(p.order(ctx)[0])
+	if err != nil {
+		return nil, err
+	}
+	return client.Models(ctx)
+}
+
+// order returns the profiles to try for a call. A pinned profile (session
+// pin on ctx, then agent pin) is used alone; otherwise profiles outside
+// their cooldown come first, and if all are cooling down they are tried in
+// order of cooldown expiry.
+func (p *rotatingProvider) order(ctx context.Context) []AuthCandidate {
+	pin := AuthProfileFromContext(ctx)
+	if !p.hasProfile(pin) {
+		pin = p.pinned
+	}
+	if pin != "" {
+		for _, c := range p.profiles {
+			if c.ID == pin {
+				return []AuthCandidate{c}
+			}
+		}
+	}
+
+	var ready, cooling []AuthCandidate
+	for _, c := range p.profiles {
+		if p.store.Available(c.ID) {
+			ready = append(ready, c)
+		} else {
+			cooling = append(cooling, c)
+		}
+	}
+	if len(ready) > 0 {
+		return ready
+	}
+	sort.SliceStable(cooling, func(i, j int) bool {
+		return p.store.CooldownUntil(cooling[i].ID) < p.store.CooldownUntil(cooling[j].ID)
+	})
+	return cooling
+}
+
+func (p *rotatingProvider) hasProfile(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range p.profiles {
+		if c.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *rotatingProvider) client(c AuthCandidate) (llm.Provider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if client, ok := p.clients[c.ID]; ok {
+		return client, nil
+	}
+	client, err := p.build(c.Secret)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[c.ID] = client
+	return client, nil
+}
@@ -0,0 +1,226 @@
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keyedProvider answers with the API key it was built with, or fails with a
+// 429 when the key is listed in limited.
+type keyedProvider struct {
+	echoProvider
+	key     string
+	limited map[string]bool
+}
+
+func (p *keyedProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	if p.limited[p.key] {
+		return nil, &llm.APIError{Provider: p.name, StatusCode: 429, Status: "429 Too Many Requests"}
+	}
+	return &llm.ChatResponse{Content: p.key, Usage: llm.Usage{PromptTokens: 3, CompletionTokens: 2}}, nil
+}
+
+func (p *keyedProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	if p.limited[p.key] {
+		return nil, &llm.APIError{Provider: p.name, StatusCode: 429, Status: "429 Too Many Requests"}
+	}
+	ch := make(chan llm.StreamChunk, 2)
+	ch <- llm.StreamChunk{Content: p.key}
+	ch <- llm.StreamChunk{Done: true, Usage: &llm.Usage{PromptTokens: 3, CompletionTokens: 2}}
+	close(ch)
+	return ch, nil
+}
+
+func newAuthTestRegistry(cfg *config.Config, limited map[string]bool) *ModelRegistry {
+	r := NewModelRegistry(cfg)
+	r.Factory = func(name string, p config.ModelProvider, apiKey string, verbose bool) (llm.Provider, error) {
+		return &keyedProvider{echoProvider: echoProvider{name: name}, key: apiKey, limited: limited}, nil
+	}
+	return r
+}
+
+func authTestConfig() *config.Config {
+	cfg := testModelsConfig()
+	cfg.Auth = config.AuthConfig{
+		Profiles: map[string]config.AuthProfile{
+			"alpha:one": {Provider: "alpha", Mode: "api-key", APIKey: "key-one"},
+			"alpha:two": {Provider: "alpha", Mode: "oauth", Token: "key-two"},
+			"alpha:env": {Provider: "alpha", Mode: "env", Env: "ALPHA_TEAM_KEY"},
+		},
+		Order: map[string][]string{"alpha": {"alpha:one", "alpha:two"}},
+	}
+	cfg.Env = map[string]string{"ALPHA_TEAM_KEY": "key-three"}
+	return cfg
+}
+
+func TestProviderAuthCandidates(t *testing.T) {
+	cfg := authTestConfig()
+
+	var ids, secrets []string
+	for _, c := range ProviderAuthCandidates(cfg, "alpha") {
+		ids = append(ids, c.ID)
+		secrets = append(secrets, c.Secret)
+	}
+	assert.Equal(t, []string{"alpha:one", "alpha:two", "alpha:env"}, ids)
+	assert.Equal(t, []string{"key-one", "key-two", "key-three"}, secrets)
+
+	t.Setenv("BETA_API_KEY", "beta-key")
+	beta := ProviderAuthCandidates(cfg, "beta")
+	require.Len(t, beta, 1)
+	assert.Equal(t, "beta:env", beta[0].ID)
+	assert.Equal(t, "beta-key", beta[0].Secret)
+}
+
+func TestRotatingProvider_RotatesOn429(t *testing.T) {
+	limited := map[string]bool{"key-one": true}
+	r := newAuthTestRegistry(authTestConfig(), limited)
+	now := time.Unix(1000, 0)
+	r.Auth.now = func() time.Time { return now }
+
+	resolved, err := r.Resolve("alpha/a1")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	resp, err := resolved.Client.Chat(ctx, &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-two", resp.Content)
+
+	stats := r.Auth.Snapshot()
+	assert.Equal(t, int64(1), stats["alpha:one"].RateLimits)
+	assert.Equal(t, now.Add(defaultAuthCooldown).UnixMilli(), stats["alpha:one"].CooldownUntil)
+	assert.Equal(t, int64(3), stats["alpha:two"].PromptTokens)
+
+	// While cooling down, alpha:one is skipped without another request.
+	limited["key-one"] = false
+	resp, err = resolved.Client.Chat(ctx, &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-two", resp.Content)
+	assert.Equal(t, int64(1), r.Auth.Snapshot()["alpha:one"].Requests)
+
+	// After the cooldown it is first in line again.
+	now = now.Add(defaultAuthCooldown + time.Second)
+	resp, err = resolved.Client.Chat(ctx, &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-one", resp.Content)
+}
+
+func TestRotatingProvider_BackoffAndExhaustion(t *testing.T) {
+	limited := map[string]bool{"key-one": true, "key-two": true, "key-three": true}
+	r := newAuthTestRegistry(authTestConfig(), limited)
+	now := time.Unix(1000, 0)
+	r.Auth.now = func() time.Time { return now }
+
+	resolved, err := r.Resolve("alpha/a1")
+	require.NoError(t, err)
+
+	_, err = resolved.Client.Chat(context.Background(), &llm.ChatRequest{Model: "a1"})
+	require.Error(t, err)
+	assert.True(t, llm.IsRateLimited(err))
+
+	// All profiles are cooling down: they are still tried, soonest first,
+	// and a second strike doubles the cooldown.
+	_, err = resolved.Client.Chat(context.Background(), &llm.ChatRequest{Model: "a1"})
+	require.Error(t, err)
+	stats := r.Auth.Snapshot()
+	assert.Equal(t, 2, stats["alpha:one"].Strikes)
+	assert.Equal(t, now.Add(2*defaultAuthCooldown).UnixMilli(), stats["alpha:one"].CooldownUntil)
+}
+
+func TestRotatingProvider_ConfiguredCooldown(t *testing.T) {
+	cfg := authTestConfig()
+	cfg.Auth.CooldownSeconds = 5
+	r := newAuthTestRegistry(cfg, map[string]bool{"key-one": true})
+	now := time.Unix(1000, 0)
+	r.Auth.now = func() time.Time { return now }
+
+	resolved, err := r.Resolve("alpha/a1")
+	require.NoError(t, err)
+	_, err = resolved.Client.Chat(context.Background(), &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(5*time.Second).UnixMilli(), r.Auth.Snapshot()["alpha:one"].CooldownUntil)
+}
+
+func TestRotatingProvider_StreamRecordsUsage(t *testing.T) {
+	r := newAuthTestRegistry(authTestConfig(), nil)
+	resolved, err := r.Resolve("alpha/a1")
+	require.NoError(t, err)
+
+	stream, err := resolved.Client.ChatStream(context.Background(), &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	for range stream {
+	}
+
+	st := r.Auth.Snapshot()["alpha:one"]
+	assert.Equal(t, int64(1), st.Requests)
+	assert.Equal(t, int64(3), st.PromptTokens)
+	assert.Equal(t, int64(2), st.CompletionTokens)
+}
+
+func TestRotatingProvider_Pinning(t *testing.T) {
+	limited := map[string]bool{"key-two": true}
+	cfg := authTestConfig()
+	cfg.Agents.Defaults.AuthProfiles = map[string]string{"alpha": "alpha:env"}
+	r := newAuthTestRegistry(cfg, limited)
+
+	resolved, err := r.Resolve("alpha/a1")
+	require.NoError(t, err)
+
+	// Agent-level pin.
+	resp, err := resolved.Client.Chat(context.Background(), &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-three", resp.Content)
+
+	// Session pin overrides the agent pin and is not rotated away from on 429.
+	ctx := WithAuthProfile(context.Background(), "alpha:two")
+	_, err = resolved.Client.Chat(ctx, &llm.ChatRequest{Model: "a1"})
+	assert.True(t, llm.IsRateLimited(err))
+
+	// A pin for another provider's profile is ignored.
+	ctx = WithAuthProfile(context.Background(), "beta:main")
+	resp, err = resolved.Client.Chat(ctx, &llm.ChatRequest{Model: "a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-three", resp.Content)
+}
+
+func TestAgent_SessionAuthProfile(t *testing.T) {
+	a := New("main", "LiteClaw", "", nil)
+	a.Models = newAuthTestRegistry(authTestConfig(), map[string]bool{})
+	a.Stream = false
+
+	require.NoError(t, a.SetSessionAuthProfile("s1", "alpha:two"))
+	assert.Equal(t, "alpha:two", a.SessionAuthProfile("s1"))
+	assert.Error(t, a.SetSessionAuthProfile("s1", "missing"))
+
+	events, err := a.Run(context.Background(), "s1", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "key-two", collectText(t, events))
+
+	events, err = a.RunWithOptions(context.Background(), "s1", "hi", RunOptions{AuthProfile: "alpha:one"})
+	require.NoError(t, err)
+	assert.Equal(t, "key-one", collectText(t, events))
+}
+
+func TestAuthStore_Persists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth-state.json")
+	s := NewAuthStore(path)
+	s.RecordSuccess("alpha:one", "alpha", llm.Usage{PromptTokens: 10, CompletionTokens: 5})
+	s.RecordError("alpha:one", "alpha", &llm.APIError{Provider: "alpha", StatusCode: 500, Status: "500 Internal Server Error"})
+
+	stats, err := LoadAuthState(path)
+	require.NoError(t, err)
+	st := stats["alpha:one"]
+	assert.Equal(t, "alpha", st.Provider)
+	assert.Equal(t, int64(2), st.Requests)
+	assert.Equal(t, int64(1), st.Errors)
+	assert.Equal(t, int64(0), st.RateLimits)
+	assert.Equal(t, int64(10), st.PromptTokens)
+	assert.Contains(t, st.LastError, "500")
+	assert.True(t, NewAuthStore(path).Available("alpha:one"))
+}
@@ -0,0 +1,331 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+)
+
+const (
+	// defaultMaxHistoryChars is the history size that triggers compaction.
+	defaultMaxHistoryChars = 200000
+	// defaultIdleFlushMinutes is the inactivity that triggers a memory flush.
+	defaultIdleFlushMinutes = 30
+	// maxFlushTurns bounds the tool round-trips of a memory flush.
+	maxFlushTurns = 5
+)
+
+// defaultMemoryFlushPrompt asks the model to persist what is worth keeping.
+const defaultMemoryFlushPrompt = `Memory flush: this conversation is about to be compacted or has gone idle, and older messages will no longer be visible to you.
+Save any durable facts, decisions, preferences, people, dates and open todos from it that are not already in memory:
+- memory_update for long-lived facts in MEMORY.md sections
+- memory_append for events and notes in today's log
+Check memory_search first to avoid duplicates. Do not save secrets or transient details.
+Reply with a one-line summary of what you saved, or "nothing to save".`
+
+// memoryFlushTools are the only tools offered during a memory flush.
+var memoryFlushTools = map[string]bool{
+	"memory_search": true,
+	"memory_get":    true,
+	"memory_append": true,
+	"memory_update": true,
+}
+
+// FlushMemory runs a silent turn that asks the model to persist durable facts
+// from a session with the memory tools. The turn is not added to the session
+// history. Sessions without new messages since the last flush are skipped.
+func (a *Agent) FlushMemory(ctx context.Context, sessionID string) (string, error) {
+	a.mu.Lock()
+	session, ok := a.sessions[sessionID]
+	if !ok || session.flushing || len(session.Messages) <= session.flushedLen {
+		a.mu.Unlock()
+		return "", nil
+	}
+	session.flushing = true
+	history := append([]Message(nil), session.Messages...)
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		session.flushing = false
+		a.mu.Unlock()
+	}()
+
+	matcher := a.Policy.Compile()
+	var defs []llm.ToolDef
+	canWrite := false
+	a.mu.RLock()
+	for _, t := range a.Tools {
+		if memoryFlushTools[t.Name()] && matcher(t.Name()) {
+			defs = append(defs, llm.ToolDef{Name: t.Name(), Description: t.Description(), Parameters: t.Parameters()})
+			canWrite = canWrite || t.Name() == "memory_append" || t.Name() == "memory_update"
+		}
+	}
+	a.mu.RUnlock()
+	if !canWrite {
+		return "", nil
+	}
+
+	provider, model, maxTokens, err := a.resolveModel(session, "")
+	if err != nil {
+		return "", err
+	}
+	ctx = tools.WithSession(ctx, a.toolSession(sessionID, ""))
+
+	prompt := a.Compaction.MemoryFlush.Prompt
+	if prompt == "" {
+		prompt = defaultMemoryFlushPrompt
+	}
+	msgs := append(history, Message{Role: "user", Content: prompt})
+
+	summary := ""
+	for turn := 0; turn < maxFlushTurns; turn++ {
+		resp, err := provider.Chat(ctx, &llm.ChatRequest{
+			Model:        model,
+			Messages:     a.convertMessages(msgs),
+			Tools:        defs,
+			SystemPrompt: a.SystemPrompt,
+			MaxTokens:    maxTokens,
+			Temperature:  a.Temperature,
+		})
+		if err != nil {
+			return "", fmt.Errorf("memory flush: %w", err)
+		}
+		summary = strings.TrimSpace(resp.Content)
+		msgs = append(msgs, Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+		if len(resp.ToolCalls) == 0 {
+			break
+		}
+		for _, tc := range resp.ToolCalls {
+			var result interface{}
+			var err error
+			if memoryFlushTools[tc.Name] {
+				result, err = a.executeTool(ctx, tc)
+			} else {
+				err = fmt.Errorf("tool %s is not available during a memory flush", tc.Name)
+			}
+			msgs = append(msgs, Message{Role: "tool", Content: toolResultString(result, err), ToolCallID: tc.ID})
+		}
+	}
+
+	a.mu.Lock()
+	if session.flushedLen < len(history) {
+		session.flushedLen = len(history)
+	}
+	a.mu.Unlock()
+
+	if a.Verbose {
+		fmt.Printf("[memory flush %s] %s\n", sessionID, summary)
+	}
+	return summary, nil
+}
+
+// compactSession applies a compaction prepared in the background, replacing
+// the oldest messages with their summary. It runs at the start of a run, so
+// it only swaps slices and never calls the model.
+func (a *Agent) compactSession(session *Session) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := session.compaction
+	session.compaction = nil
+	if p == nil || p.cut > len(session.Messages) {
+		return
+	}
+	msgs := make([]Message, 0, len(session.Messages)-p.cut+1)
+	msgs = append(msgs, Message{Role: "user", Content: compactionSummaryPrefix + p.summary})
+	session.Messages = append(msgs, session.Messages[p.cut:]...)
+	session.flushedLen = max(session.flushedLen-p.cut+1, 0)
+}
+
+// compactionSummaryPrefix introduces the summary that replaces compacted
+// messages.
+const compactionSummaryPrefix = "[Summary of the earlier conversation, which was compacted]\n"
+
+// defaultCompactionPrompt asks the model to summarise the messages being
+// compacted.
+const defaultCompactionPrompt = `Compaction: the conversation above is about to be removed from your context. Summarise it so you can continue without it: the user's goals, decisions made, facts learned, files and commands involved, and the tasks still open.`
+
+// compactionSummary is the structured reply to defaultCompactionPrompt.
+type compactionSummary struct {
+	Summary   string   `json:"summary" description:"The user's goals, decisions made, facts learned, and files and commands involved"`
+	OpenTasks []string `json:"openTasks,omitempty" description:"Tasks still open, one per item"`
+}
+
+// Validate rejects an empty summary.
+func (c compactionSummary) Validate() error {
+	if strings.TrimSpace(c.Summary) == "" {
+		return fmt.Errorf("summary must not be empty")
+	}
+	return nil
+}
+
+// text renders the summary for the session history.
+func (c compactionSummary) text() string {
+	text := strings.TrimSpace(c.Summary)
+	if len(c.OpenTasks) > 0 {
+		text += "\n\nOpen tasks:"
+		for _, task := range c.OpenTasks {
+			text += "\n- " + task
+		}
+	}
+	return text
+}
+
+// pendingCompaction is a summary of a session's oldest messages, waiting for
+// the next run to swap it in.
+type pendingCompaction struct {
+	// cut is the number of messages the summary replaces.
+	cut     int
+	summary string
+}
+
+// maybeCompact prepares a compaction in the background once a session's
+// history exceeds the configured size, so the flush and summary turns never
+// delay a reply.
+func (a *Agent) maybeCompact(session *Session) {
+	if a.Compaction.Mode == "off" {
+		return
+	}
+	limit := a.Compaction.MaxHistoryChars
+	if limit <= 0 {
+		limit = defaultMaxHistoryChars
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if session.compacting || session.compaction != nil || historyChars(session.Messages) <= limit {
+		return
+	}
+	session.compacting = true
+	go func() {
+		defer func() {
+			a.mu.Lock()
+			session.compacting = false
+			a.mu.Unlock()
+		}()
+		if err := a.prepareCompaction(context.Background(), session, limit); err != nil && a.Verbose {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}()
+}
+
+// prepareCompaction flushes memory, so nothing durable is lost, then
+// summarises the oldest messages beyond half of limit for compactSession.
+func (a *Agent) prepareCompaction(ctx context.Context, session *Session, limit int) error {
+	if a.Compaction.MemoryFlush.Enabled {
+		if _, err := a.FlushMemory(ctx, session.ID); err != nil {
+			return err
+		}
+	}
+
+	a.mu.RLock()
+	history := append([]Message(nil), session.Messages...)
+	a.mu.RUnlock()
+	cut := compactionCut(history, limit/2)
+	if cut <= 0 {
+		return nil
+	}
+
+	provider, model, maxTokens, err := a.resolveModel(session, "")
+	if err != nil {
+		return err
+	}
+	msgs := append(history[:cut:cut], Message{Role: "user", Content: defaultCompactionPrompt})
+	summary, err := llm.ChatJSON[compactionSummary](ctx, provider, llm.ChatRequest{
+		Model:        model,
+		Messages:     a.convertMessages(msgs),
+		SystemPrompt: a.SystemPrompt,
+		MaxTokens:    maxTokens,
+		Temperature:  a.Temperature,
+	})
+	if err != nil {
+		return fmt.Errorf("compaction: %w", err)
+	}
+
+	a.mu.Lock()
+	session.compaction = &pendingCompaction{cut: cut, summary: summary.text()}
+	a.mu.Unlock()
+	return nil
+}
+
+// compactionCut returns how many of the oldest messages to drop so the
+// newest ones fit in budget characters. The kept messages start at a user
+// message so tool results never lose their call.
+func compactionCut(msgs []Message, budget int) int {
+	cut := -1
+	size := 0
+	for i := len(msgs) - 1; i >= 0; i-- {
+		size += messageChars(msgs[i])
+		if size > budget && cut >= 0 {
+			break
+		}
+		if msgs[i].Role == "user" {
+			cut = i
+		}
+	}
+	return max(cut, 0)
+}
+
+func historyChars(msgs []Message) int {
+	n := 0
+	for _, m := range msgs {
+		n += messageChars(m)
+	}
+	return n
+}
+
+func messageChars(m Message) int {
+	n := len(m.Content)
+	for _, tc := range m.ToolCalls {
+		n += len(tc.Name) + len(tc.RawArguments)
+	}
+	return n
+}
+
+// RunIdleMemoryFlush flushes memory for sessions that have been inactive for
+// the configured idle time, until ctx is cancelled.
+func (a *Agent) RunIdleMemoryFlush(ctx context.Context) {
+	idle := time.Duration(a.Compaction.MemoryFlush.IdleMinutes) * time.Minute
+	if a.Compaction.MemoryFlush.IdleMinutes == 0 {
+		idle = defaultIdleFlushMinutes * time.Minute
+	}
+	if !a.Compaction.MemoryFlush.Enabled || idle <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(max(idle/4, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.flushIdleSessions(ctx, idle, now)
+		}
+	}
+}
+
+// flushIdleSessions flushes every session idle for at least idle at now.
+func (a *Agent) flushIdleSessions(ctx context.Context, idle time.Duration, now time.Time) {
+	var ids []string
+	a.mu.RLock()
+	for id, s := range a.sessions {
+		if s.running || s.flushing || s.LastActiveAt == 0 || len(s.Messages) <= s.flushedLen {
+			continue
+		}
+		if now.Sub(time.UnixMilli(s.LastActiveAt)) >= idle {
+			ids = append(ids, id)
+		}
+	}
+	a.mu.RUnlock()
+
+	for _, id := range ids {
+		if _, err := a.FlushMemory(ctx, id); err != nil && a.Verbose {
+			fmt.Printf("Warning: %v\n", err)
+		}
+	}
+}
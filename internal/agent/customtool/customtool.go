@@ -0,0 +1,363 @@
+// Package customtool describes agent tools declared in config or skill
+// frontmatter instead of Go: a command or HTTP request template filled in
+// from the call's arguments.
+package customtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output formats.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Spec declares a custom tool. Exactly one of Command, Args or HTTP is set.
+//
+// Templates refer to arguments as {{name}}. Each value is escaped for where
+// it lands: shell-quoted in Command, passed verbatim as one argument in
+// Args, percent-encoded in HTTP URLs and JSON-encoded in HTTP bodies. In
+// Command, placeholders must stand outside quotes and backticks, since the
+// value's own quotes would end the enclosing ones.
+type Spec struct {
+	// Name is the tool name the model calls ([A-Za-z0-9_-], at most 64).
+	Name        string `json:"name" yaml:"name" mapstructure:"name"`
+	Description string `json:"description" yaml:"description" mapstructure:"description"`
+	// Parameters is the JSON Schema of the arguments (an object schema).
+	Parameters map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty" mapstructure:"parameters"`
+	// Command is a shell command template run with bash -c.
+	Command string `json:"command,omitempty" yaml:"command,omitempty" mapstructure:"command"`
+	// Args is an argv template run without a shell. An element that is a
+	// single placeholder for an omitted argument is dropped.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty" mapstructure:"args"`
+	// HTTP is a request template.
+	HTTP *HTTPSpec `json:"http,omitempty" yaml:"http,omitempty" mapstructure:"http"`
+	// Workdir is the command's working directory (default: the workspace,
+	// or the skill's directory for skill tools).
+	Workdir string `json:"workdir,omitempty" yaml:"workdir,omitempty" mapstructure:"workdir"`
+	// Env adds environment variables; values may reference the gateway's
+	// environment as ${VAR}. Arguments are also exported as
+	// LITECLAW_ARG_<NAME>.
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env"`
+	// TimeoutSeconds limits a call (default: 60).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty" mapstructure:"timeoutSeconds"`
+	// Output is "text" (default) or "json", which parses stdout or the
+	// response body.
+	Output string `json:"output,omitempty" yaml:"output,omitempty" mapstructure:"output"`
+}
+
+// HTTPSpec is an HTTP request template.
+type HTTPSpec struct {
+	// Method defaults to GET.
+	Method string `json:"method,omitempty" yaml:"method,omitempty" mapstructure:"method"`
+	URL    string `json:"url" yaml:"url" mapstructure:"url"`
+	// Headers values may reference ${VAR} from the environment, e.g. for
+	// API keys, as well as arguments.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty" mapstructure:"headers"`
+	// Body is a template whose placeholders become JSON values, e.g.
+	// {"q": {{query}}}. When empty, POST, PUT and PATCH send the arguments
+	// as a JSON object.
+	Body string `json:"body,omitempty" yaml:"body,omitempty" mapstructure:"body"`
+}
+
+var (
+	namePattern   = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+	envRefRe      = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Validate checks the spec's shape and that its templates only refer to
+// declared parameters.
+func (s *Spec) Validate() error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("custom tool name %q must be 1-64 letters, digits, _ or -", s.Name)
+	}
+	kinds := 0
+	if s.Command != "" {
+		kinds++
+	}
+	if len(s.Args) > 0 {
+		kinds++
+	}
+	if s.HTTP != nil {
+		kinds++
+		if s.HTTP.URL == "" {
+			return fmt.Errorf("custom tool %s: http.url is required", s.Name)
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("custom tool %s: set exactly one of command, args or http", s.Name)
+	}
+	switch s.Output {
+	case "", OutputText, OutputJSON:
+	default:
+		return fmt.Errorf("custom tool %s: output must be text or json", s.Name)
+	}
+	if t, ok := s.Parameters["type"]; ok && t != "object" {
+		return fmt.Errorf("custom tool %s: parameters must be an object schema", s.Name)
+	}
+
+	if name, ok := quotedPlaceholder(s.Command); ok {
+		return fmt.Errorf("custom tool %s: {{%s}} is inside quotes or backticks in command; leave it bare, it is quoted when expanded", s.Name, name)
+	}
+	declared := s.properties()
+	for _, tmpl := range s.templates() {
+		for _, m := range placeholderRe.FindAllStringSubmatch(tmpl, -1) {
+			if _, ok := declared[m[1]]; !ok {
+				return fmt.Errorf("custom tool %s: template refers to undeclared parameter %q", s.Name, m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// quotedPlaceholder returns the first placeholder in a shell command that
+// sits inside single quotes, double quotes or backticks.
+func quotedPlaceholder(cmd string) (string, bool) {
+	locs := placeholderRe.FindAllStringSubmatchIndex(cmd, -1)
+	var quote byte
+	next := 0
+	for i := 0; i < len(cmd) && next < len(locs); i++ {
+		// A placeholder whose first brace was escaped is literal text.
+		for next < len(locs) && locs[next][0] < i {
+			next++
+		}
+		if next < len(locs) && locs[next][0] == i {
+			if quote != 0 {
+				return cmd[locs[next][2]:locs[next][3]], true
+			}
+			i = locs[next][1] - 1
+			next++
+			continue
+		}
+		switch c := cmd[i]; {
+		case c == '\\' && quote != '\'':
+			i++
+		case quote == 0 && (c == '\'' || c == '"' || c == '`'):
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+	}
+	return "", false
+}
+
+// templates lists every string the spec expands.
+func (s *Spec) templates() []string {
+	out := append([]string{s.Command}, s.Args...)
+	if s.HTTP != nil {
+		out = append(out, s.HTTP.URL, s.HTTP.Body)
+		for _, v := range s.HTTP.Headers {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (s *Spec) properties() map[string]interface{} {
+	props, _ := s.Parameters["properties"].(map[string]interface{})
+	return props
+}
+
+// Schema returns the parameters schema, defaulting to an empty object.
+func (s *Spec) Schema() map[string]interface{} {
+	if len(s.Parameters) == 0 {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	out := make(map[string]interface{}, len(s.Parameters)+1)
+	for k, v := range s.Parameters {
+		out[k] = v
+	}
+	if _, ok := out["type"]; !ok {
+		out["type"] = "object"
+	}
+	return out
+}
+
+// CheckArgs reports missing required arguments and values whose JSON type
+// differs from the declared one.
+func (s *Spec) CheckArgs(args map[string]interface{}) error {
+	var required []string
+	switch r := s.Parameters["required"].(type) {
+	case []string:
+		required = r
+	case []interface{}:
+		for _, v := range r {
+			if name, ok := v.(string); ok {
+				required = append(required, name)
+			}
+		}
+	}
+	for _, name := range required {
+		if args[name] == nil {
+			return fmt.Errorf("%s is required", name)
+		}
+	}
+	props := s.properties()
+	for _, name := range sortedKeys(args) {
+		prop, _ := props[name].(map[string]interface{})
+		want, _ := prop["type"].(string)
+		if want == "" || args[name] == nil {
+			continue
+		}
+		if !hasType(args[name], want) {
+			return fmt.Errorf("%s must be %s", name, want)
+		}
+	}
+	return nil
+}
+
+func hasType(v interface{}, want string) bool {
+	switch want {
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		n, ok := v.(float64)
+		return ok && n == float64(int64(n))
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+// Value formats an argument as text: strings as-is, whole numbers without
+// a decimal point and anything else as JSON. Omitted arguments are "".
+func Value(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		if val == float64(int64(val)) {
+			return strconv.FormatInt(int64(val), 10)
+		}
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// Expand replaces the placeholders in tmpl with escape(Value(arg)).
+func Expand(tmpl string, args map[string]interface{}, escape func(string) string) string {
+	return placeholderRe.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := placeholderRe.FindStringSubmatch(m)[1]
+		return escape(Value(args[name]))
+	})
+}
+
+// ShellCommand expands Command with shell-quoted arguments.
+func (s *Spec) ShellCommand(args map[string]interface{}) string {
+	return Expand(s.Command, args, ShellQuote)
+}
+
+// Argv expands Args, dropping elements that are a lone placeholder for an
+// omitted argument.
+func (s *Spec) Argv(args map[string]interface{}) []string {
+	var out []string
+	for _, a := range s.Args {
+		if m := placeholderRe.FindStringSubmatch(a); m != nil && m[0] == strings.TrimSpace(a) && args[m[1]] == nil {
+			continue
+		}
+		out = append(out, Expand(a, args, func(v string) string { return v }))
+	}
+	return out
+}
+
+// RequestURL expands the HTTP URL, path-escaping arguments before the
+// query and query-escaping them after it.
+func (s *Spec) RequestURL(args map[string]interface{}) (string, error) {
+	tmpl := s.HTTP.URL
+	path, query, hasQuery := strings.Cut(tmpl, "?")
+	out := Expand(path, args, url.PathEscape)
+	if hasQuery {
+		out += "?" + Expand(query, args, url.QueryEscape)
+	}
+	u, err := url.Parse(out)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("URL scheme must be http or https")
+	}
+	return out, nil
+}
+
+// RequestBody expands the HTTP body with JSON-encoded arguments. With no
+// body template it is the arguments as a JSON object for POST, PUT and
+// PATCH, and empty otherwise.
+func (s *Spec) RequestBody(args map[string]interface{}) (string, error) {
+	if s.HTTP.Body == "" {
+		switch s.Method() {
+		case "POST", "PUT", "PATCH":
+			data, err := json.Marshal(args)
+			return string(data), err
+		}
+		return "", nil
+	}
+	return placeholderRe.ReplaceAllStringFunc(s.HTTP.Body, func(m string) string {
+		data, _ := json.Marshal(args[placeholderRe.FindStringSubmatch(m)[1]])
+		return string(data)
+	}), nil
+}
+
+// Method is the HTTP method, GET by default.
+func (s *Spec) Method() string {
+	if s.HTTP == nil || s.HTTP.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(s.HTTP.Method)
+}
+
+// ExpandEnv replaces ${VAR} references with values from getenv. Bare $VAR
+// is left alone so shell snippets keep their meaning.
+func ExpandEnv(s string, getenv func(string) string) string {
+	return envRefRe.ReplaceAllStringFunc(s, func(m string) string {
+		return getenv(envRefRe.FindStringSubmatch(m)[1])
+	})
+}
+
+// ShellQuote quotes s as a single POSIX shell word.
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ArgEnv returns the arguments as LITECLAW_ARG_<NAME>=value entries.
+func ArgEnv(args map[string]interface{}) []string {
+	var env []string
+	for _, k := range sortedKeys(args) {
+		name := strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(k))
+		env = append(env, "LITECLAW_ARG_"+name+"="+Value(args[k]))
+	}
+	return env
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,106 @@
+package customtool
+
+import (
+	"strings"
+	"testing"
+)
+
+func searchSpec() Spec {
+	return Spec{
+		Name: "search_issues",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{"type": "string"},
+				"limit": map[string]interface{}{"type": "integer"},
+			},
+			"required": []interface{}{"query"},
+		},
+		Command: "gh search issues {{query}} --limit {{ limit }}",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	spec := searchSpec()
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cases := map[string]func(s *Spec){
+		"must be 1-64":         func(s *Spec) { s.Name = "bad name" },
+		"exactly one":          func(s *Spec) { s.Args = []string{"gh"} },
+		"undeclared parameter": func(s *Spec) { s.Command = "echo {{repo}}" },
+		"output must be":       func(s *Spec) { s.Output = "yaml" },
+		"http.url is required": func(s *Spec) { s.Command, s.HTTP = "", &HTTPSpec{} },
+		"must be an object":    func(s *Spec) { s.Parameters["type"] = "array" },
+		"inside quotes":        func(s *Spec) { s.Command = `gh search issues "{{query}}"` },
+		"inside quotes or":     func(s *Spec) { s.Command = `echo 'q: {{query}}'` },
+		"backticks":            func(s *Spec) { s.Command = "echo `printf %s {{query}}`" },
+	}
+	for want, mutate := range cases {
+		spec := searchSpec()
+		mutate(&spec)
+		if err := spec.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v", want, err)
+		}
+	}
+
+	// Quotes that close before the placeholder, or are escaped, are fine.
+	for _, cmd := range []string{`echo "q:" {{query}} 'done'`, `echo \" {{query}}`, `echo 'it''s' {{query}}`} {
+		spec := searchSpec()
+		spec.Command = cmd
+		if err := spec.Validate(); err != nil {
+			t.Errorf("%s: %v", cmd, err)
+		}
+	}
+}
+
+func TestExpandEscapesPerContext(t *testing.T) {
+	spec := searchSpec()
+	args := map[string]interface{}{"query": "it's $(rm -rf /)", "limit": float64(5)}
+	if got := spec.ShellCommand(args); got != `gh search issues 'it'\''s $(rm -rf /)' --limit '5'` {
+		t.Errorf("ShellCommand = %s", got)
+	}
+
+	spec.Command = ""
+	spec.Args = []string{"gh", "search", "{{query}}", "--limit={{limit}}", "{{limit}}"}
+	if got := strings.Join(spec.Argv(map[string]interface{}{"query": "a b"}), "|"); got != "gh|search|a b|--limit=" {
+		t.Errorf("Argv = %s", got)
+	}
+
+	spec.Args = nil
+	spec.HTTP = &HTTPSpec{URL: "https://api.example.com/search/{{query}}?q={{query}}&n={{limit}}", Method: "post", Body: `{"q": {{query}}, "n": {{limit}}}`}
+	u, err := spec.RequestURL(args)
+	if err != nil || u != "https://api.example.com/search/it%27s%20$%28rm%20-rf%20%2F%29?q=it%27s+%24%28rm+-rf+%2F%29&n=5" {
+		t.Errorf("RequestURL = %s, %v", u, err)
+	}
+	if body, _ := spec.RequestBody(args); body != `{"q": "it's $(rm -rf /)", "n": 5}` {
+		t.Errorf("RequestBody = %s", body)
+	}
+	spec.HTTP.Body = ""
+	if body, _ := spec.RequestBody(map[string]interface{}{"query": "x"}); body != `{"query":"x"}` {
+		t.Errorf("default body = %s", body)
+	}
+	spec.HTTP.URL = "file:///etc/passwd"
+	if _, err := spec.RequestURL(args); err == nil {
+		t.Error("file URL was accepted")
+	}
+}
+
+func TestCheckArgsAndEnv(t *testing.T) {
+	spec := searchSpec()
+	if err := spec.CheckArgs(map[string]interface{}{"limit": float64(1)}); err == nil || err.Error() != "query is required" {
+		t.Errorf("missing = %v", err)
+	}
+	if err := spec.CheckArgs(map[string]interface{}{"query": "x", "limit": 1.5}); err == nil || err.Error() != "limit must be integer" {
+		t.Errorf("wrong type = %v", err)
+	}
+	env := ArgEnv(map[string]interface{}{"query": "x", "max-n": float64(3)})
+	if strings.Join(env, " ") != "LITECLAW_ARG_MAX_N=3 LITECLAW_ARG_QUERY=x" {
+		t.Errorf("ArgEnv = %v", env)
+	}
+	getenv := func(k string) string { return map[string]string{"TOKEN": "s3cret"}[k] }
+	if got := ExpandEnv("Bearer ${TOKEN} $HOME", getenv); got != "Bearer s3cret $HOME" {
+		t.Errorf("ExpandEnv = %s", got)
+	}
+}
@@ -5,8 +5,8 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -37,12 +37,13 @@ func (p *AnthropicProvider) Name() string {
 
 // Anthropic API types
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	System    string             `json:"system,omitempty"`
-	Messages  []anthropicMessage `json:"messages"`
-	Tools     []anthropicTool    `json:"tools,omitempty"`
-	Stream    bool               `json:"stream,omitempty"`
+	Model      string             `json:"model"`
+	MaxTokens  int                `json:"max_tokens"`
+	System     string             `json:"system,omitempty"`
+	Messages   []anthropicMessage `json:"messages"`
+	Tools      []anthropicTool    `json:"tools,omitempty"`
+	ToolChoice interface{}        `json:"tool_choice,omitempty"`
+	Stream     bool               `json:"stream,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -102,7 +103,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRe
 
 	if resp.StatusCode != http.StatusOK {
 		errBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("anthropic API error: %s, body: %s", resp.Status, string(errBody))
+		return nil, newAPIError("anthropic", resp, errBody)
 	}
 
 	var anthropicResp anthropicResponse
@@ -110,7 +111,7 @@ func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRe
 		return nil, err
 	}
 
-	return p.parseResponse(&anthropicResp), nil
+	return p.parseResponse(&anthropicResp, responseFormatToolName(req.ResponseFormat)), nil
 }
 
 // ChatStream sends a streaming chat completion request.
@@ -138,15 +139,26 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest) (<
 	if resp.StatusCode != http.StatusOK {
 		errBody, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("anthropic API error: %s, body: %s", resp.Status, string(errBody))
+		return nil, newAPIError("anthropic", resp, errBody)
 	}
 
+	formatTool := responseFormatToolName(req.ResponseFormat)
 	chunks := make(chan StreamChunk, 100)
 
 	go func() {
 		defer close(chunks)
 		defer func() { _ = resp.Body.Close() }()
 
+		// message_start reports the input tokens and message_delta the
+		// running output count; the total goes out with the final chunk.
+		var usage *Usage
+		done := func() StreamChunk {
+			if usage != nil {
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			}
+			return StreamChunk{Done: true, Usage: usage}
+		}
+
 		reader := bufio.NewReader(resp.Body)
 		for {
 			line, err := reader.ReadBytes('\n')
@@ -171,7 +183,7 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest) (<
 			if strings.HasPrefix(lineStr, "data: ") {
 				data := strings.TrimPrefix(lineStr, "data: ")
 				if data == "[DONE]" {
-					chunks <- StreamChunk{Done: true}
+					chunks <- done()
 					return
 				}
 
@@ -182,14 +194,35 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest) (<
 
 				eventType, _ := event["type"].(string)
 				switch eventType {
+				case "message_start":
+					if msg, ok := event["message"].(map[string]interface{}); ok {
+						if u, ok := msg["usage"].(map[string]interface{}); ok {
+							usage = &Usage{
+								PromptTokens:     intField(u, "input_tokens"),
+								CompletionTokens: intField(u, "output_tokens"),
+							}
+						}
+					}
+				case "message_delta":
+					if u, ok := event["usage"].(map[string]interface{}); ok {
+						if usage == nil {
+							usage = &Usage{}
+						}
+						usage.CompletionTokens = intField(u, "output_tokens")
+					}
 				case "content_block_delta":
 					if delta, ok := event["delta"].(map[string]interface{}); ok {
 						if text, ok := delta["text"].(string); ok {
 							chunks <- StreamChunk{Content: text}
 						}
+						// With a response format the JSON document arrives as
+						// the forced tool's input; surface it as content.
+						if partial, ok := delta["partial_json"].(string); ok && formatTool != "" {
+							chunks <- StreamChunk{Content: partial}
+						}
 					}
 				case "message_stop":
-					chunks <- StreamChunk{Done: true}
+					chunks <- done()
 					return
 				case "error":
 					if e, ok := event["error"].(map[string]interface{}); ok {
@@ -205,6 +238,12 @@ func (p *AnthropicProvider) ChatStream(ctx context.Context, req *ChatRequest) (<
 	return chunks, nil
 }
 
+// intField reads a JSON number from a decoded event.
+func intField(m map[string]interface{}, key string) int {
+	n, _ := m[key].(float64)
+	return int(n)
+}
+
 // Models returns available models.
 func (p *AnthropicProvider) Models(ctx context.Context) ([]string, error) {
 	// Anthropic doesn't have a models endpoint, return known models
@@ -230,6 +269,23 @@ func (p *AnthropicProvider) buildRequest(req *ChatRequest) *anthropicRequest {
 			Role:    m.Role,
 			Content: m.Content,
 		}
+		if len(m.Images) > 0 {
+			var blocks []map[string]interface{}
+			for _, img := range m.Images {
+				blocks = append(blocks, map[string]interface{}{
+					"type": "image",
+					"source": map[string]interface{}{
+						"type":       "base64",
+						"media_type": img.MimeType,
+						"data":       base64.StdEncoding.EncodeToString(img.Data),
+					},
+				})
+			}
+			if m.Content != "" {
+				blocks = append(blocks, map[string]interface{}{"type": "text", "text": m.Content})
+			}
+			messages[i].Content = blocks
+		}
 	}
 
 	anthropicReq := &anthropicRequest{
@@ -252,10 +308,36 @@ func (p *AnthropicProvider) buildRequest(req *ChatRequest) *anthropicRequest {
 		})
 	}
 
+	// Structured output: force a tool whose input schema is the requested format.
+	if name := responseFormatToolName(req.ResponseFormat); name != "" {
+		schema := req.ResponseFormat.Schema
+		if req.ResponseFormat.Type != ResponseFormatJSONSchema || schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		anthropicReq.Tools = append(anthropicReq.Tools, anthropicTool{
+			Name:        name,
+			Description: "Respond by calling this tool with the answer as its input.",
+			InputSchema: schema,
+		})
+		anthropicReq.ToolChoice = map[string]interface{}{"type": "tool", "name": name}
+	}
+
 	return anthropicReq
 }
 
-func (p *AnthropicProvider) parseResponse(resp *anthropicResponse) *ChatResponse {
+// responseFormatToolName returns the forced tool name used for a response
+// format, or "" when the request is free text.
+func responseFormatToolName(rf *ResponseFormat) string {
+	if rf == nil || (rf.Type != ResponseFormatJSONSchema && rf.Type != ResponseFormatJSONObject) {
+		return ""
+	}
+	if rf.Name != "" {
+		return rf.Name
+	}
+	return defaultResponseFormatName
+}
+
+func (p *AnthropicProvider) parseResponse(resp *anthropicResponse, formatTool string) *ChatResponse {
 	result := &ChatResponse{
 		FinishReason: resp.StopReason,
 		Usage: Usage{
@@ -270,6 +352,12 @@ func (p *AnthropicProvider) parseResponse(resp *anthropicResponse) *ChatResponse
 		case "text":
 			result.Content += content.Text
 		case "tool_use":
+			if formatTool != "" && content.Name == formatTool {
+				data, _ := json.Marshal(content.Input)
+				result.Content += string(data)
+				result.FinishReason = "end_turn"
+				continue
+			}
 			args := make(map[string]interface{})
 			if input, ok := content.Input.(map[string]interface{}); ok {
 				args = input
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnthropic_ChatStreamUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":25,"output_tokens":1}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":15}}`,
+			`{"type":"message_stop"}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", e)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewAnthropicProvider("test-key", srv.URL)
+	stream, err := p.ChatStream(context.Background(), &ChatRequest{
+		Model:    "claude-test",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var text strings.Builder
+	var final StreamChunk
+	for chunk := range stream {
+		require.Empty(t, chunk.Error)
+		text.WriteString(chunk.Content)
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	assert.Equal(t, "Hello", text.String())
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, Usage{PromptTokens: 25, CompletionTokens: 15, TotalTokens: 40}, *final.Usage)
+}
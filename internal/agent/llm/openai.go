@@ -94,6 +94,8 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 		chatReq.Temperature = float32(req.Temperature)
 	}
 
+	chatReq.ResponseFormat = convertToOpenAIResponseFormat(req.ResponseFormat)
+
 	resp, err := p.client.CreateChatCompletion(ctx, chatReq)
 	if err != nil {
 		return nil, err
@@ -114,6 +116,9 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 			TotalTokens:      resp.Usage.TotalTokens,
 		},
 	}
+	if details := resp.Usage.CompletionTokensDetails; details != nil {
+		result.Usage.ReasoningTokens = details.ReasoningTokens
+	}
 
 	// Convert tool calls
 	for _, tc := range choice.Message.ToolCalls {
@@ -145,6 +150,8 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest) (<-ch
 		Model:    req.Model,
 		Messages: messages,
 		Stream:   true,
+		// Usage arrives in an extra chunk before the end of the stream.
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
 
 	if len(tools) > 0 {
@@ -160,6 +167,8 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest) (<-ch
 		chatReq.Temperature = float32(req.Temperature)
 	}
 
+	chatReq.ResponseFormat = convertToOpenAIResponseFormat(req.ResponseFormat)
+
 	stream, err := p.client.CreateChatCompletionStream(ctx, chatReq)
 	if err != nil {
 		return nil, err
@@ -171,10 +180,11 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest) (<-ch
 		defer close(chunks)
 		defer func() { _ = stream.Close() }()
 
+		var usage *Usage
 		for {
 			resp, err := stream.Recv()
 			if errors.Is(err, io.EOF) {
-				chunks <- StreamChunk{Done: true}
+				chunks <- StreamChunk{Done: true, Usage: usage}
 				return
 			}
 			if err != nil {
@@ -182,6 +192,17 @@ func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest) (<-ch
 				return
 			}
 
+			if u := resp.Usage; u != nil {
+				usage = &Usage{
+					PromptTokens:     u.PromptTokens,
+					CompletionTokens: u.CompletionTokens,
+					TotalTokens:      u.TotalTokens,
+				}
+				if details := u.CompletionTokensDetails; details != nil {
+					usage.ReasoningTokens = details.ReasoningTokens
+				}
+			}
+
 			if len(resp.Choices) > 0 {
 				delta := resp.Choices[0].Delta
 				chunk := StreamChunk{
@@ -239,6 +260,19 @@ func convertToOpenAIMessages(msgs []Message, systemPrompt string) []openai.ChatC
 		if m.ToolCallID != "" {
 			msg.ToolCallID = m.ToolCallID
 		}
+		if len(m.Images) > 0 {
+			// Content and MultiContent are mutually exclusive.
+			msg.Content = ""
+			if m.Content != "" {
+				msg.MultiContent = append(msg.MultiContent, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: m.Content})
+			}
+			for _, img := range m.Images {
+				msg.MultiContent = append(msg.MultiContent, openai.ChatMessagePart{
+					Type:     openai.ChatMessagePartTypeImageURL,
+					ImageURL: &openai.ChatMessageImageURL{URL: img.DataURL(), Detail: openai.ImageURLDetailAuto},
+				})
+			}
+		}
 
 		if len(m.ToolCalls) > 0 {
 			for _, tc := range m.ToolCalls {
@@ -286,6 +320,39 @@ func convertToOpenAITools(tools []ToolDef) []openai.Tool {
 	return result
 }
 
+// schemaMarshaler adapts an arbitrary JSON Schema value to json.Marshaler.
+type schemaMarshaler struct {
+	schema interface{}
+}
+
+func (s schemaMarshaler) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.schema)
+}
+
+func convertToOpenAIResponseFormat(rf *ResponseFormat) *openai.ChatCompletionResponseFormat {
+	if rf == nil {
+		return nil
+	}
+	switch rf.Type {
+	case ResponseFormatJSONSchema:
+		name := rf.Name
+		if name == "" {
+			name = defaultResponseFormatName
+		}
+		return &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+			JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+				Name:   name,
+				Schema: schemaMarshaler{schema: rf.Schema},
+			},
+		}
+	case ResponseFormatJSONObject:
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	default:
+		return nil
+	}
+}
+
 func parseArguments(args string) map[string]interface{} {
 	var result map[string]interface{}
 	if err := json.Unmarshal([]byte(args), &result); err != nil {
@@ -0,0 +1,472 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIResponsesProvider implements the Provider interface on top of the
+// OpenAI Responses API (POST /responses).
+type OpenAIResponsesProvider struct {
+	apiKey     string
+	baseURL    string
+	HTTPClient *http.Client
+	Verbose    bool
+	// ReasoningModels are the models whose reasoning items are requested
+	// encrypted and replayed with later turns. Responses are not stored, so
+	// the items must travel with the history.
+	ReasoningModels map[string]bool
+}
+
+// NewOpenAIResponsesProvider creates a Responses API provider.
+func NewOpenAIResponsesProvider(apiKey, baseURL string) *OpenAIResponsesProvider {
+	if baseURL == "" {
+		baseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAIResponsesProvider{
+		apiKey:     apiKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// Name returns the provider name.
+func (p *OpenAIResponsesProvider) Name() string {
+	return "openai-responses"
+}
+
+// Responses API types
+type responsesRequest struct {
+	Model           string               `json:"model"`
+	Instructions    string               `json:"instructions,omitempty"`
+	Input           []responsesInputItem `json:"input"`
+	Tools           []responsesTool      `json:"tools,omitempty"`
+	MaxOutputTokens int                  `json:"max_output_tokens,omitempty"`
+	Temperature     float64              `json:"temperature,omitempty"`
+	TopP            float64              `json:"top_p,omitempty"`
+	Text            *responsesText       `json:"text,omitempty"`
+	Include         []string             `json:"include,omitempty"`
+	Store           bool                 `json:"store"`
+	Stream          bool                 `json:"stream,omitempty"`
+}
+
+// responsesInputItem is a message, function_call or function_call_output item.
+type responsesInputItem struct {
+	Type string `json:"type"`
+	Role string `json:"role,omitempty"`
+	// Content is a string, or input_text/input_image parts for images.
+	Content   interface{} `json:"content,omitempty"`
+	CallID    string      `json:"call_id,omitempty"`
+	Name      string      `json:"name,omitempty"`
+	Arguments string      `json:"arguments,omitempty"`
+	Output    string      `json:"output,omitempty"`
+	// Raw, when set, is sent verbatim instead (replayed reasoning items).
+	Raw json.RawMessage `json:"-"`
+}
+
+// MarshalJSON sends Raw items verbatim.
+func (i responsesInputItem) MarshalJSON() ([]byte, error) {
+	if i.Raw != nil {
+		return i.Raw, nil
+	}
+	type plain responsesInputItem
+	return json.Marshal(plain(i))
+}
+
+type responsesTool struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters"`
+}
+
+type responsesText struct {
+	Format responsesTextFormat `json:"format"`
+}
+
+type responsesTextFormat struct {
+	Type   string      `json:"type"`
+	Name   string      `json:"name,omitempty"`
+	Schema interface{} `json:"schema,omitempty"`
+}
+
+type responsesResponse struct {
+	ID                string                `json:"id"`
+	Status            string                `json:"status"`
+	Output            []responsesOutputItem `json:"output"`
+	Usage             *responsesUsage       `json:"usage"`
+	Error             *responsesError       `json:"error"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+}
+
+type responsesOutputItem struct {
+	Type      string `json:"type"` // "message", "function_call", "reasoning", ...
+	ID        string `json:"id"`
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Content   []struct {
+		Type string `json:"type"` // "output_text" or "refusal"
+		Text string `json:"text"`
+	} `json:"content"`
+	// EncryptedContent is set on reasoning items when requested.
+	EncryptedContent string `json:"encrypted_content"`
+	// Raw is the item as received.
+	Raw json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON keeps the raw item so reasoning can be replayed as sent.
+func (i *responsesOutputItem) UnmarshalJSON(data []byte) error {
+	type plain responsesOutputItem
+	if err := json.Unmarshal(data, (*plain)(i)); err != nil {
+		return err
+	}
+	i.Raw = append(json.RawMessage(nil), data...)
+	return nil
+}
+
+// replayable reports whether the item is reasoning that can be sent back:
+// without stored responses only encrypted reasoning can be.
+func (i *responsesOutputItem) replayable() bool {
+	return i.Type == "reasoning" && i.EncryptedContent != ""
+}
+
+type responsesUsage struct {
+	InputTokens         int `json:"input_tokens"`
+	OutputTokens        int `json:"output_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	OutputTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"output_tokens_details"`
+}
+
+type responsesError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// responsesStreamEvent covers the fields of the stream events we consume.
+type responsesStreamEvent struct {
+	Type        string               `json:"type"`
+	Delta       string               `json:"delta"`
+	OutputIndex int                  `json:"output_index"`
+	Item        *responsesOutputItem `json:"item"`
+	Response    *responsesResponse   `json:"response"`
+	Message     string               `json:"message"`
+}
+
+// Chat sends a Responses API request and returns the full response.
+func (p *OpenAIResponsesProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var rr responsesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rr); err != nil {
+		return nil, err
+	}
+	if rr.Error != nil && rr.Error.Message != "" {
+		return nil, errors.New(rr.Error.Message)
+	}
+	return p.parseResponse(&rr), nil
+}
+
+// ChatStream sends a streaming Responses API request.
+func (p *OpenAIResponsesProvider) ChatStream(ctx context.Context, req *ChatRequest) (<-chan StreamChunk, error) {
+	resp, err := p.send(ctx, p.buildRequest(req, true))
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan StreamChunk, 100)
+
+	go func() {
+		defer close(chunks)
+		defer func() { _ = resp.Body.Close() }()
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				if err == io.EOF {
+					chunks <- StreamChunk{Done: true}
+				} else {
+					chunks <- StreamChunk{Error: err.Error()}
+				}
+				return
+			}
+
+			lineStr := string(bytes.TrimSpace(line))
+			if !strings.HasPrefix(lineStr, "data:") {
+				continue // blank lines and "event:" lines; the type is repeated in data
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(lineStr, "data:"))
+			if data == "[DONE]" {
+				chunks <- StreamChunk{Done: true}
+				return
+			}
+
+			var event responsesStreamEvent
+			if err := json.Unmarshal([]byte(data), &event); err != nil {
+				continue
+			}
+
+			switch event.Type {
+			case "response.output_text.delta", "response.refusal.delta":
+				if event.Delta != "" {
+					chunks <- StreamChunk{Content: event.Delta}
+				}
+			case "response.output_item.added":
+				if event.Item != nil && event.Item.Type == "function_call" {
+					chunks <- StreamChunk{ToolCalls: []ToolCall{{
+						ID:           event.Item.CallID,
+						Index:        event.OutputIndex,
+						Name:         event.Item.Name,
+						RawArguments: event.Item.Arguments,
+					}}}
+				}
+			case "response.output_item.done":
+				if event.Item != nil && event.Item.replayable() {
+					chunks <- StreamChunk{Reasoning: []json.RawMessage{event.Item.Raw}}
+				}
+			case "response.function_call_arguments.delta":
+				if event.Delta != "" {
+					chunks <- StreamChunk{ToolCalls: []ToolCall{{
+						Index:        event.OutputIndex,
+						RawArguments: event.Delta,
+					}}}
+				}
+			case "response.completed", "response.incomplete":
+				chunk := StreamChunk{Done: true}
+				if event.Response != nil && event.Response.Usage != nil {
+					usage := convertResponsesUsage(event.Response.Usage)
+					chunk.Usage = &usage
+				}
+				chunks <- chunk
+				return
+			case "response.failed":
+				msg := "response failed"
+				if event.Response != nil && event.Response.Error != nil && event.Response.Error.Message != "" {
+					msg = event.Response.Error.Message
+				}
+				chunks <- StreamChunk{Error: msg}
+				return
+			case "error":
+				chunks <- StreamChunk{Error: event.Message}
+				return
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// Models returns available models.
+func (p *OpenAIResponsesProvider) Models(ctx context.Context) ([]string, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError("openai", resp, errBody)
+	}
+
+	var list struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, err
+	}
+	var models []string
+	for _, m := range list.Data {
+		models = append(models, m.ID)
+	}
+	return models, nil
+}
+
+func (p *OpenAIResponsesProvider) send(ctx context.Context, rr *responsesRequest) (*http.Response, error) {
+	body, err := json.Marshal(rr)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Verbose && len(rr.Input) > 0 {
+		last := rr.Input[len(rr.Input)-1]
+		fmt.Printf("[LLM %s] Request last item: %s %s: %.100s...\n", rr.Model, last.Type, last.Role, fmt.Sprint(last.Content)+last.Output)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/responses", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	if rr.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := p.HTTPClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		return nil, newAPIError("openai", resp, errBody)
+	}
+	return resp, nil
+}
+
+// buildRequest maps the agent conversation to Responses input items:
+// assistant tool calls become function_call items and tool results become
+// function_call_output items linked by call_id. Reasoning items recorded
+// with an assistant message are replayed ahead of it.
+func (p *OpenAIResponsesProvider) buildRequest(req *ChatRequest, stream bool) *responsesRequest {
+	rr := &responsesRequest{
+		Model:           req.Model,
+		Instructions:    req.SystemPrompt,
+		MaxOutputTokens: req.MaxTokens,
+		Temperature:     req.Temperature,
+		TopP:            req.TopP,
+		Stream:          stream,
+	}
+	if p.ReasoningModels[req.Model] {
+		rr.Include = []string{"reasoning.encrypted_content"}
+		// Reasoning models reject sampling parameters.
+		rr.Temperature, rr.TopP = 0, 0
+	}
+
+	for _, m := range req.Messages {
+		if m.Role == "assistant" {
+			for _, item := range m.Reasoning {
+				rr.Input = append(rr.Input, responsesInputItem{Raw: item})
+			}
+		}
+		switch {
+		case m.Role == "tool":
+			rr.Input = append(rr.Input, responsesInputItem{
+				Type:   "function_call_output",
+				CallID: m.ToolCallID,
+				Output: m.Content,
+			})
+		case m.Role == "assistant" && len(m.ToolCalls) > 0:
+			if m.Content != "" {
+				rr.Input = append(rr.Input, responsesInputItem{Type: "message", Role: "assistant", Content: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				args := tc.RawArguments
+				if args == "" {
+					argsBytes, _ := json.Marshal(tc.Arguments)
+					args = string(argsBytes)
+				}
+				rr.Input = append(rr.Input, responsesInputItem{
+					Type:      "function_call",
+					CallID:    tc.ID,
+					Name:      tc.Name,
+					Arguments: args,
+				})
+			}
+		case len(m.Images) > 0:
+			var parts []map[string]interface{}
+			if m.Content != "" {
+				parts = append(parts, map[string]interface{}{"type": "input_text", "text": m.Content})
+			}
+			for _, img := range m.Images {
+				parts = append(parts, map[string]interface{}{"type": "input_image", "image_url": img.DataURL()})
+			}
+			rr.Input = append(rr.Input, responsesInputItem{Type: "message", Role: m.Role, Content: parts})
+		default:
+			rr.Input = append(rr.Input, responsesInputItem{Type: "message", Role: m.Role, Content: m.Content})
+		}
+	}
+
+	for _, t := range req.Tools {
+		rr.Tools = append(rr.Tools, responsesTool{
+			Type:        "function",
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters:  t.Parameters,
+		})
+	}
+
+	if rf := req.ResponseFormat; rf != nil {
+		switch rf.Type {
+		case ResponseFormatJSONSchema:
+			name := rf.Name
+			if name == "" {
+				name = defaultResponseFormatName
+			}
+			rr.Text = &responsesText{Format: responsesTextFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: rf.Schema}}
+		case ResponseFormatJSONObject:
+			rr.Text = &responsesText{Format: responsesTextFormat{Type: ResponseFormatJSONObject}}
+		}
+	}
+
+	return rr
+}
+
+func (p *OpenAIResponsesProvider) parseResponse(rr *responsesResponse) *ChatResponse {
+	result := &ChatResponse{FinishReason: "stop"}
+	if rr.IncompleteDetails != nil && rr.IncompleteDetails.Reason != "" {
+		result.FinishReason = rr.IncompleteDetails.Reason
+	}
+	if rr.Usage != nil {
+		result.Usage = convertResponsesUsage(rr.Usage)
+	}
+
+	for _, item := range rr.Output {
+		switch item.Type {
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" || c.Type == "refusal" {
+					result.Content += c.Text
+				}
+			}
+		case "reasoning":
+			if item.replayable() {
+				result.Reasoning = append(result.Reasoning, item.Raw)
+			}
+		case "function_call":
+			result.ToolCalls = append(result.ToolCalls, ToolCall{
+				ID:           item.CallID,
+				Name:         item.Name,
+				Arguments:    parseArguments(item.Arguments),
+				RawArguments: item.Arguments,
+			})
+		}
+	}
+	if len(result.ToolCalls) > 0 {
+		result.FinishReason = "tool_calls"
+	}
+	return result
+}
+
+func convertResponsesUsage(u *responsesUsage) Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+		ReasoningTokens:  u.OutputTokensDetails.ReasoningTokens,
+	}
+}
@@ -0,0 +1,241 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// responsesFixture serves canned Responses API replies and records requests.
+func responsesFixture(t *testing.T, requests *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/responses", r.URL.Path)
+		require.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*requests = append(*requests, body)
+
+		if stream, _ := body["stream"].(bool); !stream {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{
+				"id": "resp_1",
+				"status": "completed",
+				"output": [
+					{"type": "reasoning", "id": "rs_1", "summary": []},
+					{"type": "message", "id": "msg_1", "role": "assistant", "content": [{"type": "output_text", "text": "Checking the weather."}]},
+					{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "weather", "arguments": "{\"city\":\"Oslo\"}"}
+				],
+				"usage": {"input_tokens": 20, "output_tokens": 30, "total_tokens": 50, "output_tokens_details": {"reasoning_tokens": 12}}
+			}`))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"response.created","response":{"id":"resp_2","status":"in_progress"}}`,
+			`{"type":"response.output_item.added","output_index":0,"item":{"type":"reasoning","id":"rs_1"}}`,
+			`{"type":"response.output_item.added","output_index":1,"item":{"type":"message","id":"msg_1","role":"assistant","content":[]}}`,
+			`{"type":"response.output_text.delta","output_index":1,"item_id":"msg_1","delta":"Hel"}`,
+			`{"type":"response.output_text.delta","output_index":1,"item_id":"msg_1","delta":"lo"}`,
+			`{"type":"response.output_item.added","output_index":2,"item":{"type":"function_call","id":"fc_1","call_id":"call_9","name":"weather","arguments":""}}`,
+			`{"type":"response.function_call_arguments.delta","output_index":2,"item_id":"fc_1","delta":"{\"city\":"}`,
+			`{"type":"response.function_call_arguments.delta","output_index":2,"item_id":"fc_1","delta":"\"Oslo\"}"}`,
+			`{"type":"response.completed","response":{"id":"resp_2","status":"completed","usage":{"input_tokens":5,"output_tokens":7,"total_tokens":12,"output_tokens_details":{"reasoning_tokens":3}}}}`,
+		}
+		for _, e := range events {
+			var typ struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(e), &typ)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typ.Type, e)
+		}
+	}))
+}
+
+func TestOpenAIResponses_Chat(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := responsesFixture(t, &requests)
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("test-key", srv.URL+"/v1")
+	resp, err := p.Chat(context.Background(), &ChatRequest{
+		Model:        "o4-mini",
+		SystemPrompt: "You are helpful.",
+		Messages: []Message{
+			{Role: "user", Content: "Weather in Oslo?"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_0", Name: "weather", Arguments: map[string]interface{}{"city": "Bergen"}}}},
+			{Role: "tool", ToolCallID: "call_0", Content: "rain"},
+		},
+		Tools:     []ToolDef{{Name: "weather", Description: "Get weather", Parameters: map[string]interface{}{"type": "object"}}},
+		MaxTokens: 256,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Checking the weather.", resp.Content)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "call_1", resp.ToolCalls[0].ID)
+	assert.Equal(t, "Oslo", resp.ToolCalls[0].Arguments["city"])
+	assert.Equal(t, "tool_calls", resp.FinishReason)
+	assert.Equal(t, Usage{PromptTokens: 20, CompletionTokens: 30, TotalTokens: 50, ReasoningTokens: 12}, resp.Usage)
+
+	require.Len(t, requests, 1)
+	body := requests[0]
+	assert.Equal(t, "You are helpful.", body["instructions"])
+	assert.Equal(t, float64(256), body["max_output_tokens"])
+	assert.Equal(t, false, body["store"])
+
+	input := body["input"].([]interface{})
+	require.Len(t, input, 3)
+	assert.Equal(t, map[string]interface{}{"type": "message", "role": "user", "content": "Weather in Oslo?"}, input[0])
+	assert.Equal(t, map[string]interface{}{"type": "function_call", "call_id": "call_0", "name": "weather", "arguments": `{"city":"Bergen"}`}, input[1])
+	assert.Equal(t, map[string]interface{}{"type": "function_call_output", "call_id": "call_0", "output": "rain"}, input[2])
+
+	tools := body["tools"].([]interface{})
+	assert.Equal(t, "function", tools[0].(map[string]interface{})["type"])
+	assert.Equal(t, "weather", tools[0].(map[string]interface{})["name"])
+}
+
+func TestOpenAIResponses_ChatStream(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := responsesFixture(t, &requests)
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("test-key", srv.URL+"/v1")
+	stream, err := p.ChatStream(context.Background(), &ChatRequest{
+		Model:    "o4-mini",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var text strings.Builder
+	var calls []ToolCall
+	var final StreamChunk
+	for chunk := range stream {
+		require.Empty(t, chunk.Error)
+		text.WriteString(chunk.Content)
+		calls = append(calls, chunk.ToolCalls...)
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	assert.Equal(t, "Hello", text.String())
+	require.Len(t, calls, 3)
+	assert.Equal(t, ToolCall{ID: "call_9", Index: 2, Name: "weather"}, calls[0])
+	assert.Equal(t, `{"city":"Oslo"}`, calls[1].RawArguments+calls[2].RawArguments)
+	assert.Equal(t, 2, calls[2].Index)
+
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, 3, final.Usage.ReasoningTokens)
+	assert.Equal(t, 12, final.Usage.TotalTokens)
+	assert.Equal(t, true, requests[0]["stream"])
+}
+
+func TestOpenAIResponses_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"error":{"message":"slow down"}}`))
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("test-key", srv.URL)
+	_, err := p.Chat(context.Background(), &ChatRequest{Model: "o4-mini"})
+	require.Error(t, err)
+	assert.True(t, IsRateLimited(err))
+	assert.Equal(t, "7s", RetryAfter(err).String())
+}
+
+func TestMessageImages(t *testing.T) {
+	img := Image{MimeType: "image/png", Data: []byte("png")}
+	msgs := []Message{{Role: "user", Content: "What is this?", Images: []Image{img}}}
+	assert.Equal(t, "data:image/png;base64,cG5n", img.DataURL())
+
+	chat := convertToOpenAIMessages(msgs, "")
+	require.Len(t, chat, 1)
+	assert.Empty(t, chat[0].Content)
+	require.Len(t, chat[0].MultiContent, 2)
+	assert.Equal(t, "What is this?", chat[0].MultiContent[0].Text)
+	assert.Equal(t, img.DataURL(), chat[0].MultiContent[1].ImageURL.URL)
+
+	rr := NewOpenAIResponsesProvider("k", "").buildRequest(&ChatRequest{Messages: msgs}, false)
+	data, err := json.Marshal(rr.Input[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"message","role":"user","content":[{"type":"input_text","text":"What is this?"},{"type":"input_image","image_url":"data:image/png;base64,cG5n"}]}`, string(data))
+
+	ar := NewAnthropicProvider("k", "").buildRequest(&ChatRequest{Messages: msgs})
+	data, err = json.Marshal(ar.Messages[0])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role":"user","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"cG5n"}},{"type":"text","text":"What is this?"}]}`, string(data))
+}
+
+func TestOpenAIResponses_ReplaysReasoning(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		if stream, _ := body["stream"].(bool); !stream {
+			_, _ = w.Write([]byte(`{"status": "completed", "output": [
+				{"type": "reasoning", "id": "rs_1", "summary": [], "encrypted_content": "enc-1"},
+				{"type": "reasoning", "id": "rs_2", "summary": []},
+				{"type": "function_call", "call_id": "call_1", "name": "weather", "arguments": "{}"}
+			]}`))
+			return
+		}
+		for _, e := range []string{
+			`{"type":"response.output_item.done","output_index":0,"item":{"type":"reasoning","id":"rs_3","summary":[],"encrypted_content":"enc-3"}}`,
+			`{"type":"response.completed","response":{"status":"completed"}}`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", e)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIResponsesProvider("test-key", srv.URL)
+	p.ReasoningModels = map[string]bool{"o4-mini": true}
+	resp, err := p.Chat(context.Background(), &ChatRequest{Model: "o4-mini", Temperature: 0.7, TopP: 0.9, Messages: []Message{{Role: "user", Content: "Weather?"}}})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"reasoning.encrypted_content"}, requests[0]["include"])
+	assert.NotContains(t, requests[0], "temperature")
+	assert.NotContains(t, requests[0], "top_p")
+	// Only encrypted reasoning can be replayed without stored responses.
+	require.Len(t, resp.Reasoning, 1)
+	assert.Contains(t, string(resp.Reasoning[0]), `"enc-1"`)
+
+	stream, err := p.ChatStream(context.Background(), &ChatRequest{
+		Model: "o4-mini",
+		Messages: []Message{
+			{Role: "user", Content: "Weather?"},
+			{Role: "assistant", ToolCalls: resp.ToolCalls, Reasoning: resp.Reasoning},
+			{Role: "tool", ToolCallID: "call_1", Content: "sun"},
+		},
+	})
+	require.NoError(t, err)
+	var streamed []json.RawMessage
+	for chunk := range stream {
+		streamed = append(streamed, chunk.Reasoning...)
+	}
+	require.Len(t, streamed, 1)
+	assert.Contains(t, string(streamed[0]), `"enc-3"`)
+
+	input := requests[1]["input"].([]interface{})
+	require.Len(t, input, 4)
+	assert.Equal(t, map[string]interface{}{"type": "reasoning", "id": "rs_1", "summary": []interface{}{}, "encrypted_content": "enc-1"}, input[1])
+	assert.Equal(t, "function_call", input[2].(map[string]interface{})["type"])
+
+	// Models not marked as reasoning do not ask for encrypted reasoning.
+	_, err = p.Chat(context.Background(), &ChatRequest{Model: "gpt-4o", Temperature: 0.7, Messages: []Message{{Role: "user", Content: "hi"}}})
+	require.NoError(t, err)
+	assert.Nil(t, requests[2]["include"])
+	assert.Equal(t, 0.7, requests[2]["temperature"])
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAI_ChatStreamUsage(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range []string{
+			`{"choices":[{"index":0,"delta":{"content":"Hi"}}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":4,"total_tokens":13,"completion_tokens_details":{"reasoning_tokens":2}}}`,
+			`[DONE]`,
+		} {
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", e)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProviderWithConfig("test-key", srv.URL)
+	stream, err := p.ChatStream(context.Background(), &ChatRequest{
+		Model:    "gpt-test",
+		Messages: []Message{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, err)
+
+	var final StreamChunk
+	for chunk := range stream {
+		require.Empty(t, chunk.Error)
+		if chunk.Done {
+			final = chunk
+		}
+	}
+
+	assert.Equal(t, map[string]interface{}{"include_usage": true}, body["stream_options"])
+	require.NotNil(t, final.Usage)
+	assert.Equal(t, Usage{PromptTokens: 9, CompletionTokens: 4, TotalTokens: 13, ReasoningTokens: 2}, *final.Usage)
+}
@@ -3,6 +3,8 @@ package llm
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 )
 
 // Provider is the interface for LLM providers.
@@ -30,6 +32,23 @@ type ChatRequest struct {
 	Temperature  float64   `json:"temperature,omitempty"`
 	TopP         float64   `json:"topP,omitempty"`
 	Stop         []string  `json:"stop,omitempty"`
+	// ResponseFormat constrains the reply to JSON. Nil means free text.
+	ResponseFormat *ResponseFormat `json:"responseFormat,omitempty"`
+}
+
+// Response format types.
+const (
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat requests JSON output, optionally constrained by a JSON Schema.
+// OpenAI-compatible providers map it to response_format; Anthropic forces a
+// tool call whose input is the JSON document.
+type ResponseFormat struct {
+	Type   string      `json:"type"`             // "json_object" or "json_schema"
+	Name   string      `json:"name,omitempty"`   // Schema name (json_schema)
+	Schema interface{} `json:"schema,omitempty"` // JSON Schema (json_schema)
 }
 
 // ChatResponse represents a chat completion response.
@@ -38,6 +57,9 @@ type ChatResponse struct {
 	ToolCalls    []ToolCall `json:"toolCalls,omitempty"`
 	FinishReason string     `json:"finishReason"`
 	Usage        Usage      `json:"usage"`
+	// Reasoning holds opaque reasoning items to replay with the assistant
+	// message (see Message.Reasoning).
+	Reasoning []json.RawMessage `json:"reasoning,omitempty"`
 }
 
 // StreamChunk represents a streaming chunk.
@@ -46,6 +68,10 @@ type StreamChunk struct {
 	ToolCalls []ToolCall `json:"toolCalls,omitempty"`
 	Done      bool       `json:"done"`
 	Error     string     `json:"error,omitempty"`
+	// Usage is set on the final chunk by providers that report it.
+	Usage *Usage `json:"usage,omitempty"`
+	// Reasoning carries completed reasoning items (see Message.Reasoning).
+	Reasoning []json.RawMessage `json:"reasoning,omitempty"`
 }
 
 // Message represents a chat message.
@@ -54,6 +80,24 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCalls  []ToolCall `json:"toolCalls,omitempty"`
 	ToolCallID string     `json:"toolCallId,omitempty"`
+	// Images are sent alongside Content to vision-capable models.
+	Images []Image `json:"images,omitempty"`
+	// Reasoning holds the provider's opaque reasoning items for an
+	// assistant message. The Responses API replays them so reasoning models
+	// keep their chain of thought across tool calls; other providers ignore
+	// them.
+	Reasoning []json.RawMessage `json:"reasoning,omitempty"`
+}
+
+// Image is an inline image attached to a message.
+type Image struct {
+	MimeType string `json:"mimeType"`
+	Data     []byte `json:"data"`
+}
+
+// DataURL returns the image as a base64 data URL.
+func (i Image) DataURL() string {
+	return "data:" + i.MimeType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
 }
 
 // ToolDef represents a tool definition.
@@ -77,4 +121,6 @@ type Usage struct {
 	PromptTokens     int `json:"promptTokens"`
 	CompletionTokens int `json:"completionTokens"`
 	TotalTokens      int `json:"totalTokens"`
+	// ReasoningTokens is the part of CompletionTokens spent on hidden reasoning.
+	ReasoningTokens int `json:"reasoningTokens,omitempty"`
 }
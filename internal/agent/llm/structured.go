@@ -0,0 +1,215 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// defaultResponseFormatName names a json_schema format that has no explicit name.
+const defaultResponseFormatName = "structured_output"
+
+// Validator is implemented by structured output types that check their own
+// semantic constraints after decoding.
+type Validator interface {
+	Validate() error
+}
+
+// jsonSchemaFor builds a JSON Schema for v's type from its Go structure.
+// Field names follow `json` tags and every field is required, as OpenAI's
+// strict mode demands; fields with omitempty may be null instead.
+// A `description` struct tag is copied into the schema.
+func jsonSchemaFor(v interface{}) map[string]interface{} {
+	return schemaForType(reflect.TypeOf(v))
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for _, f := range structFields(t) {
+			prop := schemaForType(f.field.Type)
+			if desc := f.field.Tag.Get("description"); desc != "" {
+				prop["description"] = desc
+			}
+			if typ, ok := prop["type"].(string); ok && f.omitempty {
+				prop["type"] = []string{typ, "null"}
+			}
+			properties[f.name] = prop
+			required = append(required, f.name)
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+type jsonField struct {
+	name      string
+	omitempty bool
+	field     reflect.StructField
+}
+
+// structFields lists the JSON-visible fields of a struct type.
+func structFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{
+			name:      name,
+			omitempty: strings.Contains(opts, "omitempty"),
+			field:     f,
+		})
+	}
+	return fields
+}
+
+// decodeJSON decodes a model reply into T. It tolerates surrounding prose or
+// Markdown code fences, rejects unknown fields, checks that fields without
+// omitempty are present (omitempty fields may be missing or null), and runs
+// Validate if T implements Validator.
+func decodeJSON[T any](content string) (T, error) {
+	var out T
+	raw := extractJSON(content)
+	if raw == "" {
+		return out, fmt.Errorf("response contains no JSON")
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&out); err != nil {
+		return out, fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	if t := reflect.TypeOf(out); t != nil {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() == reflect.Struct {
+			var present map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(raw), &present); err == nil {
+				for _, f := range structFields(t) {
+					if _, ok := present[f.name]; !ok && !f.omitempty {
+						return out, fmt.Errorf("invalid JSON response: missing required field %q", f.name)
+					}
+				}
+			}
+		}
+	}
+
+	if v, ok := any(&out).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return out, fmt.Errorf("invalid JSON response: %w", err)
+		}
+	} else if v, ok := any(out).(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return out, fmt.Errorf("invalid JSON response: %w", err)
+		}
+	}
+	return out, nil
+}
+
+// extractJSON returns the first JSON object or array in s.
+func extractJSON(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		s = strings.TrimPrefix(s, "json")
+		if idx := strings.LastIndex(s, "```"); idx >= 0 {
+			s = s[:idx]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+
+	start := strings.IndexAny(s, "{[")
+	if start < 0 {
+		return ""
+	}
+	dec := json.NewDecoder(strings.NewReader(s[start:]))
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return ""
+	}
+	return string(bytes.TrimSpace(raw))
+}
+
+// ChatJSON sends req with a json_schema response format derived from T and
+// decodes the reply into T. If the reply fails to decode or validate, the
+// error is fed back to the model once before giving up.
+func ChatJSON[T any](ctx context.Context, p Provider, req ChatRequest) (T, error) {
+	var zero T
+	if req.ResponseFormat == nil {
+		req.ResponseFormat = &ResponseFormat{
+			Type:   ResponseFormatJSONSchema,
+			Name:   defaultResponseFormatName,
+			Schema: jsonSchemaFor(zero),
+		}
+	}
+	messages := append([]Message(nil), req.Messages...)
+
+	const maxAttempts = 2
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		req.Messages = messages
+		resp, err := p.Chat(ctx, &req)
+		if err != nil {
+			return zero, err
+		}
+		out, err := decodeJSON[T](resp.Content)
+		if err == nil {
+			return out, nil
+		}
+		lastErr = err
+		messages = append(messages,
+			Message{Role: "assistant", Content: resp.Content},
+			Message{Role: "user", Content: fmt.Sprintf("Your reply was not valid: %v. Reply again with only the corrected JSON.", err)},
+		)
+	}
+	return zero, lastErr
+}
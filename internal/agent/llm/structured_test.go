@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cronIntent struct {
+	Schedule string   `json:"schedule" description:"Cron expression"`
+	Message  string   `json:"message"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+func (c cronIntent) Validate() error {
+	if c.Schedule == "" {
+		return fmt.Errorf("schedule must not be empty")
+	}
+	return nil
+}
+
+func TestJSONSchemaFor(t *testing.T) {
+	schema := jsonSchemaFor(cronIntent{})
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"schedule", "message", "tags"}, schema["required"])
+
+	props := schema["properties"].(map[string]interface{})
+	assert.Equal(t, "Cron expression", props["schedule"].(map[string]interface{})["description"])
+	assert.Equal(t, "string", props["schedule"].(map[string]interface{})["type"])
+	assert.Equal(t, []string{"array", "null"}, props["tags"].(map[string]interface{})["type"])
+}
+
+func TestDecodeJSON(t *testing.T) {
+	got, err := decodeJSON[cronIntent]("Sure!\n```json\n{\"schedule\": \"0 9 * * *\", \"message\": \"standup\"}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "0 9 * * *", got.Schedule)
+
+	got, err = decodeJSON[cronIntent](`Here you go: {"schedule": "@daily", "message": "hi"} done`)
+	require.NoError(t, err)
+	assert.Equal(t, "@daily", got.Schedule)
+
+	got, err = decodeJSON[cronIntent](`{"schedule": "@daily", "message": "hi", "tags": null}`)
+	require.NoError(t, err)
+	assert.Nil(t, got.Tags)
+
+	_, err = decodeJSON[cronIntent](`{"schedule": "@daily"}`)
+	assert.ErrorContains(t, err, `missing required field "message"`)
+
+	_, err = decodeJSON[cronIntent](`{"schedule": "", "message": "x"}`)
+	assert.ErrorContains(t, err, "schedule must not be empty")
+
+	_, err = decodeJSON[cronIntent](`{"schedule": "@daily", "message": "x", "extra": 1}`)
+	assert.Error(t, err)
+
+	_, err = decodeJSON[cronIntent]("no json here")
+	assert.Error(t, err)
+}
+
+func TestChatJSON_OpenAI(t *testing.T) {
+	var requests []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		requests = append(requests, body)
+
+		// First reply is invalid, second is corrected.
+		content := `{"schedule": "", "message": "standup"}`
+		if len(requests) > 1 {
+			content = `{"schedule": "0 9 * * 1-5", "message": "standup"}`
+		}
+		reply, _ := json.Marshal(content)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s},"finish_reason":"stop"}]}`, reply)
+	}))
+	defer srv.Close()
+
+	p := NewOpenAIProviderWithConfig("test-key", srv.URL)
+	got, err := ChatJSON[cronIntent](context.Background(), p, ChatRequest{
+		Model:    "gpt-test",
+		Messages: []Message{{Role: "user", Content: "every weekday at 9 remind me of standup"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0 9 * * 1-5", got.Schedule)
+
+	require.Len(t, requests, 2)
+	format := requests[0]["response_format"].(map[string]interface{})
+	assert.Equal(t, "json_schema", format["type"])
+	jsonSchema := format["json_schema"].(map[string]interface{})
+	assert.Equal(t, "structured_output", jsonSchema["name"])
+	assert.Equal(t, "object", jsonSchema["schema"].(map[string]interface{})["type"])
+
+	// The retry carries the invalid reply and the validation error.
+	msgs := requests[1]["messages"].([]interface{})
+	last := msgs[len(msgs)-1].(map[string]interface{})
+	assert.Contains(t, last["content"], "schedule must not be empty")
+}
+
+func TestChatJSON_AnthropicForcedTool(t *testing.T) {
+	var body map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{
+			"content": [{"type": "tool_use", "id": "toolu_1", "name": "cron_intent", "input": {"schedule": "@hourly", "message": "ping"}}],
+			"stop_reason": "tool_use",
+			"usage": {"input_tokens": 10, "output_tokens": 5}
+		}`))
+	}))
+	defer srv.Close()
+
+	p := NewAnthropicProvider("test-key", srv.URL)
+	got, err := ChatJSON[cronIntent](context.Background(), p, ChatRequest{
+		Model:    "claude-test",
+		Messages: []Message{{Role: "user", Content: "ping me hourly"}},
+		ResponseFormat: &ResponseFormat{
+			Type:   ResponseFormatJSONSchema,
+			Name:   "cron_intent",
+			Schema: jsonSchemaFor(cronIntent{}),
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, cronIntent{Schedule: "@hourly", Message: "ping"}, got)
+
+	assert.Equal(t, map[string]interface{}{"type": "tool", "name": "cron_intent"}, body["tool_choice"])
+	tools := body["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	assert.Equal(t, "cron_intent", tools[0].(map[string]interface{})["name"])
+}
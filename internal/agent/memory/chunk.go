@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxChunkChars is the size paragraphs are merged up to within a section.
+const maxChunkChars = 800
+
+// Chunk is an indexed span of a memory source.
+type Chunk struct {
+	// Path is relative to the workspace, or "transcripts/<file>" for transcripts.
+	Path      string
+	StartLine int
+	EndLine   int
+	// Heading is the nearest Markdown heading above the chunk.
+	Heading string
+	Text    string
+	// Time drives the recency boost.
+	Time time.Time
+}
+
+// chunkMarkdown splits Markdown into chunks at headings and blank lines,
+// merging short paragraphs of the same section.
+func chunkMarkdown(path, content string, ts time.Time) []Chunk {
+	var chunks []Chunk
+	heading := ""
+	var buf []string
+	start := 0
+
+	flush := func(end int) {
+		text := strings.TrimSpace(strings.Join(buf, "\n"))
+		if text != "" {
+			chunks = append(chunks, Chunk{
+				Path:      path,
+				StartLine: start,
+				EndLine:   end,
+				Heading:   heading,
+				Text:      text,
+				Time:      ts,
+			})
+		}
+		buf = nil
+		start = 0
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	inFence := false
+	for i, line := range lines {
+		n := i + 1
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+
+		if !inFence && strings.HasPrefix(trimmed, "#") {
+			flush(n - 1)
+			heading = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			start = n
+			buf = append(buf, line)
+			continue
+		}
+
+		if !inFence && trimmed == "" {
+			// Paragraph break: close the chunk once it is large enough.
+			if len(strings.Join(buf, "\n")) >= maxChunkChars/2 {
+				flush(n - 1)
+			} else if len(buf) > 0 {
+				buf = append(buf, line)
+			}
+			continue
+		}
+
+		if start == 0 {
+			start = n
+		}
+		buf = append(buf, line)
+		if len(strings.Join(buf, "\n")) >= maxChunkChars && !inFence {
+			flush(n)
+		}
+	}
+	flush(len(lines))
+
+	// Trailing blank lines are not part of a chunk.
+	for i := range chunks {
+		chunks[i].EndLine = chunks[i].StartLine + strings.Count(chunks[i].Text, "\n")
+	}
+	return chunks
+}
+
+// transcriptLine is the subset of a session transcript entry that is indexed.
+type transcriptLine struct {
+	Type    string `json:"type"`
+	Message *struct {
+		Role    string `json:"role"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Timestamp int64 `json:"timestamp"`
+	} `json:"message"`
+}
+
+// chunkTranscript turns each message of a JSONL session transcript into a
+// chunk whose lines point at the transcript line.
+func chunkTranscript(path, file string) ([]Chunk, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var chunks []Chunk
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	n := 0
+	for scanner.Scan() {
+		n++
+		var entry transcriptLine
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.Type != "message" || entry.Message == nil {
+			continue
+		}
+		var parts []string
+		for _, c := range entry.Message.Content {
+			if c.Type == "text" && strings.TrimSpace(c.Text) != "" {
+				parts = append(parts, c.Text)
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		chunks = append(chunks, Chunk{
+			Path:      path,
+			StartLine: n,
+			EndLine:   n,
+			Heading:   entry.Message.Role,
+			Text:      entry.Message.Role + ": " + strings.Join(parts, "\n"),
+			Time:      time.UnixMilli(entry.Message.Timestamp),
+		})
+	}
+	return chunks, scanner.Err()
+}
@@ -0,0 +1,504 @@
+// Package memory indexes the agent's memory files, and optionally its session
+// transcripts, for ranked search.
+package memory
+
+import (
+	"context"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// TranscriptPrefix is prepended to transcript paths in search results.
+const TranscriptPrefix = "transcripts/"
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	defaultHalfLifeDays  = 30
+	defaultRecencyWeight = 0.2
+	defaultEmbedWeight   = 0.5
+	maxSnippetChars      = 320
+	embedBatchSize       = 64
+)
+
+// Config controls what is indexed and how results are ranked.
+type Config struct {
+	// Transcripts also indexes session transcripts.
+	Transcripts bool `json:"transcripts" yaml:"transcripts" mapstructure:"transcripts"`
+	// RecencyHalfLifeDays is the age at which the recency boost halves
+	// (default: 30).
+	RecencyHalfLifeDays float64 `json:"recencyHalfLifeDays,omitempty" yaml:"recencyHalfLifeDays,omitempty" mapstructure:"recencyHalfLifeDays"`
+	// RecencyWeight is the boost given to brand-new chunks, e.g. 0.2 for +20%
+	// (default: 0.2, negative disables).
+	RecencyWeight float64 `json:"recencyWeight,omitempty" yaml:"recencyWeight,omitempty" mapstructure:"recencyWeight"`
+	// Embeddings enables hybrid search with an embedding model.
+	Embeddings EmbeddingsConfig `json:"embeddings" yaml:"embeddings" mapstructure:"embeddings"`
+}
+
+// EmbeddingsConfig selects an OpenAI-compatible embedding model.
+type EmbeddingsConfig struct {
+	// Provider is a key of models.providers; empty disables embeddings.
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Model is the embedding model (default: text-embedding-3-small).
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+	// Weight is the share of the score taken by vector similarity
+	// (default: 0.5).
+	Weight float64 `json:"weight,omitempty" yaml:"weight,omitempty" mapstructure:"weight"`
+}
+
+// Result is a ranked chunk.
+type Result struct {
+	Path      string  `json:"path"`
+	StartLine int     `json:"startLine"`
+	EndLine   int     `json:"endLine"`
+	Heading   string  `json:"heading,omitempty"`
+	Snippet   string  `json:"snippet"`
+	Score     float64 `json:"score"`
+}
+
+// fileEntry is an indexed source file.
+type fileEntry struct {
+	modTime time.Time
+	size    int64
+	docs    []doc
+}
+
+// doc is a chunk with its term statistics.
+type doc struct {
+	Chunk
+	terms  map[string]int
+	length int
+}
+
+// Index is an incrementally refreshed BM25 index. It is safe for concurrent
+// use.
+type Index struct {
+	// Embedder enables hybrid ranking when set.
+	Embedder Embedder
+
+	workspace   string
+	transcripts string
+	cfg         Config
+	now         func() time.Time
+
+	mu      sync.Mutex
+	files   map[string]*fileEntry
+	vectors map[string][]float32
+}
+
+// NewIndex creates an index over workspace/MEMORY.md and workspace/memory.
+// transcriptsDir is only indexed when cfg.Transcripts is set.
+func NewIndex(workspace, transcriptsDir string, cfg Config) *Index {
+	if cfg.RecencyHalfLifeDays <= 0 {
+		cfg.RecencyHalfLifeDays = defaultHalfLifeDays
+	}
+	if cfg.RecencyWeight == 0 {
+		cfg.RecencyWeight = defaultRecencyWeight
+	}
+	if cfg.Embeddings.Weight <= 0 || cfg.Embeddings.Weight > 1 {
+		cfg.Embeddings.Weight = defaultEmbedWeight
+	}
+	return &Index{
+		workspace:   workspace,
+		transcripts: transcriptsDir,
+		cfg:         cfg,
+		now:         time.Now,
+		files:       make(map[string]*fileEntry),
+		vectors:     make(map[string][]float32),
+	}
+}
+
+// Workspace returns the directory memory paths are relative to.
+func (ix *Index) Workspace() string {
+	return ix.workspace
+}
+
+// TranscriptsDir returns the transcript directory, or "" when transcripts
+// are not indexed.
+func (ix *Index) TranscriptsDir() string {
+	if !ix.cfg.Transcripts {
+		return ""
+	}
+	return ix.transcripts
+}
+
+// Refresh re-reads sources whose mtime or size changed and drops removed ones.
+func (ix *Index) Refresh() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.refreshLocked()
+}
+
+func (ix *Index) refreshLocked() error {
+	seen := make(map[string]bool)
+
+	add := func(rel, full string, info fs.FileInfo, transcript bool) {
+		seen[rel] = true
+		if e, ok := ix.files[rel]; ok && e.modTime.Equal(info.ModTime()) && e.size == info.Size() {
+			return
+		}
+		var chunks []Chunk
+		if transcript {
+			var err error
+			if chunks, err = chunkTranscript(rel, full); err != nil {
+				return
+			}
+		} else {
+			data, err := os.ReadFile(full)
+			if err != nil {
+				return
+			}
+			chunks = chunkMarkdown(rel, string(data), chunkTime(full, info))
+		}
+		entry := &fileEntry{modTime: info.ModTime(), size: info.Size()}
+		for _, c := range chunks {
+			terms := tokenize(c.Text)
+			tf := make(map[string]int, len(terms))
+			for _, t := range terms {
+				tf[t]++
+			}
+			entry.docs = append(entry.docs, doc{Chunk: c, terms: tf, length: len(terms)})
+		}
+		ix.files[rel] = entry
+	}
+
+	if info, err := os.Stat(filepath.Join(ix.workspace, "MEMORY.md")); err == nil && !info.IsDir() {
+		add("MEMORY.md", filepath.Join(ix.workspace, "MEMORY.md"), info, false)
+	}
+
+	memDir := filepath.Join(ix.workspace, "memory")
+	_ = filepath.WalkDir(memDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, err := filepath.Rel(ix.workspace, path)
+		if err != nil {
+			return nil
+		}
+		add(filepath.ToSlash(rel), path, info, false)
+		return nil
+	})
+
+	if dir := ix.TranscriptsDir(); dir != "" {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".jsonl") {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			add(TranscriptPrefix+e.Name(), filepath.Join(dir, e.Name()), info, true)
+		}
+	}
+
+	for rel := range ix.files {
+		if !seen[rel] {
+			delete(ix.files, rel)
+		}
+	}
+	return nil
+}
+
+// Search refreshes the index and returns up to limit chunks ranked by BM25,
+// blended with embedding similarity when an Embedder is set, and boosted by
+// recency.
+func (ix *Index) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	if limit <= 0 {
+		limit = 5
+	}
+
+	// Refreshing replaces a file's docs rather than changing them, so the
+	// snapshot stays valid after the lock is released and slow embedding
+	// calls do not block refreshes or other searches.
+	ix.mu.Lock()
+	if err := ix.refreshLocked(); err != nil {
+		ix.mu.Unlock()
+		return nil, err
+	}
+	var docs []*doc
+	for _, e := range ix.files {
+		for i := range e.docs {
+			docs = append(docs, &e.docs[i])
+		}
+	}
+	ix.mu.Unlock()
+	if len(docs) == 0 {
+		return []Result{}, nil
+	}
+
+	qterms := uniq(tokenize(query))
+	scores := bm25(docs, qterms)
+
+	var sims []float64
+	if ix.Embedder != nil {
+		// Embedding failures degrade to keyword-only ranking.
+		sims, _ = ix.similarities(ctx, query, docs)
+	}
+
+	maxBM := 0.0
+	for _, s := range scores {
+		maxBM = math.Max(maxBM, s)
+	}
+
+	now := ix.now()
+	type scored struct {
+		d     *doc
+		score float64
+	}
+	var ranked []scored
+	for i, d := range docs {
+		s := scores[i]
+		if sims != nil {
+			w := ix.cfg.Embeddings.Weight
+			norm := 0.0
+			if maxBM > 0 {
+				norm = s / maxBM
+			}
+			s = (1-w)*norm + w*math.Max(sims[i], 0)
+		}
+		if s <= 0 {
+			continue
+		}
+		ranked = append(ranked, scored{d: d, score: s * ix.recency(d.Time, now)})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		if ranked[i].d.Path != ranked[j].d.Path {
+			return ranked[i].d.Path < ranked[j].d.Path
+		}
+		return ranked[i].d.StartLine < ranked[j].d.StartLine
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	results := make([]Result, 0, len(ranked))
+	for _, r := range ranked {
+		results = append(results, Result{
+			Path:      r.d.Path,
+			StartLine: r.d.StartLine,
+			EndLine:   r.d.EndLine,
+			Heading:   r.d.Heading,
+			Snippet:   snippet(r.d.Text, qterms),
+			Score:     math.Round(r.score*1000) / 1000,
+		})
+	}
+	return results, nil
+}
+
+// bm25 scores every doc against the query terms.
+func bm25(docs []*doc, qterms []string) []float64 {
+	total := 0
+	df := make(map[string]int, len(qterms))
+	for _, d := range docs {
+		total += d.length
+		for _, t := range qterms {
+			if d.terms[t] > 0 {
+				df[t]++
+			}
+		}
+	}
+	n := float64(len(docs))
+	avgdl := float64(total) / n
+	if avgdl == 0 {
+		avgdl = 1
+	}
+
+	scores := make([]float64, len(docs))
+	for i, d := range docs {
+		for _, t := range qterms {
+			tf := float64(d.terms[t])
+			if tf == 0 {
+				continue
+			}
+			idf := math.Log(1 + (n-float64(df[t])+0.5)/(float64(df[t])+0.5))
+			scores[i] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(d.length)/avgdl))
+		}
+	}
+	return scores
+}
+
+// recency returns a multiplier in [1, 1+RecencyWeight] that halves its boost
+// every RecencyHalfLifeDays.
+func (ix *Index) recency(ts, now time.Time) float64 {
+	if ix.cfg.RecencyWeight < 0 || ts.IsZero() {
+		return 1
+	}
+	age := now.Sub(ts).Hours() / 24
+	if age < 0 {
+		age = 0
+	}
+	return 1 + ix.cfg.RecencyWeight*math.Pow(0.5, age/ix.cfg.RecencyHalfLifeDays)
+}
+
+// similarities embeds the query and any chunks not yet cached. The cache is
+// only locked around lookups and updates, not while embedding.
+func (ix *Index) similarities(ctx context.Context, query string, docs []*doc) ([]float64, error) {
+	vectors := make(map[string][]float32, len(docs))
+	var missing []string
+	ix.mu.Lock()
+	for _, d := range docs {
+		if v, ok := ix.vectors[d.Text]; ok {
+			vectors[d.Text] = v
+		} else {
+			missing = append(missing, d.Text)
+		}
+	}
+	ix.mu.Unlock()
+
+	missing = uniq(missing)
+	embedded := make(map[string][]float32, len(missing))
+	var embedErr error
+	for start := 0; start < len(missing); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(missing))
+		vecs, err := ix.Embedder.Embed(ctx, missing[start:end])
+		if err != nil {
+			embedErr = err
+			break
+		}
+		for i, v := range vecs {
+			embedded[missing[start+i]] = v
+		}
+	}
+
+	// Cache what was embedded and forget vectors for chunks that no longer
+	// exist.
+	ix.mu.Lock()
+	for text, v := range embedded {
+		ix.vectors[text] = v
+	}
+	live := make(map[string]bool)
+	for _, e := range ix.files {
+		for _, d := range e.docs {
+			live[d.Text] = true
+		}
+	}
+	for text := range ix.vectors {
+		if !live[text] {
+			delete(ix.vectors, text)
+		}
+	}
+	ix.mu.Unlock()
+	if embedErr != nil {
+		return nil, embedErr
+	}
+
+	qv, err := ix.Embedder.Embed(ctx, []string{query})
+	if err != nil || len(qv) == 0 {
+		return nil, err
+	}
+	sims := make([]float64, len(docs))
+	for i, d := range docs {
+		v, ok := vectors[d.Text]
+		if !ok {
+			v = embedded[d.Text]
+		}
+		sims[i] = cosine(qv[0], v)
+	}
+	return sims, nil
+}
+
+var datePattern = regexp.MustCompile(`(\d{4})-(\d{2})-(\d{2})`)
+
+// chunkTime prefers a date in the file name (memory/2024-05-01.md) over mtime.
+func chunkTime(path string, info fs.FileInfo) time.Time {
+	if m := datePattern.FindString(filepath.Base(path)); m != "" {
+		if ts, err := time.ParseInLocation("2006-01-02", m, time.Local); err == nil {
+			return ts
+		}
+	}
+	return info.ModTime()
+}
+
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "but": true, "by": true, "did": true, "do": true, "for": true,
+	"from": true, "had": true, "has": true, "have": true, "how": true, "i": true,
+	"in": true, "is": true, "it": true, "its": true, "me": true, "my": true,
+	"of": true, "on": true, "or": true, "so": true, "that": true, "the": true,
+	"this": true, "to": true, "was": true, "we": true, "were": true, "what": true,
+	"when": true, "where": true, "which": true, "who": true, "why": true,
+	"will": true, "with": true, "you": true, "your": true,
+}
+
+// tokenize lowercases text, splits on non-alphanumerics and drops stopwords.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := fields[:0]
+	for _, f := range fields {
+		if !stopwords[f] {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func uniq(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := make([]string, 0, len(items))
+	for _, s := range items {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// snippet returns text trimmed to a window around the first query term.
+func snippet(text string, qterms []string) string {
+	if len(text) <= maxSnippetChars {
+		return text
+	}
+	lower := strings.ToLower(text)
+	pos := -1
+	for _, t := range qterms {
+		if i := strings.Index(lower, t); i >= 0 && (pos < 0 || i < pos) {
+			pos = i
+		}
+	}
+	start := 0
+	if pos > maxSnippetChars/3 {
+		start = pos - maxSnippetChars/3
+	}
+	end := min(start+maxSnippetChars, len(text))
+	// Avoid cutting UTF-8 sequences.
+	for start > 0 && !utf8Start(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8Start(text[end]) {
+		end++
+	}
+	out := strings.TrimSpace(text[start:end])
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(text) {
+		out += "…"
+	}
+	return out
+}
+
+func utf8Start(b byte) bool {
+	return b&0xC0 != 0x80
+}
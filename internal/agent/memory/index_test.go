@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	content := "# Projects\n\nLiteclaw gateway work.\n\n## Travel\n\nFlight to Lisbon on May 3.\nHotel booked.\n"
+	chunks := chunkMarkdown("MEMORY.md", content, time.Time{})
+	require.Len(t, chunks, 2)
+
+	assert.Equal(t, "Projects", chunks[0].Heading)
+	assert.Equal(t, 1, chunks[0].StartLine)
+	assert.Equal(t, 3, chunks[0].EndLine)
+
+	assert.Equal(t, "Travel", chunks[1].Heading)
+	assert.Equal(t, 5, chunks[1].StartLine)
+	assert.Equal(t, 8, chunks[1].EndLine)
+	assert.Contains(t, chunks[1].Text, "Lisbon")
+}
+
+func TestIndexSearchRanksByBM25(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "MEMORY.md"), "# Preferences\n\nPrefers tea over coffee.\n\n# Work\n\nThe database migration uses postgres. Postgres upgrade planned; postgres replicas too.\n")
+	writeFile(t, filepath.Join(dir, "memory", "notes", "db.md"), "# DB\n\nMentioned postgres once.\n")
+
+	ix := NewIndex(dir, "", Config{RecencyWeight: -1})
+	results, err := ix.Search(context.Background(), "postgres upgrade", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Equal(t, "MEMORY.md", results[0].Path)
+	assert.Equal(t, 5, results[0].StartLine)
+	assert.Equal(t, 7, results[0].EndLine)
+	assert.Equal(t, "memory/notes/db.md", results[1].Path)
+	assert.Greater(t, results[0].Score, results[1].Score)
+
+	results, err = ix.Search(context.Background(), "the and of", 5)
+	require.NoError(t, err)
+	assert.Empty(t, results, "stopword-only queries match nothing")
+}
+
+func TestIndexRecencyBoost(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "memory", "2020-01-01.md"), "Standup notes about the deploy.\n")
+	writeFile(t, filepath.Join(dir, "memory", "2024-06-01.md"), "Standup notes about the deploy.\n")
+
+	ix := NewIndex(dir, "", Config{})
+	ix.now = func() time.Time { return time.Date(2024, 6, 2, 0, 0, 0, 0, time.Local) }
+
+	results, err := ix.Search(context.Background(), "deploy", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "memory/2024-06-01.md", results[0].Path)
+	assert.Greater(t, results[0].Score, results[1].Score)
+}
+
+func TestIndexIncrementalRefresh(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memory", "a.md")
+	writeFile(t, path, "alpha\n")
+
+	ix := NewIndex(dir, "", Config{})
+	results, err := ix.Search(context.Background(), "alpha", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Unchanged files are not re-read.
+	entry := ix.files["memory/a.md"]
+	_, err = ix.Search(context.Background(), "alpha", 5)
+	require.NoError(t, err)
+	assert.Same(t, entry, ix.files["memory/a.md"])
+
+	writeFile(t, path, "beta gamma\n")
+	require.NoError(t, os.Chtimes(path, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	results, err = ix.Search(context.Background(), "alpha", 5)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+	results, err = ix.Search(context.Background(), "gamma", 5)
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, ix.Refresh())
+	assert.Empty(t, ix.files)
+}
+
+func TestIndexTranscripts(t *testing.T) {
+	dir := t.TempDir()
+	sessions := filepath.Join(t.TempDir(), "sessions")
+	writeFile(t, filepath.Join(sessions, "s1.jsonl"), strings.Join([]string{
+		`{"type":"session","id":"s1"}`,
+		`{"type":"message","message":{"role":"user","content":[{"type":"text","text":"What is the wifi password at the cabin?"}],"timestamp":1717200000000}}`,
+		`{"type":"message","message":{"role":"assistant","content":[{"type":"text","text":"It is on the fridge."}],"timestamp":1717200001000}}`,
+	}, "\n")+"\n")
+
+	results, err := NewIndex(dir, sessions, Config{}).Search(context.Background(), "cabin wifi", 5)
+	require.NoError(t, err)
+	assert.Empty(t, results, "transcripts are opt-in")
+
+	ix := NewIndex(dir, sessions, Config{Transcripts: true})
+	results, err = ix.Search(context.Background(), "cabin wifi", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "transcripts/s1.jsonl", results[0].Path)
+	assert.Equal(t, 2, results[0].StartLine)
+	assert.Equal(t, "user", results[0].Heading)
+	assert.True(t, strings.HasPrefix(results[0].Snippet, "user: "))
+}
+
+type fakeEmbedder struct{ calls int }
+
+// Embed maps texts mentioning "feline" or "cat" to the same direction.
+func (f *fakeEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	f.calls++
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		lower := strings.ToLower(text)
+		if strings.Contains(lower, "cat") || strings.Contains(lower, "feline") {
+			out[i] = []float32{1, 0}
+		} else {
+			out[i] = []float32{0, 1}
+		}
+	}
+	return out, nil
+}
+
+func TestIndexHybridEmbeddings(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "memory", "pets.md"), "# Pets\n\nThe cat is called Miso.\n")
+	writeFile(t, filepath.Join(dir, "memory", "car.md"), "# Car\n\nService due in March.\n")
+
+	emb := &fakeEmbedder{}
+	ix := NewIndex(dir, "", Config{})
+	ix.Embedder = emb
+
+	results, err := ix.Search(context.Background(), "feline", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "memory/pets.md", results[0].Path)
+
+	// Chunk vectors are cached; only the query is embedded again.
+	calls := emb.calls
+	_, err = ix.Search(context.Background(), "feline", 5)
+	require.NoError(t, err)
+	assert.Equal(t, calls+1, emb.calls)
+}
+
+// blockingEmbedder holds every Embed call until release is closed.
+type blockingEmbedder struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	select {
+	case b.started <- struct{}{}:
+	default:
+	}
+	<-b.release
+	return (&fakeEmbedder{}).Embed(ctx, texts)
+}
+
+func TestIndexSearchEmbedsOutsideLock(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "memory", "pets.md"), "# Pets\n\nThe cat is called Miso.\n")
+
+	emb := &blockingEmbedder{started: make(chan struct{}, 1), release: make(chan struct{})}
+	ix := NewIndex(dir, "", Config{})
+	ix.Embedder = emb
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := ix.Search(context.Background(), "feline", 5)
+		done <- err
+	}()
+	<-emb.started
+
+	// A refresh (e.g. after a memory write) is not held up by the embedding call.
+	refreshed := make(chan error, 1)
+	go func() { refreshed <- ix.Refresh() }()
+	select {
+	case err := <-refreshed:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Refresh blocked while Search was embedding")
+	}
+
+	close(emb.release)
+	require.NoError(t, <-done)
+}
+
+func TestSnippetWindowsAroundMatch(t *testing.T) {
+	text := strings.Repeat("filler ", 100) + "needle here " + strings.Repeat("tail ", 100)
+	s := snippet(text, []string{"needle"})
+	assert.Contains(t, s, "needle")
+	assert.True(t, strings.HasPrefix(s, "…"))
+	assert.True(t, strings.HasSuffix(s, "…"))
+	assert.LessOrEqual(t, len(s), maxSnippetChars+8)
+}
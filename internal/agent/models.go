@@ -0,0 +1,237 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/config"
+)
+
+// defaultMaxTokens is used when a model entry does not declare maxTokens.
+const defaultMaxTokens = 4096
+
+// ResolvedModel is a model reference bound to a ready-to-use provider.
+type ResolvedModel struct {
+	Ref       string // canonical "provider/model"
+	Provider  string
+	Model     string
+	Entry     config.ModelEntry
+	MaxTokens int
+	Client    llm.Provider
+}
+
+// ProviderFactory builds an llm.Provider for a configured provider.
+type ProviderFactory func(name string, p config.ModelProvider, apiKey string, verbose bool) (llm.Provider, error)
+
+// ModelRegistry lazily builds and caches llm.Provider instances for
+// "provider/model" refs and aliases from models.providers.
+// When watching a config file, changes on disk (e.g. `liteclaw models set`)
+// are picked up on the next resolve without restarting the gateway.
+type ModelRegistry struct {
+	mu        sync.Mutex
+	cfg       *config.Config
+	providers map[string]llm.Provider
+
+	configPath string
+	modTime    time.Time
+
+	// Factory builds providers; defaults to NewProviderFromConfig.
+	Factory ProviderFactory
+	Verbose bool
+	// Auth tracks per-profile usage and rate-limit cooldowns.
+	Auth *AuthStore
+}
+
+// NewModelRegistry creates a registry backed by the given config.
+func NewModelRegistry(cfg *config.Config) *ModelRegistry {
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	auth := NewAuthStore("")
+	auth.BaseCooldown = authCooldown(cfg.Auth)
+	return &ModelRegistry{
+		cfg:       cfg,
+		providers: make(map[string]llm.Provider),
+		Factory:   NewProviderFromConfig,
+		Auth:      auth,
+	}
+}
+
+// WatchConfig makes the registry reload the config whenever the file at path changes.
+func (r *ModelRegistry) WatchConfig(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configPath = path
+	if info, err := os.Stat(path); err == nil {
+		r.modTime = info.ModTime()
+	}
+}
+
+// SetConfig replaces the config and drops all cached providers.
+func (r *ModelRegistry) SetConfig(cfg *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfg = cfg
+	r.providers = make(map[string]llm.Provider)
+}
+
+// Config returns the current config, reloading it first if the watched file changed.
+func (r *ModelRegistry) Config() *config.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshLocked()
+	return r.cfg
+}
+
+// DefaultRef returns the configured primary model.
+func (r *ModelRegistry) DefaultRef() string {
+	return r.Config().Agents.Defaults.Model.Primary
+}
+
+// Resolve resolves a "provider/model" or alias to a provider instance.
+// An empty ref resolves to the primary model.
+func (r *ModelRegistry) Resolve(ref string) (*ResolvedModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.refreshLocked()
+
+	providerName, modelID, err := r.cfg.ResolveModelRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	client, ok := r.providers[providerName]
+	if !ok {
+		p := r.cfg.Models.Providers[providerName]
+		factory, verbose := r.Factory, r.Verbose
+		client, err = newRotatingProvider(
+			providerName,
+			ProviderAuthCandidates(r.cfg, providerName),
+			r.cfg.Agents.Defaults.AuthProfiles[providerName],
+			r.Auth,
+			func(secret string) (llm.Provider, error) {
+				return factory(providerName, p, secret, verbose)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		r.providers[providerName] = client
+	}
+
+	resolved := &ResolvedModel{
+		Ref:       providerName + "/" + modelID,
+		Provider:  providerName,
+		Model:     modelID,
+		MaxTokens: defaultMaxTokens,
+		Client:    client,
+	}
+	if entry, ok := r.cfg.FindModel(providerName, modelID); ok {
+		resolved.Entry = entry
+		if entry.MaxTokens > 0 {
+			resolved.MaxTokens = entry.MaxTokens
+		}
+	}
+	return resolved, nil
+}
+
+// refreshLocked reloads the config if the watched file changed. Caller holds r.mu.
+func (r *ModelRegistry) refreshLocked() {
+	if r.configPath == "" {
+		return
+	}
+	info, err := os.Stat(r.configPath)
+	if err != nil || !info.ModTime().After(r.modTime) {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		if r.Verbose {
+			fmt.Printf("Warning: failed to reload config: %v\n", err)
+		}
+		return
+	}
+	r.modTime = info.ModTime()
+	r.cfg = cfg
+	r.providers = make(map[string]llm.Provider)
+	if r.Auth != nil {
+		r.Auth.SetBaseCooldown(authCooldown(cfg.Auth))
+	}
+}
+
+// ProviderAPIKey looks up the API key for a provider.
+// Convention: PROVIDERNAME_API_KEY (e.g. MINIMAX_API_KEY) from the process
+// env, falling back to the config env block (case-insensitive). Dashes in
+// the name may also be written as underscores (MY_PROVIDER_API_KEY).
+func ProviderAPIKey(cfg *config.Config, providerName string) string {
+	name := strings.ToUpper(providerName) + "_API_KEY"
+	if v := lookupEnv(cfg, name); v != "" {
+		return v
+	}
+	if alt := strings.ReplaceAll(name, "-", "_"); alt != name {
+		return lookupEnv(cfg, alt)
+	}
+	return ""
+}
+
+// lookupEnv reads a variable from the process env, falling back to the
+// config env block (case-insensitive).
+func lookupEnv(cfg *config.Config, name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	for k, v := range cfg.Env {
+		if strings.EqualFold(k, name) && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// NewProviderFromConfig builds a provider based on the config "api" field.
+func NewProviderFromConfig(name string, p config.ModelProvider, apiKey string, verbose bool) (llm.Provider, error) {
+	if p.BaseURL == "" {
+		return nil, fmt.Errorf("baseUrl for provider '%s' is empty. Please set it in 'models.providers.%s.baseUrl'", name, name)
+	}
+
+	if apiKey == "" {
+		// Ollama typically doesn't require an API key
+		if !strings.EqualFold(name, "ollama") {
+			targetKey := strings.ToUpper(name) + "_API_KEY"
+			return nil, fmt.Errorf("API Key '%s' is missing. Run 'liteclaw models auth login %s' or add '%s' to the 'env' section of liteclaw.json", targetKey, name, targetKey)
+		}
+		apiKey = "ollama" // Use placeholder
+	}
+
+	switch p.API {
+	case "anthropic-messages":
+		prov := llm.NewAnthropicProvider(apiKey, p.BaseURL)
+		prov.Verbose = verbose
+		return prov, nil
+	case "openai-responses":
+		prov := llm.NewOpenAIResponsesProvider(apiKey, p.BaseURL)
+		prov.Verbose = verbose
+		for _, m := range p.Models {
+			if m.Reasoning {
+				if prov.ReasoningModels == nil {
+					prov.ReasoningModels = make(map[string]bool)
+				}
+				prov.ReasoningModels[m.ID] = true
+			}
+		}
+		return prov, nil
+	case "openai-completions", "":
+		prov := llm.NewOpenAIProviderWithConfig(apiKey, p.BaseURL)
+		prov.Verbose = verbose
+		return prov, nil
+	default:
+		fmt.Printf("Warning: Unknown API type '%s' for provider '%s'. Defaulting to OpenAI.\n", p.API, name)
+		prov := llm.NewOpenAIProviderWithConfig(apiKey, p.BaseURL)
+		prov.Verbose = verbose
+		return prov, nil
+	}
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProvider answers every request with the model it was asked to use.
+type echoProvider struct {
+	name string
+}
+
+func (p *echoProvider) Name() string { return p.name }
+
+func (p *echoProvider) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	return &llm.ChatResponse{Content: p.name + "/" + req.Model}, nil
+}
+
+func (p *echoProvider) ChatStream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	ch := make(chan llm.StreamChunk, 2)
+	ch <- llm.StreamChunk{Content: p.name + "/" + req.Model}
+	ch <- llm.StreamChunk{Done: true}
+	close(ch)
+	return ch, nil
+}
+
+func (p *echoProvider) Models(ctx context.Context) ([]string, error) { return nil, nil }
+
+func testModelsConfig() *config.Config {
+	return &config.Config{
+		Models: config.ModelsConfig{
+			Providers: map[string]config.ModelProvider{
+				"alpha": {BaseURL: "http://alpha", Models: []config.ModelEntry{{ID: "a1", MaxTokens: 1000}}},
+				"beta":  {BaseURL: "http://beta", Models: []config.ModelEntry{{ID: "b1"}}},
+			},
+		},
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Model:  config.AgentModelConfig{Primary: "alpha/a1"},
+				Models: map[string]config.AgentModelMap{"beta/b1": {Alias: "fast"}},
+			},
+		},
+	}
+}
+
+func newTestRegistry(cfg *config.Config, builds *int) *ModelRegistry {
+	r := NewModelRegistry(cfg)
+	r.Factory = func(name string, p config.ModelProvider, apiKey string, verbose bool) (llm.Provider, error) {
+		*builds++
+		return &echoProvider{name: name}, nil
+	}
+	return r
+}
+
+func collectText(t *testing.T, events <-chan StreamEvent) string {
+	t.Helper()
+	var out string
+	for evt := range events {
+		switch evt.Type {
+		case "text":
+			out += evt.Content
+		case "error":
+			t.Fatalf("unexpected error event: %s", evt.Error)
+		}
+	}
+	return out
+}
+
+func TestModelRegistry_ResolveAndCache(t *testing.T) {
+	builds := 0
+	r := newTestRegistry(testModelsConfig(), &builds)
+
+	def, err := r.Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, "alpha/a1", def.Ref)
+	assert.Equal(t, 1000, def.MaxTokens)
+
+	alias, err := r.Resolve("FAST")
+	require.NoError(t, err)
+	assert.Equal(t, "beta/b1", alias.Ref)
+	assert.Equal(t, defaultMaxTokens, alias.MaxTokens)
+
+	_, err = r.Resolve("beta/b1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, builds, "providers should be built once and cached")
+
+	_, err = r.Resolve("gamma/x")
+	assert.Error(t, err)
+	_, err = r.Resolve("unknown-alias")
+	assert.Error(t, err)
+}
+
+func TestAgent_ModelPrecedence(t *testing.T) {
+	builds := 0
+	a := New("main", "LiteClaw", "", nil)
+	a.Models = newTestRegistry(testModelsConfig(), &builds)
+	a.Stream = true
+	ctx := context.Background()
+
+	events, err := a.Run(ctx, "s1", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "alpha/a1", collectText(t, events))
+
+	ref, err := a.SetSessionModel("s1", "fast")
+	require.NoError(t, err)
+	assert.Equal(t, "beta/b1", ref)
+	assert.Equal(t, "beta/b1", a.SessionModel("s1"))
+	events, err = a.Run(ctx, "s1", "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "beta/b1", collectText(t, events))
+
+	events, err = a.RunWithOptions(ctx, "s1", "hi", RunOptions{Model: "alpha/a1"})
+	require.NoError(t, err)
+	assert.Equal(t, "alpha/a1", collectText(t, events))
+
+	_, err = a.SetSessionModel("s1", "nope/model")
+	assert.Error(t, err)
+}
+
+func TestModelRegistry_ReloadsWatchedConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "liteclaw.json")
+	t.Setenv("LITECLAW_CONFIG_PATH", configPath)
+	t.Setenv("LITECLAW_STATE_DIR", tempDir)
+
+	cfg := testModelsConfig()
+	require.NoError(t, config.Save(cfg))
+
+	builds := 0
+	r := newTestRegistry(cfg, &builds)
+	r.WatchConfig(configPath)
+	assert.Equal(t, "alpha/a1", r.DefaultRef())
+
+	// Simulate `liteclaw models set beta/b1` from another process.
+	cfg.Agents.Defaults.Model.Primary = "beta/b1"
+	require.NoError(t, config.Save(cfg))
+	future := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(configPath, future, future))
+
+	resolved, err := r.Resolve("")
+	require.NoError(t, err)
+	assert.Equal(t, "beta/b1", resolved.Ref)
+
+	// auth.cooldownSeconds is reloaded along with the models.
+	cfg.Auth.CooldownSeconds = 7
+	require.NoError(t, config.Save(cfg))
+	future = future.Add(time.Second)
+	require.NoError(t, os.Chtimes(configPath, future, future))
+	r.Config()
+	assert.Equal(t, 7*time.Second, r.Auth.BaseCooldown)
+}
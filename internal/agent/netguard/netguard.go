@@ -0,0 +1,180 @@
+// Package netguard keeps agent HTTP requests away from private networks. It
+// checks every address a host resolves to at dial time, so redirects and DNS
+// rebinding cannot reach loopback, private or link-local services.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrBlocked is returned when a destination resolves only to disallowed
+// addresses.
+var ErrBlocked = errors.New("destination is a private or local address")
+
+// extraBlocked are ranges not covered by the net.IP classification helpers.
+var extraBlocked = mustCIDRs(
+	"0.0.0.0/8",     // "this" network
+	"100.64.0.0/10", // carrier-grade NAT
+	"192.0.0.0/24",  // IETF protocol assignments
+	"198.18.0.0/15", // benchmarking
+	"240.0.0.0/4",   // reserved
+	"64:ff9b::/96",  // NAT64, may map to private IPv4
+)
+
+// Guard decides which destinations are reachable.
+type Guard struct {
+	hosts map[string]bool
+	nets  []*net.IPNet
+}
+
+// New creates a guard. allow lists hostnames, IPs or CIDRs that may be
+// reached even though they are private, e.g. "localhost" or "10.0.0.0/8".
+func New(allow []string) *Guard {
+	g := &Guard{hosts: make(map[string]bool)}
+	for _, a := range allow {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == "" {
+			continue
+		}
+		if _, n, err := net.ParseCIDR(a); err == nil {
+			g.nets = append(g.nets, n)
+			continue
+		}
+		if ip := net.ParseIP(a); ip != nil {
+			g.nets = append(g.nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		g.hosts[strings.TrimSuffix(a, ".")] = true
+	}
+	return g
+}
+
+// Blocked reports whether ip is private, loopback, link-local or otherwise
+// non-public and not allowlisted.
+func (g *Guard) Blocked(ip net.IP) bool {
+	for _, n := range g.nets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return reason(ip) != ""
+}
+
+// Check resolves host and returns an error if none of its addresses may be
+// reached. It is a pre-flight check; DialContext enforces the same rule.
+func (g *Guard) Check(ctx context.Context, host string) error {
+	_, err := g.resolve(ctx, host)
+	return err
+}
+
+// DialContext dials only permitted addresses. The connection goes to the
+// checked IP, never to a second resolution of the name.
+func (g *Guard) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := g.resolve(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// Client returns an HTTP client whose connections pass through the guard.
+// Proxies are disabled since they would bypass the address check.
+func (g *Guard) Client(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = g.DialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// resolve returns the permitted addresses of host.
+func (g *Guard) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	allowedName := g.hosts[name]
+
+	var ips []net.IP
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+
+	var allowed []net.IP
+	var why string
+	for _, ip := range ips {
+		if allowedName || !g.Blocked(ip) {
+			allowed = append(allowed, ip)
+		} else if why == "" {
+			why = fmt.Sprintf("%s is a %s address", ip, reason(ip))
+		}
+	}
+	if len(allowed) == 0 {
+		if why == "" {
+			why = "no addresses"
+		}
+		return nil, fmt.Errorf("%w: %s resolves to %s; allowlist it to permit access", ErrBlocked, host, why)
+	}
+	return allowed, nil
+}
+
+// reason names the non-public class of ip, or "" for public addresses.
+func reason(ip net.IP) string {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	switch {
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate():
+		return "private"
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return "link-local"
+	case ip.IsUnspecified():
+		return "unspecified"
+	case ip.IsMulticast(), ip.IsInterfaceLocalMulticast():
+		return "multicast"
+	}
+	for _, n := range extraBlocked {
+		if n.Contains(ip) {
+			return "reserved"
+		}
+	}
+	return ""
+}
+
+func mustCIDRs(cidrs ...string) []*net.IPNet {
+	out := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, n)
+	}
+	return out
+}
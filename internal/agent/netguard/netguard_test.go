@@ -0,0 +1,53 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlocked(t *testing.T) {
+	g := New(nil)
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "::1", "fe80::1", "fd00::1", "::ffff:127.0.0.1",
+	} {
+		assert.True(t, g.Blocked(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "1.1.1.1", "2606:4700:4700::1111"} {
+		assert.False(t, g.Blocked(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	g := New([]string{"10.0.0.0/8", "127.0.0.1", "intranet.local"})
+	assert.False(t, g.Blocked(net.ParseIP("10.9.9.9")))
+	assert.False(t, g.Blocked(net.ParseIP("127.0.0.1")))
+	assert.True(t, g.Blocked(net.ParseIP("192.168.0.1")))
+	assert.True(t, g.hosts["intranet.local"])
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	_, err := New(nil).Client(5 * time.Second).Get(srv.URL)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrBlocked), err.Error())
+
+	resp, err := New([]string{"127.0.0.1"}).Client(5 * time.Second).Get(srv.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Error(t, New(nil).Check(context.Background(), "localhost"))
+}
@@ -0,0 +1,214 @@
+// Package pathguard resolves file paths for the agent's filesystem tools and
+// confines them to configured read and write roots.
+package pathguard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Access is the kind of file access being checked.
+type Access int
+
+const (
+	// Read covers reading, listing and searching.
+	Read Access = iota
+	// Write covers creating, modifying and deleting.
+	Write
+)
+
+func (a Access) String() string {
+	if a == Write {
+		return "write"
+	}
+	return "read"
+}
+
+var (
+	// ErrOutsideRoots is returned for paths outside every allowed root.
+	ErrOutsideRoots = errors.New("path is outside the allowed roots")
+	// ErrDenied is returned for paths on the deny list.
+	ErrDenied = errors.New("path is protected")
+)
+
+// DefaultDeny lists home-relative locations that hold credentials. They are
+// always denied unless a more specific root is configured below them.
+var DefaultDeny = []string{
+	"~/.ssh",
+	"~/.gnupg",
+	"~/.aws",
+	"~/.azure",
+	"~/.config/gcloud",
+	"~/.kube",
+	"~/.docker",
+	"~/.netrc",
+	"~/.git-credentials",
+	"~/.password-store",
+	"/etc/shadow",
+	"/etc/sudoers",
+}
+
+// Config lists the roots an agent may read and write. The workspace is always
+// both readable and writable.
+type Config struct {
+	// ReadRoots are extra directories the agent may read.
+	ReadRoots []string `json:"readRoots" yaml:"readRoots" mapstructure:"readRoots"`
+	// WriteRoots are extra directories the agent may write. Write roots are
+	// also readable.
+	WriteRoots []string `json:"writeRoots" yaml:"writeRoots" mapstructure:"writeRoots"`
+	// Deny lists extra paths that must never be accessed.
+	Deny []string `json:"deny" yaml:"deny" mapstructure:"deny"`
+}
+
+// Guard resolves and checks paths.
+type Guard struct {
+	workspace string
+	read      []string
+	write     []string
+	deny      []string
+}
+
+// New creates a guard rooted at workspace. protected are additional deny
+// entries supplied by the caller, such as the state directory.
+func New(cfg Config, workspace string, protected ...string) *Guard {
+	g := &Guard{workspace: realPath(expand(workspace, ""))}
+	g.write = append(g.write, g.workspace)
+	for _, p := range cfg.WriteRoots {
+		g.write = append(g.write, g.normalize(p))
+	}
+	g.read = append(g.read, g.write...)
+	for _, p := range cfg.ReadRoots {
+		g.read = append(g.read, g.normalize(p))
+	}
+	for _, list := range [][]string{DefaultDeny, cfg.Deny, protected} {
+		for _, p := range list {
+			if strings.TrimSpace(p) != "" {
+				g.deny = append(g.deny, g.normalize(p))
+			}
+		}
+	}
+	return g
+}
+
+// WithReadRoots returns a copy of g that may also read roots, e.g. the
+// inbound media of one session.
+func (g *Guard) WithReadRoots(roots ...string) *Guard {
+	c := *g
+	c.read = append([]string(nil), g.read...)
+	for _, p := range roots {
+		if strings.TrimSpace(p) != "" {
+			c.read = append(c.read, g.normalize(p))
+		}
+	}
+	return &c
+}
+
+// Workspace returns the directory relative paths are resolved against.
+func (g *Guard) Workspace() string {
+	return g.workspace
+}
+
+// Resolve expands ~, resolves path relative to the workspace, follows
+// symlinks and checks the result against the roots for access. It returns
+// the real path to use for I/O.
+func (g *Guard) Resolve(path string, access Access) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	resolved := g.normalize(path)
+	if err := g.check(resolved, access); err != nil {
+		return "", fmt.Errorf("%s %s: %w", access, path, err)
+	}
+	return resolved, nil
+}
+
+// Allowed reports whether an already resolved path may be accessed. It is
+// used while walking directory trees.
+func (g *Guard) Allowed(path string, access Access) bool {
+	return g.check(realPath(path), access) == nil
+}
+
+func (g *Guard) check(path string, access Access) error {
+	roots := g.read
+	key := "readRoots"
+	if access == Write {
+		roots = g.write
+		key = "writeRoots"
+	}
+
+	// The most specific entry wins, so a root configured inside a denied
+	// directory (e.g. the managed skills dir) is still reachable.
+	root := longestMatch(roots, path)
+	denied := longestMatch(g.deny, path)
+	if denied != "" && len(denied) >= len(root) {
+		return fmt.Errorf("%w (%s)", ErrDenied, denied)
+	}
+	if root == "" {
+		return fmt.Errorf("%w; ask the user to add it to agents.defaults.paths.%s", ErrOutsideRoots, key)
+	}
+	return nil
+}
+
+// normalize expands ~, makes path absolute relative to the workspace and
+// resolves symlinks.
+func (g *Guard) normalize(path string) string {
+	return realPath(expand(path, g.workspace))
+}
+
+// expand resolves ~ and relative paths.
+func expand(path, base string) string {
+	path = strings.TrimSpace(path)
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(base, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Clean(path)
+}
+
+// realPath resolves symlinks in the longest existing prefix of path, so a
+// file that does not exist yet is still checked against where it would land.
+func realPath(path string) string {
+	var rest []string
+	cur := path
+	for {
+		if resolved, err := filepath.EvalSymlinks(cur); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return path
+		}
+		rest = append([]string{filepath.Base(cur)}, rest...)
+		cur = parent
+	}
+}
+
+// longestMatch returns the longest entry that contains path.
+func longestMatch(entries []string, path string) string {
+	best := ""
+	for _, e := range entries {
+		if within(e, path) && len(e) > len(best) {
+			best = e
+		}
+	}
+	return best
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
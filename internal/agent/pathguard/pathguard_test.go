@@ -0,0 +1,80 @@
+package pathguard
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveRelativeToWorkspace(t *testing.T) {
+	ws := t.TempDir()
+	g := New(Config{}, ws)
+
+	got, err := g.Resolve("notes/today.md", Write)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(g.Workspace(), "notes", "today.md"), got)
+
+	_, err = g.Resolve("../escape.txt", Write)
+	assert.True(t, errors.Is(err, ErrOutsideRoots))
+}
+
+func TestReadAndWriteRoots(t *testing.T) {
+	ws, docs, out := t.TempDir(), t.TempDir(), t.TempDir()
+	g := New(Config{ReadRoots: []string{docs}, WriteRoots: []string{out}}, ws)
+
+	_, err := g.Resolve(filepath.Join(docs, "a.txt"), Read)
+	assert.NoError(t, err)
+	_, err = g.Resolve(filepath.Join(docs, "a.txt"), Write)
+	assert.ErrorIs(t, err, ErrOutsideRoots)
+	assert.ErrorContains(t, err, "agents.defaults.paths.writeRoots")
+
+	// Write roots are readable too.
+	_, err = g.Resolve(filepath.Join(out, "b.txt"), Read)
+	assert.NoError(t, err)
+	_, err = g.Resolve(filepath.Join(out, "b.txt"), Write)
+	assert.NoError(t, err)
+}
+
+func TestSymlinkEscape(t *testing.T) {
+	ws, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "secret.txt")
+	require.NoError(t, os.WriteFile(secret, []byte("key"), 0o600))
+	require.NoError(t, os.Symlink(secret, filepath.Join(ws, "link.txt")))
+	require.NoError(t, os.Symlink(outside, filepath.Join(ws, "dir")))
+
+	g := New(Config{}, ws)
+	_, err := g.Resolve("link.txt", Read)
+	assert.ErrorIs(t, err, ErrOutsideRoots)
+
+	// A file that does not exist yet is checked where it would be created.
+	_, err = g.Resolve("dir/new.txt", Write)
+	assert.ErrorIs(t, err, ErrOutsideRoots)
+	assert.False(t, g.Allowed(filepath.Join(ws, "link.txt"), Read))
+}
+
+func TestDenyList(t *testing.T) {
+	home := t.TempDir()
+	state := filepath.Join(home, ".liteclaw")
+	skills := filepath.Join(state, "skills")
+	require.NoError(t, os.MkdirAll(skills, 0o755))
+	t.Setenv("HOME", home)
+
+	// The whole home directory is a root, but credentials and the state dir
+	// stay protected; the skills dir inside it is explicitly readable.
+	g := New(Config{ReadRoots: []string{skills}, WriteRoots: []string{"~"}}, filepath.Join(home, "clawd"), state)
+
+	_, err := g.Resolve("~/.ssh/id_ed25519", Read)
+	assert.ErrorIs(t, err, ErrDenied)
+	_, err = g.Resolve("~/.liteclaw/liteclaw.json", Write)
+	assert.ErrorIs(t, err, ErrDenied)
+	_, err = g.Resolve("~/.liteclaw/skills/weather/SKILL.md", Read)
+	assert.NoError(t, err)
+	_, err = g.Resolve("~/.liteclaw/skills/weather/SKILL.md", Write)
+	assert.ErrorIs(t, err, ErrDenied)
+	_, err = g.Resolve("~/projects/app/main.go", Write)
+	assert.NoError(t, err)
+}
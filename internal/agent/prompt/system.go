@@ -122,27 +122,29 @@ func buildToolLines(tools []string) []string {
 	var lines []string
 	summaries := map[string]string{
 		// File tools
-		"read":        "Read file contents",
-		"write":       "Create or overwrite files",
-		"edit":        "Make precise edits to files",
-		"apply_patch": "Apply multi-file patches",
-		"grep":        "Search file contents for patterns",
-		"find":        "Find files by glob pattern",
-		"ls":          "List directory contents",
-		"list":        "List directory contents",
+		"read":              "Read file contents",
+		"write":             "Create or overwrite files",
+		"edit":              "Make precise edits to files",
+		"apply_patch":       "Apply multi-file patches",
+		"grep":              "Search file contents for patterns",
+		"find":              "Find files by glob pattern",
+		"ls":                "List directory contents",
+		"list":              "List directory contents",
+		"doc_read":          "Extract text and metadata from PDF, DOCX, XLSX/CSV, PPTX and HTML files by page range",
+		"workspace_history": "Show the workspace's git log/diffs and revert a file to an earlier revision",
 
 		// Execution tools
 		"exec":    "Run shell commands (pty available for TTY-required CLIs)",
 		"process": "Manage background exec sessions",
 
 		// Web tools
-		"web_search": "Search the web (Brave API)",
+		"web_search": "Search the web",
 		"web_fetch":  "Fetch and extract readable content from a URL",
 
 		// Browser and UI tools
-		"browser": "Control web browser",
+		"browser": "Control web browser (managed headless profiles or the Chrome extension relay)",
 		"canvas":  "Present/eval/snapshot the Canvas",
-		"nodes":   "List/describe/notify/camera/screen on paired nodes",
+		"nodes":   "List paired nodes and invoke their commands (system.run/camera/screen/location)",
 
 		// Scheduling
 		"cron": "Manage cron jobs and wake events (use for reminders; when scheduling a reminder, write the systemEvent text as something that will read like a reminder when it fires, and mention that it is a reminder depending on the time gap between setting and firing; include recent context in reminder text if appropriate)",
@@ -162,8 +164,9 @@ func buildToolLines(tools []string) []string {
 		"session_status":   "Show a /status-equivalent status card (usage + time + Reasoning/Verbose/Elevated); use for model-use questions (📊 session_status); optional per-session model override",
 
 		// Media
-		"image": "Analyze an image with the configured image model",
-		"tts":   "Convert text to speech and return a MEDIA: path. Use when the user requests audio or TTS is enabled. Copy the MEDIA line exactly.",
+		"image":          "Analyze one or more images with the configured image model",
+		"image_generate": "Generate or edit images; the files are attached to your reply (or pass a path to message as media)",
+		"tts":            "Convert text to speech; the audio is attached to your reply as a voice note. Use when the user requests audio.",
 
 		// Memory
 		"memory_search": "Mandatory recall step: semantically search MEMORY.md + memory/*.md (and optional session transcripts) before answering questions about prior work, decisions, dates, people, preferences, or todos; returns top snippets with path + lines.",
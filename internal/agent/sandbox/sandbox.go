@@ -0,0 +1,403 @@
+// Package sandbox runs agent shell commands in an isolated environment using
+// bubblewrap (Linux namespaces) or a rootless container runtime.
+package sandbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sandbox modes.
+const (
+	ModeOff     = "off"
+	ModeNonMain = "non-main"
+	ModeAll     = "all"
+)
+
+// Session types used to decide whether a run is sandboxed.
+const (
+	SessionMain     = "main"
+	SessionDirect   = "direct"
+	SessionGroup    = "group"
+	SessionCron     = "cron"
+	SessionSubagent = "subagent"
+)
+
+// Backend names.
+const (
+	BackendAuto   = "auto"
+	BackendBwrap  = "bwrap"
+	BackendPodman = "podman"
+	BackendDocker = "docker"
+)
+
+// DefaultImage is the container image used when none is configured.
+const DefaultImage = "debian:bookworm-slim"
+
+// ErrUnavailable is returned when a sandbox is required but no backend works.
+var ErrUnavailable = errors.New("no sandbox backend available")
+
+// Config selects when and how commands are sandboxed.
+type Config struct {
+	// Mode is "off" (default), "non-main" (sandbox every session except the
+	// main one) or "all".
+	Mode string `json:"mode" yaml:"mode" mapstructure:"mode"`
+	// SessionTypes, when set, lists the session types to sandbox
+	// (main, direct, group, cron, subagent) and takes precedence over Mode.
+	SessionTypes []string `json:"sessionTypes" yaml:"sessionTypes" mapstructure:"sessionTypes"`
+	// Backend is "auto" (default), "bwrap", "podman" or "docker".
+	Backend string `json:"backend" yaml:"backend" mapstructure:"backend"`
+	// Network allows network access from inside the sandbox.
+	Network bool `json:"network" yaml:"network" mapstructure:"network"`
+	// Image is the container image for podman/docker.
+	Image string `json:"image" yaml:"image" mapstructure:"image"`
+	// CPUs limits CPU share (containers: cgroup limit, bwrap: CPU time of
+	// cpus × timeoutSeconds).
+	CPUs float64 `json:"cpus" yaml:"cpus" mapstructure:"cpus"`
+	// MemoryMB limits memory (containers: cgroup limit, bwrap: address space).
+	MemoryMB int `json:"memoryMb" yaml:"memoryMb" mapstructure:"memoryMb"`
+	// PidsLimit limits the number of processes (containers: cgroup limit,
+	// bwrap: RLIMIT_NPROC, which counts every process the host user runs,
+	// not just the sandbox's, so it must allow for those too).
+	PidsLimit int `json:"pidsLimit" yaml:"pidsLimit" mapstructure:"pidsLimit"`
+	// TimeoutSeconds caps the wall-clock time of a sandboxed command.
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeoutSeconds" mapstructure:"timeoutSeconds"`
+	// ReadOnlyPaths are extra host paths made visible read-only.
+	ReadOnlyPaths []string `json:"readOnlyPaths" yaml:"readOnlyPaths" mapstructure:"readOnlyPaths"`
+}
+
+// Applies reports whether a session of the given type must be sandboxed.
+func (c Config) Applies(sessionType string) bool {
+	if len(c.SessionTypes) > 0 {
+		for _, t := range c.SessionTypes {
+			if strings.EqualFold(strings.TrimSpace(t), sessionType) {
+				return true
+			}
+		}
+		return false
+	}
+	switch strings.ToLower(c.Mode) {
+	case ModeAll:
+		return true
+	case ModeNonMain:
+		return sessionType != SessionMain
+	default:
+		return false
+	}
+}
+
+// ClassifySession derives the session type from a session key. hint is the
+// chat type reported by the channel ("direct", "group", "channel", "thread").
+func ClassifySession(key, hint string) string {
+	switch {
+	case key == "main" || strings.HasSuffix(key, ":main"):
+		return SessionMain
+	case strings.HasPrefix(key, "cron:"):
+		return SessionCron
+	case strings.HasPrefix(key, "subagent:"):
+		return SessionSubagent
+	}
+	switch hint {
+	case "group", "channel", "thread":
+		return SessionGroup
+	default:
+		return SessionDirect
+	}
+}
+
+// Sandbox wraps commands for the configured backend.
+type Sandbox struct {
+	cfg       Config
+	workspace string
+
+	// lookPath, probe and stop are replaceable in tests.
+	lookPath func(file string) (string, error)
+	probe    func(name string, args ...string) error
+	stop     func(runtime, container string) error
+
+	once    sync.Once
+	backend string
+	err     error
+}
+
+// New creates a sandbox rooted at workspace, which is mounted read-write.
+func New(cfg Config, workspace string) *Sandbox {
+	if abs, err := filepath.Abs(workspace); err == nil {
+		workspace = abs
+	}
+	return &Sandbox{
+		cfg:       cfg,
+		workspace: workspace,
+		lookPath:  exec.LookPath,
+		probe:     runProbe,
+		stop:      stopContainer,
+	}
+}
+
+// Config returns the sandbox configuration.
+func (s *Sandbox) Config() Config {
+	return s.cfg
+}
+
+// Workspace returns the read-write root visible inside the sandbox.
+func (s *Sandbox) Workspace() string {
+	return s.workspace
+}
+
+// Required reports whether commands for the session type must be sandboxed.
+func (s *Sandbox) Required(sessionType string) bool {
+	return s.cfg.Applies(sessionType)
+}
+
+// Timeout caps a requested timeout at the configured limit.
+func (s *Sandbox) Timeout(requested time.Duration) time.Duration {
+	limit := time.Duration(s.cfg.TimeoutSeconds) * time.Second
+	if limit > 0 && (requested <= 0 || requested > limit) {
+		return limit
+	}
+	return requested
+}
+
+// Backend returns the backend in use, detecting it on first call.
+func (s *Sandbox) Backend() (string, error) {
+	s.once.Do(func() {
+		s.backend, s.err = s.detect()
+	})
+	return s.backend, s.err
+}
+
+func (s *Sandbox) detect() (string, error) {
+	candidates := []string{BackendBwrap, BackendPodman, BackendDocker}
+	if b := strings.ToLower(s.cfg.Backend); b != "" && b != BackendAuto {
+		candidates = []string{b}
+	}
+
+	var reasons []string
+	for _, name := range candidates {
+		if _, err := s.lookPath(name); err != nil {
+			reasons = append(reasons, name+": not installed")
+			continue
+		}
+		var err error
+		switch name {
+		case BackendBwrap:
+			// User namespaces may be disabled even when bwrap is installed.
+			err = s.probe(name, "--ro-bind", "/", "/", "--unshare-all", "true")
+		case BackendPodman, BackendDocker:
+			err = s.probe(name, "version")
+		default:
+			err = fmt.Errorf("unknown backend")
+		}
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("%w (%s); install bubblewrap or podman, or set agents.defaults.sandbox.mode to \"off\"",
+		ErrUnavailable, strings.Join(reasons, ", "))
+}
+
+// Command builds an *exec.Cmd that runs a bash command inside the sandbox.
+// workdir defaults to the workspace and must lie inside it.
+func (s *Sandbox) Command(ctx context.Context, command, workdir string) (*exec.Cmd, error) {
+	if workdir == "" {
+		workdir = s.workspace
+	}
+	workdir, err := filepath.Abs(workdir)
+	if err != nil {
+		return nil, err
+	}
+	if !within(s.workspace, workdir) {
+		return nil, fmt.Errorf("workdir %s is outside the sandbox workspace %s", workdir, s.workspace)
+	}
+
+	backend, err := s.Backend()
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == BackendBwrap {
+		args := s.bwrapArgs(command, workdir)
+		return exec.CommandContext(ctx, args[0], args[1:]...), nil
+	}
+
+	name := containerName()
+	args := s.containerArgs(backend, name, command, workdir)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// Killing the runtime client leaves the container running, so a
+	// cancelled or timed-out command stops the container by name.
+	cmd.Cancel = func() error {
+		_ = s.stop(backend, name)
+		if cmd.Process == nil {
+			return nil
+		}
+		return cmd.Process.Kill()
+	}
+	return cmd, nil
+}
+
+// bwrapArgs builds a bubblewrap invocation: system directories read-only,
+// home and other user data hidden, the workspace read-write.
+func (s *Sandbox) bwrapArgs(command, workdir string) []string {
+	args := []string{BackendBwrap, "--die-with-parent", "--new-session", "--unshare-all"}
+	if s.cfg.Network {
+		args = append(args, "--share-net")
+	}
+	for _, dir := range []string{"/usr", "/bin", "/sbin", "/lib", "/lib32", "/lib64", "/etc", "/opt"} {
+		info, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Readlink(dir); err == nil {
+				args = append(args, "--symlink", target, dir)
+			}
+			continue
+		}
+		args = append(args, "--ro-bind", dir, dir)
+	}
+	for _, p := range s.cfg.ReadOnlyPaths {
+		args = append(args, "--ro-bind-try", p, p)
+	}
+	args = append(args,
+		"--proc", "/proc",
+		"--dev", "/dev",
+		"--tmpfs", "/tmp",
+		"--bind", s.workspace, s.workspace,
+		// The workspace's git metadata stays read-only: hooks or config
+		// planted there would run on the host at the next auto-commit.
+		"--ro-bind-try", filepath.Join(s.workspace, ".git"), filepath.Join(s.workspace, ".git"),
+		"--chdir", workdir,
+		"--setenv", "HOME", s.workspace,
+		"--",
+		"bash", "-c", s.limitPrefix()+command,
+	)
+	return args
+}
+
+// limitPrefix applies ulimits for backends without cgroup limits. The CPU
+// time limit backs up the wall-clock timeout for processes that outlive it.
+// ulimit -u counts all of the host user's processes; see Config.PidsLimit.
+func (s *Sandbox) limitPrefix() string {
+	var b strings.Builder
+	if s.cfg.MemoryMB > 0 {
+		fmt.Fprintf(&b, "ulimit -v %d; ", s.cfg.MemoryMB*1024)
+	}
+	if s.cfg.TimeoutSeconds > 0 {
+		cpu := float64(s.cfg.TimeoutSeconds)
+		if s.cfg.CPUs > 0 {
+			cpu *= s.cfg.CPUs
+		}
+		fmt.Fprintf(&b, "ulimit -t %d; ", max(int(math.Ceil(cpu)), 1))
+	}
+	if s.cfg.PidsLimit > 0 {
+		fmt.Fprintf(&b, "ulimit -u %d; ", s.cfg.PidsLimit)
+	}
+	return b.String()
+}
+
+// containerArgs builds a podman/docker invocation with a read-only root
+// filesystem, dropped capabilities and the workspace bind-mounted.
+func (s *Sandbox) containerArgs(runtime, name, command, workdir string) []string {
+	args := []string{runtime, "run", "--rm", "-i",
+		"--name", name,
+		"--read-only",
+		"--tmpfs", "/tmp",
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+	}
+	if !s.cfg.Network {
+		args = append(args, "--network", "none")
+	}
+	if s.cfg.CPUs > 0 {
+		args = append(args, "--cpus", fmt.Sprintf("%g", s.cfg.CPUs))
+	}
+	if s.cfg.MemoryMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", s.cfg.MemoryMB))
+	}
+	if s.cfg.PidsLimit > 0 {
+		args = append(args, "--pids-limit", fmt.Sprint(s.cfg.PidsLimit))
+	}
+	switch runtime {
+	case BackendPodman:
+		// Rootless podman: keep the host uid so workspace files stay owned by the user.
+		args = append(args, "--userns", "keep-id")
+	case BackendDocker:
+		args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+	}
+	for _, p := range s.cfg.ReadOnlyPaths {
+		args = append(args, "-v", p+":"+p+":ro")
+	}
+	image := s.cfg.Image
+	if image == "" {
+		image = DefaultImage
+	}
+	args = append(args, "-v", s.workspace+":"+s.workspace+":rw")
+	if gitDir := filepath.Join(s.workspace, ".git"); isDir(gitDir) {
+		args = append(args, "-v", gitDir+":"+gitDir+":ro")
+	}
+	args = append(args,
+		"-w", workdir,
+		"-e", "HOME="+s.workspace,
+		image,
+		"bash", "-c", command,
+	)
+	return args
+}
+
+// containerName returns a unique name for a sandbox container.
+func containerName() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "liteclaw-sandbox-" + hex.EncodeToString(b)
+}
+
+// stopContainer kills a sandbox container by name.
+func stopContainer(runtime, container string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return exec.CommandContext(ctx, runtime, "kill", container).Run()
+}
+
+// isDir reports whether path is an existing directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// within reports whether path is root or below it.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func runProbe(name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if err != nil {
+		msg := strings.TrimSpace(string(out))
+		if len(msg) > 120 {
+			msg = msg[:120]
+		}
+		if msg != "" {
+			return fmt.Errorf("%v: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
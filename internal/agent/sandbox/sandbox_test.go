@@ -0,0 +1,135 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSandbox returns a sandbox whose backend detection sees only the given binaries.
+func fakeSandbox(cfg Config, workspace string, installed ...string) *Sandbox {
+	s := New(cfg, workspace)
+	s.lookPath = func(file string) (string, error) {
+		for _, name := range installed {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+	s.probe = func(name string, args ...string) error { return nil }
+	return s
+}
+
+func TestConfigApplies(t *testing.T) {
+	assert.False(t, Config{}.Applies(SessionGroup))
+	assert.False(t, Config{Mode: ModeOff}.Applies(SessionGroup))
+	assert.True(t, Config{Mode: ModeAll}.Applies(SessionMain))
+	assert.False(t, Config{Mode: ModeNonMain}.Applies(SessionMain))
+	assert.True(t, Config{Mode: ModeNonMain}.Applies(SessionCron))
+
+	byType := Config{Mode: ModeAll, SessionTypes: []string{"group", "subagent"}}
+	assert.True(t, byType.Applies(SessionGroup))
+	assert.True(t, byType.Applies(SessionSubagent))
+	assert.False(t, byType.Applies(SessionDirect), "sessionTypes takes precedence over mode")
+}
+
+func TestClassifySession(t *testing.T) {
+	assert.Equal(t, SessionMain, ClassifySession("main", ""))
+	assert.Equal(t, SessionMain, ClassifySession("agent:main:main", ""))
+	assert.Equal(t, SessionCron, ClassifySession("cron:job1", ""))
+	assert.Equal(t, SessionSubagent, ClassifySession("subagent:main:1234abcd", ""))
+	assert.Equal(t, SessionGroup, ClassifySession("telegram:42", "group"))
+	assert.Equal(t, SessionGroup, ClassifySession("discord:42", "thread"))
+	assert.Equal(t, SessionDirect, ClassifySession("telegram:42", "direct"))
+	assert.Equal(t, SessionDirect, ClassifySession("telegram:42", ""))
+}
+
+func TestBackendDetection(t *testing.T) {
+	ws := t.TempDir()
+
+	backend, err := fakeSandbox(Config{}, ws, "docker", "podman").Backend()
+	require.NoError(t, err)
+	assert.Equal(t, BackendPodman, backend, "auto prefers bwrap, then podman")
+
+	_, err = fakeSandbox(Config{}, ws).Backend()
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrUnavailable))
+	assert.Contains(t, err.Error(), "bwrap: not installed")
+
+	s := fakeSandbox(Config{Backend: BackendBwrap}, ws, "bwrap", "podman")
+	s.probe = func(name string, args ...string) error { return errors.New("user namespaces disabled") }
+	_, err = s.Backend()
+	assert.ErrorContains(t, err, "user namespaces disabled")
+}
+
+func TestBwrapCommand(t *testing.T) {
+	ws := t.TempDir()
+	s := fakeSandbox(Config{Mode: ModeAll, MemoryMB: 256, ReadOnlyPaths: []string{"/srv/data"}}, ws, "bwrap")
+
+	cmd, err := s.Command(context.Background(), "make test", filepath.Join(ws, "sub"))
+	require.NoError(t, err)
+	args := strings.Join(cmd.Args, " ")
+
+	assert.Equal(t, "bwrap", cmd.Args[0])
+	assert.Contains(t, args, "--unshare-all")
+	assert.NotContains(t, args, "--share-net")
+	assert.Contains(t, args, "--bind "+ws+" "+ws)
+	assert.Contains(t, args, "--ro-bind-try "+filepath.Join(ws, ".git")+" "+filepath.Join(ws, ".git"))
+	assert.Contains(t, args, "--chdir "+filepath.Join(ws, "sub"))
+	assert.Contains(t, args, "--ro-bind-try /srv/data /srv/data")
+	assert.Equal(t, "ulimit -v 262144; make test", cmd.Args[len(cmd.Args)-1])
+
+	_, err = s.Command(context.Background(), "ls", t.TempDir())
+	assert.ErrorContains(t, err, "outside the sandbox workspace")
+}
+
+func TestBwrapLimits(t *testing.T) {
+	ws := t.TempDir()
+	s := fakeSandbox(Config{Mode: ModeAll, CPUs: 0.5, MemoryMB: 256, PidsLimit: 64, TimeoutSeconds: 30}, ws, "bwrap")
+
+	cmd, err := s.Command(context.Background(), "make", "")
+	require.NoError(t, err)
+	assert.Equal(t, "ulimit -v 262144; ulimit -t 15; ulimit -u 64; make", cmd.Args[len(cmd.Args)-1])
+}
+
+func TestContainerCommand(t *testing.T) {
+	ws := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(ws, ".git"), 0o755))
+	s := fakeSandbox(Config{Backend: BackendPodman, CPUs: 0.5, MemoryMB: 512, PidsLimit: 64, Network: true}, ws, "podman")
+
+	cmd, err := s.Command(context.Background(), "echo hi", "")
+	require.NoError(t, err)
+	args := strings.Join(cmd.Args, " ")
+
+	assert.Regexp(t, `^podman run --rm -i --name liteclaw-sandbox-[0-9a-f]{16} --read-only`, args)
+	assert.NotContains(t, args, "--network none")
+	assert.Contains(t, args, "--cpus 0.5 --memory 512m --pids-limit 64")
+	assert.Contains(t, args, "-v "+ws+":"+ws+":rw -v "+ws+"/.git:"+ws+"/.git:ro -w "+ws)
+	assert.Contains(t, args, DefaultImage+" bash -c echo hi")
+
+	// Cancelling the command stops the container, not just the client.
+	var stopped []string
+	s.stop = func(runtime, container string) error {
+		stopped = append(stopped, runtime, container)
+		return nil
+	}
+	require.NotNil(t, cmd.Cancel)
+	require.NoError(t, cmd.Cancel())
+	assert.Equal(t, []string{"podman", cmd.Args[5]}, stopped)
+}
+
+func TestTimeoutCap(t *testing.T) {
+	s := New(Config{TimeoutSeconds: 30}, t.TempDir())
+	assert.Equal(t, 30*time.Second, s.Timeout(time.Minute))
+	assert.Equal(t, 10*time.Second, s.Timeout(10*time.Second))
+	assert.Equal(t, time.Minute, New(Config{}, t.TempDir()).Timeout(time.Minute))
+}
@@ -3,16 +3,29 @@ package agent
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/google/uuid"
+	"github.com/liteclaw/liteclaw/internal/agent/customtool"
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 	"github.com/liteclaw/liteclaw/internal/agent/prompt"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
 	"github.com/liteclaw/liteclaw/internal/agent/skills"
 	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/agent/transcribe"
 	"github.com/liteclaw/liteclaw/internal/agent/workspace"
+	"github.com/liteclaw/liteclaw/internal/browser"
+	"github.com/liteclaw/liteclaw/internal/canvas"
+	"github.com/liteclaw/liteclaw/internal/channels"
 	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/liteclaw/liteclaw/internal/cron"
 	mcp "github.com/liteclaw/liteclaw/mcp"
@@ -24,6 +37,29 @@ type Service struct {
 	Agent     *Agent
 	Scheduler *cron.Scheduler
 	Verbose   bool
+
+	// TTS synthesises spoken replies; see SpeakReply.
+	TTS *tools.TtsTool
+	// Transcriber converts inbound audio to text; nil when not configured.
+	Transcriber transcribe.Transcriber
+	// Browsers runs the browser tool's managed profiles.
+	Browsers *browser.Manager
+	// Canvas hosts the per-session canvases the canvas tool renders to
+	// once the gateway serves it; see UseCanvas.
+	Canvas *canvas.Host
+
+	// canvasTool and nodesTool reach remote nodes; see UseNodes.
+	canvasTool *tools.CanvasTool
+	nodesTool  *tools.NodesTool
+	// gatewayTool manages the gateway; see UseGateway.
+	gatewayTool *tools.GatewayTool
+	// notify delivers background notices to a session; see UseNotices.
+	notify func(sessionKey, text string)
+
+	// spawnSlots bounds concurrently running sub-agent sessions.
+	spawnSlots chan struct{}
+	// stop ends the service's background loops; see Close.
+	stop context.CancelFunc
 }
 
 func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
@@ -41,11 +77,9 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 		}
 	}
 
-	// 2. Determine Provider
-	var provider llm.Provider
-	var model string
-
-	// Parse Primary Model from Config (e.g. "minimax/MiniMax-M2.1")
+	// 1. Resolve the primary model through the provider registry.
+	// Per-run overrides (sessions, cron payloads, sub-agents) are resolved
+	// through the same registry at run time.
 	primaryStr := cfg.Agents.Defaults.Model.Primary
 	if primaryStr == "" {
 		if !cfg.Wizard.Mock { // If not in a mock/test state
@@ -54,89 +88,29 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 		}
 	}
 
-	parts := strings.SplitN(primaryStr, "/", 2)
-	if len(parts) != 2 {
-		fmt.Printf("Config Error: Invalid model format '%s'. Expected 'provider/model' (e.g. 'minimax/MiniMax-M2.1')\n", primaryStr)
-		os.Exit(1)
-	}
-
-	providerName := parts[0]
-	model = parts[1]
-
-	// 1. Check Provider Config
-	p, ok := cfg.Models.Providers[providerName]
-	if !ok {
-		fmt.Printf("Config Error: Provider '%s' is not defined in 'models.providers' section of liteclaw.json\n", providerName)
-		os.Exit(1)
+	registry := NewModelRegistry(cfg)
+	registry.Verbose = cfg.Logging.Verbose
+	registry.Auth = NewAuthStore(AuthStatePath())
+	registry.Auth.BaseCooldown = authCooldown(cfg.Auth)
+	if _, err := os.Stat(config.ConfigPath()); err == nil {
+		registry.WatchConfig(config.ConfigPath())
 	}
 
-	baseURL := p.BaseURL
-	if baseURL == "" {
-		fmt.Printf("Config Error: baseUrl for provider '%s' is empty. Please set it in 'models.providers.%s.baseUrl'\n", providerName, providerName)
+	primary, err := registry.Resolve(primaryStr)
+	if err != nil {
+		fmt.Printf("Config Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// 2. Check API Key
-	// Convention: PROVIDERNAME_API_KEY (e.g. MINIMAX_API_KEY)
-	targetKey := strings.ToUpper(providerName) + "_API_KEY"
-	apiKey := os.Getenv(targetKey)
-
-	// Fallback to cfg.Env (case-insensitive)
-	if apiKey == "" {
-		for k, v := range cfg.Env {
-			if strings.EqualFold(k, targetKey) {
-				apiKey = v
-				break
-			}
-		}
-	}
-
-	if apiKey == "" {
-		// Special case: Ollama typically doesn't require API key
-		if strings.EqualFold(providerName, "ollama") {
-			apiKey = "ollama" // Use placeholder
-		} else {
-			fmt.Printf("Config Error: API Key '%s' is missing.\n", targetKey)
-			fmt.Printf("  To set it, run: liteclaw models auth login %s\n", providerName)
-			fmt.Printf("  Or add '%s' to the 'env' section of liteclaw.json\n", targetKey)
-			os.Exit(1)
-		}
-	}
-
-	// 3. Init Provider
-	// Default behavior based on config "api" field
-	switch p.API {
-	case "anthropic-messages":
-		prov := llm.NewAnthropicProvider(apiKey, baseURL)
-		prov.Verbose = cfg.Logging.Verbose
-		provider = prov
-	case "openai-completions", "":
-		// Default to OpenAI
-		prov := llm.NewOpenAIProviderWithConfig(apiKey, baseURL)
-		prov.Verbose = cfg.Logging.Verbose
-		provider = prov
-	default:
-		// Fallback or error? For now default to OpenAI to be safe
-		fmt.Printf("Warning: Unknown API type '%s' for provider '%s'. Defaulting to OpenAI.\n", p.API, providerName)
-		prov := llm.NewOpenAIProviderWithConfig(apiKey, baseURL)
-		prov.Verbose = cfg.Logging.Verbose
-		provider = prov
-	}
+	model := primary.Model
 
 	// Create Agent
-	ag := New("main", "LiteClaw", model, provider)
+	ag := New("main", "LiteClaw", model, primary.Client)
+	ag.Models = registry
 	ag.Policy = cfg.Agents.Defaults.Tools
 	ag.Stream = cfg.Agents.Defaults.Stream
-	// Try to resolve max tokens from model config
-	maxTokens := 4096
-	for _, m := range p.Models {
-		if m.ID == model && m.MaxTokens > 0 {
-			maxTokens = m.MaxTokens
-			break
-		}
-	}
+	ag.Compaction = cfg.Agents.Defaults.Compaction
 	// We'll pass this in ChatRequest during agent.Run
-	ag.MaxTokens = maxTokens
+	ag.MaxTokens = primary.MaxTokens
 	ag.Temperature = 0.7
 	ag.LogSystemPrompt = cfg.Logging.PrintSystemPrompt
 
@@ -145,9 +119,13 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 	if workspaceDir == "" {
 		workspaceDir = workspace.ResolveDefaultDir()
 	}
-	if err := workspace.EnsureWorkspace(workspaceDir); err != nil {
-		if cfg.Logging.Verbose {
-			fmt.Printf("Failed to ensure workspace: %v\n", err)
+	workspaceRepo, err := workspace.EnsureWorkspace(workspaceDir, cfg.Agents.Defaults.Git)
+	if err != nil && (cfg.Logging.Verbose || cfg.Agents.Defaults.Git.Enabled) {
+		fmt.Printf("Failed to ensure workspace: %v\n", err)
+	}
+	if workspaceRepo != nil && cfg.Agents.Defaults.Git.AutoCommitEnabled() {
+		ag.AfterRun = func(run RunInfo) {
+			commitRun(workspaceRepo, run)
 		}
 	}
 
@@ -201,7 +179,7 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 		// Run agent
 		// Note: We might need a fresh context if the job ctx is cancelled too early,
 		// but usually job ctx is tied to the task duration.
-		stream, err := ag.Run(ctx, sessionID, text)
+		stream, err := ag.RunWithOptions(ctx, sessionID, text, RunOptions{Model: job.Payload.Model})
 		if err != nil {
 			return err
 		}
@@ -266,47 +244,227 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 
 	sched.Start()
 
+	subagentSlots := cfg.Agents.Defaults.Subagents.MaxConcurrent
+	if subagentSlots <= 0 {
+		subagentSlots = 8
+	}
+	svc := &Service{
+		Config:     cfg,
+		Agent:      ag,
+		Scheduler:  sched,
+		Verbose:    cfg.Logging.Verbose,
+		spawnSlots: make(chan struct{}, subagentSlots),
+	}
+
+	spawnTool := tools.NewSessionsSpawnTool()
+	spawnTool.Spawner = svc
+
+	// Background exec sessions log under the state dir and report their exit
+	// to the session that started them: the notice is delivered right away
+	// and queued so the agent sees it on the session's next turn.
+	procs := tools.Processes()
+	procs.LogDir = filepath.Join(config.StateDir(), "processes")
+	procs.OnExit = func(p tools.ProcessInfo) {
+		if p.SessionKey == "" {
+			return
+		}
+		text := describeProcessExit(p)
+		ag.EnqueueSystemEvent(p.SessionKey, text)
+		if svc.notify != nil {
+			svc.notify(p.SessionKey, text)
+		}
+	}
+
+	execTool := tools.NewExecTool()
+	execTool.Sandbox = newSandbox(cfg.Agents.Defaults.Sandbox, workspaceDir)
+	for _, a := range cfg.Agents.List {
+		if a.Sandbox != nil {
+			if execTool.AgentSandboxes == nil {
+				execTool.AgentSandboxes = make(map[string]*sandbox.Sandbox)
+			}
+			execTool.AgentSandboxes[a.ID] = newSandbox(*a.Sandbox, workspaceDir)
+		}
+	}
+
+	// Skill directories: workspace/skills, managed skills (~/.liteclaw/skills), and repo-root/skills (dev mode)
+	cwd, _ := os.Getwd()
+	repoSkillsDir := filepath.Join(cwd, "../skills")
+	moltGoSkillsDir := filepath.Join(cwd, "skills")
+
+	// Managed skills directory - where `skill install` puts downloaded skills
+	managedSkillsDir := filepath.Join(config.StateDir(), "skills")
+
+	skillDirs := []string{managedSkillsDir, moltGoSkillsDir, repoSkillsDir}
+	guard := fileGuard(cfg.Agents.Defaults.Paths, workspaceDir, skillDirs...)
+	agentGuards := make(map[string]*pathguard.Guard)
+	for _, a := range cfg.Agents.List {
+		if a.Paths != nil {
+			agentGuards[a.ID] = fileGuard(*a.Paths, workspaceDir, skillDirs...)
+		}
+	}
+	ag.PathGuard = sessionGuard(guard, agentGuards)
+	readTool, writeTool, editTool, listTool := tools.NewReadTool(), tools.NewWriteTool(), tools.NewEditTool(), tools.NewListTool()
+	readTool.Paths, writeTool.Paths, editTool.Paths, listTool.Paths = guard, guard, guard, guard
+	patchTool := tools.NewApplyPatchTool()
+	patchTool.Paths = guard
+	docReadTool := tools.NewDocReadTool()
+	docReadTool.Paths = guard
+
+	// Memory search ranks memory files, and optionally session transcripts,
+	// with BM25 plus recency; an embedding provider adds vector similarity.
+	memCfg := cfg.Agents.Defaults.Memory
+	memIndex := memory.NewIndex(workspaceDir, filepath.Join(config.StateDir(), "agents", "main", "sessions"), memCfg)
+	if emb := memCfg.Embeddings; emb.Provider != "" {
+		baseURL := ""
+		if p, ok := cfg.Models.Providers[emb.Provider]; ok {
+			baseURL = p.BaseURL
+		}
+		memIndex.Embedder = memory.NewOpenAIEmbedder(ProviderAPIKey(cfg, emb.Provider), baseURL, emb.Model)
+	}
+	memorySearchTool := tools.NewMemorySearchTool(workspaceDir)
+	memorySearchTool.Index = memIndex
+	memoryGetTool := tools.NewMemoryGetTool(workspaceDir)
+	memoryGetTool.TranscriptsDir = memIndex.TranscriptsDir()
+	memoryGetTool.Paths = guard
+	memoryAppendTool := tools.NewMemoryAppendTool(workspaceDir)
+	memoryAppendTool.Index = memIndex
+	memoryUpdateTool := tools.NewMemoryUpdateTool(workspaceDir)
+	memoryUpdateTool.Index = memIndex
+
+	// web_fetch caches extracted pages under the state dir and refuses
+	// private addresses unless allowlisted.
+	fetchCfg := cfg.Tools.Web.Fetch
+	fetchTool := tools.NewWebFetchTool()
+	fetchTool.CacheDir = filepath.Join(config.StateDir(), "cache", "web")
+	fetchTool.Net = netguard.New(fetchCfg.AllowPrivate)
+	if fetchCfg.MaxBytes > 0 {
+		fetchTool.MaxBytes = fetchCfg.MaxBytes
+	}
+	if fetchCfg.MaxChars > 0 {
+		fetchTool.MaxChars = fetchCfg.MaxChars
+	}
+	if fetchCfg.CacheTTLMinutes != 0 {
+		fetchTool.CacheTTL = time.Duration(fetchCfg.CacheTTLMinutes) * time.Minute
+	}
+
+	// tts writes audio into the workspace so replies can attach it.
+	ttsCfg := cfg.Tools.TTS
+	ttsTool := tools.NewTtsTool()
+	ttsTool.Engine = ttsEngine(cfg)
+	ttsTool.OutputDir = filepath.Join(workspaceDir, "media", "tts")
+	ttsTool.DefaultVoice = ttsCfg.Voice
+	if ttsCfg.Format != "" {
+		ttsTool.DefaultFormat = ttsCfg.Format
+	} else if _, ok := ttsTool.Engine.(*tools.CommandSpeech); ok {
+		ttsTool.DefaultFormat = "wav"
+	}
+	svc.TTS = ttsTool
+	svc.Transcriber = newTranscriber(cfg)
+
+	// image sends pictures to tools.image.model, or to the primary model
+	// when it accepts image input.
+	imageCfg := cfg.Tools.Image
+	imageTool := tools.NewImageTool(workspaceDir)
+	imageTool.Net = fetchTool.Net
+	imageTool.Paths = guard
+	imageTool.Resolve = func() (*tools.VisionModel, error) {
+		return resolveVisionModel(registry)
+	}
+	if imageCfg.MaxBytes > 0 {
+		imageTool.MaxDownloadBytes = imageCfg.MaxBytes
+	}
+	if imageCfg.MaxDimension > 0 {
+		imageTool.MaxDimension = imageCfg.MaxDimension
+	}
+	if imageCfg.MaxImages > 0 {
+		imageTool.MaxImages = imageCfg.MaxImages
+	}
+
+	// image_generate saves pictures into the workspace; replies attach them.
+	genCfg := cfg.Tools.ImageGenerate
+	imageGenTool := tools.NewImageGenerateTool(workspaceDir)
+	imageGenTool.Generator = imageGenerator(cfg)
+	imageGenTool.DefaultSize = genCfg.Size
+	imageGenTool.DefaultQuality = genCfg.Quality
+	imageGenTool.Net = fetchTool.Net
+	imageGenTool.Paths = guard
+
+	messageTool := tools.NewMessageTool(sender)
+	messageTool.AgentDir = workspaceDir
+	messageTool.Paths = guard
+
+	// browser drives managed Chromium profiles directly and sends
+	// extension profiles to the relay.
+	browserCfg := cfg.Tools.Browser
+	svc.Browsers = browser.NewManager(browserProfiles(cfg, workspaceDir))
+	browserTool := tools.NewBrowserTool()
+	browserTool.Managed = svc.Browsers
+	browserTool.DefaultProfile = browserCfg.DefaultProfile
+	if browserTool.DefaultProfile == "" {
+		browserTool.DefaultProfile = "liteclaw"
+	}
+	browserTool.ScreenshotDir = filepath.Join(workspaceDir, "media", "browser")
+	browserTool.AgentDir = workspaceDir
+	browserTool.Paths = guard
+	browserTool.Net = netguard.New(browserCfg.AllowPrivate)
+
+	svc.Canvas = canvas.NewHost(filepath.Join(workspaceDir, "canvas"))
+	svc.Canvas.Capture = canvasCapture(svc.Browsers, browserTool.DefaultProfile)
+	gatewayTool := tools.NewGatewayTool()
+	gatewayTool.AllowChanges = cfg.Tools.Gateway.AllowChanges
+	svc.gatewayTool = gatewayTool
+
+	canvasTool := tools.NewCanvasTool()
+	nodesTool := tools.NewNodesTool()
+	nodesTool.Sandbox, nodesTool.AgentSandboxes = execTool.Sandbox, execTool.AgentSandboxes
+	svc.canvasTool, svc.nodesTool = canvasTool, nodesTool
+
+	searchTool := tools.NewWebSearchTool()
+	searchTool.Backends = webSearchBackends(cfg)
+	if n := cfg.Tools.Web.Search.Count; n > 0 {
+		searchTool.DefaultCount = n
+	}
+
 	// Register Tools
 	ag.RegisterTools(
-		tools.NewExecTool(),
-		tools.NewReadTool(),
-		tools.NewWriteTool(),
-		tools.NewEditTool(),
-		tools.NewListTool(),
-		tools.NewMemorySearchTool(workspaceDir),
-		tools.NewMemoryGetTool(workspaceDir),
+		execTool,
+		readTool,
+		writeTool,
+		editTool,
+		patchTool,
+		listTool,
+		docReadTool,
+		memorySearchTool,
+		memoryGetTool,
+		memoryAppendTool,
+		memoryUpdateTool,
 		// New tools for prompt parity
-		tools.NewGatewayTool(),
-		tools.NewMessageTool(sender),
+		gatewayTool,
+		messageTool,
 		tools.NewAgentsListTool(),
 		tools.NewSessionStatusTool(),
 		tools.NewSessionsListTool(),
 		tools.NewSessionsSendTool(),
 		tools.NewSessionsHistoryTool(),
-		tools.NewSessionsSpawnTool(),
-		tools.NewWebSearchTool(),
-		tools.NewWebFetchTool(),
+		spawnTool,
+		searchTool,
+		fetchTool,
 		tools.NewProcessTool(),
-		tools.NewBrowserTool(),
-		tools.NewCanvasTool(),
-		tools.NewNodesTool(),
+		browserTool,
+		canvasTool,
+		nodesTool,
 		tools.NewCronTool(sched),
-		tools.NewTtsTool(),
-		tools.NewImageTool(workspaceDir),
+		ttsTool,
+		imageTool,
+		imageGenTool,
 	)
-
-	// Dynamically extract tool names
-	toolNames := ag.ExtractToolNames()
+	if workspaceRepo != nil {
+		historyTool := tools.NewWorkspaceHistoryTool(workspaceRepo)
+		historyTool.Index = memIndex
+		ag.RegisterTools(historyTool)
+	}
 
 	// Load Skills
-	// Scan workspace/skills, managed skills (~/.liteclaw/skills), and repo-root/skills (dev mode)
-	cwd, _ := os.Getwd()
-	repoSkillsDir := filepath.Join(cwd, "../skills")
-	moltGoSkillsDir := filepath.Join(cwd, "skills")
-
-	// Managed skills directory - where `skill install` puts downloaded skills
-	managedSkillsDir := filepath.Join(config.StateDir(), "skills")
-
 	skillLoader := skills.NewLoader(
 		filepath.Join(workspaceDir, "skills"), // workspace skills (~/clawd/skills)
 		managedSkillsDir,                      // managed skills (~/.liteclaw/skills) - from ClawdHub
@@ -338,6 +496,13 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 
 	skillsPrompt := skills.FormatForPrompt(eligibleSkills)
 
+	// Custom tools from tools.custom and skill frontmatter; they share the
+	// tool policy and exec's sandbox.
+	ag.RegisterTools(customTools(cfg, eligibleSkills, ag.Tools, workspaceDir, execTool)...)
+
+	// Dynamically extract tool names
+	toolNames := ag.ExtractToolNames()
+
 	// Build Model Aliases
 	var modelAliases []string
 	if cfg.Agents.Defaults.Models != nil {
@@ -356,7 +521,7 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 		WithSkillsPrompt(skillsPrompt).
 		WithModelAliases(modelAliases).
 		WithDocsPath("/opt/clawdbot/docs").
-		WithWorkspaceNotes([]string{"Reminder: commit your changes in this workspace after edits."}).
+		WithWorkspaceNotes(workspaceNotes(workspaceRepo, cfg.Agents.Defaults.Git)).
 		WithReasoningTagHint(false).
 		WithConfig("off"). // Default reasoning
 		WithRuntimeInfo(prompt.RuntimeInfo{
@@ -382,26 +547,176 @@ func NewService(cfg *config.Config, sender tools.MessageSender) *Service {
 
 	ag.Verbose = cfg.Logging.Verbose
 
-	return &Service{
-		Config:    cfg,
-		Agent:     ag,
-		Scheduler: sched,
-		Verbose:   cfg.Logging.Verbose,
+	// Persist durable facts from sessions that have gone quiet.
+	bg, stop := context.WithCancel(context.Background())
+	svc.stop = stop
+	go ag.RunIdleMemoryFlush(bg)
+
+	return svc
+}
+
+// customTools builds the tools declared in tools.custom and in skills.
+// Config tools run in the workspace and skill tools in the skill's
+// directory; a tool whose name is already taken is skipped.
+func customTools(cfg *config.Config, loaded []*skills.Skill, builtin []tools.Tool, workspaceDir string, exec *tools.ExecTool) []tools.Tool {
+	taken := make(map[string]bool)
+	for _, t := range builtin {
+		taken[t.Name()] = true
+	}
+	var out []tools.Tool
+	add := func(spec customtool.Spec, dir, source string) {
+		if err := spec.Validate(); err != nil {
+			fmt.Printf("Warning: skipping custom tool from %s: %v\n", source, err)
+			return
+		}
+		if taken[spec.Name] {
+			fmt.Printf("Warning: skipping custom tool %q from %s: the name is already in use\n", spec.Name, source)
+			return
+		}
+		taken[spec.Name] = true
+		t := tools.NewCustomTool(spec)
+		t.Dir, t.Source = dir, source
+		t.Sandbox, t.AgentSandboxes = exec.Sandbox, exec.AgentSandboxes
+		out = append(out, t)
+	}
+	for _, spec := range cfg.Tools.Custom {
+		add(spec, workspaceDir, "config")
+	}
+	for _, sk := range loaded {
+		for _, spec := range sk.Tools {
+			add(spec, filepath.Dir(sk.Location), "skill "+sk.Name)
+		}
+	}
+	return out
+}
+
+// workspaceNotes tells the model how its workspace edits are tracked.
+func workspaceNotes(repo *workspace.Repo, git workspace.GitConfig) []string {
+	switch {
+	case repo == nil:
+		return nil
+	case git.AutoCommitEnabled():
+		return []string{"This workspace is a git repository. Files you change with write, edit, apply_patch and the memory tools are committed automatically after each run; commit changes made through exec yourself. Use workspace_history to review them or revert a file."}
+	default:
+		return []string{"This workspace is a git repository. Reminder: commit your changes in this workspace after edits; use workspace_history to review them or revert a file."}
+	}
+}
+
+// commitRun commits the workspace files the run's file-writing tools
+// changed, recording the session and run in the message. Other changes,
+// such as files written by exec, are left for the agent or user to commit.
+func commitRun(repo *workspace.Repo, run RunInfo) {
+	if len(run.Files) == 0 {
+		return
+	}
+	short := run.RunID
+	if len(short) > 8 {
+		short = short[:8]
+	}
+	message := fmt.Sprintf("Agent run %s in %s\n\nSession: %s\nRun: %s\nTools: %s",
+		short, run.SessionID, run.SessionID, run.RunID, strings.Join(run.Tools, ", "))
+	if _, err := repo.CommitFiles(message, run.Files); err != nil {
+		fmt.Printf("Warning: failed to commit workspace changes for run %s: %v\n", run.RunID, err)
+	}
+}
+
+// newSandbox returns a sandbox for cfg, or nil when it never applies.
+func newSandbox(cfg config.SandboxConfig, workspaceDir string) *sandbox.Sandbox {
+	if (cfg.Mode == "" || cfg.Mode == sandbox.ModeOff) && len(cfg.SessionTypes) == 0 {
+		return nil
+	}
+	return sandbox.New(sandbox.Config(cfg), workspaceDir)
+}
+
+// fileGuard confines file tools to the workspace and configured roots.
+// Skill docs stay readable; the rest of the state dir, inbound channel media
+// included, and the config file are protected. See sessionGuard.
+func fileGuard(cfg config.PathsConfig, workspaceDir string, skillDirs ...string) *pathguard.Guard {
+	paths := pathguard.Config(cfg)
+	paths.ReadRoots = append(append([]string(nil), paths.ReadRoots...), skillDirs...)
+	// The workspace's .git is off limits: hooks or config written there
+	// would run on the host at the next auto-commit.
+	return pathguard.New(paths, workspaceDir, config.StateDir(), config.ConfigPath(), filepath.Join(workspaceDir, ".git"))
+}
+
+// sessionGuard picks the guard of the session's agent, falling back to
+// def, and lets a channel session read the media its peer sent. The main
+// session belongs to the operator and reads all inbound media.
+func sessionGuard(def *pathguard.Guard, byAgent map[string]*pathguard.Guard) func(tools.SessionContext) *pathguard.Guard {
+	inbound := channels.InboundMediaDir("", "")
+	return func(sc tools.SessionContext) *pathguard.Guard {
+		guard := def
+		if g, ok := byAgent[sc.AgentID]; ok {
+			guard = g
+		}
+		if sc.Type == sandbox.SessionMain {
+			return guard.WithReadRoots(inbound)
+		}
+		if dir := channels.SessionMediaDir(sc.Key); dir != "" {
+			return guard.WithReadRoots(dir)
+		}
+		return guard
 	}
 }
 
+// Close stops the idle memory flush, the cron scheduler, the managed
+// browsers and any background processes.
+func (s *Service) Close() {
+	if s.stop != nil {
+		s.stop()
+	}
+	if s.Scheduler != nil {
+		s.Scheduler.Stop()
+	}
+	if s.Browsers != nil {
+		s.Browsers.Close()
+	}
+	tools.Processes().KillAll()
+}
+
+// UseNodes routes the canvas and nodes tools through the gateway's node
+// registry.
+func (s *Service) UseNodes(nodes tools.NodeInvoker) {
+	s.canvasTool.Nodes = nodes
+	s.nodesTool.Nodes = nodes
+}
+
+// UseCanvas routes the canvas tool to the canvas host, served at baseURL
+// and opened with accessKey when the gateway requires auth.
+func (s *Service) UseCanvas(baseURL, accessKey string) {
+	s.Canvas.BaseURL = baseURL
+	s.Canvas.AccessKey = accessKey
+	s.canvasTool.Canvas = s.Canvas
+}
+
+// UseGateway lets the gateway tool inspect and manage the running gateway.
+func (s *Service) UseGateway(gw tools.GatewayControl) {
+	s.gatewayTool.Gateway = gw
+}
+
+// UseNotices delivers background notices, such as a process exiting, to
+// the session's history and chat without waiting for its next turn.
+func (s *Service) UseNotices(notify func(sessionKey, text string)) {
+	s.notify = notify
+}
+
 func (s *Service) GetScheduler() *cron.Scheduler {
 	return s.Scheduler
 }
 
 // ProcessChat handles the chat.send request
 func (s *Service) ProcessChat(ctx context.Context, sessionID, message string, onDelta func(string)) error {
-	if s.Agent.Provider == nil {
+	return s.ProcessChatWithOptions(ctx, sessionID, message, RunOptions{}, onDelta)
+}
+
+// ProcessChatWithOptions handles a chat turn with per-run options (e.g. a model override).
+func (s *Service) ProcessChatWithOptions(ctx context.Context, sessionID, message string, opts RunOptions, onDelta func(string)) error {
+	if s.Agent.Provider == nil && s.Agent.Models == nil {
 		onDelta("No API keys configured (MINIMAX_API_KEY or OPENAI_API_KEY). Echo: " + message)
 		return nil
 	}
 
-	events, err := s.Agent.Run(ctx, sessionID, message)
+	events, err := s.Agent.RunWithOptions(ctx, sessionID, message, opts)
 	if err != nil {
 		return err
 	}
@@ -420,7 +735,15 @@ func (s *Service) ProcessChat(ctx context.Context, sessionID, message string, on
 		case "tool_call":
 			// Tool calls are silent to the user
 		case "tool_result":
-			// Tool results are silent to the user
+			// Tool results are silent to the user, but media they carry
+			// (e.g. tts audio) goes out with the reply.
+			if opts.OnAttachment != nil && event.ToolResult != nil && event.ToolResult.Error == "" {
+				if r, ok := event.ToolResult.Result.(tools.AttachmentResult); ok {
+					for _, a := range r.ReplyAttachments() {
+						opts.OnAttachment(a)
+					}
+				}
+			}
 		case "error":
 			return fmt.Errorf("agent error: %s", event.Error)
 		}
@@ -429,6 +752,75 @@ func (s *Service) ProcessChat(ctx context.Context, sessionID, message string, on
 	return nil
 }
 
+// TranscribeAudio converts an inbound audio file to text. It returns
+// transcribe.ErrNotConfigured when tools.transcription is not set up.
+func (s *Service) TranscribeAudio(ctx context.Context, path string) (*transcribe.Transcript, error) {
+	if s.Transcriber == nil {
+		return nil, transcribe.ErrNotConfigured
+	}
+	return s.Transcriber.Transcribe(ctx, path)
+}
+
+// DefaultAutoTTSMaxChars is the longest reply spoken by auto-TTS.
+const DefaultAutoTTSMaxChars = 1500
+
+// SpeakReply synthesises a voice note for an assistant reply when
+// tools.tts.auto asks for one; inboundVoice reports whether the user's
+// message was itself audio. It returns nil when no voice note is due.
+func (s *Service) SpeakReply(ctx context.Context, reply string, inboundVoice bool) (*tools.MediaAttachment, error) {
+	if s.TTS == nil || s.Config == nil {
+		return nil, nil
+	}
+	tc := s.Config.Tools.TTS
+	switch strings.ToLower(tc.Auto) {
+	case "always":
+	case "inbound":
+		if !inboundVoice {
+			return nil, nil
+		}
+	default:
+		return nil, nil
+	}
+
+	spoken := speakableText(reply)
+	maxChars := tc.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultAutoTTSMaxChars
+	}
+	if spoken == "" || len([]rune(spoken)) > maxChars {
+		return nil, nil
+	}
+	res, err := s.TTS.Speak(ctx, tools.SpeechRequest{Text: spoken}, true)
+	if err != nil {
+		return nil, err
+	}
+	a := res.ReplyAttachments()[0]
+	return &a, nil
+}
+
+var (
+	speechCodeBlock = regexp.MustCompile("(?s)```.*?```")
+	speechLink      = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	speechURL       = regexp.MustCompile(`https?://\S+`)
+	speechMarkup    = regexp.MustCompile("(?m)^[ \t]*(#{1,6}|>|[-*+]|\\d+\\.)[ \t]+|[*_~`]+")
+)
+
+// speakableText strips markdown, code blocks and URLs from a reply so it
+// reads naturally aloud.
+func speakableText(s string) string {
+	s = speechCodeBlock.ReplaceAllString(s, "")
+	s = speechLink.ReplaceAllString(s, "$1")
+	s = speechURL.ReplaceAllString(s, "")
+	s = speechMarkup.ReplaceAllString(s, "")
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 // LoadSessionHistory loads persisted history into the agent's session.
 // Call this before ProcessChat to restore conversation context.
 func (s *Service) LoadSessionHistory(sessionID string, messages []Message) {
@@ -437,6 +829,95 @@ func (s *Service) LoadSessionHistory(sessionID string, messages []Message) {
 	}
 }
 
+// SetSessionModel sets a per-session model override ("provider/model" or alias)
+// and returns the canonical ref. An empty ref clears the override.
+func (s *Service) SetSessionModel(sessionID, ref string) (string, error) {
+	if s.Agent == nil {
+		return "", fmt.Errorf("agent not initialized")
+	}
+	return s.Agent.SetSessionModel(sessionID, ref)
+}
+
+// SessionModel returns the effective model for a session.
+func (s *Service) SessionModel(sessionID string) string {
+	if s.Agent == nil {
+		return ""
+	}
+	return s.Agent.SessionModel(sessionID)
+}
+
+// SetSessionAuthProfile pins a session to an auth profile. An empty ID clears the pin.
+func (s *Service) SetSessionAuthProfile(sessionID, profileID string) error {
+	if s.Agent == nil {
+		return fmt.Errorf("agent not initialized")
+	}
+	return s.Agent.SetSessionAuthProfile(sessionID, profileID)
+}
+
+// SessionAuthProfile returns the auth profile pinned for a session, if any.
+func (s *Service) SessionAuthProfile(sessionID string) string {
+	if s.Agent == nil {
+		return ""
+	}
+	return s.Agent.SessionAuthProfile(sessionID)
+}
+
+// SpawnSession implements tools.SessionSpawner. The sub-agent runs in the
+// background in its own session, using the requested model if any.
+func (s *Service) SpawnSession(ctx context.Context, req tools.SpawnRequest) (*tools.SessionsSpawnResult, error) {
+	if req.Model != "" && s.Agent.Models != nil {
+		resolved, err := s.Agent.Models.Resolve(req.Model)
+		if err != nil {
+			return nil, fmt.Errorf("invalid model: %w", err)
+		}
+		req.Model = resolved.Ref
+	}
+
+	agentID := req.AgentID
+	if agentID == "" {
+		agentID = s.Agent.ID
+	}
+	runID := uuid.New().String()
+	sessionKey := fmt.Sprintf("subagent:%s:%s", agentID, runID[:8])
+
+	select {
+	case s.spawnSlots <- struct{}{}:
+	default:
+		return nil, fmt.Errorf("too many sub-agents running (max %d)", cap(s.spawnSlots))
+	}
+
+	events, err := s.Agent.RunWithOptions(context.Background(), sessionKey, req.Message, RunOptions{
+		Model:        req.Model,
+		SystemPrompt: req.SystemPrompt,
+		RunID:        runID,
+	})
+	if err != nil {
+		<-s.spawnSlots
+		return nil, err
+	}
+
+	go func() {
+		defer func() { <-s.spawnSlots }()
+		for evt := range events {
+			if evt.Type == "error" && s.Verbose {
+				fmt.Printf("[SUBAGENT %s] Error: %s\n", sessionKey, evt.Error)
+			}
+		}
+	}()
+
+	model := req.Model
+	if model == "" {
+		model = s.SessionModel(sessionKey)
+	}
+	return &tools.SessionsSpawnResult{
+		RunID:      runID,
+		SessionKey: sessionKey,
+		Label:      req.Label,
+		Model:      model,
+		Status:     "spawned",
+	}, nil
+}
+
 // HasSession checks if the agent has this session in memory.
 func (s *Service) HasSession(sessionID string) bool {
 	if s.Agent != nil {
@@ -445,6 +926,272 @@ func (s *Service) HasSession(sessionID string) bool {
 	return false
 }
 
+// webSearchBackends builds the web_search backends from tools.web.search:
+// the primary provider followed by its fallbacks. Without a search block the
+// tool keeps its Brave default.
+func webSearchBackends(cfg *config.Config) []tools.SearchBackend {
+	sc := cfg.Tools.Web.Search
+	if sc.Provider == "" && len(sc.Fallback) == 0 {
+		return nil
+	}
+	primary := sc.Provider
+	if primary == "" {
+		primary = "brave"
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	var backends []tools.SearchBackend
+	seen := make(map[string]bool)
+	for _, name := range append([]string{primary}, sc.Fallback...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case "brave":
+			key := sc.Brave.APIKey
+			if key == "" {
+				key = lookupEnv(cfg, "BRAVE_API_KEY")
+			}
+			backends = append(backends, &tools.BraveSearch{APIKey: key, BaseURL: sc.Brave.BaseURL, Client: client})
+		case "searxng":
+			backends = append(backends, &tools.SearXNGSearch{BaseURL: sc.SearXNG.BaseURL, Params: sc.SearXNG.Params, Client: client})
+		case "tavily":
+			key := sc.Tavily.APIKey
+			if key == "" {
+				key = lookupEnv(cfg, "TAVILY_API_KEY")
+			}
+			backends = append(backends, &tools.TavilySearch{APIKey: key, BaseURL: sc.Tavily.BaseURL, Params: sc.Tavily.Params, Client: client})
+		case "model":
+			m := sc.Model
+			if m.Provider != "" {
+				if p, ok := cfg.Models.Providers[m.Provider]; ok && m.BaseURL == "" {
+					m.BaseURL = p.BaseURL
+				}
+				if m.APIKey == "" {
+					m.APIKey = ProviderAPIKey(cfg, m.Provider)
+				}
+			}
+			backends = append(backends, &tools.ModelSearch{APIKey: m.APIKey, BaseURL: m.BaseURL, Model: m.Model, Params: m.Params, Client: client})
+		default:
+			fmt.Printf("Warning: unknown web search provider %q in tools.web.search\n", name)
+		}
+	}
+	return backends
+}
+
+// resolveVisionModel resolves tools.image.model, falling back to the
+// primary model when its entry declares image input.
+func resolveVisionModel(registry *ModelRegistry) (*tools.VisionModel, error) {
+	ref := registry.Config().Tools.Image.Model
+	resolved, err := registry.Resolve(ref)
+	if err != nil {
+		return nil, fmt.Errorf("vision model: %w", err)
+	}
+	if ref == "" && !slices.Contains(resolved.Entry.Input, "image") {
+		return nil, fmt.Errorf("primary model %s does not list image input; set tools.image.model to a vision model", resolved.Ref)
+	}
+	return &tools.VisionModel{
+		Client:    resolved.Client,
+		Ref:       resolved.Ref,
+		Model:     resolved.Model,
+		MaxTokens: resolved.MaxTokens,
+	}, nil
+}
+
+// ttsEngine builds the tts engine from tools.tts.
+func ttsEngine(cfg *config.Config) tools.TtsEngine {
+	tc := cfg.Tools.TTS
+	switch strings.ToLower(tc.Engine) {
+	case "command":
+		cc := tc.Command
+		return &tools.CommandSpeech{
+			Command: cc.Command,
+			Args:    cc.Args,
+			Format:  cc.Format,
+			Env:     cc.Env,
+			Timeout: time.Duration(cc.TimeoutSeconds) * time.Second,
+		}
+	case "", "openai":
+		oc := tc.OpenAI
+		if oc.Provider != "" {
+			if p, ok := cfg.Models.Providers[oc.Provider]; ok && oc.BaseURL == "" {
+				oc.BaseURL = p.BaseURL
+			}
+			if oc.APIKey == "" {
+				oc.APIKey = ProviderAPIKey(cfg, oc.Provider)
+			}
+		}
+		if oc.APIKey == "" {
+			oc.APIKey = lookupEnv(cfg, "OPENAI_API_KEY")
+		}
+		return &tools.OpenAISpeech{
+			APIKey:  oc.APIKey,
+			BaseURL: oc.BaseURL,
+			Model:   oc.Model,
+			Client:  &http.Client{Timeout: 60 * time.Second},
+		}
+	default:
+		fmt.Printf("Warning: unknown tts engine %q in tools.tts\n", tc.Engine)
+		return nil
+	}
+}
+
+// imageGenerator builds the image_generate backend from
+// tools.imageGenerate.
+func imageGenerator(cfg *config.Config) tools.ImageGenerator {
+	gc := cfg.Tools.ImageGenerate
+	if gc.Provider != "" {
+		if p, ok := cfg.Models.Providers[gc.Provider]; ok && gc.BaseURL == "" {
+			gc.BaseURL = p.BaseURL
+		}
+		if gc.APIKey == "" {
+			gc.APIKey = ProviderAPIKey(cfg, gc.Provider)
+		}
+	}
+	if gc.APIKey == "" {
+		gc.APIKey = lookupEnv(cfg, "OPENAI_API_KEY")
+	}
+	timeout := 3 * time.Minute
+	if gc.TimeoutSeconds > 0 {
+		timeout = time.Duration(gc.TimeoutSeconds) * time.Second
+	}
+	return &tools.OpenAIImages{
+		APIKey:  gc.APIKey,
+		BaseURL: gc.BaseURL,
+		Model:   gc.Model,
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// browserProfiles builds the managed browser profiles: the built-in
+// liteclaw profile plus every tools.browser.profiles entry that is not an
+// extension profile. Each keeps its user data under the state dir.
+func browserProfiles(cfg *config.Config, workspaceDir string) map[string]browser.Config {
+	bc := cfg.Tools.Browser
+	timeout := 30 * time.Second
+	if bc.TimeoutSeconds > 0 {
+		timeout = time.Duration(bc.TimeoutSeconds) * time.Second
+	}
+	headless := bc.Headless == nil || *bc.Headless
+
+	configured := map[string]config.BrowserProfileConfig{"liteclaw": {}}
+	for name, pc := range bc.Profiles {
+		configured[name] = pc
+	}
+	profiles := make(map[string]browser.Config)
+	for name, pc := range configured {
+		if pc.Driver != "" && pc.Driver != "managed" {
+			continue
+		}
+		bcfg := browser.Config{
+			ControlURL:  pc.CDPURL,
+			Headless:    headless,
+			Timeout:     timeout,
+			ExecPath:    bc.ExecutablePath,
+			UserDataDir: filepath.Join(config.StateDir(), "browser", name, "user-data"),
+			DownloadDir: filepath.Join(workspaceDir, "downloads"),
+		}
+		if pc.Headless != nil {
+			bcfg.Headless = *pc.Headless
+		}
+		if pc.ExecutablePath != "" {
+			bcfg.ExecPath = pc.ExecutablePath
+		}
+		profiles[name] = bcfg
+	}
+	return profiles
+}
+
+// canvasCapture screenshots canvas pages in the given managed browser
+// profile (falling back to the built-in one), or returns nil when neither
+// is managed.
+func canvasCapture(browsers *browser.Manager, profile string) func(ctx context.Context, url, format string) ([]byte, error) {
+	if !browsers.Has(profile) {
+		profile = "liteclaw"
+	}
+	if !browsers.Has(profile) {
+		return nil
+	}
+	return func(ctx context.Context, url, format string) ([]byte, error) {
+		b, err := browsers.Get(ctx, profile)
+		if err != nil {
+			return nil, fmt.Errorf("canvas snapshot needs the headless browser: %w", err)
+		}
+		tab, err := b.Open(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = b.CloseTab(context.Background(), tab.ID) }()
+
+		// Give the viewer time to connect and render replayed frames.
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return b.Screenshot(ctx, tab.ID, "", true, format)
+	}
+}
+
+// newTranscriber builds the inbound audio transcriber from
+// tools.transcription, or returns nil when none is configured.
+func newTranscriber(cfg *config.Config) transcribe.Transcriber {
+	tc := cfg.Tools.Transcription
+	switch strings.ToLower(tc.Engine) {
+	case "":
+		return nil
+	case "command":
+		cc := tc.Command
+		return &transcribe.Command{
+			Command:  cc.Command,
+			Args:     cc.Args,
+			Language: tc.Language,
+			Env:      cc.Env,
+			Timeout:  time.Duration(cc.TimeoutSeconds) * time.Second,
+		}
+	case "openai":
+		oc := tc.OpenAI
+		if oc.Provider != "" {
+			if p, ok := cfg.Models.Providers[oc.Provider]; ok && oc.BaseURL == "" {
+				oc.BaseURL = p.BaseURL
+			}
+			if oc.APIKey == "" {
+				oc.APIKey = ProviderAPIKey(cfg, oc.Provider)
+			}
+		}
+		if oc.APIKey == "" {
+			oc.APIKey = lookupEnv(cfg, "OPENAI_API_KEY")
+		}
+		return &transcribe.OpenAI{
+			APIKey:   oc.APIKey,
+			BaseURL:  oc.BaseURL,
+			Model:    oc.Model,
+			Language: tc.Language,
+			Prompt:   oc.Prompt,
+			Client:   &http.Client{Timeout: 2 * time.Minute},
+		}
+	default:
+		fmt.Printf("Warning: unknown transcription engine %q in tools.transcription\n", tc.Engine)
+		return nil
+	}
+}
+
+// describeProcessExit formats a background process exit notice.
+func describeProcessExit(p tools.ProcessInfo) string {
+	command := p.Command
+	if len(command) > 80 {
+		command = command[:77] + "..."
+	}
+	switch p.Status {
+	case tools.ProcessKilled:
+		return fmt.Sprintf("Background process %s (%s) was killed.", p.ID, command)
+	default:
+		return fmt.Sprintf("Background process %s (%s) exited with code %d. Read its output with the process tool.", p.ID, command, p.ExitCode)
+	}
+}
+
 func sanitizeModelOutput(raw string) string {
 	if strings.Contains(raw, "<final>") {
 		return extractFinalContent(raw)
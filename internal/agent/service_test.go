@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/channels"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSpeech records what it was asked to say.
+type fakeSpeech struct {
+	said []string
+}
+
+func (f *fakeSpeech) Name() string { return "fake" }
+
+func (f *fakeSpeech) Synthesize(ctx context.Context, req tools.SpeechRequest) (*tools.Speech, error) {
+	f.said = append(f.said, req.Text)
+	return &tools.Speech{Data: []byte("audio"), Format: "mp3"}, nil
+}
+
+func TestSpeakableText(t *testing.T) {
+	in := "# Result\n\nSee **[the docs](https://example.com/docs)** at https://example.com.\n\n```go\nfmt.Println()\n```\n- one `item`"
+	assert.Equal(t, "Result\nSee the docs at\none item", speakableText(in))
+}
+
+func TestService_SpeakReply(t *testing.T) {
+	engine := &fakeSpeech{}
+	tts := tools.NewTtsTool()
+	tts.Engine = engine
+	tts.OutputDir = t.TempDir()
+	svc := &Service{Config: &config.Config{}, TTS: tts}
+	ctx := context.Background()
+
+	a, err := svc.SpeakReply(ctx, "hello", true)
+	require.NoError(t, err)
+	assert.Nil(t, a, "auto-TTS is off by default")
+
+	svc.Config.Tools.TTS.Auto = "inbound"
+	a, err = svc.SpeakReply(ctx, "hello", false)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+	a, err = svc.SpeakReply(ctx, "*hello*", true)
+	require.NoError(t, err)
+	require.NotNil(t, a)
+	assert.True(t, a.Voice)
+	assert.Equal(t, "audio/mpeg", a.MimeType)
+
+	svc.Config.Tools.TTS.Auto = "always"
+	svc.Config.Tools.TTS.MaxChars = 10
+	a, err = svc.SpeakReply(ctx, "this reply is too long to speak", false)
+	require.NoError(t, err)
+	assert.Nil(t, a)
+
+	assert.Equal(t, []string{"hello"}, engine.said)
+}
+
+func TestSessionGuardScopesInboundMedia(t *testing.T) {
+	state := t.TempDir()
+	t.Setenv("LITECLAW_STATE_DIR", state)
+	write := func(peer, text string) string {
+		dir := channels.InboundMediaDir("telegram", peer)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		path := filepath.Join(dir, "notes.txt")
+		require.NoError(t, os.WriteFile(path, []byte(text), 0o644))
+		return path
+	}
+	mine, theirs := write("42", "inbound notes"), write("7", "someone else's notes")
+	require.NoError(t, os.WriteFile(filepath.Join(state, "secrets.txt"), []byte("secret"), 0o600))
+
+	docRead := tools.NewDocReadTool()
+	docRead.Paths = fileGuard(config.PathsConfig{}, t.TempDir())
+	guardFor := sessionGuard(docRead.Paths, nil)
+	session := func(key, typ string) context.Context {
+		sc := tools.SessionContext{Key: key, Type: typ}
+		sc.Paths = guardFor(sc)
+		return tools.WithSession(context.Background(), sc)
+	}
+	read := func(ctx context.Context, path string) error {
+		_, err := docRead.Execute(ctx, map[string]interface{}{"path": path})
+		return err
+	}
+
+	peer := session("telegram:42", sandbox.SessionDirect)
+	assert.NoError(t, read(peer, mine))
+	assert.Error(t, read(peer, theirs), "a session only reads its own peer's media")
+	assert.Error(t, read(peer, filepath.Join(state, "secrets.txt")), "the rest of the state dir stays protected")
+	assert.Error(t, read(context.Background(), mine), "without a session no inbound media is readable")
+
+	main := session("main", sandbox.SessionMain)
+	assert.NoError(t, read(main, mine))
+	assert.NoError(t, read(main, theirs))
+}
+
+func TestSessionGuardUsesAgentPaths(t *testing.T) {
+	t.Setenv("LITECLAW_STATE_DIR", t.TempDir())
+	ws, shared := t.TempDir(), t.TempDir()
+	notes := filepath.Join(shared, "notes.md")
+	require.NoError(t, os.WriteFile(notes, []byte("shared"), 0o644))
+
+	def := fileGuard(config.PathsConfig{}, ws)
+	guardFor := sessionGuard(def, map[string]*pathguard.Guard{
+		"research": fileGuard(config.PathsConfig{ReadRoots: []string{shared}}, ws),
+	})
+
+	_, err := guardFor(tools.SessionContext{Key: "main", Type: sandbox.SessionMain, AgentID: "main"}).Resolve(notes, pathguard.Read)
+	assert.Error(t, err, "agents without paths use agents.defaults.paths")
+	_, err = guardFor(tools.SessionContext{Key: "sub", Type: sandbox.SessionSubagent, AgentID: "research"}).Resolve(notes, pathguard.Read)
+	assert.NoError(t, err)
+}
+
+func TestFileGuardDeniesWorkspaceGit(t *testing.T) {
+	t.Setenv("LITECLAW_STATE_DIR", t.TempDir())
+	ws := t.TempDir()
+	guard := fileGuard(config.PathsConfig{}, ws)
+
+	_, err := guard.Resolve(filepath.Join(ws, ".git", "hooks", "post-commit"), pathguard.Write)
+	assert.Error(t, err)
+	_, err = guard.Resolve(filepath.Join(ws, "notes.md"), pathguard.Write)
+	assert.NoError(t, err)
+}
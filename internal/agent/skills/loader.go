@@ -9,6 +9,8 @@ import (
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/liteclaw/liteclaw/internal/agent/customtool"
 )
 
 // Skill represents a loaded skill.
@@ -19,6 +21,9 @@ type Skill struct {
 	Location    string            `json:"location"`
 	Content     string            `json:"content,omitempty"`
 	Metadata    *ClawdbotMetadata `json:"metadata,omitempty"`
+	// Tools are custom tools the skill declares in its frontmatter; they
+	// run in the skill's directory.
+	Tools []customtool.Spec `yaml:"tools" json:"tools,omitempty"`
 }
 
 // ClawdbotMetadata holds Clawdbot-specific skill metadata.
@@ -123,10 +128,11 @@ func ParseSkill(content string) (*Skill, error) {
 
 	// Parse frontmatter - metadata can be a string or a map
 	var fm struct {
-		Name        string      `yaml:"name"`
-		Description string      `yaml:"description"`
-		Homepage    string      `yaml:"homepage"`
-		Metadata    interface{} `yaml:"metadata"` // Can be string or map
+		Name        string            `yaml:"name"`
+		Description string            `yaml:"description"`
+		Homepage    string            `yaml:"homepage"`
+		Metadata    interface{}       `yaml:"metadata"` // Can be string or map
+		Tools       []customtool.Spec `yaml:"tools"`
 	}
 
 	if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
@@ -138,6 +144,7 @@ func ParseSkill(content string) (*Skill, error) {
 		Description: fm.Description,
 		Homepage:    fm.Homepage,
 		Content:     body,
+		Tools:       fm.Tools,
 	}
 
 	// Parse metadata - handle both string and map formats
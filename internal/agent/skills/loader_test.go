@@ -67,6 +67,41 @@ Manage containers.
 	}
 }
 
+func TestParseSkillWithTools(t *testing.T) {
+	content := `---
+name: issues
+description: Issue tracker
+tools:
+  - name: issue_search
+    description: Search issues
+    parameters:
+      type: object
+      properties:
+        query: {type: string}
+      required: [query]
+    args: ["./search.sh", "{{query}}"]
+    timeoutSeconds: 10
+---
+
+# Issues
+`
+
+	skill, err := ParseSkill(content)
+	if err != nil {
+		t.Fatalf("Failed to parse skill: %v", err)
+	}
+	if len(skill.Tools) != 1 {
+		t.Fatalf("Expected 1 tool, got %d", len(skill.Tools))
+	}
+	tool := skill.Tools[0]
+	if tool.Name != "issue_search" || len(tool.Args) != 2 || tool.TimeoutSeconds != 10 {
+		t.Errorf("Unexpected tool %+v", tool)
+	}
+	if err := tool.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
 func TestLoadSkill(t *testing.T) {
 	// Create temp directory
 	dir := t.TempDir()
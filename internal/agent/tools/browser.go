@@ -7,12 +7,20 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+	"github.com/liteclaw/liteclaw/internal/browser"
 )
 
-// BrowserTool controls browser instances via a control server.
+// BrowserTool controls browser instances: managed profiles drive Chromium
+// directly over CDP, other profiles go through the control server and the
+// extension relay.
 type BrowserTool struct {
 	// DefaultControlURL is the default browser control server URL.
 	DefaultControlURL string
@@ -22,8 +30,22 @@ type BrowserTool struct {
 	AllowedControlHosts []string
 	// Timeout is the request timeout.
 	Timeout time.Duration
-	// ScreenshotDir is where screenshots are saved.
+	// ScreenshotDir is where screenshots and PDFs are saved.
 	ScreenshotDir string
+	// Managed runs the managed profiles; nil sends every profile to the
+	// control server.
+	Managed *browser.Manager
+	// DefaultProfile is used when a call names no profile.
+	DefaultProfile string
+	// AgentDir resolves relative upload paths.
+	AgentDir string
+	// Paths, when set, confines uploads to the agent's readable roots.
+	Paths *pathguard.Guard
+	// Net decides which hosts open and navigate may load; nil blocks
+	// private addresses.
+	Net *netguard.Guard
+	// MaxSnapshotChars caps managed snapshots.
+	MaxSnapshotChars int
 }
 
 // NewBrowserTool creates a new browser tool.
@@ -39,6 +61,7 @@ func NewBrowserTool() *BrowserTool {
 		AllowHostControl:  os.Getenv("ALLOW_HOST_BROWSER_CONTROL") == "true",
 		Timeout:           30 * time.Second,
 		ScreenshotDir:     os.TempDir(),
+		MaxSnapshotChars:  DefaultBrowserSnapshotChars,
 	}
 }
 
@@ -51,6 +74,8 @@ func (t *BrowserTool) Name() string {
 func (t *BrowserTool) Description() string {
 	return `Control browser instances for web automation and testing.
 
+PROFILES: managed profiles (default "liteclaw") run a headless Chromium with their own user data; "chrome" drives the user's browser through the extension relay.
+
 ACTIONS:
 - status: Get browser and profile status
 - start: Start the browser for a profile
@@ -61,20 +86,18 @@ ACTIONS:
 - focus: Focus a specific tab
 - close: Close a tab or the current page
 - navigate: Navigate a tab to a URL
-- snapshot: Get page DOM snapshot (accessibility tree/AI snapshot)
+- snapshot: Get the page's accessibility tree; each element has a ref like [ref=e12] (refs expire when the page navigates)
 - screenshot: Capture page or element screenshot
-- click: Click an element (via /act)
-- type: Type text into an element (via /act)
-- evaluate: Execute JavaScript (via /act)
-- wait: Wait for selector, text, or timeout (via /act)
-- resize: Resize the browser window (via /act)
+- act: Interact with the page; kind is click, type, press, hover, drag, select, evaluate, wait or resize
+- click, type, press, hover, drag, select, evaluate, wait, resize: Shorthands for act
 - console: Get recent console messages
 - pdf: Save the current page as PDF
-- upload: Handle file upload dialogs (file chooser hook)
-- dialog: Handle JS alert/confirm/prompt dialogs (dialog hook)
+- upload: Set files on a file input, or arm the next file chooser
+- dialog: Accept or dismiss the open (or next) JS alert/confirm/prompt
+- downloads: List files the managed browser downloaded
 
-Use snapshot to understand page structure before interacting.
-Element identifiers (ref) can be accessibility labels or IDs from snapshot.`
+Use snapshot to understand page structure before interacting, then pass
+element refs from it (e.g. ref: "e12") to act. CSS selectors also work.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -84,7 +107,29 @@ func (t *BrowserTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"description": "Browser action: tabs, open, close, navigate, snapshot, screenshot, click, type",
+				"description": "Browser action",
+				"enum": []string{"status", "start", "stop", "profiles", "tabs", "open", "focus", "close", "navigate", "snapshot", "screenshot",
+					"act", "click", "type", "press", "hover", "drag", "select", "evaluate", "wait", "resize", "console", "pdf", "upload", "dialog", "downloads"},
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "Interaction for act: click, type, press, hover, drag, select, evaluate, wait, resize",
+			},
+			"ref": map[string]interface{}{
+				"type":        "string",
+				"description": "Element ref from the last snapshot, e.g. e12",
+			},
+			"endRef": map[string]interface{}{
+				"type":        "string",
+				"description": "Drop target ref for drag",
+			},
+			"interactive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Snapshot only interactive elements",
+			},
+			"maxChars": map[string]interface{}{
+				"type":        "number",
+				"description": "Snapshot size limit in characters",
 			},
 			"tabId": map[string]interface{}{
 				"type":        "string",
@@ -96,15 +141,69 @@ func (t *BrowserTool) Parameters() interface{} {
 			},
 			"selector": map[string]interface{}{
 				"type":        "string",
-				"description": "CSS selector or accessibility label (ref) for actions",
+				"description": "CSS selector, when no ref fits",
 			},
 			"text": map[string]interface{}{
 				"type":        "string",
-				"description": "Text for type action",
+				"description": "Text to type, or to wait for",
+			},
+			"submit": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Press Enter after typing",
+			},
+			"key": map[string]interface{}{
+				"type":        "string",
+				"description": "Key for press, e.g. Enter, Escape, ArrowDown, Control+A",
+			},
+			"values": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Option values or labels for select",
+			},
+			"script": map[string]interface{}{
+				"type":        "string",
+				"description": "JavaScript for evaluate: an expression or function (given the element when a selector is set)",
+			},
+			"fullPage": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Capture the full page in screenshot",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"description": "Screenshot format: png or jpeg",
+			},
+			"paths": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Files for upload",
+			},
+			"accept": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Accept (true) or dismiss (false) a dialog",
+			},
+			"promptText": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to enter in a prompt dialog",
+			},
+			"timeMs": map[string]interface{}{
+				"type":        "number",
+				"description": "Milliseconds to wait",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "number",
+				"description": "Action timeout in milliseconds",
+			},
+			"width": map[string]interface{}{
+				"type":        "number",
+				"description": "Viewport width for resize",
+			},
+			"height": map[string]interface{}{
+				"type":        "number",
+				"description": "Viewport height for resize",
 			},
 			"profile": map[string]interface{}{
 				"type":        "string",
-				"description": "Browser profile (e.g., 'chrome' for extension)",
+				"description": "Browser profile: a managed profile such as 'liteclaw', or 'chrome' for the extension relay",
 			},
 		},
 		"required": []string{"action"},
@@ -123,6 +222,7 @@ type BrowserTab struct {
 type BrowserResult struct {
 	Action     string       `json:"action"`
 	Success    bool         `json:"success"`
+	Profile    string       `json:"profile,omitempty"`
 	TabID      string       `json:"tabId,omitempty"`
 	Tabs       []BrowserTab `json:"tabs,omitempty"`
 	URL        string       `json:"url,omitempty"`
@@ -141,6 +241,23 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		return nil, fmt.Errorf("action is required")
 	}
 
+	profile, _ := params["profile"].(string)
+	if profile == "" {
+		profile = t.DefaultProfile
+	}
+	if action == "open" || action == "navigate" {
+		target, _ := params["url"].(string)
+		if target == "" {
+			target, _ = params["targetUrl"].(string)
+		}
+		if err := t.checkURL(ctx, target); err != nil {
+			return nil, err
+		}
+	}
+	if t.Managed != nil && t.Managed.Has(profile) && action != "profiles" {
+		return t.executeManaged(ctx, profile, action, params)
+	}
+
 	controlURL := t.DefaultControlURL
 	if url, ok := params["controlUrl"].(string); ok && url != "" {
 		controlURL = url
@@ -151,8 +268,6 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		return nil, fmt.Errorf("browser control URL not configured. Set BROWSER_CONTROL_URL or provide controlUrl parameter")
 	}
 
-	profile, _ := params["profile"].(string)
-
 	switch action {
 	case "status":
 		return t.getStatus(ctx, controlURL, profile)
@@ -182,69 +297,18 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 		return t.navigate(ctx, controlURL, profile, tabID, url)
 	case "snapshot":
 		tabID, _ := params["tabId"].(string)
-		return t.getSnapshot(ctx, controlURL, profile, tabID)
+		return t.getSnapshot(ctx, controlURL, profile, tabID, t.snapshotOptions(params))
 	case "screenshot":
 		tabID, _ := params["tabId"].(string)
 		format, _ := params["format"].(string)
 		fullPage, _ := params["fullPage"].(bool)
 		return t.takeScreenshot(ctx, controlURL, profile, tabID, format, fullPage)
-	case "click":
-		selector, _ := params["selector"].(string)
-		if selector == "" {
-			selector, _ = params["ref"].(string)
-		}
+	case "act", "click", "type", "press", "hover", "drag", "select", "evaluate", "wait", "resize":
 		tabID, _ := params["tabId"].(string)
-		return t.act(ctx, controlURL, profile, "click", map[string]interface{}{
-			"targetId": tabID,
-			"ref":      selector,
-		})
-	case "type":
-		selector, _ := params["selector"].(string)
-		if selector == "" {
-			selector, _ = params["ref"].(string)
-		}
-		text, _ := params["text"].(string)
-		tabID, _ := params["tabId"].(string)
-		return t.act(ctx, controlURL, profile, "type", map[string]interface{}{
-			"targetId": tabID,
-			"ref":      selector,
-			"text":     text,
-		})
-	case "evaluate":
-		script, _ := params["script"].(string)
-		if script == "" {
-			script, _ = params["fn"].(string)
-		}
-		tabID, _ := params["tabId"].(string)
-		return t.act(ctx, controlURL, profile, "evaluate", map[string]interface{}{
-			"targetId": tabID,
-			"fn":       script,
-		})
-	case "wait":
-		selector, _ := params["selector"].(string)
-		text, _ := params["text"].(string)
-		timeout, _ := params["timeout"].(float64)
-		tabID, _ := params["tabId"].(string)
-		waitParams := map[string]interface{}{
-			"targetId": tabID,
-		}
-		if selector != "" {
-			waitParams["selector"] = selector
-		}
-		if text != "" {
-			waitParams["text"] = text
-		}
-		if timeout > 0 {
-			waitParams["timeoutMs"] = int(timeout)
-		}
-		return t.act(ctx, controlURL, profile, "wait", waitParams)
-	case "resize":
-		width, _ := params["width"].(float64)
-		height, _ := params["height"].(float64)
-		return t.act(ctx, controlURL, profile, "resize", map[string]interface{}{
-			"width":  int(width),
-			"height": int(height),
-		})
+		req := browserActRequest(action, params)
+		return t.act(ctx, controlURL, profile, req.Kind, relayActBody(tabID, req))
+	case "downloads":
+		return nil, fmt.Errorf("downloads are only tracked for managed profiles")
 	case "console":
 		tabID, _ := params["tabId"].(string)
 		return t.getConsole(ctx, controlURL, profile, tabID)
@@ -271,6 +335,30 @@ func (t *BrowserTool) Execute(ctx context.Context, params map[string]interface{}
 	}
 }
 
+// checkURL refuses URLs the browser may not load: anything but http(s), and
+// hosts that resolve to private addresses unless Net allows them. An empty
+// URL opens a blank tab.
+func (t *BrowserTool) checkURL(ctx context.Context, raw string) error {
+	if raw == "" || raw == "about:blank" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q: only http and https can be opened", u.Scheme)
+	}
+	guard := t.Net
+	if guard == nil {
+		guard = netguard.New(nil)
+	}
+	if err := guard.Check(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("refusing to open %s: %w", u.Host, err)
+	}
+	return nil
+}
+
 // Browser control server communication
 
 func (t *BrowserTool) sendCommand(ctx context.Context, controlURL, method, path string, payload interface{}, profile string) (map[string]interface{}, error) {
@@ -446,10 +534,20 @@ func (t *BrowserTool) navigate(ctx context.Context, controlURL, profile, tabID,
 	}, nil
 }
 
-func (t *BrowserTool) getSnapshot(ctx context.Context, controlURL, profile, tabID string) (*BrowserResult, error) {
-	path := "/snapshot"
+func (t *BrowserTool) getSnapshot(ctx context.Context, controlURL, profile, tabID string, opts browser.SnapshotOptions) (*BrowserResult, error) {
+	query := url.Values{}
 	if tabID != "" {
-		path += "?targetId=" + tabID
+		query.Set("targetId", tabID)
+	}
+	if opts.MaxChars > 0 {
+		query.Set("maxChars", strconv.Itoa(opts.MaxChars))
+	}
+	if opts.Interactive {
+		query.Set("interactive", "true")
+	}
+	path := "/snapshot"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
 	}
 	resp, err := t.sendCommand(ctx, controlURL, "GET", path, nil, profile)
 	if err != nil {
@@ -544,14 +642,36 @@ func (t *BrowserTool) stopBrowser(ctx context.Context, controlURL, profile strin
 
 func (t *BrowserTool) listProfiles(ctx context.Context, controlURL string) (*BrowserResult, error) {
 	resp, err := t.sendCommand(ctx, controlURL, "GET", "/profiles", nil, "")
-	if err != nil {
-		return nil, err
+	if t.Managed == nil {
+		if err != nil {
+			return nil, err
+		}
+		return &BrowserResult{
+			Action:  "profiles",
+			Success: true,
+			Result:  resp["profiles"],
+		}, nil
 	}
 
+	managed := []browser.ProfileStatus{}
+	for _, name := range t.Managed.Profiles() {
+		if st, err := t.Managed.Status(ctx, name); err == nil {
+			managed = append(managed, st)
+		}
+	}
+	result := map[string]interface{}{
+		"default": t.DefaultProfile,
+		"managed": managed,
+	}
+	if err != nil {
+		result["extensionError"] = err.Error()
+	} else {
+		result["extension"] = resp["profiles"]
+	}
 	return &BrowserResult{
 		Action:  "profiles",
 		Success: true,
-		Result:  resp["profiles"],
+		Result:  result,
 	}, nil
 }
 
@@ -0,0 +1,312 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+	"github.com/liteclaw/liteclaw/internal/browser"
+)
+
+// DefaultBrowserSnapshotChars caps the accessibility tree returned by
+// snapshot.
+const DefaultBrowserSnapshotChars = browser.DefaultSnapshotChars
+
+// executeManaged runs action against a managed profile's browser.
+func (t *BrowserTool) executeManaged(ctx context.Context, profile, action string, params map[string]interface{}) (*BrowserResult, error) {
+	switch action {
+	case "status":
+		return t.managedStatus(ctx, profile)
+	case "start":
+		if _, err := t.Managed.Get(ctx, profile); err != nil {
+			return nil, err
+		}
+		return t.managedStatus(ctx, profile)
+	case "stop":
+		if err := t.Managed.Stop(profile); err != nil {
+			return nil, err
+		}
+		return t.managedStatus(ctx, profile)
+	}
+
+	b, err := t.Managed.Get(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	tabID, _ := params["tabId"].(string)
+	res := &BrowserResult{Action: action, Success: true, Profile: profile, TabID: tabID}
+
+	switch action {
+	case "tabs":
+		tabs, err := b.Tabs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		res.Tabs = make([]BrowserTab, 0, len(tabs))
+		for _, tab := range tabs {
+			res.Tabs = append(res.Tabs, BrowserTab{ID: tab.ID, URL: tab.URL, Title: tab.Title, Active: tab.Active})
+		}
+
+	case "open":
+		url, _ := params["url"].(string)
+		if url == "" {
+			url, _ = params["targetUrl"].(string)
+		}
+		info, err := b.Open(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		res.setTab(info)
+
+	case "focus":
+		if tabID == "" {
+			return nil, fmt.Errorf("tabId is required")
+		}
+		if err := b.Focus(ctx, tabID); err != nil {
+			return nil, err
+		}
+
+	case "close":
+		if err := b.CloseTab(ctx, tabID); err != nil {
+			return nil, err
+		}
+
+	case "navigate":
+		url, _ := params["url"].(string)
+		if url == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		info, err := b.Navigate(ctx, tabID, url)
+		if err != nil {
+			return nil, err
+		}
+		res.setTab(info)
+
+	case "snapshot":
+		info, text, err := b.Snapshot(ctx, tabID, t.snapshotOptions(params))
+		if err != nil {
+			return nil, err
+		}
+		res.setTab(info)
+		res.Snapshot = text
+
+	case "screenshot":
+		selector, _ := params["selector"].(string)
+		fullPage, _ := params["fullPage"].(bool)
+		format, _ := params["format"].(string)
+		if format == "jpg" {
+			format = "jpeg"
+		}
+		if format != "jpeg" {
+			format = "png"
+		}
+		data, err := b.Screenshot(ctx, tabID, selector, fullPage, format)
+		if err != nil {
+			return nil, err
+		}
+		ext := format
+		if ext == "jpeg" {
+			ext = "jpg"
+		}
+		if res.FilePath, err = t.saveCapture(data, "screenshot", ext); err != nil {
+			return nil, err
+		}
+
+	case "pdf":
+		data, err := b.PDF(ctx, tabID)
+		if err != nil {
+			return nil, err
+		}
+		if res.FilePath, err = t.saveCapture(data, "page", "pdf"); err != nil {
+			return nil, err
+		}
+
+	case "act", "click", "type", "press", "hover", "drag", "select", "evaluate", "wait", "resize":
+		req := browserActRequest(action, params)
+		res.Action = req.Kind
+		if res.Result, err = b.Act(ctx, tabID, req); err != nil {
+			return nil, err
+		}
+
+	case "console":
+		messages, err := b.Console(ctx, tabID)
+		if err != nil {
+			return nil, err
+		}
+		res.Result = messages
+
+	case "upload":
+		selector, _ := params["selector"].(string)
+		if selector == "" {
+			selector, _ = params["ref"].(string)
+		}
+		var paths []string
+		for _, p := range stringList(params["paths"]) {
+			if sessionPaths(ctx, t.Paths) == nil && !filepath.IsAbs(p) && t.AgentDir != "" {
+				p = filepath.Join(t.AgentDir, p)
+			}
+			resolved, err := resolvePath(ctx, t.Paths, p, pathguard.Read)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(resolved); err != nil {
+				return nil, fmt.Errorf("upload file: %w", err)
+			}
+			paths = append(paths, resolved)
+		}
+		if len(paths) == 0 {
+			return nil, fmt.Errorf("paths is required")
+		}
+		if err := b.Upload(ctx, tabID, selector, paths); err != nil {
+			return nil, err
+		}
+		res.Result = paths
+
+	case "dialog":
+		accept, _ := params["accept"].(bool)
+		promptText, _ := params["promptText"].(string)
+		message, err := b.Dialog(ctx, tabID, accept, promptText)
+		if err != nil {
+			return nil, err
+		}
+		if message != "" {
+			res.Result = map[string]interface{}{"message": message}
+		}
+
+	case "downloads":
+		res.Result = b.Downloads()
+
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+	return res, nil
+}
+
+func (t *BrowserTool) managedStatus(ctx context.Context, profile string) (*BrowserResult, error) {
+	st, err := t.Managed.Status(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	return &BrowserResult{
+		Action:  "status",
+		Success: true,
+		Profile: profile,
+		Result:  st,
+	}, nil
+}
+
+// saveCapture writes a screenshot or PDF to ScreenshotDir.
+func (t *BrowserTool) saveCapture(data []byte, prefix, ext string) (string, error) {
+	dir := t.ScreenshotDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.%s", prefix, time.Now().Format("20060102-150405.000"), ext))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to save %s: %w", prefix, err)
+	}
+	return path, nil
+}
+
+func (t *BrowserTool) snapshotOptions(params map[string]interface{}) browser.SnapshotOptions {
+	opts := browser.SnapshotOptions{MaxChars: t.MaxSnapshotChars}
+	if n := intParam(params["maxChars"]); n > 0 {
+		opts.MaxChars = n
+	}
+	opts.Interactive, _ = params["interactive"].(bool)
+	return opts
+}
+
+func (r *BrowserResult) setTab(info browser.TabInfo) {
+	r.TabID = info.ID
+	r.URL = info.URL
+	r.Title = info.Title
+}
+
+// browserActRequest builds an act request from tool params; action is
+// the shorthand used, or "act" to read the kind from params.
+func browserActRequest(action string, params map[string]interface{}) browser.ActRequest {
+	req := browser.ActRequest{Kind: action}
+	if action == "act" {
+		req.Kind, _ = params["kind"].(string)
+	}
+	for _, key := range []string{"ref", "selector", "startRef"} {
+		if req.Selector == "" {
+			req.Selector, _ = params[key].(string)
+		}
+	}
+	req.EndSelector, _ = params["endRef"].(string)
+	if req.EndSelector == "" {
+		req.EndSelector, _ = params["endSelector"].(string)
+	}
+	req.Text, _ = params["text"].(string)
+	req.Submit, _ = params["submit"].(bool)
+	req.DoubleClick, _ = params["doubleClick"].(bool)
+	req.Key, _ = params["key"].(string)
+	req.Values = stringList(params["values"])
+	req.Fn, _ = params["script"].(string)
+	if req.Fn == "" {
+		req.Fn, _ = params["fn"].(string)
+	}
+	req.TimeMs = intParam(params["timeMs"])
+	req.TimeoutMs = intParam(params["timeout"])
+	req.Width = intParam(params["width"])
+	req.Height = intParam(params["height"])
+	return req
+}
+
+// relayActBody converts an act request to the control server's /act body.
+func relayActBody(tabID string, req browser.ActRequest) map[string]interface{} {
+	body := map[string]interface{}{}
+	data, _ := json.Marshal(req)
+	_ = json.Unmarshal(data, &body)
+	if sel, ok := body["selector"]; ok {
+		body["ref"] = sel
+		delete(body, "selector")
+	}
+	if end, ok := body["endSelector"]; ok {
+		body["startRef"] = body["ref"]
+		body["endRef"] = end
+		delete(body, "endSelector")
+	}
+	delete(body, "kind")
+	body["targetId"] = tabID
+	return body
+}
+
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
+	return nil
+}
+
+func intParam(v interface{}) int {
+	switch v := v.(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+)
+
+// SessionContext identifies the session a tool call belongs to.
+type SessionContext struct {
+	// Key is the session key (e.g. "main", "telegram:123", "cron:<id>").
+	Key string
+	// Type is the session type: main, direct, group, cron or subagent.
+	Type string
+	// AgentID is the agent the session runs as, e.g. "main" or the agentId
+	// a sub-agent was spawned with.
+	AgentID string
+	// Paths, when set, replaces the file tools' guard for the session.
+	Paths *pathguard.Guard
+}
+
+type sessionContextKey struct{}
+
+// WithSession attaches the calling session to ctx for tool execution.
+func WithSession(ctx context.Context, sc SessionContext) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sc)
+}
+
+// SessionFromContext returns the calling session, if the agent set one.
+func SessionFromContext(ctx context.Context) (SessionContext, bool) {
+	sc, ok := ctx.Value(sessionContextKey{}).(SessionContext)
+	return sc, ok
+}
+
+// sessionPaths returns the guard for the calling session: the session's
+// own guard when the agent set one, otherwise def.
+func sessionPaths(ctx context.Context, def *pathguard.Guard) *pathguard.Guard {
+	if sc, ok := SessionFromContext(ctx); ok && sc.Paths != nil {
+		return sc.Paths
+	}
+	return def
+}
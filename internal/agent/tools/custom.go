@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/liteclaw/liteclaw/internal/agent/customtool"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
+)
+
+const (
+	// DefaultCustomToolTimeout limits a custom tool call without its own
+	// timeoutSeconds.
+	DefaultCustomToolTimeout = 60 * time.Second
+	// DefaultCustomToolMaxOutput caps the output returned to the model.
+	DefaultCustomToolMaxOutput = 50000
+)
+
+// CustomTool runs a tool declared in config or a skill (tools.custom) by
+// expanding its command or HTTP template with the call's arguments.
+type CustomTool struct {
+	Spec customtool.Spec
+	// Dir is the working directory when the spec sets none.
+	Dir string
+	// Source names where the tool was declared, e.g. "config" or a skill.
+	Source string
+	// Sandbox, when set, isolates commands for the session types it
+	// applies to, as for exec.
+	Sandbox *sandbox.Sandbox
+	// AgentSandboxes replace Sandbox per agent ID, as for exec.
+	AgentSandboxes map[string]*sandbox.Sandbox
+	// Client sends HTTP requests (default: http.DefaultClient).
+	Client    *http.Client
+	MaxOutput int
+}
+
+// NewCustomTool creates a tool from spec.
+func NewCustomTool(spec customtool.Spec) *CustomTool {
+	return &CustomTool{Spec: spec, MaxOutput: DefaultCustomToolMaxOutput}
+}
+
+// Name returns the tool name.
+func (t *CustomTool) Name() string {
+	return t.Spec.Name
+}
+
+// Description returns the tool description.
+func (t *CustomTool) Description() string {
+	return t.Spec.Description
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *CustomTool) Parameters() interface{} {
+	return t.Spec.Schema()
+}
+
+// CustomToolResult is the outcome of a command tool call. HTTP tools and
+// json output return the parsed value instead.
+type CustomToolResult struct {
+	ExitCode  int    `json:"exitCode"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Sandbox   string `json:"sandbox,omitempty"`
+}
+
+// Execute runs the command or request.
+func (t *CustomTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if err := t.Spec.CheckArgs(params); err != nil {
+		return nil, err
+	}
+	timeout := DefaultCustomToolTimeout
+	if t.Spec.TimeoutSeconds > 0 {
+		timeout = time.Duration(t.Spec.TimeoutSeconds) * time.Second
+	}
+	if t.Spec.HTTP != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return t.request(ctx, params)
+	}
+	return t.run(ctx, params, timeout)
+}
+
+// run executes the command template.
+func (t *CustomTool) run(ctx context.Context, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	workdir := t.Spec.Workdir
+	if workdir == "" {
+		workdir = t.Dir
+	}
+	sb := sessionSandbox(ctx, t.Sandbox, t.AgentSandboxes)
+	if sb != nil {
+		timeout = sb.Timeout(timeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	var backend string
+	switch {
+	case sb != nil:
+		// The sandbox takes a shell command, so argv templates are quoted
+		// into one.
+		command := t.Spec.ShellCommand(params)
+		if len(t.Spec.Args) > 0 {
+			var quoted []string
+			for _, a := range t.Spec.Argv(params) {
+				quoted = append(quoted, customtool.ShellQuote(a))
+			}
+			command = strings.Join(quoted, " ")
+		}
+		var err error
+		if cmd, err = sb.Command(ctx, command, workdir); err != nil {
+			return nil, fmt.Errorf("refusing to run %s: %w", t.Spec.Name, err)
+		}
+		backend, _ = sb.Backend()
+	case len(t.Spec.Args) > 0:
+		argv := t.Spec.Argv(params)
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("%s: args expanded to an empty command", t.Spec.Name)
+		}
+		cmd = exec.CommandContext(ctx, argv[0], argv[1:]...)
+		cmd.Dir = workdir
+	default:
+		cmd = exec.CommandContext(ctx, "bash", "-c", t.Spec.ShellCommand(params))
+		cmd.Dir = workdir
+	}
+	cmd.Env = append(cmd.Environ(), t.env(params)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return nil, fmt.Errorf("%s timed out after %s", t.Spec.Name, timeout)
+	}
+	res := &CustomToolResult{Stdout: stdout.String(), Stderr: stderr.String(), Sandbox: backend}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		res.ExitCode = exitErr.ExitCode()
+	} else if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		msg := strings.TrimSpace(res.Stderr)
+		if msg == "" {
+			msg = strings.TrimSpace(res.Stdout)
+		}
+		return nil, fmt.Errorf("%s exited with status %d: %s", t.Spec.Name, res.ExitCode, truncateText(msg, 2000))
+	}
+
+	if t.Spec.Output == customtool.OutputJSON {
+		return parseJSONOutput(t.Spec.Name, stdout.Bytes())
+	}
+	res.Stdout, res.Truncated = t.limit(res.Stdout)
+	res.Stderr, _ = t.limit(res.Stderr)
+	return res, nil
+}
+
+// env is the spec's environment, with ${VAR} expanded, plus the arguments.
+func (t *CustomTool) env(params map[string]interface{}) []string {
+	var env []string
+	for k, v := range t.Spec.Env {
+		env = append(env, k+"="+customtool.ExpandEnv(v, os.Getenv))
+	}
+	return append(env, customtool.ArgEnv(params)...)
+}
+
+// request sends the HTTP template.
+func (t *CustomTool) request(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	spec := t.Spec
+	// Environment references are resolved in the template before arguments
+	// are substituted, so an argument cannot pull in a secret.
+	spec.HTTP = &customtool.HTTPSpec{
+		Method:  t.Spec.HTTP.Method,
+		URL:     customtool.ExpandEnv(t.Spec.HTTP.URL, os.Getenv),
+		Body:    t.Spec.HTTP.Body,
+		Headers: t.Spec.HTTP.Headers,
+	}
+	u, err := spec.RequestURL(params)
+	if err != nil {
+		return nil, err
+	}
+	body, err := spec.RequestBody(params)
+	if err != nil {
+		return nil, err
+	}
+	var rd io.Reader
+	if body != "" {
+		rd = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, spec.Method(), u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	stripNewlines := strings.NewReplacer("\r", "", "\n", "").Replace
+	for k, v := range spec.HTTP.Headers {
+		v = customtool.ExpandEnv(v, os.Getenv)
+		req.Header.Set(k, customtool.Expand(v, params, stripNewlines))
+	}
+
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s: HTTP %d: %s", t.Spec.Name, resp.StatusCode, truncateText(strings.TrimSpace(string(data)), 2000))
+	}
+	if t.Spec.Output == customtool.OutputJSON {
+		return parseJSONOutput(t.Spec.Name, data)
+	}
+	text, truncated := t.limit(string(data))
+	return map[string]interface{}{"status": resp.StatusCode, "body": text, "truncated": truncated}, nil
+}
+
+func (t *CustomTool) limit(s string) (string, bool) {
+	max := t.MaxOutput
+	if max <= 0 {
+		max = DefaultCustomToolMaxOutput
+	}
+	if len(s) <= max {
+		return s, false
+	}
+	return truncateText(s, max), true
+}
+
+func parseJSONOutput(name string, data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(data), &v); err != nil {
+		return nil, fmt.Errorf("%s did not return JSON: %w (output: %s)", name, err, truncateText(string(data), 500))
+	}
+	return v, nil
+}
+
+// truncateText cuts s to at most max bytes on a rune boundary.
+func truncateText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max] + "…"
+}
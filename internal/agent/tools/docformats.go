@@ -0,0 +1,662 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxDocPartBytes caps how much of a single archive member is inflated,
+// so a zip bomb cannot exhaust memory.
+const maxDocPartBytes = 64 << 20
+
+// xlsxMaxColumns is the widest sheet Excel allows (column XFD), and
+// xlsxMaxCells caps the rows and cells, padding included, kept per sheet.
+const (
+	xlsxMaxColumns = 16384
+	xlsxMaxCells   = 1 << 20
+)
+
+// extractPDF reads the text of each page. Lines are split where the text
+// position moves vertically and words where glyph positioning leaves a gap.
+func extractPDF(f *os.File, size int64) (doc *Document, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+	r, err := pdf.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	doc = &Document{Format: "pdf", Unit: "page", Metadata: map[string]string{}}
+	info := r.Trailer().Key("Info")
+	for key, name := range map[string]string{
+		"Title": "title", "Author": "author", "Subject": "subject", "Keywords": "keywords",
+		"Creator": "creator", "Producer": "producer", "CreationDate": "created", "ModDate": "modified",
+	} {
+		if v := strings.TrimSpace(info.Key(key).Text()); v != "" {
+			doc.Metadata[name] = strings.TrimPrefix(v, "D:")
+		}
+	}
+
+	fonts := map[string]*pdf.Font{}
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		section := DocumentSection{Number: i}
+		if !p.V.IsNull() {
+			for _, name := range p.Fonts() {
+				if _, ok := fonts[name]; !ok {
+					font := p.Font(name)
+					fonts[name] = &font
+				}
+			}
+			section.Text = pdfPageText(p, fonts)
+		}
+		doc.Sections = append(doc.Sections, section)
+	}
+	return doc, nil
+}
+
+// pdfPageText interprets a page's content stream, tracking just enough of
+// the text matrix to place line breaks.
+func pdfPageText(p pdf.Page, fonts map[string]*pdf.Font) string {
+	var b strings.Builder
+	// Text in fonts the page does not declare decodes as PDFDocEncoding.
+	fallback := pdf.Font{}.Encoder()
+	enc := fallback
+	var y, lastY float64
+	shown := false
+	show := func(s string) {
+		if s == "" {
+			return
+		}
+		if shown && math.Abs(y-lastY) > 1 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(enc.Decode(s))
+		lastY, shown = y, true
+	}
+	newline := func() {
+		if shown {
+			b.WriteByte('\n')
+			shown = false
+		}
+	}
+
+	interpret := func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		switch op {
+		case "BT":
+			y = 0
+		case "Tf":
+			enc = fallback
+			if len(args) == 2 {
+				if font, ok := fonts[args[0].Name()]; ok {
+					enc = font.Encoder()
+				}
+			}
+		case "Td", "TD":
+			if len(args) == 2 {
+				y += args[1].Float64()
+			}
+		case "Tm":
+			if len(args) == 6 {
+				y = args[5].Float64()
+			}
+		case "T*":
+			newline()
+		case "Tj":
+			if len(args) == 1 {
+				show(args[0].RawString())
+			}
+		case "'", "\"":
+			newline()
+			if len(args) > 0 {
+				show(args[len(args)-1].RawString())
+			}
+		case "TJ":
+			if len(args) != 1 {
+				return
+			}
+			for i := 0; i < args[0].Len(); i++ {
+				v := args[0].Index(i)
+				switch v.Kind() {
+				case pdf.String:
+					show(v.RawString())
+				case pdf.Integer, pdf.Real:
+					// Offsets are in thousandths of an em; large negative
+					// ones separate words.
+					if v.Float64() < -200 && shown {
+						b.WriteByte(' ')
+					}
+				}
+			}
+		}
+	}
+
+	// Contents is a stream or an array of streams forming one program.
+	contents := p.V.Key("Contents")
+	if contents.Kind() == pdf.Array {
+		for i := 0; i < contents.Len(); i++ {
+			pdf.Interpret(contents.Index(i), interpret)
+		}
+	} else if contents.Kind() == pdf.Stream {
+		pdf.Interpret(contents, interpret)
+	}
+	return b.String()
+}
+
+// openXML is an Office Open XML package (docx, xlsx, pptx).
+type openXML struct {
+	files map[string]*zip.File
+}
+
+func openOOXML(f *os.File, size int64) (*openXML, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open document: %w", err)
+	}
+	pkg := &openXML{files: map[string]*zip.File{}}
+	for _, zf := range zr.File {
+		pkg.files[zf.Name] = zf
+	}
+	return pkg, nil
+}
+
+func (p *openXML) has(name string) bool {
+	_, ok := p.files[name]
+	return ok
+}
+
+func (p *openXML) read(name string) ([]byte, error) {
+	zf, ok := p.files[name]
+	if !ok {
+		return nil, fmt.Errorf("document part %s is missing", name)
+	}
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	data, err := io.ReadAll(io.LimitReader(rc, maxDocPartBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDocPartBytes {
+		return nil, fmt.Errorf("document part %s is too large", name)
+	}
+	return data, nil
+}
+
+// format identifies the package by its main part.
+func (p *openXML) format() string {
+	switch {
+	case p.has("word/document.xml"):
+		return "docx"
+	case p.has("xl/workbook.xml"):
+		return "xlsx"
+	case p.has("ppt/presentation.xml"):
+		return "pptx"
+	}
+	return ""
+}
+
+// metadata reads the core and extended document properties.
+func (p *openXML) metadata() map[string]string {
+	meta := map[string]string{}
+	names := map[string]string{
+		"title": "title", "subject": "subject", "creator": "author", "keywords": "keywords",
+		"description": "description", "lastModifiedBy": "lastModifiedBy", "created": "created",
+		"modified": "modified", "Application": "application", "Pages": "pages", "Words": "words",
+		"Slides": "slides",
+	}
+	for _, part := range []string{"docProps/core.xml", "docProps/app.xml"} {
+		data, err := p.read(part)
+		if err != nil {
+			continue
+		}
+		dec := xml.NewDecoder(bytes.NewReader(data))
+		var current string
+		for {
+			tok, err := dec.Token()
+			if err != nil {
+				break
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				current = names[t.Name.Local]
+			case xml.CharData:
+				if v := strings.TrimSpace(string(t)); current != "" && v != "" {
+					meta[current] = v
+				}
+			case xml.EndElement:
+				current = ""
+			}
+		}
+	}
+	return meta
+}
+
+type ooxmlRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// targets maps relationship IDs of part to the parts they point at.
+func (p *openXML) targets(part string) map[string]string {
+	dir, file := path.Split(part)
+	data, err := p.read(dir + "_rels/" + file + ".rels")
+	if err != nil {
+		return nil
+	}
+	var rels ooxmlRels
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil
+	}
+	out := map[string]string{}
+	for _, r := range rels.Rels {
+		if strings.HasPrefix(r.Target, "/") {
+			out[r.ID] = strings.TrimPrefix(r.Target, "/")
+		} else {
+			out[r.ID] = path.Join(dir, r.Target)
+		}
+	}
+	return out
+}
+
+func extractDOCX(pkg *openXML) (*Document, error) {
+	data, err := pkg.read("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+	pages, err := ooxmlText(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Format: "docx", Unit: "page", Metadata: pkg.metadata()}
+	for i, text := range pages {
+		doc.Sections = append(doc.Sections, DocumentSection{Number: i + 1, Text: text})
+	}
+	return doc, nil
+}
+
+func extractPPTX(pkg *openXML) (*Document, error) {
+	data, err := pkg.read("ppt/presentation.xml")
+	if err != nil {
+		return nil, err
+	}
+	var pres struct {
+		Slides []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := xml.Unmarshal(data, &pres); err != nil {
+		return nil, fmt.Errorf("failed to parse presentation: %w", err)
+	}
+	targets := pkg.targets("ppt/presentation.xml")
+	doc := &Document{Format: "pptx", Unit: "slide", Metadata: pkg.metadata()}
+	for _, s := range pres.Slides {
+		target, ok := targets[s.RID]
+		if !ok {
+			continue
+		}
+		slide, err := pkg.read(target)
+		if err != nil {
+			return nil, err
+		}
+		pages, err := ooxmlText(slide)
+		if err != nil {
+			return nil, err
+		}
+		doc.Sections = append(doc.Sections, DocumentSection{Number: len(doc.Sections) + 1, Text: strings.Join(pages, "\n")})
+	}
+	return doc, nil
+}
+
+// ooxmlText extracts the text of a WordprocessingML or DrawingML part.
+// Paragraphs end lines, table cells are tab separated, and explicit page
+// breaks start a new page.
+func ooxmlText(data []byte) ([]string, error) {
+	var pages []string
+	var b strings.Builder
+	inText, runDepth, cellDepth := false, 0, 0
+	// Paragraphs inside a table cell are joined by a space, written only
+	// when more text follows in the same cell.
+	cellSpace := false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse document XML: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "r":
+				runDepth++
+			case "tc":
+				cellDepth++
+			case "tab":
+				if runDepth > 0 {
+					b.WriteByte('\t')
+				}
+			case "br", "cr":
+				if runDepth == 0 && t.Name.Local == "br" && b.Len() > 0 {
+					// DrawingML line breaks sit between runs.
+					b.WriteByte('\n')
+				} else if runDepth > 0 && xmlAttr(t, "type") == "page" {
+					pages = append(pages, b.String())
+					b.Reset()
+				} else if runDepth > 0 {
+					b.WriteByte('\n')
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "r":
+				runDepth--
+			case "p":
+				if cellDepth > 0 {
+					cellSpace = true
+				} else {
+					b.WriteByte('\n')
+				}
+			case "tc":
+				cellDepth--
+				cellSpace = false
+				b.WriteByte('\t')
+			case "tr":
+				b.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				if cellSpace {
+					b.WriteByte(' ')
+					cellSpace = false
+				}
+				b.Write(t)
+			}
+		}
+	}
+	pages = append(pages, b.String())
+	return pages, nil
+}
+
+func xmlAttr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func extractXLSX(pkg *openXML) (*Document, error) {
+	data, err := pkg.read("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	var book struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("failed to parse workbook: %w", err)
+	}
+	var shared []string
+	if data, err := pkg.read("xl/sharedStrings.xml"); err == nil {
+		if shared, err = xlsxSharedStrings(data); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := pkg.targets("xl/workbook.xml")
+	doc := &Document{Format: "xlsx", Unit: "sheet", Metadata: pkg.metadata()}
+	for _, s := range book.Sheets {
+		target, ok := targets[s.RID]
+		if !ok {
+			continue
+		}
+		data, err := pkg.read(target)
+		if err != nil {
+			return nil, err
+		}
+		rows, truncated, err := xlsxRows(data, shared)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+		text := tableText(rows)
+		if truncated {
+			if text != "" {
+				text += "\n"
+			}
+			text += fmt.Sprintf("[sheet truncated after %d cells]", xlsxMaxCells)
+		}
+		doc.Sections = append(doc.Sections, DocumentSection{Number: len(doc.Sections) + 1, Name: s.Name, Text: text})
+	}
+	return doc, nil
+}
+
+// xlsxSharedStrings reads the shared string table, joining rich text runs
+// and skipping phonetic hints.
+func xlsxSharedStrings(data []byte) ([]string, error) {
+	var out []string
+	var b strings.Builder
+	inText, inPhonetic := false, false
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse shared strings: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				b.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				out = append(out, b.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				b.Write(t)
+			}
+		}
+	}
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// xlsxRows returns the cell values of a worksheet, placing each cell in
+// the column its reference names. It stops after xlsxMaxCells cells and
+// reports whether it did.
+func xlsxRows(data []byte, shared []string) ([][]string, bool, error) {
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(data, &sheet); err != nil {
+		return nil, false, fmt.Errorf("failed to parse worksheet: %w", err)
+	}
+	var rows [][]string
+	cells := 0
+	for _, r := range sheet.Rows {
+		// Empty rows count too, so a sheet of blank rows is capped as well.
+		if cells++; cells > xlsxMaxCells {
+			return rows, true, nil
+		}
+		var row []string
+		for _, c := range r.Cells {
+			col := len(row)
+			if n, ok := columnIndex(c.Ref); ok && n >= col {
+				col = n
+			}
+			if col >= xlsxMaxColumns {
+				break
+			}
+			if cells+col-len(row)+1 > xlsxMaxCells {
+				return append(rows, row), true, nil
+			}
+			cells += col - len(row) + 1
+			for len(row) < col {
+				row = append(row, "")
+			}
+			value := c.Value
+			switch c.Type {
+			case "s":
+				if i, err := strconv.Atoi(c.Value); err == nil && i >= 0 && i < len(shared) {
+					value = shared[i]
+				}
+			case "inlineStr":
+				value = c.Inline
+			case "b":
+				value = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	return rows, false, nil
+}
+
+var cellRef = regexp.MustCompile(`^([A-Z]+)[0-9]*$`)
+
+// columnIndex converts the column letters of a cell reference such as
+// "C7" to a zero-based index. References past column XFD are invalid.
+func columnIndex(ref string) (int, bool) {
+	m := cellRef.FindStringSubmatch(strings.ToUpper(ref))
+	if m == nil || len(m[1]) > 3 {
+		return 0, false
+	}
+	n := 0
+	for _, ch := range m[1] {
+		n = n*26 + int(ch-'A'+1)
+	}
+	if n > xlsxMaxColumns {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+func extractCSV(f io.Reader, comma rune, name string) (*Document, error) {
+	r := csv.NewReader(f)
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	return &Document{
+		Format:   "csv",
+		Unit:     "sheet",
+		Metadata: map[string]string{"rows": strconv.Itoa(len(rows))},
+		Sections: []DocumentSection{{Number: 1, Name: name, Text: tableText(rows)}},
+	}, nil
+}
+
+// tableText renders rows as tab-separated lines, dropping empty rows and
+// trailing empty cells.
+func tableText(rows [][]string) string {
+	var b strings.Builder
+	for _, row := range rows {
+		for len(row) > 0 && strings.TrimSpace(row[len(row)-1]) == "" {
+			row = row[:len(row)-1]
+		}
+		if len(row) == 0 {
+			continue
+		}
+		for i, cell := range row {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ").Replace(cell))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func extractHTML(f io.Reader) (*Document, error) {
+	title, text, err := extractReadable(f, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{Format: "html", Unit: "page", Metadata: map[string]string{}}
+	if title != "" {
+		doc.Metadata["title"] = title
+	}
+	doc.Sections = []DocumentSection{{Number: 1, Text: text}}
+	return doc, nil
+}
+
+func extractPlain(f io.Reader) (*Document, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("file is not UTF-8 text")
+	}
+	return &Document{Format: "text", Unit: "page", Sections: []DocumentSection{{Number: 1, Text: string(data)}}}, nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// cleanText trims trailing space from lines and collapses runs of blank
+// lines.
+func cleanText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t ")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
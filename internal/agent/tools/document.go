@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+)
+
+const (
+	// DefaultDocReadMaxChars is how much text doc_read returns per call.
+	DefaultDocReadMaxChars = 20000
+	// DefaultDocReadMaxBytes is the largest file doc_read opens.
+	DefaultDocReadMaxBytes = 50 << 20
+)
+
+// Document is the extracted text of a PDF, Office, CSV or HTML file, split
+// into pages, sheets or slides.
+type Document struct {
+	// Format is pdf, docx, xlsx, csv, pptx, html or text.
+	Format string
+	// Unit names the sections: page, sheet or slide.
+	Unit string
+	// Metadata holds properties such as title, author and created.
+	Metadata map[string]string
+	Sections []DocumentSection
+}
+
+// DocumentSection is one page, sheet or slide of a Document.
+type DocumentSection struct {
+	// Number is the 1-based position in the document.
+	Number int
+	// Name is the sheet name, if any.
+	Name string
+	Text string
+}
+
+// ExtractDocument reads the text of the document at path. The format is
+// taken from the extension, or sniffed from the content when that is
+// unknown.
+func ExtractDocument(path string) (*Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > DefaultDocReadMaxBytes {
+		return nil, fmt.Errorf("%s is %d bytes; the limit is %d", path, info.Size(), DefaultDocReadMaxBytes)
+	}
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var doc *Document
+	switch format := documentFormat(path, head); format {
+	case "pdf":
+		doc, err = extractPDF(f, info.Size())
+	case "docx", "xlsx", "pptx", "zip":
+		var pkg *openXML
+		if pkg, err = openOOXML(f, info.Size()); err != nil {
+			return nil, err
+		}
+		switch pkg.format() {
+		case "docx":
+			doc, err = extractDOCX(pkg)
+		case "xlsx":
+			doc, err = extractXLSX(pkg)
+		case "pptx":
+			doc, err = extractPPTX(pkg)
+		default:
+			return nil, fmt.Errorf("%s is not a Word, Excel or PowerPoint document", filepath.Base(path))
+		}
+	case "csv", "tsv":
+		comma := ','
+		if format == "tsv" {
+			comma = '\t'
+		}
+		doc, err = extractCSV(f, comma, filepath.Base(path))
+	case "html":
+		doc, err = extractHTML(f)
+	case "text":
+		doc, err = extractPlain(f)
+	default:
+		return nil, fmt.Errorf("unsupported document type %q (supported: PDF, DOCX, XLSX, CSV, PPTX, HTML and text)", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range doc.Sections {
+		doc.Sections[i].Text = cleanText(doc.Sections[i].Text)
+	}
+	return doc, nil
+}
+
+// documentFormat picks a parser from the file extension, falling back to
+// the leading bytes.
+func documentFormat(path string, head []byte) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return "pdf"
+	case ".docx", ".docm":
+		return "docx"
+	case ".xlsx", ".xlsm":
+		return "xlsx"
+	case ".pptx", ".pptm":
+		return "pptx"
+	case ".csv":
+		return "csv"
+	case ".tsv":
+		return "tsv"
+	case ".html", ".htm", ".xhtml":
+		return "html"
+	case ".txt", ".md", ".markdown", ".log", ".json", ".xml", ".yaml", ".yml":
+		return "text"
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return "pdf"
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		return "zip"
+	}
+	ct := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(ct, "text/html"):
+		return "html"
+	case strings.HasPrefix(ct, "text/plain"):
+		return "text"
+	}
+	return ""
+}
+
+// Text renders the sections numbered in pages, or all of them when pages
+// is empty, each under a heading such as "--- Page 2 ---".
+func (d *Document) Text(pages []int) string {
+	want := map[int]bool{}
+	for _, p := range pages {
+		want[p] = true
+	}
+	var parts []string
+	for _, s := range d.Sections {
+		if len(want) > 0 && !want[s.Number] {
+			continue
+		}
+		unit := strings.ToUpper(d.Unit[:1]) + d.Unit[1:]
+		heading := fmt.Sprintf("--- %s %d ---", unit, s.Number)
+		if s.Name != "" {
+			heading = fmt.Sprintf("--- %s %d: %s ---", unit, s.Number, s.Name)
+		}
+		parts = append(parts, heading+"\n"+s.Text)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// parsePageRange parses a selection such as "1-3,5,8-" against a document
+// of total sections.
+func parsePageRange(spec string, total int) ([]int, error) {
+	var pages []int
+	seen := map[int]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to := part, part
+		if i := strings.Index(part, "-"); i >= 0 {
+			from, to = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+			if from == "" {
+				from = "1"
+			}
+			if to == "" {
+				to = strconv.Itoa(total)
+			}
+		}
+		start, err1 := strconv.Atoi(from)
+		end, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || start < 1 || end < start {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		if start > total {
+			return nil, fmt.Errorf("page %d is out of range; the document has %d", start, total)
+		}
+		if end > total {
+			end = total
+		}
+		for p := start; p <= end; p++ {
+			if !seen[p] {
+				seen[p] = true
+				pages = append(pages, p)
+			}
+		}
+	}
+	return pages, nil
+}
+
+// DocReadTool extracts text from documents.
+type DocReadTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+	// MaxChars is the default amount of text returned per call.
+	MaxChars int
+}
+
+// NewDocReadTool creates a new document reading tool.
+func NewDocReadTool() *DocReadTool {
+	return &DocReadTool{MaxChars: DefaultDocReadMaxChars}
+}
+
+// Name returns the tool name.
+func (t *DocReadTool) Name() string {
+	return "doc_read"
+}
+
+// Description returns the tool description.
+func (t *DocReadTool) Description() string {
+	return `Extract text from a PDF, Word (.docx), Excel (.xlsx/.csv), PowerPoint (.pptx) or HTML file, with page, sheet or slide headings and document metadata.
+Use pages to select a range (e.g. "1-3,7"). Long text is cut at maxChars; call again with offset set to nextOffset to continue.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *DocReadTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "Path to the document (relative paths resolve against the workspace)",
+			},
+			"pages": map[string]interface{}{
+				"type":        "string",
+				"description": "Pages, sheets or slides to read, e.g. \"2\", \"1-3,7\" or \"5-\" (default: all)",
+			},
+			"maxChars": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum characters to return (default: %d)", DefaultDocReadMaxChars),
+				"minimum":     1,
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset to continue from (the nextOffset of a previous call)",
+				"minimum":     0,
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+// DocReadResult is the text of a document, or a slice of it.
+type DocReadResult struct {
+	Path     string            `json:"path"`
+	Format   string            `json:"format"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Unit and Total describe the whole document, e.g. 12 pages.
+	Unit  string `json:"unit"`
+	Total int    `json:"total"`
+	Pages string `json:"pages,omitempty"`
+	// Offset and TotalChars locate Text within the selected text.
+	Offset     int    `json:"offset"`
+	TotalChars int    `json:"totalChars"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	NextOffset int    `json:"nextOffset,omitempty"`
+	Text       string `json:"text"`
+	Note       string `json:"note,omitempty"`
+}
+
+// Execute extracts the document.
+func (t *DocReadTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	path, _ := params["path"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Read)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ExtractDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DocReadResult{Path: path, Format: doc.Format, Metadata: doc.Metadata, Unit: doc.Unit, Total: len(doc.Sections)}
+	var pages []int
+	if spec, _ := params["pages"].(string); strings.TrimSpace(spec) != "" {
+		if pages, err = parsePageRange(spec, len(doc.Sections)); err != nil {
+			return nil, err
+		}
+		res.Pages = spec
+	}
+
+	text := []rune(doc.Text(pages))
+	res.TotalChars = len(text)
+	maxChars := t.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultDocReadMaxChars
+	}
+	if n, ok := params["maxChars"].(float64); ok && n >= 1 {
+		maxChars = int(n)
+	}
+	if n, ok := params["offset"].(float64); ok && n > 0 {
+		res.Offset = int(n)
+	}
+	if res.Offset > len(text) {
+		return nil, fmt.Errorf("offset %d is past the end of the text (%d characters)", res.Offset, len(text))
+	}
+	end := res.Offset + maxChars
+	if end < len(text) {
+		res.Truncated, res.NextOffset = true, end
+	} else {
+		end = len(text)
+	}
+	res.Text = string(text[res.Offset:end])
+
+	if doc.Format == "pdf" && strings.TrimSpace(documentBody(doc, pages)) == "" {
+		res.Note = "No extractable text; the PDF may be scanned images. Render pages to images and use the image tool instead."
+	}
+	return res, nil
+}
+
+// documentBody is the selected sections' text without headings.
+func documentBody(doc *Document, pages []int) string {
+	want := map[int]bool{}
+	for _, p := range pages {
+		want[p] = true
+	}
+	var b strings.Builder
+	for _, s := range doc.Sections {
+		if len(want) == 0 || want[s.Number] {
+			b.WriteString(s.Text)
+		}
+	}
+	return b.String()
+}
@@ -5,12 +5,16 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // EditTool performs incremental file edits.
-type EditTool struct{}
+type EditTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewEditTool creates a new edit tool.
 func NewEditTool() *EditTool {
@@ -37,7 +41,7 @@ func (t *EditTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "Absolute path to the file to edit",
+				"description": "Path to the file to edit (relative paths resolve against the workspace)",
 			},
 			"oldText": map[string]interface{}{
 				"type":        "string",
@@ -80,10 +84,9 @@ func (t *EditTool) Execute(ctx context.Context, params map[string]interface{}) (
 	newText, _ := params["newText"].(string)
 	dryRun, _ := params["dryRun"].(bool)
 
-	// Expand path
-	if path[0] == '~' {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[1:])
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Write)
+	if err != nil {
+		return nil, err
 	}
 
 	// Read existing content
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/creack/pty"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
 )
 
 // ExecTool executes shell commands.
@@ -21,6 +23,11 @@ type ExecTool struct {
 	AllowedDirs []string
 	// SafeBins are commands that can be auto-approved.
 	SafeBins []string
+	// Sandbox, when set, isolates commands for the session types it applies to.
+	Sandbox *sandbox.Sandbox
+	// AgentSandboxes replace Sandbox for sessions of the given agent IDs; a
+	// nil entry runs that agent's commands on the host.
+	AgentSandboxes map[string]*sandbox.Sandbox
 }
 
 // NewExecTool creates a new exec tool.
@@ -40,7 +47,8 @@ func (t *ExecTool) Name() string {
 func (t *ExecTool) Description() string {
 	return `Execute a shell command. Use for running commands, scripts, and interacting with the system.
 The command runs in a bash shell. Use workdir to specify the working directory.
-For long-running commands, set background=true to run asynchronously.`
+For long-running commands, set background=true to run asynchronously; the result contains a process id
+for the process tool (read output, write stdin, send keys, kill). Set pty=true for commands that need a terminal.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -64,6 +72,10 @@ func (t *ExecTool) Parameters() interface{} {
 				"type":        "boolean",
 				"description": "Run command in background (default: false)",
 			},
+			"pty": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Run in a pseudo-terminal for TTY-required CLIs (default: false)",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -77,6 +89,8 @@ type ExecResult struct {
 	Stderr   string `json:"stderr"`
 	Duration int64  `json:"durationMs"`
 	TimedOut bool   `json:"timedOut,omitempty"`
+	// Sandbox is the sandbox backend the command ran in, if any.
+	Sandbox string `json:"sandbox,omitempty"`
 }
 
 // Execute runs the command.
@@ -95,29 +109,61 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (
 	}
 
 	workdir, _ := params["workdir"].(string)
-	if workdir == "" {
-		workdir, _ = os.Getwd()
-	}
+	sb := sessionSandbox(ctx, t.Sandbox, t.AgentSandboxes)
 
 	timeoutSec, _ := params["timeout"].(float64)
 	timeout := t.DefaultTimeout
 	if timeoutSec > 0 {
 		timeout = time.Duration(timeoutSec) * time.Second
 	}
+	if sb != nil {
+		timeout = sb.Timeout(timeout)
+	}
 
+	usePTY, _ := params["pty"].(bool)
 	background, _ := params["background"].(bool)
 	if background {
-		// Start in background
-		cmd := exec.Command("bash", "-c", command)
-		cmd.Dir = workdir
-		if err := cmd.Start(); err != nil {
+		// Background processes outlive the call. On the host they run until
+		// killed; in the sandbox the configured time limit still applies.
+		bgCtx, cancel := context.Background(), context.CancelFunc(func() {})
+		if sb != nil {
+			if limit := sb.Timeout(time.Duration(timeoutSec) * time.Second); limit > 0 {
+				bgCtx, cancel = context.WithTimeout(bgCtx, limit)
+			}
+		}
+		cmd, backend, err := t.command(bgCtx, sb, command, workdir)
+		if err != nil {
+			cancel()
 			return nil, err
 		}
-		return map[string]interface{}{
-			"pid":     cmd.Process.Pid,
-			"status":  "running",
+		sc, _ := SessionFromContext(ctx)
+		id := GenerateProcessID()
+		p, err := processManager.Start(id, command, cmd, ProcessStartOptions{
+			SessionKey: sc.Key,
+			PTY:        usePTY,
+			Sandbox:    backend,
+		})
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		go func() {
+			<-p.done
+			cancel()
+		}()
+		res := map[string]interface{}{
+			"id":      id,
+			"pid":     p.PID,
+			"status":  ProcessRunning,
 			"command": command,
-		}, nil
+		}
+		if p.LogPath != "" {
+			res["log"] = p.LogPath
+		}
+		if backend != "" {
+			res["sandbox"] = backend
+		}
+		return res, nil
 	}
 
 	// Create context with timeout
@@ -127,20 +173,35 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]interface{}) (
 	start := time.Now()
 
 	// Execute command
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
-	cmd.Dir = workdir
+	cmd, backend, err := t.command(ctx, sb, command, workdir)
+	if err != nil {
+		return nil, err
+	}
+
+	if usePTY {
+		if result, ok := t.runPTY(ctx, cmd, command, start); ok {
+			result.Sandbox = backend
+			return result, nil
+		}
+		// PTY unavailable: fall back to pipes.
+		cmd, _, err = t.command(ctx, sb, command, workdir)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
 	result := &ExecResult{
 		Command:  command,
 		Stdout:   stdout.String(),
 		Stderr:   stderr.String(),
 		Duration: time.Since(start).Milliseconds(),
+		Sandbox:  backend,
 	}
 
 	if ctx.Err() == context.DeadlineExceeded {
@@ -172,43 +233,20 @@ func NewExecPtyTool() *ExecPtyTool {
 
 // Execute runs the command with PTY.
 func (t *ExecPtyTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	command, _ := params["command"].(string)
-	if command == "" {
-		return nil, fmt.Errorf("command is required")
-	}
-
-	// SAFETY: Prevent sudo from blocking indefinitely for password.
-	trimmedCmd := strings.TrimSpace(command)
-	if strings.HasPrefix(trimmedCmd, "sudo") {
-		if !strings.Contains(trimmedCmd, "-n") && !strings.Contains(trimmedCmd, "--non-interactive") {
-			command = strings.Replace(command, "sudo", "sudo -n", 1)
-		}
-	}
-
-	workdir, _ := params["workdir"].(string)
-	if workdir == "" {
-		workdir, _ = os.Getwd()
-	}
-
-	timeoutSec, _ := params["timeout"].(float64)
-	timeout := t.DefaultTimeout
-	if timeoutSec > 0 {
-		timeout = time.Duration(timeoutSec) * time.Second
+	withPTY := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		withPTY[k] = v
 	}
+	withPTY["pty"] = true
+	return t.ExecTool.Execute(ctx, withPTY)
+}
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-
-	start := time.Now()
-
-	cmd := exec.CommandContext(ctx, "bash", "-c", command)
-	cmd.Dir = workdir
-
-	// Start with PTY
+// runPTY runs cmd attached to a pseudo-terminal and collects its output.
+// It reports false if the PTY could not be started.
+func (t *ExecTool) runPTY(ctx context.Context, cmd *exec.Cmd, command string, start time.Time) (*ExecResult, bool) {
 	ptmx, err := pty.Start(cmd)
 	if err != nil {
-		// Fallback to regular exec
-		return t.ExecTool.Execute(ctx, params)
+		return nil, false
 	}
 	defer func() { _ = ptmx.Close() }()
 
@@ -233,14 +271,73 @@ func (t *ExecPtyTool) Execute(ctx context.Context, params map[string]interface{}
 	if ctx.Err() == context.DeadlineExceeded {
 		result.TimedOut = true
 		result.ExitCode = -1
-		return result, nil
+		return result, true
 	}
 
 	if exitErr, ok := cmdErr.(*exec.ExitError); ok {
 		result.ExitCode = exitErr.ExitCode()
 	}
 
-	return result, nil
+	return result, true
+}
+
+// sessionSandbox returns the sandbox the calling session must run in, or
+// nil to run on the host. The session's agent may override def. Calls
+// without session context are treated as non-main sessions.
+func sessionSandbox(ctx context.Context, def *sandbox.Sandbox, byAgent map[string]*sandbox.Sandbox) *sandbox.Sandbox {
+	sc, _ := SessionFromContext(ctx)
+	sb := def
+	if s, ok := byAgent[sc.AgentID]; ok {
+		sb = s
+	}
+	if sb == nil || !sb.Required(sc.Type) {
+		return nil
+	}
+	return sb
+}
+
+// command builds the bash invocation, inside sb when it is set. It returns
+// the sandbox backend name, or "" when running on the host.
+func (t *ExecTool) command(ctx context.Context, sb *sandbox.Sandbox, command, workdir string) (*exec.Cmd, string, error) {
+	if sb != nil {
+		cmd, err := sb.Command(ctx, command, workdir)
+		if err != nil {
+			return nil, "", fmt.Errorf("refusing to run command: %w", err)
+		}
+		backend, _ := sb.Backend()
+		return cmd, backend, nil
+	}
+
+	if workdir == "" {
+		workdir, _ = os.Getwd()
+	}
+	if err := t.checkAllowedDir(workdir); err != nil {
+		return nil, "", err
+	}
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = workdir
+	return cmd, "", nil
+}
+
+// checkAllowedDir enforces AllowedDirs for host execution.
+func (t *ExecTool) checkAllowedDir(workdir string) error {
+	if len(t.AllowedDirs) == 0 {
+		return nil
+	}
+	abs, err := filepath.Abs(workdir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range t.AllowedDirs {
+		allowed, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(allowed, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("workdir %s is not within the allowed directories", abs)
 }
 
 // IsSafeBin checks if a command starts with a safe binary.
@@ -6,10 +6,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // ReadTool reads file contents.
-type ReadTool struct{}
+type ReadTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewReadTool creates a new read tool.
 func NewReadTool() *ReadTool {
@@ -34,7 +40,7 @@ func (t *ReadTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "Absolute path to the file to read",
+				"description": "Path to the file to read (relative paths resolve against the workspace)",
 			},
 			"startLine": map[string]interface{}{
 				"type":        "integer",
@@ -56,10 +62,9 @@ func (t *ReadTool) Execute(ctx context.Context, params map[string]interface{}) (
 		return nil, fmt.Errorf("path is required")
 	}
 
-	// Expand path
-	if path[0] == '~' {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[1:])
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Read)
+	if err != nil {
+		return nil, err
 	}
 
 	// Read file
@@ -78,7 +83,10 @@ func (t *ReadTool) Execute(ctx context.Context, params map[string]interface{}) (
 }
 
 // WriteTool writes content to a file.
-type WriteTool struct{}
+type WriteTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewWriteTool creates a new write tool.
 func NewWriteTool() *WriteTool {
@@ -103,7 +111,7 @@ func (t *WriteTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "Absolute path to the file to write",
+				"description": "Path to the file to write (relative paths resolve against the workspace)",
 			},
 			"content": map[string]interface{}{
 				"type":        "string",
@@ -128,10 +136,9 @@ func (t *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 	content, _ := params["content"].(string)
 	appendMode, _ := params["append"].(bool)
 
-	// Expand path
-	if path[0] == '~' {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[1:])
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Write)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure directory exists
@@ -167,7 +174,10 @@ func (t *WriteTool) Execute(ctx context.Context, params map[string]interface{})
 }
 
 // ListTool lists directory contents.
-type ListTool struct{}
+type ListTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewListTool creates a new list tool.
 func NewListTool() *ListTool {
@@ -191,7 +201,7 @@ func (t *ListTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "Absolute path to the directory to list",
+				"description": "Path to the directory to list (relative paths resolve against the workspace)",
 			},
 			"recursive": map[string]interface{}{
 				"type":        "boolean",
@@ -217,10 +227,9 @@ func (t *ListTool) Execute(ctx context.Context, params map[string]interface{}) (
 		return nil, fmt.Errorf("path is required")
 	}
 
-	// Expand path
-	if path[0] == '~' {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[1:])
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Read)
+	if err != nil {
+		return nil, err
 	}
 
 	entries, err := os.ReadDir(path)
@@ -248,3 +257,17 @@ func (t *ListTool) Execute(ctx context.Context, params map[string]interface{}) (
 		"count":   len(files),
 	}, nil
 }
+
+// resolvePath expands a tool path argument. With a guard (the calling
+// session's, else g) it is resolved against the workspace and checked
+// against the allowed roots; without one only ~ is expanded.
+func resolvePath(ctx context.Context, g *pathguard.Guard, path string, access pathguard.Access) (string, error) {
+	if g = sessionPaths(ctx, g); g != nil {
+		return g.Resolve(path, access)
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[1:])
+	}
+	return path, nil
+}
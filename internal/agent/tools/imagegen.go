@@ -0,0 +1,472 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+)
+
+const (
+	// DefaultImageGenerateMaxImages caps the images produced per call.
+	DefaultImageGenerateMaxImages = 4
+	// maxGeneratedImageBytes caps each image accepted from a generator.
+	maxGeneratedImageBytes = 50 << 20
+	// imageEditMaxDimension and imageEditMaxBytes bound input images
+	// uploaded for edits.
+	imageEditMaxDimension = 4096
+	imageEditMaxBytes     = 25 << 20
+)
+
+// ImageGenRequest is a normalised image generation or edit request.
+type ImageGenRequest struct {
+	Prompt string
+	// Size is e.g. 1024x1024 or auto; empty uses the generator default.
+	Size    string
+	Quality string
+	N       int
+	// Inputs are images to edit; empty generates from scratch.
+	Inputs []ImageInput
+	// Mask marks, with transparency, the area of the first input to edit.
+	Mask *ImageInput
+}
+
+// ImageInput is an image uploaded to a generator.
+type ImageInput struct {
+	MimeType string
+	Data     []byte
+}
+
+// GeneratedImage is one image returned by a generator.
+type GeneratedImage struct {
+	Data []byte
+	// RevisedPrompt is the prompt the model actually used, when reported.
+	RevisedPrompt string
+}
+
+// ImageGenerator produces images from a prompt.
+type ImageGenerator interface {
+	Name() string
+	Generate(ctx context.Context, req ImageGenRequest) ([]GeneratedImage, error)
+}
+
+// OpenAIImages generates images through an OpenAI-compatible
+// /images/generations endpoint and edits them through /images/edits.
+type OpenAIImages struct {
+	APIKey string
+	// BaseURL defaults to https://api.openai.com/v1.
+	BaseURL string
+	// Model defaults to gpt-image-1.
+	Model  string
+	Client *http.Client
+}
+
+// Name returns the generator name.
+func (g *OpenAIImages) Name() string {
+	return "openai"
+}
+
+// Generate creates or edits images for req.
+func (g *OpenAIImages) Generate(ctx context.Context, req ImageGenRequest) ([]GeneratedImage, error) {
+	if g.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is not set (OPENAI_API_KEY or tools.imageGenerate.apiKey)")
+	}
+	base := strings.TrimRight(g.BaseURL, "/")
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	model := g.Model
+	if model == "" {
+		model = "gpt-image-1"
+	}
+	// DALL·E models return URLs unless asked for base64; gpt-image models
+	// always return base64 and reject the parameter.
+	legacy := strings.HasPrefix(model, "dall-e")
+
+	var httpReq *http.Request
+	var err error
+	if len(req.Inputs) == 0 {
+		body := map[string]interface{}{
+			"model":  model,
+			"prompt": req.Prompt,
+		}
+		if req.N > 0 {
+			body["n"] = req.N
+		}
+		if req.Size != "" {
+			body["size"] = req.Size
+		}
+		if req.Quality != "" {
+			body["quality"] = req.Quality
+		}
+		if legacy {
+			body["response_format"] = "b64_json"
+		}
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		httpReq, err = http.NewRequestWithContext(ctx, "POST", base+"/images/generations", bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+	} else {
+		var body bytes.Buffer
+		w := multipart.NewWriter(&body)
+		_ = w.WriteField("model", model)
+		_ = w.WriteField("prompt", req.Prompt)
+		if req.N > 0 {
+			_ = w.WriteField("n", fmt.Sprint(req.N))
+		}
+		if req.Size != "" {
+			_ = w.WriteField("size", req.Size)
+		}
+		if req.Quality != "" {
+			_ = w.WriteField("quality", req.Quality)
+		}
+		if legacy {
+			_ = w.WriteField("response_format", "b64_json")
+		}
+		field := "image"
+		if len(req.Inputs) > 1 {
+			field = "image[]"
+		}
+		for i, in := range req.Inputs {
+			if err := writeImagePart(w, field, fmt.Sprintf("image-%d", i+1), in); err != nil {
+				return nil, err
+			}
+		}
+		if req.Mask != nil {
+			if err := writeImagePart(w, "mask", "mask", *req.Mask); err != nil {
+				return nil, err
+			}
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		httpReq, err = http.NewRequestWithContext(ctx, "POST", base+"/images/edits", &body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", w.FormDataContentType())
+	}
+	httpReq.Header.Set("Authorization", "Bearer "+g.APIKey)
+
+	client := g.Client
+	if client == nil {
+		client = &http.Client{Timeout: 3 * time.Minute}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("image request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("image API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out struct {
+		Data []struct {
+			B64JSON       string `json:"b64_json"`
+			URL           string `json:"url"`
+			RevisedPrompt string `json:"revised_prompt"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	images := make([]GeneratedImage, 0, len(out.Data))
+	for _, d := range out.Data {
+		var data []byte
+		switch {
+		case d.B64JSON != "":
+			data, err = base64.StdEncoding.DecodeString(d.B64JSON)
+			if err != nil {
+				return nil, fmt.Errorf("invalid image data: %w", err)
+			}
+		case d.URL != "":
+			data, err = g.download(ctx, client, d.URL)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+		if len(data) > maxGeneratedImageBytes {
+			return nil, fmt.Errorf("image exceeds %d bytes", maxGeneratedImageBytes)
+		}
+		images = append(images, GeneratedImage{Data: data, RevisedPrompt: d.RevisedPrompt})
+	}
+	if len(images) == 0 {
+		return nil, fmt.Errorf("image API returned no images")
+	}
+	return images, nil
+}
+
+// download fetches an image the API returned by URL.
+func (g *OpenAIImages) download(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("image download failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image download failed: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxGeneratedImageBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("image download failed: %w", err)
+	}
+	return data, nil
+}
+
+// writeImagePart adds in as a file part named after its format, since the
+// API infers the type from the file name and content type.
+func writeImagePart(w *multipart.Writer, field, name string, in ImageInput) error {
+	ext := strings.TrimPrefix(in.MimeType, "image/")
+	if ext == "jpeg" {
+		ext = "jpg"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s.%s"`, field, name, ext))
+	h.Set("Content-Type", in.MimeType)
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(in.Data)
+	return err
+}
+
+// ImageGenerateTool creates and edits images and attaches them to the
+// reply.
+type ImageGenerateTool struct {
+	// Generator produces the images.
+	Generator ImageGenerator
+	// AgentDir resolves relative input paths.
+	AgentDir string
+	// Paths, when set, confines input images to the agent's read roots.
+	Paths *pathguard.Guard
+	// OutputDir is where generated images are saved.
+	OutputDir string
+	// DefaultSize and DefaultQuality apply when the call names none.
+	DefaultSize    string
+	DefaultQuality string
+	// MaxImages caps the images per call.
+	MaxImages int
+	// Net guards input image downloads; nil blocks private addresses.
+	Net *netguard.Guard
+}
+
+// NewImageGenerateTool creates a new image generation tool.
+func NewImageGenerateTool(agentDir string) *ImageGenerateTool {
+	return &ImageGenerateTool{
+		AgentDir:  agentDir,
+		OutputDir: filepath.Join(agentDir, "media", "images"),
+		MaxImages: DefaultImageGenerateMaxImages,
+	}
+}
+
+// Name returns the tool name.
+func (t *ImageGenerateTool) Name() string {
+	return "image_generate"
+}
+
+// Description returns the tool description.
+func (t *ImageGenerateTool) Description() string {
+	return `Generate images from a text prompt, or edit existing images when input images are given.
+The images are saved to the workspace and attached to your reply; on channels that support media they are sent as photos.
+Describe the subject, style, composition and any text to render.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *ImageGenerateTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"prompt": map[string]interface{}{
+				"type":        "string",
+				"description": "Description of the image to create, or of the change to make",
+			},
+			"images": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Images to edit or use as references (paths, URLs or data URLs)",
+			},
+			"mask": map[string]interface{}{
+				"type":        "string",
+				"description": "PNG whose transparent areas mark where the first image should be edited",
+			},
+			"size": map[string]interface{}{
+				"type":        "string",
+				"description": "Image size, e.g. 1024x1024, 1536x1024, 1024x1536 or auto",
+			},
+			"quality": map[string]interface{}{
+				"type":        "string",
+				"description": "Quality: low, medium, high or auto (standard or hd for dall-e-3)",
+			},
+			"n": map[string]interface{}{
+				"type":        "integer",
+				"description": "Number of images to generate (default: 1)",
+				"minimum":     1,
+			},
+		},
+		"required": []string{"prompt"},
+	}
+}
+
+// GeneratedImageFile describes a saved image.
+type GeneratedImageFile struct {
+	FilePath      string `json:"filePath"`
+	MimeType      string `json:"mimeType"`
+	Width         int    `json:"width,omitempty"`
+	Height        int    `json:"height,omitempty"`
+	Bytes         int    `json:"bytes"`
+	RevisedPrompt string `json:"revisedPrompt,omitempty"`
+}
+
+// ImageGenerateResult represents the image generation result.
+type ImageGenerateResult struct {
+	Prompt string               `json:"prompt"`
+	Engine string               `json:"engine"`
+	Edited bool                 `json:"edited,omitempty"`
+	Images []GeneratedImageFile `json:"images"`
+}
+
+// ReplyAttachments implements AttachmentResult.
+func (r *ImageGenerateResult) ReplyAttachments() []MediaAttachment {
+	out := make([]MediaAttachment, 0, len(r.Images))
+	for _, img := range r.Images {
+		out = append(out, MediaAttachment{Type: "image", Path: img.FilePath, MimeType: img.MimeType})
+	}
+	return out
+}
+
+// Execute generates or edits images.
+func (t *ImageGenerateTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	prompt, _ := params["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+	if t.Generator == nil {
+		return nil, fmt.Errorf("no image generator is configured (tools.imageGenerate)")
+	}
+
+	req := ImageGenRequest{Prompt: prompt, Size: t.DefaultSize, Quality: t.DefaultQuality, N: 1}
+	if s, _ := params["size"].(string); s != "" {
+		req.Size = s
+	}
+	if q, _ := params["quality"].(string); q != "" {
+		req.Quality = q
+	}
+	if n, ok := params["n"].(float64); ok && n >= 1 {
+		req.N = int(n)
+	}
+	maxImages := t.MaxImages
+	if maxImages <= 0 {
+		maxImages = DefaultImageGenerateMaxImages
+	}
+	if req.N > maxImages {
+		return nil, fmt.Errorf("n is %d; the limit is %d", req.N, maxImages)
+	}
+
+	src := imageSource{AgentDir: t.AgentDir, Paths: t.Paths, Net: t.Net}
+	if list, ok := params["images"].([]interface{}); ok {
+		for _, v := range list {
+			s, _ := v.(string)
+			if s == "" {
+				continue
+			}
+			in, err := t.loadInput(ctx, src, s)
+			if err != nil {
+				return nil, err
+			}
+			req.Inputs = append(req.Inputs, in)
+		}
+	}
+	if m, _ := params["mask"].(string); m != "" {
+		if len(req.Inputs) == 0 {
+			return nil, fmt.Errorf("mask requires an image to edit")
+		}
+		data, err := src.load(ctx, strings.TrimPrefix(m, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", imageLabel(m), err)
+		}
+		if _, format, err := image.DecodeConfig(bytes.NewReader(data)); err != nil || format != "png" {
+			return nil, fmt.Errorf("%s: mask must be a PNG", imageLabel(m))
+		}
+		req.Mask = &ImageInput{MimeType: "image/png", Data: data}
+	}
+
+	images, err := t.Generator.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(t.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output dir: %w", err)
+	}
+	result := &ImageGenerateResult{
+		Prompt: prompt,
+		Engine: t.Generator.Name(),
+		Edited: len(req.Inputs) > 0,
+	}
+	stamp := time.Now().Format("20060102-150405")
+	for _, img := range images {
+		file := GeneratedImageFile{MimeType: "image/png", Bytes: len(img.Data), RevisedPrompt: img.RevisedPrompt}
+		ext := "png"
+		if cfg, format, err := image.DecodeConfig(bytes.NewReader(img.Data)); err == nil {
+			file.Width, file.Height = cfg.Width, cfg.Height
+			if mime, ok := modelImageFormats[format]; ok {
+				file.MimeType = mime
+				ext = format
+			}
+		}
+		if ext == "jpeg" {
+			ext = "jpg"
+		}
+		file.FilePath = filepath.Join(t.OutputDir, fmt.Sprintf("image-%s-%s.%s", stamp, uuid.New().String()[:8], ext))
+		if err := os.WriteFile(file.FilePath, img.Data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save image: %w", err)
+		}
+		result.Images = append(result.Images, file)
+	}
+	return result, nil
+}
+
+// loadInput reads an image to edit, re-encoding it when its format or size
+// is not accepted for upload.
+func (t *ImageGenerateTool) loadInput(ctx context.Context, src imageSource, s string) (ImageInput, error) {
+	s = strings.TrimPrefix(s, "@") // Some LLMs add this
+	data, err := src.load(ctx, s)
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("%s: %w", imageLabel(s), err)
+	}
+	out, mime, _, err := prepareImage(data, imageEditMaxDimension, imageEditMaxBytes)
+	if err != nil {
+		return ImageInput{}, fmt.Errorf("%s: %w", imageLabel(s), err)
+	}
+	return ImageInput{MimeType: mime, Data: out}, nil
+}
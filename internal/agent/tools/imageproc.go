@@ -0,0 +1,149 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+
+	// Decoders registered for image.Decode; bmp is re-encoded before it
+	// reaches the model.
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// DefaultImageMaxDimension is the longest side sent to vision models.
+	DefaultImageMaxDimension = 2048
+	// DefaultImageMaxBytes is the largest encoded image sent to a model.
+	DefaultImageMaxBytes = 5 << 20
+	// maxImagePixels guards against decompression bombs.
+	maxImagePixels = 100_000_000
+)
+
+// modelImageFormats are the formats vision models accept as-is.
+var modelImageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// ImageInfo describes an input image and, when it had to be adapted, what
+// was sent to the model.
+type ImageInfo struct {
+	Source   string `json:"source"`
+	MimeType string `json:"mimeType"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Bytes    int    `json:"bytes"`
+	// Resized is set when the image was scaled or re-encoded.
+	Resized      bool   `json:"resized,omitempty"`
+	SentWidth    int    `json:"sentWidth,omitempty"`
+	SentHeight   int    `json:"sentHeight,omitempty"`
+	SentMimeType string `json:"sentMimeType,omitempty"`
+	SentBytes    int    `json:"sentBytes,omitempty"`
+}
+
+// prepareImage validates data as an image and, when it is larger than
+// maxDim or maxBytes or in a format models do not take, scales it to fit
+// and re-encodes it. It returns the bytes to send and their MIME type.
+func prepareImage(data []byte, maxDim, maxBytes int) ([]byte, string, ImageInfo, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ImageInfo{}, fmt.Errorf("unsupported or corrupt image: %w", err)
+	}
+	info := ImageInfo{MimeType: "image/" + format, Width: cfg.Width, Height: cfg.Height, Bytes: len(data)}
+	mime, accepted := modelImageFormats[format]
+	if accepted {
+		info.MimeType = mime
+		if cfg.Width <= maxDim && cfg.Height <= maxDim && len(data) <= maxBytes {
+			return data, mime, info, nil
+		}
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return nil, "", info, fmt.Errorf("image is too large to process (%dx%d)", cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", info, fmt.Errorf("failed to decode image: %w", err)
+	}
+	out, mime, bounds, err := encodeForModel(img, format, maxDim, maxBytes)
+	if err != nil {
+		return nil, "", info, err
+	}
+	info.Resized = true
+	info.SentWidth = bounds.Dx()
+	info.SentHeight = bounds.Dy()
+	info.SentMimeType = mime
+	info.SentBytes = len(out)
+	return out, mime, info, nil
+}
+
+// encodeForModel scales img to fit maxDim and encodes it under maxBytes,
+// keeping PNG for lossless sources where it fits and otherwise falling
+// back to JPEG, shrinking further until the result is small enough.
+func encodeForModel(img image.Image, format string, maxDim, maxBytes int) ([]byte, string, image.Rectangle, error) {
+	lossless := format == "png" || format == "gif" || format == "bmp"
+	dim := maxDim
+	for attempt := 0; attempt < 6 && dim >= 64; attempt++ {
+		scaled := scaleToFit(img, dim)
+		var buf bytes.Buffer
+		if lossless {
+			if err := png.Encode(&buf, scaled); err == nil && buf.Len() <= maxBytes {
+				return buf.Bytes(), "image/png", scaled.Bounds(), nil
+			}
+		}
+		flat := flatten(scaled)
+		for _, q := range []int{85, 70} {
+			buf.Reset()
+			if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: q}); err != nil {
+				return nil, "", image.Rectangle{}, err
+			}
+			if buf.Len() <= maxBytes {
+				return buf.Bytes(), "image/jpeg", scaled.Bounds(), nil
+			}
+		}
+		dim = dim * 3 / 4
+	}
+	return nil, "", image.Rectangle{}, fmt.Errorf("could not fit image within %d bytes", maxBytes)
+}
+
+// scaleToFit downscales img so neither side exceeds maxDim.
+func scaleToFit(img image.Image, maxDim int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxDim && h <= maxDim {
+		return img
+	}
+	if w >= h {
+		h = max(1, h*maxDim/w)
+		w = maxDim
+	} else {
+		w = max(1, w*maxDim/h)
+		h = maxDim
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Over, nil)
+	return dst
+}
+
+// flatten composites img onto white, since JPEG has no alpha channel.
+func flatten(img image.Image) image.Image {
+	if _, ok := img.(*image.YCbCr); ok {
+		return img
+	}
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
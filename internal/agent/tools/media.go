@@ -5,29 +5,67 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // ImageTool analyzes images using vision models.
 type ImageTool struct {
-	// AgentDir is the directory for saving temporary files.
+	// AgentDir resolves relative image paths.
 	AgentDir string
-	// ModelProvider is the vision model provider.
-	ModelProvider string
-	// ModelID is the vision model ID.
-	ModelID string
+	// Paths, when set, confines local images to the agent's read roots.
+	Paths *pathguard.Guard
+	// Resolve picks the vision model for a call.
+	Resolve func() (*VisionModel, error)
+	// MaxDownloadBytes caps each image read from a URL or file.
+	MaxDownloadBytes int64
+	// MaxDimension is the longest side sent to the model.
+	MaxDimension int
+	// MaxImageBytes is the largest encoded image sent to the model.
+	MaxImageBytes int
+	// MaxImages caps the images per call.
+	MaxImages int
+	// Net guards URL downloads; nil blocks private addresses.
+	Net *netguard.Guard
+	// Timeout bounds each download.
+	Timeout time.Duration
+}
+
+// VisionModel is a resolved vision-capable model.
+type VisionModel struct {
+	Client llm.Provider
+	// Ref is the canonical "provider/model".
+	Ref       string
+	Model     string
+	MaxTokens int
 }
 
+const (
+	// DefaultImageDownloadBytes caps each downloaded image.
+	DefaultImageDownloadBytes = 20 << 20
+	// DefaultImageMaxImages caps the images per call.
+	DefaultImageMaxImages = 8
+)
+
 // NewImageTool creates a new image tool.
 func NewImageTool(agentDir string) *ImageTool {
 	return &ImageTool{
-		AgentDir:      agentDir,
-		ModelProvider: "openai",
-		ModelID:       "gpt-4o-mini",
+		AgentDir:         agentDir,
+		MaxDownloadBytes: DefaultImageDownloadBytes,
+		MaxDimension:     DefaultImageMaxDimension,
+		MaxImageBytes:    DefaultImageMaxBytes,
+		MaxImages:        DefaultImageMaxImages,
+		Timeout:          30 * time.Second,
 	}
 }
 
@@ -38,9 +76,9 @@ func (t *ImageTool) Name() string {
 
 // Description returns the tool description.
 func (t *ImageTool) Description() string {
-	return `Analyze an image with a vision model.
-Provide a prompt and image path or URL.
-Use for understanding image contents, extracting text from images, and visual analysis.`
+	return `Analyze one or more images with a vision model.
+Provide a prompt and image paths, URLs or data URLs; large images are downscaled automatically.
+Use for understanding image contents, extracting text from images, comparing images, and visual analysis.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -50,97 +88,227 @@ func (t *ImageTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"prompt": map[string]interface{}{
 				"type":        "string",
-				"description": "What to analyze or ask about the image",
+				"description": "What to analyze or ask about the image(s)",
 			},
 			"image": map[string]interface{}{
 				"type":        "string",
-				"description": "Path to the image file, URL, or base64 data URL",
+				"description": "Path to the image file, http(s) URL, or base64 data URL",
+			},
+			"images": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Several images to analyze together (paths, URLs or data URLs)",
 			},
 		},
-		"required": []string{"image"},
 	}
 }
 
 // ImageAnalysisResult represents the image analysis result.
 type ImageAnalysisResult struct {
-	Text     string `json:"text"`
-	Image    string `json:"image"`
-	Provider string `json:"provider"`
-	Model    string `json:"model"`
+	Text   string      `json:"text"`
+	Images []ImageInfo `json:"images"`
+	Model  string      `json:"model"`
 }
 
-// Execute analyzes the image.
+// Execute analyzes the images.
 func (t *ImageTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	imageInput, _ := params["image"].(string)
-	if imageInput == "" {
-		return nil, fmt.Errorf("image is required")
+	var sources []string
+	if s, _ := params["image"].(string); s != "" {
+		sources = append(sources, s)
+	}
+	if list, ok := params["images"].([]interface{}); ok {
+		for _, v := range list {
+			if s, _ := v.(string); s != "" {
+				sources = append(sources, s)
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("image or images is required")
+	}
+	maxImages := t.MaxImages
+	if maxImages <= 0 {
+		maxImages = DefaultImageMaxImages
+	}
+	if len(sources) > maxImages {
+		return nil, fmt.Errorf("too many images: %d (max %d)", len(sources), maxImages)
 	}
 
 	prompt, _ := params["prompt"].(string)
 	if prompt == "" {
 		prompt = "Describe this image in detail."
+		if len(sources) > 1 {
+			prompt = "Describe these images in detail."
+		}
 	}
 
-	// Handle @ prefix (some LLMs add this)
-	imageInput = strings.TrimPrefix(imageInput, "@")
+	if t.Resolve == nil {
+		return nil, fmt.Errorf("no vision model is configured (tools.image.model)")
+	}
+	vm, err := t.Resolve()
+	if err != nil {
+		return nil, err
+	}
 
-	// Determine image type
-	var base64Data string
-	var mimeType string
+	maxDim := t.MaxDimension
+	if maxDim <= 0 {
+		maxDim = DefaultImageMaxDimension
+	}
+	maxBytes := t.MaxImageBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultImageMaxBytes
+	}
 
-	if strings.HasPrefix(imageInput, "data:") {
-		// Base64 data URL
-		data, mime, err := parseDataURL(imageInput)
+	images := make([]llm.Image, 0, len(sources))
+	infos := make([]ImageInfo, 0, len(sources))
+	for _, src := range sources {
+		src = strings.TrimPrefix(src, "@") // Some LLMs add this
+		data, err := t.source().load(ctx, src)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", imageLabel(src), err)
+		}
+		out, mime, info, err := prepareImage(data, maxDim, maxBytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", imageLabel(src), err)
+		}
+		info.Source = imageLabel(src)
+		images = append(images, llm.Image{MimeType: mime, Data: out})
+		infos = append(infos, info)
+	}
+
+	resp, err := vm.Client.Chat(ctx, &llm.ChatRequest{
+		Model:     vm.Model,
+		MaxTokens: vm.MaxTokens,
+		Messages:  []llm.Message{{Role: "user", Content: prompt, Images: images}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("vision model %s: %w", vm.Ref, err)
+	}
+
+	return &ImageAnalysisResult{
+		Text:   strings.TrimSpace(resp.Content),
+		Images: infos,
+		Model:  vm.Ref,
+	}, nil
+}
+
+// source returns a loader bounded by the tool's limits.
+func (t *ImageTool) source() imageSource {
+	return imageSource{AgentDir: t.AgentDir, Paths: t.Paths, Net: t.Net, Timeout: t.Timeout, MaxBytes: t.MaxDownloadBytes}
+}
+
+// imageSource reads images named by path, URL or data URL.
+type imageSource struct {
+	AgentDir string
+	Paths    *pathguard.Guard
+	Net      *netguard.Guard
+	Timeout  time.Duration
+	MaxBytes int64
+}
+
+// load reads an image from a data URL, http(s) URL or local path.
+func (s imageSource) load(ctx context.Context, src string) ([]byte, error) {
+	limit := s.MaxBytes
+	if limit <= 0 {
+		limit = DefaultImageDownloadBytes
+	}
+
+	switch {
+	case strings.HasPrefix(src, "data:"):
+		encoded, _, err := parseDataURL(src)
 		if err != nil {
 			return nil, fmt.Errorf("invalid data URL: %w", err)
 		}
-		base64Data = data
-		mimeType = mime
-	} else if strings.HasPrefix(imageInput, "http://") || strings.HasPrefix(imageInput, "https://") {
-		// URL - for now, just pass the URL reference
-		return &ImageAnalysisResult{
-			Text:     fmt.Sprintf("Image analysis requires local file. URL provided: %s. Use web_fetch to download first.", imageInput),
-			Image:    imageInput,
-			Provider: t.ModelProvider,
-			Model:    t.ModelID,
-		}, nil
-	} else {
-		// Local file path
-		imagePath := imageInput
-		if imagePath[0] == '~' {
+		data, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data URL: %w", err)
+		}
+		return data, nil
+
+	case strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://"):
+		guard := s.Net
+		if guard == nil {
+			guard = netguard.New(nil)
+		}
+		timeout := s.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", src, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "image/*")
+		resp, err := guard.Client(timeout).Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+		}
+		if resp.ContentLength > limit {
+			return nil, fmt.Errorf("image is %d bytes; the limit is %d", resp.ContentLength, limit)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+		if err != nil {
+			return nil, fmt.Errorf("download failed: %w", err)
+		}
+		if int64(len(data)) > limit {
+			return nil, fmt.Errorf("image exceeds %d bytes", limit)
+		}
+		return data, nil
+
+	case sessionPaths(ctx, s.Paths) != nil:
+		imagePath, err := resolvePath(ctx, s.Paths, src, pathguard.Read)
+		if err != nil {
+			return nil, err
+		}
+		return readImageFile(imagePath, limit)
+
+	default:
+		imagePath := src
+		if strings.HasPrefix(imagePath, "~") {
 			home, _ := os.UserHomeDir()
 			imagePath = filepath.Join(home, imagePath[1:])
 		}
-
 		if !filepath.IsAbs(imagePath) {
-			if t.AgentDir != "" {
-				imagePath = filepath.Join(t.AgentDir, imagePath)
+			if s.AgentDir != "" {
+				imagePath = filepath.Join(s.AgentDir, imagePath)
 			} else {
 				cwd, _ := os.Getwd()
 				imagePath = filepath.Join(cwd, imagePath)
 			}
 		}
-
-		data, err := os.ReadFile(imagePath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read image: %w", err)
-		}
-
-		base64Data = base64.StdEncoding.EncodeToString(data)
-		mimeType = guessMimeType(imagePath)
+		return readImageFile(imagePath, limit)
 	}
+}
 
-	// Note: Actual vision model call would go here
-	// For now, return a placeholder indicating the tool is ready
-	_ = base64Data // Would be used in API call
-	_ = mimeType
+// readImageFile reads a local image of at most limit bytes.
+func readImageFile(path string, limit int64) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("image is %d bytes; the limit is %d", info.Size(), limit)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	return data, nil
+}
 
-	return &ImageAnalysisResult{
-		Text:     fmt.Sprintf("[Image analysis tool ready. Image loaded from: %s. Prompt: %s. Vision model integration pending.]", imageInput, prompt),
-		Image:    imageInput,
-		Provider: t.ModelProvider,
-		Model:    t.ModelID,
-	}, nil
+// imageLabel shortens data URLs for results and errors.
+func imageLabel(src string) string {
+	if strings.HasPrefix(src, "data:") {
+		if i := strings.IndexAny(src, ";,"); i > 0 {
+			return src[:i] + " (data URL)"
+		}
+		return "data URL"
+	}
+	return src
 }
 
 // parseDataURL parses a data URL and returns base64 data and mime type.
@@ -184,16 +352,66 @@ func guessMimeType(path string) string {
 		return "image/bmp"
 	case ".svg":
 		return "image/svg+xml"
+	case ".mp3":
+		return "audio/mpeg"
+	case ".ogg", ".opus":
+		return "audio/ogg"
+	case ".aac":
+		return "audio/aac"
+	case ".flac":
+		return "audio/flac"
+	case ".wav":
+		return "audio/wav"
+	case ".mp4":
+		return "video/mp4"
+	case ".webm":
+		return "video/webm"
+	case ".pdf":
+		return "application/pdf"
 	default:
 		return "application/octet-stream"
 	}
 }
 
+// MediaAttachment is a file produced by a tool that should be delivered
+// alongside the reply, e.g. synthesised speech.
+type MediaAttachment struct {
+	// Type is "image", "audio", "video" or "file".
+	Type     string `json:"type"`
+	Path     string `json:"path"`
+	MimeType string `json:"mimeType,omitempty"`
+	// Voice asks channels that support it to deliver audio as a voice note.
+	Voice bool `json:"voice,omitempty"`
+}
+
+// AttachmentResult is implemented by tool results that carry media for the
+// reply.
+type AttachmentResult interface {
+	ReplyAttachments() []MediaAttachment
+}
+
 // MessageSender defines the interface for sending messages via channels.
 type MessageSender interface {
 	SendMessage(ctx context.Context, channel, target, message string) error
 }
 
+// MediaSender is implemented by senders that can deliver attachments.
+type MediaSender interface {
+	SendMedia(ctx context.Context, channel, target, message string, media []MediaAttachment) error
+}
+
+// mediaAttachment describes the file at path for delivery.
+func mediaAttachment(path string) MediaAttachment {
+	mime := guessMimeType(path)
+	typ := "file"
+	for _, kind := range []string{"image", "audio", "video"} {
+		if strings.HasPrefix(mime, kind+"/") {
+			typ = kind
+		}
+	}
+	return MediaAttachment{Type: typ, Path: path, MimeType: mime}
+}
+
 // MessageTool sends messages via channel plugins.
 type MessageTool struct {
 	// DefaultChannel is the default messaging channel.
@@ -202,6 +420,11 @@ type MessageTool struct {
 	AccountID string
 	// Sender handles the actual message delivery.
 	Sender MessageSender
+	// AgentDir resolves relative media paths.
+	AgentDir string
+	// Paths, when set, confines attached media to the agent's readable
+	// roots.
+	Paths *pathguard.Guard
 }
 
 // NewMessageTool creates a new message tool.
@@ -255,7 +478,7 @@ func (t *MessageTool) Parameters() interface{} {
 			},
 			"media": map[string]interface{}{
 				"type":        "string",
-				"description": "Path to media file to send",
+				"description": "Path to a media file to send, e.g. an image from image_generate",
 			},
 			"replyTo": map[string]interface{}{
 				"type":        "string",
@@ -321,10 +544,16 @@ func (t *MessageTool) Execute(ctx context.Context, params map[string]interface{}
 
 	switch action {
 	case "send":
-		if message == "" {
+		mediaPath, _ := params["media"].(string)
+		if message == "" && mediaPath == "" {
 			return nil, fmt.Errorf("message content is required")
 		}
-		err := t.Sender.SendMessage(ctx, channel, target, message)
+		var err error
+		if mediaPath != "" {
+			err = t.sendMedia(ctx, channel, target, message, mediaPath)
+		} else {
+			err = t.Sender.SendMessage(ctx, channel, target, message)
+		}
 		if err != nil {
 			return &MessageResult{
 				Action:  action,
@@ -350,3 +579,27 @@ func (t *MessageTool) Execute(ctx context.Context, params map[string]interface{}
 		}, nil
 	}
 }
+
+// sendMedia delivers the file at path with message as its caption.
+func (t *MessageTool) sendMedia(ctx context.Context, channel, target, message, path string) error {
+	ms, ok := t.Sender.(MediaSender)
+	if !ok {
+		return fmt.Errorf("message sender cannot send media")
+	}
+	path = strings.TrimPrefix(path, "@")
+	if sessionPaths(ctx, t.Paths) == nil && !filepath.IsAbs(path) && t.AgentDir != "" {
+		path = filepath.Join(t.AgentDir, path)
+	}
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Read)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("media: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("media %s is not a regular file", path)
+	}
+	return ms.SendMedia(ctx, channel, target, message, []MediaAttachment{mediaAttachment(path)})
+}
@@ -8,6 +8,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // MemorySearchTool searches memory files for relevant content.
@@ -16,6 +20,11 @@ type MemorySearchTool struct {
 	AgentDir string
 	// AgentSessionKey is the current session key.
 	AgentSessionKey string
+	// Index is the memory index to search. When nil, one over AgentDir is
+	// created on first use.
+	Index *memory.Index
+
+	once sync.Once
 }
 
 // NewMemorySearchTool creates a new memory search tool.
@@ -32,9 +41,9 @@ func (t *MemorySearchTool) Name() string {
 
 // Description returns the tool description.
 func (t *MemorySearchTool) Description() string {
-	return `Semantically search MEMORY.md and memory/*.md files.
+	return `Search MEMORY.md, memory/**/*.md and (when enabled) past session transcripts, ranked by relevance and recency.
 Mandatory recall step before answering questions about prior work, decisions, dates, people, preferences, or todos.
-Returns top snippets with path and line numbers.`
+Returns top snippets with path and line range; pass them to memory_get to read more.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -56,12 +65,7 @@ func (t *MemorySearchTool) Parameters() interface{} {
 }
 
 // MemorySearchResult represents a search result.
-type MemorySearchResult struct {
-	Path    string  `json:"path"`
-	Line    int     `json:"line"`
-	Content string  `json:"content"`
-	Score   float64 `json:"score"`
-}
+type MemorySearchResult = memory.Result
 
 // MemorySearchResults represents the search results.
 type MemorySearchResults struct {
@@ -82,45 +86,19 @@ func (t *MemorySearchTool) Execute(ctx context.Context, params map[string]interf
 		maxResults = int(mr)
 	}
 
-	agentDir := t.AgentDir
-	if agentDir == "" {
-		cwd, _ := os.Getwd()
-		agentDir = cwd
-	}
-
-	// Find memory files
-	memoryFiles := []string{}
-
-	// Check MEMORY.md
-	mainMemory := filepath.Join(agentDir, "MEMORY.md")
-	if _, err := os.Stat(mainMemory); err == nil {
-		memoryFiles = append(memoryFiles, mainMemory)
-	}
-
-	// Check memory/*.md
-	memoryDir := filepath.Join(agentDir, "memory")
-	if entries, err := os.ReadDir(memoryDir); err == nil {
-		for _, entry := range entries {
-			if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".md") {
-				memoryFiles = append(memoryFiles, filepath.Join(memoryDir, entry.Name()))
+	t.once.Do(func() {
+		if t.Index == nil {
+			agentDir := t.AgentDir
+			if agentDir == "" {
+				agentDir, _ = os.Getwd()
 			}
+			t.Index = memory.NewIndex(agentDir, "", memory.Config{})
 		}
-	}
+	})
 
-	// Simple keyword search (semantic search would require embeddings)
-	results := []MemorySearchResult{}
-	queryLower := strings.ToLower(query)
-	queryWords := strings.Fields(queryLower)
-
-	for _, filePath := range memoryFiles {
-		matches := t.searchFile(filePath, queryWords, agentDir)
-		results = append(results, matches...)
-	}
-
-	// Sort by score and limit
-	// Simple sort by number of matching words
-	if len(results) > maxResults {
-		results = results[:maxResults]
+	results, err := t.Index.Search(ctx, query, maxResults)
+	if err != nil {
+		return nil, fmt.Errorf("memory search failed: %w", err)
 	}
 
 	return &MemorySearchResults{
@@ -130,53 +108,15 @@ func (t *MemorySearchTool) Execute(ctx context.Context, params map[string]interf
 	}, nil
 }
 
-func (t *MemorySearchTool) searchFile(filePath string, queryWords []string, agentDir string) []MemorySearchResult {
-	results := []MemorySearchResult{}
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return results
-	}
-	defer func() { _ = file.Close() }()
-
-	relPath, _ := filepath.Rel(agentDir, filePath)
-	if relPath == "" {
-		relPath = filePath
-	}
-
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-		lineLower := strings.ToLower(line)
-
-		// Count matching words
-		matchCount := 0
-		for _, word := range queryWords {
-			if strings.Contains(lineLower, word) {
-				matchCount++
-			}
-		}
-
-		if matchCount > 0 {
-			score := float64(matchCount) / float64(len(queryWords))
-			results = append(results, MemorySearchResult{
-				Path:    relPath,
-				Line:    lineNum,
-				Content: line,
-				Score:   score,
-			})
-		}
-	}
-
-	return results
-}
-
 // MemoryGetTool reads specific lines from memory files.
 type MemoryGetTool struct {
 	// AgentDir is the agent's working directory.
 	AgentDir string
+	// TranscriptsDir backs "transcripts/<file>" paths returned by
+	// memory_search. Empty disables transcript reads.
+	TranscriptsDir string
+	// Paths, when set, also confines reads to the agent's roots.
+	Paths *pathguard.Guard
 }
 
 // NewMemoryGetTool creates a new memory get tool.
@@ -193,7 +133,7 @@ func (t *MemoryGetTool) Name() string {
 
 // Description returns the tool description.
 func (t *MemoryGetTool) Description() string {
-	return `Read specific lines from MEMORY.md or memory/*.md files.
+	return `Read specific lines from MEMORY.md, memory/**/*.md or indexed transcripts/*.jsonl files.
 Use after memory_search to pull only the needed lines and keep context small.`
 }
 
@@ -245,21 +185,35 @@ func (t *MemoryGetTool) Execute(ctx context.Context, params map[string]interface
 		numLines = int(l)
 	}
 
-	agentDir := t.AgentDir
-	if agentDir == "" {
-		cwd, _ := os.Getwd()
-		agentDir = cwd
+	agentDir, err := filepath.Abs(memoryAgentDir(t.AgentDir))
+	if err != nil {
+		return nil, err
 	}
 
 	// Resolve path
 	fullPath := relPath
-	if !filepath.IsAbs(relPath) {
-		fullPath = filepath.Join(agentDir, relPath)
-	}
+	if name, ok := strings.CutPrefix(relPath, memory.TranscriptPrefix); ok {
+		if t.TranscriptsDir == "" {
+			return nil, fmt.Errorf("transcript search is not enabled")
+		}
+		if name != filepath.Base(name) || !strings.HasSuffix(name, ".jsonl") {
+			return nil, fmt.Errorf("invalid transcript path: %s", relPath)
+		}
+		fullPath = filepath.Join(t.TranscriptsDir, name)
+	} else {
+		if !filepath.IsAbs(relPath) {
+			fullPath = filepath.Join(agentDir, relPath)
+		}
+		fullPath = filepath.Clean(fullPath)
 
-	// Validate it's a memory file
-	if !strings.Contains(fullPath, "MEMORY.md") && !strings.Contains(fullPath, "memory/") {
-		return nil, fmt.Errorf("path must be MEMORY.md or within memory/ directory")
+		// Only MEMORY.md and files under memory/ are memory.
+		memoryDir := filepath.Join(agentDir, "memory") + string(filepath.Separator)
+		if fullPath != filepath.Join(agentDir, "MEMORY.md") && !strings.HasPrefix(fullPath, memoryDir) {
+			return nil, fmt.Errorf("path must be MEMORY.md or within memory/ directory")
+		}
+		if fullPath, err = resolvePath(ctx, t.Paths, fullPath, pathguard.Read); err != nil {
+			return nil, err
+		}
 	}
 
 	// Read file
@@ -271,6 +225,7 @@ func (t *MemoryGetTool) Execute(ctx context.Context, params map[string]interface
 
 	// Read lines
 	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
 	lineNum := 0
 	var lines []string
 	totalLines := 0
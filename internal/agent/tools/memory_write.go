@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
+)
+
+// memoryWriteMu serialises memory file writes across tools and sessions.
+var memoryWriteMu sync.Mutex
+
+// MemoryAppendTool appends notes to today's daily memory file.
+type MemoryAppendTool struct {
+	// AgentDir is the agent's working directory.
+	AgentDir string
+	// Index is refreshed after each write so new notes are searchable.
+	Index *memory.Index
+}
+
+// NewMemoryAppendTool creates a new memory append tool.
+func NewMemoryAppendTool(agentDir string) *MemoryAppendTool {
+	return &MemoryAppendTool{AgentDir: agentDir}
+}
+
+// Name returns the tool name.
+func (t *MemoryAppendTool) Name() string {
+	return "memory_append"
+}
+
+// Description returns the tool description.
+func (t *MemoryAppendTool) Description() string {
+	return `Append a note to today's memory/YYYY-MM-DD.md daily log.
+Use for events, decisions and facts worth recalling later. Use memory_update for long-lived facts in MEMORY.md.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *MemoryAppendTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown to append",
+			},
+			"heading": map[string]interface{}{
+				"type":        "string",
+				"description": "Optional heading placed above the note",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+// MemoryWriteResult describes a memory file change.
+type MemoryWriteResult struct {
+	Path      string `json:"path"`
+	Action    string `json:"action"`
+	Section   string `json:"section,omitempty"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+}
+
+// Execute appends the note.
+func (t *MemoryAppendTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	text, _ := params["text"].(string)
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	heading, _ := params["heading"].(string)
+
+	now := time.Now()
+	rel := filepath.ToSlash(filepath.Join("memory", now.Format("2006-01-02")+".md"))
+	full := filepath.Join(memoryAgentDir(t.AgentDir), rel)
+
+	memoryWriteMu.Lock()
+	defer memoryWriteMu.Unlock()
+
+	existing, err := os.ReadFile(full)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	content := string(existing)
+	if content == "" {
+		content = "# " + now.Format("2006-01-02") + "\n"
+	}
+	content = strings.TrimRight(content, "\n") + "\n\n"
+	start := strings.Count(content, "\n") + 1
+
+	block := text
+	if heading = strings.TrimSpace(heading); heading != "" {
+		block = "## " + heading + "\n\n" + text
+	}
+	content += block + "\n"
+
+	if err := writeMemoryFile(full, content); err != nil {
+		return nil, err
+	}
+	refreshMemoryIndex(t.Index)
+
+	return &MemoryWriteResult{
+		Path:      rel,
+		Action:    "appended",
+		Section:   heading,
+		StartLine: start,
+		EndLine:   start + strings.Count(block, "\n"),
+	}, nil
+}
+
+// MemoryUpdateTool rewrites a section of MEMORY.md.
+type MemoryUpdateTool struct {
+	// AgentDir is the agent's working directory.
+	AgentDir string
+	// Index is refreshed after each write so updates are searchable.
+	Index *memory.Index
+}
+
+// NewMemoryUpdateTool creates a new memory update tool.
+func NewMemoryUpdateTool(agentDir string) *MemoryUpdateTool {
+	return &MemoryUpdateTool{AgentDir: agentDir}
+}
+
+// Name returns the tool name.
+func (t *MemoryUpdateTool) Name() string {
+	return "memory_update"
+}
+
+// Description returns the tool description.
+func (t *MemoryUpdateTool) Description() string {
+	return `Replace, extend or delete a section of MEMORY.md, the curated long-term memory.
+Sections are matched by heading text (case-insensitive); a missing section is created.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *MemoryUpdateTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"section": map[string]interface{}{
+				"type":        "string",
+				"description": "Heading text of the section, e.g. \"Preferences\"",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Markdown body for the section (without the heading)",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"replace", "append", "delete"},
+				"description": "replace the body (default), append to it, or delete the section",
+			},
+		},
+		"required": []string{"section"},
+	}
+}
+
+// Execute updates the section.
+func (t *MemoryUpdateTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	section, _ := params["section"].(string)
+	section = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(section), "#"))
+	if section == "" {
+		return nil, fmt.Errorf("section is required")
+	}
+	body, _ := params["content"].(string)
+	body = strings.Trim(body, "\n")
+	mode, _ := params["mode"].(string)
+	if mode == "" {
+		mode = "replace"
+	}
+	if mode != "replace" && mode != "append" && mode != "delete" {
+		return nil, fmt.Errorf("invalid mode: %s", mode)
+	}
+	if mode != "delete" && strings.TrimSpace(body) == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+
+	full := filepath.Join(memoryAgentDir(t.AgentDir), "MEMORY.md")
+
+	memoryWriteMu.Lock()
+	defer memoryWriteMu.Unlock()
+
+	existing, err := os.ReadFile(full)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read MEMORY.md: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(existing), "\n"), "\n")
+	if len(existing) == 0 {
+		lines = []string{"# Memory"}
+	}
+
+	res := &MemoryWriteResult{Path: "MEMORY.md", Section: section}
+	start, end := findSection(lines, section)
+	var out []string
+	switch {
+	case start < 0 && mode == "delete":
+		return nil, fmt.Errorf("section not found: %s", section)
+	case start < 0:
+		out = append(lines, "", "## "+section, "")
+		res.StartLine = len(out) - 1
+		out = append(out, body)
+		res.Action = "created"
+	case mode == "delete":
+		out = append(append([]string{}, lines[:start]...), lines[end:]...)
+		res.StartLine, res.EndLine = start+1, start
+		res.Action = "deleted"
+	default:
+		current := strings.Trim(strings.Join(lines[start+1:end], "\n"), "\n")
+		if mode == "append" && current != "" {
+			body = current + "\n" + body
+		}
+		out = append([]string{}, lines[:start+1]...)
+		out = append(out, "")
+		out = append(out, body)
+		if end < len(lines) {
+			out = append(out, "")
+		}
+		out = append(out, lines[end:]...)
+		res.StartLine = start + 1
+		res.Action = "replaced"
+		if mode == "append" {
+			res.Action = "appended"
+		}
+	}
+	if res.Action != "deleted" {
+		res.EndLine = res.StartLine + 1 + strings.Count(body, "\n") + 1
+	}
+
+	if err := writeMemoryFile(full, strings.Join(out, "\n")+"\n"); err != nil {
+		return nil, err
+	}
+	refreshMemoryIndex(t.Index)
+	return res, nil
+}
+
+// findSection locates the heading named section and returns its line index
+// and the index where the section ends (the next heading of the same or a
+// higher level). start is -1 when the section does not exist.
+func findSection(lines []string, section string) (start, end int) {
+	start, level := -1, 0
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			inFence = !inFence
+		}
+		if inFence || !strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		l := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		name := strings.TrimSpace(trimmed[l:])
+		if start < 0 {
+			if strings.EqualFold(name, section) {
+				start, level = i, l
+			}
+			continue
+		}
+		if l <= level {
+			return start, i
+		}
+	}
+	return start, len(lines)
+}
+
+func memoryAgentDir(dir string) string {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	return dir
+}
+
+func writeMemoryFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create memory directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+func refreshMemoryIndex(ix *memory.Index) {
+	if ix != nil {
+		_ = ix.Refresh()
+	}
+}
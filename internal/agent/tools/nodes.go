@@ -14,9 +14,10 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
 )
 
-// CanvasTool controls node canvas displays.
+// CanvasTool controls the gateway's canvas host and node canvas displays.
 type CanvasTool struct {
 	// GatewayURL is the gateway server URL.
 	GatewayURL string
@@ -24,6 +25,23 @@ type CanvasTool struct {
 	GatewayToken string
 	// Timeout is the request timeout.
 	Timeout time.Duration
+	// Nodes is the gateway's node registry; when nil, requests go to
+	// GatewayURL.
+	Nodes NodeInvoker
+	// Canvas is the gateway's canvas host; when set, calls without a node
+	// render there for the calling session.
+	Canvas CanvasHost
+}
+
+// CanvasHost renders per-session canvases served by the gateway.
+type CanvasHost interface {
+	Present(ctx context.Context, session, target string) (string, error)
+	Hide(ctx context.Context, session string) error
+	Navigate(ctx context.Context, session, url string) (string, error)
+	Eval(ctx context.Context, session, script string) (string, error)
+	Snapshot(ctx context.Context, session, format string) ([]byte, error)
+	PushA2UI(ctx context.Context, session, jsonl string) (int, error)
+	ResetA2UI(ctx context.Context, session string) error
 }
 
 // NewCanvasTool creates a new canvas tool.
@@ -42,7 +60,10 @@ func (t *CanvasTool) Name() string {
 
 // Description returns the tool description.
 func (t *CanvasTool) Description() string {
-	return `Control node canvases for displaying UI and content.
+	return `Control the canvas for displaying UI and content. Without a node, the
+canvas is the gateway-hosted page for this session (its URL is returned and
+files placed in the workspace canvas/<session>/ directory are served there);
+with a node, the node's own canvas display is used.
 
 ACTIONS:
 - present: Show canvas with optional URL and placement
@@ -68,7 +89,7 @@ func (t *CanvasTool) Parameters() interface{} {
 			},
 			"node": map[string]interface{}{
 				"type":        "string",
-				"description": "Node ID (optional, uses first available)",
+				"description": "Node ID or name (optional; defaults to the gateway canvas, or the first node with a canvas)",
 			},
 			"target": map[string]interface{}{
 				"type":        "string",
@@ -121,6 +142,7 @@ type CanvasResult struct {
 	Action     string `json:"action"`
 	Success    bool   `json:"success"`
 	NodeID     string `json:"nodeId,omitempty"`
+	URL        string `json:"url,omitempty"`
 	Screenshot string `json:"screenshot,omitempty"`
 	FilePath   string `json:"filePath,omitempty"`
 	Result     string `json:"result,omitempty"`
@@ -135,6 +157,9 @@ func (t *CanvasTool) Execute(ctx context.Context, params map[string]interface{})
 	}
 
 	nodeID, _ := params["node"].(string)
+	if nodeID == "" && t.Canvas != nil {
+		return t.local(ctx, action, params)
+	}
 
 	switch action {
 	case "present":
@@ -151,9 +176,7 @@ func (t *CanvasTool) Execute(ctx context.Context, params map[string]interface{})
 		format, _ := params["outputFormat"].(string)
 		return t.snapshot(ctx, nodeID, format)
 	case "a2ui_push":
-		jsonl, _ := params["jsonl"].(string)
-		jsonlPath, _ := params["jsonlPath"].(string)
-		return t.a2uiPush(ctx, nodeID, jsonl, jsonlPath)
+		return t.a2uiPush(ctx, nodeID, params)
 	case "a2ui_reset":
 		return t.a2uiReset(ctx, nodeID)
 	default:
@@ -161,54 +184,99 @@ func (t *CanvasTool) Execute(ctx context.Context, params map[string]interface{})
 	}
 }
 
-func (t *CanvasTool) invokeNode(ctx context.Context, nodeID, command string, cmdParams map[string]interface{}) (map[string]interface{}, error) {
-	if t.GatewayURL == "" {
-		// Mock mode
-		return map[string]interface{}{
-			"success": true,
-			"mock":    true,
-		}, nil
+// local performs action on the gateway canvas of the calling session.
+func (t *CanvasTool) local(ctx context.Context, action string, params map[string]interface{}) (*CanvasResult, error) {
+	session := "main"
+	if sc, ok := SessionFromContext(ctx); ok && sc.Key != "" {
+		session = sc.Key
 	}
+	res := &CanvasResult{Action: action, Success: true}
 
-	payload := map[string]interface{}{
-		"nodeId":         nodeID,
-		"command":        command,
-		"params":         cmdParams,
-		"idempotencyKey": uuid.New().String(),
+	var err error
+	switch action {
+	case "present":
+		target, _ := params["target"].(string)
+		res.URL, err = t.Canvas.Present(ctx, session, target)
+	case "hide":
+		err = t.Canvas.Hide(ctx, session)
+	case "navigate":
+		url, _ := params["url"].(string)
+		res.URL, err = t.Canvas.Navigate(ctx, session, url)
+	case "eval":
+		js, _ := params["javaScript"].(string)
+		res.Result, err = t.Canvas.Eval(ctx, session, js)
+	case "snapshot":
+		format, _ := params["outputFormat"].(string)
+		if format == "" {
+			format = "png"
+		}
+		var data []byte
+		if data, err = t.Canvas.Snapshot(ctx, session, format); err == nil {
+			res.Screenshot = base64.StdEncoding.EncodeToString(data)
+			res.FilePath = saveSnapshot(data, format)
+		}
+	case "a2ui_push":
+		var content string
+		if content, err = a2uiContent(params); err == nil {
+			var n int
+			n, err = t.Canvas.PushA2UI(ctx, session, content)
+			res.Result = fmt.Sprintf("pushed %d frames", n)
+		}
+	case "a2ui_reset":
+		err = t.Canvas.ResetA2UI(ctx, session)
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
 	}
-
-	data, err := json.Marshal(payload)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+		return nil, err
 	}
+	return res, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", t.GatewayURL+"/node.invoke", strings.NewReader(string(data)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// a2uiContent returns the jsonl param, or the contents of jsonlPath.
+func a2uiContent(params map[string]interface{}) (string, error) {
+	content, _ := params["jsonl"].(string)
+	jsonlPath, _ := params["jsonlPath"].(string)
+	if content == "" && jsonlPath != "" {
+		data, err := os.ReadFile(jsonlPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read jsonl file: %w", err)
+		}
+		content = string(data)
+	}
+	if content == "" {
+		return "", fmt.Errorf("jsonl or jsonlPath is required")
 	}
+	return content, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	if t.GatewayToken != "" {
-		req.Header.Set("Authorization", "Bearer "+t.GatewayToken)
+// saveSnapshot writes a canvas screenshot to a temp file and returns its
+// path, or "" if it could not be written.
+func saveSnapshot(data []byte, format string) string {
+	ext := ".png"
+	if format == "jpeg" {
+		ext = ".jpg"
+	}
+	filePath := filepath.Join(os.TempDir(), fmt.Sprintf("canvas_%s%s", uuid.New().String()[:8], ext))
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return ""
 	}
+	return filePath
+}
 
-	client := &http.Client{Timeout: t.Timeout}
-	resp, err := client.Do(req)
+func (t *CanvasTool) invokeNode(ctx context.Context, nodeID, command string, cmdParams map[string]interface{}) (map[string]interface{}, error) {
+	nodes, err := nodeInvoker(t.Nodes, t.GatewayURL, t.GatewayToken, t.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, err
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
+	payload, err := nodes.InvokeNode(ctx, nodeID, command, cmdParams, t.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	result, _ := payload.(map[string]interface{})
+	if result == nil {
+		result = map[string]interface{}{}
 	}
-
 	return result, nil
 }
 
@@ -292,12 +360,7 @@ func (t *CanvasTool) eval(ctx context.Context, nodeID, javaScript string) (*Canv
 		return nil, err
 	}
 
-	result := ""
-	if payload, ok := resp["payload"].(map[string]interface{}); ok {
-		if r, ok := payload["result"].(string); ok {
-			result = r
-		}
-	}
+	result, _ := resp["result"].(string)
 
 	return &CanvasResult{
 		Action:  "eval",
@@ -319,27 +382,12 @@ func (t *CanvasTool) snapshot(ctx context.Context, nodeID, format string) (*Canv
 		return nil, err
 	}
 
-	screenshotData := ""
-	if payload, ok := resp["payload"].(map[string]interface{}); ok {
-		if data, ok := payload["base64"].(string); ok {
-			screenshotData = data
-		}
-	}
+	screenshotData, _ := resp["base64"].(string)
 
 	// Save to file
 	filePath := ""
-	if screenshotData != "" {
-		ext := ".png"
-		if format == "jpeg" {
-			ext = ".jpg"
-		}
-		fileName := fmt.Sprintf("canvas_%s%s", uuid.New().String()[:8], ext)
-		filePath = filepath.Join(os.TempDir(), fileName)
-
-		data, err := base64.StdEncoding.DecodeString(screenshotData)
-		if err == nil {
-			_ = os.WriteFile(filePath, data, 0644)
-		}
+	if data, err := base64.StdEncoding.DecodeString(screenshotData); err == nil && len(data) > 0 {
+		filePath = saveSnapshot(data, format)
 	}
 
 	return &CanvasResult{
@@ -351,21 +399,13 @@ func (t *CanvasTool) snapshot(ctx context.Context, nodeID, format string) (*Canv
 	}, nil
 }
 
-func (t *CanvasTool) a2uiPush(ctx context.Context, nodeID, jsonl, jsonlPath string) (*CanvasResult, error) {
-	content := jsonl
-	if content == "" && jsonlPath != "" {
-		data, err := os.ReadFile(jsonlPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read jsonl file: %w", err)
-		}
-		content = string(data)
-	}
-
-	if content == "" {
-		return nil, fmt.Errorf("jsonl or jsonlPath is required")
+func (t *CanvasTool) a2uiPush(ctx context.Context, nodeID string, params map[string]interface{}) (*CanvasResult, error) {
+	content, err := a2uiContent(params)
+	if err != nil {
+		return nil, err
 	}
 
-	_, err := t.invokeNode(ctx, nodeID, "canvas.a2ui.pushJSONL", map[string]interface{}{
+	_, err = t.invokeNode(ctx, nodeID, "canvas.a2ui.pushJSONL", map[string]interface{}{
 		"jsonl": content,
 	})
 	if err != nil {
@@ -392,6 +432,113 @@ func (t *CanvasTool) a2uiReset(ctx context.Context, nodeID string) (*CanvasResul
 	}, nil
 }
 
+// NodeInvoker reaches the nodes connected to a gateway.
+type NodeInvoker interface {
+	// ListNodes returns the connected nodes.
+	ListNodes(ctx context.Context) ([]NodeInfo, error)
+	// InvokeNode runs command on a node and returns its payload. nodeID
+	// may be a node ID or name; when empty, the first node advertising
+	// command is used.
+	InvokeNode(ctx context.Context, nodeID, command string, params map[string]interface{}, timeout time.Duration) (interface{}, error)
+}
+
+// nodeInvoker returns local when set, or a client for the gateway at url.
+func nodeInvoker(local NodeInvoker, url, token string, timeout time.Duration) (NodeInvoker, error) {
+	if local != nil {
+		return local, nil
+	}
+	if url == "" {
+		return nil, fmt.Errorf("no gateway available for nodes; start the gateway and connect a node with `liteclaw node run`")
+	}
+	return &gatewayNodes{URL: strings.TrimRight(url, "/"), Token: token, Timeout: timeout}, nil
+}
+
+// gatewayNodes reaches a gateway's node registry over its REST API.
+type gatewayNodes struct {
+	URL     string
+	Token   string
+	Timeout time.Duration
+}
+
+func (g *gatewayNodes) ListNodes(ctx context.Context) ([]NodeInfo, error) {
+	var resp struct {
+		Nodes []NodeInfo `json:"nodes"`
+	}
+	if err := g.call(ctx, http.MethodGet, "/api/nodes", nil, 0, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Nodes, nil
+}
+
+func (g *gatewayNodes) InvokeNode(ctx context.Context, nodeID, command string, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	var resp struct {
+		Payload interface{} `json:"payload"`
+	}
+	body := map[string]interface{}{
+		"nodeId":         nodeID,
+		"command":        command,
+		"params":         params,
+		"timeoutMs":      timeout.Milliseconds(),
+		"idempotencyKey": uuid.New().String(),
+	}
+	if err := g.call(ctx, http.MethodPost, "/api/nodes/invoke", body, timeout, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Payload, nil
+}
+
+// call sends a request to the gateway, allowing timeout on top of the
+// client timeout for invokes that wait on a node.
+func (g *gatewayNodes) call(ctx context.Context, method, path string, payload interface{}, timeout time.Duration, out interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		body = strings.NewReader(string(data))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.URL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+
+	client := &http.Client{Timeout: g.Timeout + timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		var e struct {
+			Message string `json:"message"`
+			Error   string `json:"error"`
+		}
+		_ = json.Unmarshal(data, &e)
+		if e.Error == "" {
+			e.Error = e.Message
+		}
+		if e.Error == "" {
+			e.Error = strings.TrimSpace(string(data))
+		}
+		return fmt.Errorf("gateway error (%d): %s", resp.StatusCode, e.Error)
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
 // NodesTool manages gateway nodes.
 type NodesTool struct {
 	// GatewayURL is the gateway server URL.
@@ -400,8 +547,15 @@ type NodesTool struct {
 	GatewayToken string
 	// AgentSessionKey is the current session key.
 	AgentSessionKey string
-	// Timeout is the request timeout.
+	// Timeout is the default invoke timeout.
 	Timeout time.Duration
+	// Nodes is the gateway's node registry; when nil, requests go to
+	// GatewayURL.
+	Nodes NodeInvoker
+	// Sandbox and AgentSandboxes mirror the exec tool's: sessions that
+	// must run commands in a sandbox may not run them on a node instead.
+	Sandbox        *sandbox.Sandbox
+	AgentSandboxes map[string]*sandbox.Sandbox
 }
 
 // NewNodesTool creates a new nodes tool.
@@ -420,15 +574,15 @@ func (t *NodesTool) Name() string {
 
 // Description returns the tool description.
 func (t *NodesTool) Description() string {
-	return `Manage gateway nodes (devices, displays, services).
+	return `Use paired nodes: remote machines connected to the gateway with ` + "`liteclaw node run`" + `.
 
 ACTIONS:
-- list: List all connected nodes
+- list: List connected nodes and the commands each advertises
 - status: Get status of a specific node
 - invoke: Invoke a command on a node
-- subscribe: Subscribe to node events
 
-Nodes can be displays, cameras, microphones, or other devices.`
+Common commands: system.run {command, cwd?, env?, timeoutMs?}, system.which {bins},
+camera.snap, screen.snapshot, location.get, canvas.present {url}.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -438,12 +592,12 @@ func (t *NodesTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"description": "Action: list, status, invoke, subscribe",
-				"enum":        []string{"list", "status", "invoke", "subscribe"},
+				"description": "Action: list, status, invoke",
+				"enum":        []string{"list", "status", "invoke"},
 			},
 			"nodeId": map[string]interface{}{
 				"type":        "string",
-				"description": "Node ID for node-specific actions",
+				"description": "Node ID or name for node-specific actions",
 			},
 			"command": map[string]interface{}{
 				"type":        "string",
@@ -453,6 +607,10 @@ func (t *NodesTool) Parameters() interface{} {
 				"type":        "object",
 				"description": "Parameters for invoke action",
 			},
+			"timeoutMs": map[string]interface{}{
+				"type":        "integer",
+				"description": "How long to wait for the node (default 30000)",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -464,7 +622,10 @@ type NodeInfo struct {
 	Name         string   `json:"name"`
 	Type         string   `json:"type"`
 	Status       string   `json:"status"`
+	Platform     string   `json:"platform,omitempty"`
 	Capabilities []string `json:"capabilities,omitempty"`
+	Commands     []string `json:"commands,omitempty"`
+	ConnectedAt  int64    `json:"connectedAtMs,omitempty"`
 }
 
 // NodesResult represents a nodes action result.
@@ -483,274 +644,58 @@ func (t *NodesTool) Execute(ctx context.Context, params map[string]interface{})
 	if action == "" {
 		return nil, fmt.Errorf("action is required")
 	}
+	nodes, err := nodeInvoker(t.Nodes, t.GatewayURL, t.GatewayToken, t.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	nodeID, _ := params["nodeId"].(string)
 
 	switch action {
 	case "list":
-		return t.listNodes(ctx)
+		list, err := nodes.ListNodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &NodesResult{Action: "list", Success: true, Nodes: list}, nil
+
 	case "status":
-		nodeID, _ := params["nodeId"].(string)
-		return t.getStatus(ctx, nodeID)
+		if nodeID == "" {
+			return nil, fmt.Errorf("nodeId is required")
+		}
+		list, err := nodes.ListNodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if list[i].ID == nodeID || strings.EqualFold(list[i].Name, nodeID) {
+				return &NodesResult{Action: "status", Success: true, Node: &list[i]}, nil
+			}
+		}
+		return &NodesResult{Action: "status", Success: true, Node: &NodeInfo{ID: nodeID, Status: "disconnected"}}, nil
+
 	case "invoke":
-		nodeID, _ := params["nodeId"].(string)
 		command, _ := params["command"].(string)
+		if nodeID == "" {
+			return nil, fmt.Errorf("nodeId is required")
+		}
+		if command == "" {
+			return nil, fmt.Errorf("command is required")
+		}
+		if command == "system.run" && sessionSandbox(ctx, t.Sandbox, t.AgentSandboxes) != nil {
+			return nil, fmt.Errorf("system.run is not allowed from sandboxed sessions")
+		}
 		cmdParams, _ := params["params"].(map[string]interface{})
-		return t.invoke(ctx, nodeID, command, cmdParams)
-	case "subscribe":
-		nodeID, _ := params["nodeId"].(string)
-		return t.subscribe(ctx, nodeID)
-	default:
-		return nil, fmt.Errorf("unknown action: %s", action)
-	}
-}
-
-func (t *NodesTool) callGateway(ctx context.Context, method string, payload interface{}) (map[string]interface{}, error) {
-	if t.GatewayURL == "" {
-		return map[string]interface{}{
-			"success": true,
-			"mock":    true,
-		}, nil
-	}
-
-	data, err := json.Marshal(payload)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal payload: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", t.GatewayURL+"/"+method, strings.NewReader(string(data)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if t.GatewayToken != "" {
-		req.Header.Set("Authorization", "Bearer "+t.GatewayToken)
-	}
-
-	client := &http.Client{Timeout: t.Timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var result map[string]interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
-	}
-
-	return result, nil
-}
-
-func (t *NodesTool) listNodes(ctx context.Context) (*NodesResult, error) {
-	resp, err := t.callGateway(ctx, "nodes.list", nil)
-	if err != nil {
-		return nil, err
-	}
-
-	nodes := []NodeInfo{}
-	if nodesData, ok := resp["nodes"].([]interface{}); ok {
-		for _, nd := range nodesData {
-			if nm, ok := nd.(map[string]interface{}); ok {
-				node := NodeInfo{
-					ID:     fmt.Sprint(nm["id"]),
-					Name:   fmt.Sprint(nm["name"]),
-					Type:   fmt.Sprint(nm["type"]),
-					Status: fmt.Sprint(nm["status"]),
-				}
-				if caps, ok := nm["capabilities"].([]interface{}); ok {
-					for _, c := range caps {
-						node.Capabilities = append(node.Capabilities, fmt.Sprint(c))
-					}
-				}
-				nodes = append(nodes, node)
-			}
+		timeout := t.Timeout
+		if ms := intParam(params["timeoutMs"]); ms > 0 {
+			timeout = time.Duration(ms) * time.Millisecond
 		}
-	}
-
-	return &NodesResult{
-		Action:  "list",
-		Success: true,
-		Nodes:   nodes,
-	}, nil
-}
-
-func (t *NodesTool) getStatus(ctx context.Context, nodeID string) (*NodesResult, error) {
-	if nodeID == "" {
-		return nil, fmt.Errorf("nodeId is required")
-	}
-
-	resp, err := t.callGateway(ctx, "nodes.status", map[string]interface{}{
-		"nodeId": nodeID,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	node := &NodeInfo{
-		ID:     nodeID,
-		Status: "unknown",
-	}
-	if nd, ok := resp["node"].(map[string]interface{}); ok {
-		node.Name = fmt.Sprint(nd["name"])
-		node.Type = fmt.Sprint(nd["type"])
-		node.Status = fmt.Sprint(nd["status"])
-	}
-
-	return &NodesResult{
-		Action:  "status",
-		Success: true,
-		Node:    node,
-	}, nil
-}
-
-func (t *NodesTool) invoke(ctx context.Context, nodeID, command string, cmdParams map[string]interface{}) (*NodesResult, error) {
-	if nodeID == "" {
-		return nil, fmt.Errorf("nodeId is required")
-	}
-	if command == "" {
-		return nil, fmt.Errorf("command is required")
-	}
-
-	resp, err := t.callGateway(ctx, "node.invoke", map[string]interface{}{
-		"nodeId":         nodeID,
-		"command":        command,
-		"params":         cmdParams,
-		"idempotencyKey": uuid.New().String(),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &NodesResult{
-		Action:  "invoke",
-		Success: true,
-		Result:  resp["result"],
-	}, nil
-}
-
-func (t *NodesTool) subscribe(ctx context.Context, nodeID string) (*NodesResult, error) {
-	if nodeID == "" {
-		return nil, fmt.Errorf("nodeId is required")
-	}
-
-	_, err := t.callGateway(ctx, "nodes.subscribe", map[string]interface{}{
-		"nodeId": nodeID,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &NodesResult{
-		Action:  "subscribe",
-		Success: true,
-	}, nil
-}
-
-// TtsTool provides text-to-speech functionality.
-type TtsTool struct {
-	// DefaultVoice is the default voice to use.
-	DefaultVoice string
-	// OutputDir is where audio files are saved.
-	OutputDir string
-}
-
-// NewTtsTool creates a new TTS tool.
-func NewTtsTool() *TtsTool {
-	return &TtsTool{
-		DefaultVoice: "alloy",
-		OutputDir:    os.TempDir(),
-	}
-}
-
-// Name returns the tool name.
-func (t *TtsTool) Name() string {
-	return "tts"
-}
-
-// Description returns the tool description.
-func (t *TtsTool) Description() string {
-	return `Convert text to speech audio.
-Supports multiple voices and output formats.
-Audio can be played directly or saved to file.`
-}
-
-// Parameters returns the JSON Schema for parameters.
-func (t *TtsTool) Parameters() interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"text": map[string]interface{}{
-				"type":        "string",
-				"description": "Text to convert to speech",
-			},
-			"voice": map[string]interface{}{
-				"type":        "string",
-				"description": "Voice to use (alloy, echo, fable, onyx, nova, shimmer)",
-				"enum":        []string{"alloy", "echo", "fable", "onyx", "nova", "shimmer"},
-			},
-			"speed": map[string]interface{}{
-				"type":        "number",
-				"description": "Speaking speed (0.25 to 4.0, default: 1.0)",
-				"minimum":     0.25,
-				"maximum":     4.0,
-			},
-			"format": map[string]interface{}{
-				"type":        "string",
-				"description": "Output format: mp3, opus, aac, flac",
-				"enum":        []string{"mp3", "opus", "aac", "flac"},
-			},
-			"play": map[string]interface{}{
-				"type":        "boolean",
-				"description": "Play audio immediately after generation",
-			},
-		},
-		"required": []string{"text"},
-	}
-}
-
-// TtsResult represents TTS generation result.
-type TtsResult struct {
-	Text     string  `json:"text"`
-	Voice    string  `json:"voice"`
-	Format   string  `json:"format"`
-	FilePath string  `json:"filePath,omitempty"`
-	Duration float64 `json:"duration,omitempty"`
-	Played   bool    `json:"played"`
-	Error    string  `json:"error,omitempty"`
-}
-
-// Execute generates speech from text.
-func (t *TtsTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	text, _ := params["text"].(string)
-	if text == "" {
-		return nil, fmt.Errorf("text is required")
-	}
-
-	voice, _ := params["voice"].(string)
-	if voice == "" {
-		voice = t.DefaultVoice
-	}
+		result, err := nodes.InvokeNode(ctx, nodeID, command, cmdParams, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return &NodesResult{Action: "invoke", Success: true, Result: result}, nil
 
-	format, _ := params["format"].(string)
-	if format == "" {
-		format = "mp3"
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
 	}
-
-	// Note: Actual TTS API integration would go here
-	// For now, return a placeholder indicating the tool is ready
-	fileName := fmt.Sprintf("tts_%s.%s", uuid.New().String()[:8], format)
-	filePath := filepath.Join(t.OutputDir, fileName)
-
-	return &TtsResult{
-		Text:     text,
-		Voice:    voice,
-		Format:   format,
-		FilePath: filePath,
-		Played:   false,
-	}, nil
 }
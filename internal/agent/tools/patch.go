@@ -0,0 +1,519 @@
+// Package tools provides agent tool implementations.
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+)
+
+// ApplyPatchTool applies multi-file patches atomically.
+type ApplyPatchTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
+
+// NewApplyPatchTool creates a new apply_patch tool.
+func NewApplyPatchTool() *ApplyPatchTool {
+	return &ApplyPatchTool{}
+}
+
+// Name returns the tool name.
+func (t *ApplyPatchTool) Name() string {
+	return "apply_patch"
+}
+
+// Description returns the tool description.
+func (t *ApplyPatchTool) Description() string {
+	return `Apply a patch that adds, updates, deletes or moves one or more files.
+All hunks are validated before anything is written; if any hunk fails, no file changes.
+Format:
+*** Begin Patch
+*** Add File: path/new.txt
++first line
+*** Update File: path/old.go
+*** Move to: path/renamed.go   (optional)
+@@ func main() {               (optional anchor line)
+ context line
+-removed line
++added line
+*** Delete File: path/gone.txt
+*** End Patch
+Include about 3 lines of context around each change.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *ApplyPatchTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"patch": map[string]interface{}{
+				"type":        "string",
+				"description": "The full patch text, from *** Begin Patch to *** End Patch",
+			},
+			"dryRun": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Validate the patch and report the summary without writing",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+// Patch operation kinds.
+const (
+	PatchAdd    = "add"
+	PatchUpdate = "update"
+	PatchDelete = "delete"
+)
+
+// PatchFileResult summarizes the change to one file.
+type PatchFileResult struct {
+	Path    string `json:"path"`
+	Action  string `json:"action"`
+	MovedTo string `json:"movedTo,omitempty"`
+	Hunks   int    `json:"hunks,omitempty"`
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+}
+
+// PatchResult is the result of an apply_patch call.
+type PatchResult struct {
+	Files  []PatchFileResult `json:"files"`
+	DryRun bool              `json:"dryRun,omitempty"`
+}
+
+type patchHunk struct {
+	anchor string
+	old    []string
+	new    []string
+	eof    bool
+
+	added, removed int
+}
+
+type patchOp struct {
+	kind   string
+	path   string
+	moveTo string
+	lines  []string // content for add
+	hunks  []patchHunk
+}
+
+// Execute applies the patch.
+func (t *ApplyPatchTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	text, _ := params["patch"].(string)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("patch is required")
+	}
+	dryRun, _ := params["dryRun"].(bool)
+
+	ops, err := parsePatch(text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate everything and compute new contents before touching disk.
+	writes := map[string]*string{}    // resolved path -> new content (nil = delete)
+	modes := map[string]os.FileMode{} // mode for a new file, e.g. a moved one's
+	var order []string
+	stage := func(path string, content *string, mode os.FileMode) error {
+		if _, ok := writes[path]; ok {
+			return fmt.Errorf("%s is changed more than once in this patch", path)
+		}
+		writes[path] = content
+		if mode != 0 {
+			modes[path] = mode
+		}
+		order = append(order, path)
+		return nil
+	}
+
+	result := &PatchResult{DryRun: dryRun}
+	var problems []string
+	for _, op := range ops {
+		res, err := t.prepare(ctx, op, stage)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		result.Files = append(result.Files, res)
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("patch not applied, no files were changed:\n%s", strings.Join(problems, "\n"))
+	}
+	if dryRun {
+		return result, nil
+	}
+
+	if err := commitWrites(order, writes, modes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// prepare resolves paths and computes the new content for one operation.
+// stage records a write; a non-zero mode applies when the file is created.
+func (t *ApplyPatchTool) prepare(ctx context.Context, op patchOp, stage func(string, *string, os.FileMode) error) (PatchFileResult, error) {
+	res := PatchFileResult{Path: op.path, Action: op.kind}
+	path, err := resolvePath(ctx, t.Paths, op.path, pathguard.Write)
+	if err != nil {
+		return res, err
+	}
+
+	switch op.kind {
+	case PatchAdd:
+		if _, err := os.Stat(path); err == nil {
+			return res, fmt.Errorf("%s: cannot add, file already exists", op.path)
+		}
+		content := joinLines(op.lines, true)
+		res.Added = len(op.lines)
+		return res, stage(path, &content, 0)
+
+	case PatchDelete:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return res, fmt.Errorf("%s: cannot delete: %w", op.path, err)
+		}
+		lines, _ := splitLines(string(data))
+		res.Removed = len(lines)
+		return res, stage(path, nil, 0)
+	}
+
+	if len(op.hunks) == 0 && op.moveTo == "" {
+		return res, fmt.Errorf("%s: update has no hunks", op.path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return res, fmt.Errorf("%s: cannot update: %w", op.path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return res, fmt.Errorf("%s: cannot update: %w", op.path, err)
+	}
+	lines, trailingNewline := splitLines(string(data))
+	updated, err := applyHunks(lines, op.hunks)
+	if err != nil {
+		return res, fmt.Errorf("%s: %w", op.path, err)
+	}
+	res.Hunks = len(op.hunks)
+	for _, h := range op.hunks {
+		res.Added += h.added
+		res.Removed += h.removed
+	}
+	content := joinLines(updated, trailingNewline)
+
+	if op.moveTo == "" {
+		return res, stage(path, &content, 0)
+	}
+	dest, err := resolvePath(ctx, t.Paths, op.moveTo, pathguard.Write)
+	if err != nil {
+		return res, err
+	}
+	if _, err := os.Stat(dest); err == nil {
+		return res, fmt.Errorf("%s: cannot move, %s already exists", op.path, op.moveTo)
+	}
+	res.MovedTo = op.moveTo
+	if err := stage(path, nil, 0); err != nil {
+		return res, err
+	}
+	// The moved file keeps its mode, e.g. a script stays executable.
+	return res, stage(dest, &content, info.Mode().Perm())
+}
+
+// parsePatch parses the *** Begin Patch format.
+func parsePatch(text string) ([]patchOp, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i >= len(lines) || strings.TrimSpace(lines[i]) != "*** Begin Patch" {
+		return nil, fmt.Errorf("patch must start with *** Begin Patch")
+	}
+	i++
+
+	var ops []patchOp
+	var cur *patchOp
+	var hunk *patchHunk
+	flushHunk := func() {
+		if cur != nil && hunk != nil {
+			cur.hunks = append(cur.hunks, *hunk)
+		}
+		hunk = nil
+	}
+	flushOp := func() {
+		flushHunk()
+		if cur != nil {
+			ops = append(ops, *cur)
+		}
+		cur = nil
+	}
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "*** End Patch":
+			flushOp()
+			if len(ops) == 0 {
+				return nil, fmt.Errorf("patch contains no file operations")
+			}
+			return ops, nil
+		case strings.HasPrefix(line, "*** Add File: "):
+			flushOp()
+			cur = &patchOp{kind: PatchAdd, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Add File: "))}
+		case strings.HasPrefix(line, "*** Update File: "):
+			flushOp()
+			cur = &patchOp{kind: PatchUpdate, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Update File: "))}
+		case strings.HasPrefix(line, "*** Delete File: "):
+			flushOp()
+			cur = &patchOp{kind: PatchDelete, path: strings.TrimSpace(strings.TrimPrefix(line, "*** Delete File: "))}
+		case strings.HasPrefix(line, "*** Move to: "):
+			if cur == nil || cur.kind != PatchUpdate || hunk != nil {
+				return nil, fmt.Errorf("line %d: *** Move to must follow *** Update File", i+1)
+			}
+			cur.moveTo = strings.TrimSpace(strings.TrimPrefix(line, "*** Move to: "))
+		case strings.TrimSpace(line) == "*** End of File":
+			if hunk == nil {
+				return nil, fmt.Errorf("line %d: *** End of File outside a hunk", i+1)
+			}
+			hunk.eof = true
+		case cur == nil:
+			return nil, fmt.Errorf("line %d: expected a file operation, got %q", i+1, line)
+		case cur.kind == PatchAdd:
+			if !strings.HasPrefix(line, "+") {
+				return nil, fmt.Errorf("line %d: lines of an added file must start with +", i+1)
+			}
+			cur.lines = append(cur.lines, line[1:])
+		case cur.kind == PatchDelete:
+			if strings.TrimSpace(line) != "" {
+				return nil, fmt.Errorf("line %d: unexpected content after *** Delete File", i+1)
+			}
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk = &patchHunk{anchor: strings.TrimSpace(strings.TrimPrefix(line, "@@"))}
+		case line == "" && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "***"):
+			// Blank separator before the next operation.
+		default:
+			if hunk == nil {
+				// Tolerate a missing @@ before the first hunk.
+				hunk = &patchHunk{}
+			}
+			prefix, body := byte(' '), ""
+			if line != "" {
+				prefix, body = line[0], line[1:]
+			}
+			switch prefix {
+			case ' ':
+				hunk.old = append(hunk.old, body)
+				hunk.new = append(hunk.new, body)
+			case '-':
+				hunk.old = append(hunk.old, body)
+				hunk.removed++
+			case '+':
+				hunk.new = append(hunk.new, body)
+				hunk.added++
+			default:
+				return nil, fmt.Errorf("line %d: hunk lines must start with ' ', '-' or '+', got %q", i+1, line)
+			}
+		}
+	}
+	return nil, fmt.Errorf("patch must end with *** End Patch")
+}
+
+// applyHunks applies hunks in order. Matching is exact first, then ignores
+// trailing whitespace, then surrounding whitespace.
+func applyHunks(lines []string, hunks []patchHunk) ([]string, error) {
+	out := append([]string(nil), lines...)
+	cursor := 0
+	for n, h := range hunks {
+		if h.anchor != "" {
+			idx := findLines(out, []string{h.anchor}, cursor)
+			if idx < 0 {
+				return nil, fmt.Errorf("hunk %d: anchor line %q not found", n+1, h.anchor)
+			}
+			cursor = idx + 1
+		}
+
+		var idx int
+		switch {
+		case len(h.old) == 0 && h.eof:
+			idx = len(out)
+		case len(h.old) == 0:
+			idx = cursor
+		case h.eof:
+			idx = findLines(out, h.old, len(out)-len(h.old))
+		default:
+			idx = findLines(out, h.old, cursor)
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("hunk %d: context not found%s\nexpected:\n%s",
+				n+1, nearestHint(out, h.old), indentLines(h.old))
+		}
+
+		out = append(out[:idx], append(append([]string(nil), h.new...), out[idx+len(h.old):]...)...)
+		cursor = idx + len(h.new)
+	}
+	return out, nil
+}
+
+// findLines returns the index of want in lines at or after start, or -1.
+func findLines(lines, want []string, start int) int {
+	if start < 0 {
+		start = 0
+	}
+	normalizers := []func(string) string{
+		func(s string) string { return s },
+		func(s string) string { return strings.TrimRight(s, " \t") },
+		strings.TrimSpace,
+	}
+	for _, norm := range normalizers {
+	search:
+		for i := start; i+len(want) <= len(lines); i++ {
+			for j, w := range want {
+				if norm(lines[i+j]) != norm(w) {
+					continue search
+				}
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+// nearestHint points at the line matching the hunk's first line, if any.
+func nearestHint(lines, want []string) string {
+	if len(want) == 0 {
+		return ""
+	}
+	first := strings.TrimSpace(want[0])
+	for i, l := range lines {
+		if strings.TrimSpace(l) == first {
+			return fmt.Sprintf(" (first line matches line %d, but the following lines differ)", i+1)
+		}
+	}
+	return " (first line not found in file)"
+}
+
+func indentLines(lines []string) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString("  | ")
+		b.WriteString(l)
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// splitLines splits file content into lines and reports whether it ended
+// with a newline.
+func splitLines(s string) ([]string, bool) {
+	if s == "" {
+		return nil, true
+	}
+	trailing := strings.HasSuffix(s, "\n")
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n"), trailing
+}
+
+func joinLines(lines []string, trailingNewline bool) string {
+	s := strings.Join(lines, "\n")
+	if trailingNewline && len(lines) > 0 {
+		s += "\n"
+	}
+	return s
+}
+
+// commitWrites writes every staged file to a temporary sibling first, then
+// renames them into place. Existing files keep their mode; new files get
+// the one in modes, or 0644. If any step fails, files already replaced are
+// restored from their original contents.
+func commitWrites(order []string, writes map[string]*string, modes map[string]os.FileMode) error {
+	type original struct {
+		data   []byte
+		mode   os.FileMode
+		exists bool
+	}
+	originals := map[string]original{}
+	temps := map[string]string{}
+	cleanup := func() {
+		for _, tmp := range temps {
+			_ = os.Remove(tmp)
+		}
+	}
+
+	for _, path := range order {
+		o := original{mode: 0644}
+		if mode, ok := modes[path]; ok {
+			o.mode = mode
+		}
+		if info, err := os.Stat(path); err == nil {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				cleanup()
+				return err
+			}
+			o = original{data: data, mode: info.Mode().Perm(), exists: true}
+		}
+		originals[path] = o
+
+		content := writes[path]
+		if content == nil {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			cleanup()
+			return err
+		}
+		f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".patch-*")
+		if err != nil {
+			cleanup()
+			return err
+		}
+		temps[path] = f.Name()
+		_, werr := f.WriteString(*content)
+		cerr := f.Close()
+		if werr == nil {
+			werr = cerr
+		}
+		if werr == nil {
+			werr = os.Chmod(f.Name(), o.mode)
+		}
+		if werr != nil {
+			cleanup()
+			return werr
+		}
+	}
+
+	var done []string
+	rollback := func() {
+		for _, path := range done {
+			o := originals[path]
+			if o.exists {
+				_ = os.WriteFile(path, o.data, o.mode)
+			} else {
+				_ = os.Remove(path)
+			}
+		}
+		cleanup()
+	}
+	for _, path := range order {
+		var err error
+		if tmp, ok := temps[path]; ok {
+			err = os.Rename(tmp, path)
+			delete(temps, path)
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			rollback()
+			return fmt.Errorf("failed to apply patch to %s (changes rolled back): %w", path, err)
+		}
+		done = append(done, path)
+	}
+	return nil
+}
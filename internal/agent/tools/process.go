@@ -4,38 +4,553 @@ package tools
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
-	"syscall"
 	"time"
+
+	"github.com/creack/pty"
+)
+
+// Process statuses.
+const (
+	ProcessRunning = "running"
+	ProcessDone    = "done"
+	ProcessError   = "error"
+	ProcessKilled  = "killed"
 )
 
+// DefaultProcessBufferSize is the in-memory output kept per process.
+const DefaultProcessBufferSize = 256 * 1024
+
 // ProcessManager manages background processes.
 type ProcessManager struct {
 	mu        sync.RWMutex
 	processes map[string]*ManagedProcess
+
+	// LogDir receives one <id>.log file per process with its full output.
+	LogDir string
+	// BufferSize is the size of each process's in-memory ring buffer.
+	BufferSize int
+	// OnExit, when set, is called after a process exits.
+	OnExit func(ProcessInfo)
 }
 
 // ManagedProcess represents a managed background process.
 type ManagedProcess struct {
-	ID        string    `json:"id"`
-	Command   string    `json:"command"`
-	PID       int       `json:"pid"`
-	Status    string    `json:"status"` // running, done, error
-	StartedAt time.Time `json:"startedAt"`
-	EndedAt   time.Time `json:"endedAt,omitempty"`
-	ExitCode  int       `json:"exitCode,omitempty"`
-	Output    string    `json:"output,omitempty"`
-	Error     string    `json:"error,omitempty"`
+	ID         string    `json:"id"`
+	Command    string    `json:"command"`
+	PID        int       `json:"pid"`
+	Status     string    `json:"status"` // running, done, error, killed
+	SessionKey string    `json:"sessionKey,omitempty"`
+	PTY        bool      `json:"pty,omitempty"`
+	Sandbox    string    `json:"sandbox,omitempty"`
+	LogPath    string    `json:"logPath,omitempty"`
+	StartedAt  time.Time `json:"startedAt"`
+	EndedAt    time.Time `json:"endedAt,omitempty"`
+	ExitCode   int       `json:"exitCode,omitempty"`
+	Output     string    `json:"output,omitempty"`
+	Error      string    `json:"error,omitempty"`
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	tty   *os.File
+	ring  *ringBuffer
+	log   *os.File
+	done  chan struct{}
+}
 
-	cmd *exec.Cmd
+// ProcessStartOptions describes a process session started by Start.
+type ProcessStartOptions struct {
+	// SessionKey is the agent session that owns the process.
+	SessionKey string
+	// PTY runs the process in a pseudo-terminal.
+	PTY bool
+	// Sandbox is the sandbox backend the command runs in, if any.
+	Sandbox string
 }
 
 // Global process manager instance
-var processManager = &ProcessManager{
-	processes: make(map[string]*ManagedProcess),
+var processManager = NewProcessManager(filepath.Join(os.TempDir(), "liteclaw-processes"))
+
+// NewProcessManager creates a process manager that writes logs to logDir.
+func NewProcessManager(logDir string) *ProcessManager {
+	return &ProcessManager{
+		processes:  make(map[string]*ManagedProcess),
+		LogDir:     logDir,
+		BufferSize: DefaultProcessBufferSize,
+	}
+}
+
+// Processes returns the process manager shared by the exec and process tools.
+func Processes() *ProcessManager {
+	return processManager
+}
+
+// Start starts cmd as a process session, capturing its output and keeping
+// stdin (or the PTY) open for Write and SendKeys.
+func (m *ProcessManager) Start(id, command string, cmd *exec.Cmd, opts ProcessStartOptions) (*ManagedProcess, error) {
+	p := &ManagedProcess{
+		ID:         id,
+		Command:    command,
+		Status:     ProcessRunning,
+		SessionKey: opts.SessionKey,
+		PTY:        opts.PTY,
+		Sandbox:    opts.Sandbox,
+		cmd:        cmd,
+		ring:       newRingBuffer(m.BufferSize),
+		done:       make(chan struct{}),
+	}
+
+	if m.LogDir != "" {
+		if err := os.MkdirAll(m.LogDir, 0700); err == nil {
+			p.LogPath = filepath.Join(m.LogDir, id+".log")
+			p.log, _ = os.OpenFile(p.LogPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if p.log == nil {
+				p.LogPath = ""
+			}
+		}
+	}
+	out := &processWriter{m: m, p: p}
+
+	copied := make(chan struct{})
+	if opts.PTY {
+		tty, err := pty.Start(cmd)
+		if err != nil {
+			p.closeLog()
+			return nil, err
+		}
+		p.tty = tty
+		go func() {
+			_, _ = io.Copy(out, tty)
+			close(copied)
+		}()
+	} else {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			p.closeLog()
+			return nil, err
+		}
+		p.stdin = stdin
+		cmd.Stdout = out
+		cmd.Stderr = out
+		setProcessGroup(cmd)
+		if err := cmd.Start(); err != nil {
+			p.closeLog()
+			return nil, err
+		}
+		close(copied)
+	}
+
+	p.PID = cmd.Process.Pid
+	p.StartedAt = time.Now()
+
+	m.mu.Lock()
+	m.processes[id] = p
+	m.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		if p.tty != nil {
+			// The PTY reader ends once the child side is closed.
+			select {
+			case <-copied:
+			case <-time.After(2 * time.Second):
+			}
+			_ = p.tty.Close()
+		}
+		<-copied
+
+		m.mu.Lock()
+		p.EndedAt = time.Now()
+		switch {
+		case p.Status == ProcessKilled:
+		case err != nil:
+			p.Status = ProcessError
+			p.Error = err.Error()
+		default:
+			p.Status = ProcessDone
+		}
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			p.ExitCode = exitErr.ExitCode()
+		}
+		p.closeLog()
+		info := p.info()
+		onExit := m.OnExit
+		m.mu.Unlock()
+
+		close(p.done)
+		if onExit != nil {
+			onExit(info)
+		}
+	}()
+
+	return p, nil
+}
+
+// Get returns a snapshot of a process.
+func (m *ProcessManager) Get(id string) (ProcessInfo, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.processes[id]
+	if !ok {
+		return ProcessInfo{}, false
+	}
+	return p.info(), true
+}
+
+// List returns all tracked processes, newest first.
+func (m *ProcessManager) List() []ProcessInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]ProcessInfo, 0, len(m.processes))
+	for _, p := range m.processes {
+		list = append(list, p.info())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt > list[j].StartedAt })
+	return list
+}
+
+// Wait blocks until the process exits or ctx is done.
+func (m *ProcessManager) Wait(ctx context.Context, id string) (ProcessInfo, error) {
+	m.mu.RLock()
+	p, ok := m.processes[id]
+	m.mu.RUnlock()
+	if !ok {
+		return ProcessInfo{}, fmt.Errorf("process not found: %s", id)
+	}
+	if p.done != nil {
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return ProcessInfo{}, ctx.Err()
+		}
+	}
+	info, _ := m.Get(id)
+	return info, nil
+}
+
+// ProcessOutput is a slice of a process's output.
+type ProcessOutput struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	// Offset is where Data starts in the process's output stream.
+	Offset int64 `json:"offset"`
+	// NextOffset is the offset to pass to read the following output.
+	NextOffset int64  `json:"nextOffset"`
+	Data       string `json:"data"`
+	// Skipped is the number of bytes between the requested offset and Offset
+	// that are no longer available.
+	Skipped  int64 `json:"skipped,omitempty"`
+	ExitCode int   `json:"exitCode,omitempty"`
+}
+
+// Read returns up to limit bytes of output starting at offset. Output that
+// has left the ring buffer is read back from the log file.
+func (m *ProcessManager) Read(id string, offset int64, limit int) (*ProcessOutput, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	p, ok := m.processes[id]
+	if !ok {
+		return nil, fmt.Errorf("process not found: %s", id)
+	}
+	if limit <= 0 {
+		limit = 16 * 1024
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	res := &ProcessOutput{ID: id, Status: p.Status, ExitCode: p.ExitCode}
+	if p.ring == nil {
+		// Registered without output capture.
+		data := p.Output
+		if offset > int64(len(data)) {
+			offset = int64(len(data))
+		}
+		end := offset + int64(limit)
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		res.Offset, res.NextOffset, res.Data = offset, end, data[offset:end]
+		return res, nil
+	}
+
+	total := p.ring.total
+	if offset > total {
+		offset = total
+	}
+	start := p.ring.start()
+	if offset < start && p.LogPath != "" {
+		if data, err := readAt(p.LogPath, offset, limit); err == nil {
+			res.Offset, res.Data = offset, string(data)
+			res.NextOffset = offset + int64(len(data))
+			return res, nil
+		}
+	}
+	if offset < start {
+		res.Skipped = start - offset
+		offset = start
+	}
+	data := p.ring.slice(offset, limit)
+	res.Offset, res.Data = offset, string(data)
+	res.NextOffset = offset + int64(len(data))
+	return res, nil
+}
+
+// Write sends data to the process's stdin (or PTY). With eof the stdin pipe
+// is closed afterwards.
+func (m *ProcessManager) Write(id, data string, eof bool) error {
+	m.mu.RLock()
+	p, ok := m.processes[id]
+	running := ok && p.Status == ProcessRunning
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("process not found: %s", id)
+	}
+	if !running {
+		return fmt.Errorf("process %s is not running", id)
+	}
+
+	var w io.Writer
+	switch {
+	case p.tty != nil:
+		w = p.tty
+	case p.stdin != nil:
+		w = p.stdin
+	default:
+		return fmt.Errorf("process %s does not accept input", id)
+	}
+	if data != "" {
+		if _, err := io.WriteString(w, data); err != nil {
+			return err
+		}
+	}
+	if eof {
+		if p.tty != nil {
+			// Ctrl-D signals end of input on a terminal.
+			_, err := io.WriteString(p.tty, "\x04")
+			return err
+		}
+		return p.stdin.Close()
+	}
+	return nil
+}
+
+// SendKeys sends named keys (Enter, C-c, Up, ...) to a process.
+func (m *ProcessManager) SendKeys(id string, keys []string) error {
+	var b strings.Builder
+	for _, k := range keys {
+		seq, err := keySequence(k)
+		if err != nil {
+			return err
+		}
+		b.WriteString(seq)
+	}
+	return m.Write(id, b.String(), false)
+}
+
+// Kill terminates a running process and the children it started.
+func (m *ProcessManager) Kill(id string) error {
+	m.mu.Lock()
+	p, ok := m.processes[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("process not found: %s", id)
+	}
+	if p.Status != ProcessRunning {
+		m.mu.Unlock()
+		return nil
+	}
+	p.Status = ProcessKilled
+	m.mu.Unlock()
+
+	// A sandbox container outlives its runtime client; the command's
+	// Cancel stops it.
+	if p.Sandbox != "" && p.cmd.Cancel != nil {
+		_ = p.cmd.Cancel()
+	}
+	if err := killProcess(p.PID); err != nil {
+		return fmt.Errorf("failed to kill process: %w", err)
+	}
+	return nil
+}
+
+// KillAll terminates every running process.
+func (m *ProcessManager) KillAll() {
+	m.mu.RLock()
+	var ids []string
+	for id, p := range m.processes {
+		if p.Status == ProcessRunning {
+			ids = append(ids, id)
+		}
+	}
+	m.mu.RUnlock()
+	for _, id := range ids {
+		_ = m.Kill(id)
+	}
+}
+
+// Prune forgets processes that ended more than ttl ago and removes their logs.
+func (m *ProcessManager) Prune(ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, p := range m.processes {
+		if p.Status != ProcessRunning && !p.EndedAt.IsZero() && time.Since(p.EndedAt) > ttl {
+			if p.LogPath != "" {
+				_ = os.Remove(p.LogPath)
+			}
+			delete(m.processes, id)
+		}
+	}
+}
+
+func (p *ManagedProcess) info() ProcessInfo {
+	info := ProcessInfo{
+		ID:         p.ID,
+		Command:    p.Command,
+		PID:        p.PID,
+		Status:     p.Status,
+		SessionKey: p.SessionKey,
+		PTY:        p.PTY,
+		Sandbox:    p.Sandbox,
+		LogPath:    p.LogPath,
+		StartedAt:  p.StartedAt.Format(time.RFC3339),
+		ExitCode:   p.ExitCode,
+		Error:      p.Error,
+	}
+	if p.ring != nil {
+		info.OutputBytes = p.ring.total
+	} else {
+		info.OutputBytes = int64(len(p.Output))
+	}
+	if !p.EndedAt.IsZero() {
+		info.EndedAt = p.EndedAt.Format(time.RFC3339)
+	}
+	return info
+}
+
+func (p *ManagedProcess) closeLog() {
+	if p.log != nil {
+		_ = p.log.Close()
+		p.log = nil
+	}
+}
+
+// processWriter appends output to the ring buffer and the log file.
+type processWriter struct {
+	m *ProcessManager
+	p *ManagedProcess
+}
+
+func (w *processWriter) Write(b []byte) (int, error) {
+	w.m.mu.Lock()
+	defer w.m.mu.Unlock()
+	w.p.ring.Write(b)
+	if w.p.log != nil {
+		_, _ = w.p.log.Write(b)
+	}
+	return len(b), nil
+}
+
+// ringBuffer keeps the last size bytes written and counts the total.
+type ringBuffer struct {
+	buf   []byte
+	size  int
+	total int64
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	if size <= 0 {
+		size = DefaultProcessBufferSize
+	}
+	return &ringBuffer{size: size}
+}
+
+func (r *ringBuffer) Write(b []byte) {
+	r.total += int64(len(b))
+	if len(b) >= r.size {
+		r.buf = append(r.buf[:0], b[len(b)-r.size:]...)
+		return
+	}
+	if over := len(r.buf) + len(b) - r.size; over > 0 {
+		r.buf = append(r.buf[:0], r.buf[over:]...)
+	}
+	r.buf = append(r.buf, b...)
+}
+
+// start is the stream offset of the oldest byte still buffered.
+func (r *ringBuffer) start() int64 {
+	return r.total - int64(len(r.buf))
+}
+
+func (r *ringBuffer) slice(offset int64, limit int) []byte {
+	from := int(offset - r.start())
+	to := from + limit
+	if to > len(r.buf) {
+		to = len(r.buf)
+	}
+	return append([]byte(nil), r.buf[from:to]...)
+}
+
+func readAt(path string, offset int64, limit int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	buf := make([]byte, limit)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// namedKeys maps key names to terminal input sequences.
+var namedKeys = map[string]string{
+	"enter":     "\r",
+	"return":    "\r",
+	"tab":       "\t",
+	"space":     " ",
+	"escape":    "\x1b",
+	"esc":       "\x1b",
+	"backspace": "\x7f",
+	"delete":    "\x1b[3~",
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+	"home":      "\x1b[H",
+	"end":       "\x1b[F",
+	"pageup":    "\x1b[5~",
+	"pagedown":  "\x1b[6~",
+}
+
+// keySequence converts a key name such as "Enter", "Up" or "C-c" into the
+// bytes a terminal would send.
+func keySequence(key string) (string, error) {
+	if seq, ok := namedKeys[strings.ToLower(key)]; ok {
+		return seq, nil
+	}
+	lower := strings.ToLower(key)
+	for _, prefix := range []string{"c-", "ctrl-", "ctrl+"} {
+		if strings.HasPrefix(lower, prefix) {
+			rest := lower[len(prefix):]
+			if len(rest) == 1 && rest[0] >= 'a' && rest[0] <= 'z' {
+				return string(rune(rest[0] - 'a' + 1)), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown key %q (use names like Enter, Tab, Up, C-c, or write text with action=write)", key)
 }
 
 // ProcessTool manages background processes.
@@ -58,12 +573,16 @@ func (t *ProcessTool) Name() string {
 
 // Description returns the tool description.
 func (t *ProcessTool) Description() string {
-	return `Manage background processes started by the exec tool.
+	return `Manage background processes started by exec with background=true.
 Actions:
-- status: Check the status of a background process by ID or PID
 - list: List all tracked background processes
-- kill: Terminate a background process by ID or PID
-- output: Get the output of a background process`
+- status: Check the status of a process by ID or PID
+- output: Read output from offset (default 0); pass the returned nextOffset to read only new output
+- write: Send text to the process's stdin (eof=true closes stdin)
+- send_keys: Send keys to a process, e.g. ["Enter"], ["C-c"], ["Up", "Enter"] (pty=true sessions)
+- wait: Wait up to timeout seconds for the process to exit
+- kill: Terminate a process
+You are notified in this session when a background process exits.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -73,8 +592,8 @@ func (t *ProcessTool) Parameters() interface{} {
 		"properties": map[string]interface{}{
 			"action": map[string]interface{}{
 				"type":        "string",
-				"description": "Action to perform: status, list, kill, output",
-				"enum":        []string{"status", "list", "kill", "output"},
+				"description": "Action to perform: list, status, output, write, send_keys, wait, kill",
+				"enum":        []string{"list", "status", "output", "write", "send_keys", "wait", "kill"},
 			},
 			"id": map[string]interface{}{
 				"type":        "string",
@@ -84,6 +603,31 @@ func (t *ProcessTool) Parameters() interface{} {
 				"type":        "integer",
 				"description": "System PID of the process",
 			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Output offset to read from (output action)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum bytes to return (output action, default 16384)",
+			},
+			"data": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to write (write action); include \\n to submit a line",
+			},
+			"eof": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Close stdin after writing (write action)",
+			},
+			"keys": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Key names to send (send_keys action)",
+			},
+			"timeout": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seconds to wait (wait action, default 30)",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -97,13 +641,19 @@ type ProcessListResult struct {
 
 // ProcessInfo represents info about a process.
 type ProcessInfo struct {
-	ID        string `json:"id"`
-	Command   string `json:"command"`
-	PID       int    `json:"pid"`
-	Status    string `json:"status"`
-	StartedAt string `json:"startedAt"`
-	EndedAt   string `json:"endedAt,omitempty"`
-	ExitCode  int    `json:"exitCode,omitempty"`
+	ID          string `json:"id"`
+	Command     string `json:"command"`
+	PID         int    `json:"pid"`
+	Status      string `json:"status"`
+	SessionKey  string `json:"sessionKey,omitempty"`
+	PTY         bool   `json:"pty,omitempty"`
+	Sandbox     string `json:"sandbox,omitempty"`
+	LogPath     string `json:"logPath,omitempty"`
+	OutputBytes int64  `json:"outputBytes"`
+	StartedAt   string `json:"startedAt"`
+	EndedAt     string `json:"endedAt,omitempty"`
+	ExitCode    int    `json:"exitCode,omitempty"`
+	Error       string `json:"error,omitempty"`
 }
 
 // Execute performs the process action.
@@ -113,190 +663,192 @@ func (t *ProcessTool) Execute(ctx context.Context, params map[string]interface{}
 		return nil, fmt.Errorf("action is required")
 	}
 
-	switch action {
-	case "list":
-		return t.listProcesses()
-	case "status":
-		return t.getStatus(params)
-	case "kill":
-		return t.killProcess(params)
-	case "output":
-		return t.getOutput(params)
-	default:
-		return nil, fmt.Errorf("unknown action: %s", action)
+	processManager.Prune(t.CleanupDuration)
+
+	// A session only sees the processes it started, so a sandboxed session
+	// cannot read or drive another session's host processes. Calls without
+	// a session (the operator's CLI and REST views) see everything.
+	owner := ""
+	if sc, ok := SessionFromContext(ctx); ok {
+		owner = sc.Key
 	}
-}
 
-func (t *ProcessTool) listProcesses() (*ProcessListResult, error) {
-	processManager.mu.RLock()
-	defer processManager.mu.RUnlock()
+	if action == "list" {
+		processes := processManager.List()
+		if owner != "" {
+			mine := make([]ProcessInfo, 0, len(processes))
+			for _, p := range processes {
+				if p.SessionKey == owner {
+					mine = append(mine, p)
+				}
+			}
+			processes = mine
+		}
+		return &ProcessListResult{Processes: processes, Count: len(processes)}, nil
+	}
+
+	id, err := t.findProcess(params, owner)
+	if err != nil {
+		return nil, err
+	}
 
-	processes := make([]ProcessInfo, 0, len(processManager.processes))
-	for _, p := range processManager.processes {
-		info := ProcessInfo{
-			ID:        p.ID,
-			Command:   p.Command,
-			PID:       p.PID,
-			Status:    p.Status,
-			StartedAt: p.StartedAt.Format(time.RFC3339),
-			ExitCode:  p.ExitCode,
+	switch action {
+	case "status":
+		info, _ := processManager.Get(id)
+		return info, nil
+	case "output", "log":
+		offset, _ := params["offset"].(float64)
+		limit, _ := params["limit"].(float64)
+		return processManager.Read(id, int64(offset), int(limit))
+	case "write":
+		data, _ := params["data"].(string)
+		eof, _ := params["eof"].(bool)
+		if data == "" && !eof {
+			return nil, fmt.Errorf("data is required")
+		}
+		if err := processManager.Write(id, data, eof); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"id": id, "written": len(data), "eof": eof}, nil
+	case "send_keys":
+		keys := toStringSlice(params["keys"])
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("keys is required")
+		}
+		if err := processManager.SendKeys(id, keys); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"id": id, "keys": keys}, nil
+	case "wait":
+		timeout := 30 * time.Second
+		if s, ok := params["timeout"].(float64); ok && s > 0 {
+			timeout = time.Duration(s) * time.Second
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		info, err := processManager.Wait(waitCtx, id)
+		if err == context.DeadlineExceeded {
+			info, _ = processManager.Get(id)
+			return info, nil
+		}
+		return info, err
+	case "kill":
+		info, _ := processManager.Get(id)
+		if info.Status != ProcessRunning {
+			return map[string]interface{}{
+				"id":      id,
+				"status":  info.Status,
+				"message": "process is not running",
+			}, nil
 		}
-		if !p.EndedAt.IsZero() {
-			info.EndedAt = p.EndedAt.Format(time.RFC3339)
+		if err := processManager.Kill(id); err != nil {
+			return nil, err
 		}
-		processes = append(processes, info)
+		return map[string]interface{}{
+			"id":      id,
+			"pid":     info.PID,
+			"status":  ProcessKilled,
+			"message": "process killed",
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
 	}
-
-	return &ProcessListResult{
-		Processes: processes,
-		Count:     len(processes),
-	}, nil
 }
 
-func (t *ProcessTool) findProcess(params map[string]interface{}) (*ManagedProcess, error) {
+// findProcess resolves the id or pid parameter to a process ID. When owner
+// is set, processes started by other sessions are reported as not found.
+func (t *ProcessTool) findProcess(params map[string]interface{}, owner string) (string, error) {
 	id, _ := params["id"].(string)
 	pid, _ := params["pid"].(float64)
 
 	processManager.mu.RLock()
 	defer processManager.mu.RUnlock()
 
+	visible := func(p *ManagedProcess) bool {
+		return owner == "" || p.SessionKey == owner
+	}
 	if id != "" {
-		if p, ok := processManager.processes[id]; ok {
-			return p, nil
+		if p, ok := processManager.processes[id]; ok && visible(p) {
+			return id, nil
 		}
-		return nil, fmt.Errorf("process not found: %s", id)
+		return "", fmt.Errorf("process not found: %s", id)
 	}
 
 	if pid > 0 {
 		pidInt := int(pid)
 		for _, p := range processManager.processes {
-			if p.PID == pidInt {
-				return p, nil
+			if p.PID == pidInt && visible(p) {
+				return p.ID, nil
 			}
 		}
-		return nil, fmt.Errorf("process not found with PID: %d", pidInt)
+		return "", fmt.Errorf("process not found with PID: %d", pidInt)
 	}
 
-	return nil, fmt.Errorf("id or pid is required")
+	return "", fmt.Errorf("id or pid is required")
 }
 
-func (t *ProcessTool) getStatus(params map[string]interface{}) (interface{}, error) {
-	p, err := t.findProcess(params)
-	if err != nil {
-		return nil, err
-	}
-
-	// Update status if still running
-	if p.Status == "running" && p.cmd != nil && p.cmd.Process != nil {
-		proc, err := os.FindProcess(p.PID)
-		if err == nil {
-			err = proc.Signal(syscall.Signal(0))
-			if err != nil {
-				p.Status = "done"
-				p.EndedAt = time.Now()
+// toStringSlice converts a JSON array parameter to strings.
+func toStringSlice(v interface{}) []string {
+	switch vals := v.(type) {
+	case []string:
+		return vals
+	case []interface{}:
+		out := make([]string, 0, len(vals))
+		for _, item := range vals {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
 			}
 		}
+		return out
+	case string:
+		if vals != "" {
+			return []string{vals}
+		}
 	}
-
-	result := ProcessInfo{
-		ID:        p.ID,
-		Command:   p.Command,
-		PID:       p.PID,
-		Status:    p.Status,
-		StartedAt: p.StartedAt.Format(time.RFC3339),
-		ExitCode:  p.ExitCode,
-	}
-	if !p.EndedAt.IsZero() {
-		result.EndedAt = p.EndedAt.Format(time.RFC3339)
-	}
-
-	return result, nil
-}
-
-func (t *ProcessTool) killProcess(params map[string]interface{}) (interface{}, error) {
-	p, err := t.findProcess(params)
-	if err != nil {
-		return nil, err
-	}
-
-	if p.Status != "running" {
-		return map[string]interface{}{
-			"id":      p.ID,
-			"status":  p.Status,
-			"message": "process is not running",
-		}, nil
-	}
-
-	proc, err := os.FindProcess(p.PID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to find process: %w", err)
-	}
-
-	if err := proc.Kill(); err != nil {
-		return nil, fmt.Errorf("failed to kill process: %w", err)
-	}
-
-	processManager.mu.Lock()
-	p.Status = "killed"
-	p.EndedAt = time.Now()
-	processManager.mu.Unlock()
-
-	return map[string]interface{}{
-		"id":      p.ID,
-		"pid":     p.PID,
-		"status":  "killed",
-		"message": "process killed",
-	}, nil
-}
-
-func (t *ProcessTool) getOutput(params map[string]interface{}) (interface{}, error) {
-	p, err := t.findProcess(params)
-	if err != nil {
-		return nil, err
-	}
-
-	return map[string]interface{}{
-		"id":     p.ID,
-		"pid":    p.PID,
-		"status": p.Status,
-		"output": p.Output,
-		"error":  p.Error,
-		"length": len(p.Output),
-	}, nil
+	return nil
 }
 
-// RegisterProcess registers a background process for tracking.
+// RegisterProcess registers an already started process for tracking.
+// Its output is not captured; use ProcessManager.Start for full sessions.
 func RegisterProcess(id string, command string, cmd *exec.Cmd) {
 	processManager.mu.Lock()
 	defer processManager.mu.Unlock()
 
-	processManager.processes[id] = &ManagedProcess{
+	p := &ManagedProcess{
 		ID:        id,
 		Command:   command,
 		PID:       cmd.Process.Pid,
-		Status:    "running",
+		Status:    ProcessRunning,
 		StartedAt: time.Now(),
 		cmd:       cmd,
+		done:      make(chan struct{}),
 	}
+	processManager.processes[id] = p
 
 	// Start goroutine to wait for completion
 	go func() {
 		err := cmd.Wait()
 		processManager.mu.Lock()
-		defer processManager.mu.Unlock()
-
-		if p, ok := processManager.processes[id]; ok {
-			p.EndedAt = time.Now()
-			if err != nil {
-				p.Status = "error"
-				p.Error = err.Error()
-				if exitErr, ok := err.(*exec.ExitError); ok {
-					p.ExitCode = exitErr.ExitCode()
-				}
-			} else {
-				p.Status = "done"
-				p.ExitCode = 0
+		p.EndedAt = time.Now()
+		if err != nil {
+			if p.Status != ProcessKilled {
+				p.Status = ProcessError
 			}
+			p.Error = err.Error()
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				p.ExitCode = exitErr.ExitCode()
+			}
+		} else {
+			p.Status = ProcessDone
+			p.ExitCode = 0
+		}
+		info := p.info()
+		onExit := processManager.OnExit
+		processManager.mu.Unlock()
+
+		close(p.done)
+		if onExit != nil {
+			onExit(info)
 		}
 	}()
 }
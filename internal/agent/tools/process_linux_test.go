@@ -0,0 +1,47 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessKillChildren(t *testing.T) {
+	m := NewProcessManager("")
+	p, err := m.Start("proc_tree", "sleep in background", exec.Command("sh", "-c", "sleep 60 & echo $!; wait"), ProcessStartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var child int
+	for i := 0; i < 50 && child == 0; i++ {
+		time.Sleep(20 * time.Millisecond)
+		out, _ := m.Read(p.ID, 0, 0)
+		if out != nil {
+			child, _ = strconv.Atoi(strings.TrimSpace(out.Data))
+		}
+	}
+	if child == 0 {
+		t.Fatal("child pid was not printed")
+	}
+	if err := m.Kill(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.Wait(ctx, p.ID); err != nil {
+		t.Fatal(err)
+	}
+	// The orphaned child is gone, or a zombie awaiting its new parent.
+	for i := 0; i < 50; i++ {
+		stat, err := os.ReadFile("/proc/" + strconv.Itoa(child) + "/stat")
+		if err != nil || strings.Contains(string(stat), ") Z ") {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("child %d survived Kill", child)
+}
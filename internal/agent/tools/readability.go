@@ -0,0 +1,538 @@
+package tools
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// unlikelyContent matches class/id values of page chrome.
+	unlikelyContent = regexp.MustCompile(`(?i)(^|[\s_-])(comment|comments|sidebar|footer|nav|navbar|menu|breadcrumb|advert|ads?|sponsor|promo|related|share|social|cookie|banner|popup|modal|subscribe|newsletter|masthead|skip)([\s_-]|$)`)
+	// likelyContent rescues elements that match both patterns.
+	likelyContent = regexp.MustCompile(`(?i)(article|content|main|post|entry|story|body|text)`)
+)
+
+// prunedTags never contain readable content.
+var prunedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Iframe: true, atom.Form: true, atom.Button: true,
+	atom.Input: true, atom.Select: true, atom.Textarea: true, atom.Nav: true,
+	atom.Footer: true, atom.Aside: true, atom.Object: true, atom.Embed: true,
+	atom.Canvas: true, atom.Dialog: true, atom.Link: true, atom.Meta: true,
+}
+
+// extractReadable parses HTML and returns the page title and its main
+// content as Markdown, or as plain text when plain is set. Relative links
+// are resolved against base.
+func extractReadable(r io.Reader, base *url.URL, plain bool) (string, string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	if b := findElement(doc, atom.Base); b != nil && base != nil {
+		if href, err := base.Parse(attr(b, "href")); err == nil && attr(b, "href") != "" {
+			base = href
+		}
+	}
+
+	title := documentTitle(doc)
+	body := findElement(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	pruneNodes(body)
+
+	w := &mdWriter{base: base, plain: plain}
+	w.render(mainContent(body))
+	return title, w.String(), nil
+}
+
+func documentTitle(doc *html.Node) string {
+	var og string
+	walkElements(doc, func(n *html.Node) bool {
+		if n.DataAtom == atom.Meta && (attr(n, "property") == "og:title" || attr(n, "name") == "twitter:title") && og == "" {
+			og = strings.TrimSpace(attr(n, "content"))
+		}
+		return true
+	})
+	if t := findElement(doc, atom.Title); t != nil {
+		if title := collapseSpace(textContent(t)); title != "" {
+			return title
+		}
+	}
+	return og
+}
+
+// pruneNodes removes scripts, navigation, hidden elements and page chrome.
+func pruneNodes(root *html.Node) {
+	var remove []*html.Node
+	walkElements(root, func(n *html.Node) bool {
+		if prunedTags[n.DataAtom] || isHidden(n) {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom == atom.Header && n.Parent != nil && n.Parent.DataAtom == atom.Body {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom != atom.Body && n.DataAtom != atom.Article && n.DataAtom != atom.Main {
+			sig := attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "role")
+			if unlikelyContent.MatchString(sig) && !likelyContent.MatchString(sig) {
+				remove = append(remove, n)
+				return false
+			}
+		}
+		return true
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isHidden(n *html.Node) bool {
+	if hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" {
+		return true
+	}
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// mainContent picks the element holding the page's primary text: an
+// article or main element when present, otherwise the container that
+// scores highest on paragraph text with few links.
+func mainContent(body *html.Node) *html.Node {
+	var best *html.Node
+	bestLen := 0
+	walkElements(body, func(n *html.Node) bool {
+		if n.DataAtom == atom.Article {
+			if l := len(collapseSpace(textContent(n))); l > bestLen {
+				best, bestLen = n, l
+			}
+		}
+		return true
+	})
+	if best != nil && bestLen >= 200 {
+		return best
+	}
+
+	var main *html.Node
+	walkElements(body, func(n *html.Node) bool {
+		if main == nil && (n.DataAtom == atom.Main || attr(n, "role") == "main") {
+			main = n
+		}
+		return main == nil
+	})
+	if main != nil {
+		return main
+	}
+
+	scores := make(map[*html.Node]float64)
+	walkElements(body, func(n *html.Node) bool {
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Blockquote && n.DataAtom != atom.Li {
+			return true
+		}
+		text := collapseSpace(textContent(n))
+		if len(text) < 25 || n.Parent == nil {
+			return true
+		}
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+		scores[n.Parent] += score
+		if gp := n.Parent.Parent; gp != nil && gp.Type == html.ElementNode {
+			scores[gp] += score / 2
+		}
+		return true
+	})
+
+	best = nil
+	bestScore := 0.0
+	for n, s := range scores {
+		s *= 1 - linkDensity(n)
+		if s > bestScore {
+			best, bestScore = n, s
+		}
+	}
+	if best == nil {
+		return body
+	}
+	return best
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := len(collapseSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	walkElements(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			links += len(collapseSpace(textContent(c)))
+			return false
+		}
+		return true
+	})
+	return float64(links) / float64(total)
+}
+
+// mdWriter renders an HTML subtree as Markdown or plain text.
+type mdWriter struct {
+	base  *url.URL
+	plain bool
+	b     strings.Builder
+}
+
+func (w *mdWriter) sub() *mdWriter {
+	return &mdWriter{base: w.base, plain: w.plain}
+}
+
+// String returns the rendered text with blank lines collapsed.
+func (w *mdWriter) String() string {
+	lines := strings.Split(w.b.String(), "\n")
+	var out []string
+	blank := true
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		out = append(out, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+func (w *mdWriter) block() {
+	w.b.WriteString("\n\n")
+}
+
+func (w *mdWriter) text(s string) {
+	if s == "" {
+		return
+	}
+	body := collapseSpace(s)
+	if body == "" || strings.IndexAny(s[:1], " \t\r\n\f") == 0 {
+		w.spaceBefore()
+	}
+	w.b.WriteString(body)
+	if body != "" && strings.IndexAny(s[len(s)-1:], " \t\r\n\f") == 0 {
+		w.b.WriteByte(' ')
+	}
+}
+
+// inline renders children to a single line.
+func (w *mdWriter) inline(n *html.Node) string {
+	s := w.sub()
+	s.children(n)
+	return strings.Join(strings.Fields(s.String()), " ")
+}
+
+func (w *mdWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c)
+	}
+}
+
+func (w *mdWriter) render(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.DocumentNode:
+		w.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := w.inline(n)
+		if text == "" {
+			return
+		}
+		w.block()
+		if !w.plain {
+			w.b.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		}
+		w.b.WriteString(text)
+		w.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.Header,
+		atom.Figure, atom.Figcaption, atom.Address, atom.Details, atom.Summary,
+		atom.Dl, atom.Dt, atom.Dd, atom.Center:
+		w.block()
+		w.children(n)
+		w.block()
+	case atom.Br:
+		w.b.WriteString("\n")
+	case atom.Hr:
+		if !w.plain {
+			w.block()
+			w.b.WriteString("---")
+		}
+		w.block()
+	case atom.A:
+		text := w.inline(n)
+		href := w.resolve(attr(n, "href"))
+		if w.plain || href == "" || text == "" {
+			w.text(text)
+			return
+		}
+		w.spaceBefore()
+		w.b.WriteString("[" + strings.ReplaceAll(text, "]", "\\]") + "](" + href + ")")
+	case atom.Img:
+		alt := collapseSpace(attr(n, "alt"))
+		src := w.resolve(attr(n, "src"))
+		if w.plain || src == "" {
+			w.text(alt)
+			return
+		}
+		w.spaceBefore()
+		w.b.WriteString("![" + alt + "](" + src + ")")
+	case atom.Strong, atom.B:
+		w.wrap(n, "**")
+	case atom.Em, atom.I:
+		w.wrap(n, "_")
+	case atom.Del, atom.S, atom.Strike:
+		w.wrap(n, "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		w.wrap(n, "`")
+	case atom.Pre:
+		code := strings.Trim(textContent(n), "\n")
+		if code == "" {
+			return
+		}
+		w.block()
+		if w.plain {
+			w.b.WriteString(code)
+		} else {
+			lang := ""
+			if c := findElement(n, atom.Code); c != nil {
+				for _, cls := range strings.Fields(attr(c, "class")) {
+					if l, ok := strings.CutPrefix(cls, "language-"); ok {
+						lang = l
+					}
+				}
+			}
+			w.b.WriteString("```" + lang + "\n" + code + "\n```")
+		}
+		w.block()
+	case atom.Ul, atom.Ol:
+		w.list(n)
+	case atom.Blockquote:
+		s := w.sub()
+		s.children(n)
+		body := s.String()
+		if body == "" {
+			return
+		}
+		w.block()
+		if w.plain {
+			w.b.WriteString(body)
+		} else {
+			for i, line := range strings.Split(body, "\n") {
+				if i > 0 {
+					w.b.WriteString("\n")
+				}
+				w.b.WriteString(strings.TrimRight("> "+line, " "))
+			}
+		}
+		w.block()
+	case atom.Table:
+		w.table(n)
+	case atom.Head, atom.Title:
+		return
+	default:
+		w.children(n)
+	}
+}
+
+func (w *mdWriter) spaceBefore() {
+	cur := w.b.String()
+	if cur != "" && !strings.HasSuffix(cur, " ") && !strings.HasSuffix(cur, "\n") && !strings.HasSuffix(cur, "(") {
+		w.b.WriteByte(' ')
+	}
+}
+
+func (w *mdWriter) wrap(n *html.Node, marker string) {
+	text := w.inline(n)
+	if text == "" {
+		return
+	}
+	if w.plain {
+		w.text(text)
+		return
+	}
+	w.spaceBefore()
+	w.b.WriteString(marker + text + marker)
+}
+
+func (w *mdWriter) list(n *html.Node) {
+	ordered := n.DataAtom == atom.Ol
+	i := 1
+	w.block()
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.DataAtom != atom.Li {
+			continue
+		}
+		s := w.sub()
+		s.children(c)
+		item := s.String()
+		if item == "" {
+			continue
+		}
+		marker := "- "
+		if ordered {
+			marker = fmt.Sprintf("%d. ", i)
+		}
+		i++
+		for j, line := range strings.Split(item, "\n") {
+			if j == 0 {
+				w.b.WriteString(marker + line)
+			} else if line != "" {
+				w.b.WriteString("\n" + strings.Repeat(" ", len(marker)) + line)
+			}
+		}
+		w.b.WriteString("\n")
+	}
+	w.block()
+}
+
+func (w *mdWriter) table(n *html.Node) {
+	var rows [][]string
+	header := false
+	walkElements(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.Table && c != n {
+			return false
+		}
+		if c.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				cells = append(cells, strings.ReplaceAll(w.inline(cell), "|", "\\|"))
+				if len(rows) == 0 && cell.DataAtom == atom.Th {
+					header = true
+				}
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return
+	}
+
+	w.block()
+	if w.plain {
+		for _, r := range rows {
+			w.b.WriteString(strings.Join(r, "\t") + "\n")
+		}
+		w.block()
+		return
+	}
+
+	cols := 0
+	for _, r := range rows {
+		cols = max(cols, len(r))
+	}
+	writeRow := func(r []string) {
+		for len(r) < cols {
+			r = append(r, "")
+		}
+		w.b.WriteString("| " + strings.Join(r, " | ") + " |\n")
+	}
+	if !header {
+		// Markdown tables need a header row.
+		writeRow(make([]string, cols))
+	} else {
+		writeRow(rows[0])
+		rows = rows[1:]
+	}
+	w.b.WriteString("|" + strings.Repeat(" --- |", cols) + "\n")
+	for _, r := range rows {
+		writeRow(r)
+	}
+	w.block()
+}
+
+// resolve makes href absolute and drops script and fragment-only links.
+func (w *mdWriter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	lower := strings.ToLower(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(lower, "javascript:") || strings.HasPrefix(lower, "data:") {
+		return ""
+	}
+	if w.base != nil {
+		if u, err := w.base.Parse(href); err == nil {
+			return strings.ReplaceAll(u.String(), " ", "%20")
+		}
+	}
+	return href
+}
+
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && !fn(c) {
+			continue
+		}
+		walkElements(c, fn)
+	}
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walkElements(n, func(c *html.Node) bool {
+		if found == nil && c.DataAtom == a {
+			found = c
+		}
+		return found == nil
+	})
+	return found
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseSpace replaces whitespace runs with single spaces and trims.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
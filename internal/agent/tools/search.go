@@ -4,16 +4,22 @@ package tools
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // GrepTool searches for patterns in files.
-type GrepTool struct{}
+type GrepTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewGrepTool creates a new grep tool.
 func NewGrepTool() *GrepTool {
@@ -82,10 +88,9 @@ func (t *GrepTool) Execute(ctx context.Context, params map[string]interface{}) (
 		maxResults = int(m)
 	}
 
-	// Expand path
-	if path[0] == '~' {
-		home, _ := os.UserHomeDir()
-		path = filepath.Join(home, path[1:])
+	path, err := resolvePath(ctx, t.Paths, path, pathguard.Read)
+	if err != nil {
+		return nil, err
 	}
 
 	// Try ripgrep first
@@ -98,6 +103,7 @@ func (t *GrepTool) Execute(ctx context.Context, params map[string]interface{}) (
 }
 
 func (t *GrepTool) searchWithRipgrep(ctx context.Context, rgPath, pattern, path string, ignoreCase bool, maxResults int) (interface{}, error) {
+	guard := sessionPaths(ctx, t.Paths)
 	args := []string{"--json", "-m", fmt.Sprintf("%d", maxResults)}
 	if ignoreCase {
 		args = append(args, "-i")
@@ -116,12 +122,28 @@ func (t *GrepTool) searchWithRipgrep(ctx context.Context, rgPath, pattern, path
 
 	// Parse ripgrep JSON output
 	var matches []GrepMatch
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if strings.Contains(line, `"type":"match"`) {
-			// Simplified parsing - in production, use proper JSON parsing
-			// This is a placeholder for full implementation
-			matches = append(matches, GrepMatch{Line: line})
+	for _, line := range strings.Split(string(output), "\n") {
+		var event struct {
+			Type string `json:"type"`
+			Data struct {
+				Path       struct{ Text string } `json:"path"`
+				Lines      struct{ Text string } `json:"lines"`
+				LineNumber int                   `json:"line_number"`
+			} `json:"data"`
+		}
+		if json.Unmarshal([]byte(line), &event) != nil || event.Type != "match" {
+			continue
+		}
+		if guard != nil && !guard.Allowed(event.Data.Path.Text, pathguard.Read) {
+			continue
+		}
+		matches = append(matches, GrepMatch{
+			File:       event.Data.Path.Text,
+			LineNumber: event.Data.LineNumber,
+			Line:       strings.TrimRight(event.Data.Lines.Text, "\r\n"),
+		})
+		if len(matches) >= maxResults {
+			break
 		}
 	}
 
@@ -139,11 +161,18 @@ func (t *GrepTool) searchBuiltin(ctx context.Context, pattern, path string, igno
 	}
 
 	var matches []GrepMatch
+	guard := sessionPaths(ctx, t.Paths)
 
 	err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil // Skip errors
 		}
+		if guard != nil && !guard.Allowed(filePath, pathguard.Read) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		if info.IsDir() {
 			return nil
 		}
@@ -185,7 +214,10 @@ func (t *GrepTool) searchBuiltin(ctx context.Context, pattern, path string, igno
 }
 
 // FindTool finds files by name pattern.
-type FindTool struct{}
+type FindTool struct {
+	// Paths, when set, confines the tool to the agent's roots.
+	Paths *pathguard.Guard
+}
 
 // NewFindTool creates a new find tool.
 func NewFindTool() *FindTool {
@@ -246,19 +278,25 @@ func (t *FindTool) Execute(ctx context.Context, params map[string]interface{}) (
 		maxDepth = int(d)
 	}
 
-	// Expand path
-	if searchPath[0] == '~' {
-		home, _ := os.UserHomeDir()
-		searchPath = filepath.Join(home, searchPath[1:])
+	searchPath, err := resolvePath(ctx, t.Paths, searchPath, pathguard.Read)
+	if err != nil {
+		return nil, err
 	}
 
 	var files []string
+	guard := sessionPaths(ctx, t.Paths)
 	baseDepth := strings.Count(searchPath, string(os.PathSeparator))
 
-	err := filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(searchPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
+		if guard != nil && !guard.Allowed(path, pathguard.Read) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		// Check depth
 		if maxDepth >= 0 {
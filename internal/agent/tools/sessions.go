@@ -213,12 +213,30 @@ func (t *SessionsSendTool) Execute(ctx context.Context, params map[string]interf
 	}, nil
 }
 
+// SpawnRequest describes a sub-agent session to start.
+type SpawnRequest struct {
+	AgentID      string
+	Label        string
+	Message      string
+	Model        string
+	SystemPrompt string
+	// ParentSessionKey is the session that requested the spawn.
+	ParentSessionKey string
+}
+
+// SessionSpawner starts sub-agent sessions.
+type SessionSpawner interface {
+	SpawnSession(ctx context.Context, req SpawnRequest) (*SessionsSpawnResult, error)
+}
+
 // SessionsSpawnTool spawns new agent sessions.
 type SessionsSpawnTool struct {
 	// AgentSessionKey is the current agent's session key.
 	AgentSessionKey string
 	// AgentChannel is the messaging channel.
 	AgentChannel string
+	// Spawner runs the spawned session. When nil, a placeholder result is returned.
+	Spawner SessionSpawner
 }
 
 // NewSessionsSpawnTool creates a new sessions spawn tool.
@@ -273,6 +291,7 @@ type SessionsSpawnResult struct {
 	RunID      string `json:"runId"`
 	SessionKey string `json:"sessionKey"`
 	Label      string `json:"label,omitempty"`
+	Model      string `json:"model,omitempty"`
 	Status     string `json:"status"`
 	Error      string `json:"error,omitempty"`
 }
@@ -287,6 +306,19 @@ func (t *SessionsSpawnTool) Execute(ctx context.Context, params map[string]inter
 	label, _ := params["label"].(string)
 	agentID, _ := params["agentId"].(string)
 
+	if t.Spawner != nil {
+		model, _ := params["model"].(string)
+		systemPrompt, _ := params["systemPrompt"].(string)
+		return t.Spawner.SpawnSession(ctx, SpawnRequest{
+			AgentID:          agentID,
+			Label:            label,
+			Message:          message,
+			Model:            model,
+			SystemPrompt:     systemPrompt,
+			ParentSessionKey: t.AgentSessionKey,
+		})
+	}
+
 	// Note: This would integrate with the gateway to spawn sessions
 	// For now, return a placeholder
 	sessionKey := fmt.Sprintf("spawn_%s_%d", agentID, time.Now().UnixNano())
@@ -355,10 +355,35 @@ func (t *AgentsListTool) Execute(ctx context.Context, params map[string]interfac
 	}, nil
 }
 
-// GatewayTool calls Gateway API methods.
+// GatewayControl is the gateway's operator surface used by the gateway
+// tool.
+type GatewayControl interface {
+	// Status reports the gateway's uptime, channels and sessions.
+	Status(ctx context.Context) (interface{}, error)
+	// ConfigGet returns the config value at a dotted path (the whole
+	// config when empty) with secrets redacted.
+	ConfigGet(ctx context.Context, path string) (interface{}, error)
+	// ConfigSchema returns the JSON Schema of the config file.
+	ConfigSchema(ctx context.Context) (interface{}, error)
+	// ConfigPatch validates and applies a JSON merge patch to the config
+	// file, writing a backup first.
+	ConfigPatch(ctx context.Context, patch map[string]interface{}) (interface{}, error)
+	// Reload rebuilds the agent from the current config in-process.
+	Reload(ctx context.Context) (interface{}, error)
+	// Restart restarts the gateway; once it is back it reports to
+	// sessionKey.
+	Restart(ctx context.Context, sessionKey, reason string) (interface{}, error)
+}
+
+// GatewayTool inspects and manages the gateway.
 type GatewayTool struct {
 	// AgentSessionKey is the current agent's session key.
 	AgentSessionKey string
+	// Gateway is the running gateway; nil outside it.
+	Gateway GatewayControl
+	// AllowChanges enables config.patch, reload and restart
+	// (tools.gateway.allowChanges).
+	AllowChanges bool
 }
 
 // NewGatewayTool creates a new gateway tool.
@@ -373,8 +398,18 @@ func (t *GatewayTool) Name() string {
 
 // Description returns the tool description.
 func (t *GatewayTool) Description() string {
-	return `Call Gateway API methods directly.
-Use for advanced gateway operations not covered by specialized tools.`
+	return `Inspect and manage the LiteClaw gateway.
+
+ACTIONS:
+- status: Uptime, version, channels and session count
+- config.get: Read config at a dotted path (e.g. "agents.defaults.model"); secrets are redacted
+- config.schema: JSON Schema of the config file
+- config.patch: Apply a JSON merge patch (null removes a key); validated, with a backup written first
+- reload: Rebuild the agent from the saved config without restarting
+- restart: Restart the gateway; you will be told in this session once it is back
+
+config.patch, reload and restart need tools.gateway.allowChanges. Reload applies
+model, tool and skill changes; channel and port changes need a restart.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -382,44 +417,90 @@ func (t *GatewayTool) Parameters() interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
-			"method": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"description": "Gateway action to perform",
+				"enum":        []string{"status", "config.get", "config.schema", "config.patch", "reload", "restart"},
+			},
+			"path": map[string]interface{}{
 				"type":        "string",
-				"description": "Gateway method to call",
+				"description": "Dotted config path for config.get (empty for the whole config)",
 			},
-			"params": map[string]interface{}{
+			"patch": map[string]interface{}{
 				"type":        "object",
-				"description": "Parameters for the method",
+				"description": "JSON merge patch for config.patch, e.g. {\"agents\":{\"defaults\":{\"model\":{\"primary\":\"openai/gpt-4o\"}}}}",
 			},
-			"timeoutMs": map[string]interface{}{
-				"type":        "integer",
-				"description": "Request timeout in milliseconds",
+			"reason": map[string]interface{}{
+				"type":        "string",
+				"description": "Why the gateway is restarting (shown when it reports back)",
 			},
 		},
-		"required": []string{"method"},
+		"required": []string{"action"},
 	}
 }
 
-// GatewayResult represents a gateway call result.
+// GatewayResult represents a gateway action result.
 type GatewayResult struct {
-	Method  string      `json:"method"`
+	Action  string      `json:"action"`
 	Status  string      `json:"status"`
 	Payload interface{} `json:"payload,omitempty"`
 	Error   string      `json:"error,omitempty"`
 }
 
-// Execute calls the gateway method.
+// Execute performs the gateway action.
 func (t *GatewayTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	method, _ := params["method"].(string)
-	if method == "" {
-		return nil, fmt.Errorf("method is required")
+	action, _ := params["action"].(string)
+	if action == "" {
+		// Older callers named the action "method".
+		action, _ = params["method"].(string)
+	}
+	if action == "" {
+		return nil, fmt.Errorf("action is required")
+	}
+	if t.Gateway == nil {
+		return nil, fmt.Errorf("the gateway tool is only available inside the gateway")
+	}
+	switch action {
+	case "config.patch", "reload", "restart":
+		if !t.AllowChanges {
+			return nil, fmt.Errorf("%s is disabled; an operator must set tools.gateway.allowChanges to true", action)
+		}
 	}
 
-	// Note: This would make actual gateway calls
-	// For now, return a placeholder
-	return &GatewayResult{
-		Method: method,
-		Status: "pending",
-	}, nil
+	var payload interface{}
+	var err error
+	status := "ok"
+	switch action {
+	case "status":
+		payload, err = t.Gateway.Status(ctx)
+	case "config.get":
+		path, _ := params["path"].(string)
+		payload, err = t.Gateway.ConfigGet(ctx, path)
+	case "config.schema":
+		payload, err = t.Gateway.ConfigSchema(ctx)
+	case "config.patch":
+		patch, ok := params["patch"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("patch must be an object")
+		}
+		payload, err = t.Gateway.ConfigPatch(ctx, patch)
+	case "reload":
+		payload, err = t.Gateway.Reload(ctx)
+	case "restart":
+		sessionKey := t.AgentSessionKey
+		if sc, ok := SessionFromContext(ctx); ok && sc.Key != "" {
+			sessionKey = sc.Key
+		}
+		reason, _ := params["reason"].(string)
+		payload, err = t.Gateway.Restart(ctx, sessionKey, reason)
+		status = "restarting"
+	default:
+		return nil, fmt.Errorf("unknown action: %s", action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &GatewayResult{Action: action, Status: status, Payload: payload}, nil
 }
 
 // SessionStatusTool checks session status.
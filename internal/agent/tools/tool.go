@@ -3,6 +3,8 @@ package tools
 
 import (
 	"context"
+
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
 )
 
 // Tool is the interface for agent tools.
@@ -20,6 +22,35 @@ type Tool interface {
 	Execute(ctx context.Context, params map[string]interface{}) (interface{}, error)
 }
 
+// ChangedFiles returns the files a successful call of a file-writing tool
+// (write, edit, apply_patch, memory_append or memory_update) changed, as
+// absolute paths or paths relative to the workspace.
+func ChangedFiles(tool string, result interface{}) []string {
+	switch r := result.(type) {
+	case map[string]interface{}:
+		if path, ok := r["path"].(string); ok && tool == "write" {
+			return []string{path}
+		}
+	case *EditResult:
+		return []string{r.Path}
+	case *PatchResult:
+		if r.DryRun {
+			return nil
+		}
+		var files []string
+		for _, f := range r.Files {
+			files = append(files, f.Path)
+			if f.MovedTo != "" {
+				files = append(files, f.MovedTo)
+			}
+		}
+		return files
+	case *MemoryWriteResult:
+		return []string{r.Path}
+	}
+	return nil
+}
+
 // Result represents a tool execution result.
 type Result struct {
 	Success bool        `json:"success"`
@@ -93,15 +124,21 @@ func (r *Registry) Names() []string {
 func NewDefaultRegistry(opts *RegistryOptions) *Registry {
 	r := NewRegistry()
 
+	var paths *pathguard.Guard
+	if opts != nil {
+		paths = opts.Paths
+	}
+
 	// File system tools
-	r.Register(NewReadTool())
-	r.Register(NewWriteTool())
-	r.Register(NewListTool())
-	r.Register(NewEditTool())
+	r.Register(&ReadTool{Paths: paths})
+	r.Register(&WriteTool{Paths: paths})
+	r.Register(&ListTool{Paths: paths})
+	r.Register(&EditTool{Paths: paths})
+	r.Register(&ApplyPatchTool{Paths: paths})
 
 	// Search tools
-	r.Register(NewGrepTool())
-	r.Register(NewFindTool())
+	r.Register(&GrepTool{Paths: paths})
+	r.Register(&FindTool{Paths: paths})
 
 	// Execution tools
 	r.Register(NewExecTool())
@@ -132,6 +169,8 @@ func NewDefaultRegistry(opts *RegistryOptions) *Registry {
 	// Memory tools
 	r.Register(NewMemorySearchTool(agentDir))
 	r.Register(NewMemoryGetTool(agentDir))
+	r.Register(NewMemoryAppendTool(agentDir))
+	r.Register(NewMemoryUpdateTool(agentDir))
 
 	// Session tools
 	r.Register(NewSessionsListTool())
@@ -155,4 +194,6 @@ type RegistryOptions struct {
 	AgentSessionKey string
 	// Sender handles message delivery
 	Sender MessageSender
+	// Paths, when set, confines the filesystem tools to the agent's roots.
+	Paths *pathguard.Guard
 }
@@ -1,10 +1,37 @@
 package tools
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/customtool"
+	"github.com/liteclaw/liteclaw/internal/agent/llm"
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"github.com/liteclaw/liteclaw/internal/agent/pathguard"
+	"github.com/liteclaw/liteclaw/internal/agent/sandbox"
+	"github.com/liteclaw/liteclaw/internal/agent/workspace"
+	"github.com/liteclaw/liteclaw/internal/browser"
 )
 
 func TestReadToolExecute(t *testing.T) {
@@ -116,7 +143,7 @@ func TestDefaultRegistryHasAllTools(t *testing.T) {
 
 	expectedTools := []string{
 		// File system tools
-		"read", "write", "list", "edit",
+		"read", "write", "list", "edit", "apply_patch",
 		// Search tools
 		"grep", "find",
 		// Execution tools
@@ -128,7 +155,7 @@ func TestDefaultRegistryHasAllTools(t *testing.T) {
 		// Media tools
 		"image", "message",
 		// Memory tools
-		"memory_search", "memory_get",
+		"memory_search", "memory_get", "memory_append", "memory_update",
 		// Session tools
 		"sessions_list", "sessions_send", "sessions_spawn", "sessions_history",
 		// System tools
@@ -209,6 +236,167 @@ func TestBrowserToolName(t *testing.T) {
 	}
 }
 
+func TestBrowserToolRoutesProfiles(t *testing.T) {
+	var paths []string
+	var actBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/act":
+			_ = json.NewDecoder(r.Body).Decode(&actBody)
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		case "/profiles":
+			_, _ = w.Write([]byte(`{"profiles":[{"name":"chrome"}]}`))
+		default:
+			_, _ = w.Write([]byte(`{"tabs":[{"targetId":"T1","url":"https://example.com","title":"Example"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewBrowserTool()
+	tool.DefaultControlURL = srv.URL
+	tool.Managed = browser.NewManager(map[string]browser.Config{"liteclaw": {Headless: true}})
+	tool.DefaultProfile = "liteclaw"
+	ctx := context.Background()
+
+	// The default profile is managed; status must not launch it.
+	res, err := tool.Execute(ctx, map[string]interface{}{"action": "status"})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	out := res.(*BrowserResult)
+	if st, ok := out.Result.(browser.ProfileStatus); !ok || out.Profile != "liteclaw" || st.Running {
+		t.Fatalf("managed status = %+v", out)
+	}
+	if len(paths) != 0 {
+		t.Fatalf("managed status reached the relay: %v", paths)
+	}
+
+	res, err = tool.Execute(ctx, map[string]interface{}{"action": "tabs", "profile": "chrome"})
+	if err != nil {
+		t.Fatalf("tabs: %v", err)
+	}
+	if tabs := res.(*BrowserResult).Tabs; len(tabs) != 1 || tabs[0].ID != "T1" {
+		t.Fatalf("relay tabs = %+v", tabs)
+	}
+	if paths[0] != "/tabs?profile=chrome" {
+		t.Fatalf("relay path = %s", paths[0])
+	}
+
+	_, err = tool.Execute(ctx, map[string]interface{}{"action": "press", "profile": "chrome", "ref": "e3", "key": "Enter", "tabId": "T1"})
+	if err != nil {
+		t.Fatalf("press: %v", err)
+	}
+	if actBody["kind"] != "press" || actBody["ref"] != "e3" || actBody["key"] != "Enter" || actBody["targetId"] != "T1" {
+		t.Fatalf("act body = %v", actBody)
+	}
+
+	res, err = tool.Execute(ctx, map[string]interface{}{"action": "profiles"})
+	if err != nil {
+		t.Fatalf("profiles: %v", err)
+	}
+	profiles := res.(*BrowserResult).Result.(map[string]interface{})
+	if managed := profiles["managed"].([]browser.ProfileStatus); len(managed) != 1 || managed[0].Name != "liteclaw" {
+		t.Fatalf("managed profiles = %v", profiles["managed"])
+	}
+	if profiles["extension"] == nil {
+		t.Fatalf("extension profiles missing: %v", profiles)
+	}
+}
+
+func TestBrowserToolRefusesURLs(t *testing.T) {
+	var opened []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opened = append(opened, r.URL.Path)
+		_, _ = w.Write([]byte(`{"targetId":"T1"}`))
+	}))
+	defer srv.Close()
+
+	tool := NewBrowserTool()
+	tool.DefaultControlURL = srv.URL
+	ctx := context.Background()
+	for _, target := range []string{"file:///etc/passwd", "javascript:alert(1)", "chrome://settings", "http://127.0.0.1:8080/admin", "http://169.254.169.254/latest/meta-data"} {
+		if _, err := tool.Execute(ctx, map[string]interface{}{"action": "open", "profile": "chrome", "url": target}); err == nil {
+			t.Errorf("open %s succeeded", target)
+		}
+		if _, err := tool.Execute(ctx, map[string]interface{}{"action": "navigate", "profile": "chrome", "tabId": "T1", "url": target}); err == nil {
+			t.Errorf("navigate %s succeeded", target)
+		}
+	}
+	if len(opened) != 0 {
+		t.Fatalf("refused URLs reached the relay: %v", opened)
+	}
+
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "open", "profile": "chrome", "url": "http://127.0.0.1:8080/"}); err != nil {
+		t.Errorf("open allowlisted host: %v", err)
+	}
+}
+
+func TestBrowserToolManagedChromium(t *testing.T) {
+	found := false
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "headless-shell"} {
+		if _, err := exec.LookPath(name); err == nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Skip("no Chromium installed")
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Form</title></head><body>
+<input id="name"><button id="go" onclick="document.getElementById('out').textContent='Hello ' + document.getElementById('name').value">Go</button>
+<p id="out"></p></body></html>`))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	manager := browser.NewManager(map[string]browser.Config{"liteclaw": {
+		Headless:    true,
+		Timeout:     20 * time.Second,
+		UserDataDir: filepath.Join(dir, "profile"),
+		DownloadDir: filepath.Join(dir, "downloads"),
+	}})
+	defer manager.Close()
+	tool := NewBrowserTool()
+	tool.Managed = manager
+	tool.DefaultProfile = "liteclaw"
+	tool.ScreenshotDir = dir
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	ctx := context.Background()
+
+	run := func(params map[string]interface{}) *BrowserResult {
+		t.Helper()
+		res, err := tool.Execute(ctx, params)
+		if err != nil {
+			t.Fatalf("%s: %v", params["action"], err)
+		}
+		return res.(*BrowserResult)
+	}
+
+	if open := run(map[string]interface{}{"action": "open", "url": srv.URL}); open.Title != "Form" || open.TabID == "" {
+		t.Fatalf("open = %+v", open)
+	}
+	snap := run(map[string]interface{}{"action": "snapshot", "interactive": true})
+	button := regexp.MustCompile(`\[ref=(e\d+)\] button "Go"`).FindStringSubmatch(snap.Snapshot)
+	if button == nil {
+		t.Fatalf("snapshot has no Go button:\n%s", snap.Snapshot)
+	}
+	run(map[string]interface{}{"action": "type", "selector": "#name", "text": "Ada"})
+	run(map[string]interface{}{"action": "click", "ref": button[1]})
+	eval := run(map[string]interface{}{"action": "evaluate", "script": "document.getElementById('out').textContent"})
+	if eval.Result != "Hello Ada" {
+		t.Fatalf("evaluate = %v", eval.Result)
+	}
+	shot := run(map[string]interface{}{"action": "screenshot"})
+	if data, err := os.ReadFile(shot.FilePath); err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Fatalf("screenshot %s: %v", shot.FilePath, err)
+	}
+}
+
 func TestCanvasToolName(t *testing.T) {
 	tool := NewCanvasTool()
 	if name := tool.Name(); name != "canvas" {
@@ -223,6 +411,38 @@ func TestNodesToolName(t *testing.T) {
 	}
 }
 
+type recordingNodes struct{ invoked []string }
+
+func (n *recordingNodes) ListNodes(ctx context.Context) ([]NodeInfo, error) { return nil, nil }
+
+func (n *recordingNodes) InvokeNode(ctx context.Context, nodeID, command string, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	n.invoked = append(n.invoked, command)
+	return map[string]interface{}{}, nil
+}
+
+func TestNodesToolSystemRunFollowsSandbox(t *testing.T) {
+	nodes := &recordingNodes{}
+	tool := NewNodesTool()
+	tool.Nodes = nodes
+	tool.Sandbox = sandbox.New(sandbox.Config{Mode: sandbox.ModeNonMain}, t.TempDir())
+	run := map[string]interface{}{"action": "invoke", "nodeId": "mac", "command": "system.run", "params": map[string]interface{}{"command": []interface{}{"id"}}}
+
+	groupCtx := WithSession(context.Background(), SessionContext{Key: "telegram:42", Type: sandbox.SessionGroup})
+	if _, err := tool.Execute(groupCtx, run); err == nil {
+		t.Error("system.run from a sandboxed session succeeded")
+	}
+	if _, err := tool.Execute(groupCtx, map[string]interface{}{"action": "invoke", "nodeId": "mac", "command": "camera.snap"}); err != nil {
+		t.Errorf("camera.snap from a sandboxed session: %v", err)
+	}
+	mainCtx := WithSession(context.Background(), SessionContext{Key: "main", Type: sandbox.SessionMain})
+	if _, err := tool.Execute(mainCtx, run); err != nil {
+		t.Errorf("system.run from the main session: %v", err)
+	}
+	if got := strings.Join(nodes.invoked, ","); got != "camera.snap,system.run" {
+		t.Errorf("invoked = %s, want camera.snap,system.run", got)
+	}
+}
+
 func TestTtsToolName(t *testing.T) {
 	tool := NewTtsTool()
 	if name := tool.Name(); name != "tts" {
@@ -263,3 +483,1615 @@ func TestProcessToolActions(t *testing.T) {
 		t.Error("Count should be non-negative")
 	}
 }
+
+func TestProcessToolSessionScope(t *testing.T) {
+	p, err := processManager.Start("proc_scoped", "sleep 60", exec.Command("sleep", "60"), ProcessStartOptions{SessionKey: "telegram:42"})
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer func() {
+		_ = processManager.Kill(p.ID)
+		_, _ = processManager.Wait(context.Background(), p.ID)
+		processManager.mu.Lock()
+		delete(processManager.processes, p.ID)
+		processManager.mu.Unlock()
+	}()
+
+	tool := NewProcessTool()
+	other := WithSession(context.Background(), SessionContext{Key: "telegram:7", Type: sandbox.SessionGroup})
+	res, err := tool.Execute(other, map[string]interface{}{"action": "list"})
+	if err != nil {
+		t.Fatalf("list error: %v", err)
+	}
+	for _, info := range res.(*ProcessListResult).Processes {
+		if info.ID == p.ID {
+			t.Fatalf("list from another session includes %s", p.ID)
+		}
+	}
+	for _, params := range []map[string]interface{}{
+		{"action": "kill", "id": p.ID},
+		{"action": "status", "pid": float64(p.PID)},
+	} {
+		if _, err := tool.Execute(other, params); err == nil {
+			t.Errorf("%v from another session succeeded", params)
+		}
+	}
+
+	owner := WithSession(context.Background(), SessionContext{Key: "telegram:42", Type: sandbox.SessionGroup})
+	if _, err := tool.Execute(owner, map[string]interface{}{"action": "status", "id": p.ID}); err != nil {
+		t.Errorf("status from the owning session: %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"action": "status", "id": p.ID}); err != nil {
+		t.Errorf("status without a session: %v", err)
+	}
+}
+
+func TestExecToolSandboxRefusal(t *testing.T) {
+	tool := NewExecTool()
+	tool.Sandbox = sandbox.New(sandbox.Config{Mode: sandbox.ModeNonMain, Backend: "no-such-runtime"}, t.TempDir())
+
+	groupCtx := WithSession(context.Background(), SessionContext{Key: "telegram:42", Type: sandbox.SessionGroup})
+	_, err := tool.Execute(groupCtx, map[string]interface{}{"command": "echo hi"})
+	if err == nil || !errors.Is(err, sandbox.ErrUnavailable) {
+		t.Fatalf("Execute() error = %v, want sandbox.ErrUnavailable", err)
+	}
+
+	// The main session is not sandboxed in non-main mode and runs on the host.
+	mainCtx := WithSession(context.Background(), SessionContext{Key: "main", Type: sandbox.SessionMain})
+	res, err := tool.Execute(mainCtx, map[string]interface{}{"command": "echo hi"})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if out := res.(*ExecResult).Stdout; out != "hi\n" {
+		t.Errorf("Stdout = %q, want %q", out, "hi\n")
+	}
+}
+
+func TestExecToolAllowedDirs(t *testing.T) {
+	allowed := t.TempDir()
+	tool := NewExecTool()
+	tool.AllowedDirs = []string{allowed}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"command": "pwd", "workdir": allowed}); err != nil {
+		t.Errorf("Execute() in allowed dir error = %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"command": "pwd", "workdir": t.TempDir()}); err == nil {
+		t.Error("Execute() outside allowed dirs should fail")
+	}
+}
+
+func TestFileToolsPathGuard(t *testing.T) {
+	ws, outside := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("key"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(ws, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	guard := pathguard.New(pathguard.Config{}, ws)
+	ctx := context.Background()
+
+	write := &WriteTool{Paths: guard}
+	if _, err := write.Execute(ctx, map[string]interface{}{"path": "notes.md", "content": "hi"}); err != nil {
+		t.Fatalf("relative write error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "notes.md")); err != nil {
+		t.Errorf("relative write should land in the workspace: %v", err)
+	}
+
+	read := &ReadTool{Paths: guard}
+	if _, err := read.Execute(ctx, map[string]interface{}{"path": filepath.Join(outside, "secret.txt")}); !errors.Is(err, pathguard.ErrOutsideRoots) {
+		t.Errorf("read outside roots error = %v, want ErrOutsideRoots", err)
+	}
+	if _, err := read.Execute(ctx, map[string]interface{}{"path": "escape/secret.txt"}); !errors.Is(err, pathguard.ErrOutsideRoots) {
+		t.Errorf("read through symlink error = %v, want ErrOutsideRoots", err)
+	}
+
+	edit := &EditTool{Paths: guard}
+	if _, err := edit.Execute(ctx, map[string]interface{}{"path": "escape/secret.txt", "oldText": "key", "newText": "x"}); err == nil {
+		t.Error("edit through symlink should fail")
+	}
+
+	grep := &GrepTool{Paths: guard}
+	res, err := grep.Execute(ctx, map[string]interface{}{"pattern": "key", "path": "."})
+	if err != nil {
+		t.Fatalf("grep error = %v", err)
+	}
+	if n := res.(map[string]interface{})["count"].(int); n != 0 {
+		t.Errorf("grep should not follow symlinks out of the workspace, got %d matches", n)
+	}
+}
+
+func TestApplyPatchTool(t *testing.T) {
+	ws := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(ws, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(ws, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	write("main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")  \n}\n")
+	write("old.txt", "a\nb\nc\n")
+	write("gone.txt", "bye\n")
+	if err := os.Chmod(filepath.Join(ws, "old.txt"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := &ApplyPatchTool{Paths: pathguard.New(pathguard.Config{}, ws)}
+	patch := `*** Begin Patch
+*** Update File: main.go
+@@ func main() {
+-	println("hi")
++	println("hello")
+ }
+*** Update File: old.txt
+*** Move to: sub/new.txt
+@@
+ a
+-b
++B
+ c
+*** Add File: added.txt
++one
++two
+*** Delete File: gone.txt
+*** End Patch`
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch})
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	files := res.(*PatchResult).Files
+	if len(files) != 4 {
+		t.Fatalf("got %d file results, want 4", len(files))
+	}
+	if files[0].Added != 1 || files[0].Removed != 1 || files[1].MovedTo != "sub/new.txt" || files[2].Added != 2 {
+		t.Errorf("unexpected summary: %+v", files)
+	}
+
+	if got := read("main.go"); got != "package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := read("sub/new.txt"); got != "a\nB\nc\n" {
+		t.Errorf("sub/new.txt = %q", got)
+	}
+	if info, err := os.Stat(filepath.Join(ws, "sub/new.txt")); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0755 {
+		t.Errorf("sub/new.txt mode = %v, want the source's 0755", info.Mode().Perm())
+	}
+	if got := read("added.txt"); got != "one\ntwo\n" {
+		t.Errorf("added.txt = %q", got)
+	}
+	for _, name := range []string{"old.txt", "gone.txt"} {
+		if _, err := os.Stat(filepath.Join(ws, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be gone", name)
+		}
+	}
+}
+
+func TestApplyPatchToolAtomic(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.WriteFile(filepath.Join(ws, "a.txt"), []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := &ApplyPatchTool{Paths: pathguard.New(pathguard.Config{}, ws)}
+
+	// The second hunk does not match, so the first file must stay untouched.
+	patch := `*** Begin Patch
+*** Update File: a.txt
+-one
++ONE
+*** Add File: b.txt
++new
+*** Update File: a2.txt
+-missing
++x
+*** End Patch`
+	_, err := tool.Execute(context.Background(), map[string]interface{}{"patch": patch})
+	if err == nil || !strings.Contains(err.Error(), "no files were changed") {
+		t.Fatalf("Execute() error = %v, want validation failure", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(ws, "a.txt"))
+	if string(data) != "one\ntwo\n" {
+		t.Errorf("a.txt changed to %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "b.txt")); !os.IsNotExist(err) {
+		t.Error("b.txt should not be created")
+	}
+
+	mismatch := "*** Begin Patch\n*** Update File: a.txt\n one\n-three\n+3\n*** End Patch"
+	_, err = tool.Execute(context.Background(), map[string]interface{}{"patch": mismatch})
+	if err == nil || !strings.Contains(err.Error(), "hunk 1: context not found") || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Execute() mismatch error = %v", err)
+	}
+
+	outside := "*** Begin Patch\n*** Add File: ../escape.txt\n+x\n*** End Patch"
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"patch": outside}); err == nil || !strings.Contains(err.Error(), pathguard.ErrOutsideRoots.Error()) {
+		t.Errorf("Execute() outside workspace error = %v", err)
+	}
+}
+
+func TestExecToolAgentSandbox(t *testing.T) {
+	tool := NewExecTool()
+	tool.AgentSandboxes = map[string]*sandbox.Sandbox{
+		"locked": sandbox.New(sandbox.Config{Mode: sandbox.ModeAll, Backend: "no-such-runtime"}, t.TempDir()),
+	}
+
+	ctx := WithSession(context.Background(), SessionContext{Key: "main", Type: sandbox.SessionMain, AgentID: "main"})
+	if _, err := tool.Execute(ctx, map[string]interface{}{"command": "true"}); err != nil {
+		t.Fatalf("main agent: Execute() error = %v", err)
+	}
+	ctx = WithSession(context.Background(), SessionContext{Key: "subagent:locked:1", Type: sandbox.SessionSubagent, AgentID: "locked"})
+	if _, err := tool.Execute(ctx, map[string]interface{}{"command": "true"}); !errors.Is(err, sandbox.ErrUnavailable) {
+		t.Fatalf("locked agent: Execute() error = %v, want sandbox.ErrUnavailable", err)
+	}
+}
+
+func TestProcessSessionStdinAndOffsets(t *testing.T) {
+	exited := make(chan ProcessInfo, 1)
+	processManager.OnExit = func(p ProcessInfo) { exited <- p }
+	defer func() { processManager.OnExit = nil }()
+
+	ctx := WithSession(context.Background(), SessionContext{Key: "main", Type: "main"})
+	res, err := NewExecTool().Execute(ctx, map[string]interface{}{
+		"command":    "echo ready; read line; echo got:$line",
+		"background": true,
+	})
+	if err != nil {
+		t.Fatalf("exec background error = %v", err)
+	}
+	id := res.(map[string]interface{})["id"].(string)
+
+	tool := NewProcessTool()
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "write", "id": id, "data": "hello\n"}); err != nil {
+		t.Fatalf("write error = %v", err)
+	}
+	info, err := tool.Execute(ctx, map[string]interface{}{"action": "wait", "id": id, "timeout": float64(10)})
+	if err != nil {
+		t.Fatalf("wait error = %v", err)
+	}
+	if st := info.(ProcessInfo).Status; st != ProcessDone {
+		t.Fatalf("status = %s, want done", st)
+	}
+
+	out, err := tool.Execute(ctx, map[string]interface{}{"action": "output", "id": id})
+	if err != nil {
+		t.Fatalf("output error = %v", err)
+	}
+	first := out.(*ProcessOutput)
+	if first.Data != "ready\ngot:hello\n" {
+		t.Errorf("output = %q", first.Data)
+	}
+	more, _ := tool.Execute(ctx, map[string]interface{}{"action": "output", "id": id, "offset": float64(first.NextOffset)})
+	if d := more.(*ProcessOutput).Data; d != "" {
+		t.Errorf("output after nextOffset = %q, want empty", d)
+	}
+
+	select {
+	case p := <-exited:
+		if p.ID != id || p.SessionKey != "main" {
+			t.Errorf("exit notification = %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("no exit notification")
+	}
+}
+
+func TestProcessRingBufferFallsBackToLog(t *testing.T) {
+	m := NewProcessManager(t.TempDir())
+	m.BufferSize = 8
+	p, err := m.Start("proc_ring", "printf", exec.Command("printf", "0123456789abcdef"), ProcessStartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Wait(context.Background(), p.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tail, _ := m.Read(p.ID, 8, 0)
+	if tail.Data != "89abcdef" {
+		t.Errorf("ring read = %q", tail.Data)
+	}
+	head, _ := m.Read(p.ID, 0, 4)
+	if head.Data != "0123" || head.NextOffset != 4 {
+		t.Errorf("log read = %q (next %d)", head.Data, head.NextOffset)
+	}
+}
+
+func TestProcessKillAll(t *testing.T) {
+	m := NewProcessManager(t.TempDir())
+	p, err := m.Start("proc_sleep", "sleep 60", exec.Command("sleep", "60"), ProcessStartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.KillAll()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := m.Wait(ctx, p.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Status != ProcessKilled {
+		t.Errorf("status = %s, want killed", info.Status)
+	}
+}
+
+func TestProcessSendKeysPTY(t *testing.T) {
+	m := NewProcessManager("")
+	p, err := m.Start("proc_pty", "cat", exec.Command("cat"), ProcessStartOptions{PTY: true})
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	if err := m.Write(p.ID, "hi", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SendKeys(p.ID, []string{"Enter", "C-d"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := m.Wait(ctx, p.ID); err != nil {
+		t.Fatalf("cat did not exit after C-d: %v", err)
+	}
+	out, _ := m.Read(p.ID, 0, 0)
+	if !strings.Contains(out.Data, "hi") {
+		t.Errorf("pty output = %q", out.Data)
+	}
+	if _, err := keySequence("Hyper-x"); err == nil {
+		t.Error("unknown key should fail")
+	}
+}
+
+func TestMemorySearchAndGetTranscript(t *testing.T) {
+	dir := t.TempDir()
+	sessions := filepath.Join(dir, "sessions")
+	if err := os.MkdirAll(sessions, 0755); err != nil {
+		t.Fatal(err)
+	}
+	line := `{"type":"message","message":{"role":"user","content":[{"type":"text","text":"remember the garage code 4821"}],"timestamp":1717200000000}}`
+	if err := os.WriteFile(filepath.Join(sessions, "abc.jsonl"), []byte(`{"type":"session"}`+"\n"+line+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	search := NewMemorySearchTool(dir)
+	search.Index = memory.NewIndex(dir, sessions, memory.Config{Transcripts: true})
+	res, err := search.Execute(context.Background(), map[string]interface{}{"query": "garage code"})
+	if err != nil {
+		t.Fatalf("memory_search error = %v", err)
+	}
+	results := res.(*MemorySearchResults)
+	if results.Count != 1 || results.Results[0].Path != "transcripts/abc.jsonl" || results.Results[0].StartLine != 2 {
+		t.Fatalf("memory_search results = %+v", results.Results)
+	}
+
+	get := NewMemoryGetTool(dir)
+	if _, err := get.Execute(context.Background(), map[string]interface{}{"path": "transcripts/abc.jsonl"}); err == nil {
+		t.Error("memory_get should refuse transcripts when not enabled")
+	}
+	get.TranscriptsDir = search.Index.TranscriptsDir()
+	if _, err := get.Execute(context.Background(), map[string]interface{}{"path": "transcripts/../abc.jsonl"}); err == nil {
+		t.Error("memory_get should reject transcript path traversal")
+	}
+	out, err := get.Execute(context.Background(), map[string]interface{}{"path": "transcripts/abc.jsonl", "from": float64(2), "lines": float64(1)})
+	if err != nil {
+		t.Fatalf("memory_get error = %v", err)
+	}
+	if got := out.(*MemoryGetResult).Text; got != line {
+		t.Errorf("memory_get text = %q", got)
+	}
+}
+
+func TestMemoryGetConfinedToMemoryFiles(t *testing.T) {
+	ws, outside := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, "memory"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		filepath.Join(ws, "MEMORY.md"):          "facts",
+		filepath.Join(ws, "memory", "today.md"): "notes",
+		filepath.Join(ws, "secret.txt"):         "key",
+		filepath.Join(outside, "id_rsa"):        "private",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(outside, filepath.Join(ws, "memory", "escape")); err != nil {
+		t.Fatal(err)
+	}
+	get := NewMemoryGetTool(ws)
+	get.Paths = pathguard.New(pathguard.Config{}, ws)
+	ctx := context.Background()
+
+	for _, path := range []string{"MEMORY.md", "memory/today.md", filepath.Join(ws, "memory", "today.md")} {
+		if _, err := get.Execute(ctx, map[string]interface{}{"path": path}); err != nil {
+			t.Errorf("memory_get %s error = %v", path, err)
+		}
+	}
+	for _, path := range []string{
+		"memory/../secret.txt",
+		"memory/../../" + filepath.Base(outside) + "/id_rsa",
+		filepath.Join(outside, "memory", "MEMORY.md"),
+		"notMEMORY.md",
+		"memory/escape/id_rsa",
+	} {
+		if _, err := get.Execute(ctx, map[string]interface{}{"path": path}); err == nil {
+			t.Errorf("memory_get %s was allowed", path)
+		}
+	}
+}
+
+func TestMemoryAppendTool(t *testing.T) {
+	dir := t.TempDir()
+	tool := NewMemoryAppendTool(dir)
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"text": "Booked flights."}); err != nil {
+		t.Fatalf("memory_append error = %v", err)
+	}
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"text": "Prefers aisle seats.", "heading": "Travel"})
+	if err != nil {
+		t.Fatalf("memory_append error = %v", err)
+	}
+	out := res.(*MemoryWriteResult)
+	today := time.Now().Format("2006-01-02")
+	if out.Path != "memory/"+today+".md" || out.StartLine != 5 || out.EndLine != 7 {
+		t.Errorf("memory_append result = %+v", out)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, "memory", today+".md"))
+	want := "# " + today + "\n\nBooked flights.\n\n## Travel\n\nPrefers aisle seats.\n"
+	if string(data) != want {
+		t.Errorf("daily file = %q, want %q", data, want)
+	}
+}
+
+func TestMemoryUpdateTool(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "MEMORY.md")
+	if err := os.WriteFile(path, []byte("# Memory\n\n## Preferences\n\n- Tea\n\n### Food\n\n- Vegetarian\n\n## People\n\n- Ana\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewMemoryUpdateTool(dir)
+	run := func(params map[string]interface{}) *MemoryWriteResult {
+		t.Helper()
+		res, err := tool.Execute(context.Background(), params)
+		if err != nil {
+			t.Fatalf("memory_update(%v) error = %v", params, err)
+		}
+		return res.(*MemoryWriteResult)
+	}
+
+	// Replacing a section also replaces its subsections.
+	if res := run(map[string]interface{}{"section": "preferences", "content": "- Coffee"}); res.Action != "replaced" || res.StartLine != 3 {
+		t.Errorf("replace result = %+v", res)
+	}
+	run(map[string]interface{}{"section": "People", "content": "- Bruno", "mode": "append"})
+	if res := run(map[string]interface{}{"section": "Projects", "content": "- liteclaw"}); res.Action != "created" {
+		t.Errorf("create result = %+v", res)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "# Memory\n\n## Preferences\n\n- Coffee\n\n## People\n\n- Ana\n- Bruno\n\n## Projects\n\n- liteclaw\n"
+	if string(data) != want {
+		t.Errorf("MEMORY.md = %q, want %q", data, want)
+	}
+
+	run(map[string]interface{}{"section": "People", "mode": "delete"})
+	data, _ = os.ReadFile(path)
+	if strings.Contains(string(data), "People") {
+		t.Errorf("section not deleted: %q", data)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"section": "Missing", "mode": "delete"}); err == nil {
+		t.Error("deleting a missing section should fail")
+	}
+}
+
+func TestWebFetchReadableMarkdown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Release notes</title><script>var x = 1;</script></head><body>
+<header><a href="/">Home</a></header>
+<nav><a href="/a">Menu A</a> <a href="/b">Menu B</a></nav>
+<article>
+  <h1>Version 2.0</h1>
+  <p>This release, after months of work, adds <strong>streaming</strong> and a new <a href="/docs/api">API guide</a>.</p>
+  <ul><li>Faster startup</li><li>Smaller binary</li></ul>
+  <table><tr><th>OS</th><th>Status</th></tr><tr><td>Linux</td><td>Supported</td></tr></table>
+  <pre><code class="language-go">fmt.Println("hi")</code></pre>
+</article>
+<div class="sidebar">Related posts</div>
+<footer>Copyright</footer>
+</body></html>`))
+	}))
+	defer srv.Close()
+
+	tool := NewWebFetchTool()
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL + "/blog/v2"})
+	if err != nil {
+		t.Fatalf("web_fetch error = %v", err)
+	}
+	out := res.(*WebFetchResult)
+	if out.Title != "Release notes" {
+		t.Errorf("title = %q", out.Title)
+	}
+	for _, want := range []string{
+		"# Version 2.0",
+		"adds **streaming** and a new [API guide](" + srv.URL + "/docs/api).",
+		"- Faster startup\n- Smaller binary",
+		"| OS | Status |\n| --- | --- |\n| Linux | Supported |",
+		"```go\nfmt.Println(\"hi\")\n```",
+	} {
+		if !strings.Contains(out.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, out.Text)
+		}
+	}
+	for _, unwanted := range []string{"Menu A", "Related posts", "Copyright", "var x"} {
+		if strings.Contains(out.Text, unwanted) {
+			t.Errorf("text contains %q:\n%s", unwanted, out.Text)
+		}
+	}
+}
+
+func TestWebFetchCharset(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/meta" {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html><head><meta charset=\"windows-1252\"></head><body><p>Cr\xe8me br\xfbl\xe9e \x80 5</p></body></html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=iso-8859-1")
+		_, _ = w.Write([]byte("caf\xe9"))
+	}))
+	defer srv.Close()
+
+	tool := NewWebFetchTool()
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	for path, want := range map[string]string{"/plain": "café", "/meta": "Crème brûlée € 5"} {
+		res, err := tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL + path})
+		if err != nil {
+			t.Fatalf("web_fetch %s error = %v", path, err)
+		}
+		if got := res.(*WebFetchResult).Text; got != want {
+			t.Errorf("web_fetch %s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestWebFetchPaginationAndCache(t *testing.T) {
+	hits := 0
+	body := strings.Repeat("a", 150) + "\n\n" + strings.Repeat("b", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	tool := NewWebFetchTool()
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	tool.CacheDir = t.TempDir()
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL, "maxChars": float64(200)})
+	if err != nil {
+		t.Fatalf("web_fetch error = %v", err)
+	}
+	first := res.(*WebFetchResult)
+	if !first.Truncated || first.NextOffset != 150 || first.TotalChars != 252 || first.Cached {
+		t.Fatalf("first page = %+v", first)
+	}
+
+	res, err = tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL, "maxChars": float64(200), "offset": float64(first.NextOffset)})
+	if err != nil {
+		t.Fatalf("web_fetch error = %v", err)
+	}
+	second := res.(*WebFetchResult)
+	if second.Truncated || !second.Cached || strings.TrimSpace(second.Text) != strings.Repeat("b", 100) {
+		t.Fatalf("second page = %+v", second)
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1", hits)
+	}
+
+	tool.MaxBytes = 10
+	tool.CacheDir = ""
+	res, err = tool.Execute(context.Background(), map[string]interface{}{"url": srv.URL})
+	if err != nil {
+		t.Fatalf("web_fetch error = %v", err)
+	}
+	if out := res.(*WebFetchResult); !out.BodyTruncated || out.TotalChars != 10 {
+		t.Errorf("byte-limited fetch = %+v", out)
+	}
+}
+
+func TestWebFetchBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+
+	_, err := NewWebFetchTool().Execute(context.Background(), map[string]interface{}{"url": srv.URL + "/api/config"})
+	if err == nil || !errors.Is(err, netguard.ErrBlocked) {
+		t.Fatalf("web_fetch of loopback error = %v, want ErrBlocked", err)
+	}
+}
+
+func TestWebSearchBackends(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/brave":
+			if r.Header.Get("X-Subscription-Token") != "bk" || r.URL.Query().Get("freshness") != "pw" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"web":{"results":[{"title":"Go","url":"https://go.dev/","description":"The Go language","age":"2 days ago"}]}}`))
+		case "/searx/search":
+			if r.URL.Query().Get("format") != "json" || r.URL.Query().Get("time_range") != "week" || r.URL.Query().Get("engines") != "ddg" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"results":[{"title":"Go","url":"https://go.dev/","content":"From searx","publishedDate":"2024-01-01"}],"answers":["42"]}`))
+		case "/tavily/search":
+			var body map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if r.Header.Get("Authorization") != "Bearer tk" || body["search_depth"] != "advanced" || body["time_range"] != "week" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"answer":"Go is a language.","results":[{"title":"Go","url":"https://go.dev/","content":"From tavily"}]}`))
+		case "/llm/chat/completions":
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Go 1.24 is out.","annotations":[{"type":"url_citation","url_citation":{"url":"https://go.dev/blog","title":"Go blog"}}]}}],"citations":["https://go.dev/blog","https://go.dev/doc"]}`))
+		default:
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	params := map[string]interface{}{"query": "golang", "freshness": "pw"}
+	cases := []struct {
+		backend SearchBackend
+		desc    string
+		answer  string
+		count   int
+	}{
+		{&BraveSearch{APIKey: "bk", BaseURL: srv.URL + "/brave"}, "The Go language", "", 1},
+		{&SearXNGSearch{BaseURL: srv.URL + "/searx/", Params: map[string]string{"engines": "ddg"}}, "From searx", "42", 1},
+		{&TavilySearch{APIKey: "tk", BaseURL: srv.URL + "/tavily", Params: map[string]string{"search_depth": "advanced"}}, "From tavily", "Go is a language.", 1},
+		{&ModelSearch{BaseURL: srv.URL + "/llm", Model: "sonar"}, "", "Go 1.24 is out.", 2},
+	}
+	for _, tc := range cases {
+		tool := NewWebSearchTool()
+		tool.Backends = []SearchBackend{tc.backend}
+		res, err := tool.Execute(context.Background(), params)
+		if err != nil {
+			t.Fatalf("%s: web_search error = %v", tc.backend.Name(), err)
+		}
+		out := res.(*WebSearchResult)
+		if out.Provider != tc.backend.Name() || out.Count != tc.count || out.Answer != tc.answer || out.Results[0].Description != tc.desc {
+			t.Errorf("%s: result = %+v", tc.backend.Name(), out)
+		}
+		if out.Results[0].SiteName != "go.dev" {
+			t.Errorf("%s: siteName = %q", tc.backend.Name(), out.Results[0].SiteName)
+		}
+	}
+}
+
+func TestWebSearchFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ok/search" {
+			_, _ = w.Write([]byte(`{"results":[{"title":"A","url":"https://a.example/","content":"a"},{"title":"B","url":"https://b.example/","content":"b"}]}`))
+			return
+		}
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	tool := NewWebSearchTool()
+	tool.Backends = []SearchBackend{
+		&BraveSearch{APIKey: "k", BaseURL: srv.URL + "/brave"},
+		&TavilySearch{},
+		&SearXNGSearch{BaseURL: srv.URL + "/ok"},
+	}
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x", "count": float64(1)})
+	if err != nil {
+		t.Fatalf("web_search error = %v", err)
+	}
+	out := res.(*WebSearchResult)
+	if out.Provider != "searxng" || out.Count != 1 || len(out.Errors) != 2 || !strings.Contains(out.Errors[0], "429") {
+		t.Errorf("fallback result = %+v", out)
+	}
+
+	tool.Backends = tool.Backends[:2]
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"query": "x"}); err == nil || !strings.Contains(err.Error(), "tavily") {
+		t.Errorf("all backends failing error = %v", err)
+	}
+}
+
+func TestTtsToolOpenAISpeech(t *testing.T) {
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" || r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte("ID3-audio"))
+	}))
+	defer srv.Close()
+
+	tool := NewTtsTool()
+	tool.Engine = &OpenAISpeech{APIKey: "k", BaseURL: srv.URL + "/v1"}
+	tool.OutputDir = t.TempDir()
+	tool.DefaultVoice = "nova"
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hello there", "speed": 1.5})
+	if err != nil {
+		t.Fatalf("tts error = %v", err)
+	}
+	out := res.(*TtsResult)
+	if got["input"] != "hello there" || got["voice"] != "nova" || got["response_format"] != "mp3" || got["speed"] != 1.5 {
+		t.Errorf("request body = %v", got)
+	}
+	data, err := os.ReadFile(out.FilePath)
+	if err != nil || string(data) != "ID3-audio" {
+		t.Fatalf("audio file = %q, %v", data, err)
+	}
+	if filepath.Dir(out.FilePath) != tool.OutputDir || out.MimeType != "audio/mpeg" || out.Engine != "openai" {
+		t.Errorf("result = %+v", out)
+	}
+	atts := out.ReplyAttachments()
+	if len(atts) != 1 || atts[0].Type != "audio" || !atts[0].Voice || atts[0].Path != out.FilePath {
+		t.Errorf("attachments = %+v", atts)
+	}
+
+	tool.Engine = &OpenAISpeech{APIKey: "wrong", BaseURL: srv.URL + "/v1"}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"text": "x"}); err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("API error = %v", err)
+	}
+}
+
+func TestTtsToolCommandSpeech(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	tool := NewTtsTool()
+	tool.OutputDir = t.TempDir()
+	// Text arrives on stdin because no argument mentions {text}.
+	tool.Engine = &CommandSpeech{
+		Command: "sh",
+		Args:    []string{"-c", `printf 'RIFF' > "$1"; cat >> "$1"; printf '%s' "$2" >> "$1"`, "tts", "{output}", "{voice}"},
+	}
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hi", "voice": "amy", "voiceNote": false})
+	if err != nil {
+		t.Fatalf("tts error = %v", err)
+	}
+	out := res.(*TtsResult)
+	data, _ := os.ReadFile(out.FilePath)
+	if string(data) != "RIFFhiamy" || out.Format != "wav" || out.VoiceNote {
+		t.Errorf("result = %+v, audio = %q", out, data)
+	}
+
+	tool.Engine = &CommandSpeech{Command: "sh", Args: []string{"-c", "echo broken >&2; exit 3"}}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hi"}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("command failure error = %v", err)
+	}
+
+	tool.Engine = nil
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"text": "hi"}); err == nil {
+		t.Error("expected an error without an engine")
+	}
+}
+
+func TestWavDuration(t *testing.T) {
+	header := make([]byte, 44)
+	copy(header, "RIFF")
+	copy(header[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint32(header[28:], 32000)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], 16000)
+	wav := append(header, make([]byte, 16000)...)
+	if d := wavDuration(wav); d != 0.5 {
+		t.Errorf("wavDuration = %v, want 0.5", d)
+	}
+	if d := wavDuration([]byte("ID3")); d != 0 {
+		t.Errorf("wavDuration(mp3) = %v", d)
+	}
+}
+
+// visionStub is an llm.Provider that records the last chat request.
+type visionStub struct {
+	req *llm.ChatRequest
+}
+
+func (v *visionStub) Name() string { return "stub" }
+
+func (v *visionStub) Chat(ctx context.Context, req *llm.ChatRequest) (*llm.ChatResponse, error) {
+	v.req = req
+	return &llm.ChatResponse{Content: " two cats "}, nil
+}
+
+func (v *visionStub) ChatStream(ctx context.Context, req *llm.ChatRequest) (<-chan llm.StreamChunk, error) {
+	return nil, errors.New("not supported")
+}
+
+func (v *visionStub) Models(ctx context.Context) ([]string, error) { return nil, nil }
+
+func encodeTestPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, h/2, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageToolAnalyze(t *testing.T) {
+	big := encodeTestPNG(t, 3000, 1000)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(big)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	small := encodeTestPNG(t, 40, 30)
+	if err := os.WriteFile(filepath.Join(dir, "small.png"), small, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stub := &visionStub{}
+	tool := NewImageTool(dir)
+	tool.Net = netguard.New([]string{"127.0.0.1"})
+	tool.Resolve = func() (*VisionModel, error) {
+		return &VisionModel{Client: stub, Ref: "openai/gpt-4o", Model: "gpt-4o", MaxTokens: 512}, nil
+	}
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{
+		"prompt": "How many cats?",
+		"images": []interface{}{"small.png", srv.URL + "/big.png", "data:image/png;base64," + base64.StdEncoding.EncodeToString(small)},
+	})
+	if err != nil {
+		t.Fatalf("image error = %v", err)
+	}
+	out := res.(*ImageAnalysisResult)
+	if out.Text != "two cats" || out.Model != "openai/gpt-4o" || len(out.Images) != 3 {
+		t.Fatalf("result = %+v", out)
+	}
+	if info := out.Images[0]; info.Width != 40 || info.Height != 30 || info.MimeType != "image/png" || info.Resized {
+		t.Errorf("small image info = %+v", info)
+	}
+	if info := out.Images[1]; info.Width != 3000 || !info.Resized || info.SentWidth != 2048 || info.SentHeight != 682 {
+		t.Errorf("large image info = %+v", info)
+	}
+	if info := out.Images[2]; info.Source != "data:image/png (data URL)" {
+		t.Errorf("data URL info = %+v", info)
+	}
+
+	msg := stub.req.Messages[0]
+	if stub.req.Model != "gpt-4o" || msg.Content != "How many cats?" || len(msg.Images) != 3 || !bytes.Equal(msg.Images[0].Data, small) {
+		t.Errorf("vision request = %+v", stub.req)
+	}
+
+	// Private addresses are refused without an allowlist.
+	tool.Net = nil
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"image": srv.URL}); err == nil || !errors.Is(err, netguard.ErrBlocked) {
+		t.Errorf("private URL error = %v", err)
+	}
+
+	// Local images are confined to the guard's read roots.
+	outside := filepath.Join(t.TempDir(), "outside.png")
+	if err := os.WriteFile(outside, small, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool.Paths = pathguard.New(pathguard.Config{}, dir)
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"image": "small.png"}); err != nil {
+		t.Errorf("guarded workspace image error = %v", err)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"image": outside}); err == nil {
+		t.Error("expected an image outside the read roots to be refused")
+	}
+
+	tool.MaxImages = 1
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"images": []interface{}{"a.png", "b.png"}}); err == nil {
+		t.Error("expected too many images error")
+	}
+
+	tool.Resolve = nil
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"image": "small.png"}); err == nil || !strings.Contains(err.Error(), "tools.image.model") {
+		t.Errorf("unconfigured error = %v", err)
+	}
+}
+
+func TestPrepareImageReencodesToFitBytes(t *testing.T) {
+	noisy := image.NewRGBA(image.Rect(0, 0, 600, 600))
+	rng := rand.New(rand.NewSource(1))
+	for i := range noisy.Pix {
+		noisy.Pix[i] = byte(rng.Intn(256))
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, noisy); err != nil {
+		t.Fatal(err)
+	}
+
+	out, mime, info, err := prepareImage(buf.Bytes(), 2048, 200<<10)
+	if err != nil {
+		t.Fatalf("prepareImage error = %v", err)
+	}
+	if len(out) > 200<<10 || mime != "image/jpeg" || !info.Resized {
+		t.Errorf("prepareImage = %d bytes, %s, %+v", len(out), mime, info)
+	}
+
+	if _, _, _, err := prepareImage([]byte("not an image"), 2048, 1<<20); err == nil {
+		t.Error("expected an error for non-image data")
+	}
+}
+
+func TestImageGenerateTool(t *testing.T) {
+	generated := encodeTestPNG(t, 64, 32)
+	var gen map[string]interface{}
+	var edit struct {
+		prompt, mimeType string
+		images           int
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/images/generations":
+			_ = json.NewDecoder(r.Body).Decode(&gen)
+		case "/v1/images/edits":
+			if err := r.ParseMultipartForm(10 << 20); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			edit.prompt = r.FormValue("prompt")
+			edit.images = len(r.MultipartForm.File["image"])
+			if edit.images > 0 {
+				edit.mimeType = r.MultipartForm.File["image"][0].Header.Get("Content-Type")
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		b64 := base64.StdEncoding.EncodeToString(generated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": []map[string]string{{"b64_json": b64, "revised_prompt": "a red line"}, {"b64_json": b64}},
+		})
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tool := NewImageGenerateTool(dir)
+	tool.Generator = &OpenAIImages{APIKey: "k", BaseURL: srv.URL + "/v1"}
+	tool.DefaultSize = "1024x1024"
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{"prompt": "a line", "n": float64(2), "quality": "low"})
+	if err != nil {
+		t.Fatalf("image_generate error = %v", err)
+	}
+	out := res.(*ImageGenerateResult)
+	if gen["prompt"] != "a line" || gen["model"] != "gpt-image-1" || gen["size"] != "1024x1024" || gen["quality"] != "low" || gen["n"] != float64(2) {
+		t.Errorf("generation request = %v", gen)
+	}
+	if _, ok := gen["response_format"]; ok {
+		t.Errorf("gpt-image request should not set response_format: %v", gen)
+	}
+	if len(out.Images) != 2 || out.Edited || out.Images[0].RevisedPrompt != "a red line" {
+		t.Fatalf("result = %+v", out)
+	}
+	img := out.Images[0]
+	if img.Width != 64 || img.Height != 32 || img.MimeType != "image/png" || filepath.Dir(img.FilePath) != filepath.Join(dir, "media", "images") {
+		t.Errorf("image = %+v", img)
+	}
+	if data, err := os.ReadFile(img.FilePath); err != nil || !bytes.Equal(data, generated) {
+		t.Errorf("saved image = %d bytes, %v", len(data), err)
+	}
+	atts := out.ReplyAttachments()
+	if len(atts) != 2 || atts[0].Type != "image" || atts[0].Path != img.FilePath {
+		t.Errorf("attachments = %+v", atts)
+	}
+
+	// An input image switches to the edits endpoint.
+	res, err = tool.Execute(context.Background(), map[string]interface{}{"prompt": "make it blue", "images": []interface{}{img.FilePath}})
+	if err != nil {
+		t.Fatalf("image edit error = %v", err)
+	}
+	if !res.(*ImageGenerateResult).Edited || edit.prompt != "make it blue" || edit.images != 1 || edit.mimeType != "image/png" {
+		t.Errorf("edit request = %+v", edit)
+	}
+
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"prompt": "x", "n": float64(9)}); err == nil {
+		t.Error("expected an error for too many images")
+	}
+	tool.Generator = &OpenAIImages{APIKey: "wrong", BaseURL: srv.URL + "/v1"}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"prompt": "x"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("API error = %v", err)
+	}
+}
+
+// mediaSender records messages and media sent through the message tool.
+type mediaSender struct {
+	text  string
+	media []MediaAttachment
+}
+
+func (s *mediaSender) SendMessage(ctx context.Context, channel, target, message string) error {
+	s.text = message
+	return nil
+}
+
+func (s *mediaSender) SendMedia(ctx context.Context, channel, target, message string, media []MediaAttachment) error {
+	s.text = message
+	s.media = media
+	return nil
+}
+
+func TestMessageToolSendsMedia(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cat.png"), encodeTestPNG(t, 4, 4), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sender := &mediaSender{}
+	tool := NewMessageTool(sender)
+	tool.AgentDir = dir
+
+	res, err := tool.Execute(context.Background(), map[string]interface{}{
+		"action": "send", "channel": "telegram", "target": "42", "media": "cat.png",
+	})
+	if err != nil || res.(*MessageResult).Status != "sent" {
+		t.Fatalf("send = %+v, %v", res, err)
+	}
+	if len(sender.media) != 1 || sender.media[0].Type != "image" || sender.media[0].Path != filepath.Join(dir, "cat.png") || sender.text != "" {
+		t.Errorf("media = %+v", sender.media)
+	}
+
+	res, _ = tool.Execute(context.Background(), map[string]interface{}{
+		"action": "send", "channel": "telegram", "target": "42", "media": "missing.png",
+	})
+	if res.(*MessageResult).Status != "error" {
+		t.Errorf("missing media result = %+v", res)
+	}
+
+	outside := filepath.Join(t.TempDir(), "secret.png")
+	if err := os.WriteFile(outside, encodeTestPNG(t, 4, 4), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tool.Paths = pathguard.New(pathguard.Config{}, dir)
+	res, _ = tool.Execute(context.Background(), map[string]interface{}{
+		"action": "send", "channel": "telegram", "target": "42", "media": outside,
+	})
+	if res.(*MessageResult).Status != "error" || len(sender.media) != 1 {
+		t.Errorf("media outside the workspace: result = %+v, sent = %+v", res, sender.media)
+	}
+	res, _ = tool.Execute(context.Background(), map[string]interface{}{
+		"action": "send", "channel": "telegram", "target": "42", "media": "cat.png",
+	})
+	if res.(*MessageResult).Status != "sent" {
+		t.Errorf("guarded media in the workspace: result = %+v", res)
+	}
+}
+
+// fakeGateway records the gateway actions the tool performs.
+type fakeGateway struct {
+	calls   []string
+	restart string
+}
+
+func (g *fakeGateway) Status(ctx context.Context) (interface{}, error) {
+	g.calls = append(g.calls, "status")
+	return map[string]interface{}{"status": "running"}, nil
+}
+
+func (g *fakeGateway) ConfigGet(ctx context.Context, path string) (interface{}, error) {
+	g.calls = append(g.calls, "config.get "+path)
+	return path, nil
+}
+
+func (g *fakeGateway) ConfigSchema(ctx context.Context) (interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (g *fakeGateway) ConfigPatch(ctx context.Context, patch map[string]interface{}) (interface{}, error) {
+	g.calls = append(g.calls, "config.patch")
+	return patch, nil
+}
+
+func (g *fakeGateway) Reload(ctx context.Context) (interface{}, error) {
+	g.calls = append(g.calls, "reload")
+	return nil, nil
+}
+
+func (g *fakeGateway) Restart(ctx context.Context, sessionKey, reason string) (interface{}, error) {
+	g.restart = sessionKey + ": " + reason
+	return nil, nil
+}
+
+func TestGatewayToolRequiresAllowChanges(t *testing.T) {
+	gw := &fakeGateway{}
+	tool := NewGatewayTool()
+	ctx := WithSession(context.Background(), SessionContext{Key: "telegram:42"})
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "status"}); err == nil {
+		t.Fatal("status without a gateway should fail")
+	}
+	tool.Gateway = gw
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "config.get", "path": "gateway.port"}); err != nil {
+		t.Fatalf("config.get: %v", err)
+	}
+	for _, action := range []string{"config.patch", "reload", "restart"} {
+		_, err := tool.Execute(ctx, map[string]interface{}{"action": action, "patch": map[string]interface{}{}})
+		if err == nil || !strings.Contains(err.Error(), "allowChanges") {
+			t.Errorf("%s without allowChanges: %v", action, err)
+		}
+	}
+
+	tool.AllowChanges = true
+	if _, err := tool.Execute(ctx, map[string]interface{}{"action": "config.patch", "patch": "x"}); err == nil {
+		t.Error("non-object patch was accepted")
+	}
+	res, err := tool.Execute(ctx, map[string]interface{}{"action": "restart", "reason": "new model"})
+	if err != nil || res.(*GatewayResult).Status != "restarting" {
+		t.Fatalf("restart = %+v, %v", res, err)
+	}
+	if gw.restart != "telegram:42: new model" {
+		t.Errorf("restart reported to %q", gw.restart)
+	}
+	if strings.Join(gw.calls, ",") != "config.get gateway.port" {
+		t.Errorf("calls = %v", gw.calls)
+	}
+}
+
+// writeZip writes an archive with the given members.
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writePDF writes a minimal PDF with one Helvetica page per content stream.
+func writePDF(t *testing.T, path string, pages ...string) {
+	t.Helper()
+	objs := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	var kids []string
+	for _, content := range pages {
+		n := len(objs) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", n))
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>", n+1, n+2),
+			"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content)+1, content))
+	}
+	objs[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+	objs = append(objs, "<< /Title (Quarterly Report) /Author (Ada) >>")
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, obj := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, len(objs), xref)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractDocument(t *testing.T) {
+	dir := t.TempDir()
+	const rels = `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">%s</Relationships>`
+	const r = `xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+
+	writeZip(t, filepath.Join(dir, "memo.docx"), map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left"/></w:tabs></w:pPr><w:r><w:t>Hello</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">world</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>a</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>b</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:br w:type="page"/><w:t>Second page</w:t></w:r></w:p></w:body></w:document>`,
+		"docProps/core.xml": `<cp:coreProperties xmlns:cp="cp" xmlns:dc="dc"><dc:title>Memo</dc:title><dc:creator>Ada</dc:creator></cp:coreProperties>`,
+	})
+	writeZip(t, filepath.Join(dir, "book.xlsx"), map[string]string{
+		"xl/workbook.xml":            `<workbook ` + r + `><sheets><sheet name="Sales" sheetId="1" r:id="rId1"/><sheet name="Empty" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": fmt.Sprintf(rels, `<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet2.xml"/>`),
+		"xl/sharedStrings.xml":       `<sst><si><t>Region</t></si><si><r><t>To</t></r><r><t>tal</t></r><rPh><t>x</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml":   `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row><row r="2"><c r="A2" t="inlineStr"><is><t>North</t></is></c><c r="C2"><v>42</v></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml":   `<worksheet><sheetData/></worksheet>`,
+	})
+	writeZip(t, filepath.Join(dir, "deck.pptx"), map[string]string{
+		"ppt/presentation.xml":            `<p:presentation xmlns:p="p" ` + r + `><p:sldIdLst><p:sldId id="257" r:id="rId3"/><p:sldId id="256" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": fmt.Sprintf(rels, `<Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="slides/slide2.xml"/>`),
+		"ppt/slides/slide1.xml":           `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Later</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide2.xml":           `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Intro</a:t></a:r><a:br/><a:r><a:t>line two</a:t></a:r></a:p></p:sld>`,
+	})
+	_ = os.WriteFile(filepath.Join(dir, "data.csv"), []byte("name,qty\n\"Widget, large\",3\n"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "page.html"), []byte("<html><head><title>Notes</title></head><body><article><h1>Heading</h1><p>Body text.</p></article></body></html>"), 0644)
+	writePDF(t, filepath.Join(dir, "report.pdf"),
+		"BT /F1 12 Tf 72 720 Td (First line) Tj 0 -14 Td [(Second) -300 (line)] TJ ET",
+		"BT /F1 12 Tf 72 720 Td (Page two) Tj ET")
+
+	cases := []struct {
+		file, format, text string
+		meta               map[string]string
+	}{
+		{"memo.docx", "docx", "--- Page 1 ---\nHello\tworld\na\tb\n\n--- Page 2 ---\nSecond page", map[string]string{"title": "Memo", "author": "Ada"}},
+		{"book.xlsx", "xlsx", "--- Sheet 1: Sales ---\nRegion\t\tTotal\nNorth\t\t42\n\n--- Sheet 2: Empty ---\n", nil},
+		{"deck.pptx", "pptx", "--- Slide 1 ---\nIntro\nline two\n\n--- Slide 2 ---\nLater", nil},
+		{"data.csv", "csv", "--- Sheet 1: data.csv ---\nname\tqty\nWidget, large\t3", map[string]string{"rows": "2"}},
+		{"page.html", "html", "--- Page 1 ---\n# Heading\n\nBody text.", map[string]string{"title": "Notes"}},
+		{"report.pdf", "pdf", "--- Page 1 ---\nFirst line\nSecond line\n\n--- Page 2 ---\nPage two", map[string]string{"title": "Quarterly Report", "author": "Ada"}},
+	}
+	for _, tc := range cases {
+		doc, err := ExtractDocument(filepath.Join(dir, tc.file))
+		if err != nil {
+			t.Errorf("%s: %v", tc.file, err)
+			continue
+		}
+		if doc.Format != tc.format {
+			t.Errorf("%s: format = %q", tc.file, doc.Format)
+		}
+		if got := doc.Text(nil); got != tc.text {
+			t.Errorf("%s: text = %q, want %q", tc.file, got, tc.text)
+		}
+		for k, v := range tc.meta {
+			if doc.Metadata[k] != v {
+				t.Errorf("%s: metadata[%s] = %q, want %q", tc.file, k, doc.Metadata[k], v)
+			}
+		}
+	}
+
+	if _, err := ExtractDocument(filepath.Join(dir, "missing.doc")); err == nil {
+		t.Error("missing file was accepted")
+	}
+	_ = os.WriteFile(filepath.Join(dir, "legacy.doc"), []byte{0xd0, 0xcf, 0x11, 0xe0, 0, 1, 2}, 0644)
+	if _, err := ExtractDocument(filepath.Join(dir, "legacy.doc")); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("legacy .doc = %v", err)
+	}
+}
+
+func TestXlsxRowsLimits(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "C7": 2, "XFD1": 16383, "XFE1": -1, "AAAAAAAAAAAAAA1": -1} {
+		n, ok := columnIndex(ref)
+		if !ok {
+			n = -1
+		}
+		if n != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, n, want)
+		}
+	}
+
+	// Out-of-range references fall back to the next column.
+	rows, truncated, err := xlsxRows([]byte(`<worksheet><sheetData><row><c r="A1"><v>a</v></c><c r="ZZZZZZZZZZZZ1"><v>b</v></c></row></sheetData></worksheet>`), nil)
+	if err != nil || truncated || len(rows) != 1 || strings.Join(rows[0], ",") != "a,b" {
+		t.Fatalf("rows = %q, truncated = %v, err = %v", rows, truncated, err)
+	}
+
+	// Each XFD cell pads its row to 16384 columns; the cap stops the sheet.
+	var b strings.Builder
+	b.WriteString("<worksheet><sheetData>")
+	for i := 0; i < 2*xlsxMaxCells/xlsxMaxColumns; i++ {
+		fmt.Fprintf(&b, `<row><c r="XFD%d"><v>x</v></c></row>`, i+1)
+	}
+	b.WriteString("</sheetData></worksheet>")
+	rows, truncated, err = xlsxRows([]byte(b.String()), nil)
+	if err != nil || !truncated {
+		t.Fatalf("truncated = %v, err = %v", truncated, err)
+	}
+	cells := 0
+	for _, row := range rows {
+		cells += len(row)
+	}
+	if cells > xlsxMaxCells {
+		t.Errorf("kept %d cells, want at most %d", cells, xlsxMaxCells)
+	}
+}
+
+func TestDocReadToolPagesAndOffsets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.pdf")
+	writePDF(t, path, "BT /F1 12 Tf (alpha) Tj ET", "BT /F1 12 Tf (bravo) Tj ET", "BT /F1 12 Tf (charlie) Tj ET")
+	tool := NewDocReadTool()
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, map[string]interface{}{"path": path, "pages": "2-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := res.(*DocReadResult)
+	if r.Total != 3 || r.Unit != "page" || r.Text != "--- Page 2 ---\nbravo\n\n--- Page 3 ---\ncharlie" || r.Truncated {
+		t.Fatalf("pages 2- = %+v", r)
+	}
+
+	var got strings.Builder
+	offset := 0.0
+	for i := 0; ; i++ {
+		res, err := tool.Execute(ctx, map[string]interface{}{"path": path, "maxChars": 10.0, "offset": offset})
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := res.(*DocReadResult)
+		got.WriteString(r.Text)
+		if !r.Truncated {
+			break
+		}
+		if i > 10 {
+			t.Fatal("offsets do not advance")
+		}
+		offset = float64(r.NextOffset)
+	}
+	if want := "--- Page 1 ---\nalpha\n\n--- Page 2 ---\nbravo\n\n--- Page 3 ---\ncharlie"; got.String() != want {
+		t.Errorf("continued text = %q", got.String())
+	}
+
+	for _, spec := range []string{"4", "0-2", "x", "3-1"} {
+		if _, err := tool.Execute(ctx, map[string]interface{}{"path": path, "pages": spec}); err == nil {
+			t.Errorf("pages %q was accepted", spec)
+		}
+	}
+}
+
+func TestCustomToolCommand(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	t.Setenv("CUSTOM_TOOL_GREETING", "hello")
+	params := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string"},
+		},
+		"required": []interface{}{"name"},
+	}
+	tool := NewCustomTool(customtool.Spec{
+		Name:       "greet",
+		Parameters: params,
+		Command:    `echo "$GREETING" {{name}} "$LITECLAW_ARG_NAME"; pwd`,
+		Env:        map[string]string{"GREETING": "${CUSTOM_TOOL_GREETING}"},
+	})
+	tool.Dir = dir
+	ctx := context.Background()
+
+	res, err := tool.Execute(ctx, map[string]interface{}{"name": "a'b; exit 3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.(*CustomToolResult).Stdout; got != "hello a'b; exit 3 a'b; exit 3\n"+dir+"\n" {
+		t.Errorf("stdout = %q", got)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{}); err == nil {
+		t.Error("missing required argument was accepted")
+	}
+
+	tool = NewCustomTool(customtool.Spec{
+		Name:       "echo_json",
+		Parameters: params,
+		Args:       []string{"printf", `{"name": "%s"}`, "{{name}}"},
+		Output:     customtool.OutputJSON,
+	})
+	res, err = tool.Execute(ctx, map[string]interface{}{"name": "$(id)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.(map[string]interface{})["name"]; got != "$(id)" {
+		t.Errorf("json output = %v", res)
+	}
+
+	tool = NewCustomTool(customtool.Spec{Name: "fail", Command: "echo broken >&2; exit 2"})
+	if _, err := tool.Execute(ctx, nil); err == nil || !strings.Contains(err.Error(), "status 2: broken") {
+		t.Errorf("failing command err = %v", err)
+	}
+}
+
+func TestCustomToolHTTP(t *testing.T) {
+	var gotPath, gotQuery, gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery, gotAuth = r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Get("Authorization")
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		if r.URL.Query().Get("fail") != "" {
+			http.Error(w, "nope", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+	t.Setenv("CUSTOM_TOOL_TOKEN", "s3cret")
+
+	tool := NewCustomTool(customtool.Spec{
+		Name: "lookup",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"id":   map[string]interface{}{"type": "string"},
+				"fail": map[string]interface{}{"type": "boolean"},
+			},
+		},
+		HTTP: &customtool.HTTPSpec{
+			Method:  "POST",
+			URL:     srv.URL + "/items/{{id}}?fail={{fail}}",
+			Headers: map[string]string{"Authorization": "Bearer ${CUSTOM_TOOL_TOKEN}"},
+			Body:    `{"id": {{id}}}`,
+		},
+		Output: customtool.OutputJSON,
+	})
+	ctx := context.Background()
+	res, err := tool.Execute(ctx, map[string]interface{}{"id": "a/b c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.(map[string]interface{})["ok"] != true {
+		t.Errorf("result = %v", res)
+	}
+	if gotPath != "/items/a%2Fb%20c" || gotQuery != "fail=" || gotAuth != "Bearer s3cret" || gotBody != `{"id": "a/b c"}` {
+		t.Errorf("request = %s ? %s, %q, %s", gotPath, gotQuery, gotAuth, gotBody)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"id": "x", "fail": true}); err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("error status err = %v", err)
+	}
+}
+
+func TestWorkspaceHistoryTool(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	soul := filepath.Join(dir, "SOUL.md")
+	if err := os.WriteFile(soul, []byte("Be kind.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := workspace.InitRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(soul, []byte("Be rude.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("scratch\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitAll("Agent run 1234abcd in main\n\nSession: main\nRun: 1234abcd"); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewWorkspaceHistoryTool(repo)
+	ctx := WithSession(context.Background(), SessionContext{Key: "main"})
+
+	res, err := tool.Execute(ctx, map[string]interface{}{"action": "log", "path": "SOUL.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := res.(map[string]interface{})["commits"].([]workspace.Commit)
+	if len(commits) != 2 || commits[0].Subject != "Agent run 1234abcd in main" || !strings.Contains(commits[0].Body, "Run: 1234abcd") {
+		t.Fatalf("log = %+v", commits)
+	}
+	if strings.Join(commits[0].Files, ",") != "SOUL.md" || commits[1].Subject != "Initial workspace" {
+		t.Errorf("log = %+v", commits)
+	}
+
+	res, err = tool.Execute(ctx, map[string]interface{}{"action": "diff", "rev": commits[0].Hash, "path": "SOUL.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := res.(*WorkspaceHistoryResult).Text; !strings.Contains(diff, "-Be kind.") || !strings.Contains(diff, "+Be rude.") {
+		t.Errorf("diff = %s", diff)
+	}
+
+	res, err = tool.Execute(ctx, map[string]interface{}{"action": "revert", "rev": commits[1].Hash, "path": "SOUL.md"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.(*WorkspaceHistoryResult).Commit == "" {
+		t.Error("revert made no commit")
+	}
+	if data, _ := os.ReadFile(soul); string(data) != "Be kind.\n" {
+		t.Errorf("SOUL.md = %q", data)
+	}
+	res, err = tool.Execute(ctx, map[string]interface{}{"action": "show", "rev": "HEAD", "path": "SOUL.md"})
+	if err != nil || res.(*WorkspaceHistoryResult).Text != "Be kind.\n" {
+		t.Errorf("show = %v, %v", res, err)
+	}
+	if latest, _ := repo.Log("", 1); len(latest) != 1 || latest[0].Body != "Session: main" || strings.Join(latest[0].Files, ",") != "SOUL.md" {
+		t.Errorf("revert commit = %+v", latest)
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"action": "show", "rev": "--output=/tmp/x", "path": "SOUL.md"},
+		{"action": "revert", "rev": "HEAD", "path": "../outside.md"},
+		{"action": "revert", "rev": "HEAD", "path": ".git/config"},
+	} {
+		if _, err := tool.Execute(ctx, params); err == nil {
+			t.Errorf("%v was accepted", params)
+		}
+	}
+}
+
+func TestWorkspaceCommitFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	image := filepath.Join(dir, "media", "shot.png")
+	if err := os.WriteFile(image, []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repo, err := workspace.InitRepo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if initial, _ := repo.Log("", 1); len(initial) != 1 || strings.Join(initial[0].Files, ",") != ".gitignore" {
+		t.Fatalf("initial commit = %+v, want only .gitignore", initial)
+	}
+
+	for name, content := range map[string]string{"SOUL.md": "Be kind.\n", "scratch.md": "from exec\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	written := ChangedFiles("write", map[string]interface{}{"path": filepath.Join(dir, "SOUL.md")})
+	patched := ChangedFiles("apply_patch", &PatchResult{Files: []PatchFileResult{{Path: "gone.md", Action: PatchDelete}}})
+	paths := append(append(written, patched...), image, "../outside.md")
+	if _, err := repo.CommitFiles("Agent run", paths); err != nil {
+		t.Fatal(err)
+	}
+	latest, _ := repo.Log("", 1)
+	if len(latest) != 1 || latest[0].Subject != "Agent run" || strings.Join(latest[0].Files, ",") != "SOUL.md" {
+		t.Errorf("run commit = %+v, want only SOUL.md", latest)
+	}
+	if got := ChangedFiles("read", map[string]interface{}{"path": "SOUL.md"}); got != nil {
+		t.Errorf("read changed %v", got)
+	}
+
+	// Hooks planted in the workspace's .git never run.
+	marker := filepath.Join(t.TempDir(), "hook-ran")
+	hook := filepath.Join(dir, ".git", "hooks", "post-commit")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\ntouch "+marker+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "SOUL.md"), []byte("Be brave.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if hash, err := repo.CommitFiles("Agent run", written); err != nil || hash == "" {
+		t.Fatalf("CommitFiles = %q, %v", hash, err)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("post-commit hook ran")
+	}
+	if _, err := repo.Diff("HEAD", "SOUL.md"); err != nil {
+		t.Errorf("Diff error = %v", err)
+	}
+}
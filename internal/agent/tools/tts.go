@@ -0,0 +1,428 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultTtsMaxChars caps the text synthesised per call.
+	DefaultTtsMaxChars = 4096
+	// maxSpeechBytes caps the audio accepted from an engine.
+	maxSpeechBytes = 25 << 20
+)
+
+// SpeechRequest is a normalised text-to-speech request.
+type SpeechRequest struct {
+	Text  string
+	Voice string
+	// Format is the requested container: mp3, opus, aac, flac or wav.
+	Format string
+	// Speed is the speaking rate; 0 means the engine default.
+	Speed float64
+}
+
+// Speech is synthesised audio.
+type Speech struct {
+	Data []byte
+	// Format is the container actually produced, which may differ from
+	// the requested one for engines with a fixed output.
+	Format string
+}
+
+// TtsEngine synthesises speech.
+type TtsEngine interface {
+	Name() string
+	Synthesize(ctx context.Context, req SpeechRequest) (*Speech, error)
+}
+
+// OpenAISpeech synthesises speech through an OpenAI-compatible
+// /audio/speech endpoint.
+type OpenAISpeech struct {
+	APIKey string
+	// BaseURL defaults to https://api.openai.com/v1.
+	BaseURL string
+	// Model defaults to gpt-4o-mini-tts.
+	Model  string
+	Client *http.Client
+}
+
+// Name returns the engine name.
+func (e *OpenAISpeech) Name() string {
+	return "openai"
+}
+
+// Synthesize requests audio for req.
+func (e *OpenAISpeech) Synthesize(ctx context.Context, req SpeechRequest) (*Speech, error) {
+	if e.APIKey == "" {
+		return nil, fmt.Errorf("OpenAI API key is not set (OPENAI_API_KEY or tools.tts.openai.apiKey)")
+	}
+	base := e.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	model := e.Model
+	if model == "" {
+		model = "gpt-4o-mini-tts"
+	}
+	voice := req.Voice
+	if voice == "" {
+		voice = "alloy"
+	}
+	format := req.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	body := map[string]interface{}{
+		"model":           model,
+		"input":           req.Text,
+		"voice":           voice,
+		"response_format": format,
+	}
+	if req.Speed > 0 {
+		body["speed"] = req.Speed
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(base, "/")+"/audio/speech", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+e.APIKey)
+
+	client := e.Client
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("speech request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("speech API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, maxSpeechBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+	if len(audio) > maxSpeechBytes {
+		return nil, fmt.Errorf("audio exceeds %d bytes", maxSpeechBytes)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("speech API returned no audio")
+	}
+	return &Speech{Data: audio, Format: format}, nil
+}
+
+// CommandSpeech runs a local synthesiser such as piper or the bundled
+// sherpa-onnx-tts wrapper.
+//
+// Args may contain {text}, {output}, {voice}, {format} and {speed}. When no
+// argument mentions {text} the text is written to stdin, and when none
+// mentions {output} the audio is read from stdout.
+type CommandSpeech struct {
+	Command string
+	Args    []string
+	// Format is the container the command writes (default: wav).
+	Format string
+	// Env holds extra environment variables, e.g. SHERPA_ONNX_MODEL_DIR.
+	Env     map[string]string
+	Timeout time.Duration
+}
+
+// Name returns the engine name.
+func (e *CommandSpeech) Name() string {
+	return "command"
+}
+
+// Synthesize runs the command for req.
+func (e *CommandSpeech) Synthesize(ctx context.Context, req SpeechRequest) (*Speech, error) {
+	if e.Command == "" {
+		return nil, fmt.Errorf("tts command is not set (tools.tts.command.command)")
+	}
+	format := e.Format
+	if format == "" {
+		format = "wav"
+	}
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tmpDir, err := os.MkdirTemp("", "liteclaw-tts-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	output := filepath.Join(tmpDir, "speech."+format)
+
+	speed := ""
+	if req.Speed > 0 {
+		speed = fmt.Sprintf("%g", req.Speed)
+	}
+	replacer := strings.NewReplacer(
+		"{text}", req.Text,
+		"{output}", output,
+		"{voice}", req.Voice,
+		"{format}", format,
+		"{speed}", speed,
+	)
+	var textArg, outputArg bool
+	args := make([]string, 0, len(e.Args))
+	for _, a := range e.Args {
+		textArg = textArg || strings.Contains(a, "{text}")
+		outputArg = outputArg || strings.Contains(a, "{output}")
+		args = append(args, replacer.Replace(a))
+	}
+
+	cmd := exec.CommandContext(ctx, e.Command, args...)
+	cmd.Env = os.Environ()
+	for k, v := range e.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	if !textArg {
+		cmd.Stdin = strings.NewReader(req.Text)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 512 {
+			msg = msg[len(msg)-512:]
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("tts command timed out after %s", timeout)
+		}
+		return nil, fmt.Errorf("tts command failed: %w: %s", err, msg)
+	}
+
+	var audio []byte
+	if outputArg {
+		if audio, err = os.ReadFile(output); err != nil {
+			return nil, fmt.Errorf("tts command wrote no audio: %w", err)
+		}
+	} else {
+		audio = stdout.Bytes()
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("tts command produced no audio")
+	}
+	if len(audio) > maxSpeechBytes {
+		return nil, fmt.Errorf("audio exceeds %d bytes", maxSpeechBytes)
+	}
+	return &Speech{Data: audio, Format: format}, nil
+}
+
+// TtsTool converts text to speech and attaches the audio to the reply.
+type TtsTool struct {
+	// Engine synthesises the audio.
+	Engine TtsEngine
+	// DefaultVoice is used when the call names no voice.
+	DefaultVoice string
+	// DefaultFormat is used when the call names no format (default: mp3).
+	DefaultFormat string
+	// OutputDir is where audio files are saved.
+	OutputDir string
+	// MaxChars caps the text per call.
+	MaxChars int
+}
+
+// NewTtsTool creates a new TTS tool.
+func NewTtsTool() *TtsTool {
+	return &TtsTool{
+		DefaultFormat: "mp3",
+		OutputDir:     os.TempDir(),
+		MaxChars:      DefaultTtsMaxChars,
+	}
+}
+
+// Name returns the tool name.
+func (t *TtsTool) Name() string {
+	return "tts"
+}
+
+// Description returns the tool description.
+func (t *TtsTool) Description() string {
+	return `Convert text to speech audio.
+The audio is saved to the workspace and attached to your reply; on channels that support it, it is delivered as a voice note.
+Keep the text conversational; do not read out code or long URLs.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *TtsTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Text to convert to speech",
+			},
+			"voice": map[string]interface{}{
+				"type":        "string",
+				"description": "Voice to use (engine specific, e.g. alloy, echo, fable, onyx, nova, shimmer for OpenAI)",
+			},
+			"speed": map[string]interface{}{
+				"type":        "number",
+				"description": "Speaking speed (0.25 to 4.0, default: 1.0)",
+				"minimum":     0.25,
+				"maximum":     4.0,
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"description": "Output format: mp3, opus, aac, flac, wav",
+				"enum":        []string{"mp3", "opus", "aac", "flac", "wav"},
+			},
+			"voiceNote": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Deliver as a voice note where the channel supports it (default: true)",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+// TtsResult represents TTS generation result.
+type TtsResult struct {
+	Text      string  `json:"text"`
+	Voice     string  `json:"voice,omitempty"`
+	Format    string  `json:"format"`
+	Engine    string  `json:"engine"`
+	FilePath  string  `json:"filePath"`
+	MimeType  string  `json:"mimeType"`
+	Bytes     int     `json:"bytes"`
+	Duration  float64 `json:"duration,omitempty"`
+	VoiceNote bool    `json:"voiceNote"`
+}
+
+// ReplyAttachments implements AttachmentResult.
+func (r *TtsResult) ReplyAttachments() []MediaAttachment {
+	return []MediaAttachment{{
+		Type:     "audio",
+		Path:     r.FilePath,
+		MimeType: r.MimeType,
+		Voice:    r.VoiceNote,
+	}}
+}
+
+// Execute generates speech from text.
+func (t *TtsTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	text, _ := params["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+	req := SpeechRequest{Text: text}
+	req.Voice, _ = params["voice"].(string)
+	req.Format, _ = params["format"].(string)
+	req.Speed, _ = params["speed"].(float64)
+	voiceNote := true
+	if v, ok := params["voiceNote"].(bool); ok {
+		voiceNote = v
+	}
+	return t.Speak(ctx, req, voiceNote)
+}
+
+// Speak synthesises req and saves the audio under OutputDir.
+func (t *TtsTool) Speak(ctx context.Context, req SpeechRequest, voiceNote bool) (*TtsResult, error) {
+	if t.Engine == nil {
+		return nil, fmt.Errorf("no text-to-speech engine is configured (tools.tts)")
+	}
+	maxChars := t.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultTtsMaxChars
+	}
+	if n := len([]rune(req.Text)); n > maxChars {
+		return nil, fmt.Errorf("text is %d characters; the limit is %d", n, maxChars)
+	}
+	if req.Voice == "" {
+		req.Voice = t.DefaultVoice
+	}
+	if req.Format == "" {
+		req.Format = t.DefaultFormat
+	}
+	if req.Speed != 0 && (req.Speed < 0.25 || req.Speed > 4) {
+		return nil, fmt.Errorf("speed must be between 0.25 and 4.0")
+	}
+
+	speech, err := t.Engine.Synthesize(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(t.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output dir: %w", err)
+	}
+	name := fmt.Sprintf("tts-%s-%s.%s", time.Now().Format("20060102-150405"), uuid.New().String()[:8], speech.Format)
+	filePath := filepath.Join(t.OutputDir, name)
+	if err := os.WriteFile(filePath, speech.Data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save audio: %w", err)
+	}
+
+	result := &TtsResult{
+		Text:      req.Text,
+		Voice:     req.Voice,
+		Format:    speech.Format,
+		Engine:    t.Engine.Name(),
+		FilePath:  filePath,
+		MimeType:  guessMimeType(filePath),
+		Bytes:     len(speech.Data),
+		VoiceNote: voiceNote,
+	}
+	if speech.Format == "wav" {
+		result.Duration = wavDuration(speech.Data)
+	}
+	return result, nil
+}
+
+// wavDuration returns the length in seconds of a PCM WAV file, or 0 when
+// the header cannot be read.
+func wavDuration(data []byte) float64 {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0
+	}
+	var byteRate uint32
+	for off := 12; off+8 <= len(data); {
+		id := string(data[off : off+4])
+		size := binary.LittleEndian.Uint32(data[off+4 : off+8])
+		body := off + 8
+		switch id {
+		case "fmt ":
+			if body+12 <= len(data) {
+				byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+			}
+		case "data":
+			if byteRate == 0 {
+				return 0
+			}
+			// Streaming writers leave the size unset.
+			if size == 0 || size == 0xFFFFFFFF || int(size) > len(data)-body {
+				size = uint32(len(data) - body)
+			}
+			return float64(size) / float64(byteRate)
+		}
+		off = body + int(size) + int(size%2)
+	}
+	return 0
+}
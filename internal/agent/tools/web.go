@@ -4,6 +4,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,10 +14,36 @@ import (
 	"time"
 )
 
-// WebSearchTool searches the web using Brave Search API.
+// SearchQuery is a normalised web search request.
+type SearchQuery struct {
+	Query      string
+	Count      int
+	Country    string
+	SearchLang string
+	UILang     string
+	// Freshness is pd, pw, pm or py.
+	Freshness string
+}
+
+// SearchResponse is what a backend returns.
+type SearchResponse struct {
+	Results []SearchResult
+	// Answer is a synthesised answer, for backends that provide one.
+	Answer string
+}
+
+// SearchBackend runs web searches against one provider.
+type SearchBackend interface {
+	Name() string
+	Search(ctx context.Context, q SearchQuery) (*SearchResponse, error)
+}
+
+// WebSearchTool searches the web through one or more backends.
 type WebSearchTool struct {
-	// APIKey is the Brave Search API key.
+	// APIKey is the Brave Search API key, used when Backends is empty.
 	APIKey string
+	// Backends are tried in order until one succeeds.
+	Backends []SearchBackend
 	// DefaultCount is the default number of results.
 	DefaultCount int
 	// Timeout is the request timeout.
@@ -40,9 +67,9 @@ func (t *WebSearchTool) Name() string {
 
 // Description returns the tool description.
 func (t *WebSearchTool) Description() string {
-	return `Search the web using Brave Search API.
+	return `Search the web.
 Supports region-specific and localized search via country and language parameters.
-Returns titles, URLs, and snippets for fast research.`
+Returns titles, URLs, and snippets for fast research, plus a short answer when the backend provides one.`
 }
 
 // Parameters returns the JSON Schema for parameters.
@@ -92,22 +119,14 @@ type SearchResult struct {
 
 // WebSearchResult represents the search results.
 type WebSearchResult struct {
-	Query   string         `json:"query"`
-	Count   int            `json:"count"`
-	Results []SearchResult `json:"results"`
-	TookMs  int64          `json:"tookMs"`
-}
-
-// BraveSearchResponse represents the Brave Search API response.
-type BraveSearchResponse struct {
-	Web struct {
-		Results []struct {
-			Title       string `json:"title"`
-			URL         string `json:"url"`
-			Description string `json:"description"`
-			Age         string `json:"age"`
-		} `json:"results"`
-	} `json:"web"`
+	Query    string         `json:"query"`
+	Provider string         `json:"provider"`
+	Count    int            `json:"count"`
+	Answer   string         `json:"answer,omitempty"`
+	Results  []SearchResult `json:"results"`
+	// Errors lists backends that failed before Provider answered.
+	Errors []string `json:"errors,omitempty"`
+	TookMs int64    `json:"tookMs"`
 }
 
 // Execute performs the web search.
@@ -117,10 +136,6 @@ func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 		return nil, fmt.Errorf("query is required")
 	}
 
-	if t.APIKey == "" {
-		return nil, fmt.Errorf("BRAVE_API_KEY environment variable is not set")
-	}
-
 	count := t.DefaultCount
 	if c, ok := params["count"].(float64); ok && c > 0 {
 		count = int(c)
@@ -129,297 +144,155 @@ func (t *WebSearchTool) Execute(ctx context.Context, params map[string]interface
 		}
 	}
 
-	country, _ := params["country"].(string)
-	freshness, _ := params["freshness"].(string)
-	searchLang, _ := params["search_lang"].(string)
-	uiLang, _ := params["ui_lang"].(string)
-
-	start := time.Now()
-
-	// Build URL
-	searchURL, _ := url.Parse("https://api.search.brave.com/res/v1/web/search")
-	q := searchURL.Query()
-	q.Set("q", query)
-	q.Set("count", fmt.Sprintf("%d", count))
-	if country != "" {
-		q.Set("country", country)
-	}
-	if freshness != "" {
-		q.Set("freshness", freshness)
-	}
-	if searchLang != "" {
-		q.Set("search_lang", searchLang)
-	}
-	if uiLang != "" {
-		q.Set("ui_lang", uiLang)
-	}
-	searchURL.RawQuery = q.Encode()
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", searchURL.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
+	q := SearchQuery{Query: query, Count: count}
+	q.Country, _ = params["country"].(string)
+	q.Freshness, _ = params["freshness"].(string)
+	q.SearchLang, _ = params["search_lang"].(string)
+	q.UILang, _ = params["ui_lang"].(string)
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Subscription-Token", t.APIKey)
-
-	// Execute request
-	client := &http.Client{Timeout: t.Timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("search request failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("search API error (%d): %s", resp.StatusCode, string(body))
-	}
-
-	// Parse response
-	var braveResp BraveSearchResponse
-	if err := json.NewDecoder(resp.Body).Decode(&braveResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	backends := t.Backends
+	if len(backends) == 0 {
+		if t.APIKey == "" {
+			return nil, fmt.Errorf("BRAVE_API_KEY environment variable is not set and no search backend is configured (tools.web.search)")
+		}
+		backends = []SearchBackend{&BraveSearch{APIKey: t.APIKey, Client: &http.Client{Timeout: t.Timeout}}}
 	}
 
-	// Map results
-	results := make([]SearchResult, 0, len(braveResp.Web.Results))
-	for _, r := range braveResp.Web.Results {
-		var siteName string
-		if u, err := url.Parse(r.URL); err == nil {
-			siteName = u.Hostname()
+	start := time.Now()
+	var failures []string
+	var errs []error
+	for _, b := range backends {
+		resp, err := b.Search(ctx, q)
+		if err != nil {
+			failures = append(failures, b.Name()+": "+err.Error())
+			errs = append(errs, fmt.Errorf("%s: %w", b.Name(), err))
+			if ctx.Err() != nil {
+				break
+			}
+			continue
 		}
 
-		results = append(results, SearchResult{
-			Title:       r.Title,
-			URL:         r.URL,
-			Description: r.Description,
-			Published:   r.Age,
-			SiteName:    siteName,
-		})
+		results := resp.Results
+		if len(results) > count {
+			results = results[:count]
+		}
+		for i := range results {
+			if results[i].SiteName == "" {
+				if u, err := url.Parse(results[i].URL); err == nil {
+					results[i].SiteName = u.Hostname()
+				}
+			}
+		}
+		return &WebSearchResult{
+			Query:    query,
+			Provider: b.Name(),
+			Count:    len(results),
+			Answer:   resp.Answer,
+			Results:  results,
+			Errors:   failures,
+			TookMs:   time.Since(start).Milliseconds(),
+		}, nil
 	}
-
-	return &WebSearchResult{
-		Query:   query,
-		Count:   len(results),
-		Results: results,
-		TookMs:  time.Since(start).Milliseconds(),
-	}, nil
-}
-
-// WebFetchTool fetches and extracts content from URLs.
-type WebFetchTool struct {
-	// Timeout is the request timeout.
-	Timeout time.Duration
-	// MaxChars is the maximum characters to return.
-	MaxChars int
-	// UserAgent is the User-Agent header.
-	UserAgent string
+	return nil, fmt.Errorf("web search failed: %w", errors.Join(errs...))
 }
 
-// NewWebFetchTool creates a new web fetch tool.
-func NewWebFetchTool() *WebFetchTool {
-	return &WebFetchTool{
-		Timeout:   30 * time.Second,
-		MaxChars:  50000,
-		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_7_2) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
-	}
+// BraveSearch queries the Brave Search API.
+type BraveSearch struct {
+	APIKey string
+	// BaseURL defaults to https://api.search.brave.com/res/v1/web/search.
+	BaseURL string
+	Client  *http.Client
 }
 
-// Name returns the tool name.
-func (t *WebFetchTool) Name() string {
-	return "web_fetch"
+// Name returns the backend name.
+func (b *BraveSearch) Name() string {
+	return "brave"
 }
 
-// Description returns the tool description.
-func (t *WebFetchTool) Description() string {
-	return `Fetch and extract readable content from a URL.
-Converts HTML to text/markdown for easy processing.
-Use for lightweight page access without browser automation.`
+// BraveSearchResponse represents the Brave Search API response.
+type BraveSearchResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+			Age         string `json:"age"`
+		} `json:"results"`
+	} `json:"web"`
 }
 
-// Parameters returns the JSON Schema for parameters.
-func (t *WebFetchTool) Parameters() interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"url": map[string]interface{}{
-				"type":        "string",
-				"description": "HTTP or HTTPS URL to fetch",
-			},
-			"maxChars": map[string]interface{}{
-				"type":        "integer",
-				"description": "Maximum characters to return (default: 50000)",
-			},
-			"extractMode": map[string]interface{}{
-				"type":        "string",
-				"description": "Extraction mode: 'text' or 'markdown' (default: markdown)",
-				"enum":        []string{"text", "markdown"},
-			},
-		},
-		"required": []string{"url"},
+// Search runs the query.
+func (b *BraveSearch) Search(ctx context.Context, q SearchQuery) (*SearchResponse, error) {
+	if b.APIKey == "" {
+		return nil, fmt.Errorf("brave API key is not set (BRAVE_API_KEY)")
 	}
-}
 
-// WebFetchResult represents the fetch result.
-type WebFetchResult struct {
-	URL         string `json:"url"`
-	FinalURL    string `json:"finalUrl"`
-	Status      int    `json:"status"`
-	ContentType string `json:"contentType"`
-	Length      int    `json:"length"`
-	Truncated   bool   `json:"truncated"`
-	TookMs      int64  `json:"tookMs"`
-	Text        string `json:"text"`
-}
-
-// Execute fetches the URL content.
-func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-	urlStr, _ := params["url"].(string)
-	if urlStr == "" {
-		return nil, fmt.Errorf("url is required")
+	base := b.BaseURL
+	if base == "" {
+		base = "https://api.search.brave.com/res/v1/web/search"
 	}
-
-	// Validate URL
-	parsedURL, err := url.Parse(urlStr)
+	searchURL, err := url.Parse(base)
 	if err != nil {
-		return nil, fmt.Errorf("invalid URL: %w", err)
+		return nil, fmt.Errorf("invalid base URL: %w", err)
 	}
-	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return nil, fmt.Errorf("invalid URL: must be http or https")
+	v := searchURL.Query()
+	v.Set("q", q.Query)
+	v.Set("count", fmt.Sprintf("%d", q.Count))
+	if q.Country != "" {
+		v.Set("country", q.Country)
 	}
-
-	maxChars := t.MaxChars
-	if mc, ok := params["maxChars"].(float64); ok && mc > 0 {
-		maxChars = int(mc)
-	}
-
-	start := time.Now()
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	if q.Freshness != "" {
+		v.Set("freshness", q.Freshness)
 	}
-
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("User-Agent", t.UserAgent)
-	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-
-	// Execute request
-	client := &http.Client{
-		Timeout: t.Timeout,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			return nil
-		},
+	if q.SearchLang != "" {
+		v.Set("search_lang", q.SearchLang)
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("fetch failed: %w", err)
+	if q.UILang != "" {
+		v.Set("ui_lang", q.UILang)
 	}
-	defer func() { _ = resp.Body.Close() }()
+	searchURL.RawQuery = v.Encode()
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		// Assuming truncateForDiff is available in tool package or using simple string limit
-		return nil, fmt.Errorf("fetch failed (%d): %s", resp.StatusCode, string(body))
-	}
-
-	// Read body with limit
-	limitReader := io.LimitReader(resp.Body, int64(maxChars*2))
-	body, err := io.ReadAll(limitReader)
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL.String(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.APIKey)
 
-	text := string(body)
-	contentType := resp.Header.Get("Content-Type")
-
-	// Simple HTML to text extraction
-	if strings.Contains(contentType, "text/html") {
-		text = extractTextFromHTML(text)
+	var braveResp BraveSearchResponse
+	if err := doSearchRequest(b.Client, req, &braveResp); err != nil {
+		return nil, err
 	}
 
-	truncated := false
-	if len(text) > maxChars {
-		text = text[:maxChars]
-		truncated = true
+	results := make([]SearchResult, 0, len(braveResp.Web.Results))
+	for _, r := range braveResp.Web.Results {
+		results = append(results, SearchResult{
+			Title:       r.Title,
+			URL:         r.URL,
+			Description: r.Description,
+			Published:   r.Age,
+		})
 	}
-
-	return &WebFetchResult{
-		URL:         urlStr,
-		FinalURL:    resp.Request.URL.String(),
-		Status:      resp.StatusCode,
-		ContentType: contentType,
-		Length:      len(text),
-		Truncated:   truncated,
-		TookMs:      time.Since(start).Milliseconds(),
-		Text:        text,
-	}, nil
+	return &SearchResponse{Results: results}, nil
 }
 
-// extractTextFromHTML performs a basic HTML to text extraction.
-func extractTextFromHTML(html string) string {
-	// Remove script and style tags
-	html = removeTagContent(html, "script")
-	html = removeTagContent(html, "style")
-
-	// Remove all HTML tags
-	var result strings.Builder
-	inTag := false
-	for _, r := range html {
-		if r == '<' {
-			inTag = true
-			continue
-		}
-		if r == '>' {
-			inTag = false
-			result.WriteRune(' ')
-			continue
-		}
-		if !inTag {
-			result.WriteRune(r)
-		}
+// doSearchRequest sends req and decodes a JSON response into out.
+func doSearchRequest(client *http.Client, req *http.Request, out interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
 	}
-
-	// Clean up whitespace
-	text := result.String()
-	text = strings.ReplaceAll(text, "\t", " ")
-	text = strings.ReplaceAll(text, "\r", "")
-
-	// Collapse multiple newlines
-	lines := strings.Split(text, "\n")
-	var cleanLines []string
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			cleanLines = append(cleanLines, line)
-		}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("search request failed: %w", err)
 	}
+	defer func() { _ = resp.Body.Close() }()
 
-	return strings.Join(cleanLines, "\n")
-}
-
-// removeTagContent removes content between opening and closing tags.
-func removeTagContent(html, tag string) string {
-	openTag := "<" + tag
-	closeTag := "</" + tag + ">"
-
-	result := html
-	for {
-		start := strings.Index(strings.ToLower(result), openTag)
-		if start == -1 {
-			break
-		}
-		end := strings.Index(strings.ToLower(result[start:]), closeTag)
-		if end == -1 {
-			break
-		}
-		result = result[:start] + result[start+end+len(closeTag):]
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("search API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
 	}
-	return result
+	return nil
 }
@@ -0,0 +1,359 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/netguard"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	// DefaultWebFetchMaxBytes caps the downloaded response body.
+	DefaultWebFetchMaxBytes = 2 << 20
+	// DefaultWebFetchMaxChars is the page size returned per call.
+	DefaultWebFetchMaxChars = 50000
+	// DefaultWebFetchCacheTTL is how long extracted pages are reused.
+	DefaultWebFetchCacheTTL = 15 * time.Minute
+)
+
+// WebFetchTool fetches and extracts content from URLs.
+type WebFetchTool struct {
+	// Timeout is the request timeout.
+	Timeout time.Duration
+	// MaxChars is the maximum characters to return per page.
+	MaxChars int
+	// MaxBytes caps the downloaded response body.
+	MaxBytes int64
+	// UserAgent is the User-Agent header.
+	UserAgent string
+	// CacheDir holds extracted pages. Empty disables caching.
+	CacheDir string
+	// CacheTTL is how long cached pages stay fresh.
+	CacheTTL time.Duration
+	// Net blocks private and local destinations. When nil, every
+	// non-public address is refused.
+	Net *netguard.Guard
+}
+
+// NewWebFetchTool creates a new web fetch tool.
+func NewWebFetchTool() *WebFetchTool {
+	return &WebFetchTool{
+		Timeout:   30 * time.Second,
+		MaxChars:  DefaultWebFetchMaxChars,
+		MaxBytes:  DefaultWebFetchMaxBytes,
+		UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_7_2) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+		CacheTTL:  DefaultWebFetchCacheTTL,
+	}
+}
+
+// Name returns the tool name.
+func (t *WebFetchTool) Name() string {
+	return "web_fetch"
+}
+
+// Description returns the tool description.
+func (t *WebFetchTool) Description() string {
+	return `Fetch a URL and extract its main readable content as markdown (headings, links, lists, tables) or plain text.
+Long pages are paginated: pass nextOffset back as offset to continue. Results are cached briefly.
+Private, loopback and link-local addresses are blocked. Use the browser tool for pages that need JavaScript.`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *WebFetchTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "HTTP or HTTPS URL to fetch",
+			},
+			"maxChars": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum characters to return (default: %d)", DefaultWebFetchMaxChars),
+			},
+			"offset": map[string]interface{}{
+				"type":        "integer",
+				"description": "Character offset to start from, for the next page of a long document",
+			},
+			"extractMode": map[string]interface{}{
+				"type":        "string",
+				"description": "Extraction mode: 'text' or 'markdown' (default: markdown)",
+				"enum":        []string{"text", "markdown"},
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// WebFetchResult represents the fetch result.
+type WebFetchResult struct {
+	URL         string `json:"url"`
+	FinalURL    string `json:"finalUrl"`
+	Status      int    `json:"status"`
+	ContentType string `json:"contentType"`
+	Title       string `json:"title,omitempty"`
+	Length      int    `json:"length"`
+	Offset      int    `json:"offset"`
+	NextOffset  int    `json:"nextOffset,omitempty"`
+	TotalChars  int    `json:"totalChars"`
+	Truncated   bool   `json:"truncated"`
+	// BodyTruncated is set when the download hit the byte limit.
+	BodyTruncated bool   `json:"bodyTruncated,omitempty"`
+	Cached        bool   `json:"cached,omitempty"`
+	TookMs        int64  `json:"tookMs"`
+	Text          string `json:"text"`
+}
+
+// webFetchPage is an extracted document as stored in the cache.
+type webFetchPage struct {
+	URL           string    `json:"url"`
+	FinalURL      string    `json:"finalUrl"`
+	Status        int       `json:"status"`
+	ContentType   string    `json:"contentType"`
+	Title         string    `json:"title,omitempty"`
+	Text          string    `json:"text"`
+	BodyTruncated bool      `json:"bodyTruncated,omitempty"`
+	FetchedAt     time.Time `json:"fetchedAt"`
+}
+
+// Execute fetches the URL content.
+func (t *WebFetchTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	urlStr, _ := params["url"].(string)
+	if urlStr == "" {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	// Validate URL
+	parsedURL, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid URL: must be http or https")
+	}
+
+	maxChars := t.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultWebFetchMaxChars
+	}
+	if mc, ok := params["maxChars"].(float64); ok && mc > 0 {
+		maxChars = int(mc)
+	}
+	offset := 0
+	if o, ok := params["offset"].(float64); ok && o > 0 {
+		offset = int(o)
+	}
+	mode, _ := params["extractMode"].(string)
+	if mode != "text" {
+		mode = "markdown"
+	}
+
+	start := time.Now()
+
+	key := webFetchCacheKey(urlStr, mode)
+	page, cached := t.cacheGet(key)
+	if !cached {
+		page, err = t.fetch(ctx, parsedURL, mode == "text")
+		if err != nil {
+			return nil, err
+		}
+		t.cachePut(key, page)
+	}
+
+	runes := []rune(page.Text)
+	if offset > len(runes) {
+		return nil, fmt.Errorf("offset %d is beyond the end of the document (%d chars)", offset, len(runes))
+	}
+	end := min(offset+maxChars, len(runes))
+	if end < len(runes) {
+		// Prefer ending a page at a paragraph break.
+		if cut := strings.LastIndex(string(runes[offset:end]), "\n\n"); cut > 0 {
+			if n := len([]rune(string(runes[offset:end])[:cut])); n > maxChars/2 {
+				end = offset + n
+			}
+		}
+	}
+	text := string(runes[offset:end])
+
+	res := &WebFetchResult{
+		URL:           urlStr,
+		FinalURL:      page.FinalURL,
+		Status:        page.Status,
+		ContentType:   page.ContentType,
+		Title:         page.Title,
+		Length:        len([]rune(text)),
+		Offset:        offset,
+		TotalChars:    len(runes),
+		Truncated:     end < len(runes),
+		BodyTruncated: page.BodyTruncated,
+		Cached:        cached,
+		TookMs:        time.Since(start).Milliseconds(),
+		Text:          text,
+	}
+	if res.Truncated {
+		res.NextOffset = end
+	}
+	return res, nil
+}
+
+// fetch downloads and extracts a page.
+func (t *WebFetchTool) fetch(ctx context.Context, u *url.URL, plain bool) (*webFetchPage, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.8")
+	req.Header.Set("User-Agent", t.UserAgent)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	guard := t.Net
+	if guard == nil {
+		guard = netguard.New(nil)
+	}
+	client := guard.Client(t.Timeout)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return fmt.Errorf("too many redirects")
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+		}
+		return nil
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("fetch failed (%d): %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	maxBytes := t.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultWebFetchMaxBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	page := &webFetchPage{
+		URL:         u.String(),
+		FinalURL:    resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		FetchedAt:   time.Now(),
+	}
+	if int64(len(body)) > maxBytes {
+		body = body[:maxBytes]
+		page.BodyTruncated = true
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(page.ContentType)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	if !isTextMedia(mediaType) {
+		return nil, fmt.Errorf("unsupported content type %q; web_fetch only extracts text and HTML", mediaType)
+	}
+
+	// Decode to UTF-8 using the header charset, a BOM or <meta charset>.
+	decoded, err := charset.NewReader(bytes.NewReader(body), page.ContentType)
+	if err != nil {
+		decoded = bytes.NewReader(body)
+	}
+
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" {
+		page.Title, page.Text, err = extractReadable(decoded, resp.Request.URL, plain)
+		if err != nil {
+			return nil, err
+		}
+		return page, nil
+	}
+
+	text, err := io.ReadAll(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	page.Text = strings.ToValidUTF8(string(text), "�")
+	return page, nil
+}
+
+func isTextMedia(mediaType string) bool {
+	switch {
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case mediaType == "application/xhtml+xml", mediaType == "application/json",
+		mediaType == "application/xml", mediaType == "application/javascript",
+		mediaType == "application/x-yaml", mediaType == "application/yaml":
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+func webFetchCacheKey(rawURL, mode string) string {
+	sum := sha256.Sum256([]byte(mode + "\x00" + rawURL))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cacheGet returns a fresh cached page.
+func (t *WebFetchTool) cacheGet(key string) (*webFetchPage, bool) {
+	if t.CacheDir == "" || t.CacheTTL <= 0 {
+		return nil, false
+	}
+	data, err := os.ReadFile(filepath.Join(t.CacheDir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	var page webFetchPage
+	if err := json.Unmarshal(data, &page); err != nil || time.Since(page.FetchedAt) > t.CacheTTL {
+		return nil, false
+	}
+	return &page, true
+}
+
+// cachePut stores a page and drops expired entries. Failures only cost a
+// refetch, so they are ignored.
+func (t *WebFetchTool) cachePut(key string, page *webFetchPage) {
+	if t.CacheDir == "" || t.CacheTTL <= 0 {
+		return
+	}
+	if err := os.MkdirAll(t.CacheDir, 0700); err != nil {
+		return
+	}
+	if entries, err := os.ReadDir(t.CacheDir); err == nil {
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && time.Since(info.ModTime()) > t.CacheTTL {
+				_ = os.Remove(filepath.Join(t.CacheDir, e.Name()))
+			}
+		}
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return
+	}
+	tmp := filepath.Join(t.CacheDir, key+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err == nil {
+		_ = os.Rename(tmp, filepath.Join(t.CacheDir, key+".json"))
+	}
+}
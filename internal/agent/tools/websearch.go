@@ -0,0 +1,289 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// SearXNGSearch queries a self-hosted SearXNG instance's JSON API.
+type SearXNGSearch struct {
+	// BaseURL is the instance root, e.g. http://localhost:8888.
+	BaseURL string
+	// Params are extra query parameters such as engines or categories.
+	Params map[string]string
+	Client *http.Client
+}
+
+// Name returns the backend name.
+func (s *SearXNGSearch) Name() string {
+	return "searxng"
+}
+
+// Search runs the query.
+func (s *SearXNGSearch) Search(ctx context.Context, q SearchQuery) (*SearchResponse, error) {
+	if s.BaseURL == "" {
+		return nil, fmt.Errorf("searxng base URL is not set")
+	}
+	searchURL, err := url.Parse(strings.TrimRight(s.BaseURL, "/") + "/search")
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	v := searchURL.Query()
+	v.Set("q", q.Query)
+	v.Set("format", "json")
+	if lang := searchLanguage(q); lang != "" {
+		v.Set("language", lang)
+	}
+	if r, ok := freshnessRange[q.Freshness]; ok {
+		v.Set("time_range", r)
+	}
+	for k, val := range s.Params {
+		v.Set(k, val)
+	}
+	searchURL.RawQuery = v.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", searchURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	var out struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"publishedDate"`
+		} `json:"results"`
+		Answers []interface{} `json:"answers"`
+	}
+	if err := doSearchRequest(s.Client, req, &out); err != nil {
+		return nil, err
+	}
+
+	resp := &SearchResponse{}
+	for _, r := range out.Results {
+		resp.Results = append(resp.Results, SearchResult{
+			Title:       r.Title,
+			URL:         r.URL,
+			Description: r.Content,
+			Published:   r.PublishedDate,
+		})
+	}
+	// Answers are strings in older releases and objects in newer ones.
+	for _, a := range out.Answers {
+		switch a := a.(type) {
+		case string:
+			resp.Answer = a
+		case map[string]interface{}:
+			resp.Answer, _ = a["answer"].(string)
+		}
+		if resp.Answer != "" {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// TavilySearch queries the Tavily search API, or a compatible endpoint.
+type TavilySearch struct {
+	APIKey string
+	// BaseURL defaults to https://api.tavily.com.
+	BaseURL string
+	// Params are extra request fields such as search_depth or topic.
+	Params map[string]string
+	Client *http.Client
+}
+
+// Name returns the backend name.
+func (s *TavilySearch) Name() string {
+	return "tavily"
+}
+
+// Search runs the query.
+func (s *TavilySearch) Search(ctx context.Context, q SearchQuery) (*SearchResponse, error) {
+	if s.APIKey == "" {
+		return nil, fmt.Errorf("tavily API key is not set (TAVILY_API_KEY)")
+	}
+	base := s.BaseURL
+	if base == "" {
+		base = "https://api.tavily.com"
+	}
+
+	body := map[string]interface{}{
+		"query":          q.Query,
+		"max_results":    q.Count,
+		"include_answer": true,
+	}
+	if r, ok := freshnessRange[q.Freshness]; ok {
+		body["time_range"] = r
+	}
+	if q.Country != "" {
+		body["country"] = strings.ToLower(q.Country)
+	}
+	for k, v := range s.Params {
+		body[k] = v
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(base, "/")+"/search", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+
+	var out struct {
+		Answer  string `json:"answer"`
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"published_date"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(s.Client, req, &out); err != nil {
+		return nil, err
+	}
+
+	resp := &SearchResponse{Answer: out.Answer}
+	for _, r := range out.Results {
+		resp.Results = append(resp.Results, SearchResult{
+			Title:       r.Title,
+			URL:         r.URL,
+			Description: r.Content,
+			Published:   r.PublishedDate,
+		})
+	}
+	return resp, nil
+}
+
+// ModelSearch asks a search-capable model behind an OpenAI-compatible
+// /chat/completions endpoint (e.g. Perplexity Sonar or OpenAI search
+// models) and turns its citations into results.
+type ModelSearch struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	// Params are extra request fields such as search_context_size.
+	Params map[string]string
+	Client *http.Client
+}
+
+// Name returns the backend name.
+func (s *ModelSearch) Name() string {
+	return "model"
+}
+
+// Search runs the query.
+func (s *ModelSearch) Search(ctx context.Context, q SearchQuery) (*SearchResponse, error) {
+	if s.BaseURL == "" || s.Model == "" {
+		return nil, fmt.Errorf("model search needs a base URL and model")
+	}
+
+	prompt := "Search the web and answer concisely, citing your sources."
+	if q.Freshness != "" {
+		prompt += " Prefer results from the last " + map[string]string{"pd": "day", "pw": "week", "pm": "month", "py": "year"}[q.Freshness] + "."
+	}
+	if lang := searchLanguage(q); lang != "" {
+		prompt += " Answer in language: " + lang + "."
+	}
+	body := map[string]interface{}{
+		"model": s.Model,
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt},
+			{"role": "user", "content": q.Query},
+		},
+	}
+	for k, v := range s.Params {
+		body[k] = v
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(s.BaseURL, "/")+"/chat/completions", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message struct {
+				Content     string `json:"content"`
+				Annotations []struct {
+					Type        string `json:"type"`
+					URLCitation struct {
+						URL   string `json:"url"`
+						Title string `json:"title"`
+					} `json:"url_citation"`
+				} `json:"annotations"`
+			} `json:"message"`
+		} `json:"choices"`
+		// Perplexity returns sources at the top level.
+		Citations     []string `json:"citations"`
+		SearchResults []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Date    string `json:"date"`
+			Snippet string `json:"snippet"`
+		} `json:"search_results"`
+	}
+	if err := doSearchRequest(s.Client, req, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Choices) == 0 {
+		return nil, fmt.Errorf("model returned no choices")
+	}
+
+	msg := out.Choices[0].Message
+	resp := &SearchResponse{Answer: strings.TrimSpace(msg.Content)}
+	seen := make(map[string]bool)
+	add := func(r SearchResult) {
+		if r.URL == "" || seen[r.URL] {
+			return
+		}
+		seen[r.URL] = true
+		resp.Results = append(resp.Results, r)
+	}
+	for _, r := range out.SearchResults {
+		add(SearchResult{Title: r.Title, URL: r.URL, Description: r.Snippet, Published: r.Date})
+	}
+	for _, a := range msg.Annotations {
+		if a.Type == "url_citation" {
+			add(SearchResult{Title: a.URLCitation.Title, URL: a.URLCitation.URL})
+		}
+	}
+	for _, c := range out.Citations {
+		add(SearchResult{Title: c, URL: c})
+	}
+	return resp, nil
+}
+
+// freshnessRange maps Brave freshness codes to time ranges.
+var freshnessRange = map[string]string{
+	"pd": "day",
+	"pw": "week",
+	"pm": "month",
+	"py": "year",
+}
+
+func searchLanguage(q SearchQuery) string {
+	if q.SearchLang != "" {
+		return q.SearchLang
+	}
+	return q.UILang
+}
@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
+	"github.com/liteclaw/liteclaw/internal/agent/workspace"
+)
+
+const (
+	// DefaultWorkspaceHistoryLimit is how many commits log lists.
+	DefaultWorkspaceHistoryLimit = 20
+	// DefaultWorkspaceHistoryMaxChars caps diffs and file contents.
+	DefaultWorkspaceHistoryMaxChars = 50000
+)
+
+// WorkspaceHistoryTool browses the workspace's git history and reverts
+// files to earlier revisions.
+type WorkspaceHistoryTool struct {
+	Repo *workspace.Repo
+	// Index is refreshed after a revert so restored memory is searchable.
+	Index    *memory.Index
+	MaxChars int
+}
+
+// NewWorkspaceHistoryTool creates a new workspace history tool.
+func NewWorkspaceHistoryTool(repo *workspace.Repo) *WorkspaceHistoryTool {
+	return &WorkspaceHistoryTool{Repo: repo, MaxChars: DefaultWorkspaceHistoryMaxChars}
+}
+
+// Name returns the tool name.
+func (t *WorkspaceHistoryTool) Name() string {
+	return "workspace_history"
+}
+
+// Description returns the tool description.
+func (t *WorkspaceHistoryTool) Description() string {
+	return `Review and undo changes to workspace files, which are tracked in git.
+Actions: log (commits, optionally for one path), diff (changes made by rev, or uncommitted changes without rev), show (a file's content at rev), revert (restore path to its content at rev and commit).`
+}
+
+// Parameters returns the JSON Schema for parameters.
+func (t *WorkspaceHistoryTool) Parameters() interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type": "string",
+				"enum": []string{"log", "diff", "show", "revert"},
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "File relative to the workspace, e.g. SOUL.md (required for show and revert)",
+			},
+			"rev": map[string]interface{}{
+				"type":        "string",
+				"description": "Commit hash from log, or a git revision such as HEAD~1 (required for show and revert)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Commits to list (default: %d)", DefaultWorkspaceHistoryLimit),
+				"minimum":     1,
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+// WorkspaceHistoryResult is the outcome of a diff, show or revert.
+type WorkspaceHistoryResult struct {
+	Action    string `json:"action"`
+	Path      string `json:"path,omitempty"`
+	Rev       string `json:"rev,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Commit is the commit recording a revert; empty when nothing changed.
+	Commit string `json:"commit,omitempty"`
+}
+
+// Execute runs the action.
+func (t *WorkspaceHistoryTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if t.Repo == nil {
+		return nil, fmt.Errorf("workspace history is not enabled (set agents.defaults.git.enabled)")
+	}
+	action, _ := params["action"].(string)
+	path, _ := params["path"].(string)
+	rev, _ := params["rev"].(string)
+
+	res := &WorkspaceHistoryResult{Action: action, Path: path, Rev: rev}
+	var err error
+	switch action {
+	case "log":
+		limit := DefaultWorkspaceHistoryLimit
+		if n, ok := params["limit"].(float64); ok && n >= 1 {
+			limit = int(n)
+		}
+		commits, err := t.Repo.Log(path, limit)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"commits": commits}, nil
+	case "diff":
+		res.Text, err = t.Repo.Diff(rev, path)
+	case "show":
+		if path == "" || rev == "" {
+			return nil, fmt.Errorf("path and rev are required for show")
+		}
+		res.Text, err = t.Repo.Show(rev, path)
+	case "revert":
+		if path == "" || rev == "" {
+			return nil, fmt.Errorf("path and rev are required for revert")
+		}
+		message := fmt.Sprintf("Revert %s to %s", path, rev)
+		if sc, ok := SessionFromContext(ctx); ok && sc.Key != "" {
+			message += "\n\nSession: " + sc.Key
+		}
+		if res.Commit, err = t.Repo.Revert(rev, path, message); err == nil {
+			refreshMemoryIndex(t.Index)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q (use log, diff, show or revert)", action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	maxChars := t.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultWorkspaceHistoryMaxChars
+	}
+	if len(res.Text) > maxChars {
+		res.Text, res.Truncated = truncateText(res.Text, maxChars), true
+	}
+	return res, nil
+}
@@ -0,0 +1,252 @@
+// Package transcribe turns inbound audio into text before the agent turn.
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured is returned when no transcriber is set up.
+var ErrNotConfigured = errors.New("no transcriber is configured (tools.transcription)")
+
+// MaxAudioBytes is the largest file accepted for transcription.
+const MaxAudioBytes = 25 << 20
+
+// Transcript is the text recognised in an audio file.
+type Transcript struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// Transcriber converts an audio file to text.
+type Transcriber interface {
+	Name() string
+	Transcribe(ctx context.Context, path string) (*Transcript, error)
+}
+
+// OpenAI transcribes through an OpenAI-compatible /audio/transcriptions
+// endpoint (OpenAI, Groq, a local whisper server, ...).
+type OpenAI struct {
+	APIKey string
+	// BaseURL defaults to https://api.openai.com/v1.
+	BaseURL string
+	// Model defaults to whisper-1.
+	Model string
+	// Language is an optional ISO-639-1 hint.
+	Language string
+	// Prompt is optional context such as names and jargon.
+	Prompt string
+	Client *http.Client
+}
+
+// Name returns the transcriber name.
+func (t *OpenAI) Name() string {
+	return "openai"
+}
+
+// Transcribe uploads the file and returns its transcript.
+func (t *OpenAI) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	f, err := openAudio(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	base := t.BaseURL
+	if base == "" {
+		base = "https://api.openai.com/v1"
+	}
+	model := t.Model
+	if model == "" {
+		model = "whisper-1"
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	_ = w.WriteField("model", model)
+	// Only whisper-1 returns duration and language in verbose_json.
+	if model == "whisper-1" {
+		_ = w.WriteField("response_format", "verbose_json")
+	} else {
+		_ = w.WriteField("response_format", "json")
+	}
+	if t.Language != "" {
+		_ = w.WriteField("language", t.Language)
+	}
+	if t.Prompt != "" {
+		_ = w.WriteField("prompt", t.Prompt)
+	}
+	part, err := w.CreateFormFile("file", audioFileName(path))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimRight(base, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	client := t.Client
+	if client == nil {
+		client = &http.Client{Timeout: 2 * time.Minute}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("transcription API error (%d): %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var out Transcript
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	out.Text = strings.TrimSpace(out.Text)
+	return &out, nil
+}
+
+// Command runs a local recogniser such as whisper.cpp or faster-whisper.
+//
+// Args may contain {input}, {output} and {language}. When an argument
+// mentions {output} the transcript is read from that file (or from
+// {output}.txt, which whisper.cpp appends); otherwise from stdout.
+type Command struct {
+	Command  string
+	Args     []string
+	Language string
+	// Env holds extra environment variables.
+	Env     map[string]string
+	Timeout time.Duration
+}
+
+// Name returns the transcriber name.
+func (t *Command) Name() string {
+	return "command"
+}
+
+// Transcribe runs the command on the file.
+func (t *Command) Transcribe(ctx context.Context, path string) (*Transcript, error) {
+	if t.Command == "" {
+		return nil, fmt.Errorf("transcription command is not set (tools.transcription.command.command)")
+	}
+	f, err := openAudio(path)
+	if err != nil {
+		return nil, err
+	}
+	_ = f.Close()
+
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	tmpDir, err := os.MkdirTemp("", "liteclaw-stt-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+	output := filepath.Join(tmpDir, "transcript")
+
+	replacer := strings.NewReplacer("{input}", path, "{output}", output, "{language}", t.Language)
+	var outputArg bool
+	args := make([]string, 0, len(t.Args))
+	for _, a := range t.Args {
+		outputArg = outputArg || strings.Contains(a, "{output}")
+		args = append(args, replacer.Replace(a))
+	}
+
+	cmd := exec.CommandContext(ctx, t.Command, args...)
+	cmd.Env = os.Environ()
+	for k, v := range t.Env {
+		cmd.Env = append(cmd.Env, k+"="+os.ExpandEnv(v))
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("transcription command timed out after %s", timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 512 {
+			msg = msg[len(msg)-512:]
+		}
+		return nil, fmt.Errorf("transcription command failed: %w: %s", err, msg)
+	}
+
+	text := stdout.String()
+	if outputArg {
+		data, err := os.ReadFile(output)
+		if errors.Is(err, os.ErrNotExist) {
+			data, err = os.ReadFile(output + ".txt")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("transcription command wrote no transcript: %w", err)
+		}
+		text = string(data)
+	}
+	return &Transcript{Text: strings.TrimSpace(text), Language: t.Language}, nil
+}
+
+// openAudio opens path after checking it is a regular file within the
+// size limit.
+func openAudio(path string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > MaxAudioBytes {
+		_ = f.Close()
+		return nil, fmt.Errorf("audio is %d bytes; the limit is %d", info.Size(), MaxAudioBytes)
+	}
+	return f, nil
+}
+
+// audioFileName returns an upload name whose extension the API accepts;
+// Telegram voice notes arrive as .oga, which OpenAI rejects.
+func audioFileName(path string) string {
+	name := filepath.Base(path)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".oga", ".opus":
+		return strings.TrimSuffix(name, filepath.Ext(name)) + ".ogg"
+	case "":
+		return name + ".ogg"
+	}
+	return name
+}
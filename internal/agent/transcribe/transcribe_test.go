@@ -0,0 +1,71 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeAudio(t *testing.T, name string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte("OggS-audio"), 0644))
+	return path
+}
+
+func TestOpenAITranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer k", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+		assert.Equal(t, "de", r.FormValue("language"))
+		f, hdr, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(f)
+		assert.Equal(t, "voice.ogg", hdr.Filename)
+		assert.Equal(t, "OggS-audio", string(data))
+		_, _ = w.Write([]byte(`{"text":" Hallo Welt ","language":"german","duration":1.5}`))
+	}))
+	defer srv.Close()
+
+	tr := &OpenAI{APIKey: "k", BaseURL: srv.URL + "/v1", Language: "de"}
+	out, err := tr.Transcribe(context.Background(), writeAudio(t, "voice.oga"))
+	require.NoError(t, err)
+	assert.Equal(t, "Hallo Welt", out.Text)
+	assert.Equal(t, 1.5, out.Duration)
+
+	_, err = tr.Transcribe(context.Background(), filepath.Join(t.TempDir(), "missing.ogg"))
+	assert.Error(t, err)
+}
+
+func TestCommandTranscribe(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	audio := writeAudio(t, "a.ogg")
+
+	// whisper.cpp style: -of {output} writes {output}.txt
+	tr := &Command{Command: "sh", Args: []string{"-c", `printf ' heard %s ' "$(cat "$1")" > "$2.txt"`, "stt", "{input}", "{output}"}}
+	out, err := tr.Transcribe(context.Background(), audio)
+	require.NoError(t, err)
+	assert.Equal(t, "heard OggS-audio", out.Text)
+
+	tr = &Command{Command: "sh", Args: []string{"-c", `echo "lang=$1"`, "stt", "{language}"}, Language: "en"}
+	out, err = tr.Transcribe(context.Background(), audio)
+	require.NoError(t, err)
+	assert.Equal(t, "lang=en", out.Text)
+
+	tr = &Command{Command: "sh", Args: []string{"-c", "echo no model >&2; exit 1"}}
+	_, err = tr.Transcribe(context.Background(), audio)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no model")
+}
@@ -0,0 +1,329 @@
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitConfig tracks the workspace in git (agents.defaults.git).
+type GitConfig struct {
+	// Enabled initialises the workspace as a git repository.
+	Enabled bool `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	// AutoCommit commits the files changed by each agent run (default: true).
+	AutoCommit *bool `json:"autoCommit,omitempty" yaml:"autoCommit,omitempty" mapstructure:"autoCommit"`
+}
+
+// AutoCommitEnabled reports whether runs are committed.
+func (c GitConfig) AutoCommitEnabled() bool {
+	return c.Enabled && (c.AutoCommit == nil || *c.AutoCommit)
+}
+
+// Commits are attributed to the agent rather than the user's git identity.
+const (
+	gitAuthorName  = "LiteClaw"
+	gitAuthorEmail = "liteclaw@localhost"
+)
+
+// defaultGitignore keeps generated media, downloads and caches out of the
+// workspace history.
+const defaultGitignore = `# Generated files the agent does not need to version.
+media/
+downloads/
+.cache/
+node_modules/
+__pycache__/
+`
+
+// ErrNotRepo is returned when the workspace is not its own git repository.
+var ErrNotRepo = errors.New("workspace is not a git repository")
+
+// Repo is a workspace tracked with git. Its methods serialise git calls so
+// concurrent runs do not race on the index.
+type Repo struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// Commit is one entry of the workspace history.
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Body holds the trailers, e.g. "Session: ..." and "Run: ...".
+	Body  string   `json:"body,omitempty"`
+	Files []string `json:"files,omitempty"`
+}
+
+// InitRepo makes dir a git repository, committing its current files apart
+// from those matched by a default .gitignore, and returns it. An existing
+// repository rooted at dir is reused. A directory inside another repository
+// is refused, so the agent never commits to a project it merely lives in.
+func InitRepo(dir string) (*Repo, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is not installed: %w", err)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Repo{Dir: abs}
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err == nil {
+		return r, nil
+	}
+	if top, err := r.git("rev-parse", "--show-toplevel"); err == nil {
+		return nil, fmt.Errorf("workspace %s is inside the git repository %s", abs, strings.TrimSpace(top))
+	}
+	if _, err := r.git("init", "-q"); err != nil {
+		return nil, err
+	}
+	ignore := filepath.Join(abs, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		if err := os.WriteFile(ignore, []byte(defaultGitignore), 0644); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := r.CommitAll("Initial workspace"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// OpenRepo returns the repository rooted at dir.
+func OpenRepo(dir string) (*Repo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(abs, ".git")); err != nil {
+		return nil, ErrNotRepo
+	}
+	return &Repo{Dir: abs}, nil
+}
+
+// CommitAll commits every change in the workspace and returns the new
+// commit's hash, or "" when there was nothing to commit.
+func (r *Repo) CommitAll(message string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commit(message)
+}
+
+// CommitFiles commits the changes to paths and returns the new commit's
+// hash, or "" when none of them changed. Paths outside the workspace,
+// ignored by git, or neither present nor tracked are skipped.
+func (r *Repo) CommitFiles(message string, paths []string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var rels []string
+	seen := make(map[string]bool)
+	for _, p := range paths {
+		rel, err := r.rel(p)
+		if err != nil || rel == "." || seen[rel] {
+			continue
+		}
+		seen[rel] = true
+		if _, err := r.git("check-ignore", "-q", "--", rel); err == nil {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(r.Dir, rel)); err != nil {
+			if out, _ := r.git("ls-files", "--", rel); strings.TrimSpace(out) == "" {
+				continue
+			}
+		}
+		rels = append(rels, rel)
+	}
+	if len(rels) == 0 {
+		return "", nil
+	}
+	return r.commit(message, rels...)
+}
+
+// commit commits the changes to paths, or to every file when none are
+// given.
+func (r *Repo) commit(message string, paths ...string) (string, error) {
+	pathspec := append([]string{"--"}, paths...)
+	if _, err := r.git(append([]string{"add", "-A"}, pathspec...)...); err != nil {
+		return "", err
+	}
+	if _, err := r.git(append([]string{"diff", "--cached", "--quiet"}, pathspec...)...); err == nil {
+		return "", nil
+	}
+	if _, err := r.git(append([]string{"commit", "-q", "--no-verify", "-m", message}, pathspec...)...); err != nil {
+		return "", err
+	}
+	hash, err := r.git("rev-parse", "HEAD")
+	return strings.TrimSpace(hash), err
+}
+
+// Log lists up to limit commits, newest first, touching path (or any file
+// when path is empty).
+func (r *Repo) Log(path string, limit int) ([]Commit, error) {
+	args := []string{"log", "--format=%x1e%H%x1f%aI%x1f%s%x1f%b%x1f", "--name-only"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("-n%d", limit))
+	}
+	if path != "" {
+		rel, err := r.rel(path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--", rel)
+	}
+	r.mu.Lock()
+	out, err := r.git(args...)
+	r.mu.Unlock()
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var commits []Commit
+	for _, rec := range strings.Split(out, "\x1e") {
+		fields := strings.Split(rec, "\x1f")
+		if len(fields) < 5 {
+			continue
+		}
+		c := Commit{Hash: fields[0], Subject: fields[2], Body: strings.TrimSpace(fields[3])}
+		c.Date, _ = time.Parse(time.RFC3339, fields[1])
+		for _, f := range strings.Split(fields[4], "\n") {
+			if f = strings.TrimSpace(f); f != "" {
+				c.Files = append(c.Files, f)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// Diff returns the changes made by rev, or the uncommitted changes when rev
+// is empty, limited to path when it is set.
+func (r *Repo) Diff(rev, path string) (string, error) {
+	var args []string
+	if rev == "" {
+		args = []string{"diff", "--no-ext-diff", "--no-textconv", "HEAD"}
+	} else {
+		if err := checkRev(rev); err != nil {
+			return "", err
+		}
+		args = []string{"show", "--format=commit %H%n%s%n", "--no-color", "--no-ext-diff", "--no-textconv", rev}
+	}
+	if path != "" {
+		rel, err := r.rel(path)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, "--", rel)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.git(args...)
+}
+
+// Show returns the content of path at rev.
+func (r *Repo) Show(rev, path string) (string, error) {
+	if err := checkRev(rev); err != nil {
+		return "", err
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.git("show", rev+":"+filepath.ToSlash(rel))
+}
+
+// Revert restores path to its content at rev, deleting it if it did not
+// exist then, and commits just that path. It returns the new commit's
+// hash, or "" when the file already matched.
+func (r *Repo) Revert(rev, path, message string) (string, error) {
+	if err := checkRev(rev); err != nil {
+		return "", err
+	}
+	rel, err := r.rel(path)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.git("rev-parse", "--verify", "--quiet", rev+"^{commit}"); err != nil {
+		return "", fmt.Errorf("unknown revision %q", rev)
+	}
+	if _, err := r.git("cat-file", "-e", rev+":"+filepath.ToSlash(rel)); err == nil {
+		if _, err := r.git("checkout", rev, "--", rel); err != nil {
+			return "", err
+		}
+	} else if err := os.RemoveAll(filepath.Join(r.Dir, rel)); err != nil {
+		return "", err
+	}
+	return r.commit(message, rel)
+}
+
+// rel makes path relative to the workspace, refusing paths outside it.
+func (r *Repo) rel(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.Dir, path)
+	}
+	rel, err := filepath.Rel(r.Dir, filepath.Clean(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the workspace", path)
+	}
+	if rel == ".git" || strings.HasPrefix(rel, ".git"+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is git's own data", path)
+	}
+	return rel, nil
+}
+
+// checkRev rejects revisions git would take as options.
+func checkRev(rev string) error {
+	if rev == "" || strings.HasPrefix(rev, "-") || strings.ContainsAny(rev, " \t\n:") {
+		return fmt.Errorf("invalid revision %q", rev)
+	}
+	return nil
+}
+
+// safeGitConfig overrides repository settings that make git run programs,
+// since the agent can write to the workspace: hooks, the fsmonitor, and
+// external diff, pager and ssh commands.
+var safeGitConfig = []string{
+	"-c", "core.hooksPath=/dev/null",
+	"-c", "core.fsmonitor=false",
+	"-c", "core.pager=cat",
+	"-c", "core.sshCommand=ssh",
+	"-c", "diff.external=",
+	"-c", "commit.gpgsign=false",
+	"-c", "core.quotepath=false",
+}
+
+// git runs a git command in the workspace and returns its stdout.
+func (r *Repo) git(args ...string) (string, error) {
+	sub := args[0]
+	args = append(append([]string(nil), safeGitConfig...), args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.Dir
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME="+gitAuthorName, "GIT_AUTHOR_EMAIL="+gitAuthorEmail,
+		"GIT_COMMITTER_NAME="+gitAuthorName, "GIT_COMMITTER_EMAIL="+gitAuthorEmail,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return stdout.String(), fmt.Errorf("git %s: %w", sub, err)
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", sub, msg)
+	}
+	return stdout.String(), nil
+}
@@ -78,13 +78,15 @@ func loadTemplate(filename string) (string, error) {
 }
 
 // EnsureWorkspace ensures the workspace directory and default files exist.
-func EnsureWorkspace(dir string) error {
+// When git is enabled it also makes the workspace a git repository and
+// returns it; otherwise the returned repo is nil.
+func EnsureWorkspace(dir string, git GitConfig) (*Repo, error) {
 	if dir == "" {
 		dir = ResolveDefaultDir()
 	}
 
 	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create workspace dir: %w", err)
+		return nil, fmt.Errorf("failed to create workspace dir: %w", err)
 	}
 
 	// Define defaults, trying to load from templates first
@@ -133,7 +135,15 @@ func EnsureWorkspace(dir string) error {
 			}
 		}
 	}
-	return nil
+
+	if !git.Enabled {
+		return nil, nil
+	}
+	repo, err := InitRepo(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to track workspace in git: %w", err)
+	}
+	return repo, nil
 }
 
 // LoadBootstrapFiles loads all standard configuration files from the workspace
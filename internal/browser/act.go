@@ -0,0 +1,423 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/input"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+	"github.com/chromedp/chromedp/kb"
+)
+
+// ActRequest is an interaction with a page.
+type ActRequest struct {
+	// Kind is click, type, press, hover, drag, select, evaluate, wait,
+	// resize or close.
+	Kind string `json:"kind"`
+	// Selector is the target element: a snapshot ref such as e12, or a CSS
+	// selector.
+	Selector string `json:"selector,omitempty"`
+	// EndSelector is where drag drops, as a ref or CSS selector.
+	EndSelector string `json:"endSelector,omitempty"`
+	// Text is typed by type, or awaited by wait.
+	Text string `json:"text,omitempty"`
+	// Submit presses Enter after type.
+	Submit bool `json:"submit,omitempty"`
+	// DoubleClick makes click a double click.
+	DoubleClick bool `json:"doubleClick,omitempty"`
+	// Key is pressed by press, e.g. Enter, ArrowDown or Control+A.
+	Key string `json:"key,omitempty"`
+	// Values are the options chosen by select.
+	Values []string `json:"values,omitempty"`
+	// Fn is the JavaScript run by evaluate: an expression or a function,
+	// which receives the element when a selector is given.
+	Fn string `json:"fn,omitempty"`
+	// TimeMs makes wait sleep.
+	TimeMs int `json:"timeMs,omitempty"`
+	// TimeoutMs overrides the action timeout.
+	TimeoutMs int `json:"timeoutMs,omitempty"`
+	Width     int `json:"width,omitempty"`
+	Height    int `json:"height,omitempty"`
+}
+
+// Act performs req on a tab, or the active tab when id is empty, and
+// returns the evaluate or select result. Selector may be a CSS selector
+// or a ref from the tab's last snapshot.
+func (b *Browser) Act(ctx context.Context, id string, req ActRequest) (interface{}, error) {
+	if req.Kind == "close" {
+		return nil, b.CloseTab(ctx, id)
+	}
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	timeout := b.timeout()
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	if req.Kind == "wait" && req.TimeMs > 0 {
+		timeout += time.Duration(req.TimeMs) * time.Millisecond
+	}
+
+	var result interface{}
+	err = b.runFor(ctx, t, timeout, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		result, err = act(ctx, req, t.refMap())
+		return err
+	}))
+	return result, err
+}
+
+// act performs req over the CDP executor in ctx, so it serves both managed
+// tabs and the extension relay. ctx bounds waits.
+func act(ctx context.Context, req ActRequest, refs RefMap) (interface{}, error) {
+	switch req.Kind {
+	case "click":
+		node, err := resolve(ctx, req.Selector, refs)
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := nodeCenter(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		var opts []chromedp.MouseOption
+		if req.DoubleClick {
+			opts = append(opts, chromedp.ClickCount(2))
+		}
+		return nil, chromedp.MouseClickXY(x, y, opts...).Do(ctx)
+
+	case "type":
+		node, err := resolve(ctx, req.Selector, refs)
+		if err != nil {
+			return nil, err
+		}
+		// type replaces the field's content rather than appending to it.
+		if err := callOn(node, `function() { if ("value" in this) { this.value = ""; this.dispatchEvent(new Event("input", {bubbles: true})); } }`, nil).Do(ctx); err != nil {
+			return nil, err
+		}
+		if err := dom.Focus().WithBackendNodeID(node).Do(ctx); err != nil {
+			return nil, err
+		}
+		if err := chromedp.KeyEvent(req.Text).Do(ctx); err != nil {
+			return nil, err
+		}
+		if req.Submit {
+			return nil, chromedp.KeyEvent(kb.Enter).Do(ctx)
+		}
+		return nil, nil
+
+	case "press":
+		keys, mods, err := parseKey(req.Key)
+		if err != nil {
+			return nil, err
+		}
+		if req.Selector != "" {
+			node, err := resolve(ctx, req.Selector, refs)
+			if err != nil {
+				return nil, err
+			}
+			if err := dom.Focus().WithBackendNodeID(node).Do(ctx); err != nil {
+				return nil, err
+			}
+		}
+		var opts []chromedp.KeyOption
+		if len(mods) > 0 {
+			opts = append(opts, chromedp.KeyModifiers(mods...))
+		}
+		return nil, chromedp.KeyEvent(keys, opts...).Do(ctx)
+
+	case "hover":
+		node, err := resolve(ctx, req.Selector, refs)
+		if err != nil {
+			return nil, err
+		}
+		x, y, err := nodeCenter(ctx, node)
+		if err != nil {
+			return nil, err
+		}
+		return nil, chromedp.MouseEvent(input.MouseMoved, x, y).Do(ctx)
+
+	case "drag":
+		from, err := resolve(ctx, req.Selector, refs)
+		if err != nil {
+			return nil, err
+		}
+		to, err := resolve(ctx, req.EndSelector, refs)
+		if err != nil {
+			return nil, fmt.Errorf("drag target: %w", err)
+		}
+		return nil, drag(ctx, from, to)
+
+	case "select":
+		if len(req.Values) == 0 {
+			return nil, fmt.Errorf("values is required")
+		}
+		node, err := resolve(ctx, req.Selector, refs)
+		if err != nil {
+			return nil, err
+		}
+		var selected []string
+		err = callOn(node, `function(values) {
+			if (!this.options) throw new Error("element is not a <select>");
+			const chosen = [];
+			for (const o of this.options) {
+				o.selected = values.includes(o.value) || values.includes(o.label);
+				if (o.selected) chosen.push(o.value);
+			}
+			this.dispatchEvent(new Event("input", {bubbles: true}));
+			this.dispatchEvent(new Event("change", {bubbles: true}));
+			return chosen;
+		}`, &selected, req.Values).Do(ctx)
+		if err == nil && len(selected) == 0 {
+			err = fmt.Errorf("no option matches %v", req.Values)
+		}
+		return selected, err
+
+	case "evaluate":
+		if req.Fn == "" {
+			return nil, fmt.Errorf("fn is required")
+		}
+		var result interface{}
+		if req.Selector != "" {
+			node, err := resolve(ctx, req.Selector, refs)
+			if err != nil {
+				return nil, err
+			}
+			err = callOn(node, "function() { return ("+req.Fn+")(this); }", &result).Do(ctx)
+			return result, err
+		}
+		expr := req.Fn
+		if isFunction(expr) {
+			expr = "(" + expr + ")()"
+		}
+		err := evaluate(ctx, expr, &result)
+		return result, err
+
+	case "wait":
+		switch {
+		case req.Selector != "":
+			return nil, poll(ctx, func() (bool, error) {
+				node, err := resolve(ctx, req.Selector, refs)
+				if err != nil {
+					if IsRef(req.Selector) {
+						return false, err
+					}
+					return false, nil
+				}
+				var visible bool
+				err = callOn(node, `function() { const r = this.getBoundingClientRect(); return r.width > 0 && r.height > 0 && getComputedStyle(this).visibility !== "hidden"; }`, &visible).Do(ctx)
+				return err == nil && visible, nil
+			})
+		case req.Text != "":
+			return nil, poll(ctx, func() (bool, error) {
+				var found bool
+				err := evaluate(ctx, fmt.Sprintf("!!document.body && document.body.innerText.includes(%q)", req.Text), &found)
+				return err == nil && found, nil
+			})
+		case req.TimeMs > 0:
+			return nil, chromedp.Sleep(time.Duration(req.TimeMs) * time.Millisecond).Do(ctx)
+		default:
+			return nil, fmt.Errorf("wait needs a selector, ref, text or timeMs")
+		}
+
+	case "resize":
+		if req.Width <= 0 || req.Height <= 0 {
+			return nil, fmt.Errorf("width and height are required")
+		}
+		return nil, chromedp.EmulateViewport(int64(req.Width), int64(req.Height)).Do(ctx)
+
+	default:
+		return nil, fmt.Errorf("unknown act kind: %s", req.Kind)
+	}
+}
+
+// drag presses the mouse on from, moves it in steps to to and releases it.
+func drag(ctx context.Context, from, to cdp.BackendNodeID) error {
+	x0, y0, err := nodeCenter(ctx, from)
+	if err != nil {
+		return err
+	}
+	x1, y1, err := nodeCenter(ctx, to)
+	if err != nil {
+		return err
+	}
+	if err := input.DispatchMouseEvent(input.MouseMoved, x0, y0).Do(ctx); err != nil {
+		return err
+	}
+	if err := input.DispatchMouseEvent(input.MousePressed, x0, y0).WithButton(input.Left).WithButtons(1).WithClickCount(1).Do(ctx); err != nil {
+		return err
+	}
+	const steps = 10
+	for i := 1; i <= steps; i++ {
+		x := x0 + (x1-x0)*float64(i)/steps
+		y := y0 + (y1-y0)*float64(i)/steps
+		if err := input.DispatchMouseEvent(input.MouseMoved, x, y).WithButton(input.Left).WithButtons(1).Do(ctx); err != nil {
+			return err
+		}
+	}
+	return input.DispatchMouseEvent(input.MouseReleased, x1, y1).WithButton(input.Left).WithClickCount(1).Do(ctx)
+}
+
+// evaluate runs expr in the page, awaiting promises, and decodes the
+// result into res.
+func evaluate(ctx context.Context, expr string, res interface{}) error {
+	out, exc, err := runtime.Evaluate(expr).WithReturnByValue(true).WithAwaitPromise(true).Do(ctx)
+	if err != nil {
+		return err
+	}
+	if exc != nil {
+		return exceptionError(exc)
+	}
+	if res != nil && out != nil && len(out.Value) > 0 {
+		return json.Unmarshal(out.Value, res)
+	}
+	return nil
+}
+
+// poll calls check until it reports true, fails, or ctx ends.
+func poll(ctx context.Context, check func() (bool, error)) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		ok, err := check()
+		if err != nil || ok {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// callOn calls the JavaScript function fn with the node as this, decoding
+// its return value into res when non-nil.
+func callOn(node cdp.BackendNodeID, fn string, res interface{}, args ...interface{}) chromedp.Action {
+	return chromedp.ActionFunc(func(ctx context.Context) error {
+		obj, err := dom.ResolveNode().WithBackendNodeID(node).Do(ctx)
+		if err != nil && strings.Contains(err.Error(), "No node") {
+			return fmt.Errorf("element is no longer in the page; take a new snapshot")
+		}
+		if err != nil {
+			return err
+		}
+		callArgs := make([]*runtime.CallArgument, 0, len(args))
+		for _, a := range args {
+			raw, err := json.Marshal(a)
+			if err != nil {
+				return err
+			}
+			callArgs = append(callArgs, &runtime.CallArgument{Value: raw})
+		}
+		out, exc, err := runtime.CallFunctionOn(fn).
+			WithObjectID(obj.ObjectID).
+			WithArguments(callArgs).
+			WithReturnByValue(true).
+			WithAwaitPromise(true).
+			Do(ctx)
+		if err != nil {
+			return err
+		}
+		if exc != nil {
+			return exceptionError(exc)
+		}
+		if res != nil && out != nil && len(out.Value) > 0 {
+			return json.Unmarshal(out.Value, res)
+		}
+		return nil
+	})
+}
+
+// exceptionError turns a thrown JavaScript exception into an error.
+func exceptionError(exc *runtime.ExceptionDetails) error {
+	if exc.Exception != nil && exc.Exception.Description != "" {
+		return fmt.Errorf("%s", exc.Exception.Description)
+	}
+	return fmt.Errorf("%s", exc.Text)
+}
+
+// nodeCenter scrolls node into view and returns its centre point.
+func nodeCenter(ctx context.Context, node cdp.BackendNodeID) (float64, float64, error) {
+	if err := dom.ScrollIntoViewIfNeeded().WithBackendNodeID(node).Do(ctx); err != nil {
+		return 0, 0, err
+	}
+	box, err := dom.GetBoxModel().WithBackendNodeID(node).Do(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	q := box.Content
+	if len(q) < 8 {
+		return 0, 0, fmt.Errorf("element has no box")
+	}
+	return (q[0] + q[2] + q[4] + q[6]) / 4, (q[1] + q[3] + q[5] + q[7]) / 4, nil
+}
+
+// parseKey turns "Control+Shift+ArrowDown" into the key to send and its
+// modifiers.
+func parseKey(spec string) (string, []input.Modifier, error) {
+	if spec == "" {
+		return "", nil, fmt.Errorf("key is required")
+	}
+	parts := strings.Split(spec, "+")
+	name := parts[len(parts)-1]
+	if name == "" && len(parts) > 1 { // "Control++"
+		name = "+"
+		parts = parts[:len(parts)-1]
+	}
+	var mods []input.Modifier
+	for _, m := range parts[:len(parts)-1] {
+		switch strings.ToLower(m) {
+		case "control", "ctrl":
+			mods = append(mods, input.ModifierCtrl)
+		case "shift":
+			mods = append(mods, input.ModifierShift)
+		case "alt", "option":
+			mods = append(mods, input.ModifierAlt)
+		case "meta", "command", "cmd":
+			mods = append(mods, input.ModifierMeta)
+		default:
+			return "", nil, fmt.Errorf("unknown modifier %q", m)
+		}
+	}
+
+	if utf8.RuneCountInString(name) == 1 {
+		return name, mods, nil
+	}
+	switch strings.ToLower(name) {
+	case "esc":
+		name = "Escape"
+	case "return":
+		name = "Enter"
+	case "space":
+		return " ", mods, nil
+	}
+	for r, k := range kb.Keys {
+		if strings.EqualFold(k.Key, name) || strings.EqualFold(k.Code, name) {
+			return string(r), mods, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown key %q", name)
+}
+
+// isFunction reports whether js is a function rather than an expression.
+func isFunction(js string) bool {
+	js = strings.TrimSpace(js)
+	if strings.HasPrefix(js, "function") || strings.HasPrefix(js, "async ") {
+		return true
+	}
+	arrow := strings.Index(js, "=>")
+	if arrow < 0 {
+		return false
+	}
+	head := strings.TrimSpace(js[:arrow])
+	return strings.HasPrefix(head, "(") || !strings.ContainsAny(head, " .;\"'`")
+}
@@ -3,168 +3,765 @@ package browser
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 )
 
-// Browser manages browser automation.
+// maxConsoleMessages caps the console messages kept per tab.
+const maxConsoleMessages = 200
+
+// Browser manages one Chromium instance, launched locally or attached
+// over CDP, and its tabs.
 type Browser struct {
-	ctx        context.Context
-	cancel     context.CancelFunc
-	controlURL string
-	connected  bool
+	cfg Config
+
+	mu          sync.Mutex
+	allocCancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	tabs        map[target.ID]*tab
+	active      target.ID
+	downloads   []Download
+	connected   bool
 }
 
 // Config holds browser configuration.
 type Config struct {
-	ControlURL string // CDP endpoint, e.g., http://localhost:9222
+	// ControlURL attaches to a running browser's CDP endpoint, e.g.
+	// http://localhost:9222. Empty launches a local Chromium.
+	ControlURL string
 	Headless   bool
-	Timeout    time.Duration
+	// Timeout bounds each action (default: 30s).
+	Timeout time.Duration
+	// ExecPath overrides Chromium discovery when launching.
+	ExecPath string
+	// UserDataDir isolates the profile's cookies and storage.
+	UserDataDir string
+	// DownloadDir receives downloaded files.
+	DownloadDir string
+}
+
+// TabInfo represents information about a browser tab.
+type TabInfo struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Active bool   `json:"active,omitempty"`
+}
+
+// ConsoleMessage is a console entry or uncaught exception from a tab.
+type ConsoleMessage struct {
+	Type string    `json:"type"`
+	Text string    `json:"text"`
+	Time time.Time `json:"time"`
+}
+
+// Download is a file the browser downloaded.
+type Download struct {
+	URL   string `json:"url"`
+	Path  string `json:"path,omitempty"`
+	State string `json:"state"`
+	Bytes int64  `json:"bytes,omitempty"`
+	guid  string
+}
+
+// tab is an attached page target.
+type tab struct {
+	id     target.ID
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	console []ConsoleMessage
+	// dialog is the JavaScript dialog currently open, if any.
+	dialog *page.EventJavascriptDialogOpening
+	// dialogReply answers the next dialog when armed.
+	dialogReply *dialogReply
+	// uploadFiles answer the next file chooser when armed.
+	uploadFiles []string
+	// refs maps the last snapshot's refs to nodes until the page
+	// navigates.
+	refs RefMap
+}
+
+type dialogReply struct {
+	accept     bool
+	promptText string
 }
 
 // New creates a new browser instance.
 func New(cfg *Config) *Browser {
 	return &Browser{
-		controlURL: cfg.ControlURL,
+		cfg:  *cfg,
+		tabs: make(map[target.ID]*tab),
 	}
 }
 
-// Connect connects to the browser.
+// Connect launches or attaches to the browser.
 func (b *Browser) Connect(ctx context.Context) error {
-	allocCtx, cancel := chromedp.NewRemoteAllocator(ctx, b.controlURL)
-	b.cancel = cancel
+	b.mu.Lock()
+	if b.connected {
+		b.mu.Unlock()
+		return nil
+	}
 
-	taskCtx, taskCancel := chromedp.NewContext(allocCtx)
-	b.ctx = taskCtx
+	// The browser outlives the call that starts it.
+	var allocCtx context.Context
+	var allocCancel context.CancelFunc
+	if b.cfg.ControlURL != "" {
+		allocCtx, allocCancel = chromedp.NewRemoteAllocator(context.Background(), b.cfg.ControlURL)
+	} else {
+		opts := append([]chromedp.ExecAllocatorOption{}, chromedp.DefaultExecAllocatorOptions[:]...)
+		if !b.cfg.Headless {
+			opts = append(opts, chromedp.Flag("headless", false))
+		}
+		if b.cfg.UserDataDir != "" {
+			if err := os.MkdirAll(b.cfg.UserDataDir, 0700); err != nil {
+				b.mu.Unlock()
+				return err
+			}
+			opts = append(opts, chromedp.UserDataDir(b.cfg.UserDataDir))
+		}
+		if b.cfg.ExecPath != "" {
+			opts = append(opts, chromedp.ExecPath(b.cfg.ExecPath))
+		}
+		allocCtx, allocCancel = chromedp.NewExecAllocator(context.Background(), opts...)
+	}
+	browserCtx, cancel := chromedp.NewContext(allocCtx)
 
-	// Test connection
-	if err := chromedp.Run(taskCtx, chromedp.Navigate("about:blank")); err != nil {
-		taskCancel()
+	// The first Run starts the browser and must use the browser context
+	// itself: chromedp stops the browser when that context ends.
+	started := make(chan error, 1)
+	go func() { started <- chromedp.Run(browserCtx) }()
+	var err error
+	select {
+	case err = <-started:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-time.After(b.timeout()):
+		err = fmt.Errorf("timed out after %s", b.timeout())
+	}
+	if err != nil {
+		b.mu.Unlock()
 		cancel()
-		return err
+		allocCancel()
+		if b.cfg.ControlURL != "" {
+			return fmt.Errorf("failed to attach to browser at %s: %w", b.cfg.ControlURL, err)
+		}
+		return fmt.Errorf("failed to launch Chromium (install chromium or set tools.browser.executablePath): %w", err)
 	}
 
+	b.ctx = browserCtx
+	b.cancel = cancel
+	b.allocCancel = allocCancel
 	b.connected = true
+
+	first := &tab{id: chromedp.FromContext(browserCtx).Target.TargetID, ctx: browserCtx}
+	b.watch(first)
+	b.tabs[first.id] = first
+	b.active = first.id
+	b.mu.Unlock()
+
+	if b.cfg.DownloadDir != "" {
+		b.enableDownloads()
+	}
 	return nil
 }
 
-// Close closes the browser connection.
+// Close closes the browser, or detaches from it when attached over CDP.
 func (b *Browser) Close() {
-	if b.cancel != nil {
-		b.cancel()
-	}
+	b.mu.Lock()
+	tabs := b.tabs
+	cancel, allocCancel := b.cancel, b.allocCancel
+	b.tabs = make(map[target.ID]*tab)
+	b.cancel, b.allocCancel = nil, nil
 	b.connected = false
+	b.mu.Unlock()
+
+	// Cancelling waits on chromedp, whose listeners take b.mu.
+	for _, t := range tabs {
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+	if cancel != nil {
+		cancel()
+	}
+	if allocCancel != nil {
+		allocCancel()
+	}
 }
 
 // IsConnected returns whether the browser is connected.
 func (b *Browser) IsConnected() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 	return b.connected
 }
 
-// Navigate navigates to a URL.
-func (b *Browser) Navigate(ctx context.Context, url string) error {
-	return chromedp.Run(b.ctx, chromedp.Navigate(url))
+// Config returns the browser configuration.
+func (b *Browser) Config() Config {
+	return b.cfg
 }
 
-// GetContent gets the page content.
-func (b *Browser) GetContent(ctx context.Context) (string, error) {
-	var content string
-	err := chromedp.Run(b.ctx, chromedp.InnerHTML("html", &content))
-	return content, err
+func (b *Browser) timeout() time.Duration {
+	if b.cfg.Timeout > 0 {
+		return b.cfg.Timeout
+	}
+	return 30 * time.Second
 }
 
-// Screenshot takes a screenshot.
-func (b *Browser) Screenshot(ctx context.Context, fullPage bool) ([]byte, error) {
-	var buf []byte
-	var action chromedp.Action
+// browserExecutor returns ctx bound to the browser-level CDP session.
+func (b *Browser) browserExecutor(ctx context.Context) context.Context {
+	return cdp.WithExecutor(ctx, chromedp.FromContext(b.ctx).Browser)
+}
 
-	if fullPage {
-		action = chromedp.FullScreenshot(&buf, 90)
-	} else {
-		action = chromedp.CaptureScreenshot(&buf)
+// run executes actions on t, bounded by the action timeout and ctx.
+func (b *Browser) run(ctx context.Context, t *tab, actions ...chromedp.Action) error {
+	return b.runFor(ctx, t, b.timeout(), actions...)
+}
+
+// runFor executes actions on t, bounded by timeout and ctx.
+func (b *Browser) runFor(ctx context.Context, t *tab, timeout time.Duration, actions ...chromedp.Action) error {
+	runCtx, cancel := context.WithTimeout(t.ctx, timeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	err := chromedp.Run(runCtx, actions...)
+	if err != nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		t.mu.Lock()
+		blocked := t.dialog != nil
+		t.mu.Unlock()
+		if blocked {
+			return fmt.Errorf("timed out: a JavaScript dialog is open; answer it with the dialog action")
+		}
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// tab resolves id, or the active tab when id is empty, attaching to pages
+// the browser opened itself (e.g. popups).
+func (b *Browser) tab(ctx context.Context, id string) (*tab, error) {
+	b.mu.Lock()
+	if !b.connected {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("browser is not running")
+	}
+	tid := target.ID(id)
+	if tid == "" {
+		tid = b.active
+	}
+	if t, ok := b.tabs[tid]; ok {
+		b.mu.Unlock()
+		return t, nil
 	}
+	b.mu.Unlock()
 
-	if err := chromedp.Run(b.ctx, action); err != nil {
+	if tid == "" {
+		return b.OpenTab(ctx, "")
+	}
+	infos, err := chromedp.Targets(b.ctx)
+	if err != nil {
 		return nil, err
 	}
+	for _, info := range infos {
+		if info.TargetID == tid && info.Type == "page" {
+			tabCtx, cancel := chromedp.NewContext(b.ctx, chromedp.WithTargetID(tid))
+			t := &tab{id: tid, ctx: tabCtx, cancel: cancel}
+			b.watch(t)
+			if err := chromedp.Run(tabCtx); err != nil {
+				cancel()
+				return nil, err
+			}
+			b.mu.Lock()
+			b.tabs[tid] = t
+			b.mu.Unlock()
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("tab %s not found", id)
+}
 
-	return buf, nil
+// Tabs returns the open tabs.
+func (b *Browser) Tabs(ctx context.Context) ([]TabInfo, error) {
+	if !b.IsConnected() {
+		return nil, fmt.Errorf("browser is not running")
+	}
+	targets, err := chromedp.Targets(b.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	active := b.active
+	b.mu.Unlock()
+	tabs := []TabInfo{}
+	for _, t := range targets {
+		if t.Type == "page" {
+			tabs = append(tabs, TabInfo{
+				ID:     string(t.TargetID),
+				URL:    t.URL,
+				Title:  t.Title,
+				Active: t.TargetID == active,
+			})
+		}
+	}
+	return tabs, nil
 }
 
-// Click clicks on an element.
-func (b *Browser) Click(ctx context.Context, selector string) error {
-	return chromedp.Run(b.ctx,
-		chromedp.Click(selector, chromedp.ByQuery),
-	)
+// OpenTab opens url in a new tab and makes it active.
+func (b *Browser) OpenTab(ctx context.Context, url string) (*tab, error) {
+	if !b.IsConnected() {
+		return nil, fmt.Errorf("browser is not running")
+	}
+	tabCtx, cancel := chromedp.NewContext(b.ctx)
+	t := &tab{ctx: tabCtx, cancel: cancel}
+	b.watch(t)
+	// As with the browser, the target lives as long as the context used
+	// for its first Run.
+	if err := chromedp.Run(tabCtx); err != nil {
+		cancel()
+		return nil, err
+	}
+	t.id = chromedp.FromContext(tabCtx).Target.TargetID
+
+	b.mu.Lock()
+	b.tabs[t.id] = t
+	b.active = t.id
+	b.mu.Unlock()
+
+	if url != "" && url != "about:blank" {
+		if err := b.run(ctx, t, chromedp.Navigate(url)); err != nil {
+			return t, err
+		}
+	}
+	return t, nil
 }
 
-// Type types text into an element.
-func (b *Browser) Type(ctx context.Context, selector, text string) error {
-	return chromedp.Run(b.ctx,
-		chromedp.SendKeys(selector, text, chromedp.ByQuery),
-	)
+// Open opens url in a new tab and returns it.
+func (b *Browser) Open(ctx context.Context, url string) (TabInfo, error) {
+	t, err := b.OpenTab(ctx, url)
+	if t == nil {
+		return TabInfo{}, err
+	}
+	info, infoErr := b.info(ctx, t)
+	if err == nil {
+		err = infoErr
+	}
+	return info, err
 }
 
-// WaitVisible waits for an element to be visible.
-func (b *Browser) WaitVisible(ctx context.Context, selector string, timeout time.Duration) error {
-	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
-	defer cancel()
-	_ = timeoutCtx // use the timeout context for future chromedp operations if needed
+// Focus activates a tab.
+func (b *Browser) Focus(ctx context.Context, id string) error {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := target.ActivateTarget(t.id).Do(b.browserExecutor(ctx)); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.active = t.id
+	b.mu.Unlock()
+	return nil
+}
 
-	return chromedp.Run(b.ctx,
-		chromedp.WaitVisible(selector, chromedp.ByQuery),
-	)
+// CloseTab closes a tab, or the active tab when id is empty.
+func (b *Browser) CloseTab(ctx context.Context, id string) error {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := target.CloseTarget(t.id).Do(b.browserExecutor(ctx)); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	delete(b.tabs, t.id)
+	if b.active == t.id {
+		b.active = ""
+		for other := range b.tabs {
+			b.active = other
+			break
+		}
+	}
+	b.mu.Unlock()
+	if t.cancel != nil {
+		t.cancel()
+	}
+	return nil
+}
+
+// Navigate navigates a tab to a URL, dropping its snapshot refs.
+func (b *Browser) Navigate(ctx context.Context, id, url string) (TabInfo, error) {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return TabInfo{}, err
+	}
+	t.mu.Lock()
+	t.refs = nil
+	t.mu.Unlock()
+	if err := b.run(ctx, t, chromedp.Navigate(url)); err != nil {
+		return TabInfo{}, err
+	}
+	return b.info(ctx, t)
+}
+
+// info returns the tab's current URL and title.
+func (b *Browser) info(ctx context.Context, t *tab) (TabInfo, error) {
+	info := TabInfo{ID: string(t.id)}
+	err := b.run(ctx, t, chromedp.Location(&info.URL), chromedp.Title(&info.Title))
+	b.mu.Lock()
+	info.Active = b.active == t.id
+	b.mu.Unlock()
+	return info, err
+}
+
+// Screenshot captures the viewport, the full page, or the element matching
+// selector. format is png (default) or jpeg.
+func (b *Browser) Screenshot(ctx context.Context, id, selector string, fullPage bool, format string) ([]byte, error) {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	var action chromedp.Action
+	switch {
+	case selector != "":
+		action = chromedp.ActionFunc(func(ctx context.Context) error {
+			node, err := resolve(ctx, selector, t.refMap())
+			if err != nil {
+				return err
+			}
+			buf, err = elementScreenshot(ctx, node, format)
+			return err
+		})
+	case fullPage:
+		quality := 100 // png
+		if format == "jpeg" {
+			quality = 85
+		}
+		action = chromedp.FullScreenshot(&buf, quality)
+	case format == "jpeg":
+		action = chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			buf, err = page.CaptureScreenshot().WithFormat(page.CaptureScreenshotFormatJpeg).WithQuality(85).Do(ctx)
+			return err
+		})
+	default:
+		action = chromedp.CaptureScreenshot(&buf)
+	}
+	if err := b.run(ctx, t, action); err != nil {
+		return nil, err
+	}
+	return buf, nil
 }
 
-// Evaluate evaluates JavaScript in the page.
-func (b *Browser) Evaluate(ctx context.Context, js string) (interface{}, error) {
-	var result interface{}
-	err := chromedp.Run(b.ctx, chromedp.Evaluate(js, &result))
-	return result, err
+// PDF prints the page to PDF. Chromium only supports this when headless.
+func (b *Browser) PDF(ctx context.Context, id string) ([]byte, error) {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var buf []byte
+	err = b.run(ctx, t, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		buf, _, err = page.PrintToPDF().WithPrintBackground(true).Do(ctx)
+		return err
+	}))
+	return buf, err
 }
 
-// GetText gets the text content of an element.
-func (b *Browser) GetText(ctx context.Context, selector string) (string, error) {
+// Snapshot renders the tab's accessibility tree with element refs, which
+// stay valid for Act until the page navigates.
+func (b *Browser) Snapshot(ctx context.Context, id string, opts SnapshotOptions) (TabInfo, string, error) {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return TabInfo{}, "", err
+	}
 	var text string
-	err := chromedp.Run(b.ctx, chromedp.Text(selector, &text, chromedp.ByQuery))
-	return text, err
+	var refs RefMap
+	err = b.run(ctx, t, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		text, refs, err = axSnapshot(ctx, opts)
+		return err
+	}))
+	if err != nil {
+		return TabInfo{}, "", err
+	}
+	t.mu.Lock()
+	t.refs = refs
+	t.mu.Unlock()
+	info, err := b.info(ctx, t)
+	return info, text, err
 }
 
-// GetAttribute gets an attribute of an element.
-func (b *Browser) GetAttribute(ctx context.Context, selector, attr string) (string, error) {
-	var value string
-	err := chromedp.Run(b.ctx,
-		chromedp.AttributeValue(selector, attr, &value, nil, chromedp.ByQuery),
-	)
-	return value, err
+// Console returns recent console messages from a tab.
+func (b *Browser) Console(ctx context.Context, id string) ([]ConsoleMessage, error) {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]ConsoleMessage{}, t.console...), nil
 }
 
-// Tabs returns the open tabs.
-func (b *Browser) Tabs(ctx context.Context) ([]TabInfo, error) {
-	// Get targets from CDP
-	targets, err := chromedp.Targets(b.ctx)
+// Upload sets files on the file input matching selector, or, with no
+// selector, answers the next file chooser the page opens.
+func (b *Browser) Upload(ctx context.Context, id, selector string, paths []string) error {
+	t, err := b.tab(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			return err
+		}
+	}
+	if selector != "" {
+		return b.run(ctx, t, chromedp.ActionFunc(func(ctx context.Context) error {
+			node, err := resolve(ctx, selector, t.refMap())
+			if err != nil {
+				return err
+			}
+			return dom.SetFileInputFiles(paths).WithBackendNodeID(node).Do(ctx)
+		}))
+	}
+	t.mu.Lock()
+	t.uploadFiles = paths
+	t.mu.Unlock()
+	return b.run(ctx, t, page.SetInterceptFileChooserDialog(true))
+}
+
+// Dialog answers the open JavaScript dialog, or the next one when none is
+// open.
+func (b *Browser) Dialog(ctx context.Context, id string, accept bool, promptText string) (string, error) {
+	t, err := b.tab(ctx, id)
 	if err != nil {
+		return "", err
+	}
+	t.mu.Lock()
+	open := t.dialog
+	if open == nil {
+		t.dialogReply = &dialogReply{accept: accept, promptText: promptText}
+	}
+	t.mu.Unlock()
+	if open == nil {
+		return "", nil
+	}
+	action := page.HandleJavaScriptDialog(accept)
+	if promptText != "" {
+		action = action.WithPromptText(promptText)
+	}
+	if err := b.run(ctx, t, action); err != nil {
+		return "", err
+	}
+	return open.Message, nil
+}
+
+// Downloads returns the files downloaded so far.
+func (b *Browser) Downloads() []Download {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Download{}, b.downloads...)
+}
+
+// elementScreenshot captures node's bounding box.
+func elementScreenshot(ctx context.Context, node cdp.BackendNodeID, format string) ([]byte, error) {
+	if err := dom.ScrollIntoViewIfNeeded().WithBackendNodeID(node).Do(ctx); err != nil {
 		return nil, err
 	}
+	var clip page.Viewport
+	err := callOn(node, `function() {
+		const r = this.getBoundingClientRect();
+		return {x: r.left + window.scrollX, y: r.top + window.scrollY, width: r.width, height: r.height};
+	}`, &clip).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if clip.Width < 1 || clip.Height < 1 {
+		return nil, fmt.Errorf("element is not visible")
+	}
+	clip.Scale = 1
+	shot := page.CaptureScreenshot().WithClip(&clip).WithCaptureBeyondViewport(true)
+	if format == "jpeg" {
+		shot = shot.WithFormat(page.CaptureScreenshotFormatJpeg).WithQuality(85)
+	}
+	return shot.Do(ctx)
+}
 
-	var tabs []TabInfo
-	for _, t := range targets {
-		if t.Type == "page" {
-			tabs = append(tabs, TabInfo{
-				ID:    string(t.TargetID),
-				URL:   t.URL,
-				Title: t.Title,
-			})
+// watch records console output and answers armed dialogs and file
+// choosers for t.
+func (b *Browser) watch(t *tab) {
+	chromedp.ListenTarget(t.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *runtime.EventConsoleAPICalled:
+			args := make([]string, 0, len(ev.Args))
+			for _, a := range ev.Args {
+				args = append(args, remoteObjectString(a))
+			}
+			t.addConsole(string(ev.Type), strings.Join(args, " "))
+		case *runtime.EventExceptionThrown:
+			text := ev.ExceptionDetails.Text
+			if ev.ExceptionDetails.Exception != nil && ev.ExceptionDetails.Exception.Description != "" {
+				text = ev.ExceptionDetails.Exception.Description
+			}
+			t.addConsole("exception", text)
+		case *page.EventJavascriptDialogOpening:
+			t.mu.Lock()
+			reply := t.dialogReply
+			t.dialogReply = nil
+			if reply == nil {
+				t.dialog = ev
+			}
+			t.mu.Unlock()
+			if reply != nil {
+				// Listeners must not block; answer from a goroutine.
+				go func() {
+					action := page.HandleJavaScriptDialog(reply.accept)
+					if reply.promptText != "" {
+						action = action.WithPromptText(reply.promptText)
+					}
+					_ = chromedp.Run(t.ctx, action)
+				}()
+			}
+		case *page.EventFrameNavigated:
+			if ev.Frame.ParentID == "" {
+				t.mu.Lock()
+				t.refs = nil
+				t.mu.Unlock()
+			}
+		case *page.EventJavascriptDialogClosed:
+			t.mu.Lock()
+			t.dialog = nil
+			t.mu.Unlock()
+		case *page.EventFileChooserOpened:
+			t.mu.Lock()
+			files := t.uploadFiles
+			t.uploadFiles = nil
+			t.mu.Unlock()
+			if files == nil || ev.BackendNodeID == 0 {
+				return
+			}
+			go func() {
+				_ = chromedp.Run(t.ctx,
+					dom.SetFileInputFiles(files).WithBackendNodeID(ev.BackendNodeID),
+					page.SetInterceptFileChooserDialog(false),
+				)
+			}()
 		}
+	})
+}
+
+func (t *tab) refMap() RefMap {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.refs
+}
+
+func (t *tab) addConsole(typ, text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.console = append(t.console, ConsoleMessage{Type: typ, Text: text, Time: time.Now()})
+	if n := len(t.console); n > maxConsoleMessages {
+		t.console = append([]ConsoleMessage{}, t.console[n-maxConsoleMessages:]...)
 	}
+}
 
-	return tabs, nil
+// enableDownloads saves downloads to DownloadDir under their suggested
+// names.
+func (b *Browser) enableDownloads() {
+	dir := b.cfg.DownloadDir
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	chromedp.ListenBrowser(b.ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *cdpbrowser.EventDownloadWillBegin:
+			b.mu.Lock()
+			b.downloads = append(b.downloads, Download{URL: ev.URL, State: "inProgress", guid: ev.GUID, Path: ev.SuggestedFilename})
+			b.mu.Unlock()
+		case *cdpbrowser.EventDownloadProgress:
+			if ev.State == cdpbrowser.DownloadProgressStateInProgress {
+				return
+			}
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			for i := range b.downloads {
+				d := &b.downloads[i]
+				if d.guid != ev.GUID {
+					continue
+				}
+				d.State = ev.State.String()
+				d.Bytes = int64(ev.ReceivedBytes)
+				// allowAndName saves the file as its GUID; give it its
+				// suggested name.
+				saved := filepath.Join(dir, ev.GUID)
+				if ev.State == cdpbrowser.DownloadProgressStateCompleted {
+					d.Path = uniquePath(dir, d.Path)
+					if err := os.Rename(saved, d.Path); err != nil {
+						d.Path = saved
+					}
+				} else {
+					d.Path = ""
+					_ = os.Remove(saved)
+				}
+			}
+		}
+	})
+	_ = cdpbrowser.SetDownloadBehavior(cdpbrowser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(dir).
+		WithEventsEnabled(true).
+		Do(b.browserExecutor(b.ctx))
 }
 
-// TabInfo represents information about a browser tab.
-type TabInfo struct {
-	ID    string `json:"id"`
-	URL   string `json:"url"`
-	Title string `json:"title"`
+// uniquePath returns dir/name, numbered to avoid overwriting a file.
+func uniquePath(dir, name string) string {
+	name = filepath.Base(name)
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		name = "download"
+	}
+	path := filepath.Join(dir, name)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(dir, fmt.Sprintf("%s (%d)%s", stem, i, ext))
+	}
+}
+
+// remoteObjectString renders a console argument.
+func remoteObjectString(o *runtime.RemoteObject) string {
+	if o == nil {
+		return ""
+	}
+	if len(o.Value) > 0 {
+		var s string
+		if err := json.Unmarshal(o.Value, &s); err == nil {
+			return s
+		}
+		return string(o.Value)
+	}
+	if o.Description != "" {
+		return o.Description
+	}
+	return string(o.Type)
 }
@@ -0,0 +1,129 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Manager runs the browsers of managed profiles, starting each on first
+// use.
+type Manager struct {
+	mu       sync.Mutex
+	profiles map[string]Config
+	browsers map[string]*Browser
+}
+
+// ProfileStatus describes a managed profile.
+type ProfileStatus struct {
+	Name        string `json:"name"`
+	Running     bool   `json:"running"`
+	Tabs        int    `json:"tabs,omitempty"`
+	ControlURL  string `json:"cdpUrl,omitempty"`
+	Headless    bool   `json:"headless"`
+	UserDataDir string `json:"userDataDir,omitempty"`
+	DownloadDir string `json:"downloadDir,omitempty"`
+}
+
+// NewManager creates a manager for the given profiles.
+func NewManager(profiles map[string]Config) *Manager {
+	return &Manager{
+		profiles: profiles,
+		browsers: make(map[string]*Browser),
+	}
+}
+
+// Has reports whether profile is managed.
+func (m *Manager) Has(profile string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.profiles[profile]
+	return ok
+}
+
+// Profiles returns the managed profile names, sorted.
+func (m *Manager) Profiles() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.profiles))
+	for name := range m.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the browser for profile, starting it if needed.
+func (m *Manager) Get(ctx context.Context, profile string) (*Browser, error) {
+	m.mu.Lock()
+	cfg, ok := m.profiles[profile]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("unknown browser profile: %s", profile)
+	}
+	b := m.browsers[profile]
+	if b == nil {
+		b = New(&cfg)
+		m.browsers[profile] = b
+	}
+	m.mu.Unlock()
+
+	if err := b.Connect(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Status describes profile without starting it.
+func (m *Manager) Status(ctx context.Context, profile string) (ProfileStatus, error) {
+	m.mu.Lock()
+	cfg, ok := m.profiles[profile]
+	b := m.browsers[profile]
+	m.mu.Unlock()
+	if !ok {
+		return ProfileStatus{}, fmt.Errorf("unknown browser profile: %s", profile)
+	}
+
+	st := ProfileStatus{
+		Name:        profile,
+		ControlURL:  cfg.ControlURL,
+		Headless:    cfg.Headless,
+		UserDataDir: cfg.UserDataDir,
+		DownloadDir: cfg.DownloadDir,
+	}
+	if b != nil && b.IsConnected() {
+		st.Running = true
+		if tabs, err := b.Tabs(ctx); err == nil {
+			st.Tabs = len(tabs)
+		}
+	}
+	return st, nil
+}
+
+// Stop closes the browser for profile.
+func (m *Manager) Stop(profile string) error {
+	m.mu.Lock()
+	_, ok := m.profiles[profile]
+	b := m.browsers[profile]
+	delete(m.browsers, profile)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown browser profile: %s", profile)
+	}
+	if b != nil {
+		b.Close()
+	}
+	return nil
+}
+
+// Close stops every running browser.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	browsers := m.browsers
+	m.browsers = make(map[string]*Browser)
+	m.mu.Unlock()
+	for _, b := range browsers {
+		b.Close()
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
 	"github.com/gorilla/websocket"
+	"github.com/mailru/easyjson"
 )
 
 // Command represents a command sent to the extension.
@@ -33,6 +35,7 @@ type RelayManager struct {
 	connections  map[string]*websocket.Conn                   // profile -> connection
 	pendingCalls map[string]chan *Response                    // commandID -> response chan
 	tabs         map[string]map[string]map[string]interface{} // profile -> sessionId -> targetInfo
+	refs         map[string]RefMap                            // profile/targetId -> snapshot refs
 }
 
 // NewRelayManager creates a new relay manager.
@@ -41,6 +44,7 @@ func NewRelayManager() *RelayManager {
 		connections:  make(map[string]*websocket.Conn),
 		pendingCalls: make(map[string]chan *Response),
 		tabs:         make(map[string]map[string]map[string]interface{}),
+		refs:         make(map[string]RefMap),
 	}
 }
 
@@ -62,6 +66,11 @@ func (m *RelayManager) UnregisterConnection(profile string) {
 	defer m.mu.Unlock()
 	delete(m.connections, profile)
 	delete(m.tabs, profile)
+	for key := range m.refs {
+		if strings.HasPrefix(key, profile+"/") {
+			delete(m.refs, key)
+		}
+	}
 }
 
 // Call sends a command to the extension and waits for a response.
@@ -295,6 +304,13 @@ func (m *RelayManager) handleEvent(profile string, evt map[string]interface{}) {
 				// Update the matching target in our local store
 				for sid, info := range m.tabs[profile] {
 					if info["targetId"] == targetId {
+						// Snapshot refs die with the page they came from.
+						if info["url"] != targetInfo["url"] {
+							delete(m.refs, profile+"/"+sid)
+							delete(m.refs, profile+"/"+targetId)
+							delete(m.refs, profile+"/")
+						}
+						targetInfo["sessionId"] = sid
 						m.tabs[profile][sid] = targetInfo
 					}
 				}
@@ -310,6 +326,98 @@ func (m *RelayManager) handleEvent(profile string, evt map[string]interface{}) {
 	}
 }
 
+// Snapshot renders a tab's accessibility tree with element refs for Act.
+func (m *RelayManager) Snapshot(ctx context.Context, profile, targetID string, opts SnapshotOptions) (string, error) {
+	profile = m.connectedProfile(profile)
+	text, refs, err := axSnapshot(cdp.WithExecutor(ctx, &relayExecutor{m: m, profile: profile, targetID: targetID}), opts)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.refs[profile+"/"+targetID] = refs
+	m.mu.Unlock()
+	return text, nil
+}
+
+// Act performs req on a tab through the extension, resolving refs from
+// the tab's last snapshot.
+func (m *RelayManager) Act(ctx context.Context, profile, targetID string, req ActRequest) (interface{}, error) {
+	profile = m.connectedProfile(profile)
+	if req.Kind == "close" {
+		_, err := m.Call(ctx, profile, "tabs.close", nil, targetID)
+		return nil, err
+	}
+	timeout := 30 * time.Second
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+	if req.Kind == "wait" && req.TimeMs > 0 {
+		timeout += time.Duration(req.TimeMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	m.mu.RLock()
+	refs := m.refs[profile+"/"+targetID]
+	m.mu.RUnlock()
+	return act(cdp.WithExecutor(ctx, &relayExecutor{m: m, profile: profile, targetID: targetID}), req, refs)
+}
+
+// DropRefs forgets a tab's snapshot refs, e.g. after navigating it.
+func (m *RelayManager) DropRefs(profile, targetID string) {
+	profile = m.connectedProfile(profile)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.refs, profile+"/"+targetID)
+}
+
+// connectedProfile returns profile, or the profile Call falls back to
+// when it is not connected.
+func (m *RelayManager) connectedProfile(profile string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.connections[profile]; ok {
+		return profile
+	}
+	for p := range m.connections {
+		return p
+	}
+	return profile
+}
+
+// relayExecutor sends CDP commands for one tab through the extension.
+type relayExecutor struct {
+	m        *RelayManager
+	profile  string
+	targetID string
+}
+
+// Execute implements cdp.Executor.
+func (e *relayExecutor) Execute(ctx context.Context, method string, params easyjson.Marshaler, res easyjson.Unmarshaler) error {
+	var p map[string]interface{}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+	}
+	out, err := e.m.Call(ctx, e.profile, method, p, e.targetID)
+	if err != nil {
+		return err
+	}
+	if res == nil || out == nil {
+		return nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, res)
+}
+
 // ListProfiles returns the profiles of connected extensions.
 func (m *RelayManager) ListProfiles() []string {
 	m.mu.RLock()
@@ -0,0 +1,253 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/dom"
+)
+
+// DefaultSnapshotChars caps a snapshot when SnapshotOptions sets no limit.
+const DefaultSnapshotChars = 20000
+
+// maxNameChars caps each accessible name in a snapshot.
+const maxNameChars = 100
+
+// SnapshotOptions controls how the accessibility tree is rendered.
+type SnapshotOptions struct {
+	// MaxChars truncates the snapshot (default: DefaultSnapshotChars).
+	MaxChars int
+	// Interactive lists only elements that can be acted on, flat.
+	Interactive bool
+}
+
+// RefMap maps snapshot refs such as "e12" to DOM nodes.
+type RefMap map[string]cdp.BackendNodeID
+
+// refPattern matches a ref as the model may write it: e12, ref=e12 or
+// [ref=e12].
+var refPattern = regexp.MustCompile(`^\[?(?:ref=)?(e\d+)\]?$`)
+
+// interactiveRoles are the roles listed by an interactive snapshot.
+var interactiveRoles = map[string]bool{
+	"button": true, "link": true, "textbox": true, "searchbox": true,
+	"combobox": true, "listbox": true, "option": true, "checkbox": true,
+	"radio": true, "switch": true, "slider": true, "spinbutton": true,
+	"menuitem": true, "menuitemcheckbox": true, "menuitemradio": true,
+	"tab": true, "treeitem": true,
+}
+
+// structuralRoles carry no meaning of their own; their children are shown
+// in their place.
+var structuralRoles = map[string]bool{
+	"none": true, "generic": true, "presentation": true, "InlineTextBox": true,
+	"LineBreak": true, "LayoutTable": true, "LayoutTableRow": true, "LayoutTableCell": true,
+}
+
+// snapshotStates are the boolean states shown after a node's name.
+var snapshotStates = []accessibility.PropertyName{
+	accessibility.PropertyNameChecked, accessibility.PropertyNamePressed,
+	accessibility.PropertyNameSelected, accessibility.PropertyNameExpanded,
+	accessibility.PropertyNameDisabled, accessibility.PropertyNameRequired,
+	accessibility.PropertyNameFocused,
+}
+
+// IsRef reports whether s names a snapshot ref rather than a CSS selector.
+func IsRef(s string) bool {
+	return refPattern.MatchString(strings.TrimSpace(s))
+}
+
+// axSnapshot captures the page's accessibility tree over the CDP executor
+// in ctx.
+func axSnapshot(ctx context.Context, opts SnapshotOptions) (string, RefMap, error) {
+	nodes, err := accessibility.GetFullAXTree().Do(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("accessibility tree: %w", err)
+	}
+	text, refs := FormatAXTree(nodes, opts)
+	return text, refs, nil
+}
+
+// FormatAXTree renders an accessibility tree as an indented, role-based
+// outline, giving each element a ref such as [ref=e12] that act accepts in
+// place of a selector. Only rendered elements get refs.
+func FormatAXTree(nodes []*accessibility.Node, opts SnapshotOptions) (string, RefMap) {
+	maxChars := opts.MaxChars
+	if maxChars <= 0 {
+		maxChars = DefaultSnapshotChars
+	}
+	byID := make(map[accessibility.NodeID]*accessibility.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.NodeID] = n
+	}
+
+	f := &axFormatter{byID: byID, opts: opts, max: maxChars, refs: RefMap{}}
+	for _, n := range nodes {
+		if n.ParentID == "" || byID[n.ParentID] == nil {
+			f.walk(n, 0, "")
+		}
+	}
+	out := f.buf.String()
+	if f.skipped > 0 {
+		out += fmt.Sprintf("... (truncated: %d more elements)\n", f.skipped)
+	}
+	return out, f.refs
+}
+
+type axFormatter struct {
+	byID    map[accessibility.NodeID]*accessibility.Node
+	opts    SnapshotOptions
+	max     int
+	buf     strings.Builder
+	refs    RefMap
+	skipped int
+}
+
+// walk renders n at depth; parentName suppresses text that only repeats
+// its parent's name.
+func (f *axFormatter) walk(n *accessibility.Node, depth int, parentName string) {
+	role := axString(n.Role)
+	name := strings.Join(strings.Fields(axString(n.Name)), " ")
+	childDepth, childName := depth, parentName
+
+	if f.show(n, role, name, parentName) {
+		f.line(n, role, name, depth)
+		if !f.opts.Interactive {
+			childDepth = depth + 1
+		}
+		childName = name
+	}
+	for _, id := range n.ChildIDs {
+		if c := f.byID[id]; c != nil {
+			f.walk(c, childDepth, childName)
+		}
+	}
+}
+
+func (f *axFormatter) show(n *accessibility.Node, role, name, parentName string) bool {
+	if n.Ignored || structuralRoles[role] {
+		return false
+	}
+	if f.opts.Interactive {
+		return interactiveRoles[role]
+	}
+	if role == "StaticText" {
+		return name != "" && !strings.Contains(parentName, name)
+	}
+	return true
+}
+
+func (f *axFormatter) line(n *accessibility.Node, role, name string, depth int) {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString("- ")
+	if role == "StaticText" {
+		sb.WriteString("text")
+	} else {
+		if n.BackendDOMNodeID != 0 && role != "RootWebArea" {
+			sb.WriteString(fmt.Sprintf("[ref=e%d] ", len(f.refs)+1))
+		}
+		if role == "RootWebArea" {
+			role = "document"
+		}
+		sb.WriteString(role)
+	}
+	if name != "" {
+		sb.WriteString(" " + quoteName(name))
+	}
+	if v := axString(n.Value); v != "" && v != name {
+		sb.WriteString(" value=" + quoteName(v))
+	}
+	for _, p := range n.Properties {
+		if p.Name == accessibility.PropertyNameLevel {
+			sb.WriteString(" [level=" + axString(p.Value) + "]")
+			continue
+		}
+		for _, s := range snapshotStates {
+			if p.Name != s {
+				continue
+			}
+			switch v := axString(p.Value); v {
+			case "true":
+				sb.WriteString(" [" + string(s) + "]")
+			case "mixed":
+				sb.WriteString(" [" + string(s) + "=mixed]")
+			}
+		}
+	}
+	sb.WriteString("\n")
+
+	if f.skipped > 0 || f.buf.Len()+sb.Len() > f.max {
+		f.skipped++
+		return
+	}
+	if strings.Contains(sb.String(), "[ref=") {
+		f.refs[fmt.Sprintf("e%d", len(f.refs)+1)] = n.BackendDOMNodeID
+	}
+	f.buf.WriteString(sb.String())
+}
+
+// quoteName quotes an accessible name, shortening long ones.
+func quoteName(s string) string {
+	if utf8.RuneCountInString(s) > maxNameChars {
+		s = string([]rune(s)[:maxNameChars]) + "…"
+	}
+	return fmt.Sprintf("%q", s)
+}
+
+// axString renders an accessibility value as text.
+func axString(v *accessibility.Value) string {
+	if v == nil || len(v.Value) == 0 {
+		return ""
+	}
+	var out interface{}
+	if err := json.Unmarshal(v.Value, &out); err != nil {
+		return string(v.Value)
+	}
+	switch out := out.(type) {
+	case string:
+		return out
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(out)
+	}
+}
+
+// resolve finds the node for a snapshot ref or CSS selector over the CDP
+// executor in ctx.
+func resolve(ctx context.Context, selector string, refs RefMap) (cdp.BackendNodeID, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return 0, fmt.Errorf("selector or ref is required")
+	}
+	if m := refPattern.FindStringSubmatch(selector); m != nil {
+		id, ok := refs[m[1]]
+		if !ok {
+			return 0, fmt.Errorf("unknown ref %s; take a new snapshot", m[1])
+		}
+		return id, nil
+	}
+	doc, err := dom.GetDocument().WithDepth(0).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	nodeID, err := dom.QuerySelector(doc.NodeID, selector).Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	if nodeID == 0 {
+		return 0, fmt.Errorf("no element matches %q", selector)
+	}
+	node, err := dom.DescribeNode().WithNodeID(nodeID).Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return node.BackendNodeID, nil
+}
@@ -0,0 +1,214 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/accessibility"
+	"github.com/gorilla/websocket"
+)
+
+// testAXTree is a page with a heading, a link whose text repeats its name,
+// a text field and a hidden wrapper.
+const testAXTree = `{"nodes": [
+	{"nodeId": "1", "ignored": false, "role": {"type": "role", "value": "RootWebArea"}, "name": {"type": "computedString", "value": "Login"}, "childIds": ["2"], "backendDOMNodeId": 1},
+	{"nodeId": "2", "ignored": false, "role": {"type": "role", "value": "generic"}, "parentId": "1", "childIds": ["3", "4", "6", "7"], "backendDOMNodeId": 2},
+	{"nodeId": "3", "ignored": false, "role": {"type": "role", "value": "heading"}, "name": {"type": "computedString", "value": "Welcome back"}, "properties": [{"name": "level", "value": {"type": "integer", "value": 1}}], "parentId": "2", "backendDOMNodeId": 3},
+	{"nodeId": "4", "ignored": false, "role": {"type": "role", "value": "link"}, "name": {"type": "computedString", "value": "Forgot password?"}, "parentId": "2", "childIds": ["5"], "backendDOMNodeId": 4},
+	{"nodeId": "5", "ignored": false, "role": {"type": "role", "value": "StaticText"}, "name": {"type": "computedString", "value": "Forgot password?"}, "parentId": "4", "backendDOMNodeId": 5},
+	{"nodeId": "6", "ignored": false, "role": {"type": "role", "value": "textbox"}, "name": {"type": "computedString", "value": "Email"}, "value": {"type": "string", "value": "ada@example.com"}, "properties": [{"name": "required", "value": {"type": "boolean", "value": true}}], "parentId": "2", "backendDOMNodeId": 6},
+	{"nodeId": "7", "ignored": true, "role": {"type": "role", "value": "button"}, "name": {"type": "computedString", "value": "Hidden"}, "parentId": "2", "backendDOMNodeId": 7}
+]}`
+
+func testNodes(t *testing.T) []*accessibility.Node {
+	t.Helper()
+	var tree struct {
+		Nodes []*accessibility.Node `json:"nodes"`
+	}
+	if err := json.Unmarshal([]byte(testAXTree), &tree); err != nil {
+		t.Fatal(err)
+	}
+	return tree.Nodes
+}
+
+func TestFormatAXTree(t *testing.T) {
+	text, refs := FormatAXTree(testNodes(t), SnapshotOptions{})
+	want := `- document "Login"
+  - [ref=e1] heading "Welcome back" [level=1]
+  - [ref=e2] link "Forgot password?"
+  - [ref=e3] textbox "Email" value="ada@example.com" [required]
+`
+	if text != want {
+		t.Fatalf("snapshot =\n%s\nwant\n%s", text, want)
+	}
+	if len(refs) != 3 || refs["e1"] != 3 || refs["e2"] != 4 || refs["e3"] != 6 {
+		t.Fatalf("refs = %v", refs)
+	}
+
+	text, refs = FormatAXTree(testNodes(t), SnapshotOptions{Interactive: true})
+	if text != "- [ref=e1] link \"Forgot password?\"\n- [ref=e2] textbox \"Email\" value=\"ada@example.com\" [required]\n" {
+		t.Fatalf("interactive snapshot =\n%s", text)
+	}
+	if refs["e1"] != 4 {
+		t.Fatalf("interactive refs = %v", refs)
+	}
+
+	text, refs = FormatAXTree(testNodes(t), SnapshotOptions{MaxChars: 80})
+	if !strings.HasSuffix(text, "... (truncated: 2 more elements)\n") || len(refs) != 1 {
+		t.Fatalf("truncated snapshot =\n%s\nrefs = %v", text, refs)
+	}
+
+	for _, s := range []string{"e12", "ref=e12", "[ref=e12]"} {
+		if !IsRef(s) {
+			t.Errorf("IsRef(%q) = false", s)
+		}
+	}
+	if IsRef("#e12") || IsRef("button.e1") {
+		t.Error("IsRef accepted a CSS selector")
+	}
+}
+
+// fakeExtension answers CDP commands forwarded by a RelayManager.
+type fakeExtension struct {
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	commands []map[string]interface{}
+}
+
+func (f *fakeExtension) serve(t *testing.T) {
+	for {
+		_, data, err := f.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg struct {
+			ID     int64 `json:"id"`
+			Params struct {
+				Method string                 `json:"method"`
+				Params map[string]interface{} `json:"params"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Error(err)
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, map[string]interface{}{"method": msg.Params.Method, "params": msg.Params.Params})
+		f.mu.Unlock()
+
+		result := `{}`
+		switch msg.Params.Method {
+		case "Accessibility.getFullAXTree":
+			result = testAXTree
+		case "DOM.getBoxModel":
+			result = `{"model": {"content": [10, 20, 30, 20, 30, 40, 10, 40], "padding": [], "border": [], "margin": [], "width": 20, "height": 20}}`
+		}
+		reply := fmt.Sprintf(`{"id": %d, "result": %s}`, msg.ID, result)
+		f.mu.Lock()
+		err = f.conn.WriteMessage(websocket.TextMessage, []byte(reply))
+		f.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeExtension) methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for _, c := range f.commands {
+		out = append(out, c["method"].(string))
+	}
+	return out
+}
+
+func TestRelaySnapshotRefs(t *testing.T) {
+	relay := NewRelayManager()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		relay.RegisterConnection("chrome", ws)
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = relay.HandleResponse("chrome", data)
+		}
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	ext := &fakeExtension{conn: conn}
+	go ext.serve(t)
+	// Announce the tab so navigation events can be matched to it.
+	attach := `{"method": "forwardCDPEvent", "params": {"method": "Target.attachedToTarget", "params": {"sessionId": "S1", "targetInfo": {"targetId": "T1", "url": "https://example.com/login"}}}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(attach)); err != nil {
+		t.Fatal(err)
+	}
+	for len(relay.ListProfiles()) == 0 {
+		// RegisterConnection runs on the server goroutine.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx := context.Background()
+	text, err := relay.Snapshot(ctx, "chrome", "T1", SnapshotOptions{})
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if !strings.Contains(text, `[ref=e2] link "Forgot password?"`) {
+		t.Fatalf("snapshot =\n%s", text)
+	}
+
+	if _, err := relay.Act(ctx, "chrome", "T1", ActRequest{Kind: "click", Selector: "e2"}); err != nil {
+		t.Fatalf("click: %v", err)
+	}
+	ext.mu.Lock()
+	var clicks []map[string]interface{}
+	for _, c := range ext.commands {
+		if c["method"] == "DOM.scrollIntoViewIfNeeded" && c["params"].(map[string]interface{})["backendNodeId"] != float64(4) {
+			t.Errorf("scrolled the wrong node: %v", c["params"])
+		}
+		if c["method"] == "Input.dispatchMouseEvent" {
+			clicks = append(clicks, c["params"].(map[string]interface{}))
+		}
+	}
+	ext.mu.Unlock()
+	if len(clicks) != 2 || clicks[0]["type"] != "mousePressed" || clicks[0]["x"] != float64(20) || clicks[0]["y"] != float64(30) {
+		t.Fatalf("mouse events = %v (commands %v)", clicks, ext.methods())
+	}
+
+	if _, err := relay.Act(ctx, "chrome", "T1", ActRequest{Kind: "click", Selector: "e9"}); err == nil || !strings.Contains(err.Error(), "unknown ref e9") {
+		t.Fatalf("unknown ref error = %v", err)
+	}
+
+	// Navigating the tab drops its refs.
+	nav := `{"method": "forwardCDPEvent", "params": {"method": "Target.targetInfoChanged", "params": {"targetInfo": {"targetId": "T1", "url": "https://example.com/home"}}}}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(nav)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		if _, err = relay.Act(ctx, "chrome", "T1", ActRequest{Kind: "hover", Selector: "e2"}); err != nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err == nil || !strings.Contains(err.Error(), "take a new snapshot") {
+		t.Fatalf("ref survived navigation: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+// LiteClaw canvas client: renders A2UI frames and answers the agent's
+// eval/navigate/present/hide requests pushed over the canvas WebSocket.
+(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  var wsURL = script.src.replace(/__canvas\.js(\?.*)?$/, "__ws").replace(/^http/, "ws");
+  var surfaces = {};
+  var frame = null;
+
+  function root() {
+    var el = document.getElementById("a2ui-root");
+    if (!el) {
+      el = document.createElement("div");
+      el.id = "a2ui-root";
+      document.body.appendChild(el);
+    }
+    return el;
+  }
+
+  function value(v, data) {
+    if (v == null) return "";
+    if (typeof v !== "object") return String(v);
+    if ("literalString" in v) return v.literalString;
+    if ("literalNumber" in v) return String(v.literalNumber);
+    if ("literalBoolean" in v) return String(v.literalBoolean);
+    if ("path" in v) return data[v.path.replace(/^\//, "")] || "";
+    return "";
+  }
+
+  function children(props) {
+    var c = props.children || {};
+    if (Array.isArray(c)) return c;
+    return c.explicitList || [];
+  }
+
+  function render(surface, id) {
+    var entry = surface.components[id];
+    if (!entry) return document.createTextNode("");
+    var type = Object.keys(entry)[0];
+    var props = entry[type] || {};
+    var el;
+    switch (type) {
+      case "Text":
+        var hint = props.usageHint || "body";
+        el = document.createElement(/^h[1-5]$/.test(hint) ? hint : "p");
+        el.textContent = value(props.text, surface.data);
+        break;
+      case "Column":
+      case "Row":
+      case "List":
+        el = document.createElement("div");
+        el.style.display = "flex";
+        el.style.flexDirection = type === "Row" ? "row" : "column";
+        el.style.gap = "8px";
+        children(props).forEach(function (c) { el.appendChild(render(surface, c)); });
+        break;
+      case "Card":
+        el = document.createElement("div");
+        el.style.cssText = "border:1px solid #e5e5ea;border-radius:12px;padding:16px;background:#fff";
+        (props.child ? [props.child] : children(props)).forEach(function (c) { el.appendChild(render(surface, c)); });
+        break;
+      case "Image":
+        el = document.createElement("img");
+        el.src = value(props.url, surface.data);
+        el.style.maxWidth = "100%";
+        break;
+      case "Button":
+        el = document.createElement("button");
+        if (props.child) el.appendChild(render(surface, props.child));
+        else el.textContent = value(props.label, surface.data);
+        break;
+      case "Divider":
+        el = document.createElement("hr");
+        break;
+      default:
+        el = document.createElement("pre");
+        el.textContent = JSON.stringify(entry, null, 2);
+    }
+    return el;
+  }
+
+  function draw() {
+    var el = root();
+    el.innerHTML = "";
+    Object.keys(surfaces).forEach(function (id) {
+      var s = surfaces[id];
+      if (s.root) el.appendChild(render(s, s.root));
+    });
+  }
+
+  function surface(id) {
+    id = id || "main";
+    if (!surfaces[id]) surfaces[id] = { components: {}, data: {}, root: null };
+    return surfaces[id];
+  }
+
+  function apply(msg) {
+    if (msg.surfaceUpdate) {
+      var s = surface(msg.surfaceUpdate.surfaceId);
+      (msg.surfaceUpdate.components || []).forEach(function (c) { s.components[c.id] = c.component; });
+    } else if (msg.dataModelUpdate) {
+      var d = surface(msg.dataModelUpdate.surfaceId);
+      (msg.dataModelUpdate.contents || []).forEach(function (c) {
+        d.data[c.key] = c.valueString != null ? c.valueString : c.valueNumber != null ? c.valueNumber : c.valueBoolean;
+      });
+    } else if (msg.beginRendering) {
+      surface(msg.beginRendering.surfaceId).root = msg.beginRendering.root;
+    } else if (msg.deleteSurface) {
+      delete surfaces[msg.deleteSurface.surfaceId || "main"];
+    }
+  }
+
+  function show(url) {
+    if (!url) return;
+    if (!frame) {
+      frame = document.createElement("iframe");
+      frame.style.cssText = "position:fixed;inset:0;width:100%;height:100%;border:0;background:#fff;z-index:2147483647";
+      document.body.appendChild(frame);
+    }
+    frame.src = url;
+  }
+
+  function visible(on) {
+    document.documentElement.style.visibility = on ? "" : "hidden";
+  }
+
+  function evaluate(ws, msg) {
+    var reply = function (result, error) {
+      ws.send(JSON.stringify({ type: "evalResult", id: msg.id, result: result, error: error }));
+    };
+    var format = function (v) {
+      if (v === undefined) return "undefined";
+      if (typeof v === "string") return v;
+      try { return JSON.stringify(v); } catch (e) { return String(v); }
+    };
+    try {
+      Promise.resolve((0, eval)(msg.script)).then(
+        function (v) { reply(format(v), ""); },
+        function (e) { reply("", String(e)); }
+      );
+    } catch (e) {
+      reply("", String(e));
+    }
+  }
+
+  function connect() {
+    var ws = new WebSocket(wsURL);
+    ws.onmessage = function (ev) {
+      var msg = JSON.parse(ev.data);
+      switch (msg.type) {
+        case "state":
+          surfaces = {};
+          (msg.frames || []).forEach(apply);
+          if (msg.frames && msg.frames.length) draw();
+          visible(!msg.hidden);
+          show(msg.url);
+          break;
+        case "a2ui":
+          msg.frames.forEach(apply);
+          draw();
+          visible(true);
+          break;
+        case "reset":
+          surfaces = {};
+          root().innerHTML = "";
+          break;
+        case "present":
+          visible(true);
+          show(msg.url);
+          break;
+        case "navigate":
+          show(msg.url);
+          break;
+        case "hide":
+          visible(false);
+          break;
+        case "eval":
+          evaluate(ws, msg);
+          break;
+      }
+    };
+    ws.onclose = function () { setTimeout(connect, 2000); };
+  }
+
+  connect();
+})();
@@ -0,0 +1,131 @@
+package canvas
+
+import (
+	"bytes"
+	"embed"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MountPath is where the gateway serves canvases.
+const MountPath = "/canvas"
+
+const (
+	clientScript = "__canvas.js"
+	wsEndpoint   = "__ws"
+)
+
+//go:embed assets/index.html assets/canvas.js
+var assets embed.FS
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// ServeHTTP serves /<session>/... with the MountPath prefix already
+// stripped: files from the session's directory (or the built-in viewer),
+// the client script and the viewer WebSocket.
+func (h *Host) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/")
+	if rest == "" {
+		http.Redirect(w, r, MountPath+"/"+DefaultSession+"/"+query(r), http.StatusFound)
+		return
+	}
+	escaped, sub, found := strings.Cut(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/")
+	key, err := url.PathUnescape(escaped)
+	if err != nil || key == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !found {
+		http.Redirect(w, r, MountPath+"/"+escaped+"/"+query(r), http.StatusFound)
+		return
+	}
+	sub, _ = url.PathUnescape(sub)
+
+	switch sub {
+	case wsEndpoint:
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		h.serveViewer(key, conn)
+	case clientScript:
+		data, _ := assets.ReadFile("assets/canvas.js")
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = w.Write(data)
+	default:
+		h.serveFile(w, r, key, escaped, sub)
+	}
+}
+
+// serveFile serves sub from the session directory, injecting the client
+// script into HTML pages.
+func (h *Host) serveFile(w http.ResponseWriter, r *http.Request, key, escaped, sub string) {
+	dir := filepath.Join(h.root, SessionDir(key))
+	name := path.Clean("/" + sub)
+	if strings.HasSuffix(sub, "/") || sub == "" {
+		name = path.Join(name, "index.html")
+	}
+
+	f, err := http.Dir(dir).Open(name)
+	if err == nil {
+		if st, statErr := f.Stat(); statErr == nil && st.IsDir() {
+			_ = f.Close()
+			http.Redirect(w, r, MountPath+"/"+escaped+name+"/"+query(r), http.StatusFound)
+			return
+		}
+	}
+	var page []byte
+	modTime := time.Now()
+	switch {
+	case err == nil:
+		defer func() { _ = f.Close() }()
+		if !isHTML(name) {
+			st, _ := f.Stat()
+			http.ServeContent(w, r, name, st.ModTime(), f)
+			return
+		}
+		page, err = io.ReadAll(f)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if st, statErr := f.Stat(); statErr == nil {
+			modTime = st.ModTime()
+		}
+	case os.IsNotExist(err) && name == "/index.html":
+		page, _ = assets.ReadFile("assets/index.html")
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	page = injectClient(page, MountPath+"/"+escaped+"/"+clientScript)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(page))
+}
+
+func isHTML(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+func query(r *http.Request) string {
+	if r.URL.RawQuery == "" {
+		return ""
+	}
+	return "?" + r.URL.RawQuery
+}
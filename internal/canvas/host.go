@@ -0,0 +1,361 @@
+// Package canvas hosts per-session canvas pages for the agent. Pages are
+// served from the workspace canvas/ directory; A2UI frames, script
+// evaluation and navigation reach every open viewer over a WebSocket.
+package canvas
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultSession is used when a canvas call carries no session.
+const DefaultSession = "main"
+
+// evalTimeout bounds how long Eval waits for a viewer's answer.
+const evalTimeout = 10 * time.Second
+
+// Host serves canvases and relays the agent's commands to their viewers.
+type Host struct {
+	root string
+
+	// BaseURL is the canvas root as reachable from this machine, e.g.
+	// http://127.0.0.1:18789/canvas; set by the gateway when it serves the
+	// host.
+	BaseURL string
+	// AccessKey is appended to snapshot URLs when the gateway requires
+	// auth; see AccessKey.
+	AccessKey string
+	// Capture renders a URL to an image in a headless browser; nil when
+	// no browser backend is available.
+	Capture func(ctx context.Context, url, format string) ([]byte, error)
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	evals    map[string]chan evalResult
+}
+
+type session struct {
+	viewers map[*viewer]bool
+	// frames are the A2UI JSONL lines pushed since the last reset; new
+	// viewers replay them.
+	frames []json.RawMessage
+	// url is where present/navigate pointed the canvas, if not its page.
+	url    string
+	hidden bool
+}
+
+type viewer struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (v *viewer) send(msg interface{}) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	_ = v.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return v.conn.WriteJSON(msg)
+}
+
+type evalResult struct {
+	Result string
+	Error  string
+}
+
+// NewHost creates a host serving pages from root (the workspace canvas/
+// directory).
+func NewHost(root string) *Host {
+	return &Host{
+		root:     root,
+		sessions: make(map[string]*session),
+		evals:    make(map[string]chan evalResult),
+	}
+}
+
+// Root returns the directory canvases are served from.
+func (h *Host) Root() string {
+	return h.root
+}
+
+// unsafeDirChars are replaced in session directory names.
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SessionDir returns the directory a session's canvas is served from.
+func SessionDir(session string) string {
+	name := strings.Trim(unsafeDirChars.ReplaceAllString(session, "_"), ".")
+	if name == "" {
+		name = DefaultSession
+	}
+	return name
+}
+
+// URL returns the viewer URL for session under base.
+func URL(base, session string) string {
+	if session == "" {
+		session = DefaultSession
+	}
+	return strings.TrimRight(base, "/") + "/" + url.PathEscape(session) + "/"
+}
+
+// AccessKeyParam is the query parameter that opens a canvas page with its
+// access key.
+const AccessKeyParam = "canvasKey"
+
+// ContentSecurityPolicy is sent with every canvas response. Pages are
+// written by the model, so they run sandboxed in an opaque origin, away
+// from the gateway's cookies, storage and API.
+const ContentSecurityPolicy = "sandbox allow-scripts allow-forms allow-popups allow-modals allow-downloads"
+
+// AccessKey derives the credential for canvas pages from the gateway
+// token. It only opens canvases, so unlike the token it may appear in a
+// canvas URL.
+func AccessKey(token string) string {
+	if token == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte("liteclaw canvas"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sessionLocked returns the state for key; h.mu must be held.
+func (h *Host) sessionLocked(key string) *session {
+	if key == "" {
+		key = DefaultSession
+	}
+	s := h.sessions[key]
+	if s == nil {
+		s = &session{viewers: make(map[*viewer]bool)}
+		h.sessions[key] = s
+	}
+	return s
+}
+
+// broadcast sends msg to the session's viewers and returns how many got it.
+func (h *Host) broadcast(key string, msg interface{}) int {
+	h.mu.Lock()
+	s := h.sessionLocked(key)
+	viewers := make([]*viewer, 0, len(s.viewers))
+	for v := range s.viewers {
+		viewers = append(viewers, v)
+	}
+	h.mu.Unlock()
+
+	sent := 0
+	for _, v := range viewers {
+		if v.send(msg) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Viewers returns how many viewers have session open.
+func (h *Host) Viewers(key string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.sessionLocked(key).viewers)
+}
+
+// Present shows the canvas, optionally pointing it at target, and returns
+// the viewer URL.
+func (h *Host) Present(ctx context.Context, key, target string) (string, error) {
+	h.mu.Lock()
+	s := h.sessionLocked(key)
+	s.hidden = false
+	if target != "" {
+		s.url = target
+	}
+	h.mu.Unlock()
+	h.broadcast(key, map[string]interface{}{"type": "present", "url": target})
+	return URL(h.BaseURL, key), nil
+}
+
+// Hide blanks the canvas in open viewers.
+func (h *Host) Hide(ctx context.Context, key string) error {
+	h.mu.Lock()
+	h.sessionLocked(key).hidden = true
+	h.mu.Unlock()
+	h.broadcast(key, map[string]interface{}{"type": "hide"})
+	return nil
+}
+
+// Navigate points open viewers at url and returns the viewer URL.
+func (h *Host) Navigate(ctx context.Context, key, target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	h.mu.Lock()
+	h.sessionLocked(key).url = target
+	h.mu.Unlock()
+	h.broadcast(key, map[string]interface{}{"type": "navigate", "url": target})
+	return URL(h.BaseURL, key), nil
+}
+
+// PushA2UI validates JSONL, keeps its frames for later viewers and sends
+// them to open ones. It returns the number of frames pushed.
+func (h *Host) PushA2UI(ctx context.Context, key, jsonl string) (int, error) {
+	var frames []json.RawMessage
+	for i, line := range strings.Split(jsonl, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if !json.Valid([]byte(line)) || !strings.HasPrefix(line, "{") {
+			return 0, fmt.Errorf("a2ui line %d is not a JSON object", i+1)
+		}
+		frames = append(frames, json.RawMessage(line))
+	}
+	if len(frames) == 0 {
+		return 0, fmt.Errorf("jsonl is empty")
+	}
+
+	h.mu.Lock()
+	s := h.sessionLocked(key)
+	s.frames = append(s.frames, frames...)
+	s.hidden = false
+	h.mu.Unlock()
+	h.broadcast(key, map[string]interface{}{"type": "a2ui", "frames": frames})
+	return len(frames), nil
+}
+
+// ResetA2UI clears the session's A2UI surfaces.
+func (h *Host) ResetA2UI(ctx context.Context, key string) error {
+	h.mu.Lock()
+	h.sessionLocked(key).frames = nil
+	h.mu.Unlock()
+	h.broadcast(key, map[string]interface{}{"type": "reset"})
+	return nil
+}
+
+// Eval runs script in the first open viewer to answer and returns its
+// result as text.
+func (h *Host) Eval(ctx context.Context, key, script string) (string, error) {
+	if script == "" {
+		return "", fmt.Errorf("javaScript is required")
+	}
+	id := newID()
+	ch := make(chan evalResult, 1)
+	h.mu.Lock()
+	h.evals[id] = ch
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.evals, id)
+		h.mu.Unlock()
+	}()
+
+	if h.broadcast(key, map[string]interface{}{"type": "eval", "id": id, "script": script}) == 0 {
+		return "", fmt.Errorf("no viewer has the canvas open; open %s", URL(h.BaseURL, key))
+	}
+	timer := time.NewTimer(evalTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		if res.Error != "" {
+			return "", fmt.Errorf("canvas eval: %s", res.Error)
+		}
+		return res.Result, nil
+	case <-timer.C:
+		return "", fmt.Errorf("canvas eval timed out after %s", evalTimeout)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// Snapshot renders the session's canvas in the headless browser.
+func (h *Host) Snapshot(ctx context.Context, key, format string) ([]byte, error) {
+	if h.Capture == nil {
+		return nil, fmt.Errorf("canvas snapshots need the headless browser (tools.browser)")
+	}
+	if h.BaseURL == "" {
+		return nil, fmt.Errorf("canvas host is not being served")
+	}
+	target := URL(h.BaseURL, key)
+	if h.AccessKey != "" {
+		target += "?" + AccessKeyParam + "=" + url.QueryEscape(h.AccessKey)
+	}
+	return h.Capture(ctx, target, format)
+}
+
+// state is what a new viewer needs to catch up.
+func (h *Host) state(key string) map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.sessionLocked(key)
+	return map[string]interface{}{
+		"type":    "state",
+		"session": key,
+		"frames":  append([]json.RawMessage(nil), s.frames...),
+		"url":     s.url,
+		"hidden":  s.hidden,
+	}
+}
+
+// serveViewer registers conn as a viewer of key until it disconnects.
+func (h *Host) serveViewer(key string, conn *websocket.Conn) {
+	v := &viewer{conn: conn}
+	if err := v.send(h.state(key)); err != nil {
+		return
+	}
+	h.mu.Lock()
+	h.sessionLocked(key).viewers[v] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessionLocked(key).viewers, v)
+		h.mu.Unlock()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg struct {
+			Type   string `json:"type"`
+			ID     string `json:"id"`
+			Result string `json:"result"`
+			Error  string `json:"error"`
+		}
+		if json.Unmarshal(data, &msg) != nil || msg.Type != "evalResult" {
+			continue
+		}
+		h.mu.Lock()
+		ch := h.evals[msg.ID]
+		delete(h.evals, msg.ID)
+		h.mu.Unlock()
+		if ch != nil {
+			ch <- evalResult{Result: msg.Result, Error: msg.Error}
+		}
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// injectClient adds the canvas client script to an HTML page.
+func injectClient(page []byte, scriptURL string) []byte {
+	tag := []byte(`<script src="` + scriptURL + `"></script>`)
+	if i := bytes.LastIndex(bytes.ToLower(page), []byte("</body>")); i >= 0 {
+		out := append([]byte{}, page[:i]...)
+		out = append(out, tag...)
+		return append(out, page[i:]...)
+	}
+	return append(append([]byte{}, page...), tag...)
+}
@@ -0,0 +1,114 @@
+package canvas
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHostPushReplayAndEval(t *testing.T) {
+	root := t.TempDir()
+	h := NewHost(root)
+	srv := httptest.NewServer(http.StripPrefix(MountPath, h))
+	defer srv.Close()
+	h.BaseURL = srv.URL + MountPath
+	ctx := context.Background()
+
+	if _, err := h.PushA2UI(ctx, "main", "{\"beginRendering\":{\"root\":\"a\"}}\nnot json"); err == nil {
+		t.Fatal("invalid jsonl was accepted")
+	}
+	if n, err := h.PushA2UI(ctx, "main", `{"surfaceUpdate":{"surfaceId":"main","components":[]}}`+"\n\n"+`{"beginRendering":{"surfaceId":"main","root":"a"}}`); err != nil || n != 2 {
+		t.Fatalf("PushA2UI = %d, %v", n, err)
+	}
+	if _, err := h.Eval(ctx, "main", "1+1"); err == nil || !strings.Contains(err.Error(), "no viewer") {
+		t.Fatalf("eval without viewer = %v", err)
+	}
+
+	// A new viewer catches up on frames pushed before it connected.
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+MountPath+"/main/__ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	var msg struct {
+		Type   string            `json:"type"`
+		ID     string            `json:"id"`
+		Script string            `json:"script"`
+		Frames []json.RawMessage `json:"frames"`
+	}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "state" || len(msg.Frames) != 2 {
+		t.Fatalf("state = %+v, %v", msg, err)
+	}
+
+	if _, err := h.PushA2UI(ctx, "main", `{"deleteSurface":{"surfaceId":"main"}}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "a2ui" || len(msg.Frames) != 1 {
+		t.Fatalf("a2ui = %+v, %v", msg, err)
+	}
+
+	go func() {
+		if err := conn.ReadJSON(&msg); err == nil && msg.Type == "eval" {
+			_ = conn.WriteJSON(map[string]string{"type": "evalResult", "id": msg.ID, "result": "ran " + msg.Script})
+		}
+	}()
+	if out, err := h.Eval(ctx, "main", "document.title"); err != nil || out != "ran document.title" {
+		t.Fatalf("Eval = %q, %v", out, err)
+	}
+
+	if err := h.ResetA2UI(ctx, "main"); err != nil {
+		t.Fatal(err)
+	}
+	if state := h.state("main"); len(state["frames"].([]json.RawMessage)) != 0 {
+		t.Fatalf("frames after reset = %v", state["frames"])
+	}
+}
+
+func TestHostServesSessionPages(t *testing.T) {
+	root := t.TempDir()
+	h := NewHost(root)
+	srv := httptest.NewServer(http.StripPrefix(MountPath, h))
+	defer srv.Close()
+
+	dir := filepath.Join(root, SessionDir("telegram:42"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "chart.html"), []byte("<html><body><h1>Chart</h1></body></html>"), 0644)
+	_ = os.WriteFile(filepath.Join(dir, "data.json"), []byte(`{"x":1}`), 0644)
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	base := URL(MountPath, "telegram:42")
+	if code, body := get(base + "chart.html"); code != 200 || !strings.Contains(body, `<script src="`+base+`__canvas.js"></script></body>`) {
+		t.Fatalf("chart.html = %d %q", code, body)
+	}
+	if code, body := get(base + "data.json"); code != 200 || body != `{"x":1}` {
+		t.Fatalf("data.json = %d %q", code, body)
+	}
+	if code, body := get(base); code != 200 || !strings.Contains(body, "a2ui-root") || !strings.Contains(body, "__canvas.js") {
+		t.Fatalf("built-in viewer = %d %q", code, body)
+	}
+	if code, _ := get(base + "..%2f..%2fetc%2fpasswd"); code != http.StatusNotFound {
+		t.Fatalf("traversal = %d", code)
+	}
+	if code, body := get(base + "__canvas.js"); code != 200 || !strings.Contains(body, "WebSocket") {
+		t.Fatalf("client script = %d", code)
+	}
+}
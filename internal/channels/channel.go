@@ -44,11 +44,14 @@ type Message struct {
 
 // Attachment represents a message attachment.
 type Attachment struct {
-	Type     string `json:"type"` // "image", "file", "audio", "video"
+	Type     string `json:"type"` // "image", "file", "audio", "voice" (voice note), "video"
 	URL      string `json:"url,omitempty"`
 	Path     string `json:"path,omitempty"`
 	MimeType string `json:"mimeType,omitempty"`
 	Name     string `json:"name,omitempty"`
+	// Fetch, when set on an inbound attachment, downloads it and returns
+	// the local path. The gateway calls it once the sender is authorized.
+	Fetch func(ctx context.Context) (string, error) `json:"-"`
 }
 
 // IncomingMessage represents an incoming message from a channel.
@@ -0,0 +1,98 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/liteclaw/liteclaw/internal/config"
+)
+
+// DefaultMaxInboundMediaBytes caps attachments downloaded by adapters.
+const DefaultMaxInboundMediaBytes = 25 << 20
+
+// InboundMediaDir returns where adapters store attachments sent by peer on
+// a channel. Empty arguments name the enclosing directories.
+func InboundMediaDir(channel, peer string) string {
+	return filepath.Join(config.StateDir(), "media", "inbound", mediaSegment(channel), mediaSegment(peer))
+}
+
+// SessionMediaDir returns the inbound media dir of the gateway session
+// "<channel>:<peer>", or "" for other session keys.
+func SessionMediaDir(sessionKey string) string {
+	channel, peer, ok := strings.Cut(sessionKey, ":")
+	if !ok || channel == "" || peer == "" {
+		return ""
+	}
+	return InboundMediaDir(channel, peer)
+}
+
+// mediaSegment keeps an ID to a single path element.
+func mediaSegment(s string) string {
+	if s == "." || s == ".." {
+		return "_"
+	}
+	return strings.NewReplacer("/", "_", "\\", "_").Replace(s)
+}
+
+// DownloadMedia fetches url into InboundMediaDir(channel, peer) and returns
+// the local path. name is a hint for the file extension; downloads larger
+// than maxBytes (DefaultMaxInboundMediaBytes when 0) are rejected.
+func DownloadMedia(ctx context.Context, client *http.Client, channel, peer, url, name string, maxBytes int64) (string, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxInboundMediaBytes
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("media download failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("media download failed: HTTP %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxBytes {
+		return "", fmt.Errorf("media is %d bytes; the limit is %d", resp.ContentLength, maxBytes)
+	}
+
+	dir := InboundMediaDir(channel, peer)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	if len(ext) > 8 {
+		ext = ""
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", time.Now().Format("20060102-150405"), uuid.New().String()[:8], ext))
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	n, err := io.Copy(f, io.LimitReader(resp.Body, maxBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxBytes {
+		err = fmt.Errorf("media exceeds %d bytes", maxBytes)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
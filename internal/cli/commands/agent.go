@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
 	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -12,6 +13,8 @@ import (
 func NewAgentCommand() *cobra.Command {
 	var message string
 	var sessionID string
+	var model string
+	var authProfile string
 	var local bool
 
 	cmd := &cobra.Command{
@@ -22,7 +25,13 @@ func NewAgentCommand() *cobra.Command {
   liteclaw agent --message "Tell me a joke"
 
   # Run with a specific session ID
-  liteclaw agent --message "Continue conversation" --session "session-id" --local`,
+  liteclaw agent --message "Continue conversation" --session "session-id" --local
+
+  # Use a different model for this turn
+  liteclaw agent --message "Summarize this" --model fast
+
+  # Use a specific auth profile instead of rotating
+  liteclaw agent --message "Hello" --auth-profile openai:work`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if message == "" && len(args) > 0 {
 				message = args[0]
@@ -46,6 +55,7 @@ func NewAgentCommand() *cobra.Command {
 
 			// Create Service
 			svc := agent.NewService(cfg, sender)
+			defer svc.Close()
 
 			ctx := context.Background()
 
@@ -54,7 +64,7 @@ func NewAgentCommand() *cobra.Command {
 				fmt.Printf("Agent (%s) processing...\n", sessionID)
 			}
 
-			err = svc.ProcessChat(ctx, sessionID, message, func(delta string) {
+			err = svc.ProcessChatWithOptions(ctx, sessionID, message, agent.RunOptions{Model: model, AuthProfile: authProfile}, func(delta string) {
 				fmt.Print(delta)
 			})
 			fmt.Println() // Newline at end
@@ -65,6 +75,8 @@ func NewAgentCommand() *cobra.Command {
 
 	cmd.Flags().StringVarP(&message, "message", "m", "", "Message to send")
 	cmd.Flags().StringVar(&sessionID, "session", "main", "Session ID to use")
+	cmd.Flags().StringVar(&model, "model", "", "Model override for this turn (provider/model or alias)")
+	cmd.Flags().StringVar(&authProfile, "auth-profile", "", "Auth profile to use for this turn (disables key rotation)")
 	cmd.Flags().BoolVar(&local, "local", false, "Run locally (embedded) instead of via Gateway")
 
 	return cmd
@@ -77,3 +89,13 @@ func (s *cliSender) SendMessage(ctx context.Context, channel, to, text string) e
 	fmt.Printf("\n[Outbound -> %s:%s] %s\n", channel, to, text)
 	return nil
 }
+
+func (s *cliSender) SendMedia(ctx context.Context, channel, to, text string, media []tools.MediaAttachment) error {
+	for _, m := range media {
+		fmt.Printf("\n[Outbound -> %s:%s] %s: %s\n", channel, to, m.Type, m.Path)
+	}
+	if text != "" {
+		return s.SendMessage(ctx, channel, to, text)
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -427,7 +426,7 @@ func updateJob(id string, patch map[string]interface{}) error {
 
 func updateJobWithPatch(out io.Writer, id string, patch map[string]interface{}) error {
 	path := fmt.Sprintf("/cron/jobs/%s/update", id)
-	if err := callCronAPI("POST", path, patch, nil); err == nil {
+	if err := callGatewayAPI("POST", path, patch, nil); err == nil {
 		return nil
 	}
 
@@ -559,7 +558,7 @@ func loadCronJobsFromFile(path string) ([]cron.Job, error) {
 }
 
 func addCronJob(out io.Writer, job *cron.Job) error {
-	if err := callCronAPI("POST", "/cron/jobs", job, nil); err == nil {
+	if err := callGatewayAPI("POST", "/cron/jobs", job, nil); err == nil {
 		return nil
 	} else {
 		_, _ = fmt.Fprintf(out, "Warning: failed to contact gateway, falling back to local file: %v\n", err)
@@ -627,7 +626,7 @@ func writeCronStoreFile(path string, store *cron.StoreFile) error {
 
 func removeCronJob(out io.Writer, id string) error {
 	path := fmt.Sprintf("/cron/jobs/%s", id)
-	if err := callCronAPI("DELETE", path, nil, nil); err == nil {
+	if err := callGatewayAPI("DELETE", path, nil, nil); err == nil {
 		return nil
 	} else {
 		_, _ = fmt.Fprintf(out, "Warning: failed to contact gateway, falling back to local file: %v\n", err)
@@ -741,7 +740,7 @@ func applyCronPatchToJob(job *cron.Job, patch map[string]interface{}) error {
 
 func runCronJob(out io.Writer, id string) error {
 	path := fmt.Sprintf("/cron/jobs/%s/run", id)
-	if err := callCronAPI("POST", path, nil, nil); err == nil {
+	if err := callGatewayAPI("POST", path, nil, nil); err == nil {
 		return nil
 	} else {
 		_, _ = fmt.Fprintf(out, "Warning: failed to contact gateway, trying local execution: %v\n", err)
@@ -779,6 +778,7 @@ func runCronJobLocal(out io.Writer, path string, cfg *config.Config, id string)
 
 	start := time.Now()
 	svc := agent.NewService(cfg, nil)
+	defer svc.Close()
 	var resp strings.Builder
 	err = svc.ProcessChat(context.Background(), "cron:"+job.ID, job.Payload.Message, func(delta string) {
 		resp.WriteString(delta)
@@ -806,7 +806,7 @@ func showCronHistory(out io.Writer, id string) error {
 	var resp struct {
 		History []cron.JobState `json:"history"`
 	}
-	if err := callCronAPI("GET", path, nil, &resp); err == nil {
+	if err := callGatewayAPI("GET", path, nil, &resp); err == nil {
 		return printCronHistory(out, resp.History)
 	} else {
 		_, _ = fmt.Fprintf(out, "Warning: failed to contact gateway, falling back to local file: %v\n", err)
@@ -847,52 +847,3 @@ func printCronHistory(out io.Writer, history []cron.JobState) error {
 	}
 	return nil
 }
-
-func callCronAPI(method, path string, body interface{}, result interface{}) error {
-	cfg, _ := config.Load() // Ignore error, use defaults
-	port := 18789
-	if cfg != nil && cfg.Gateway.Port > 0 {
-		port = cfg.Gateway.Port
-	}
-
-	url := fmt.Sprintf("http://127.0.0.1:%d/api%s", port, path)
-
-	var bodyReader io.Reader
-	if body != nil {
-		b, err := json.Marshal(body)
-		if err != nil {
-			return err
-		}
-		bodyReader = bytes.NewReader(b)
-	}
-
-	req, err := http.NewRequest(method, url, bodyReader)
-	if err != nil {
-		return err
-	}
-	if bodyReader != nil {
-		req.Header.Set("Content-Type", "application/json")
-	}
-
-	// Add token authentication
-	token, _ := gateway.LoadClawdbotToken()
-	if token != "" {
-		req.Header.Set("Authorization", "Bearer "+token)
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("connection failed: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode >= 400 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(b))
-	}
-
-	if result != nil {
-		return json.NewDecoder(resp.Body).Decode(result)
-	}
-	return nil
-}
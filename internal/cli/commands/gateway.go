@@ -113,28 +113,8 @@ func runGatewayStart(cmd *cobra.Command, args []string) error {
 	}
 
 	// 2. Parse Flags
-	portFlag, _ := cmd.Flags().GetInt("port")
-	hostFlag, _ := cmd.Flags().GetString("host")
 	detached, _ := cmd.Flags().GetBool("detached")
-
-	port := 18789 // Default
-	if cmd.Flags().Changed("port") {
-		port = portFlag
-	} else if cfg.Gateway.Port > 0 {
-		port = cfg.Gateway.Port
-	}
-
-	host := "0.0.0.0"
-	if cmd.Flags().Changed("host") {
-		host = hostFlag
-	} else {
-		switch cfg.Gateway.Bind {
-		case "loopback":
-			host = "127.0.0.1"
-		case "public", "0.0.0.0":
-			host = "0.0.0.0"
-		}
-	}
+	host, port := gatewayListenAddr(cmd, cfg)
 
 	// 3. Handle Detached Mode
 	if detached {
@@ -213,21 +193,56 @@ func runGatewayStart(cmd *cobra.Command, args []string) error {
 
 	_, _ = fmt.Fprintf(out, "🦞 Starting LiteClaw Gateway on %s:%d\n", host, port)
 
-	server := gateway.New(&gateway.Config{
-		Host: host,
-		Port: port,
-	})
-
 	// For tests, skip actual start if configured
 	if os.Getenv("LITECLAW_SKIP_GATEWAY_START") == "true" {
 		_, _ = fmt.Fprintln(out, "Skipping actual server start for testing.")
 		return nil
 	}
 
-	if err := server.Start(); err != nil {
-		return fmt.Errorf("failed to start gateway: %w", err)
+	// Supervise the server: a requested restart starts a fresh one in
+	// this process, re-reading the listen address from config.
+	for {
+		server := gateway.New(&gateway.Config{
+			Host: host,
+			Port: port,
+		})
+		err := server.Start()
+		if !errors.Is(err, gateway.ErrRestart) {
+			if err != nil {
+				return fmt.Errorf("failed to start gateway: %w", err)
+			}
+			return nil
+		}
+		if reloaded, err := config.Load(); err == nil {
+			cfg = reloaded
+		}
+		host, port = gatewayListenAddr(cmd, cfg)
+		_, _ = fmt.Fprintf(out, "🔄 Restarting LiteClaw Gateway on %s:%d\n", host, port)
 	}
-	return nil
+}
+
+// gatewayListenAddr resolves the gateway's listen address from flags,
+// falling back to gateway.port and gateway.bind.
+func gatewayListenAddr(cmd *cobra.Command, cfg *config.Config) (string, int) {
+	port := 18789 // Default
+	if cmd.Flags().Changed("port") {
+		port, _ = cmd.Flags().GetInt("port")
+	} else if cfg.Gateway.Port > 0 {
+		port = cfg.Gateway.Port
+	}
+
+	host := "0.0.0.0"
+	if cmd.Flags().Changed("host") {
+		host, _ = cmd.Flags().GetString("host")
+	} else {
+		switch cfg.Gateway.Bind {
+		case "loopback":
+			host = "127.0.0.1"
+		case "public", "0.0.0.0":
+			host = "0.0.0.0"
+		}
+	}
+	return host, port
 }
 
 func runGatewayStop(cmd *cobra.Command) error {
@@ -278,6 +293,16 @@ func runGatewayRestart(cmd *cobra.Command) error {
 	out := cmd.OutOrStdout()
 
 	_, _ = fmt.Fprintln(out, "Restarting gateway server...")
+
+	// A running gateway restarts itself in place, keeping its terminal.
+	if pid, err := readGatewayPID(); err == nil && checkProcessRunning(pid) {
+		body := map[string]string{"reason": "liteclaw gateway restart"}
+		if err := callGatewayAPI("POST", "/gateway/restart", body, nil); err == nil {
+			_, _ = fmt.Fprintf(out, "Restart requested (PID %d).\n", pid)
+			return nil
+		}
+	}
+
 	if err := runGatewayStop(cmd); err != nil {
 		_, _ = fmt.Fprintf(out, "Warning: stop failed (%v), continuing to start...\n", err)
 	}
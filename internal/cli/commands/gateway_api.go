@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/liteclaw/liteclaw/internal/gateway"
+)
+
+// callGatewayAPI calls the local gateway REST API; path is relative to /api.
+func callGatewayAPI(method, path string, body interface{}, result interface{}) error {
+	cfg, _ := config.Load() // Ignore error, use defaults
+	port := 18789
+	if cfg != nil && cfg.Gateway.Port > 0 {
+		port = cfg.Gateway.Port
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/api%s", port, path)
+
+	var bodyReader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		bodyReader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, url, bodyReader)
+	if err != nil {
+		return err
+	}
+	if bodyReader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	// Add token authentication
+	token, _ := gateway.LoadClawdbotToken()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (%d): %s", resp.StatusCode, string(b))
+	}
+
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
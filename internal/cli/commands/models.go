@@ -231,6 +231,21 @@ func newModelsAliasesRemoveCommand() *cobra.Command {
 	}
 }
 
+// apiTypeFromInput maps a short API type name to the models.providers "api"
+// value. Unknown input falls back to openai-completions with ok=false.
+func apiTypeFromInput(input string) (api string, ok bool) {
+	switch strings.TrimSpace(strings.ToLower(input)) {
+	case "anthropic", "anthropic-messages":
+		return "anthropic-messages", true
+	case "responses", "openai-responses":
+		return "openai-responses", true
+	case "openai", "openai-completions":
+		return "openai-completions", true
+	default:
+		return "openai-completions", false
+	}
+}
+
 // normalizeAlias normalizes an alias string (lowercase, trim spaces).
 func normalizeAlias(alias string) string {
 	return strings.TrimSpace(strings.ToLower(alias))
@@ -247,19 +262,6 @@ func newModelsFallbacksCommand() *cobra.Command {
 	}
 }
 
-func newModelsScanCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:     "scan",
-		Short:   "Scan OpenRouter/Local models for tools + images",
-		Example: "  liteclaw models scan",
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Println("Scanning for models...")
-			// TODO: Implement actual scanning logic (e.g. check Ollama URL, scan OpenRouter)
-			cmd.Println("Scan check complete. (Mock)")
-		},
-	}
-}
-
 func newModelsAuthCommand() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "auth",
@@ -276,6 +278,11 @@ func newModelsAuthCommand() *cobra.Command {
 	loginCmd.Flags().StringP("provider", "p", "", "Provider ID (e.g. minimax, moonshot)")
 
 	cmd.AddCommand(loginCmd)
+	cmd.AddCommand(newModelsAuthAddCommand())
+	cmd.AddCommand(newModelsAuthListCommand())
+	cmd.AddCommand(newModelsAuthRemoveCommand())
+	cmd.AddCommand(newModelsAuthPinCommand())
+	cmd.AddCommand(newModelsAuthUnpinCommand())
 	return cmd
 }
 
@@ -308,7 +315,7 @@ func newModelsConfigCommand() *cobra.Command {
 
 Supported configuration options:
   --base-url    The API endpoint URL for the provider
-  --api-type    The API protocol type: 'openai' or 'anthropic'
+  --api-type    The API protocol type: 'openai', 'responses' or 'anthropic'
 
 If no flags are provided, the command runs in interactive mode.`,
 		Example: `  # Set Ollama to use a remote server
@@ -329,7 +336,7 @@ If no flags are provided, the command runs in interactive mode.`,
 	}
 
 	cmd.Flags().StringVar(&baseURL, "base-url", "", "Set the base URL for the provider")
-	cmd.Flags().StringVar(&apiType, "api-type", "", "Set the API type (openai or anthropic)")
+	cmd.Flags().StringVar(&apiType, "api-type", "", "Set the API type (openai, responses or anthropic)")
 
 	return cmd
 }
@@ -369,24 +376,15 @@ func runModelsConfig(cmd *cobra.Command, pName, baseURL, apiType string) error {
 
 	// Handle apiType
 	if apiType != "" {
-		if apiType == "anthropic" {
-			pConfig.API = "anthropic-messages"
-		} else {
-			pConfig.API = "openai-completions"
-		}
+		pConfig.API, _ = apiTypeFromInput(apiType)
 		updated = true
 		_, _ = fmt.Fprintf(out, "API Type set to: %s\n", pConfig.API)
 	} else {
 		_, _ = fmt.Fprintf(out, "Current API Type: %s\n", pConfig.API)
-		_, _ = fmt.Fprint(out, "New API Type (openai/anthropic, leave blank to keep): ")
+		_, _ = fmt.Fprint(out, "New API Type (openai/responses/anthropic, leave blank to keep): ")
 		input, _ := reader.ReadString('\n')
-		input = strings.TrimSpace(strings.ToLower(input))
-		switch input {
-		case "anthropic":
-			pConfig.API = "anthropic-messages"
-			updated = true
-		case "openai":
-			pConfig.API = "openai-completions"
+		if api, ok := apiTypeFromInput(input); ok {
+			pConfig.API = api
 			updated = true
 		}
 	}
@@ -461,14 +459,12 @@ func runModelsAdd(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("api key required")
 		}
 
-		_, _ = fmt.Fprint(out, "API Type (openai/anthropic) [default: openai]: ")
+		_, _ = fmt.Fprint(out, "API Type (openai/responses/anthropic) [default: openai]: ")
 		apiTypeInput, _ := reader.ReadString('\n')
 		apiTypeInput = strings.TrimSpace(strings.ToLower(apiTypeInput))
 
-		apiType := "openai-completions"
-		if apiTypeInput == "anthropic" {
-			apiType = "anthropic-messages"
-		} else if apiTypeInput != "" && apiTypeInput != "openai" {
+		apiType, ok := apiTypeFromInput(apiTypeInput)
+		if !ok && apiTypeInput != "" {
 			_, _ = fmt.Fprintf(out, "Warning: Unknown API type input '%s', defaulting to openai-completions.\n", apiTypeInput)
 		}
 
@@ -541,7 +537,6 @@ func runModelsAdd(cmd *cobra.Command, args []string) error {
 
 func runAuthLogin(cmd *cobra.Command, args []string) error {
 	out := cmd.OutOrStdout()
-	in := cmd.InOrStdin()
 
 	provider, _ := cmd.Flags().GetString("provider")
 	if provider == "" {
@@ -590,22 +585,10 @@ func runAuthLogin(cmd *cobra.Command, args []string) error {
 	}
 
 	// Prompt for key
-	_, _ = fmt.Fprintf(out, "Enter API Key for %s: ", provider)
-	var key string
-	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
-		bytePassword, err := term.ReadPassword(int(f.Fd()))
-		if err != nil {
-			return fmt.Errorf("failed to read password: %w", err)
-		}
-		_, _ = fmt.Fprintln(out) // Print newline after hidden input
-		key = string(bytePassword)
-	} else {
-		reader := bufio.NewReader(in)
-		input, _ := reader.ReadString('\n')
-		key = strings.TrimSpace(input)
+	key, err := readSecret(cmd, fmt.Sprintf("Enter API Key for %s: ", provider))
+	if err != nil {
+		return err
 	}
-
-	key = strings.TrimSpace(key)
 	if key == "" {
 		return fmt.Errorf("api key cannot be empty")
 	}
@@ -708,8 +691,9 @@ func runModelsStatus(cmd *cobra.Command, jsonOutput, plainOutput bool) error {
 	}
 
 	status := map[string]interface{}{
-		"defaults":  cfg.Agents.Defaults.Model,
-		"providers": getProviderStatus(cfg),
+		"defaults":     cfg.Agents.Defaults.Model,
+		"providers":    getProviderStatus(cfg),
+		"authProfiles": getAuthProfileStatus(cfg),
 	}
 
 	if jsonOutput {
@@ -732,6 +716,11 @@ func runModelsStatus(cmd *cobra.Command, jsonOutput, plainOutput bool) error {
 	for p, info := range status["providers"].(map[string]interface{}) {
 		_, _ = fmt.Fprintf(out, "- %s: %v\n", p, info)
 	}
+
+	if profiles := status["authProfiles"].([]authProfileStatus); len(profiles) > 0 {
+		_, _ = fmt.Fprintf(out, "\nAuth Profiles:\n")
+		renderAuthProfileTable(out, profiles)
+	}
 	_, _ = fmt.Fprintln(out)
 
 	return nil
@@ -771,10 +760,12 @@ func getProviderStatus(cfg *config.Config) map[string]interface{} {
 
 func newModelsSetCommand() *cobra.Command {
 	return &cobra.Command{
-		Use:     "set [model]",
-		Short:   "Set the default model",
-		Example: "  liteclaw models set minimax/MiniMax-M2.1",
-		Args:    cobra.ExactArgs(1),
+		Use:   "set [model]",
+		Short: "Set the default model",
+		Long:  "Set the default model. A running gateway picks up the change on its next run.",
+		Example: `  liteclaw models set minimax/MiniMax-M2.1
+  liteclaw models set fast`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			out := cmd.OutOrStdout()
 			modelRef := args[0]
@@ -784,32 +775,19 @@ func newModelsSetCommand() *cobra.Command {
 				return err
 			}
 
-			// Validation Logic
-			parts := strings.Split(modelRef, "/")
-			if len(parts) != 2 {
-				// TODO: Check Aliases here if implemented
-				return fmt.Errorf("invalid format '%s'. usage: <provider>/<model_id> (e.g. moonshot/kimi-k2-0905-preview)", modelRef)
-			}
-
-			providerName := parts[0]
-			modelID := parts[1]
-
-			pConfig, ok := cfg.Models.Providers[providerName]
-			if !ok {
-				return fmt.Errorf("unknown provider '%s'. Run 'liteclaw models list' to see available providers", providerName)
+			// Validation Logic (accepts provider/model or an alias)
+			if modelRef == "" {
+				return fmt.Errorf("usage: <provider>/<model_id> or <alias> (e.g. moonshot/kimi-k2-0905-preview)")
 			}
-
-			found := false
-			for _, m := range pConfig.Models {
-				if m.ID == modelID {
-					found = true
-					break
-				}
+			providerName, modelID, err := cfg.ResolveModelRef(modelRef)
+			if err != nil {
+				return fmt.Errorf("%w. Run 'liteclaw models list' to see available models", err)
 			}
 
-			if !found {
+			if _, found := cfg.FindModel(providerName, modelID); !found {
 				return fmt.Errorf("model '%s' not found for provider '%s'. Run 'liteclaw models list' to see available models", modelID, providerName)
 			}
+			modelRef = providerName + "/" + modelID
 
 			_, _ = fmt.Fprintf(out, "Setting default model to: %s\n", modelRef)
 			cfg.Agents.Defaults.Model.Primary = modelRef
@@ -0,0 +1,360 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// authProfileStatus is the per-profile view shown by `models status` and `models auth list`.
+type authProfileStatus struct {
+	ID               string `json:"id"`
+	Provider         string `json:"provider"`
+	Mode             string `json:"mode"`
+	HasSecret        bool   `json:"hasSecret"`
+	Pinned           bool   `json:"pinned"`
+	Requests         int64  `json:"requests"`
+	Errors           int64  `json:"errors"`
+	RateLimits       int64  `json:"rateLimits"`
+	PromptTokens     int64  `json:"promptTokens"`
+	CompletionTokens int64  `json:"completionTokens"`
+	CooldownUntil    int64  `json:"cooldownUntil,omitempty"`
+	LastError        string `json:"lastError,omitempty"`
+}
+
+func newModelsAuthAddCommand() *cobra.Command {
+	var provider, mode, envName string
+
+	cmd := &cobra.Command{
+		Use:   "add <profile-id>",
+		Short: "Add an auth profile for a provider",
+		Long: `Add a named auth profile. A provider can have several profiles; requests
+rotate to the next profile when one is rate limited (HTTP 429).
+
+Modes:
+  api-key  API key stored in liteclaw.json (prompted)
+  oauth    OAuth/bearer token stored in liteclaw.json (prompted)
+  env      Key read from an environment variable (--env)`,
+		Example: `  liteclaw models auth add openai:work --provider openai
+  liteclaw models auth add openai:team --provider openai --mode env --env OPENAI_TEAM_KEY
+  liteclaw models auth add anthropic:me --provider anthropic --mode oauth`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAuthAdd(cmd, args[0], provider, mode, envName)
+		},
+	}
+	cmd.Flags().StringVarP(&provider, "provider", "p", "", "Provider ID (required)")
+	cmd.Flags().StringVarP(&mode, "mode", "m", config.AuthModeAPIKey, "Profile mode: api-key, oauth or env")
+	cmd.Flags().StringVar(&envName, "env", "", "Environment variable holding the key (mode env)")
+	_ = cmd.MarkFlagRequired("provider")
+	return cmd
+}
+
+func runAuthAdd(cmd *cobra.Command, id, provider, mode, envName string) error {
+	out := cmd.OutOrStdout()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if _, ok := cfg.Models.Providers[provider]; !ok {
+		return fmt.Errorf("unknown provider '%s'", provider)
+	}
+
+	profile := config.AuthProfile{Provider: provider, Mode: mode}
+	switch mode {
+	case config.AuthModeEnv:
+		if envName == "" {
+			return fmt.Errorf("--env is required for mode env")
+		}
+		profile.Env = envName
+	case config.AuthModeAPIKey, config.AuthModeOAuth:
+		label := "API Key"
+		if mode == config.AuthModeOAuth {
+			label = "OAuth token"
+		}
+		secret, err := readSecret(cmd, fmt.Sprintf("Enter %s for %s: ", label, id))
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			return fmt.Errorf("%s cannot be empty", strings.ToLower(label))
+		}
+		if mode == config.AuthModeOAuth {
+			profile.Token = secret
+		} else {
+			profile.APIKey = secret
+		}
+	default:
+		return fmt.Errorf("unknown mode '%s' (use api-key, oauth or env)", mode)
+	}
+
+	if cfg.Auth.Profiles == nil {
+		cfg.Auth.Profiles = make(map[string]config.AuthProfile)
+	}
+	cfg.Auth.Profiles[id] = profile
+
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(out, "✓ Auth profile '%s' saved for %s (%s)\n", id, provider, mode)
+	return nil
+}
+
+func newModelsAuthListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List auth profiles with usage and cooldown state",
+		Example: "  liteclaw models auth list",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			profiles := getAuthProfileStatus(cfg)
+			if len(profiles) == 0 {
+				cmd.Println("No auth profiles configured.")
+				return nil
+			}
+			renderAuthProfileTable(cmd.OutOrStdout(), profiles)
+			return nil
+		},
+	}
+}
+
+func newModelsAuthRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "remove <profile-id>",
+		Aliases: []string{"rm", "delete"},
+		Short:   "Remove an auth profile",
+		Example: "  liteclaw models auth remove openai:work",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := args[0]
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			profile, ok := cfg.Auth.Profiles[id]
+			if !ok {
+				return fmt.Errorf("auth profile not found: %s", id)
+			}
+
+			delete(cfg.Auth.Profiles, id)
+			if cfg.Agents.Defaults.AuthProfiles[profile.Provider] == id {
+				delete(cfg.Agents.Defaults.AuthProfiles, profile.Provider)
+			}
+			if order := cfg.Auth.Order[profile.Provider]; len(order) > 0 {
+				kept := order[:0]
+				for _, o := range order {
+					if o != id {
+						kept = append(kept, o)
+					}
+				}
+				cfg.Auth.Order[profile.Provider] = kept
+			}
+
+			if err := config.Save(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			cmd.Printf("✓ Removed auth profile '%s'\n", id)
+			return nil
+		},
+	}
+}
+
+func newModelsAuthPinCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "pin <profile-id>",
+		Short: "Pin the agent to one auth profile of a provider (disables rotation)",
+		Long: `Pin the agent to one auth profile of its provider. Requests for that provider
+always use the pinned profile and are not rotated on rate limits.
+Sessions can override this with the gateway 'sessions.patch' authProfile param.`,
+		Example: "  liteclaw models auth pin openai:work",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id := args[0]
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			profile, ok := cfg.Auth.Profiles[id]
+			if !ok {
+				return fmt.Errorf("auth profile not found: %s", id)
+			}
+			if cfg.Agents.Defaults.AuthProfiles == nil {
+				cfg.Agents.Defaults.AuthProfiles = make(map[string]string)
+			}
+			cfg.Agents.Defaults.AuthProfiles[profile.Provider] = id
+
+			if err := config.Save(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			cmd.Printf("✓ Pinned %s to auth profile '%s'\n", profile.Provider, id)
+			return nil
+		},
+	}
+}
+
+func newModelsAuthUnpinCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "unpin <provider>",
+		Short:   "Remove the auth profile pin for a provider (re-enables rotation)",
+		Example: "  liteclaw models auth unpin openai",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			provider := args[0]
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			if _, ok := cfg.Agents.Defaults.AuthProfiles[provider]; !ok {
+				return fmt.Errorf("no auth profile pinned for %s", provider)
+			}
+			delete(cfg.Agents.Defaults.AuthProfiles, provider)
+
+			if err := config.Save(cfg); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			cmd.Printf("✓ Unpinned %s\n", provider)
+			return nil
+		},
+	}
+}
+
+// getAuthProfileStatus merges configured profiles with the usage state
+// recorded by the gateway. Implicit "<provider>:env" profiles are listed
+// when they have been used or when a provider has no configured profiles.
+func getAuthProfileStatus(cfg *config.Config) []authProfileStatus {
+	stats, _ := agent.LoadAuthState(agent.AuthStatePath())
+
+	var res []authProfileStatus
+	seen := make(map[string]bool)
+	add := func(id, provider, mode string, hasSecret bool) {
+		st := stats[id]
+		res = append(res, authProfileStatus{
+			ID:               id,
+			Provider:         provider,
+			Mode:             mode,
+			HasSecret:        hasSecret,
+			Pinned:           cfg.Agents.Defaults.AuthProfiles[provider] == id,
+			Requests:         st.Requests,
+			Errors:           st.Errors,
+			RateLimits:       st.RateLimits,
+			PromptTokens:     st.PromptTokens,
+			CompletionTokens: st.CompletionTokens,
+			CooldownUntil:    st.CooldownUntil,
+			LastError:        st.LastError,
+		})
+		seen[id] = true
+	}
+
+	for pName := range cfg.Models.Providers {
+		ids := cfg.ProviderAuthProfiles(pName)
+		for _, id := range ids {
+			p := cfg.Auth.Profiles[id]
+			mode := p.Mode
+			if mode == "" {
+				mode = config.AuthModeAPIKey
+			}
+			add(id, pName, mode, agent.AuthProfileSecret(cfg, p) != "")
+		}
+		envID := pName + ":env"
+		if _, used := stats[envID]; used || len(ids) == 0 {
+			add(envID, pName, config.AuthModeEnv, agent.ProviderAPIKey(cfg, pName) != "")
+		}
+	}
+
+	// Profiles whose provider is not (or no longer) configured.
+	for id, p := range cfg.Auth.Profiles {
+		if !seen[id] {
+			add(id, p.Provider, p.Mode, agent.AuthProfileSecret(cfg, p) != "")
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Provider != res[j].Provider {
+			return res[i].Provider < res[j].Provider
+		}
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+func renderAuthProfileTable(out io.Writer, profiles []authProfileStatus) {
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Profile", "Provider", "Mode", "Key", "Requests", "Errors", "429s", "Tokens", "Status"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+
+	now := time.Now().UnixMilli()
+	for _, p := range profiles {
+		key := "missing"
+		if p.HasSecret {
+			key = "ok"
+		}
+		status := "ready"
+		switch {
+		case !p.HasSecret:
+			status = "no credentials"
+		case p.CooldownUntil > now:
+			status = fmt.Sprintf("cooldown %s", (time.Duration(p.CooldownUntil-now) * time.Millisecond).Round(time.Second))
+		case p.LastError != "":
+			status = "error: " + truncateStatus(p.LastError, 40)
+		}
+		if p.Pinned {
+			status += " (pinned)"
+		}
+		table.Append([]string{
+			p.ID,
+			p.Provider,
+			p.Mode,
+			key,
+			fmt.Sprint(p.Requests),
+			fmt.Sprint(p.Errors),
+			fmt.Sprint(p.RateLimits),
+			fmt.Sprint(p.PromptTokens + p.CompletionTokens),
+			status,
+		})
+	}
+	table.Render()
+}
+
+func truncateStatus(s string, n int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// readSecret prompts for a secret, hiding input when reading from a terminal.
+func readSecret(cmd *cobra.Command, prompt string) (string, error) {
+	out := cmd.OutOrStdout()
+	in := cmd.InOrStdin()
+
+	_, _ = fmt.Fprint(out, prompt)
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		bytePassword, err := term.ReadPassword(int(f.Fd()))
+		if err != nil {
+			return "", fmt.Errorf("failed to read password: %w", err)
+		}
+		_, _ = fmt.Fprintln(out) // Print newline after hidden input
+		return strings.TrimSpace(string(bytePassword)), nil
+	}
+	reader := bufio.NewReader(in)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input), nil
+}
@@ -0,0 +1,505 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultScanContextWindow = 128000
+	defaultScanMaxTokens     = 8192
+)
+
+// probeColor is the colour of the probe image. A model only counts as
+// accepting images when its reply names it, so models that ignore the image
+// are not mistaken for vision models.
+const probeColor = "red"
+
+// probeImage is a solid red PNG data URL used for the vision probe.
+var probeImage = func() string {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 0xff, A: 0xff}), image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}()
+
+// scannedModel is the result of discovering and probing a single model.
+type scannedModel struct {
+	Provider      string `json:"provider"`
+	ID            string `json:"id"`
+	ContextWindow int    `json:"contextWindow,omitempty"`
+	MaxTokens     int    `json:"maxTokens,omitempty"`
+	Probed        bool   `json:"probed"`
+	Tools         bool   `json:"tools"`
+	Vision        bool   `json:"vision"`
+	LatencyMs     int64  `json:"latencyMs,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// modelScanner lists models from provider endpoints and probes their capabilities.
+type modelScanner struct {
+	client *http.Client
+}
+
+func newModelsScanCommand() *cobra.Command {
+	var providerFilter string
+	var noProbe bool
+	var yes bool
+	var noWrite bool
+	var jsonOutput bool
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "scan [provider]",
+		Short: "Scan OpenRouter/Local models for tools + images",
+		Long: `Scan configured providers for available models.
+
+Models are listed from the provider's OpenAI-compatible /models endpoint
+(or /api/tags for Ollama). Each model is then probed with a tiny tool-call
+request and an image request to detect tool calling and vision support.
+Discovered models can be written back into models.providers.`,
+		Example: `  liteclaw models scan
+  liteclaw models scan ollama
+  liteclaw models scan --no-probe --json
+  liteclaw models scan openrouter --yes`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 1 {
+				providerFilter = args[0]
+			}
+			return runModelsScan(cmd, providerFilter, !noProbe, yes, noWrite, jsonOutput, timeout)
+		},
+	}
+
+	cmd.Flags().StringVarP(&providerFilter, "provider", "p", "", "Only scan this provider")
+	cmd.Flags().BoolVar(&noProbe, "no-probe", false, "List models without probing tool/image support")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Write discovered models without prompting")
+	cmd.Flags().BoolVar(&noWrite, "no-write", false, "Only report results, never update config")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Output JSON (config is only updated with --yes)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Timeout per request")
+
+	return cmd
+}
+
+func runModelsScan(cmd *cobra.Command, providerFilter string, probe, yes, noWrite, jsonOutput bool, timeout time.Duration) error {
+	out := cmd.OutOrStdout()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for pName := range cfg.Models.Providers {
+		if providerFilter == "" || pName == providerFilter {
+			names = append(names, pName)
+		}
+	}
+	if len(names) == 0 {
+		if providerFilter != "" {
+			return fmt.Errorf("unknown provider '%s'", providerFilter)
+		}
+		return fmt.Errorf("no providers configured in models.providers")
+	}
+	sort.Strings(names)
+
+	scanner := &modelScanner{client: &http.Client{Timeout: timeout}}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	results := make(map[string][]scannedModel)
+	var all []scannedModel
+	for _, pName := range names {
+		p := cfg.Models.Providers[pName]
+		if p.API == "anthropic-messages" {
+			if !jsonOutput {
+				_, _ = fmt.Fprintf(out, "Skipping %s: model listing is not supported for api '%s'\n", pName, p.API)
+			}
+			continue
+		}
+
+		if !jsonOutput {
+			_, _ = fmt.Fprintf(out, "Scanning %s (%s)...\n", pName, p.BaseURL)
+		}
+		apiKey := agent.ProviderAPIKey(cfg, pName)
+		models, err := scanner.listModels(ctx, pName, p, apiKey)
+		if err != nil {
+			if !jsonOutput {
+				_, _ = fmt.Fprintf(out, "  ✗ %v\n", err)
+			}
+			continue
+		}
+
+		if probe {
+			for i := range models {
+				scanner.probeModel(ctx, p, apiKey, &models[i])
+			}
+		}
+
+		results[pName] = models
+		all = append(all, models...)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(map[string]interface{}{"models": all}); err != nil {
+			return err
+		}
+	} else {
+		renderScanTable(out, all, probe)
+	}
+
+	// JSON output is for scripts: without --yes there is no one to ask.
+	if noWrite || len(all) == 0 || (jsonOutput && !yes) {
+		return nil
+	}
+
+	reader := bufio.NewReader(cmd.InOrStdin())
+	changed := false
+	for _, pName := range names {
+		models := results[pName]
+		if len(models) == 0 {
+			continue
+		}
+		if !yes {
+			_, _ = fmt.Fprintf(out, "Write %d model(s) to models.providers.%s? (Y/n): ", len(models), pName)
+			confirm, _ := reader.ReadString('\n')
+			confirm = strings.TrimSpace(strings.ToLower(confirm))
+			if confirm == "n" || confirm == "no" {
+				continue
+			}
+		}
+
+		p := cfg.Models.Providers[pName]
+		added, updated := mergeScannedModels(&p, models)
+		cfg.Models.Providers[pName] = p
+		changed = true
+		if !jsonOutput {
+			_, _ = fmt.Fprintf(out, "✓ %s: %d added, %d updated\n", pName, added, updated)
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := config.Save(cfg); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+	return nil
+}
+
+func renderScanTable(out io.Writer, models []scannedModel, probed bool) {
+	if len(models) == 0 {
+		_, _ = fmt.Fprintln(out, "No models found.")
+		return
+	}
+
+	table := tablewriter.NewWriter(out)
+	table.SetHeader([]string{"Provider", "Model ID", "Tools", "Vision", "Latency", "Context"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+
+	mark := func(ok bool) string {
+		if !probed {
+			return "-"
+		}
+		if ok {
+			return "yes"
+		}
+		return "no"
+	}
+
+	for _, m := range models {
+		latency := "-"
+		if m.LatencyMs > 0 {
+			latency = fmt.Sprintf("%dms", m.LatencyMs)
+		}
+		if m.Error != "" {
+			latency = "error"
+		}
+		ctxWindow := "-"
+		if m.ContextWindow > 0 {
+			ctxWindow = strconv.Itoa(m.ContextWindow)
+		}
+		table.Append([]string{m.Provider, m.ID, mark(m.Tools), mark(m.Vision), latency, ctxWindow})
+	}
+	table.Render()
+}
+
+func isOllamaProvider(pName string, p config.ModelProvider) bool {
+	return strings.EqualFold(pName, "ollama") || p.API == "ollama" || strings.Contains(p.BaseURL, ":11434")
+}
+
+// ollamaRoot strips the OpenAI-compatible /v1 suffix from an Ollama base URL.
+func ollamaRoot(baseURL string) string {
+	return strings.TrimSuffix(strings.TrimRight(baseURL, "/"), "/v1")
+}
+
+// chatBaseURL returns the OpenAI-compatible base URL used for probe requests.
+func chatBaseURL(pName string, p config.ModelProvider) string {
+	base := strings.TrimRight(p.BaseURL, "/")
+	if isOllamaProvider(pName, p) && !strings.HasSuffix(base, "/v1") {
+		base += "/v1"
+	}
+	return base
+}
+
+func (s *modelScanner) listModels(ctx context.Context, pName string, p config.ModelProvider, apiKey string) ([]scannedModel, error) {
+	if p.BaseURL == "" {
+		return nil, fmt.Errorf("baseUrl is empty")
+	}
+	if isOllamaProvider(pName, p) {
+		return s.listOllamaModels(ctx, pName, p)
+	}
+
+	var payload struct {
+		Data []struct {
+			ID            string `json:"id"`
+			ContextLength int    `json:"context_length"`
+			TopProvider   struct {
+				ContextLength       int `json:"context_length"`
+				MaxCompletionTokens int `json:"max_completion_tokens"`
+			} `json:"top_provider"`
+		} `json:"data"`
+	}
+	if err := s.getJSON(ctx, strings.TrimRight(p.BaseURL, "/")+"/models", apiKey, &payload); err != nil {
+		return nil, err
+	}
+
+	var models []scannedModel
+	for _, d := range payload.Data {
+		if d.ID == "" {
+			continue
+		}
+		m := scannedModel{
+			Provider:      pName,
+			ID:            d.ID,
+			ContextWindow: d.ContextLength,
+			MaxTokens:     d.TopProvider.MaxCompletionTokens,
+		}
+		if m.ContextWindow == 0 {
+			m.ContextWindow = d.TopProvider.ContextLength
+		}
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+func (s *modelScanner) listOllamaModels(ctx context.Context, pName string, p config.ModelProvider) ([]scannedModel, error) {
+	root := ollamaRoot(p.BaseURL)
+
+	var tags struct {
+		Models []struct {
+			Name  string `json:"name"`
+			Model string `json:"model"`
+		} `json:"models"`
+	}
+	if err := s.getJSON(ctx, root+"/api/tags", "", &tags); err != nil {
+		return nil, err
+	}
+
+	var models []scannedModel
+	for _, t := range tags.Models {
+		id := t.Name
+		if id == "" {
+			id = t.Model
+		}
+		if id == "" {
+			continue
+		}
+		m := scannedModel{Provider: pName, ID: id}
+
+		// /api/show exposes the context length under "<arch>.context_length".
+		var show struct {
+			ModelInfo map[string]interface{} `json:"model_info"`
+		}
+		if err := s.postJSON(ctx, root+"/api/show", "", map[string]string{"model": id}, &show); err == nil {
+			for k, v := range show.ModelInfo {
+				if strings.HasSuffix(k, ".context_length") {
+					if n, ok := v.(float64); ok {
+						m.ContextWindow = int(n)
+					}
+				}
+			}
+		}
+		models = append(models, m)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// probeModel sends a tool-call request and an image request to detect
+// capabilities. A failed tool probe is reported but does not skip the
+// vision probe.
+func (s *modelScanner) probeModel(ctx context.Context, p config.ModelProvider, apiKey string, m *scannedModel) {
+	url := chatBaseURL(m.Provider, p) + "/chat/completions"
+	m.Probed = true
+
+	toolReq := map[string]interface{}{
+		"model": m.ID,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Call the ping tool now."},
+		},
+		"tools": []map[string]interface{}{
+			{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        "ping",
+					"description": "Health check. Always call this tool.",
+					"parameters":  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+				},
+			},
+		},
+		"max_tokens": 32,
+	}
+
+	var toolResp chatProbeResponse
+	start := time.Now()
+	err := s.postJSON(ctx, url, apiKey, toolReq, &toolResp)
+	m.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		m.Error = err.Error()
+	} else if len(toolResp.Choices) > 0 && len(toolResp.Choices[0].Message.ToolCalls) > 0 {
+		m.Tools = true
+	}
+
+	imageReq := map[string]interface{}{
+		"model": m.ID,
+		"messages": []map[string]interface{}{
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "text", "text": "What color is this image? Reply with one word."},
+					{"type": "image_url", "image_url": map[string]string{"url": probeImage}},
+				},
+			},
+		},
+		"max_tokens": 16,
+	}
+
+	var imageResp chatProbeResponse
+	if err := s.postJSON(ctx, url, apiKey, imageReq, &imageResp); err == nil && len(imageResp.Choices) > 0 {
+		m.Vision = strings.Contains(strings.ToLower(imageResp.Choices[0].Message.Content), probeColor)
+	}
+}
+
+type chatProbeResponse struct {
+	Choices []struct {
+		Message struct {
+			Content   string            `json:"content"`
+			ToolCalls []json.RawMessage `json:"tool_calls"`
+		} `json:"message"`
+	} `json:"choices"`
+}
+
+func (s *modelScanner) getJSON(ctx context.Context, url, apiKey string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, apiKey, out)
+}
+
+func (s *modelScanner) postJSON(ctx context.Context, url, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return s.do(req, apiKey, out)
+}
+
+func (s *modelScanner) do(req *http.Request, apiKey string, out interface{}) error {
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s: %s %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// mergeScannedModels writes scan results into a provider config.
+// Existing entries keep their name, cost and reasoning flags; discovered
+// limits and input modalities replace the old values.
+func mergeScannedModels(p *config.ModelProvider, models []scannedModel) (added, updated int) {
+	index := make(map[string]int, len(p.Models))
+	for i, m := range p.Models {
+		index[m.ID] = i
+	}
+
+	for _, sm := range models {
+		i, exists := index[sm.ID]
+		entry := config.ModelEntry{ID: sm.ID, Name: sm.ID}
+		if exists {
+			entry = p.Models[i]
+		}
+
+		// A model that named the probe image's colour reads images even
+		// when its tool probe failed.
+		if sm.Probed && (sm.Error == "" || sm.Vision) {
+			entry.Input = []string{"text"}
+			if sm.Vision {
+				entry.Input = append(entry.Input, "image")
+			}
+		} else if len(entry.Input) == 0 {
+			entry.Input = []string{"text"}
+		}
+
+		if sm.ContextWindow > 0 {
+			entry.ContextWindow = sm.ContextWindow
+		} else if entry.ContextWindow == 0 {
+			entry.ContextWindow = defaultScanContextWindow
+		}
+		if sm.MaxTokens > 0 {
+			entry.MaxTokens = sm.MaxTokens
+		} else if entry.MaxTokens == 0 {
+			entry.MaxTokens = defaultScanMaxTokens
+		}
+
+		if exists {
+			p.Models[i] = entry
+			updated++
+		} else {
+			index[sm.ID] = len(p.Models)
+			p.Models = append(p.Models, entry)
+			added++
+		}
+	}
+	return added, updated
+}
@@ -2,10 +2,16 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/liteclaw/liteclaw/internal/config"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -120,3 +126,215 @@ func TestModelsSetCommand(t *testing.T) {
 	data, _ := os.ReadFile(configPath)
 	assert.Contains(t, string(data), `"primary": "p1/m1"`)
 }
+
+func TestModelsScanCommand(t *testing.T) {
+	// OpenAI-compatible stand-in: "smart" supports tools and images, "basic"
+	// supports neither and ignores images, and "seer" fails the tool probe
+	// but reads images.
+	openaiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"data":[{"id":"smart","context_length":64000},{"id":"basic"},{"id":"seer"}]}`))
+		case "/v1/chat/completions":
+			var req map[string]interface{}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			model, _ := req["model"].(string)
+			msgs := req["messages"].([]interface{})
+			content := msgs[0].(map[string]interface{})["content"]
+			if _, isParts := content.([]interface{}); isParts {
+				if model == "basic" {
+					_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"I cannot see images."}}]}`))
+					return
+				}
+				_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"Red."}}]}`))
+				return
+			}
+			switch model {
+			case "seer":
+				http.Error(w, `{"error":"tools not supported"}`, http.StatusBadRequest)
+				return
+			case "smart":
+				_, _ = w.Write([]byte(`{"choices":[{"message":{"tool_calls":[{"id":"c1","type":"function","function":{"name":"ping","arguments":"{}"}}]}}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"pong"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer openaiSrv.Close()
+
+	// Ollama stand-in: /api/tags + /api/show + OpenAI-compatible chat.
+	ollamaSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"qwen2.5:14b"}]}`))
+		case "/api/show":
+			_, _ = w.Write([]byte(`{"model_info":{"qwen2.context_length":32768}}`))
+		case "/v1/chat/completions":
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"tool_calls":[{"id":"c1"}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ollamaSrv.Close()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "liteclaw.json")
+	initialConfig := fmt.Sprintf(`{
+		"env": {"LOCALAI_API_KEY": "sk-test"},
+		"models": {
+			"providers": {
+				"localai": {"baseUrl": "%s/v1", "api": "openai-completions", "models": [{"id": "basic", "name": "Basic", "maxTokens": 1024}]},
+				"ollama": {"baseUrl": "%s/v1", "api": "openai-completions", "models": []}
+			}
+		},
+		"agents": {"defaults": {"model": {"primary": "localai/basic"}}}
+	}`, openaiSrv.URL, ollamaSrv.URL)
+	require.NoError(t, os.WriteFile(configPath, []byte(initialConfig), 0644))
+
+	_ = os.Setenv("LITECLAW_CONFIG_PATH", configPath)
+	_ = os.Setenv("LITECLAW_STATE_DIR", tempDir)
+	defer func() { _ = os.Unsetenv("LITECLAW_CONFIG_PATH") }()
+	defer func() { _ = os.Unsetenv("LITECLAW_STATE_DIR") }()
+
+	cmd := newModelsScanCommand()
+	b := bytes.NewBufferString("")
+	cmd.SetOut(b)
+	cmd.SetArgs([]string{"--yes"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, b.String(), "localai: 2 added, 1 updated")
+	assert.Contains(t, b.String(), "ollama: 1 added, 0 updated")
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	byID := func(provider, id string) config.ModelEntry {
+		for _, m := range cfg.Models.Providers[provider].Models {
+			if m.ID == id {
+				return m
+			}
+		}
+		t.Fatalf("model %s/%s not written", provider, id)
+		return config.ModelEntry{}
+	}
+
+	smart := byID("localai", "smart")
+	assert.Equal(t, []string{"text", "image"}, smart.Input)
+	assert.Equal(t, 64000, smart.ContextWindow)
+	assert.Equal(t, 8192, smart.MaxTokens)
+
+	basic := byID("localai", "basic")
+	assert.Equal(t, "Basic", basic.Name)
+	assert.Equal(t, []string{"text"}, basic.Input)
+	assert.Equal(t, 1024, basic.MaxTokens)
+
+	seer := byID("localai", "seer")
+	assert.Equal(t, []string{"text", "image"}, seer.Input)
+
+	qwen := byID("ollama", "qwen2.5:14b")
+	assert.Equal(t, []string{"text"}, qwen.Input, "a reply without the image's colour is not vision")
+	assert.Equal(t, 32768, qwen.ContextWindow)
+}
+
+func TestModelsScanNoWrite(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[{"id":"m1"}]}`))
+	}))
+	defer srv.Close()
+
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "liteclaw.json")
+	initialConfig := fmt.Sprintf(`{"models": {"providers": {"p1": {"baseUrl": "%s", "models": []}}}}`, srv.URL)
+	require.NoError(t, os.WriteFile(configPath, []byte(initialConfig), 0644))
+
+	_ = os.Setenv("LITECLAW_CONFIG_PATH", configPath)
+	_ = os.Setenv("LITECLAW_STATE_DIR", tempDir)
+	defer func() { _ = os.Unsetenv("LITECLAW_CONFIG_PATH") }()
+	defer func() { _ = os.Unsetenv("LITECLAW_STATE_DIR") }()
+
+	cmd := newModelsScanCommand()
+	b := bytes.NewBufferString("")
+	cmd.SetOut(b)
+	cmd.SetArgs([]string{"p1", "--no-probe", "--no-write", "--json"})
+	require.NoError(t, cmd.Execute())
+	assert.Contains(t, b.String(), `"id": "m1"`)
+
+	data, _ := os.ReadFile(configPath)
+	assert.NotContains(t, string(data), `"m1"`)
+
+	// --json without --yes neither prompts nor writes.
+	cmd = newModelsScanCommand()
+	b.Reset()
+	cmd.SetOut(b)
+	cmd.SetIn(bytes.NewBufferString("y\n"))
+	cmd.SetArgs([]string{"p1", "--no-probe", "--json"})
+	require.NoError(t, cmd.Execute())
+	var parsed map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &parsed), "stdout holds only the JSON document")
+
+	data, _ = os.ReadFile(configPath)
+	assert.NotContains(t, string(data), `"m1"`)
+}
+
+func TestModelsAuthProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "liteclaw.json")
+	initialConfig := `{
+		"models": {"providers": {"p1": {"baseUrl": "http://p1.local", "models": [{"id": "m1"}]}}},
+		"agents": {"defaults": {"model": {"primary": "p1/m1"}}}
+	}`
+	require.NoError(t, os.WriteFile(configPath, []byte(initialConfig), 0644))
+	t.Setenv("LITECLAW_CONFIG_PATH", configPath)
+	t.Setenv("LITECLAW_STATE_DIR", tempDir)
+
+	addCmd := newModelsAuthAddCommand()
+	addCmd.SetOut(bytes.NewBufferString(""))
+	addCmd.SetIn(bytes.NewBufferString("sk-one\n"))
+	addCmd.SetArgs([]string{"p1:one", "--provider", "p1"})
+	require.NoError(t, addCmd.Execute())
+
+	addCmd = newModelsAuthAddCommand()
+	addCmd.SetOut(bytes.NewBufferString(""))
+	addCmd.SetArgs([]string{"p1:team", "--provider", "p1", "--mode", "env", "--env", "P1_TEAM_KEY"})
+	require.NoError(t, addCmd.Execute())
+
+	pinCmd := newModelsAuthPinCommand()
+	pinCmd.SetOut(bytes.NewBufferString(""))
+	pinCmd.SetArgs([]string{"p1:one"})
+	require.NoError(t, pinCmd.Execute())
+
+	// Usage recorded by a running gateway.
+	state := `{"p1:one": {"provider": "p1", "requests": 4, "errors": 1, "rateLimits": 1, "lastError": "p1 API error: 429 Too Many Requests"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "auth-state.json"), []byte(state), 0600))
+
+	statusCmd := newModelsStatusCommand()
+	b := bytes.NewBufferString("")
+	statusCmd.SetOut(b)
+	statusCmd.SetArgs([]string{"--json"})
+	require.NoError(t, statusCmd.Execute())
+
+	var status struct {
+		AuthProfiles []authProfileStatus `json:"authProfiles"`
+	}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &status))
+	require.Len(t, status.AuthProfiles, 2)
+
+	one := status.AuthProfiles[0]
+	assert.Equal(t, "p1:one", one.ID)
+	assert.True(t, one.HasSecret)
+	assert.True(t, one.Pinned)
+	assert.Equal(t, int64(4), one.Requests)
+	assert.Equal(t, int64(1), one.RateLimits)
+
+	team := status.AuthProfiles[1]
+	assert.Equal(t, "p1:team", team.ID)
+	assert.Equal(t, "env", team.Mode)
+	assert.False(t, team.HasSecret)
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "sk-one", cfg.Auth.Profiles["p1:one"].APIKey)
+	assert.Equal(t, "p1:one", cfg.Agents.Defaults.AuthProfiles["p1"])
+}
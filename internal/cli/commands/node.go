@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/config"
+	"github.com/liteclaw/liteclaw/internal/gateway"
+	"github.com/liteclaw/liteclaw/internal/node"
+	"github.com/liteclaw/liteclaw/internal/version"
+)
+
+// NewNodeCommand creates the node command.
+func NewNodeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "node",
+		Short: "Run this machine as a node, or use the Gateway's nodes",
+		Long: `Nodes are machines connected to the Gateway that run commands for the agent
+(system.run, camera, screen, location, canvas). A node must be approved once with
+'liteclaw pairing approve node <code>' on the Gateway host.`,
+		Example: `  liteclaw node run --url ws://gateway.local:18789 --name studio-mac
+  liteclaw node list
+  liteclaw node invoke studio-mac system.run --params '{"command":"uptime"}'`,
+	}
+
+	cmd.AddCommand(newNodeRunCommand())
+	cmd.AddCommand(newNodeListCommand())
+	cmd.AddCommand(newNodeInvokeCommand())
+
+	return cmd
+}
+
+func newNodeRunCommand() *cobra.Command {
+	var gatewayURL, token, name string
+	var commands []string
+
+	cmd := &cobra.Command{
+		Use:   "run",
+		Short: "Connect this machine to a Gateway as a node",
+		Example: `  liteclaw node run
+  liteclaw node run --url ws://192.168.1.10:18789 --token <TOKEN>
+  liteclaw node run --commands system.run,screen.snapshot`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if gatewayURL == "" {
+				port := 18789
+				if cfg, err := config.Load(); err == nil && cfg.Gateway.Port > 0 {
+					port = cfg.Gateway.Port
+				}
+				gatewayURL = fmt.Sprintf("ws://127.0.0.1:%d", port)
+			}
+			if token == "" {
+				token, _ = gateway.LoadClawdbotToken()
+			}
+			if name == "" {
+				name, _ = os.Hostname()
+			}
+
+			identity, err := gateway.LoadOrCreateDeviceIdentity(nodeIdentityPath())
+			if err != nil {
+				return fmt.Errorf("failed to load node identity: %w", err)
+			}
+
+			host := node.NewHost(commands)
+			cmd.Printf("Node %s (%s)\n", name, identity.ID[:12])
+			cmd.Printf("Commands: %s\n", strings.Join(host.Commands(), ", "))
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			return host.Run(ctx, node.Options{
+				URL:         gatewayURL,
+				Token:       token,
+				DisplayName: name,
+				Version:     version.Version,
+				Identity:    identity,
+				Logf: func(format string, args ...interface{}) {
+					cmd.Printf("[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+				},
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&gatewayURL, "url", "", "Gateway WebSocket URL (default: ws://127.0.0.1:<gateway.port>)")
+	cmd.Flags().StringVar(&token, "token", "", "Gateway authentication token")
+	cmd.Flags().StringVar(&name, "name", "", "Display name (default: hostname)")
+	cmd.Flags().StringSliceVar(&commands, "commands", nil, "Only offer these commands")
+
+	return cmd
+}
+
+// nodeIdentityPath is where this machine's node device key is kept.
+func nodeIdentityPath() string {
+	return filepath.Join(config.StateDir(), "node", "device.json")
+}
+
+func newNodeListCommand() *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List nodes connected to the Gateway",
+		Example: `  liteclaw node list --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var payload struct {
+				Nodes []tools.NodeInfo `json:"nodes"`
+			}
+			if err := callGatewayAPI("GET", "/nodes", nil, &payload); err != nil {
+				return fmt.Errorf("failed to list nodes: %w", err)
+			}
+
+			if jsonOut {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(payload)
+			}
+
+			if len(payload.Nodes) == 0 {
+				cmd.Println("No nodes connected.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tNAME\tPLATFORM\tCONNECTED\tCOMMANDS")
+			for _, n := range payload.Nodes {
+				id := n.ID
+				if len(id) > 12 {
+					id = id[:12]
+				}
+				connected := "-"
+				if n.ConnectedAt > 0 {
+					connected = time.UnixMilli(n.ConnectedAt).Format("01-02 15:04:05")
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", id, n.Name, n.Platform, connected, strings.Join(n.Commands, ","))
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output JSON")
+	return cmd
+}
+
+func newNodeInvokeCommand() *cobra.Command {
+	var paramsJSON string
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
+		Use:     "invoke <node> <command>",
+		Short:   "Run a command on a node",
+		Example: `  liteclaw node invoke studio-mac system.run --params '{"command":["ls","-la"]}'`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := map[string]interface{}{}
+			if paramsJSON != "" {
+				if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+					return fmt.Errorf("invalid --params: %w", err)
+				}
+			}
+			body := map[string]interface{}{
+				"nodeId":    args[0],
+				"command":   args[1],
+				"params":    params,
+				"timeoutMs": timeout.Milliseconds(),
+			}
+			var out map[string]interface{}
+			if err := callGatewayAPI("POST", "/nodes/invoke", body, &out); err != nil {
+				return fmt.Errorf("invoke failed: %w", err)
+			}
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(out["payload"])
+		},
+	}
+
+	cmd.Flags().StringVar(&paramsJSON, "params", "", "Command parameters as JSON")
+	cmd.Flags().DurationVar(&timeout, "timeout", gateway.DefaultNodeInvokeTimeout, "How long to wait for the node")
+	return cmd
+}
@@ -11,7 +11,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var knownPairingChannels = []string{"telegram", "discord", "imessage", "signal", "whatsapp", "slack"}
+var knownPairingChannels = []string{"telegram", "discord", "imessage", "signal", "whatsapp", "slack", "node"}
 
 func NewPairingCommand() *cobra.Command {
 	cmd := &cobra.Command{
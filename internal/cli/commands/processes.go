@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/spf13/cobra"
+)
+
+// NewProcessCommand creates the process command.
+func NewProcessCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "process",
+		Short: "Inspect background process sessions (via Gateway)",
+		Long:  `List and read background processes started by the agent's exec tool. Requires Gateway to be running.`,
+		Example: `  liteclaw process list
+  liteclaw process log proc_abc123`,
+	}
+
+	cmd.AddCommand(newProcessListCommand())
+	cmd.AddCommand(newProcessLogCommand())
+
+	return cmd
+}
+
+func newProcessListCommand() *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List background processes",
+		Example: `  liteclaw process list --json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var payload tools.ProcessListResult
+			if err := callGatewayAPI("GET", "/processes", nil, &payload); err != nil {
+				return fmt.Errorf("failed to list processes: %w", err)
+			}
+
+			if jsonOut {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(payload)
+			}
+
+			if len(payload.Processes) == 0 {
+				cmd.Println("No background processes.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tPID\tSTATUS\tSESSION\tSTARTED\tOUTPUT\tCOMMAND")
+			for _, p := range payload.Processes {
+				status := p.Status
+				if p.Status != tools.ProcessRunning && p.Status != tools.ProcessKilled {
+					status = fmt.Sprintf("%s (%d)", p.Status, p.ExitCode)
+				}
+				if p.PTY {
+					status += " pty"
+				}
+				started := p.StartedAt
+				if ts, err := time.Parse(time.RFC3339, p.StartedAt); err == nil {
+					started = ts.Format("01-02 15:04:05")
+				}
+				session := p.SessionKey
+				if session == "" {
+					session = "-"
+				}
+				command := p.Command
+				if len(command) > 60 {
+					command = command[:57] + "..."
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%dB\t%s\n",
+					p.ID, p.PID, status, session, started, p.OutputBytes, command)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Output JSON")
+	return cmd
+}
+
+func newProcessLogCommand() *cobra.Command {
+	var offset int64
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:     "log <id>",
+		Short:   "Print a background process's output",
+		Example: `  liteclaw process log proc_abc123 --offset 1024`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := fmt.Sprintf("/processes/%s/log?offset=%d&limit=%d", url.PathEscape(args[0]), offset, limit)
+			var out tools.ProcessOutput
+			if err := callGatewayAPI("GET", path, nil, &out); err != nil {
+				return fmt.Errorf("failed to read process log: %w", err)
+			}
+			if out.Skipped > 0 {
+				cmd.PrintErrf("[%d bytes no longer available]\n", out.Skipped)
+			}
+			cmd.Print(out.Data)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64Var(&offset, "offset", 0, "Byte offset to start reading from")
+	cmd.Flags().IntVar(&limit, "limit", 64*1024, "Maximum bytes to print")
+	return cmd
+}
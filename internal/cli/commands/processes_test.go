@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessListCommand(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/processes", r.URL.Path)
+		_ = json.NewEncoder(w).Encode(tools.ProcessListResult{
+			Processes: []tools.ProcessInfo{{
+				ID:          "proc_1",
+				Command:     "npm run dev",
+				PID:         4242,
+				Status:      tools.ProcessRunning,
+				SessionKey:  "telegram:123",
+				PTY:         true,
+				OutputBytes: 2048,
+				StartedAt:   "2026-01-02T03:04:05Z",
+			}},
+			Count: 1,
+		})
+	}))
+	defer server.Close()
+
+	port := strings.Split(strings.TrimPrefix(server.URL, "http://"), ":")[1]
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "liteclaw.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(fmt.Sprintf(`{"gateway":{"port":%s}}`, port)), 0644))
+	t.Setenv("LITECLAW_CONFIG_PATH", configPath)
+	t.Setenv("LITECLAW_STATE_DIR", tempDir)
+
+	cmd := newProcessListCommand()
+	var out strings.Builder
+	cmd.SetOut(&out)
+	require.NoError(t, cmd.Execute())
+
+	assert.Contains(t, out.String(), "proc_1")
+	assert.Contains(t, out.String(), "running pty")
+	assert.Contains(t, out.String(), "telegram:123")
+	assert.Contains(t, out.String(), "npm run dev")
+}
@@ -56,6 +56,8 @@ func init() {
 	rootCmd.AddCommand(commands.NewAgentCommand())
 	rootCmd.AddCommand(commands.NewLogsCommand())
 	rootCmd.AddCommand(commands.NewCronCommand())
+	rootCmd.AddCommand(commands.NewProcessCommand())
+	rootCmd.AddCommand(commands.NewNodeCommand())
 
 	// Global flags
 	rootCmd.PersistentFlags().StringP("config", "c", "", "config file (default is ~/.liteclaw/liteclaw.json)")
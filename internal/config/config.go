@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/liteclaw/liteclaw/internal/agent/customtool"
+	"github.com/liteclaw/liteclaw/internal/agent/memory"
 	"github.com/liteclaw/liteclaw/internal/agent/policy"
+	"github.com/liteclaw/liteclaw/internal/agent/workspace"
 	"github.com/spf13/viper"
 )
 
@@ -31,6 +35,7 @@ type Config struct {
 	Gateway  GatewayConfig     `json:"gateway" yaml:"gateway" mapstructure:"gateway"`
 	Skills   SkillsConfig      `json:"skills" yaml:"skills" mapstructure:"skills"`
 	Plugins  PluginsConfig     `json:"plugins" yaml:"plugins" mapstructure:"plugins"`
+	Tools    ToolsConfig       `json:"tools" yaml:"tools" mapstructure:"tools"`
 	Logging  LoggingConfig     `json:"logging" yaml:"logging" mapstructure:"logging"`
 }
 
@@ -49,11 +54,27 @@ type WizardConfig struct {
 
 type AuthConfig struct {
 	Profiles map[string]AuthProfile `json:"profiles" yaml:"profiles" mapstructure:"profiles"`
-}
+	// Order lists profile IDs per provider in rotation order. Profiles not
+	// listed are tried afterwards, sorted by ID.
+	Order map[string][]string `json:"order" yaml:"order" mapstructure:"order"`
+	// CooldownSeconds is the base cooldown applied to a profile after a 429.
+	CooldownSeconds int `json:"cooldownSeconds" yaml:"cooldownSeconds" mapstructure:"cooldownSeconds"`
+}
+
+// Auth profile modes.
+const (
+	AuthModeAPIKey = "api-key"
+	AuthModeOAuth  = "oauth"
+	AuthModeEnv    = "env"
+)
 
 type AuthProfile struct {
 	Provider string `json:"provider" yaml:"provider" mapstructure:"provider"`
 	Mode     string `json:"mode" yaml:"mode" mapstructure:"mode"`
+	APIKey   string `json:"apiKey" yaml:"apiKey" mapstructure:"apiKey"`
+	Token    string `json:"token" yaml:"token" mapstructure:"token"`
+	// Env names the environment variable holding the key (mode "env").
+	Env string `json:"env" yaml:"env" mapstructure:"env"`
 }
 
 type ModelsConfig struct {
@@ -86,6 +107,56 @@ type ModelCost struct {
 
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults" yaml:"defaults" mapstructure:"defaults"`
+	// List overrides the defaults for individual agents: "main" is the
+	// gateway's agent, other IDs are sub-agents spawned with that agentId.
+	List []AgentConfig `json:"list,omitempty" yaml:"list,omitempty" mapstructure:"list"`
+}
+
+// AgentConfig holds per-agent overrides of agents.defaults.
+type AgentConfig struct {
+	ID string `json:"id" yaml:"id" mapstructure:"id"`
+	// Sandbox replaces agents.defaults.sandbox for this agent.
+	Sandbox *SandboxConfig `json:"sandbox,omitempty" yaml:"sandbox,omitempty" mapstructure:"sandbox"`
+	// Paths replaces agents.defaults.paths for this agent.
+	Paths *PathsConfig `json:"paths,omitempty" yaml:"paths,omitempty" mapstructure:"paths"`
+}
+
+// SandboxConfig isolates exec commands; it mirrors sandbox.Config.
+type SandboxConfig struct {
+	// Mode is "off" (default), "non-main" (sandbox every session except the
+	// main one) or "all".
+	Mode string `json:"mode" yaml:"mode" mapstructure:"mode"`
+	// SessionTypes, when set, lists the session types to sandbox
+	// (main, direct, group, cron, subagent) and takes precedence over Mode.
+	SessionTypes []string `json:"sessionTypes" yaml:"sessionTypes" mapstructure:"sessionTypes"`
+	// Backend is "auto" (default), "bwrap", "podman" or "docker".
+	Backend string `json:"backend" yaml:"backend" mapstructure:"backend"`
+	// Network allows network access from inside the sandbox.
+	Network bool `json:"network" yaml:"network" mapstructure:"network"`
+	// Image is the container image for podman/docker.
+	Image string `json:"image" yaml:"image" mapstructure:"image"`
+	// CPUs limits CPU share.
+	CPUs float64 `json:"cpus" yaml:"cpus" mapstructure:"cpus"`
+	// MemoryMB limits memory.
+	MemoryMB int `json:"memoryMb" yaml:"memoryMb" mapstructure:"memoryMb"`
+	// PidsLimit limits the number of processes; under bwrap it counts every
+	// process the host user runs.
+	PidsLimit int `json:"pidsLimit" yaml:"pidsLimit" mapstructure:"pidsLimit"`
+	// TimeoutSeconds caps the wall-clock time of a sandboxed command.
+	TimeoutSeconds int `json:"timeoutSeconds" yaml:"timeoutSeconds" mapstructure:"timeoutSeconds"`
+	// ReadOnlyPaths are extra host paths made visible read-only.
+	ReadOnlyPaths []string `json:"readOnlyPaths" yaml:"readOnlyPaths" mapstructure:"readOnlyPaths"`
+}
+
+// PathsConfig confines the file tools; it mirrors pathguard.Config.
+type PathsConfig struct {
+	// ReadRoots are extra directories the agent may read.
+	ReadRoots []string `json:"readRoots" yaml:"readRoots" mapstructure:"readRoots"`
+	// WriteRoots are extra directories the agent may write. Write roots are
+	// also readable.
+	WriteRoots []string `json:"writeRoots" yaml:"writeRoots" mapstructure:"writeRoots"`
+	// Deny lists extra paths that must never be accessed.
+	Deny []string `json:"deny" yaml:"deny" mapstructure:"deny"`
 }
 
 type AgentDefaults struct {
@@ -98,6 +169,16 @@ type AgentDefaults struct {
 	Subagents     SubagentsConfig          `json:"subagents" yaml:"subagents" mapstructure:"subagents"`
 	Stream        bool                     `json:"stream" yaml:"stream" mapstructure:"stream"`
 	ShowThinking  bool                     `json:"showThinking" yaml:"showThinking" mapstructure:"showThinking"`
+	// AuthProfiles pins a provider to one auth profile (provider -> profile ID).
+	AuthProfiles map[string]string `json:"authProfiles" yaml:"authProfiles" mapstructure:"authProfiles"`
+	// Sandbox isolates exec commands for selected session types.
+	Sandbox SandboxConfig `json:"sandbox" yaml:"sandbox" mapstructure:"sandbox"`
+	// Paths confines the file tools to the workspace plus extra read/write roots.
+	Paths PathsConfig `json:"paths" yaml:"paths" mapstructure:"paths"`
+	// Memory controls memory_search indexing and ranking.
+	Memory memory.Config `json:"memory" yaml:"memory" mapstructure:"memory"`
+	// Git tracks the workspace in git and commits each run's edits.
+	Git workspace.GitConfig `json:"git" yaml:"git" mapstructure:"git"`
 }
 
 type AgentModelConfig struct {
@@ -110,6 +191,25 @@ type AgentModelMap struct {
 
 type CompactionConfig struct {
 	Mode string `json:"mode" yaml:"mode" mapstructure:"mode"`
+	// MaxHistoryChars compacts a session once its history exceeds this many
+	// characters (default: 200000): after the run that crossed it, the
+	// oldest messages are summarised in the background and the summary
+	// replaces them at the start of the next run. Mode "off" disables
+	// compaction.
+	MaxHistoryChars int `json:"maxHistoryChars,omitempty" yaml:"maxHistoryChars,omitempty" mapstructure:"maxHistoryChars"`
+	// MemoryFlush asks the model to persist durable facts before compaction
+	// and when a session goes idle.
+	MemoryFlush MemoryFlushConfig `json:"memoryFlush" yaml:"memoryFlush" mapstructure:"memoryFlush"`
+}
+
+type MemoryFlushConfig struct {
+	// Enabled turns on memory flushes (default: false).
+	Enabled bool `json:"enabled" yaml:"enabled" mapstructure:"enabled"`
+	// IdleMinutes is the inactivity after which a session is flushed
+	// (default: 30, negative disables idle flushes).
+	IdleMinutes int `json:"idleMinutes,omitempty" yaml:"idleMinutes,omitempty" mapstructure:"idleMinutes"`
+	// Prompt replaces the built-in flush instruction.
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty" mapstructure:"prompt"`
 }
 
 type SubagentsConfig struct {
@@ -117,6 +217,213 @@ type SubagentsConfig struct {
 	Tools         policy.ToolPolicy `json:"tools" yaml:"tools" mapstructure:"tools"`
 }
 
+// ToolsConfig configures individual agent tools.
+type ToolsConfig struct {
+	Web WebToolsConfig `json:"web" yaml:"web" mapstructure:"web"`
+	TTS TTSConfig      `json:"tts" yaml:"tts" mapstructure:"tts"`
+	// Transcription turns inbound voice messages into text.
+	Transcription TranscriptionConfig `json:"transcription" yaml:"transcription" mapstructure:"transcription"`
+	Image         ImageToolConfig     `json:"image" yaml:"image" mapstructure:"image"`
+	// ImageGenerate configures the image_generate tool.
+	ImageGenerate ImageGenerateConfig `json:"imageGenerate" yaml:"imageGenerate" mapstructure:"imageGenerate"`
+	Browser       BrowserToolConfig   `json:"browser" yaml:"browser" mapstructure:"browser"`
+	// Gateway configures the agent's gateway tool.
+	Gateway GatewayToolConfig `json:"gateway" yaml:"gateway" mapstructure:"gateway"`
+	// Custom declares command and HTTP tools without writing Go.
+	Custom []customtool.Spec `json:"custom,omitempty" yaml:"custom,omitempty" mapstructure:"custom"`
+}
+
+// GatewayToolConfig configures the gateway tool. Reading status, config
+// and the schema is always allowed; changes must be enabled.
+type GatewayToolConfig struct {
+	// AllowChanges lets the agent patch config, reload and restart the
+	// gateway.
+	AllowChanges bool `json:"allowChanges,omitempty" yaml:"allowChanges,omitempty" mapstructure:"allowChanges"`
+}
+
+// BrowserToolConfig configures the browser tool's profiles. Managed
+// profiles drive a local headless Chromium (or one attached over CDP);
+// extension profiles go through the Chrome extension relay.
+type BrowserToolConfig struct {
+	// DefaultProfile is used when a call names none (default: liteclaw,
+	// the managed browser).
+	DefaultProfile string `json:"defaultProfile,omitempty" yaml:"defaultProfile,omitempty" mapstructure:"defaultProfile"`
+	// ExecutablePath overrides Chromium discovery.
+	ExecutablePath string `json:"executablePath,omitempty" yaml:"executablePath,omitempty" mapstructure:"executablePath"`
+	// Headless runs managed browsers without a window (default: true).
+	Headless *bool `json:"headless,omitempty" yaml:"headless,omitempty" mapstructure:"headless"`
+	// TimeoutSeconds bounds each browser action (default: 30).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty" mapstructure:"timeoutSeconds"`
+	// Profiles adds profiles or overrides the built-in liteclaw one.
+	Profiles map[string]BrowserProfileConfig `json:"profiles,omitempty" yaml:"profiles,omitempty" mapstructure:"profiles"`
+	// AllowPrivate lists hosts, IPs or CIDRs the browser may open even
+	// though they are private, loopback or link-local.
+	AllowPrivate []string `json:"allowPrivate,omitempty" yaml:"allowPrivate,omitempty" mapstructure:"allowPrivate"`
+}
+
+// BrowserProfileConfig configures one browser profile.
+type BrowserProfileConfig struct {
+	// Driver is managed (default) or extension.
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty" mapstructure:"driver"`
+	// CDPURL attaches to a running browser instead of launching one.
+	CDPURL         string `json:"cdpUrl,omitempty" yaml:"cdpUrl,omitempty" mapstructure:"cdpUrl"`
+	Headless       *bool  `json:"headless,omitempty" yaml:"headless,omitempty" mapstructure:"headless"`
+	ExecutablePath string `json:"executablePath,omitempty" yaml:"executablePath,omitempty" mapstructure:"executablePath"`
+}
+
+// ImageToolConfig configures the image (vision) tool.
+type ImageToolConfig struct {
+	// Model is the vision model as "provider/model" or an alias. Empty uses
+	// the primary model when its entry lists "image" input.
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+	// MaxBytes caps each downloaded or local image (default: 20 MiB).
+	MaxBytes int64 `json:"maxBytes,omitempty" yaml:"maxBytes,omitempty" mapstructure:"maxBytes"`
+	// MaxDimension is the longest side sent to the model (default: 2048).
+	MaxDimension int `json:"maxDimension,omitempty" yaml:"maxDimension,omitempty" mapstructure:"maxDimension"`
+	// MaxImages caps the images per call (default: 8).
+	MaxImages int `json:"maxImages,omitempty" yaml:"maxImages,omitempty" mapstructure:"maxImages"`
+}
+
+// ImageGenerateConfig configures an OpenAI-compatible /images endpoint for
+// image_generate. The key falls back to the models.providers entry named by
+// Provider, then OPENAI_API_KEY.
+type ImageGenerateConfig struct {
+	APIKey   string `json:"apiKey,omitempty" yaml:"apiKey,omitempty" mapstructure:"apiKey"`
+	BaseURL  string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty" mapstructure:"baseUrl"`
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Model defaults to gpt-image-1.
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+	// Size is the default size, e.g. 1024x1024 or auto.
+	Size string `json:"size,omitempty" yaml:"size,omitempty" mapstructure:"size"`
+	// Quality is the default quality (low, medium, high; standard or hd
+	// for dall-e-3).
+	Quality string `json:"quality,omitempty" yaml:"quality,omitempty" mapstructure:"quality"`
+	// TimeoutSeconds bounds one request (default: 180).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty" mapstructure:"timeoutSeconds"`
+}
+
+// TTSConfig configures the tts tool and spoken replies.
+type TTSConfig struct {
+	// Engine is openai or command (default: openai).
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty" mapstructure:"engine"`
+	// Voice is the default voice.
+	Voice string `json:"voice,omitempty" yaml:"voice,omitempty" mapstructure:"voice"`
+	// Format is the default container (default: mp3 for openai, wav for
+	// command).
+	Format string `json:"format,omitempty" yaml:"format,omitempty" mapstructure:"format"`
+	// Auto speaks replies as voice notes on channels that support them:
+	// off (default), always, or inbound to answer voice messages in kind.
+	Auto string `json:"auto,omitempty" yaml:"auto,omitempty" mapstructure:"auto"`
+	// MaxChars is the longest reply auto-TTS will speak (default: 1500).
+	MaxChars int              `json:"maxChars,omitempty" yaml:"maxChars,omitempty" mapstructure:"maxChars"`
+	OpenAI   TTSOpenAIConfig  `json:"openai" yaml:"openai" mapstructure:"openai"`
+	Command  TTSCommandConfig `json:"command" yaml:"command" mapstructure:"command"`
+}
+
+// TTSOpenAIConfig configures an OpenAI-compatible /audio/speech endpoint.
+// The key falls back to the models.providers entry named by Provider, then
+// OPENAI_API_KEY.
+type TTSOpenAIConfig struct {
+	APIKey   string `json:"apiKey,omitempty" yaml:"apiKey,omitempty" mapstructure:"apiKey"`
+	BaseURL  string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty" mapstructure:"baseUrl"`
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Model defaults to gpt-4o-mini-tts.
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+}
+
+// TTSCommandConfig runs a local synthesiser such as piper or sherpa-onnx.
+// Args may use {text}, {output}, {voice}, {format} and {speed}.
+type TTSCommandConfig struct {
+	Command string   `json:"command,omitempty" yaml:"command,omitempty" mapstructure:"command"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty" mapstructure:"args"`
+	// Format is the container the command writes (default: wav).
+	Format string            `json:"format,omitempty" yaml:"format,omitempty" mapstructure:"format"`
+	Env    map[string]string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env"`
+	// TimeoutSeconds bounds one synthesis (default: 120).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty" mapstructure:"timeoutSeconds"`
+}
+
+// TranscriptionConfig selects the speech-to-text engine for inbound audio.
+type TranscriptionConfig struct {
+	// Engine is openai or command; empty leaves transcription off.
+	Engine string `json:"engine,omitempty" yaml:"engine,omitempty" mapstructure:"engine"`
+	// Language is an optional ISO-639-1 hint.
+	Language string                     `json:"language,omitempty" yaml:"language,omitempty" mapstructure:"language"`
+	OpenAI   TranscriptionOpenAIConfig  `json:"openai" yaml:"openai" mapstructure:"openai"`
+	Command  TranscriptionCommandConfig `json:"command" yaml:"command" mapstructure:"command"`
+}
+
+// TranscriptionOpenAIConfig configures an OpenAI-compatible
+// /audio/transcriptions endpoint. The key falls back to the
+// models.providers entry named by Provider, then OPENAI_API_KEY.
+type TranscriptionOpenAIConfig struct {
+	APIKey   string `json:"apiKey,omitempty" yaml:"apiKey,omitempty" mapstructure:"apiKey"`
+	BaseURL  string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty" mapstructure:"baseUrl"`
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Model defaults to whisper-1.
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+	// Prompt biases recognition towards names and jargon.
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty" mapstructure:"prompt"`
+}
+
+// TranscriptionCommandConfig runs a local recogniser such as whisper.cpp.
+// Args may use {input}, {output} and {language}.
+type TranscriptionCommandConfig struct {
+	Command string            `json:"command,omitempty" yaml:"command,omitempty" mapstructure:"command"`
+	Args    []string          `json:"args,omitempty" yaml:"args,omitempty" mapstructure:"args"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env,omitempty" mapstructure:"env"`
+	// TimeoutSeconds bounds one transcription (default: 300).
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty" yaml:"timeoutSeconds,omitempty" mapstructure:"timeoutSeconds"`
+}
+
+type WebToolsConfig struct {
+	Fetch  WebFetchConfig  `json:"fetch" yaml:"fetch" mapstructure:"fetch"`
+	Search WebSearchConfig `json:"search" yaml:"search" mapstructure:"search"`
+}
+
+// WebSearchConfig selects web_search backends.
+type WebSearchConfig struct {
+	// Provider is the primary backend: brave, searxng, tavily or model
+	// (default: brave).
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Fallback lists backends tried in order when the primary fails.
+	Fallback []string `json:"fallback,omitempty" yaml:"fallback,omitempty" mapstructure:"fallback"`
+	// Count is the default number of results (default: 5).
+	Count   int                 `json:"count,omitempty" yaml:"count,omitempty" mapstructure:"count"`
+	Brave   SearchBackendConfig `json:"brave" yaml:"brave" mapstructure:"brave"`
+	SearXNG SearchBackendConfig `json:"searxng" yaml:"searxng" mapstructure:"searxng"`
+	Tavily  SearchBackendConfig `json:"tavily" yaml:"tavily" mapstructure:"tavily"`
+	Model   SearchBackendConfig `json:"model" yaml:"model" mapstructure:"model"`
+}
+
+// SearchBackendConfig holds per-backend options. Keys fall back to
+// BRAVE_API_KEY / TAVILY_API_KEY; the model backend can borrow baseUrl and
+// key from a models.providers entry.
+type SearchBackendConfig struct {
+	APIKey  string `json:"apiKey,omitempty" yaml:"apiKey,omitempty" mapstructure:"apiKey"`
+	BaseURL string `json:"baseUrl,omitempty" yaml:"baseUrl,omitempty" mapstructure:"baseUrl"`
+	// Provider is a models.providers key (model backend only).
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty" mapstructure:"provider"`
+	// Model is the search model (model backend only).
+	Model string `json:"model,omitempty" yaml:"model,omitempty" mapstructure:"model"`
+	// Params are extra backend parameters, e.g. SearXNG engines or Tavily
+	// search_depth.
+	Params map[string]string `json:"params,omitempty" yaml:"params,omitempty" mapstructure:"params"`
+}
+
+type WebFetchConfig struct {
+	// MaxBytes caps the downloaded body (default: 2 MiB).
+	MaxBytes int64 `json:"maxBytes,omitempty" yaml:"maxBytes,omitempty" mapstructure:"maxBytes"`
+	// MaxChars is the default page size returned per call (default: 50000).
+	MaxChars int `json:"maxChars,omitempty" yaml:"maxChars,omitempty" mapstructure:"maxChars"`
+	// CacheTTLMinutes is how long extracted pages are cached (default: 15,
+	// negative disables the cache).
+	CacheTTLMinutes int `json:"cacheTtlMinutes,omitempty" yaml:"cacheTtlMinutes,omitempty" mapstructure:"cacheTtlMinutes"`
+	// AllowPrivate lists hosts, IPs or CIDRs that may be fetched even though
+	// they are private, loopback or link-local.
+	AllowPrivate []string `json:"allowPrivate,omitempty" yaml:"allowPrivate,omitempty" mapstructure:"allowPrivate"`
+}
+
 type MessagesConfig struct {
 	AckReactionScope string `json:"ackReactionScope" yaml:"ackReactionScope" mapstructure:"ackReactionScope"`
 }
@@ -411,6 +718,9 @@ func Load() (*Config, error) {
 	if err := v.Unmarshal(&cfg); err != nil {
 		return nil, err
 	}
+	if err := loadCustomTools(&cfg, v.ConfigFileUsed()); err != nil {
+		return nil, err
+	}
 
 	// COMPATIBILITY: Inject config.env block into the OS environment FIRST.
 	// This happens before 'expandEnvVars' so that Expansion works correctly.
@@ -431,6 +741,32 @@ func Load() (*Config, error) {
 	return &cfg, nil
 }
 
+// loadCustomTools decodes tools.custom straight from a JSON config file.
+// Viper lowercases map keys, which would break parameter names and
+// environment variable names in custom tool specs.
+func loadCustomTools(cfg *Config, path string) error {
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		return nil
+	}
+	doc, err := ReadRaw(path)
+	if err != nil {
+		return err
+	}
+	raw, ok := Lookup(doc, "tools.custom")
+	if !ok {
+		return nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	cfg.Tools.Custom = nil
+	if err := json.Unmarshal(data, &cfg.Tools.Custom); err != nil {
+		return fmt.Errorf("tools.custom: %w", err)
+	}
+	return nil
+}
+
 // setDefaults sets default configuration values.
 func setDefaults(v *viper.Viper) {
 	// Gateway defaults
@@ -459,6 +795,22 @@ func expandEnvVars(cfg *Config) {
 
 	// Expand env vars in Hooks
 	cfg.Hooks.Internal.Token = os.ExpandEnv(cfg.Hooks.Internal.Token)
+
+	// Expand env vars in auth profile secrets
+	for id, p := range cfg.Auth.Profiles {
+		p.APIKey = os.ExpandEnv(p.APIKey)
+		p.Token = os.ExpandEnv(p.Token)
+		cfg.Auth.Profiles[id] = p
+	}
+
+	// Expand env vars in web search backend keys
+	search := &cfg.Tools.Web.Search
+	for _, b := range []*SearchBackendConfig{&search.Brave, &search.SearXNG, &search.Tavily, &search.Model} {
+		b.APIKey = os.ExpandEnv(b.APIKey)
+	}
+	cfg.Tools.TTS.OpenAI.APIKey = os.ExpandEnv(cfg.Tools.TTS.OpenAI.APIKey)
+	cfg.Tools.Transcription.OpenAI.APIKey = os.ExpandEnv(cfg.Tools.Transcription.OpenAI.APIKey)
+	cfg.Tools.ImageGenerate.APIKey = os.ExpandEnv(cfg.Tools.ImageGenerate.APIKey)
 }
 
 // Save saves the configuration to the config file.
@@ -489,15 +841,104 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("agents.defaults.model.primary is required")
 	}
 
-	parts := strings.SplitN(primaryStr, "/", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid model format '%s'. Expected 'provider/model'", primaryStr)
+	if _, _, err := c.ResolveModelRef(primaryStr); err != nil {
+		return err
 	}
 
-	providerName := parts[0]
-	if _, ok := c.Models.Providers[providerName]; !ok {
-		return fmt.Errorf("provider '%s' is not defined in 'models.providers'", providerName)
+	for provider, id := range c.Agents.Defaults.AuthProfiles {
+		p, ok := c.Auth.Profiles[id]
+		if !ok {
+			return fmt.Errorf("agents.defaults.authProfiles.%s: auth profile '%s' is not defined in 'auth.profiles'", provider, id)
+		}
+		if p.Provider != provider {
+			return fmt.Errorf("agents.defaults.authProfiles.%s: auth profile '%s' belongs to provider '%s'", provider, id, p.Provider)
+		}
 	}
 
+	seen := make(map[string]bool)
+	for i := range c.Tools.Custom {
+		spec := &c.Tools.Custom[i]
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("tools.custom[%d]: %w", i, err)
+		}
+		if seen[spec.Name] {
+			return fmt.Errorf("tools.custom[%d]: duplicate tool name %q", i, spec.Name)
+		}
+		seen[spec.Name] = true
+	}
 	return nil
 }
+
+// ProviderAuthProfiles returns the IDs of the auth profiles configured for a
+// provider in rotation order: auth.order first, then the rest sorted by ID.
+func (c *Config) ProviderAuthProfiles(provider string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range c.Auth.Order[provider] {
+		if p, ok := c.Auth.Profiles[id]; ok && p.Provider == provider && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+
+	var rest []string
+	for id, p := range c.Auth.Profiles {
+		if p.Provider == provider && !seen[id] {
+			rest = append(rest, id)
+		}
+	}
+	sort.Strings(rest)
+	return append(ids, rest...)
+}
+
+// ResolveModelRef resolves a "provider/model" string or an alias defined in
+// agents.defaults.models into a provider name and model ID.
+// An empty ref resolves to agents.defaults.model.primary.
+func (c *Config) ResolveModelRef(ref string) (provider string, model string, err error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		ref = c.Agents.Defaults.Model.Primary
+	}
+	if ref == "" {
+		return "", "", fmt.Errorf("agents.defaults.model.primary is required")
+	}
+
+	if !strings.Contains(ref, "/") {
+		target := ""
+		for key, entry := range c.Agents.Defaults.Models {
+			if entry.Alias != "" && strings.EqualFold(entry.Alias, ref) {
+				target = key
+				break
+			}
+		}
+		if target == "" {
+			return "", "", fmt.Errorf("invalid model format '%s'. Expected 'provider/model' or a model alias", ref)
+		}
+		ref = target
+	}
+
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid model format '%s'. Expected 'provider/model'", ref)
+	}
+
+	if _, ok := c.Models.Providers[parts[0]]; !ok {
+		return "", "", fmt.Errorf("provider '%s' is not defined in 'models.providers'", parts[0])
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// FindModel returns the model entry for a provider/model pair, if configured.
+func (c *Config) FindModel(provider, model string) (ModelEntry, bool) {
+	p, ok := c.Models.Providers[provider]
+	if !ok {
+		return ModelEntry{}, false
+	}
+	for _, m := range p.Models {
+		if m.ID == model {
+			return m, true
+		}
+	}
+	return ModelEntry{}, false
+}
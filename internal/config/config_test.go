@@ -108,6 +108,46 @@ func TestLoadConfigFromFile(t *testing.T) {
 	}
 }
 
+func TestLoadCustomToolsKeepsCase(t *testing.T) {
+	tempDir := t.TempDir()
+	configDir := filepath.Join(tempDir, ".liteclaw")
+	_ = os.MkdirAll(configDir, 0755)
+	configContent := `{
+  "tools": {
+    "custom": [{
+      "name": "deploy",
+      "parameters": {"type": "object", "properties": {"dryRun": {"type": "boolean"}}},
+      "command": "deploy --dry-run={{dryRun}}",
+      "env": {"API_TOKEN": "${DEPLOY_TOKEN}"}
+    }]
+  }
+}`
+	if err := os.WriteFile(filepath.Join(configDir, "liteclaw.json"), []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("HOME", tempDir)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if len(cfg.Tools.Custom) != 1 {
+		t.Fatalf("Expected 1 custom tool, got %d", len(cfg.Tools.Custom))
+	}
+	tool := cfg.Tools.Custom[0]
+	if _, ok := tool.Env["API_TOKEN"]; !ok {
+		t.Errorf("Expected env key API_TOKEN, got %v", tool.Env)
+	}
+	if err := tool.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	cfg.Tools.Custom = append(cfg.Tools.Custom, tool)
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected duplicate custom tool to be rejected")
+	}
+}
+
 func TestExpandEnvVars(t *testing.T) {
 	_ = os.Setenv("TEST_TOKEN", "secret-token-value")
 	defer func() { _ = os.Unsetenv("TEST_TOKEN") }()
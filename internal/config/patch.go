@@ -0,0 +1,379 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RedactedValue replaces secrets in redacted config views.
+const RedactedValue = "__REDACTED__"
+
+// PatchResult describes an applied config patch.
+type PatchResult struct {
+	// Path is the config file that was written.
+	Path string `json:"path"`
+	// BackupPath holds the previous file contents, if there was a file.
+	BackupPath string `json:"backupPath,omitempty"`
+	// Changed lists the dotted paths the patch set or removed.
+	Changed []string `json:"changed"`
+}
+
+// ReadRaw reads the config file at path as a JSON object. A missing file
+// reads as an empty object.
+func ReadRaw(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc := map[string]interface{}{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return doc, nil
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object: %w", path, err)
+	}
+	return doc, nil
+}
+
+// Lookup returns the value at a dotted path (e.g. "gateway.auth.mode") in a
+// raw config document. An empty path returns the whole document.
+func Lookup(doc map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = doc
+	if path == "" {
+		return cur, true
+	}
+	for _, part := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// MergePatch applies a JSON merge patch (RFC 7386) to target: objects merge
+// recursively, null removes a key and anything else replaces the value.
+func MergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	out := make(map[string]interface{}, len(t))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		out[k] = MergePatch(out[k], v)
+	}
+	return out
+}
+
+// patchPaths lists the leaf paths a merge patch touches.
+func patchPaths(prefix string, patch map[string]interface{}) []string {
+	var paths []string
+	for k, v := range patch {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if obj, ok := v.(map[string]interface{}); ok && len(obj) > 0 {
+			paths = append(paths, patchPaths(path, obj)...)
+			continue
+		}
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ApplyPatch validates a JSON merge patch against the config schema,
+// merges it into the config file at path and checks the result with
+// Validate. The previous file is copied to <path>.bak before it is
+// replaced.
+func ApplyPatch(path string, patch map[string]interface{}) (*PatchResult, error) {
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("patch is empty")
+	}
+	if err := ValidatePatch(patch); err != nil {
+		return nil, err
+	}
+
+	doc, err := ReadRaw(path)
+	if err != nil {
+		return nil, err
+	}
+	merged := MergePatch(doc, patch)
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("patched config does not decode: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("patched config is invalid: %w", err)
+	}
+
+	res := &PatchResult{Path: path, Changed: patchPaths("", patch)}
+	if old, err := os.ReadFile(path); err == nil {
+		res.BackupPath = path + ".bak"
+		if err := os.WriteFile(res.BackupPath, old, 0600); err != nil {
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// Schema returns a JSON Schema for liteclaw.json derived from Config.
+func Schema() map[string]interface{} {
+	s := schemaFor(reflect.TypeOf(Config{}))
+	s["$schema"] = "http://json-schema.org/draft-07/schema#"
+	s["title"] = "LiteClaw config"
+	return s
+}
+
+func schemaFor(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaFor(t.Elem())
+	case reflect.Struct:
+		props := map[string]interface{}{}
+		for _, f := range jsonFields(t) {
+			props[f.name] = schemaFor(f.typ)
+		}
+		return map[string]interface{}{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem())}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+type jsonField struct {
+	name string
+	typ  reflect.Type
+}
+
+// jsonFields returns the JSON-visible fields of a struct type, flattening
+// embedded structs as encoding/json does.
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// ValidatePatch checks that every key a merge patch sets exists in the
+// config schema and has the right type. Nulls (removals) are always
+// allowed, and values still set to RedactedValue are rejected so a
+// redacted view cannot overwrite secrets.
+func ValidatePatch(patch map[string]interface{}) error {
+	return checkValue("", patch, reflect.TypeOf(Config{}))
+}
+
+func checkValue(path string, v interface{}, t reflect.Type) error {
+	if v == nil {
+		return nil
+	}
+	at := path
+	if at == "" {
+		at = "config"
+	}
+	mismatch := func(want string) error {
+		return fmt.Errorf("%s: expected %s, got %s", at, want, jsonKind(v))
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return checkValue(path, v, t.Elem())
+	case reflect.Struct:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		fields := map[string]reflect.Type{}
+		for _, f := range jsonFields(t) {
+			fields[f.name] = f.typ
+		}
+		for _, k := range sortedKeys(obj) {
+			ft, ok := fields[k]
+			if !ok {
+				return fmt.Errorf("%s: unknown config key", join(path, k))
+			}
+			if err := checkValue(join(path, k), obj[k], ft); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return mismatch("object")
+		}
+		for _, k := range sortedKeys(obj) {
+			if err := checkValue(join(path, k), obj[k], t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return mismatch("array")
+		}
+		for i, item := range arr {
+			if err := checkValue(fmt.Sprintf("%s[%d]", path, i), item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return mismatch("string")
+		}
+		if s == RedactedValue {
+			return fmt.Errorf("%s: value is redacted; set the real value or leave the key out", at)
+		}
+	case reflect.Bool:
+		if _, ok := v.(bool); !ok {
+			return mismatch("boolean")
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := v.(float64)
+		if !ok || n != float64(int64(n)) {
+			return mismatch("integer")
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := v.(float64); !ok {
+			return mismatch("number")
+		}
+	}
+	return nil
+}
+
+func jsonKind(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretKeys are substrings of config keys, lowercased with _ and -
+// removed, whose string values Redact hides. Keys ending in "key" (apiKey,
+// BRAVE_KEY, X-Api-Key) are hidden too.
+var secretKeys = []string{"token", "secret", "password", "passwd", "authorization", "credential", "cookie"}
+
+// Redact returns a copy of a raw config value with secret strings (API
+// keys, tokens, app secrets) replaced by RedactedValue.
+func Redact(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if s, ok := item.(string); ok && s != "" && isSecretKey(k) {
+				out[k] = RedactedValue
+				continue
+			}
+			out[k] = Redact(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = Redact(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func isSecretKey(key string) bool {
+	key = strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(key))
+	if strings.HasSuffix(key, "key") {
+		return true
+	}
+	for _, s := range secretKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
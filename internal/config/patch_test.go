@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const patchBase = `{
+  "models": {"providers": {"openai": {"baseUrl": "https://api.openai.com/v1", "models": [{"id": "gpt-4o"}]}}},
+  "agents": {"defaults": {"model": {"primary": "openai/gpt-4o"}}},
+  "channels": {"telegram": {"enabled": true, "botToken": "123:abc"}},
+  "custom": {"kept": true}
+}`
+
+func TestApplyPatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "liteclaw.json")
+	if err := os.WriteFile(path, []byte(patchBase), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := ApplyPatch(path, map[string]interface{}{
+		"gateway":  map[string]interface{}{"port": float64(9000)},
+		"channels": map[string]interface{}{"telegram": map[string]interface{}{"enabled": nil}},
+	})
+	if err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if strings.Join(res.Changed, ",") != "channels.telegram.enabled,gateway.port" {
+		t.Errorf("Changed = %v", res.Changed)
+	}
+	if backup, _ := os.ReadFile(res.BackupPath); string(backup) != patchBase {
+		t.Errorf("backup = %q", backup)
+	}
+
+	doc, err := ReadRaw(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Lookup(doc, "gateway.port"); v != float64(9000) {
+		t.Errorf("gateway.port = %v", v)
+	}
+	if _, ok := Lookup(doc, "channels.telegram.enabled"); ok {
+		t.Error("null did not remove channels.telegram.enabled")
+	}
+	if v, _ := Lookup(doc, "custom.kept"); v != true {
+		t.Error("unknown keys already in the file were dropped")
+	}
+}
+
+func TestApplyPatchRejectsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "liteclaw.json")
+	if err := os.WriteFile(path, []byte(patchBase), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		`{"gateway":{"prot":1}}`:                                     "gateway.prot: unknown config key",
+		`{"gateway":{"port":"9000"}}`:                                "gateway.port: expected integer, got string",
+		`{"gateway":{"port":1.5}}`:                                   "gateway.port: expected integer",
+		`{"channels":{"telegram":{"botToken":"__REDACTED__"}}}`:      "value is redacted",
+		`{"agents":{"defaults":{"model":{"primary":"nope/model"}}}}`: "provider 'nope' is not defined",
+	}
+	for raw, want := range cases {
+		var patch map[string]interface{}
+		_ = json.Unmarshal([]byte(raw), &patch)
+		if _, err := ApplyPatch(path, patch); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: err = %v, want %q", raw, err, want)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != patchBase {
+		t.Error("a rejected patch modified the config")
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Error("a rejected patch wrote a backup")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	var doc map[string]interface{}
+	_ = json.Unmarshal([]byte(patchBase), &doc)
+	red := Redact(doc).(map[string]interface{})
+	if v, _ := Lookup(red, "channels.telegram.botToken"); v != RedactedValue {
+		t.Errorf("botToken = %v", v)
+	}
+	if v, _ := Lookup(red, "agents.defaults.model.primary"); v != "openai/gpt-4o" {
+		t.Errorf("primary = %v", v)
+	}
+	if v, _ := Lookup(doc, "channels.telegram.botToken"); v != "123:abc" {
+		t.Error("Redact modified its input")
+	}
+}
+
+func TestRedactEnvAndHeaders(t *testing.T) {
+	var doc map[string]interface{}
+	_ = json.Unmarshal([]byte(`{
+  "mcp": {"servers": {"github": {"env": {"GITHUB_PERSONAL_ACCESS_TOKEN": "ghp_x", "OPENAI_API_KEY": "sk-x", "BRAVE_KEY": "b", "AWS_SHARED_CREDENTIALS_FILE": "/c", "LOG_LEVEL": "debug"}}}},
+  "tools": {"custom": [{"http": {"url": "https://api.example.com", "headers": {"Authorization": "Bearer x", "X-Api-Key": "k", "Cookie": "s=1", "Accept": "application/json"}}}]}
+}`), &doc)
+	red := Redact(doc).(map[string]interface{})
+	for _, path := range []string{
+		"mcp.servers.github.env.GITHUB_PERSONAL_ACCESS_TOKEN",
+		"mcp.servers.github.env.OPENAI_API_KEY",
+		"mcp.servers.github.env.BRAVE_KEY",
+		"mcp.servers.github.env.AWS_SHARED_CREDENTIALS_FILE",
+	} {
+		if v, _ := Lookup(red, path); v != RedactedValue {
+			t.Errorf("%s = %v", path, v)
+		}
+	}
+	if v, _ := Lookup(red, "mcp.servers.github.env.LOG_LEVEL"); v != "debug" {
+		t.Errorf("LOG_LEVEL = %v", v)
+	}
+	headers := red["tools"].(map[string]interface{})["custom"].([]interface{})[0].(map[string]interface{})["http"].(map[string]interface{})["headers"].(map[string]interface{})
+	for _, h := range []string{"Authorization", "X-Api-Key", "Cookie"} {
+		if headers[h] != RedactedValue {
+			t.Errorf("header %s = %v", h, headers[h])
+		}
+	}
+	if headers["Accept"] != "application/json" {
+		t.Errorf("header Accept = %v", headers["Accept"])
+	}
+}
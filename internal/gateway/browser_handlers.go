@@ -2,8 +2,11 @@ package gateway
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
+
+	"github.com/liteclaw/liteclaw/internal/browser"
 )
 
 // handleExtensionRelay handles the WebSocket connection from the browser extension.
@@ -94,6 +97,7 @@ func (s *Server) handleBrowserNavigate(c echo.Context) error {
 		return err
 	}
 	targetID, _ := params["targetId"].(string)
+	s.relayManager.DropRefs(profile, targetID)
 	result, err := s.relayManager.Call(c.Request().Context(), profile, "navigate", params, targetID)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
@@ -101,14 +105,21 @@ func (s *Server) handleBrowserNavigate(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+// handleBrowserSnapshot renders the tab's accessibility tree with refs
+// that /act accepts in place of selectors.
 func (s *Server) handleBrowserSnapshot(c echo.Context) error {
 	profile := c.QueryParam("profile")
 	targetID := c.QueryParam("targetId")
-	result, err := s.relayManager.Call(c.Request().Context(), profile, "snapshot", nil, targetID)
+	opts := browser.SnapshotOptions{Interactive: c.QueryParam("interactive") == "true"}
+	opts.MaxChars, _ = strconv.Atoi(c.QueryParam("maxChars"))
+	snapshot, err := s.relayManager.Snapshot(c.Request().Context(), profile, targetID, opts)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"targetId": targetID,
+		"snapshot": snapshot,
+	})
 }
 
 func (s *Server) handleBrowserScreenshot(c echo.Context) error {
@@ -125,18 +136,38 @@ func (s *Server) handleBrowserScreenshot(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+// handleBrowserAct performs an interaction; ref, startRef and endRef name
+// elements from the last snapshot.
 func (s *Server) handleBrowserAct(c echo.Context) error {
 	profile := c.QueryParam("profile")
-	var params map[string]interface{}
-	if err := c.Bind(&params); err != nil {
+	var body struct {
+		browser.ActRequest
+		TargetID string `json:"targetId"`
+		Ref      string `json:"ref"`
+		StartRef string `json:"startRef"`
+		EndRef   string `json:"endRef"`
+	}
+	if err := c.Bind(&body); err != nil {
 		return err
 	}
-	targetID, _ := params["targetId"].(string)
-	result, err := s.relayManager.Call(c.Request().Context(), profile, "act", params, targetID)
+	req := body.ActRequest
+	for _, sel := range []string{body.Ref, body.StartRef} {
+		if req.Selector == "" {
+			req.Selector = sel
+		}
+	}
+	if req.EndSelector == "" {
+		req.EndSelector = body.EndRef
+	}
+	result, err := s.relayManager.Act(c.Request().Context(), profile, body.TargetID, req)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
-	return c.JSON(http.StatusOK, result)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ok":       true,
+		"targetId": body.TargetID,
+		"result":   result,
+	})
 }
 
 func (s *Server) handleBrowserProfiles(c echo.Context) error {
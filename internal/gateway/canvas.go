@@ -0,0 +1,89 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/liteclaw/liteclaw/internal/canvas"
+	"github.com/liteclaw/liteclaw/internal/config"
+)
+
+// canvasCookie carries the canvas access key for canvas pages, whose
+// scripts and WebSocket cannot send an Authorization header.
+const canvasCookie = "liteclaw_canvas"
+
+// handleCanvas serves the agent's canvas host under canvas.MountPath.
+func (s *Server) handleCanvas(c echo.Context) error {
+	svc := s.currentAgent()
+	if svc == nil || svc.Canvas == nil {
+		return echo.ErrNotFound
+	}
+	c.Response().Header().Set("Content-Security-Policy", canvas.ContentSecurityPolicy)
+	http.StripPrefix(canvas.MountPath, svc.Canvas).ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+// CanvasAuthMiddleware admits canvas requests that carry the canvas
+// cookie or the gateway token in the Authorization header. A page opened
+// with ?canvasKey= (or ?token=) gets the cookie and is redirected to the
+// same URL without the credential, so page scripts never see it.
+func (s *Server) CanvasAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		cfg, err := config.Load()
+		if err != nil || cfg.Gateway.Auth.Token == "" {
+			return next(c)
+		}
+		token := cfg.Gateway.Auth.Token
+		key := canvas.AccessKey(token)
+
+		if cookie, err := c.Cookie(canvasCookie); err == nil && equalSecret(cookie.Value, key) {
+			return next(c)
+		}
+		req := c.Request()
+		if h := req.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") && equalSecret(strings.TrimPrefix(h, "Bearer "), token) {
+			return next(c)
+		}
+
+		q := req.URL.Query()
+		given := q.Get(canvas.AccessKeyParam)
+		if given == "" {
+			given = q.Get("token")
+		}
+		if given == "" {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Missing authentication token")
+		}
+		if !equalSecret(given, key) && !equalSecret(given, token) {
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid authentication token")
+		}
+		c.SetCookie(&http.Cookie{
+			Name:     canvasCookie,
+			Value:    key,
+			Path:     canvas.MountPath,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		q.Del(canvas.AccessKeyParam)
+		q.Del("token")
+		u := *req.URL
+		u.RawQuery = q.Encode()
+		return c.Redirect(http.StatusFound, u.RequestURI())
+	}
+}
+
+func equalSecret(got, want string) bool {
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+// canvasBaseURL is the canvas root as reached from this machine, used for
+// headless snapshots.
+func (s *Server) canvasBaseURL() string {
+	host := s.config.Host
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s:%d%s", host, s.config.Port, canvas.MountPath)
+}
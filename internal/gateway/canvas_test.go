@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/canvas"
+)
+
+func TestCanvasAuthSwapsTokenForCookie(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	_ = os.MkdirAll(filepath.Join(home, ".liteclaw"), 0755)
+	if err := os.WriteFile(filepath.Join(home, ".liteclaw", "liteclaw.json"), []byte(`{"gateway": {"auth": {"token": "s3cret"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := New(&Config{Host: "localhost", Port: 3456})
+	server.agentService = &agent.Service{Canvas: canvas.NewHost(t.TempDir())}
+	e := echo.New()
+	e.Any(canvas.MountPath+"/*", server.CanvasAuthMiddleware(server.handleCanvas))
+	do := func(target string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do("/canvas/main/", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no credentials: status %d", rec.Code)
+	}
+	key := canvas.AccessKey("s3cret")
+	for _, q := range []string{"token=s3cret", "canvasKey=" + key} {
+		rec := do("/canvas/main/?x=1&"+q, nil)
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/canvas/main/?x=1" {
+			t.Fatalf("%s: status %d, location %q", q, rec.Code, rec.Header().Get("Location"))
+		}
+		cookies := rec.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Value != key || !cookies[0].HttpOnly || cookies[0].Path != canvas.MountPath {
+			t.Fatalf("%s: cookies = %+v", q, cookies)
+		}
+	}
+	if rec := do("/canvas/main/?canvasKey=wrong", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong key: status %d", rec.Code)
+	}
+
+	rec := do("/canvas/main/", &http.Cookie{Name: canvasCookie, Value: key})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cookie: status %d", rec.Code)
+	}
+	if csp := rec.Header().Get("Content-Security-Policy"); !strings.HasPrefix(csp, "sandbox allow-scripts") || strings.Contains(csp, "allow-same-origin") {
+		t.Errorf("Content-Security-Policy = %q", csp)
+	}
+	if strings.Contains(rec.Body.String(), "s3cret") {
+		t.Error("page contains the gateway token")
+	}
+}
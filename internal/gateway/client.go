@@ -40,6 +40,7 @@ const (
 	ClientNameGateway = "gateway-client"
 	ClientNameTUI     = "cli" // Changed from "liteclaw-tui" to "cli" to match Clawdbot validation
 	ClientNameAgent   = "liteclaw-agent"
+	ClientNameNode    = "liteclaw-node"
 )
 
 // ConnectParams represents the connect request parameters.
@@ -180,6 +181,9 @@ type ClientOptions struct {
 	Scopes            []string
 	Caps              []string
 	Commands          []string
+	// Identity signs the connect request; an ephemeral one is generated
+	// when nil.
+	Identity *DeviceIdentity
 
 	OnEvent        func(evt *EventFrame)
 	OnHelloOk      func(hello *HelloOk)
@@ -192,6 +196,7 @@ type Client struct {
 	opts     ClientOptions
 	ws       *websocket.Conn
 	mu       sync.RWMutex
+	writeMu  sync.Mutex
 	pending  map[string]*pending
 	closed   bool
 	lastSeq  int
@@ -223,14 +228,15 @@ func NewClient(opts ClientOptions) *Client {
 	if opts.Role == "" {
 		opts.Role = "operator"
 	}
-	if len(opts.Scopes) == 0 {
+	if len(opts.Scopes) == 0 && opts.Role == "operator" {
 		opts.Scopes = []string{"operator.admin"}
 	}
 
 	return &Client{
-		opts:    opts,
-		pending: make(map[string]*pending),
-		closeCh: make(chan struct{}),
+		opts:     opts,
+		pending:  make(map[string]*pending),
+		closeCh:  make(chan struct{}),
+		identity: opts.Identity,
 	}
 }
 
@@ -293,8 +299,8 @@ func (c *Client) Connect(ctx context.Context) error {
 
 	// Device Authentication
 	if c.identity == nil {
-		// Generate ephemeral identity if none exists
-		// TODO: Load from disk for persistence
+		// Generate an ephemeral identity; callers that need a stable one
+		// pass ClientOptions.Identity (see LoadOrCreateDeviceIdentity).
 		var err error
 		c.identity, err = GenerateDeviceIdentity()
 		if err != nil {
@@ -420,7 +426,10 @@ func (c *Client) Request(ctx context.Context, method string, params interface{})
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+	c.writeMu.Lock()
+	err = ws.WriteMessage(websocket.TextMessage, data)
+	c.writeMu.Unlock()
+	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
@@ -0,0 +1,193 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/canvas"
+	"github.com/liteclaw/liteclaw/internal/config"
+)
+
+// ErrRestart is returned by Start when the gateway stopped because a
+// restart was requested; the caller should start a new Server.
+var ErrRestart = errors.New("gateway restart requested")
+
+// restartDelay gives the requesting agent run time to deliver its reply
+// before the gateway shuts down.
+const restartDelay = 2 * time.Second
+
+// stdinQuit receives Ctrl+C read from a raw-mode terminal. The reader is
+// started once per process so restarts do not race for stdin.
+var (
+	stdinQuit     = make(chan struct{}, 1)
+	stdinQuitOnce sync.Once
+)
+
+// restartSentinel records a requested restart so the next Start can report
+// back to the session that asked for it.
+type restartSentinel struct {
+	SessionKey  string `json:"sessionKey,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	RequestedAt int64  `json:"requestedAt"`
+}
+
+func restartSentinelPath() string {
+	return filepath.Join(config.StateDir(), "gateway-restart.json")
+}
+
+// useAgentService wires svc to the gateway's nodes, canvas host and
+// controls.
+func (s *Server) useAgentService(svc *agent.Service, cfg *config.Config) {
+	svc.UseNodes(s.nodes)
+	token := ""
+	if cfg != nil {
+		token = cfg.Gateway.Auth.Token
+	}
+	svc.UseCanvas(s.canvasBaseURL(), canvas.AccessKey(token))
+	svc.UseGateway(s)
+	svc.UseNotices(func(sessionKey, text string) {
+		go s.deliverNotice(sessionKey, text)
+	})
+}
+
+// Status implements tools.GatewayControl.
+func (s *Server) Status(ctx context.Context) (interface{}, error) {
+	return s.statusResponse(), nil
+}
+
+// ConfigGet implements tools.GatewayControl; secrets are redacted.
+func (s *Server) ConfigGet(ctx context.Context, path string) (interface{}, error) {
+	return configValue(path, true)
+}
+
+// ConfigSchema implements tools.GatewayControl.
+func (s *Server) ConfigSchema(ctx context.Context) (interface{}, error) {
+	return config.Schema(), nil
+}
+
+// ConfigPatch implements tools.GatewayControl.
+func (s *Server) ConfigPatch(ctx context.Context, patch map[string]interface{}) (interface{}, error) {
+	res, err := config.ApplyPatch(config.ConfigPath(), patch)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info().Strs("changed", res.Changed).Str("backup", res.BackupPath).Msg("Config patched")
+	return res, nil
+}
+
+// Reload implements tools.GatewayControl. It rebuilds the agent service
+// from the saved config; channel adapters and listeners are kept, so
+// changes to them need a restart.
+func (s *Server) Reload(ctx context.Context) (interface{}, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	svc := agent.NewService(cfg, s)
+	s.useAgentService(svc, cfg)
+
+	if old := s.setAgent(svc); old != nil {
+		old.Close()
+	}
+	s.logger.Info().Msg("Agent reloaded from config")
+	return map[string]interface{}{
+		"reloaded": true,
+		"note":     "Models, tools and skills were reloaded. Channel, port and auth changes need a restart.",
+	}, nil
+}
+
+// Restart implements tools.GatewayControl. The gateway shuts down shortly
+// after and Start returns ErrRestart; once the next Start is up, it
+// reports to sessionKey.
+func (s *Server) Restart(ctx context.Context, sessionKey, reason string) (interface{}, error) {
+	sentinel := restartSentinel{SessionKey: sessionKey, Reason: reason, RequestedAt: time.Now().UnixMilli()}
+	data, _ := json.Marshal(sentinel)
+	if err := os.MkdirAll(config.StateDir(), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(restartSentinelPath(), data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to record restart: %w", err)
+	}
+	s.logger.Info().Str("session", sessionKey).Str("reason", reason).Msg("Restart requested")
+	time.AfterFunc(restartDelay, s.requestRestart)
+	return map[string]interface{}{
+		"restarting": true,
+		"inMs":       restartDelay.Milliseconds(),
+	}, nil
+}
+
+// requestRestart makes Start shut down and return ErrRestart.
+func (s *Server) requestRestart() {
+	s.restartOnce.Do(func() { close(s.restartCh) })
+}
+
+// reportRestart tells the session that requested a restart that the
+// gateway is back: the notice is added to its history and, for channel
+// sessions, sent to the chat once the adapter connects.
+func (s *Server) reportRestart() {
+	path := restartSentinelPath()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	_ = os.Remove(path)
+
+	var sentinel restartSentinel
+	if err := json.Unmarshal(data, &sentinel); err != nil || sentinel.SessionKey == "" {
+		return
+	}
+	took := time.Since(time.UnixMilli(sentinel.RequestedAt)).Round(time.Second)
+	text := fmt.Sprintf("✅ Gateway restarted and is back up (took %s).", took)
+	if sentinel.Reason != "" {
+		text = fmt.Sprintf("✅ Gateway restarted (%s) and is back up (took %s).", sentinel.Reason, took)
+	}
+	s.deliverNotice(sentinel.SessionKey, text)
+}
+
+// deliverNotice records text in a session's history and, for a channel
+// session, sends it to the chat once the channel is connected.
+func (s *Server) deliverNotice(sessionKey, text string) {
+	if err := s.sessionManager.AddMessage(sessionKey, "assistant", text); err != nil {
+		s.logger.Warn().Err(err).Str("session", sessionKey).Msg("Failed to record notice")
+	}
+
+	parts := splitKey(sessionKey)
+	if len(parts) != 2 {
+		return
+	}
+	adapter, ok := s.adapters[parts[0]]
+	if !ok {
+		return
+	}
+	for i := 0; i < 30 && !adapter.IsConnected(); i++ {
+		time.Sleep(time.Second)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.SendMessage(ctx, parts[0], parts[1], text); err != nil {
+		s.logger.Warn().Err(err).Str("session", sessionKey).Msg("Failed to deliver notice")
+	}
+}
+
+// configValue returns the saved config value at a dotted path.
+func configValue(path string, redact bool) (map[string]interface{}, error) {
+	doc, err := config.ReadRaw(config.ConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	if redact {
+		doc = config.Redact(doc).(map[string]interface{})
+	}
+	value, ok := config.Lookup(doc, path)
+	if !ok {
+		return nil, fmt.Errorf("config path %q is not set", path)
+	}
+	return map[string]interface{}{"path": path, "value": value}, nil
+}
@@ -7,7 +7,11 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -76,6 +80,97 @@ func PublicKeyToBase64Url(pubKey ed25519.PublicKey) string {
 	return base64.RawURLEncoding.EncodeToString(pubKey)
 }
 
+// VerifyDevicePayload reports whether signature is publicKey's signature of
+// payload; both are base64url encoded.
+func VerifyDevicePayload(publicKey, payload, signature string) bool {
+	pub, err := decodeBase64Url(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := decodeBase64Url(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pub), []byte(payload), sig)
+}
+
+// ParsePublicKeyBase64Url decodes a base64url encoded raw public key.
+func ParsePublicKeyBase64Url(s string) (ed25519.PublicKey, error) {
+	b, err := decodeBase64Url(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length %d", len(b))
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// decodeBase64Url accepts base64url with or without padding.
+func decodeBase64Url(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// storedIdentity is the on-disk form of a DeviceIdentity.
+type storedIdentity struct {
+	Version    int    `json:"version"`
+	DeviceID   string `json:"deviceId"`
+	PrivateKey string `json:"privateKeyPem"`
+}
+
+// LoadOrCreateDeviceIdentity reads the identity stored at path, generating
+// and saving a new one if the file does not exist.
+func LoadOrCreateDeviceIdentity(path string) (*DeviceIdentity, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var stored storedIdentity
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return nil, fmt.Errorf("parse device identity: %w", err)
+		}
+		block, _ := pem.Decode([]byte(stored.PrivateKey))
+		if block == nil {
+			return nil, fmt.Errorf("parse device identity: no private key")
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse device identity: %w", err)
+		}
+		priv, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("parse device identity: not an ed25519 key")
+		}
+		pub := priv.Public().(ed25519.PublicKey)
+		return &DeviceIdentity{ID: ComputeDeviceID(pub), PublicKey: pub, PrivateKey: priv}, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	identity, err := GenerateDeviceIdentity()
+	if err != nil {
+		return nil, err
+	}
+	der, err := EncodePrivateKeyPEM(identity.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.MarshalIndent(storedIdentity{
+		Version:    1,
+		DeviceID:   identity.ID,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
 // PEM export helpers (if needed for storage later)
 func EncodePrivateKeyPEM(privKey ed25519.PrivateKey) ([]byte, error) {
 	b, err := x509.MarshalPKCS8PrivateKey(privKey)
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"runtime"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/liteclaw/liteclaw/internal/cron"
 	"github.com/liteclaw/liteclaw/internal/version"
 )
@@ -61,6 +64,11 @@ func (s *Server) handleRoot(c echo.Context) error {
 
 // handleStatus handles GET /api/status
 func (s *Server) handleStatus(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.statusResponse())
+}
+
+// statusResponse reports the gateway's current state.
+func (s *Server) statusResponse() StatusResponse {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
@@ -91,8 +99,7 @@ func (s *Server) handleStatus(c echo.Context) error {
 		Arch:      runtime.GOARCH,
 		OS:        runtime.GOOS,
 	}
-
-	return c.JSON(http.StatusOK, resp)
+	return resp
 }
 
 // handleListSessions handles GET /api/sessions
@@ -122,53 +129,95 @@ func (s *Server) handleListChannels(c echo.Context) error {
 	})
 }
 
-// handleGetConfig handles GET /api/config
+// handleGetConfig handles GET /api/config?path=<dotted.path>[&redact=false].
+// Secrets are redacted unless the caller opts out, which is only allowed
+// when the gateway has an auth token (and the request therefore carried it).
 func (s *Server) handleGetConfig(c echo.Context) error {
-	// TODO: Implement config retrieval
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"config": map[string]interface{}{},
-	})
+	redact := true
+	if v := c.QueryParam("redact"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "redact must be true or false"})
+		}
+		redact = b
+	}
+	if !redact {
+		if cfg, err := config.Load(); err != nil || cfg.Gateway.Auth.Token == "" {
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "unredacted config reads require gateway.auth.token"})
+		}
+	}
+	res, err := configValue(c.QueryParam("path"), redact)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
-// handleUpdateConfig handles POST /api/config
+// handleUpdateConfig handles POST /api/config. The body is a JSON merge
+// patch for the config file.
 func (s *Server) handleUpdateConfig(c echo.Context) error {
-	// TODO: Implement config update
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"ok":      true,
-		"message": "Config updated",
-	})
+	var patch map[string]interface{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&patch); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "body must be a JSON object: " + err.Error()})
+	}
+	res, err := s.ConfigPatch(c.Request().Context(), patch)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
-// handleRestart handles POST /api/gateway/restart
+// handleRestart handles POST /api/gateway/restart. An optional body
+// {"sessionKey", "reason"} names the session to notify once it is back.
 func (s *Server) handleRestart(c echo.Context) error {
-	s.logger.Info().Msg("Restart requested via API")
-	// TODO: Implement restart logic
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"ok":      true,
-		"message": "Restarting...",
-	})
+	var req struct {
+		SessionKey string `json:"sessionKey"`
+		Reason     string `json:"reason"`
+	}
+	_ = json.NewDecoder(c.Request().Body).Decode(&req)
+	res, err := s.Restart(c.Request().Context(), req.SessionKey, req.Reason)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusAccepted, res)
 }
 
 // handleReload handles POST /api/gateway/reload
 func (s *Server) handleReload(c echo.Context) error {
-	s.logger.Info().Msg("Reload requested via API")
-	// TODO: Implement reload logic
-	return c.JSON(http.StatusOK, map[string]interface{}{
-		"ok":      true,
-		"message": "Configuration reloaded",
-	})
+	res, err := s.Reload(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 // Cron Handlers
 
 func (s *Server) handleCronList(c echo.Context) error {
-	jobs := s.agentService.GetScheduler().Jobs()
+	jobs := s.currentAgent().GetScheduler().Jobs()
 	return c.JSON(http.StatusOK, map[string]interface{}{"jobs": jobs})
 }
 
+// handleProcessList handles GET /api/processes
+func (s *Server) handleProcessList(c echo.Context) error {
+	processes := tools.Processes().List()
+	return c.JSON(http.StatusOK, map[string]interface{}{"processes": processes, "count": len(processes)})
+}
+
+// handleProcessLog handles GET /api/processes/:id/log?offset=&limit=
+func (s *Server) handleProcessLog(c echo.Context) error {
+	offset, _ := strconv.ParseInt(c.QueryParam("offset"), 10, 64)
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	out, err := tools.Processes().Read(c.Param("id"), offset, limit)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, out)
+}
+
 func (s *Server) handleCronGet(c echo.Context) error {
 	id := c.Param("id")
-	job, ok := s.agentService.GetScheduler().GetJob(id)
+	job, ok := s.currentAgent().GetScheduler().GetJob(id)
 	if !ok {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "job not found"})
 	}
@@ -187,7 +236,7 @@ func (s *Server) handleCronAdd(c echo.Context) error {
 	if job.ID == "" {
 		job.ID = uuid.New().String()
 	}
-	if err := s.agentService.GetScheduler().AddJob(&job); err != nil {
+	if err := s.currentAgent().GetScheduler().AddJob(&job); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	return c.JSON(http.StatusOK, map[string]interface{}{"job": job})
@@ -195,7 +244,7 @@ func (s *Server) handleCronAdd(c echo.Context) error {
 
 func (s *Server) handleCronRemove(c echo.Context) error {
 	id := c.Param("id")
-	if err := s.agentService.GetScheduler().RemoveJob(id); err != nil {
+	if err := s.currentAgent().GetScheduler().RemoveJob(id); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
@@ -203,7 +252,7 @@ func (s *Server) handleCronRemove(c echo.Context) error {
 
 func (s *Server) handleCronUpdate(c echo.Context) error {
 	id := c.Param("id")
-	scheduler := s.agentService.GetScheduler()
+	scheduler := s.currentAgent().GetScheduler()
 
 	job, ok := scheduler.GetJob(id)
 	if !ok {
@@ -240,7 +289,7 @@ func (s *Server) handleCronUpdate(c echo.Context) error {
 
 func (s *Server) handleCronRun(c echo.Context) error {
 	id := c.Param("id")
-	if err := s.agentService.GetScheduler().RunJobNow(id); err != nil {
+	if err := s.currentAgent().GetScheduler().RunJobNow(id); err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
 	return c.JSON(http.StatusOK, map[string]bool{"ok": true})
@@ -248,7 +297,7 @@ func (s *Server) handleCronRun(c echo.Context) error {
 
 func (s *Server) handleCronHistory(c echo.Context) error {
 	id := c.Param("id")
-	job, ok := s.agentService.GetScheduler().GetJob(id)
+	job, ok := s.currentAgent().GetScheduler().GetJob(id)
 	if !ok {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "job not found"})
 	}
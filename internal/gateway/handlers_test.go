@@ -0,0 +1,46 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHandleGetConfigRedactsByDefault(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configPath := filepath.Join(home, ".liteclaw", "liteclaw.json")
+	_ = os.MkdirAll(filepath.Dir(configPath), 0755)
+	write := func(content string) {
+		if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := New(&Config{Host: "localhost", Port: 3456})
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/api/config?"+query, nil), rec)
+		if err := server.handleGetConfig(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	write(`{"models": {"providers": {"openai": {"apiKey": "sk-live"}}}}`)
+	if rec := get("path=models"); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "sk-live") {
+		t.Errorf("default read = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("path=models&redact=false"); rec.Code != http.StatusForbidden {
+		t.Errorf("unredacted read without a token = %d %s", rec.Code, rec.Body.String())
+	}
+
+	write(`{"gateway": {"auth": {"token": "t"}}, "models": {"providers": {"openai": {"apiKey": "sk-live"}}}}`)
+	if rec := get("path=models&redact=false"); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "sk-live") {
+		t.Errorf("unredacted read with a token = %d %s", rec.Code, rec.Body.String())
+	}
+}
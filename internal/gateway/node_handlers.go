@@ -0,0 +1,170 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+
+	"github.com/liteclaw/liteclaw/internal/pairing"
+)
+
+// isNodeConnect reports whether a connect request comes from a node.
+func isNodeConnect(p ConnectParams) bool {
+	return p.Client.Mode == ClientModeNode || p.Role == "node"
+}
+
+// serveNode authorizes a node's connect request and, once approved, serves
+// its connection until it closes.
+func (s *Server) serveNode(ws *websocket.Conn, remoteIP, host, reqID string, p ConnectParams) {
+	var writeMu sync.Mutex
+	send := func(frame interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return ws.WriteJSON(frame)
+	}
+
+	if err := AuthorizeNode(p, time.Now()); err != nil {
+		shape := &ErrorShape{Code: "UNAUTHORIZED", Message: err.Error()}
+		var pairErr *NodePairingError
+		if errors.As(err, &pairErr) {
+			shape.Code = "NOT_PAIRED"
+			shape.Retryable = true
+			shape.Details = map[string]string{"deviceId": pairErr.DeviceID, "code": pairErr.Code}
+			s.logger.Warn().Str("device", pairErr.DeviceID).Str("name", p.Client.DisplayName).Str("code", pairErr.Code).
+				Msg("Node pairing requested; approve with `liteclaw pairing approve node <code>`")
+		} else {
+			s.logger.Warn().Err(err).Str("remote", remoteIP).Msg("Node connection rejected")
+		}
+		_ = send(ResponseFrame{Type: FrameTypeResponse, ID: reqID, Error: shape})
+		return
+	}
+
+	node := &NodeSession{
+		NodeID:      p.Device.ID,
+		DisplayName: p.Client.DisplayName,
+		Platform:    p.Client.Platform,
+		Version:     p.Client.Version,
+		Caps:        p.Caps,
+		Commands:    p.Commands,
+		RemoteIP:    remoteIP,
+		ConnectedAt: time.Now(),
+		send:        send,
+	}
+	if err := send(ResponseFrame{Type: FrameTypeResponse, ID: reqID, OK: true, Payload: s.helloPayload(host)}); err != nil {
+		return
+	}
+	s.nodes.Register(node)
+	defer s.nodes.Unregister(node)
+	s.logger.Info().Str("node", node.NodeID).Str("name", node.DisplayName).Strs("commands", node.Commands).Msg("Node connected")
+
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			s.logger.Info().Str("node", node.NodeID).Msg("Node disconnected")
+			return
+		}
+		var req struct {
+			ID     string          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(msg, &req); err != nil {
+			continue
+		}
+
+		switch req.Method {
+		case MethodNodeInvokeResult:
+			var res NodeInvokeResult
+			if err := json.Unmarshal(req.Params, &res); err != nil {
+				_ = send(ResponseFrame{Type: FrameTypeResponse, ID: req.ID, Error: &ErrorShape{Code: "INVALID_REQUEST", Message: err.Error()}})
+				continue
+			}
+			if !s.nodes.HandleResult(node.NodeID, res) {
+				s.logger.Debug().Str("node", node.NodeID).Str("id", res.ID).Msg("Late or unknown node invoke result")
+			}
+			_ = send(ResponseFrame{Type: FrameTypeResponse, ID: req.ID, OK: true, Payload: map[string]bool{"ok": true}})
+		case "ping":
+			_ = send(ResponseFrame{Type: FrameTypeResponse, ID: req.ID, OK: true, Payload: map[string]int64{"ts": time.Now().UnixMilli()}})
+		default:
+			_ = send(ResponseFrame{Type: FrameTypeResponse, ID: req.ID, Error: &ErrorShape{Code: "method_not_found", Message: "Method not available to nodes: " + req.Method}})
+		}
+	}
+}
+
+// nodeListPayload lists connected nodes followed by devices waiting to be
+// paired.
+func (s *Server) nodeListPayload() map[string]interface{} {
+	nodes := make([]map[string]interface{}, 0)
+	for _, n := range s.nodes.List() {
+		nodes = append(nodes, map[string]interface{}{
+			"nodeId":      n.NodeID,
+			"displayName": n.DisplayName,
+			"platform":    n.Platform,
+			"version":     n.Version,
+			"caps":        n.Caps,
+			"commands":    n.Commands,
+			"remoteIp":    n.RemoteIP,
+			"connectedAt": n.ConnectedAt.UnixMilli(),
+			"connected":   true,
+			"paired":      true,
+		})
+	}
+	if pending, err := pairing.ListChannelPairingRequests(NodePairingChannel); err == nil {
+		for _, r := range pending {
+			nodes = append(nodes, map[string]interface{}{
+				"nodeId":      r.ID,
+				"displayName": r.Meta["name"],
+				"platform":    r.Meta["platform"],
+				"connected":   false,
+				"paired":      false,
+				"pairingCode": r.Code,
+			})
+		}
+	}
+	return map[string]interface{}{
+		"ts":    time.Now().UnixMilli(),
+		"nodes": nodes,
+	}
+}
+
+// nodeInvokeRequest is the body of node.invoke over WS or REST.
+type nodeInvokeRequest struct {
+	NodeID    string                 `json:"nodeId"`
+	Command   string                 `json:"command"`
+	Params    map[string]interface{} `json:"params"`
+	TimeoutMs int64                  `json:"timeoutMs"`
+}
+
+func (s *Server) invokeNode(ctx context.Context, req nodeInvokeRequest) (interface{}, error) {
+	return s.nodes.Invoke(ctx, req.NodeID, req.Command, req.Params, time.Duration(req.TimeoutMs)*time.Millisecond)
+}
+
+// Node REST API Handlers
+
+func (s *Server) handleNodesList(c echo.Context) error {
+	nodes, _ := s.nodes.ListNodes(c.Request().Context())
+	return c.JSON(http.StatusOK, map[string]interface{}{"nodes": nodes})
+}
+
+func (s *Server) handleNodeInvoke(c echo.Context) error {
+	var req nodeInvokeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	payload, err := s.invokeNode(c.Request().Context(), req)
+	if err != nil {
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ok":      true,
+		"nodeId":  req.NodeID,
+		"command": req.Command,
+		"payload": payload,
+	})
+}
@@ -0,0 +1,340 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/pairing"
+)
+
+// NodePairingChannel is the pairing channel nodes are approved on:
+// `liteclaw pairing approve node <code>`.
+const NodePairingChannel = "node"
+
+// Node events and methods.
+const (
+	// EventNodeInvokeRequest asks a node to run a command.
+	EventNodeInvokeRequest = "node.invoke.request"
+	// MethodNodeInvokeResult is how a node answers an invoke request.
+	MethodNodeInvokeResult = "node.invoke.result"
+)
+
+// DefaultNodeInvokeTimeout bounds an invoke when the caller sets none.
+const DefaultNodeInvokeTimeout = 30 * time.Second
+
+// maxDeviceClockSkew is how far a device signature's timestamp may be from
+// the gateway's clock.
+const maxDeviceClockSkew = 10 * time.Minute
+
+// NodeSession is a node connected to the gateway.
+type NodeSession struct {
+	NodeID      string    `json:"nodeId"`
+	DisplayName string    `json:"displayName,omitempty"`
+	Platform    string    `json:"platform,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Caps        []string  `json:"caps,omitempty"`
+	Commands    []string  `json:"commands,omitempty"`
+	RemoteIP    string    `json:"remoteIp,omitempty"`
+	ConnectedAt time.Time `json:"connectedAt"`
+
+	// send writes a frame to the node's connection.
+	send func(frame interface{}) error
+}
+
+// HasCommand reports whether the node advertised command.
+func (n *NodeSession) HasCommand(command string) bool {
+	for _, c := range n.Commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+// NodeInvokeResult is a node's answer to an invoke request.
+type NodeInvokeResult struct {
+	ID      string      `json:"id"`
+	NodeID  string      `json:"nodeId,omitempty"`
+	OK      bool        `json:"ok"`
+	Payload interface{} `json:"payload,omitempty"`
+	Error   *ErrorShape `json:"error,omitempty"`
+}
+
+// NodePairingError is returned for a node whose device is not approved yet.
+type NodePairingError struct {
+	DeviceID string
+	Code     string
+}
+
+func (e *NodePairingError) Error() string {
+	return fmt.Sprintf("node %s is not paired; approve it with: liteclaw pairing approve %s %s", shortNodeID(e.DeviceID), NodePairingChannel, e.Code)
+}
+
+type pendingInvoke struct {
+	nodeID string
+	ch     chan NodeInvokeResult
+}
+
+// NodeRegistry tracks connected nodes and routes invoke requests to them.
+type NodeRegistry struct {
+	mu      sync.Mutex
+	nodes   map[string]*NodeSession
+	pending map[string]*pendingInvoke
+}
+
+// NewNodeRegistry creates an empty registry.
+func NewNodeRegistry() *NodeRegistry {
+	return &NodeRegistry{
+		nodes:   make(map[string]*NodeSession),
+		pending: make(map[string]*pendingInvoke),
+	}
+}
+
+// AuthorizeNode checks a node's connect request: the device signature must
+// verify against its public key, and the device must be approved on the
+// node pairing channel. An unknown device gets a pairing request and a
+// *NodePairingError carrying its code.
+func AuthorizeNode(p ConnectParams, now time.Time) error {
+	d := p.Device
+	if d == nil || d.ID == "" || d.PublicKey == "" || d.Signature == "" {
+		return fmt.Errorf("node connections require a device identity")
+	}
+	pub, err := ParsePublicKeyBase64Url(d.PublicKey)
+	if err != nil {
+		return fmt.Errorf("invalid device public key: %w", err)
+	}
+	if ComputeDeviceID(pub) != d.ID {
+		return fmt.Errorf("device id does not match its public key")
+	}
+	signedAt := time.UnixMilli(d.SignedAt)
+	if skew := now.Sub(signedAt); skew > maxDeviceClockSkew || skew < -maxDeviceClockSkew {
+		return fmt.Errorf("device signature expired; check the node's clock")
+	}
+	token := ""
+	if p.Auth != nil {
+		token = p.Auth.Token
+	}
+	payload := BuildDeviceAuthPayload(d.ID, p.Client.ID, p.Client.Mode, p.Role, p.Scopes, d.SignedAt, token, d.Nonce)
+	if !VerifyDevicePayload(d.PublicKey, payload, d.Signature) {
+		return fmt.Errorf("invalid device signature")
+	}
+
+	allowed, err := pairing.IsAllowed(NodePairingChannel, d.ID)
+	if err != nil {
+		return fmt.Errorf("pairing store: %w", err)
+	}
+	if allowed {
+		return nil
+	}
+	code, _, err := pairing.UpsertChannelPairingRequest(NodePairingChannel, d.ID, map[string]string{
+		"name":     p.Client.DisplayName,
+		"platform": p.Client.Platform,
+	})
+	if err != nil {
+		return fmt.Errorf("pairing store: %w", err)
+	}
+	return &NodePairingError{DeviceID: d.ID, Code: code}
+}
+
+// Register adds a connected node, replacing an older connection from the
+// same device.
+func (r *NodeRegistry) Register(n *NodeSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[n.NodeID] = n
+}
+
+// Unregister removes n if it is still the node's current connection and
+// fails its pending invokes.
+func (r *NodeRegistry) Unregister(n *NodeSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.nodes[n.NodeID] != n {
+		return
+	}
+	delete(r.nodes, n.NodeID)
+	for id, p := range r.pending {
+		if p.nodeID == n.NodeID {
+			p.ch <- NodeInvokeResult{ID: id, Error: &ErrorShape{Code: "NODE_DISCONNECTED", Message: "node disconnected"}}
+			delete(r.pending, id)
+		}
+	}
+}
+
+// List returns the connected nodes sorted by name.
+func (r *NodeRegistry) List() []*NodeSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*NodeSession, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		out = append(out, n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].DisplayName != out[j].DisplayName {
+			return out[i].DisplayName < out[j].DisplayName
+		}
+		return out[i].NodeID < out[j].NodeID
+	})
+	return out
+}
+
+// Find resolves a node by ID, name or unique ID prefix. An empty ref picks
+// the first node that advertises command.
+func (r *NodeRegistry) Find(ref, command string) (*NodeSession, error) {
+	nodes := r.List()
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes connected; run `liteclaw node run` on a machine to add one")
+	}
+	if ref == "" {
+		for _, n := range nodes {
+			if command == "" || n.HasCommand(command) {
+				return n, nil
+			}
+		}
+		return nil, fmt.Errorf("no connected node supports %s", command)
+	}
+
+	var matches []*NodeSession
+	for _, n := range nodes {
+		if n.NodeID == ref {
+			return n, nil
+		}
+		if strings.EqualFold(n.DisplayName, ref) || strings.HasPrefix(n.NodeID, ref) {
+			matches = append(matches, n)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("node not connected: %s", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("node %q is ambiguous (%d matches); use its full ID", ref, len(matches))
+	}
+}
+
+// Invoke runs command on the node named by ref and waits up to timeout for
+// its result.
+func (r *NodeRegistry) Invoke(ctx context.Context, ref, command string, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	if command == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	n, err := r.Find(ref, command)
+	if err != nil {
+		return nil, err
+	}
+	if len(n.Commands) > 0 && !n.HasCommand(command) {
+		return nil, fmt.Errorf("node %s does not support %s (commands: %s)", n.label(), command, strings.Join(n.Commands, ", "))
+	}
+	if timeout <= 0 {
+		timeout = DefaultNodeInvokeTimeout
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	id := generateID()
+	ch := make(chan NodeInvokeResult, 1)
+	r.mu.Lock()
+	r.pending[id] = &pendingInvoke{nodeID: n.NodeID, ch: ch}
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.pending, id)
+		r.mu.Unlock()
+	}()
+
+	err = n.send(EventFrame{
+		Type:  FrameTypeEvent,
+		Event: EventNodeInvokeRequest,
+		Payload: map[string]interface{}{
+			"id":        id,
+			"nodeId":    n.NodeID,
+			"command":   command,
+			"params":    params,
+			"timeoutMs": timeout.Milliseconds(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("send to node %s: %w", n.label(), err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		if res.Error != nil {
+			return nil, fmt.Errorf("%s on node %s: %s", command, n.label(), res.Error.Message)
+		}
+		if !res.OK {
+			return nil, fmt.Errorf("%s on node %s failed", command, n.label())
+		}
+		return res.Payload, nil
+	case <-timer.C:
+		return nil, fmt.Errorf("%s on node %s timed out after %s", command, n.label(), timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// HandleResult delivers a node's invoke result; it reports false when no
+// invoke from that node is waiting for it.
+func (r *NodeRegistry) HandleResult(nodeID string, res NodeInvokeResult) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.pending[res.ID]
+	if !ok || p.nodeID != nodeID {
+		return false
+	}
+	delete(r.pending, res.ID)
+	p.ch <- res
+	return true
+}
+
+// ListNodes implements tools.NodeInvoker.
+func (r *NodeRegistry) ListNodes(ctx context.Context) ([]tools.NodeInfo, error) {
+	nodes := r.List()
+	out := make([]tools.NodeInfo, 0, len(nodes))
+	for _, n := range nodes {
+		out = append(out, n.Info())
+	}
+	return out, nil
+}
+
+// InvokeNode implements tools.NodeInvoker.
+func (r *NodeRegistry) InvokeNode(ctx context.Context, nodeID, command string, params map[string]interface{}, timeout time.Duration) (interface{}, error) {
+	return r.Invoke(ctx, nodeID, command, params, timeout)
+}
+
+// Info describes the node for the nodes tool.
+func (n *NodeSession) Info() tools.NodeInfo {
+	return tools.NodeInfo{
+		ID:           n.NodeID,
+		Name:         n.DisplayName,
+		Type:         "node",
+		Status:       "connected",
+		Platform:     n.Platform,
+		Capabilities: n.Caps,
+		Commands:     n.Commands,
+		ConnectedAt:  n.ConnectedAt.UnixMilli(),
+	}
+}
+
+func (n *NodeSession) label() string {
+	if n.DisplayName != "" {
+		return n.DisplayName
+	}
+	return shortNodeID(n.NodeID)
+}
+
+func shortNodeID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/liteclaw/liteclaw/internal/pairing"
+)
+
+// testNode connects a node client that answers system.run with its
+// params and leaves other commands unanswered.
+func testNode(t *testing.T, url string, identity *DeviceIdentity) (*Client, error) {
+	t.Helper()
+	var client *Client
+	client = NewClient(ClientOptions{
+		URL:               url,
+		ClientName:        ClientNameNode,
+		ClientDisplayName: "studio",
+		Platform:          "linux",
+		Mode:              ClientModeNode,
+		Role:              "node",
+		Caps:              []string{"system"},
+		Commands:          []string{"system.run", "system.sleep"},
+		Identity:          identity,
+		OnEvent: func(evt *EventFrame) {
+			req, _ := evt.Payload.(map[string]interface{})
+			if evt.Event != EventNodeInvokeRequest || req["command"] != "system.run" {
+				return
+			}
+			go func() {
+				_, _ = client.Request(context.Background(), MethodNodeInvokeResult, NodeInvokeResult{
+					ID:      req["id"].(string),
+					OK:      true,
+					Payload: map[string]interface{}{"stdout": "ran", "params": req["params"]},
+				})
+			}()
+		},
+	})
+	return client, client.Connect(context.Background())
+}
+
+func TestNodeRegistryPairAndInvoke(t *testing.T) {
+	t.Setenv("LITECLAW_STATE_DIR", t.TempDir())
+
+	s := New(&Config{})
+	s.logger = zerolog.Nop()
+	e := echo.New()
+	e.GET("/", s.handleWebSocket)
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	identity, err := LoadOrCreateDeviceIdentity(t.TempDir() + "/node/device.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown device is turned away with a pairing code.
+	_, err = testNode(t, url, identity)
+	var shape *ErrorShape
+	if !errors.As(err, &shape) || shape.Code != "NOT_PAIRED" {
+		t.Fatalf("unpaired connect error = %v", err)
+	}
+	code, _ := shape.Details.(map[string]interface{})["code"].(string)
+	if _, err := pairing.ApproveChannelPairingCode(NodePairingChannel, code); err != nil {
+		t.Fatalf("approve %q: %v", code, err)
+	}
+
+	client, err := testNode(t, url, identity)
+	if err != nil {
+		t.Fatalf("paired connect: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	nodes, _ := s.nodes.ListNodes(context.Background())
+	if len(nodes) != 1 || nodes[0].ID != identity.ID || nodes[0].Name != "studio" || len(nodes[0].Commands) != 2 {
+		t.Fatalf("nodes = %+v", nodes)
+	}
+
+	ctx := context.Background()
+	out, err := s.nodes.InvokeNode(ctx, "studio", "system.run", map[string]interface{}{"command": "uptime"}, time.Second)
+	if err != nil {
+		t.Fatalf("invoke: %v", err)
+	}
+	if res := out.(map[string]interface{}); res["stdout"] != "ran" || res["params"].(map[string]interface{})["command"] != "uptime" {
+		t.Fatalf("invoke result = %v", out)
+	}
+
+	if _, err := s.nodes.InvokeNode(ctx, "", "system.sleep", nil, 50*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("unanswered invoke error = %v", err)
+	}
+	if _, err := s.nodes.InvokeNode(ctx, identity.ID[:8], "camera.snap", nil, time.Second); err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Fatalf("unsupported command error = %v", err)
+	}
+
+	_ = client.Close()
+	for i := 0; i < 100 && len(s.nodes.List()) > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := s.nodes.InvokeNode(ctx, "studio", "system.run", nil, time.Second); err == nil || !strings.Contains(err.Error(), "no nodes connected") {
+		t.Fatalf("invoke after disconnect error = %v", err)
+	}
+}
+
+func TestAuthorizeNodeRejectsForgedDevice(t *testing.T) {
+	t.Setenv("LITECLAW_STATE_DIR", t.TempDir())
+	identity, _ := GenerateDeviceIdentity()
+	other, _ := GenerateDeviceIdentity()
+
+	now := time.Now()
+	p := ConnectParams{
+		Client: ClientInfo{ID: ClientNameNode, Mode: ClientModeNode},
+		Role:   "node",
+	}
+	sign := func(key *DeviceIdentity, id string, signedAt time.Time) *DeviceInfo {
+		payload := BuildDeviceAuthPayload(id, p.Client.ID, p.Client.Mode, p.Role, nil, signedAt.UnixMilli(), "", "")
+		return &DeviceInfo{
+			ID:        id,
+			PublicKey: PublicKeyToBase64Url(key.PublicKey),
+			Signature: SignDevicePayload(key.PrivateKey, payload),
+			SignedAt:  signedAt.UnixMilli(),
+		}
+	}
+
+	cases := map[string]*DeviceInfo{
+		"claimed id":    sign(identity, other.ID, now),
+		"stale":         sign(identity, identity.ID, now.Add(-time.Hour)),
+		"wrong signer":  {ID: identity.ID, PublicKey: PublicKeyToBase64Url(identity.PublicKey), Signature: sign(other, identity.ID, now).Signature, SignedAt: now.UnixMilli()},
+		"missing field": {ID: identity.ID},
+	}
+	for name, d := range cases {
+		p.Device = d
+		err := AuthorizeNode(p, now)
+		var pairErr *NodePairingError
+		if err == nil || errors.As(err, &pairErr) {
+			t.Errorf("%s: AuthorizeNode = %v, want rejection", name, err)
+		}
+	}
+
+	p.Device = sign(identity, identity.ID, now)
+	var pairErr *NodePairingError
+	if err := AuthorizeNode(p, now); !errors.As(err, &pairErr) || pairErr.Code == "" {
+		t.Fatalf("valid unpaired device = %v, want pairing request", err)
+	}
+}
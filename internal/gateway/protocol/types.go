@@ -76,6 +76,7 @@ const (
 	MethodSkillsStatus    = "skills.status"
 	MethodSkillsInstall   = "skills.install"
 	MethodLogsTail        = "logs.tail"
+	MethodProcessList     = "process.list"
 )
 
 // Error codes.
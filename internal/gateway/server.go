@@ -5,10 +5,12 @@ package gateway
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
@@ -28,7 +30,10 @@ import (
 	"github.com/liteclaw/liteclaw/extensions/telegram"
 	"github.com/liteclaw/liteclaw/extensions/wecom"
 	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/agent/transcribe"
 	"github.com/liteclaw/liteclaw/internal/browser"
+	"github.com/liteclaw/liteclaw/internal/canvas"
 	"github.com/liteclaw/liteclaw/internal/channels"
 	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/liteclaw/liteclaw/internal/pairing"
@@ -55,13 +60,20 @@ type Server struct {
 	// channelManager *channels.Manager   // TODO
 	// sessionManager *session.Manager    	// Simple in-memory history: sessionKey -> list of message objects
 	// Services
+	// agentService is replaced by Reload; read it with currentAgent.
 	agentService   *agent.Service
+	agentMu        sync.RWMutex
 	sessionManager *SessionManager
 	relayManager   *browser.RelayManager
+	nodes          *NodeRegistry
 	adapters       map[string]channels.Adapter
 
 	// Dedicated servers to shutdown
 	shutdownServers []*echo.Echo
+
+	// restartCh is closed to stop Start with ErrRestart.
+	restartCh   chan struct{}
+	restartOnce sync.Once
 }
 
 // New creates a new gateway server.
@@ -82,6 +94,8 @@ func New(cfg *Config) *Server {
 		adapters:       make(map[string]channels.Adapter),
 		sessionManager: NewSessionManager(""),
 		relayManager:   browser.NewRelayManager(),
+		nodes:          NewNodeRegistry(),
+		restartCh:      make(chan struct{}),
 	}
 }
 
@@ -102,10 +116,14 @@ func (s *Server) Start() error {
 		s.logger.Warn().Err(err).Msg("Failed to load config, using defaults")
 		// Use empty or default config if load fails
 		ctxCfg := &config.Config{Env: map[string]string{}}
-		s.agentService = agent.NewService(ctxCfg, s)
+		svc := agent.NewService(ctxCfg, s)
+		s.useAgentService(svc, ctxCfg)
+		s.setAgent(svc)
 	} else {
 		s.logger.Info().Msg("Configuration loaded")
-		s.agentService = agent.NewService(cfg, s)
+		svc := agent.NewService(cfg, s)
+		s.useAgentService(svc, cfg)
+		s.setAgent(svc)
 
 		// Initialize Telegram Adapter if configured
 		if cfg.Channels.Telegram.BotToken != "" {
@@ -351,11 +369,13 @@ func (s *Server) Start() error {
 	}()
 
 	// Start dedicated Browser Control Server (18791)
+	controlServer := echo.New()
+	s.shutdownServers = append(s.shutdownServers, controlServer)
 	go func() {
 		controlAddr := fmt.Sprintf("%s:%d", s.config.Host, 18791)
 		s.logger.Info().Str("addr", controlAddr).Msg("Browser Control server starting")
 
-		e := echo.New()
+		e := controlServer
 		e.HideBanner = true
 		e.Use(middleware.CORS())
 
@@ -377,11 +397,13 @@ func (s *Server) Start() error {
 	}()
 
 	// Start dedicated Extension Relay Server (18792)
+	relayServer := echo.New()
+	s.shutdownServers = append(s.shutdownServers, relayServer)
 	go func() {
 		relayAddr := fmt.Sprintf("%s:%d", s.config.Host, 18792)
 		s.logger.Info().Str("addr", relayAddr).Msg("Extension Relay server starting")
 
-		e := echo.New()
+		e := relayServer
 		e.HideBanner = true
 
 		// Use a more permissive CORS for the extension
@@ -429,35 +451,43 @@ func (s *Server) Start() error {
 	// Print startup message
 	s.printStartupBanner()
 
+	// Tell the session that asked for a restart that we are back.
+	go s.reportRestart()
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
 
 	// Fallback: if terminal is in raw/no-ISIG mode, Ctrl+C may appear as byte 0x03.
 	// Capture it so users can still stop the gateway.
-	manualQuit := make(chan struct{}, 1)
 	if term.IsTerminal(int(os.Stdin.Fd())) {
-		go func() {
-			reader := bufio.NewReader(os.Stdin)
-			for {
-				b, err := reader.ReadByte()
-				if err != nil {
-					return
-				}
-				if b == 3 {
-					manualQuit <- struct{}{}
-					return
+		stdinQuitOnce.Do(func() {
+			go func() {
+				reader := bufio.NewReader(os.Stdin)
+				for {
+					b, err := reader.ReadByte()
+					if err != nil {
+						return
+					}
+					if b == 3 {
+						stdinQuit <- struct{}{}
+						return
+					}
 				}
-			}
-		}()
+			}()
+		})
 	}
 
+	restarting := false
 	select {
 	case <-quit:
-	case <-manualQuit:
+	case <-stdinQuit:
+	case <-s.restartCh:
+		restarting = true
 	}
 
-	s.logger.Info().Msg("Shutting down gateway server...")
+	s.logger.Info().Bool("restart", restarting).Msg("Shutting down gateway server...")
 
 	// Graceful shutdown with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -470,6 +500,9 @@ func (s *Server) Start() error {
 
 	// Stop adapter
 	// Agent service effectively stops when gateway stops receiving requests checking policies etc.
+	if svc := s.currentAgent(); svc != nil {
+		svc.Close()
+	}
 
 	// Stop auxiliary servers
 	for _, srv := range s.shutdownServers {
@@ -488,6 +521,9 @@ func (s *Server) Start() error {
 	}
 
 	s.logger.Info().Msg("Server stopped")
+	if restarting {
+		return ErrRestart
+	}
 	return nil
 }
 
@@ -548,12 +584,28 @@ func (s *Server) processChannelMessage(ctx context.Context, msg *channels.Incomi
 		}
 	}
 
+	// Attachments are only downloaded for senders that got this far.
+	s.fetchAttachments(ctx, msg)
+
+	// Voice messages are transcribed into the user turn; when that is not
+	// possible and there is nothing else to answer, tell the sender why.
+	text, notice := s.inboundText(ctx, msg)
+	if notice != "" {
+		_, err := adapter.Send(ctx, &channels.SendRequest{
+			To:      channels.Destination{ChatID: msg.ChatID},
+			Text:    notice,
+			ReplyTo: msg.ID,
+		})
+		return err
+	}
+
 	// Use SenderID as session key for simple persistence/context
 	sessionKey := fmt.Sprintf("%s:%s", msg.ChannelType, msg.SenderID)
+	svc := s.currentAgent()
 
 	// Load persisted history into agent session (restore context after gateway restart)
 	// Optimization: Check if agent already has session in memory to avoid parsing disk history on every message
-	if !s.agentService.HasSession(sessionKey) {
+	if !svc.HasSession(sessionKey) {
 		if history, err := s.sessionManager.GetHistory(sessionKey); err == nil && len(history) > 0 {
 			// Convert gateway.Message to agent.Message format
 			agentHistory := make([]agent.Message, 0, len(history))
@@ -572,19 +624,27 @@ func (s *Server) processChannelMessage(ctx context.Context, msg *channels.Incomi
 					})
 				}
 			}
-			s.agentService.LoadSessionHistory(sessionKey, agentHistory)
+			svc.LoadSessionHistory(sessionKey, agentHistory)
 		}
 	}
 
 	// Persist User Message
-	if err := s.sessionManager.AddMessage(sessionKey, "user", msg.Text); err != nil {
+	if err := s.sessionManager.AddMessage(sessionKey, "user", text); err != nil {
 		s.logger.Warn().Err(err).Str("session", sessionKey).Msg("Failed to persist user message")
 	}
+	s.restoreSessionOverrides(sessionKey)
 
 	var fullResponse strings.Builder
+	var media []tools.MediaAttachment
+	opts := agent.RunOptions{
+		SessionType: msg.ChatType,
+		OnAttachment: func(a tools.MediaAttachment) {
+			media = append(media, a)
+		},
+	}
 	// TUI Streaming Effect: print to stdout
 	fmt.Printf("\n>>> Streaming Response for %s:\n", sessionKey)
-	err := s.agentService.ProcessChat(ctx, sessionKey, msg.Text, func(delta string) {
+	err := svc.ProcessChatWithOptions(ctx, sessionKey, text, opts, func(delta string) {
 		fmt.Print(delta)
 		fullResponse.WriteString(delta)
 	})
@@ -610,19 +670,195 @@ func (s *Server) processChannelMessage(ctx context.Context, msg *channels.Incomi
 		s.logger.Warn().Err(err).Str("session", sessionKey).Msg("Failed to persist assistant message")
 	}
 
+	// Auto-TTS: speak the reply on channels that take voice notes, unless
+	// the agent already attached one.
+	caps := adapter.Capabilities()
+	if caps != nil && caps.Voice && !hasVoiceNote(media) {
+		if voice, err := svc.SpeakReply(ctx, respStr, hasAudio(msg.Attachments)); err != nil {
+			s.logger.Warn().Err(err).Str("session", sessionKey).Msg("Auto-TTS failed")
+		} else if voice != nil {
+			media = append(media, *voice)
+		}
+	}
+
 	// Send Reply
 	_, err = adapter.Send(ctx, &channels.SendRequest{
-		To:      channels.Destination{ChatID: msg.ChatID},
-		Text:    respStr,
-		ReplyTo: msg.ID,
+		To:          channels.Destination{ChatID: msg.ChatID},
+		Text:        respStr,
+		Attachments: replyAttachments(caps, media),
+		ReplyTo:     msg.ID,
 	})
 
 	return err
 }
 
+// fetchAttachments downloads the attachments adapters left to fetch,
+// dropping those that fail.
+func (s *Server) fetchAttachments(ctx context.Context, msg *channels.IncomingMessage) {
+	kept := msg.Attachments[:0]
+	for _, a := range msg.Attachments {
+		if a.Path == "" && a.Fetch != nil {
+			path, err := a.Fetch(ctx)
+			if err != nil {
+				s.logger.Warn().Err(err).Str("channel", msg.ChannelType).Str("type", a.Type).Msg("Failed to download attachment")
+				continue
+			}
+			a.Path = path
+		}
+		kept = append(kept, a)
+	}
+	msg.Attachments = kept
+}
+
+// inboundText builds the user turn for msg, prefixing transcripts of its
+// audio attachments and the extracted text of documents. notice is set
+// instead when the message is audio only and could not be transcribed.
+func (s *Server) inboundText(ctx context.Context, msg *channels.IncomingMessage) (text, notice string) {
+	var parts []string
+	var failure error
+	transcribed, documents := 0, 0
+	for _, a := range msg.Attachments {
+		if a.Path == "" {
+			continue
+		}
+		if a.Type == "file" || a.Type == "document" {
+			documents++
+			parts = append(parts, s.documentText(a))
+			continue
+		}
+		if a.Type != "audio" && a.Type != "voice" {
+			continue
+		}
+		tr, err := s.currentAgent().TranscribeAudio(ctx, a.Path)
+		switch {
+		case err != nil:
+			s.logger.Warn().Err(err).Str("file", a.Path).Msg("Failed to transcribe audio")
+			failure = err
+			parts = append(parts, fmt.Sprintf("[Audio message (%s) could not be transcribed]", a.Path))
+		case tr.Text == "":
+			transcribed++
+			parts = append(parts, fmt.Sprintf("[Audio message (%s): no speech recognised]", a.Path))
+		default:
+			transcribed++
+			parts = append(parts, fmt.Sprintf("[Transcription of audio message (%s)]\n%s", a.Path, tr.Text))
+		}
+	}
+	if len(parts) == 0 {
+		return msg.Text, ""
+	}
+	if strings.TrimSpace(msg.Text) == "" && transcribed == 0 && documents == 0 {
+		if errors.Is(failure, transcribe.ErrNotConfigured) {
+			return "", "🎙️ I can't listen to voice messages yet: transcription is not configured (tools.transcription). Please send text instead."
+		}
+		return "", "🎙️ Sorry, I couldn't transcribe that voice message. Please try again or send text."
+	}
+	if msg.Text != "" {
+		parts = append(parts, msg.Text)
+	}
+	return strings.Join(parts, "\n\n"), ""
+}
+
+// inboundDocChars is how much of an attached document's text goes into
+// the user turn; the agent reads the rest with doc_read.
+const inboundDocChars = 8000
+
+// documentText describes a file attachment for the user turn, with the
+// start of its text when it is a document doc_read understands.
+func (s *Server) documentText(a channels.Attachment) string {
+	name := a.Name
+	if name == "" {
+		name = filepath.Base(a.Path)
+	}
+	doc, err := tools.ExtractDocument(a.Path)
+	if err != nil {
+		s.logger.Debug().Err(err).Str("file", a.Path).Msg("No text extracted from attachment")
+		return fmt.Sprintf("[File attached: %s (%s)]", name, a.Path)
+	}
+
+	header := fmt.Sprintf("[Document attached: %s (%s), %s, %d %s(s)", name, a.Path, doc.Format, len(doc.Sections), doc.Unit)
+	for _, k := range []string{"title", "author"} {
+		if v := doc.Metadata[k]; v != "" {
+			header += fmt.Sprintf(", %s: %s", k, v)
+		}
+	}
+	body := []rune(doc.Text(nil))
+	if len(body) <= inboundDocChars {
+		return header + "]\n" + string(body)
+	}
+	return fmt.Sprintf("%s]\n%s\n[Showing the first %d of %d characters; use doc_read with this path and offset %d, or pages, to read more]",
+		header, string(body[:inboundDocChars]), inboundDocChars, len(body), inboundDocChars)
+}
+
+// replyAttachments converts tool media to channel attachments, dropping
+// what the channel cannot carry. Voice audio becomes a voice note where
+// supported and plain audio otherwise.
+func replyAttachments(caps *channels.Capabilities, media []tools.MediaAttachment) []channels.Attachment {
+	if caps == nil {
+		return nil
+	}
+	var out []channels.Attachment
+	for _, m := range media {
+		typ := m.Type
+		switch {
+		case typ == "audio" && m.Voice && caps.Voice:
+			typ = "voice"
+		case !caps.Media:
+			continue
+		}
+		out = append(out, channels.Attachment{
+			Type:     typ,
+			Path:     m.Path,
+			MimeType: m.MimeType,
+			Name:     filepath.Base(m.Path),
+		})
+	}
+	return out
+}
+
+func hasVoiceNote(media []tools.MediaAttachment) bool {
+	for _, m := range media {
+		if m.Type == "audio" && m.Voice {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAudio(attachments []channels.Attachment) bool {
+	for _, a := range attachments {
+		if a.Type == "audio" || a.Type == "voice" {
+			return true
+		}
+	}
+	return false
+}
+
+// restoreSessionOverrides re-applies persisted per-session model and auth
+// profile overrides to the agent.
+func (s *Server) restoreSessionOverrides(sessionKey string) {
+	svc := s.currentAgent()
+	entry := s.sessionManager.GetOrCreateSession(sessionKey)
+	if entry.Model != "" {
+		if _, err := svc.SetSessionModel(sessionKey, entry.Model); err != nil {
+			s.logger.Warn().Err(err).Str("session", sessionKey).Str("model", entry.Model).Msg("Ignoring invalid session model override")
+		}
+	}
+	if entry.AuthProfile != "" {
+		if err := svc.SetSessionAuthProfile(sessionKey, entry.AuthProfile); err != nil {
+			s.logger.Warn().Err(err).Str("session", sessionKey).Str("authProfile", entry.AuthProfile).Msg("Ignoring invalid session auth profile")
+		}
+	}
+}
+
 // SendMessage implements tools.MessageSender to allow agents to send messages via the gateway.
 func (s *Server) SendMessage(ctx context.Context, channel, target, message string) error {
-	s.logger.Info().Str("channel", channel).Str("target", target).Msg("Agent requested message send")
+	return s.SendMedia(ctx, channel, target, message, nil)
+}
+
+// SendMedia implements tools.MediaSender, delivering media as attachments
+// with message as the caption.
+func (s *Server) SendMedia(ctx context.Context, channel, target, message string, media []tools.MediaAttachment) error {
+	s.logger.Info().Str("channel", channel).Str("target", target).Int("media", len(media)).Msg("Agent requested message send")
 
 	// If implicit targeting (empty target), try to resolve from session history
 	if target == "" {
@@ -641,9 +877,15 @@ func (s *Server) SendMessage(ctx context.Context, channel, target, message strin
 		return fmt.Errorf("adapter for channel '%s' not found or not enabled", channel)
 	}
 
+	attachments := replyAttachments(adapter.Capabilities(), media)
+	if len(media) > 0 && len(attachments) == 0 {
+		return fmt.Errorf("channel '%s' does not support media", channel)
+	}
+
 	_, err := adapter.Send(ctx, &channels.SendRequest{
-		To:   channels.Destination{ChatID: target},
-		Text: message,
+		To:          channels.Destination{ChatID: target},
+		Text:        message,
+		Attachments: attachments,
 	})
 	return err
 }
@@ -723,6 +965,14 @@ func (s *Server) setupRoutes() {
 		api.POST("/cron/jobs/:id/run", s.handleCronRun)
 		api.GET("/cron/jobs/:id/history", s.handleCronHistory)
 
+		// Background process sessions
+		api.GET("/processes", s.handleProcessList)
+		api.GET("/processes/:id/log", s.handleProcessLog)
+
+		// Nodes
+		api.GET("/nodes", s.handleNodesList)
+		api.POST("/nodes/invoke", s.handleNodeInvoke)
+
 		// Gateway control
 		api.POST("/gateway/restart", s.handleRestart)
 		api.POST("/gateway/reload", s.handleReload)
@@ -740,6 +990,10 @@ func (s *Server) setupRoutes() {
 		api.GET("/browser/", s.handleBrowserStatus)
 	}
 
+	// Agent canvases
+	s.echo.Any(canvas.MountPath, s.CanvasAuthMiddleware(s.handleCanvas))
+	s.echo.Any(canvas.MountPath+"/*", s.CanvasAuthMiddleware(s.handleCanvas))
+
 	// Browser Extension Relay WebSocket
 	s.echo.GET("/extension/relay", s.HookAuthMiddleware(s.handleExtensionRelay))
 
@@ -785,18 +1039,36 @@ func (s *Server) Uptime() time.Duration {
 	}
 	return time.Since(s.startTime)
 }
+
+// currentAgent returns the agent service, which Reload may replace.
+func (s *Server) currentAgent() *agent.Service {
+	s.agentMu.RLock()
+	defer s.agentMu.RUnlock()
+	return s.agentService
+}
+
+// setAgent installs svc as the agent service and returns the one it
+// replaces.
+func (s *Server) setAgent(svc *agent.Service) (old *agent.Service) {
+	s.agentMu.Lock()
+	defer s.agentMu.Unlock()
+	old, s.agentService = s.agentService, svc
+	return old
+}
+
 func (s *Server) getDMPolicy(channelType string) string {
 	// If agent service isn't ready, default to restrictive or empty?
 	// Empty usually means open or default policy which is safer for startup
-	if s.agentService == nil || s.agentService.Config == nil {
+	svc := s.currentAgent()
+	if svc == nil || svc.Config == nil {
 		return ""
 	}
 
 	switch channelType {
 	case "telegram":
-		return s.agentService.Config.Channels.Telegram.DMPolicy
+		return svc.Config.Channels.Telegram.DMPolicy
 	case "discord":
-		return s.agentService.Config.Channels.Discord.DMPolicy
+		return svc.Config.Channels.Discord.DMPolicy
 	// TODO: Add other channels when they support DMPolicy in config
 	default:
 		return ""
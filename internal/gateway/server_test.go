@@ -1,11 +1,23 @@
 package gateway
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog"
+
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/agent/transcribe"
+	"github.com/liteclaw/liteclaw/internal/channels"
+	"github.com/liteclaw/liteclaw/internal/config"
 )
 
 func TestHandleHealth(t *testing.T) {
@@ -70,3 +82,151 @@ func TestServerNew(t *testing.T) {
 		t.Error("New() server should not be running")
 	}
 }
+
+func TestReplyAttachments(t *testing.T) {
+	media := []tools.MediaAttachment{
+		{Type: "audio", Path: "/w/media/tts/a.mp3", MimeType: "audio/mpeg", Voice: true},
+		{Type: "image", Path: "/w/b.png", MimeType: "image/png"},
+	}
+
+	got := replyAttachments(&channels.Capabilities{Media: true, Voice: true}, media)
+	if len(got) != 2 || got[0].Type != "voice" || got[0].Name != "a.mp3" || got[1].Type != "image" {
+		t.Errorf("voice+media channel attachments = %+v", got)
+	}
+	got = replyAttachments(&channels.Capabilities{Media: true}, media)
+	if len(got) != 2 || got[0].Type != "audio" {
+		t.Errorf("media-only channel attachments = %+v", got)
+	}
+	if got = replyAttachments(&channels.Capabilities{}, media); len(got) != 0 {
+		t.Errorf("text-only channel attachments = %+v", got)
+	}
+	if !hasVoiceNote(media) || hasVoiceNote(media[1:]) {
+		t.Error("hasVoiceNote mismatch")
+	}
+}
+
+// sentAdapter records the messages the gateway sends through it.
+type sentAdapter struct {
+	*channels.BaseAdapter
+	sent []string
+}
+
+func (a *sentAdapter) Start(ctx context.Context) error      { return nil }
+func (a *sentAdapter) Stop(ctx context.Context) error       { return nil }
+func (a *sentAdapter) Connect(ctx context.Context) error    { return nil }
+func (a *sentAdapter) Disconnect(ctx context.Context) error { return nil }
+func (a *sentAdapter) IsConnected() bool                    { return true }
+func (a *sentAdapter) Probe(ctx context.Context) (*channels.ProbeResult, error) {
+	return &channels.ProbeResult{}, nil
+}
+func (a *sentAdapter) SendReaction(ctx context.Context, req *channels.ReactionRequest) error {
+	return nil
+}
+func (a *sentAdapter) Send(ctx context.Context, req *channels.SendRequest) (*channels.SendResult, error) {
+	a.sent = append(a.sent, req.Text)
+	return &channels.SendResult{Success: true}, nil
+}
+
+func TestAttachmentsFetchedOnlyForAuthorizedSenders(t *testing.T) {
+	t.Setenv("LITECLAW_STATE_DIR", t.TempDir())
+	cfg := &config.Config{}
+	cfg.Channels.Telegram.DMPolicy = "pairing"
+	adapter := &sentAdapter{BaseAdapter: channels.NewBaseAdapter("telegram", "Telegram", channels.ChannelTypeTelegram, &channels.Capabilities{}, nil, zerolog.Nop())}
+	s := &Server{
+		agentService: &agent.Service{Config: cfg},
+		adapters:     map[string]channels.Adapter{"telegram": adapter},
+		logger:       zerolog.Nop(),
+	}
+
+	fetched := 0
+	msg := &channels.IncomingMessage{
+		ChannelType: "telegram", ChatID: "42", ChatType: "direct", SenderID: "42",
+		Attachments: []channels.Attachment{{Type: "voice", Fetch: func(ctx context.Context) (string, error) {
+			fetched++
+			return "/m/voice.ogg", nil
+		}}},
+	}
+	if err := s.processChannelMessage(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if fetched != 0 {
+		t.Error("an unpaired sender's attachment was downloaded")
+	}
+	if len(adapter.sent) != 1 || !strings.Contains(adapter.sent[0], "Pairing") {
+		t.Errorf("sent = %q, want pairing instructions", adapter.sent)
+	}
+
+	msg.Attachments = append(msg.Attachments, channels.Attachment{Type: "audio", Fetch: func(ctx context.Context) (string, error) {
+		return "", errors.New("unreachable")
+	}})
+	s.fetchAttachments(context.Background(), msg)
+	if fetched != 1 || len(msg.Attachments) != 1 || msg.Attachments[0].Path != "/m/voice.ogg" {
+		t.Errorf("fetched = %d, attachments = %+v", fetched, msg.Attachments)
+	}
+}
+
+// stubTranscriber returns fixed text, or fails for paths containing "bad".
+type stubTranscriber struct{}
+
+func (stubTranscriber) Name() string { return "stub" }
+
+func (stubTranscriber) Transcribe(ctx context.Context, path string) (*transcribe.Transcript, error) {
+	if strings.Contains(path, "bad") {
+		return nil, errors.New("decode failed")
+	}
+	return &transcribe.Transcript{Text: "remind me at five"}, nil
+}
+
+func TestInboundText(t *testing.T) {
+	s := &Server{agentService: &agent.Service{}, logger: zerolog.Nop()}
+	voice := []channels.Attachment{{Type: "voice", Path: "/m/voice.ogg"}}
+	ctx := context.Background()
+
+	text, notice := s.inboundText(ctx, &channels.IncomingMessage{Text: "hi"})
+	if text != "hi" || notice != "" {
+		t.Errorf("plain text = %q, %q", text, notice)
+	}
+
+	// No transcriber: voice-only turns are skipped with a notice.
+	text, notice = s.inboundText(ctx, &channels.IncomingMessage{Attachments: voice})
+	if text != "" || !strings.Contains(notice, "tools.transcription") {
+		t.Errorf("unconfigured = %q, %q", text, notice)
+	}
+	text, notice = s.inboundText(ctx, &channels.IncomingMessage{Text: "see audio", Attachments: voice})
+	if notice != "" || !strings.Contains(text, "could not be transcribed") || !strings.HasSuffix(text, "see audio") {
+		t.Errorf("unconfigured with caption = %q, %q", text, notice)
+	}
+
+	s.agentService.Transcriber = stubTranscriber{}
+	text, notice = s.inboundText(ctx, &channels.IncomingMessage{Attachments: voice})
+	if notice != "" || text != "[Transcription of audio message (/m/voice.ogg)]\nremind me at five" {
+		t.Errorf("transcribed = %q, %q", text, notice)
+	}
+	_, notice = s.inboundText(ctx, &channels.IncomingMessage{Attachments: []channels.Attachment{{Type: "audio", Path: "/m/bad.ogg"}}})
+	if !strings.Contains(notice, "couldn't transcribe") {
+		t.Errorf("failed transcription notice = %q", notice)
+	}
+
+	// Documents are extracted into the turn; other files are just named.
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "upload-1.csv")
+	_ = os.WriteFile(csvPath, []byte("name,qty\nwidget,3\n"), 0644)
+	binPath := filepath.Join(dir, "blob.bin")
+	_ = os.WriteFile(binPath, []byte{0, 1, 2, 3}, 0644)
+	text, notice = s.inboundText(ctx, &channels.IncomingMessage{Attachments: []channels.Attachment{
+		{Type: "file", Path: csvPath, Name: "stock.csv"},
+		{Type: "file", Path: binPath},
+	}})
+	want := "[Document attached: stock.csv (" + csvPath + "), csv, 1 sheet(s)]\n--- Sheet 1: upload-1.csv ---\nname\tqty\nwidget\t3" +
+		"\n\n[File attached: blob.bin (" + binPath + ")]"
+	if notice != "" || text != want {
+		t.Errorf("documents = %q, %q", text, notice)
+	}
+
+	long := filepath.Join(dir, "long.txt")
+	_ = os.WriteFile(long, []byte(strings.Repeat("x", inboundDocChars+10)), 0644)
+	text, _ = s.inboundText(ctx, &channels.IncomingMessage{Text: "summarise", Attachments: []channels.Attachment{{Type: "document", Path: long}}})
+	if !strings.Contains(text, "offset 8000") || !strings.HasSuffix(text, "summarise") {
+		t.Errorf("long document = %q", text[len(text)-200:])
+	}
+}
@@ -22,6 +22,8 @@ type SessionEntry struct {
 	ChatType      string `json:"chatType,omitempty"` // "direct" or "group"
 	UpdatedAt     int64  `json:"updatedAt"`          // Unix timestamp in ms
 	ThinkingLevel string `json:"thinkingLevel,omitempty"`
+	Model         string `json:"model,omitempty"`       // Per-session model override
+	AuthProfile   string `json:"authProfile,omitempty"` // Pinned auth profile
 }
 
 // Message represents a single chat message.
@@ -188,6 +190,30 @@ func (sm *SessionManager) GetHistory(sessionKey string) ([]Message, error) {
 	return messages, nil
 }
 
+// SetModel persists a per-session model override. An empty model clears it.
+func (sm *SessionManager) SetModel(sessionKey, model string) *SessionEntry {
+	entry := sm.GetOrCreateSession(sessionKey)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	entry.Model = model
+	entry.UpdatedAt = time.Now().UnixMilli()
+	sm.saveSessions()
+	return entry
+}
+
+// SetAuthProfile persists a per-session auth profile pin. An empty ID clears it.
+func (sm *SessionManager) SetAuthProfile(sessionKey, profileID string) *SessionEntry {
+	entry := sm.GetOrCreateSession(sessionKey)
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	entry.AuthProfile = profileID
+	entry.UpdatedAt = time.Now().UnixMilli()
+	sm.saveSessions()
+	return entry
+}
+
 func (sm *SessionManager) ListSessions() []*SessionEntry {
 	sm.mu.RLock()
 	defer sm.mu.RUnlock()
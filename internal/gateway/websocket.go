@@ -7,13 +7,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
-	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
+	"github.com/liteclaw/liteclaw/internal/agent"
+	"github.com/liteclaw/liteclaw/internal/agent/tools"
+	"github.com/liteclaw/liteclaw/internal/canvas"
 	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/liteclaw/liteclaw/internal/cron"
 )
@@ -32,6 +35,15 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 	}
 	defer func() { _ = ws.Close() }()
 
+	// Node invokes and chat runs answer from their own goroutines, and the
+	// connection allows only one writer at a time.
+	var writeMu sync.Mutex
+	send := func(frame interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return ws.WriteJSON(frame)
+	}
+
 	s.logger.Info().Msg("WebSocket client connected")
 
 	for {
@@ -63,24 +75,28 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 		// Handle specific methods
 		switch req.Method {
 		case "connect":
-			// Handshake response with Uptime
-			uptimeMs := s.Uptime().Milliseconds()
+			var params ConnectParams
+			data, _ := json.Marshal(req.Params)
+			_ = json.Unmarshal(data, &params)
+			if isNodeConnect(params) {
+				// Node connections speak their own small protocol from here on.
+				s.serveNode(ws, c.RealIP(), c.Request().Host, req.ID, params)
+				return nil
+			}
+
+			hello := s.helloPayload(c.Request().Host)
+			// The upgrade passed AuthMiddleware, so the client may open
+			// canvases; it gets their key rather than reusing its token.
+			if cfg, err := config.Load(); err == nil && cfg.Gateway.Auth.Token != "" {
+				hello["canvasKey"] = canvas.AccessKey(cfg.Gateway.Auth.Token)
+			}
 			res := map[string]interface{}{
-				"type": "res",
-				"id":   req.ID,
-				"ok":   true,
-				"payload": map[string]interface{}{
-					"type":     "hello-ok",
-					"protocol": 1,
-					"server": map[string]string{
-						"version": "dev",
-					},
-					"snapshot": map[string]interface{}{
-						"uptimeMs": uptimeMs,
-					},
-				},
+				"type":    "res",
+				"id":      req.ID,
+				"ok":      true,
+				"payload": hello,
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "config.get":
 			// Use ConfigPath() for consistency
@@ -114,7 +130,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"config": cfgObj,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "config.set":
 			raw, _ := req.Params["raw"].(string)
@@ -122,7 +138,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 			// Validation
 			var cfg config.Config
 			if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{
+				_ = send(map[string]interface{}{
 					"type":  "res",
 					"id":    req.ID,
 					"ok":    false,
@@ -132,7 +148,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 			}
 
 			if err := cfg.Validate(); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{
+				_ = send(map[string]interface{}{
 					"type":  "res",
 					"id":    req.ID,
 					"ok":    false,
@@ -145,7 +161,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 			path := config.ConfigPath()
 			err := os.WriteFile(path, []byte(raw), 0600)
 			if err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{
+				_ = send(map[string]interface{}{
 					"type":  "res",
 					"id":    req.ID,
 					"ok":    false,
@@ -163,20 +179,16 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"path": path,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "config.schema":
-			// Stub: return minimal schema
 			res := map[string]interface{}{
-				"type": "res",
-				"id":   req.ID,
-				"ok":   true,
-				"payload": map[string]interface{}{
-					"type":       "object",
-					"properties": map[string]interface{}{},
-				},
+				"type":    "res",
+				"id":      req.ID,
+				"ok":      true,
+				"payload": config.Schema(),
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "cron.status":
 			res := map[string]interface{}{
@@ -188,10 +200,10 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"running": true,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "cron.list":
-			jobs := s.agentService.GetScheduler().Jobs()
+			jobs := s.currentAgent().GetScheduler().Jobs()
 			res := map[string]interface{}{
 				"type": "res",
 				"id":   req.ID,
@@ -200,18 +212,18 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"jobs": jobs,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "cron.add":
 			jobArg, ok := req.Params["job"].(map[string]interface{})
 			if !ok {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "job param required"})
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "job param required"})
 				break
 			}
 			jobJSON, _ := json.Marshal(jobArg)
 			var job cron.Job
 			if err := json.Unmarshal(jobJSON, &job); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "invalid job json: " + err.Error()})
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "invalid job json: " + err.Error()})
 				break
 			}
 
@@ -234,10 +246,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 			if job.Payload.TimeoutSeconds == 0 {
 				job.Payload.TimeoutSeconds = 10 // Default to 10s (matches TS)
 			}
-			// Default Model if missing
-			if job.Payload.Model == "" {
-				job.Payload.Model = s.agentService.Config.Agents.Defaults.Model.Primary
-			}
+			// Leave Payload.Model empty so the job follows the current default model.
 
 			// Default Deliver to true for agentTurn
 			if job.Payload.Kind == cron.PayloadKindAgentTurn {
@@ -251,8 +260,8 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 				}
 			}
 
-			if err := s.agentService.GetScheduler().AddJob(&job); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to add job: " + err.Error()})
+			if err := s.currentAgent().GetScheduler().AddJob(&job); err != nil {
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to add job: " + err.Error()})
 				break
 			}
 
@@ -265,19 +274,19 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"job": job,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "cron.remove":
 			id, _ := req.Params["id"].(string)
 			if id == "" {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "id param required"})
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "id param required"})
 				break
 			}
-			if err := s.agentService.GetScheduler().RemoveJob(id); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to remove job: " + err.Error()})
+			if err := s.currentAgent().GetScheduler().RemoveJob(id); err != nil {
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to remove job: " + err.Error()})
 				break
 			}
-			_ = ws.WriteJSON(map[string]interface{}{
+			_ = send(map[string]interface{}{
 				"type":    "res",
 				"id":      req.ID,
 				"ok":      true,
@@ -287,41 +296,61 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 		case "cron.run":
 			id, _ := req.Params["id"].(string)
 			if id == "" {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "id param required"})
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "id param required"})
 				break
 			}
-			if err := s.agentService.GetScheduler().RunJobNow(id); err != nil {
-				_ = ws.WriteJSON(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to run job: " + err.Error()})
+			if err := s.currentAgent().GetScheduler().RunJobNow(id); err != nil {
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": "failed to run job: " + err.Error()})
 				break
 			}
-			_ = ws.WriteJSON(map[string]interface{}{
+			_ = send(map[string]interface{}{
 				"type":    "res",
 				"id":      req.ID,
 				"ok":      true,
 				"payload": map[string]interface{}{"ok": true, "id": id},
 			})
 
-		case "node.list":
-			hostname, _ := os.Hostname()
-			nodes := []map[string]interface{}{
-				{
-					"nodeId":      "liteclaw-local",
-					"displayName": hostname,
-					"connected":   true,
-					"paired":      true,
-					"platform":    runtime.GOOS,
-				},
-			}
+		case "node.list", "nodes.list":
 			res := map[string]interface{}{
-				"type": "res",
-				"id":   req.ID,
-				"ok":   true,
-				"payload": map[string]interface{}{
-					"ts":    time.Now().UnixMilli(),
-					"nodes": nodes,
-				},
+				"type":    "res",
+				"id":      req.ID,
+				"ok":      true,
+				"payload": s.nodeListPayload(),
+			}
+			_ = send(res)
+
+		case "node.describe":
+			nodeID, _ := req.Params["nodeId"].(string)
+			node, err := s.nodes.Find(nodeID, "")
+			if err != nil {
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": map[string]interface{}{"message": err.Error()}})
+				break
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(map[string]interface{}{
+				"type":    "res",
+				"id":      req.ID,
+				"ok":      true,
+				"payload": map[string]interface{}{"node": node},
+			})
+
+		case "node.invoke":
+			var invoke nodeInvokeRequest
+			data, _ := json.Marshal(req.Params)
+			_ = json.Unmarshal(data, &invoke)
+			// Invokes wait on the node, so answer from the background.
+			go func(id string) {
+				payload, err := s.invokeNode(context.Background(), invoke)
+				if err != nil {
+					_ = send(map[string]interface{}{"type": "res", "id": id, "ok": false, "error": map[string]interface{}{"message": err.Error()}})
+					return
+				}
+				_ = send(map[string]interface{}{
+					"type":    "res",
+					"id":      id,
+					"ok":      true,
+					"payload": map[string]interface{}{"ok": true, "nodeId": invoke.NodeID, "command": invoke.Command, "payload": payload},
+				})
+			}(req.ID)
 
 		case "channels.list":
 			// Stub: Return empty channels list
@@ -334,10 +363,10 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"channelOrder": []string{},
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "agents.list":
-			cfg := s.agentService.Config
+			cfg := s.currentAgent().Config
 			agentsList := make([]map[string]interface{}, 0)
 			// Return the primary agent from config as long as we don't have a multi-agent list yet
 			// In liteclaw.json, agents.list might exist.
@@ -356,7 +385,20 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"agents": agentsList,
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
+
+		case "process.list":
+			processes := tools.Processes().List()
+			res := map[string]interface{}{
+				"type": "res",
+				"id":   req.ID,
+				"ok":   true,
+				"payload": map[string]interface{}{
+					"processes": processes,
+					"count":     len(processes),
+				},
+			}
+			_ = send(res)
 
 		case "presence.list":
 			// Stub: Return empty presence list
@@ -368,7 +410,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"entries": []interface{}{},
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "sessions.list":
 			sessions := s.sessionManager.ListSessions()
@@ -386,7 +428,52 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					},
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
+
+		case "sessions.patch":
+			svc := s.currentAgent()
+			sessionKey, _ := req.Params["key"].(string)
+			if sessionKey == "" {
+				sessionKey, _ = req.Params["sessionKey"].(string)
+			}
+			if sessionKey == "" {
+				_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": map[string]interface{}{"message": "key param required"}})
+				break
+			}
+
+			if model, ok := req.Params["model"]; ok {
+				ref, _ := model.(string)
+				// The canonical ref is persisted, so an alias later renamed or
+				// repointed does not change the session's model.
+				ref, err := svc.SetSessionModel(sessionKey, ref)
+				if err != nil {
+					_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": map[string]interface{}{"message": err.Error()}})
+					break
+				}
+				s.sessionManager.SetModel(sessionKey, ref)
+			}
+
+			if profile, ok := req.Params["authProfile"]; ok {
+				profileID, _ := profile.(string)
+				if err := svc.SetSessionAuthProfile(sessionKey, profileID); err != nil {
+					_ = send(map[string]interface{}{"type": "res", "id": req.ID, "ok": false, "error": map[string]interface{}{"message": err.Error()}})
+					break
+				}
+				s.sessionManager.SetAuthProfile(sessionKey, profileID)
+			}
+
+			_ = send(map[string]interface{}{
+				"type": "res",
+				"id":   req.ID,
+				"ok":   true,
+				"payload": map[string]interface{}{
+					"ok":          true,
+					"key":         sessionKey,
+					"entry":       s.sessionManager.GetOrCreateSession(sessionKey),
+					"model":       svc.SessionModel(sessionKey),
+					"authProfile": svc.SessionAuthProfile(sessionKey),
+				},
+			})
 
 		case "chat.history":
 			sessionKey, _ := req.Params["sessionKey"].(string)
@@ -407,7 +494,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"thinkingLevel": "off",
 				},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 
 		case "chat.send":
 			// extract params
@@ -426,6 +513,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 
 			// Store User Message in Persistent History
 			_ = s.sessionManager.AddMessage(sessionKey, "user", message)
+			s.restoreSessionOverrides(sessionKey)
 
 			// 1. Send Response OK (Ack with started status)
 			res := map[string]interface{}{
@@ -437,7 +525,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 					"status": "started",
 				},
 			}
-			if err := ws.WriteJSON(res); err != nil {
+			if err := send(res); err != nil {
 				s.logger.Error().Err(err).Msg("Failed to write response")
 				break
 			}
@@ -467,11 +555,11 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 						"event":   "chat",
 						"payload": payload,
 					}
-					_ = ws.WriteJSON(event)
+					_ = send(event)
 					seq++
 				}
 
-				err := s.agentService.ProcessChat(context.Background(), sessionKey, message, func(delta string) {
+				err := s.currentAgent().ProcessChatWithOptions(context.Background(), sessionKey, message, agent.RunOptions{RunID: runId}, func(delta string) {
 					fullResponse.WriteString(delta)
 
 					// Client expects 'message' to find the text to display.
@@ -500,7 +588,7 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 						"state":        "error",
 						"errorMessage": err.Error(),
 					}
-					_ = ws.WriteJSON(map[string]interface{}{
+					_ = send(map[string]interface{}{
 						"type":    "event",
 						"event":   "chat",
 						"payload": errPayload,
@@ -538,9 +626,26 @@ func (s *Server) handleWebSocket(c echo.Context) error {
 				"ok":    false,
 				"error": map[string]interface{}{"code": "method_not_found", "message": "Method not found: " + req.Method},
 			}
-			_ = ws.WriteJSON(res)
+			_ = send(res)
 		}
 	}
 
 	return nil
 }
+
+// helloPayload is the connect response for a client that reached the
+// gateway at host.
+func (s *Server) helloPayload(host string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":          "hello-ok",
+		"protocol":      1,
+		"canvasHostUrl": "http://" + host + canvas.MountPath,
+		"server": map[string]string{
+			"version": "dev",
+		},
+		"snapshot": map[string]interface{}{
+			"uptimeMs": s.Uptime().Milliseconds(),
+			"nodes":    len(s.nodes.List()),
+		},
+	}
+}
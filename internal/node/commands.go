@@ -0,0 +1,266 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// maxOutputBytes caps the stdout and stderr returned by system.run.
+const maxOutputBytes = 256 * 1024
+
+// defaultRunTimeout bounds system.run when the request sets no timeout.
+const defaultRunTimeout = 2 * time.Minute
+
+// systemRun runs a command. params.command is an argv list, or a string
+// run through the platform shell; cwd, env and timeoutMs are optional.
+func systemRun(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	var argv []string
+	switch c := params["command"].(type) {
+	case string:
+		if c == "" {
+			return nil, fmt.Errorf("command is required")
+		}
+		if runtime.GOOS == "windows" {
+			argv = []string{"cmd", "/C", c}
+		} else {
+			argv = []string{"sh", "-c", c}
+		}
+	case []interface{}:
+		for _, a := range c {
+			argv = append(argv, fmt.Sprint(a))
+		}
+	}
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	timeout := defaultRunTimeout
+	if ms, ok := params["timeoutMs"].(float64); ok && ms > 0 {
+		timeout = time.Duration(ms) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if cwd, _ := params["cwd"].(string); cwd != "" {
+		cmd.Dir = cwd
+	}
+	if env, ok := params["env"].(map[string]interface{}); ok && len(env) > 0 {
+		cmd.Env = os.Environ()
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, k+"="+fmt.Sprint(v))
+		}
+	}
+	stdout := &limitedBuffer{max: maxOutputBytes}
+	stderr := &limitedBuffer{max: maxOutputBytes}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	result := map[string]interface{}{
+		"stdout":     stdout.String(),
+		"stderr":     stderr.String(),
+		"exitCode":   0,
+		"durationMs": time.Since(start).Milliseconds(),
+	}
+	if stdout.truncated || stderr.truncated {
+		result["truncated"] = true
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case ctx.Err() == context.DeadlineExceeded:
+		result["exitCode"] = -1
+		result["timedOut"] = true
+	case errors.As(err, &exitErr):
+		result["exitCode"] = exitErr.ExitCode()
+	default:
+		return nil, err
+	}
+	return result, nil
+}
+
+// systemWhich resolves params.bins on the node's PATH.
+func systemWhich(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	bins, _ := params["bins"].([]interface{})
+	if len(bins) == 0 {
+		return nil, fmt.Errorf("bins is required")
+	}
+	found := map[string]string{}
+	for _, b := range bins {
+		name := fmt.Sprint(b)
+		if path, err := lookPath(name); err == nil {
+			found[name] = path
+		}
+	}
+	return map[string]interface{}{"bins": found}, nil
+}
+
+// captureCommand builds the argv that writes a capture to path.
+type captureCommand func(path string) []string
+
+// captureHandler runs cmd and returns the captured file base64 encoded.
+func captureHandler(cmd captureCommand, ext string) Handler {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		dir, err := os.MkdirTemp("", "liteclaw-node-")
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+		path := filepath.Join(dir, "capture."+ext)
+
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		argv := cmd(path)
+		if out, err := exec.CommandContext(ctx, argv[0], argv[1:]...).CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("%s: %v: %s", filepath.Base(argv[0]), err, bytes.TrimSpace(out))
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("capture produced no file: %w", err)
+		}
+		return map[string]interface{}{
+			"format": ext,
+			"bytes":  len(data),
+			"base64": base64.StdEncoding.EncodeToString(data),
+		}, nil
+	}
+}
+
+// cameraCommand returns how to take a photo on this machine, or nil.
+func cameraCommand() captureCommand {
+	switch runtime.GOOS {
+	case "darwin":
+		if bin, err := lookPath("imagesnap"); err == nil {
+			return func(path string) []string { return []string{bin, "-q", "-w", "1", path} }
+		}
+		if bin, err := lookPath("ffmpeg"); err == nil {
+			return func(path string) []string {
+				return []string{bin, "-loglevel", "error", "-f", "avfoundation", "-framerate", "30", "-i", "0", "-frames:v", "1", "-y", path}
+			}
+		}
+	case "linux":
+		if _, err := os.Stat("/dev/video0"); err != nil {
+			return nil
+		}
+		if bin, err := lookPath("ffmpeg"); err == nil {
+			return func(path string) []string {
+				return []string{bin, "-loglevel", "error", "-f", "v4l2", "-i", "/dev/video0", "-frames:v", "1", "-y", path}
+			}
+		}
+	}
+	return nil
+}
+
+// screenCommand returns how to capture the screen on this machine, or nil.
+func screenCommand() captureCommand {
+	switch runtime.GOOS {
+	case "darwin":
+		if bin, err := lookPath("screencapture"); err == nil {
+			return func(path string) []string { return []string{bin, "-x", path} }
+		}
+	case "linux":
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if bin, err := lookPath("grim"); err == nil {
+				return func(path string) []string { return []string{bin, path} }
+			}
+		}
+		if os.Getenv("DISPLAY") == "" {
+			return nil
+		}
+		if bin, err := lookPath("scrot"); err == nil {
+			return func(path string) []string { return []string{bin, "--overwrite", path} }
+		}
+		if bin, err := lookPath("import"); err == nil {
+			return func(path string) []string { return []string{bin, "-window", "root", path} }
+		}
+	}
+	return nil
+}
+
+// coordinates matches the latitude and longitude CoreLocationCLI prints.
+var coordinates = regexp.MustCompile(`([-+]?\d+\.\d+)[,\s]+([-+]?\d+\.\d+)`)
+
+// locationHandler reads the machine's location with CoreLocationCLI.
+func locationHandler(bin string) Handler {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, bin).Output()
+		if err != nil {
+			return nil, fmt.Errorf("CoreLocationCLI: %w", err)
+		}
+		m := coordinates.FindSubmatch(out)
+		if m == nil {
+			return nil, fmt.Errorf("CoreLocationCLI: unexpected output %q", bytes.TrimSpace(out))
+		}
+		lat, _ := strconv.ParseFloat(string(m[1]), 64)
+		lon, _ := strconv.ParseFloat(string(m[2]), 64)
+		return map[string]interface{}{"latitude": lat, "longitude": lon}, nil
+	}
+}
+
+// openCommand returns the argv prefix that opens a URL in the desktop's
+// browser, or nil when there is none.
+func openCommand() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		if os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+			return nil
+		}
+		if bin, err := lookPath("xdg-open"); err == nil {
+			return []string{bin}
+		}
+	}
+	return nil
+}
+
+// canvasOpen shows params.url in the desktop's browser.
+func canvasOpen(open []string) Handler {
+	return func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		url, _ := params["url"].(string)
+		if url == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		argv := append(append([]string{}, open...), url)
+		// The opener may outlive the invoke, so it is not tied to ctx.
+		cmd := exec.Command(argv[0], argv[1:]...)
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		go func() { _ = cmd.Wait() }()
+		return map[string]interface{}{"url": url}, nil
+	}
+}
+
+// limitedBuffer keeps the first max bytes written to it.
+type limitedBuffer struct {
+	bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,93 @@
+// Package node runs a LiteClaw node: a machine that connects to a gateway,
+// advertises the commands it supports and runs them on the agent's behalf.
+package node
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Handler runs one node command.
+type Handler func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+
+// Host holds the commands this machine can run.
+type Host struct {
+	handlers map[string]Handler
+}
+
+// lookPath finds helper binaries; replaced in tests.
+var lookPath = exec.LookPath
+
+// NewHost returns a host with every command this machine supports. When
+// allow is non-empty only those commands are offered.
+func NewHost(allow []string) *Host {
+	h := &Host{handlers: map[string]Handler{
+		"system.run":   systemRun,
+		"system.which": systemWhich,
+	}}
+	if cmd := cameraCommand(); cmd != nil {
+		h.handlers["camera.snap"] = captureHandler(cmd, "jpg")
+	}
+	if cmd := screenCommand(); cmd != nil {
+		h.handlers["screen.snapshot"] = captureHandler(cmd, "png")
+	}
+	if bin, err := lookPath("CoreLocationCLI"); err == nil {
+		h.handlers["location.get"] = locationHandler(bin)
+	}
+	if open := openCommand(); open != nil {
+		h.handlers["canvas.present"] = canvasOpen(open)
+		h.handlers["canvas.navigate"] = canvasOpen(open)
+	}
+
+	if len(allow) > 0 {
+		keep := make(map[string]bool, len(allow))
+		for _, c := range allow {
+			keep[strings.TrimSpace(c)] = true
+		}
+		for c := range h.handlers {
+			if !keep[c] {
+				delete(h.handlers, c)
+			}
+		}
+	}
+	return h
+}
+
+// Commands returns the offered commands, sorted.
+func (h *Host) Commands() []string {
+	out := make([]string, 0, len(h.handlers))
+	for c := range h.handlers {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Caps returns the command families offered, such as "system" or "camera".
+func (h *Host) Caps() []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, c := range h.Commands() {
+		family, _, _ := strings.Cut(c, ".")
+		if !seen[family] {
+			seen[family] = true
+			out = append(out, family)
+		}
+	}
+	return out
+}
+
+// Invoke runs command with params.
+func (h *Host) Invoke(ctx context.Context, command string, params map[string]interface{}) (interface{}, error) {
+	handler, ok := h.handlers[command]
+	if !ok {
+		return nil, fmt.Errorf("unsupported command: %s", command)
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return handler(ctx, params)
+}
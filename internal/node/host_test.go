@@ -0,0 +1,48 @@
+package node
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestHostSystemRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	h := NewHost([]string{"system.run", "system.which"})
+	if got := h.Commands(); len(got) != 2 || got[0] != "system.run" {
+		t.Fatalf("Commands() = %v", got)
+	}
+	if caps := h.Caps(); len(caps) != 1 || caps[0] != "system" {
+		t.Fatalf("Caps() = %v", caps)
+	}
+
+	ctx := context.Background()
+	out, err := h.Invoke(ctx, "system.run", map[string]interface{}{
+		"command": "echo $GREETING; echo oops >&2; exit 3",
+		"env":     map[string]interface{}{"GREETING": "hi"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := out.(map[string]interface{})
+	if res["stdout"] != "hi\n" || res["stderr"] != "oops\n" || res["exitCode"] != 3 {
+		t.Fatalf("system.run = %v", res)
+	}
+
+	out, err = h.Invoke(ctx, "system.run", map[string]interface{}{
+		"command":   []interface{}{"sleep", "5"},
+		"timeoutMs": float64(50),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := out.(map[string]interface{}); res["timedOut"] != true {
+		t.Fatalf("timed out run = %v", res)
+	}
+
+	if _, err := h.Invoke(ctx, "camera.snap", nil); err == nil {
+		t.Fatal("filtered command was invoked")
+	}
+}
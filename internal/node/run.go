@@ -0,0 +1,154 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/liteclaw/liteclaw/internal/gateway"
+)
+
+// DefaultRetryInterval is how long Run waits before reconnecting.
+const DefaultRetryInterval = 5 * time.Second
+
+// Options configures a node's gateway connection.
+type Options struct {
+	// URL is the gateway WebSocket URL.
+	URL   string
+	Token string
+	// DisplayName is how the node appears in `nodes list`.
+	DisplayName string
+	Version     string
+	// Identity is the node's device key; approval is tied to it.
+	Identity *gateway.DeviceIdentity
+	// RetryInterval is the reconnect delay (default DefaultRetryInterval).
+	RetryInterval time.Duration
+	// Logf reports connection state; nil discards it.
+	Logf func(format string, args ...interface{})
+}
+
+// Run keeps the node connected to the gateway, serving invoke requests,
+// until ctx is done. While the device awaits approval it retries and logs
+// the pairing code once.
+func (h *Host) Run(ctx context.Context, opts Options) error {
+	if opts.Identity == nil {
+		return fmt.Errorf("node identity is required")
+	}
+	retry := opts.RetryInterval
+	if retry <= 0 {
+		retry = DefaultRetryInterval
+	}
+	logf := opts.Logf
+	if logf == nil {
+		logf = func(string, ...interface{}) {}
+	}
+
+	lastCode := ""
+	for {
+		err := h.serve(ctx, opts, logf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var shape *gateway.ErrorShape
+		switch {
+		case errors.As(err, &shape) && shape.Code == "NOT_PAIRED":
+			code := ""
+			if details, ok := shape.Details.(map[string]interface{}); ok {
+				code, _ = details["code"].(string)
+			}
+			if code != lastCode {
+				logf("Waiting for approval. On the gateway host run: liteclaw pairing approve %s %s", gateway.NodePairingChannel, code)
+				lastCode = code
+			}
+		case err != nil:
+			logf("Gateway connection: %v (retrying in %s)", err, retry)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retry):
+		}
+	}
+}
+
+// serve runs one connection until it closes.
+func (h *Host) serve(ctx context.Context, opts Options, logf func(string, ...interface{})) error {
+	closed := make(chan struct{})
+	var closeOnce sync.Once
+	var client *gateway.Client
+	client = gateway.NewClient(gateway.ClientOptions{
+		URL:               opts.URL,
+		Token:             opts.Token,
+		ClientName:        gateway.ClientNameNode,
+		ClientDisplayName: opts.DisplayName,
+		ClientVersion:     opts.Version,
+		Platform:          runtime.GOOS,
+		Mode:              gateway.ClientModeNode,
+		Role:              "node",
+		Caps:              h.Caps(),
+		Commands:          h.Commands(),
+		Identity:          opts.Identity,
+		OnEvent: func(evt *gateway.EventFrame) {
+			if evt.Event == gateway.EventNodeInvokeRequest {
+				go h.answer(ctx, client, evt.Payload)
+			}
+		},
+		OnClose: func(code int, reason string) {
+			closeOnce.Do(func() { close(closed) })
+		},
+	})
+	if err := client.Connect(ctx); err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	logf("Connected to %s as %s (%d commands)", opts.URL, opts.Identity.ID[:12], len(h.Commands()))
+
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-closed:
+		return fmt.Errorf("connection closed")
+	}
+}
+
+// invokeRequest is the payload of a node.invoke.request event.
+type invokeRequest struct {
+	ID        string                 `json:"id"`
+	NodeID    string                 `json:"nodeId"`
+	Command   string                 `json:"command"`
+	Params    map[string]interface{} `json:"params"`
+	TimeoutMs int64                  `json:"timeoutMs"`
+}
+
+// answer runs an invoke request and sends its result to the gateway.
+func (h *Host) answer(ctx context.Context, client *gateway.Client, payload interface{}) {
+	var req invokeRequest
+	data, _ := json.Marshal(payload)
+	if err := json.Unmarshal(data, &req); err != nil || req.ID == "" {
+		return
+	}
+
+	runCtx := ctx
+	if req.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(req.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	res := gateway.NodeInvokeResult{ID: req.ID, NodeID: req.NodeID, OK: true}
+	out, err := h.Invoke(runCtx, req.Command, req.Params)
+	if err != nil {
+		res.OK = false
+		res.Error = &gateway.ErrorShape{Code: "INVOKE_FAILED", Message: err.Error()}
+	} else {
+		res.Payload = out
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	_, _ = client.Request(sendCtx, gateway.MethodNodeInvokeResult, res)
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/gorilla/websocket"
 
+	"github.com/liteclaw/liteclaw/internal/canvas"
 	"github.com/liteclaw/liteclaw/internal/config"
 	"github.com/liteclaw/liteclaw/internal/gateway"
 )
@@ -138,6 +139,9 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.connecting = false
 		m.masking = false
 		m.messages = append(m.messages, infoStyle.Render("✓ Gateway Ready"))
+		if msg.canvasURL != "" {
+			m.messages = append(m.messages, infoStyle.Render("Canvas: "+msg.canvasURL))
+		}
 		m.viewport.SetContent(strings.Join(m.messages, "\n"))
 		m.viewport.GotoBottom()
 		return m, waitForMessage(m.conn)
@@ -214,7 +218,7 @@ func maximum(a, b int) int {
 
 // Messages
 type connectedMsg struct{ conn *websocket.Conn }
-type handshakeOkMsg struct{}
+type handshakeOkMsg struct{ canvasURL string }
 type incomingMessageMsg struct {
 	content string
 	runID   string
@@ -347,7 +351,7 @@ func waitForMessage(conn *websocket.Conn) tea.Cmd {
 		if err := json.Unmarshal(message, &frame); err == nil {
 			// Check for handshake response
 			if frame.Type == "res" && frame.ID == "handshake-1" && frame.OK {
-				return handshakeOkMsg{}
+				return handshakeOkMsg{canvasURL: canvasURL(frame.Payload)}
 			}
 
 			if frame.Type == "event" && frame.Event == "chat" {
@@ -376,6 +380,21 @@ func waitForMessage(conn *websocket.Conn) tea.Cmd {
 	}
 }
 
+// canvasURL returns the main session's canvas page from a hello payload,
+// or "" if the gateway does not host canvases. It carries the canvas
+// access key, never the gateway token.
+func canvasURL(hello map[string]interface{}) string {
+	base, _ := hello["canvasHostUrl"].(string)
+	if base == "" {
+		return ""
+	}
+	u := canvas.URL(base, "main")
+	if key, _ := hello["canvasKey"].(string); key != "" {
+		u += "?" + canvas.AccessKeyParam + "=" + url.QueryEscape(key)
+	}
+	return u
+}
+
 // Run starts the TUI with default gateway address (localhost:3456).
 func Run() error {
 	return RunWithConfig(nil)
@@ -30,6 +30,9 @@ export type GatewayHelloOk = {
   protocol: number;
   features?: { methods?: string[]; events?: string[] };
   snapshot?: unknown;
+  canvasHostUrl?: string;
+  /** Opens canvas pages; unlike the gateway token it is safe in a URL. */
+  canvasKey?: string;
   auth?: {
     deviceToken?: string;
     role?: string;
@@ -31,6 +31,13 @@ export function renderOverview(props: OverviewProps) {
   const tick = snapshot?.policy?.tickIntervalMs
     ? `${snapshot.policy.tickIntervalMs}ms`
     : "n/a";
+  const canvasUrl = (() => {
+    const base = props.hello?.canvasHostUrl;
+    if (!base) return null;
+    const session = encodeURIComponent(props.settings.sessionKey || "main");
+    const key = props.hello?.canvasKey;
+    return `${base.replace(/\/$/, "")}/${session}/${key ? `?canvasKey=${encodeURIComponent(key)}` : ""}`;
+  })();
   const authHint = (() => {
     if (props.connected || !props.lastError) return null;
     const lower = props.lastError.toLowerCase();
@@ -200,6 +207,16 @@ export function renderOverview(props: OverviewProps) {
                 : "n/a"}
             </div>
           </div>
+          <div class="stat">
+            <div class="stat-label">Canvas</div>
+            <div class="stat-value">
+              ${canvasUrl
+                ? html`<a class="session-link" href=${canvasUrl} target="_blank" rel="noreferrer"
+                    >Open</a
+                  >`
+                : "n/a"}
+            </div>
+          </div>
         </div>
         ${props.lastError
           ? html`<div class="callout danger" style="margin-top: 14px;">